@@ -300,22 +300,18 @@ func NativeCreator(pattern string) (ycsb.DBCreator, error) {
 
 func OreoRedisCreator(isRemote bool) (ycsb.DBCreator, error) {
 	redisConn1 := redisCo.NewRedisConnection(&redisCo.ConnectionOptions{
-		Address:  benConfig.RedisAddr,
-		Password: benConfig.RedisPassword,
-		PoolSize: 100,
+		Address:       benConfig.RedisAddr,
+		Password:      benConfig.RedisPassword,
+		ReaderAddress: benConfig.RedisReaderAddr,
+		PoolSize:      100,
+		Namespace:     txn.Namespace(benConfig.KeyNamespace),
 	})
 
 	redisConn1.Connect()
 	// try to warm up the connection
-	var wg sync.WaitGroup
-	for i := 1; i <= 30; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			redisConn1.Get("1")
-		}()
+	if err := txn.WarmUp(redisConn1, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
 	}
-	wg.Wait()
 	return &oreo.OreoRedisCreator{
 		IsRemote: isRemote,
 		ConnList: []*redisCo.RedisConnection{
@@ -325,33 +321,33 @@ func OreoRedisCreator(isRemote bool) (ycsb.DBCreator, error) {
 
 func OreoMongoCreator(isRemote bool) (ycsb.DBCreator, error) {
 	mongoConn1 := mongoCo.NewMongoConnection(&mongoCo.ConnectionOptions{
-		Address:        benConfig.MongoDBAddr1,
-		DBName:         "oreo",
-		CollectionName: "benchmark",
-		Username:       benConfig.MongoDBUsername,
-		Password:       benConfig.MongoDBPassword,
+		Address:           benConfig.MongoDBAddr1,
+		DBName:            "oreo",
+		CollectionName:    "benchmark",
+		Username:          benConfig.MongoDBUsername,
+		Password:          benConfig.MongoDBPassword,
+		ReadFromSecondary: benConfig.MongoDBReadFromSecondary,
+		Namespace:         txn.Namespace(benConfig.KeyNamespace),
 	})
 	mongoConn2 := mongoCo.NewMongoConnection(&mongoCo.ConnectionOptions{
-		Address:        benConfig.MongoDBAddr2,
-		DBName:         "oreo",
-		CollectionName: "benchmark",
-		Username:       benConfig.MongoDBUsername,
-		Password:       benConfig.MongoDBPassword,
+		Address:           benConfig.MongoDBAddr2,
+		DBName:            "oreo",
+		CollectionName:    "benchmark",
+		Username:          benConfig.MongoDBUsername,
+		Password:          benConfig.MongoDBPassword,
+		ReadFromSecondary: benConfig.MongoDBReadFromSecondary,
+		Namespace:         txn.Namespace(benConfig.KeyNamespace),
 	})
 
 	mongoConn1.Connect()
 	mongoConn2.Connect()
 	// try to warm up the connection
-	var wg sync.WaitGroup
-	for i := 1; i <= 30; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			mongoConn1.Get("1")
-			mongoConn2.Get("1")
-		}()
+	if err := txn.WarmUp(mongoConn1, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
+	}
+	if err := txn.WarmUp(mongoConn2, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
 	}
-	wg.Wait()
 	return &oreo.OreoMongoCreator{
 		IsRemote: isRemote,
 		ConnList: []*mongoCo.MongoConnection{
@@ -361,8 +357,9 @@ func OreoMongoCreator(isRemote bool) (ycsb.DBCreator, error) {
 // TODO: Add isRemote logic
 func OreoCouchCreator(isRemote bool) (ycsb.DBCreator, error) {
 	couchConn1 := couchdb.NewCouchDBConnection(&couchdb.ConnectionOptions{
-		Address: benConfig.CouchDBAddr,
-		DBName:  "oreo",
+		Address:   benConfig.CouchDBAddr,
+		DBName:    "oreo",
+		Namespace: txn.Namespace(benConfig.KeyNamespace),
 		// Username: CouchUsername,
 		// Password: CouchPassword,
 	})
@@ -372,15 +369,9 @@ func OreoCouchCreator(isRemote bool) (ycsb.DBCreator, error) {
 	}
 
 	// try to warm up the connection
-	var wg sync.WaitGroup
-	for i := 1; i <= 30; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			couchConn1.Get("1")
-		}()
+	if err := txn.WarmUp(couchConn1, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
 	}
-	wg.Wait()
 
 	return &oreo.OreoCouchCreator{
 		ConnList: []*couchdb.CouchDBConnection{
@@ -392,33 +383,33 @@ func OreoCreator(pattern string, isRemote bool) (ycsb.DBCreator, error) {
 
 	if pattern == "mm" {
 		mongoConn1 := mongoCo.NewMongoConnection(&mongoCo.ConnectionOptions{
-			Address:        benConfig.MongoDBAddr1,
-			DBName:         "oreo",
-			CollectionName: "benchmark",
-			Username:       benConfig.MongoDBUsername,
-			Password:       benConfig.MongoDBPassword,
+			Address:           benConfig.MongoDBAddr1,
+			DBName:            "oreo",
+			CollectionName:    "benchmark",
+			Username:          benConfig.MongoDBUsername,
+			Password:          benConfig.MongoDBPassword,
+			ReadFromSecondary: benConfig.MongoDBReadFromSecondary,
+			Namespace:         txn.Namespace(benConfig.KeyNamespace),
 		})
 		mongoConn2 := mongoCo.NewMongoConnection(&mongoCo.ConnectionOptions{
-			Address:        benConfig.MongoDBAddr2,
-			DBName:         "oreo",
-			CollectionName: "benchmark",
-			Username:       benConfig.MongoDBUsername,
-			Password:       benConfig.MongoDBPassword,
+			Address:           benConfig.MongoDBAddr2,
+			DBName:            "oreo",
+			CollectionName:    "benchmark",
+			Username:          benConfig.MongoDBUsername,
+			Password:          benConfig.MongoDBPassword,
+			ReadFromSecondary: benConfig.MongoDBReadFromSecondary,
+			Namespace:         txn.Namespace(benConfig.KeyNamespace),
 		})
 		mongoConn1.Connect()
 		mongoConn2.Connect()
 
 		// try to warm up the connection
-		var wg sync.WaitGroup
-		for i := 1; i <= 15; i++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				mongoConn1.Get("1")
-				mongoConn2.Get("1")
-			}()
+		if err := txn.WarmUp(mongoConn1, warmUpParallelism, 1); err != nil {
+			log.Printf("Error when warming up connection: %v\n", err)
+		}
+		if err := txn.WarmUp(mongoConn2, warmUpParallelism, 1); err != nil {
+			log.Printf("Error when warming up connection: %v\n", err)
 		}
-		wg.Wait()
 
 		connMap := map[string]txn.Connector{
 			"mongo1": mongoConn1,
@@ -433,31 +424,31 @@ func OreoCreator(pattern string, isRemote bool) (ycsb.DBCreator, error) {
 
 	if pattern == "rm" {
 		redisConn1 := redisCo.NewRedisConnection(&redisCo.ConnectionOptions{
-			Address:  benConfig.RedisAddr,
-			Password: benConfig.RedisPassword,
+			Address:       benConfig.RedisAddr,
+			Password:      benConfig.RedisPassword,
+			ReaderAddress: benConfig.RedisReaderAddr,
+			Namespace:     txn.Namespace(benConfig.KeyNamespace),
 		})
 
 		mongoConn1 := mongoCo.NewMongoConnection(&mongoCo.ConnectionOptions{
-			Address:        benConfig.MongoDBAddr1,
-			DBName:         "oreo",
-			CollectionName: "benchmark",
-			Username:       benConfig.MongoDBUsername,
-			Password:       benConfig.MongoDBPassword,
+			Address:           benConfig.MongoDBAddr1,
+			DBName:            "oreo",
+			CollectionName:    "benchmark",
+			Username:          benConfig.MongoDBUsername,
+			Password:          benConfig.MongoDBPassword,
+			ReadFromSecondary: benConfig.MongoDBReadFromSecondary,
+			Namespace:         txn.Namespace(benConfig.KeyNamespace),
 		})
 		redisConn1.Connect()
 		mongoConn1.Connect()
 
 		// try to warm up the connection
-		var wg sync.WaitGroup
-		for i := 1; i <= 15; i++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				redisConn1.Get("1")
-				mongoConn1.Get("1")
-			}()
+		if err := txn.WarmUp(redisConn1, warmUpParallelism, 1); err != nil {
+			log.Printf("Error when warming up connection: %v\n", err)
+		}
+		if err := txn.WarmUp(mongoConn1, warmUpParallelism, 1); err != nil {
+			log.Printf("Error when warming up connection: %v\n", err)
 		}
-		wg.Wait()
 
 		connMap := map[string]txn.Connector{
 			"redis1": redisConn1,
@@ -475,21 +466,17 @@ func OreoCreator(pattern string, isRemote bool) (ycsb.DBCreator, error) {
 
 func NewRedisConn() *redisCo.RedisConnection {
 	redisConn := redisCo.NewRedisConnection(&redisCo.ConnectionOptions{
-		Address:  benConfig.RedisAddr,
-		Password: benConfig.RedisPassword,
-		PoolSize: 100,
+		Address:       benConfig.RedisAddr,
+		Password:      benConfig.RedisPassword,
+		ReaderAddress: benConfig.RedisReaderAddr,
+		PoolSize:      100,
+		Namespace:     txn.Namespace(benConfig.KeyNamespace),
 	})
 	redisConn.Connect()
 	// try to warm up the connection
-	var wg sync.WaitGroup
-	for i := 1; i <= 30; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			redisConn.Get("1")
-		}()
+	if err := txn.WarmUp(redisConn, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
 	}
-	wg.Wait()
 
 	return redisConn
 }
@@ -502,21 +489,16 @@ func NewRedisConn() *redisCo.RedisConnection {
 //	*redisCo.RedisConnection: A pointer to the initialized Redis connection.
 func NewKVRocksConn() *redisCo.RedisConnection {
 	kvConn := redisCo.NewRedisConnection(&redisCo.ConnectionOptions{
-		Address:  benConfig.KVRocksAddr,
-		Password: benConfig.KVRocksPassword,
-		PoolSize: 100,
+		Address:   benConfig.KVRocksAddr,
+		Password:  benConfig.KVRocksPassword,
+		PoolSize:  100,
+		Namespace: txn.Namespace(benConfig.KeyNamespace),
 	})
 	kvConn.Connect()
 	// try to warm up the connection
-	var wg sync.WaitGroup
-	for i := 1; i <= 30; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			kvConn.Get("1")
-		}()
+	if err := txn.WarmUp(kvConn, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
 	}
-	wg.Wait()
 
 	return kvConn
 }
@@ -540,31 +522,28 @@ func NewMongoDBConn(id int) *mongoCo.MongoConnection {
 	}
 
 	mongoConn := mongoCo.NewMongoConnection(&mongoCo.ConnectionOptions{
-		Address:        mongoDBAddr,
-		DBName:         "oreo",
-		CollectionName: "benchmark",
-		Username:       benConfig.MongoDBUsername,
-		Password:       benConfig.MongoDBPassword,
+		Address:           mongoDBAddr,
+		DBName:            "oreo",
+		CollectionName:    "benchmark",
+		Username:          benConfig.MongoDBUsername,
+		Password:          benConfig.MongoDBPassword,
+		ReadFromSecondary: benConfig.MongoDBReadFromSecondary,
+		Namespace:         txn.Namespace(benConfig.KeyNamespace),
 	})
 	mongoConn.Connect()
 	// try to warm up the connection
-	var wg sync.WaitGroup
-	for i := 1; i <= 30; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			mongoConn.Get("1")
-		}()
+	if err := txn.WarmUp(mongoConn, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
 	}
-	wg.Wait()
 
 	return mongoConn
 }
 
 func NewCouchDBConn() *couchdb.CouchDBConnection {
 	couchConn := couchdb.NewCouchDBConnection(&couchdb.ConnectionOptions{
-		Address: benConfig.CouchDBAddr,
-		DBName:  "oreo",
+		Address:   benConfig.CouchDBAddr,
+		DBName:    "oreo",
+		Namespace: txn.Namespace(benConfig.KeyNamespace),
 		// Username: CouchUsername,
 		// Password: CouchPassword,
 	})
@@ -574,23 +553,18 @@ func NewCouchDBConn() *couchdb.CouchDBConnection {
 	}
 
 	// try to warm up the connection
-	var wg sync.WaitGroup
-	for i := 1; i <= 30; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			couchConn.Get("1")
-		}()
+	if err := txn.WarmUp(couchConn, warmUpParallelism, 1); err != nil {
+		log.Printf("Error when warming up connection: %v\n", err)
 	}
-	wg.Wait()
 
 	return couchConn
 }
 
 func NewCassandraConn() *cassandra.CassandraConnection {
 	conn := cassandra.NewCassandraConnection(&cassandra.ConnectionOptions{
-		Hosts:    benConfig.CassandraAddr,
-		Keyspace: "oreo",
+		Hosts:     benConfig.CassandraAddr,
+		Keyspace:  "oreo",
+		Namespace: txn.Namespace(benConfig.KeyNamespace),
 	})
 	err := conn.Connect()
 	if err != nil {
@@ -603,6 +577,7 @@ func NewDynamoDBConn() *dynamodb.DynamoDBConnection {
 	conn := dynamodb.NewDynamoDBConnection(&dynamodb.ConnectionOptions{
 		Endpoint:  "http://localhost:8000",
 		TableName: "oreo",
+		Namespace: txn.Namespace(benConfig.KeyNamespace),
 	})
 	err := conn.Connect()
 	if err != nil {
@@ -613,7 +588,8 @@ func NewDynamoDBConn() *dynamodb.DynamoDBConnection {
 
 func NewTiKVConn() *tikv.TiKVConnection {
 	conn := tikv.NewTiKVConnection(&tikv.ConnectionOptions{
-		PDAddrs: benConfig.TiKVAddr,
+		PDAddrs:   benConfig.TiKVAddr,
+		Namespace: txn.Namespace(benConfig.KeyNamespace),
 	})
 	err := conn.Connect()
 	if err != nil {
@@ -8,16 +8,19 @@ import (
 	"benchmark/ycsb"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime/pprof"
 	"runtime/trace"
+	"strings"
 	"time"
 
 	"github.com/cristalhq/aconfig"
 	"github.com/cristalhq/aconfig/aconfigyaml"
 	cfg "github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/oreo-dtx-lab/oreo/pkg/network"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
 )
 
 var (
@@ -37,8 +40,23 @@ var isRemote = false
 var preset = ""
 var readStrategy = ""
 var ablationLevel = 4
+var datastoreWeights = ""
+var warmUpParallelism = 30
+var seed int64 = 0
+var loadBatchSize = 0
+var manifestPath = "load_manifest.json"
+var opLogPath = ""
+var replayTimed = false
 
 func main() {
+	// exit only after the deferred profiles and sink are flushed
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	parseAndValidateFlag()
 
 	if pprofFlag {
@@ -71,7 +89,7 @@ func main() {
 	case "cg":
 		fmt.Printf("Running under Cherry Garcia Mode\n")
 		cfg.Config.ReadStrategy = cfg.Pessimistic
-		cfg.Debug.CherryGarciaMode = true
+		cfg.Config.Protocol = cfg.CherryGarcia
 		cfg.Debug.DebugMode = true
 		cfg.Debug.ConnAdditionalLatency = benConfig.Latency
 		cfg.Config.ConcurrentOptimizationLevel = 0
@@ -101,17 +119,36 @@ func main() {
 		cfg.Config.ReadStrategy = cfg.AssumeCommit
 	case "aa":
 		cfg.Config.ReadStrategy = cfg.AssumeAbort
+	case "w":
+		cfg.Config.ReadStrategy = cfg.WaitThenResolve
 	}
 
 	cfg.Config.AblationLevel = ablationLevel
 
 	measurement.InitMeasure()
 	measurement.EnableWarmUp(true)
+	sink, err := measurement.NewSink(benConfig.MetricsSink, benConfig.StatsdAddr)
+	if err != nil {
+		log.Fatalf("Error when creating the measurement sink: %v\n", err)
+	}
+	measurement.SetSink(sink)
+	interval := time.Duration(max(benConfig.MetricsInterval, 1)) * time.Second
+	stopSink := measurement.FlushSinkEvery(interval)
+	defer stopSink()
 
 	wp.ThreadCount = threadNum
-	// setupDistribution(wp, dbType)
+	if dbType == "oreo-ycsb" {
+		setupDatastoreDistribution(wp)
+	}
 	wl := createWorkload(wp)
 	client := generateClient(&wl, wp, dbType)
+	client.SetManifestPath(manifestPath)
+	if wp.Seed != 0 {
+		client.SetWorkloadFactory(func(wp *workload.WorkloadParameter) workload.Workload {
+			wl, _ := newWorkload(wp)
+			return wl
+		})
+	}
 
 	// if isRemote {
 	// 	warmUpHttpClient()
@@ -138,19 +175,89 @@ func main() {
 		fmt.Println("Load finished")
 	case "run":
 		wp.DoBenchmark = true
+		if err := client.CheckManifest(); err != nil {
+			log.Fatalf("The loaded data does not match the workload: %v\n", err)
+		}
 		fmt.Println("Start to run benchmark")
-		measurement.EnableWarmUp(false)
-		client.RunBenchmark()
+		if benConfig.AutoWarmUp.Enabled() {
+			measurement.EnableAutoWarmUp(benConfig.AutoWarmUp)
+		} else {
+			measurement.EnableWarmUp(false)
+		}
+		if opLogPath != "" {
+			f, err := os.Create(opLogPath)
+			if err != nil {
+				log.Fatalf("Error when creating the operation log: %v\n", err)
+			}
+			defer f.Close()
+			client.SetOpRecorder(newOpRecorder(f))
+		}
+		report := client.RunBenchmark()
+		if benConfig.AutoWarmUp.Enabled() {
+			fmt.Printf("The warm-up discarded %d %s operations\n",
+				measurement.WarmUpOps(), benConfig.AutoWarmUp.ObservedOp())
+		}
+		if report != nil && !report.Passed() {
+			fmt.Println("Data consistency check failed")
+			exitCode = 1
+		}
+	case "replay":
+		wp.DoBenchmark = true
+		if opLogPath == "" {
+			log.Fatalf("The replay mode needs an operation log (-oplog)\n")
+		}
+		ops, err := readOpLog(opLogPath)
+		if err != nil {
+			log.Fatalf("Error when reading the operation log: %v\n", err)
+		}
+		fmt.Printf("Start to replay %d operations\n", len(ops))
+		report, err := client.RunReplay(ops, replayTimed)
+		if err != nil {
+			log.Fatalf("Error when replaying the operation log: %v\n", err)
+		}
+		if report != nil && !report.Passed() {
+			fmt.Println("Data consistency check failed")
+			exitCode = 1
+		}
 	default:
 		panic("Invalid mode")
 	}
 
+	if !drainAsync() {
+		fmt.Println("The async operations are left unfinished")
+	}
+
 	if mode == "load" {
 		time.Sleep(2 * time.Second)
 	}
 
 }
 
+// asyncDrainTimeout is how long the benchmark waits once it has finished
+// for the operations its transactions left running in the background.
+const asyncDrainTimeout = 10 * time.Second
+
+// drainAsync prints the operations left running in the background
+// and waits for them, reporting whether they have finished.
+func drainAsync() bool {
+	return client.DrainAsync(os.Stdout, asyncDrainTimeout)
+}
+
+// newOpRecorder and readOpLog reach the client package,
+// which the client of the benchmark shadows in main.
+func newOpRecorder(w io.Writer) *client.OpRecorder {
+	return client.NewOpRecorder(w)
+}
+
+func readOpLog(path string) ([]client.Op, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return client.ReadOpLog(f)
+}
+
 // func warmUpHttpClient() {
 // 	for _, addr := range config.RemoteAddressList {
 // 		url := fmt.Sprintf("http://%s/ping", addr)
@@ -198,39 +305,37 @@ func main() {
 // }
 
 func createWorkload(wp *workload.WorkloadParameter) workload.Workload {
+	wl, desc := newWorkload(wp)
+	fmt.Println(desc)
+	return wl
+}
+
+// newWorkload creates the workload selected by the flags and describes it.
+func newWorkload(wp *workload.WorkloadParameter) (workload.Workload, string) {
 	if dbType == "oreo-ycsb" {
-		fmt.Println("This is a Oreo YCSB benchmark")
-		return workload.NewOreoYCSBWorkload(wp)
+		return workload.NewOreoYCSBWorkload(wp), "This is a Oreo YCSB benchmark"
 	}
 
 	if workloadType != "" {
 		switch workloadType {
 		case "ycsb":
-			fmt.Println("This is a YCSB benchmark")
 			wp.WorkloadName = "ycsb"
-			return workload.NewYCSBWorkload(wp)
+			return workload.NewYCSBWorkload(wp), "This is a YCSB benchmark"
 		case "multi-ycsb":
-			fmt.Println("This is a multi-ycsb benchmark")
 			wp.WorkloadName = "multi-ycsb"
-			return workload.NewMultiYCSBWorkload(wp)
+			return workload.NewMultiYCSBWorkload(wp), "This is a multi-ycsb benchmark"
 		case "dc":
-			fmt.Println("This is a data consistency test")
-			return workload.NewDataConsistencyWorkload(wp)
+			return workload.NewDataConsistencyWorkload(wp), "This is a data consistency test"
 		case "tp":
-			fmt.Println("This is a transaction performance test")
-			return workload.NewTxnPerformanceWorkload(wp)
+			return workload.NewTxnPerformanceWorkload(wp), "This is a transaction performance test"
 		case "ad":
-			fmt.Println("This is a across datastore test")
-			return workload.NewAcrossDatastoreWorkload(wp)
+			return workload.NewAcrossDatastoreWorkload(wp), "This is a across datastore test"
 		case "iot":
-			fmt.Println("This is a IoT workload")
-			return workload.NewIotWorkload(wp)
+			return workload.NewIotWorkload(wp), "This is a IoT workload"
 		case "social":
-			fmt.Println("This is a social network workload")
-			return workload.NewSocialWorkload(wp)
+			return workload.NewSocialWorkload(wp), "This is a social network workload"
 		case "order":
-			fmt.Println("This is a order workload")
-			return workload.NewOrderWorkload(wp)
+			return workload.NewOrderWorkload(wp), "This is a order workload"
 		default:
 			panic("Invalid workload type")
 		}
@@ -239,10 +344,31 @@ func createWorkload(wp *workload.WorkloadParameter) workload.Workload {
 	}
 }
 
+// setupDatastoreDistribution applies the -dw weights to the datastores
+// listed in -wl. If neither -dw nor the workload configuration specifies
+// any datastore proportion, the datastores are chosen uniformly.
+func setupDatastoreDistribution(wp *workload.WorkloadParameter) {
+	if datastoreWeights == "" && wp.HasDatastoreProportion() {
+		return
+	}
+	dbList := strings.Split(workloadType, ",")
+	weights, err := workload.ParseDatastoreWeights(dbList, datastoreWeights)
+	if err != nil {
+		log.Fatalf("Invalid datastore weights: %v\n", err)
+	}
+	if err := wp.SetDatastoreProportions(weights); err != nil {
+		log.Fatalf("Invalid datastore weights: %v\n", err)
+	}
+}
+
 func generateClient(wl *workload.Workload, wp *workload.WorkloadParameter, dbName string) *client.Client {
 	if dbType == "" {
 		panic("DBType should be specified")
 	}
+	// the native clients store the keys as they are
+	if benConfig.KeyNamespace != "" && !strings.HasPrefix(dbName, "oreo") {
+		panic("key_namespace is not supported by " + dbName)
+	}
 
 	var c *client.Client
 	switch dbName {
@@ -390,7 +516,7 @@ func generateClient(wl *workload.Workload, wp *workload.WorkloadParameter, dbNam
 func parseAndValidateFlag() {
 
 	flag.StringVar(&dbType, "d", "", "DB type")
-	flag.StringVar(&mode, "m", "load", "Mode: load or run")
+	flag.StringVar(&mode, "m", "load", "Mode: load, run or replay")
 	flag.StringVar(&workloadType, "wl", "", "Workload type")
 	flag.StringVar(&workloadConfigPath, "wc", "", "Workload configuration path")
 	flag.StringVar(&benConfigPath, "bc", "", "Benchmark configuration path")
@@ -401,6 +527,13 @@ func parseAndValidateFlag() {
 	flag.StringVar(&preset, "ps", "", "Preset configuration for evaluation")
 	flag.StringVar(&readStrategy, "read", "p", "Read Strategy")
 	flag.IntVar(&ablationLevel, "ab", 4, "Ablation level")
+	flag.IntVar(&warmUpParallelism, "wu", 30, "Number of concurrent reads used to warm up each connection")
+	flag.StringVar(&datastoreWeights, "dw", "", "Datastore weights aligned with the datastores in -wl, e.g. 70,30 (uniform if empty)")
+	flag.Int64Var(&seed, "seed", 0, "Positive seed making the operation mix reproducible, use the same one for load and run (random if 0)")
+	flag.IntVar(&loadBatchSize, "lb", 0, "Number of records loaded per batch, overriding max_load_batch_size (the configured one if 0)")
+	flag.StringVar(&manifestPath, "manifest", "load_manifest.json", "Manifest of the key space written by load and checked by run (none if empty)")
+	flag.StringVar(&opLogPath, "oplog", "", "Operation log written by run and re-executed by replay (none if empty)")
+	flag.BoolVar(&replayTimed, "timed", false, "Replay the operations with their recorded timing instead of back to back")
 	flag.Parse()
 
 	if *help {
@@ -415,6 +548,10 @@ func parseAndValidateFlag() {
 	if threadNum <= 0 {
 		panic("ThreadNum should be a positive integer")
 	}
+
+	if loadBatchSize < 0 {
+		panic("Load batch size should not be negative")
+	}
 }
 
 func displayBenchmarkInfo() {
@@ -425,6 +562,15 @@ func displayBenchmarkInfo() {
 	fmt.Printf("ThreadNum: %d\n", threadNum)
 	fmt.Printf("Remote Mode: %v\n", isRemote)
 	fmt.Printf("Read Strategy: %v\n", readStrategy)
+	if datastoreWeights != "" {
+		fmt.Printf("Datastore Weights: %v\n", datastoreWeights)
+	}
+	if seed != 0 {
+		fmt.Printf("Seed: %d\n", seed)
+	}
+	if loadBatchSize != 0 {
+		fmt.Printf("Load Batch Size: %d\n", loadBatchSize)
+	}
 	fmt.Printf("ConcurrentOptimizationLevel: %d\nAsyncLevel: %d\nMaxOutstandingRequest: %d\nMaxRecordLength: %d\n",
 		cfg.Config.ConcurrentOptimizationLevel, cfg.Config.AsyncLevel,
 		cfg.Config.MaxOutstandingRequest, cfg.Config.MaxRecordLength)
@@ -459,6 +605,36 @@ func loadConfig() *workload.WorkloadParameter {
 	benconfig.ZipfianConstant = benConfig.ZipfianConstant
 	benconfig.MaxLoadBatchSize = benConfig.MaxLoadBatchSize
 
+	codec, err := network.NewCodec(benConfig.Codec, benConfig.CodecUseNumber)
+	if err != nil {
+		log.Fatalf("Error when loading benchmark configuration: %v\n", err)
+	}
+	cfg.Config.Codec = codec
+
+	tsrEncoding, err := txn.TSREncodingFromName(benConfig.TSREncoding)
+	if err != nil {
+		log.Fatalf("Error when loading benchmark configuration: %v\n", err)
+	}
+	cfg.Config.TSREncoding = tsrEncoding
+
+	compression, err := txn.CompressionFromName(benConfig.ValueCompression)
+	if err != nil {
+		log.Fatalf("Error when loading benchmark configuration: %v\n", err)
+	}
+	cfg.Config.ValueCompression = compression
+	if benConfig.ValueCompressionThreshold > 0 {
+		cfg.Config.ValueCompressionThreshold = benConfig.ValueCompressionThreshold
+	}
+	cfg.Config.PrepareTimeout = benConfig.PrepareTimeout
+	cfg.Config.CommitWait = benConfig.CommitWait
+	cfg.Config.ClockUncertainty = benConfig.ClockUncertainty
+	cfg.Config.MaxWriteSetSize = benConfig.MaxWriteSetSize
+	// a record read from a lagging replica is only safe to commit on
+	// once it is validated against the primary
+	if benConfig.RedisReaderAddr != "" || benConfig.MongoDBReadFromSecondary {
+		cfg.Config.ReadSetValidation = true
+	}
+
 	wp := &workload.WorkloadParameter{}
 	wpLoader := aconfig.LoaderFor(wp, aconfig.Config{
 		SkipDefaults: true,
@@ -474,8 +650,33 @@ func loadConfig() *workload.WorkloadParameter {
 		log.Fatalf("Error when loading workload configuration: %v\n", err)
 		return nil
 	}
+	if err := wp.KeyDistribution.Validate(); err != nil {
+		log.Fatalf("Error when loading workload configuration: %v\n", err)
+	}
+	if err := wp.ValidateLoadMode(); err != nil {
+		log.Fatalf("Error when loading workload configuration: %v\n", err)
+	}
+	if seed != 0 {
+		wp.Seed = seed
+	}
+	if wp.Seed < 0 {
+		log.Fatalf("Error when loading workload configuration: seed should not be negative\n")
+	}
+	if loadBatchSize > 0 {
+		wp.MaxLoadBatchSize = loadBatchSize
+	}
 	benconfig.MaxLoadBatchSize = wp.MaxLoadBatchSize
-	benconfig.Client = network.NewClient(benconfig.ExecutorAddressMap)
+	benconfig.Client = network.NewClientWithOptions(benconfig.ExecutorAddressMap, network.ClientOptions{
+		MaxRequestBodySize:  benConfig.MaxBodySize,
+		MaxResponseBodySize: benConfig.MaxBodySize,
+		SeedAddr:            benConfig.ExecutorSeedAddr,
+		PeerRefreshInterval: benConfig.PeerRefreshInterval,
+		MaxIdleConnDuration: benConfig.MaxIdleConnDuration,
+		MaxConnDuration:     benConfig.MaxConnDuration,
+		DisableKeepAlive:    benConfig.DisableKeepAlive,
+		Regions:             benConfig.ExecutorRegions,
+		LocalRegion:         benConfig.LocalRegion,
+	})
 
 	return wp
 }
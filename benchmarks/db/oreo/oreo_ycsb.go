@@ -4,6 +4,7 @@ import (
 	"benchmark/pkg/benconfig"
 	"benchmark/ycsb"
 	"context"
+	"fmt"
 
 	"github.com/oreo-dtx-lab/oreo/pkg/datastore/cassandra"
 	"github.com/oreo-dtx-lab/oreo/pkg/datastore/couchdb"
@@ -31,6 +32,8 @@ var _ ycsb.DB = (*OreoYCSBDatastore)(nil)
 
 var _ ycsb.TransactionDB = (*OreoYCSBDatastore)(nil)
 
+var _ ycsb.BulkLoadDB = (*OreoYCSBDatastore)(nil)
+
 type OreoYCSBDatastore struct {
 	connMap             map[string]txn.Connector
 	globalDatastoreName string
@@ -174,6 +177,26 @@ func (r *OreoYCSBDatastore) Delete(ctx context.Context, table string, key string
 	return r.txn.Delete(table, key)
 }
 
+// BulkLoad writes the records to the datastore table as committed records
+// through its connector, without starting a transaction.
+func (r *OreoYCSBDatastore) BulkLoad(ctx context.Context, table string, keys []string, values []string) error {
+	conn, ok := r.connMap[table]
+	if !ok {
+		return fmt.Errorf("connector to %s is not found", table)
+	}
+	factory := txn.ItemFactoryFor(table)
+	if factory == nil {
+		return fmt.Errorf("item factory of %s is not found", table)
+	}
+	prefixedKeys := make([]string, 0, len(keys))
+	anyValues := make([]any, 0, len(values))
+	for i, key := range keys {
+		prefixedKeys = append(prefixedKeys, r.addPrefix(key))
+		anyValues = append(anyValues, values[i])
+	}
+	return txn.BulkLoad(conn, factory, prefixedKeys, anyValues)
+}
+
 func (r *OreoYCSBDatastore) addPrefix(key string) string {
 	prefix := ""
 	switch r.mode {
@@ -1,6 +1,7 @@
 package benconfig
 
 import (
+	"benchmark/pkg/measurement"
 	"time"
 
 	"github.com/oreo-dtx-lab/oreo/pkg/network"
@@ -22,14 +23,139 @@ type BenchmarkConfig struct {
 	Latency            time.Duration       `yaml:"latency"`
 	LatencyValue       int                 `yaml:"latency_value"`
 	MaxLoadBatchSize   int                 `yaml:"max_load_batch_size"`
+	MaxBodySize        int                 `yaml:"max_body_size"`
+	Codec              string              `yaml:"codec"`
+	CodecUseNumber     bool                `yaml:"codec_use_number"`
+	MaxInFlight        int                 `yaml:"max_in_flight"`
+
+	// ExecutorSeedAddr is the executor the clients fetch the executor
+	// addresses from, refreshing them every PeerRefreshInterval, 10s if unset.
+	// ExecutorAddressMap is used as is if unset.
+	ExecutorSeedAddr    string        `yaml:"executor_seed_addr"`
+	PeerRefreshInterval time.Duration `yaml:"peer_refresh_interval"`
+
+	// AdvertiseAddr is the address the clients reach an executor at.
+	// An executor with both AdvertiseAddr and ExecutorSeedAddr set
+	// registers with the seed every HeartbeatInterval, 5s if unset, and
+	// is dropped from /peers after three heartbeats without registering.
+	AdvertiseAddr     string        `yaml:"advertise_addr"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// MaxIdleConnDuration is how long a connection to an executor may stay
+	// idle before the client closes it, 5s if unset. MaxConnDuration is how
+	// long it is kept alive at most, no limit if unset. DisableKeepAlive
+	// closes it after every request.
+	MaxIdleConnDuration time.Duration `yaml:"max_idle_conn_duration"`
+	MaxConnDuration     time.Duration `yaml:"max_conn_duration"`
+	DisableKeepAlive    bool          `yaml:"disable_keep_alive"`
+
+	// ExecutorRegions maps the executor addresses to their regions.
+	// The clients send their requests to the executors of LocalRegion,
+	// and only to the others once all of them are marked down.
+	ExecutorRegions map[string]string `yaml:"executor_regions"`
+	LocalRegion     string            `yaml:"local_region"`
+
+	// TSREncoding is how the transaction state records are written,
+	// json if unset or compact.
+	TSREncoding string `yaml:"tsr_encoding"`
+
+	// ValueCompression is how the connectors compress the stored values,
+	// none if unset, gzip or snappy. Only the values of at least
+	// ValueCompressionThreshold bytes, 1024 if unset, are compressed.
+	ValueCompression          string `yaml:"value_compression"`
+	ValueCompressionThreshold int    `yaml:"value_compression_threshold"`
+
+	// PrepareTimeout is how long the prepare phase of a single datastore
+	// may take before the transaction is aborted, no limit if unset.
+	PrepareTimeout time.Duration `yaml:"prepare_timeout"`
+
+	// CommitWait makes the transactions wait out ClockUncertainty, the
+	// largest offset between the clocks of two clients, before their
+	// commit returns, so that the transactions started afterwards see
+	// their writes. Off if unset.
+	CommitWait       bool          `yaml:"commit_wait"`
+	ClockUncertainty time.Duration `yaml:"clock_uncertainty"`
+
+	// MaxTxnLifetime is how long after it started a transaction may
+	// prepare at the executors, which also cap the leases of the records
+	// they prepare at it. No limit if unset.
+	MaxTxnLifetime time.Duration `yaml:"max_txn_lifetime"`
+
+	// MaxWriteSetSize is the number of records a transaction may write
+	// before its writes fail, no limit if unset.
+	MaxWriteSetSize int `yaml:"max_write_set_size"`
+
+	// ReadRepairRate is the fraction of the cache hits on a TSR that the
+	// executor checks against the datastore, refreshing the stale entries.
+	// The cache is trusted as is if unset.
+	ReadRepairRate float64 `yaml:"read_repair_rate"`
+
+	// DisableCache turns the TSR cache of the executors off,
+	// which keeps their memory flat at the cost of more reads.
+	DisableCache bool `yaml:"disable_cache"`
+
+	// Chaos injects random latency and errors in the requests the executors
+	// handle, so that the clients can be tested under realistic faults.
+	// It is off unless chaos.enabled is set.
+	Chaos network.ChaosConfig `yaml:"chaos"`
+
+	// HotKeys tracks the keys the executors prepare and commit most often,
+	// reported by their /stats endpoint. It is off unless hot_keys.enabled is set.
+	HotKeys network.HotKeyConfig `yaml:"hot_keys"`
+
+	// AutoWarmUp makes the run phase discard the operations until the
+	// latency of the transactions, or of auto_warm_up.op, has stabilized,
+	// instead of measuring them all.
+	// It is off unless auto_warm_up.window is set.
+	AutoWarmUp measurement.AutoWarmUpConfig `yaml:"auto_warm_up"`
+
+	// SlowRequestThreshold is how long an executor request may take
+	// before it is logged as slow, 100ms if unset.
+	SlowRequestThreshold time.Duration `yaml:"slow_request_threshold"`
+
+	// ConnectAttempts is how many times the executor tries to connect
+	// to each datastore on startup, 10 if unset. The wait between two
+	// attempts starts at ConnectInterval, 1s if unset, and doubles each time.
+	ConnectAttempts int           `yaml:"connect_attempts"`
+	ConnectInterval time.Duration `yaml:"connect_interval"`
+	// EnsureSchema makes the executor create the tables, collections and
+	// keyspaces of the datastores that support it when they are missing,
+	// before connecting to them, instead of expecting them to be set up.
+	EnsureSchema bool `yaml:"ensure_schema"`
+
+	// MetricsSink is where the latencies are pushed while the benchmark runs,
+	// one of none, stdout and statsd.
+	MetricsSink string `yaml:"metrics_sink"`
+	StatsdAddr  string `yaml:"statsd_addr"`
+	// MetricsInterval is the number of seconds between two pushes, 1 if unset.
+	MetricsInterval int `yaml:"metrics_interval"`
+
+	// KeyNamespace prefixes every key the Oreo connectors store in the
+	// datastores, so that concurrent experiments on the same databases
+	// don't clobber each other's records. No prefix if unset. The native
+	// clients of the baselines do not support it.
+	KeyNamespace string `yaml:"key_namespace"`
 
 	RedisAddr     string `yaml:"redis_addr"`
 	RedisPassword string `yaml:"redis_password"`
+	// RedisMode is one of single, cluster and sentinel, single if unset.
+	// RedisAddrs are the seed nodes of the cluster or the sentinels.
+	RedisMode       string   `yaml:"redis_mode"`
+	RedisAddrs      []string `yaml:"redis_addrs"`
+	RedisMasterName string   `yaml:"redis_master_name"`
+	// RedisReaderAddr is a read replica of Redis serving the reads
+	// of the records, which are served by the primary if it is unset.
+	// Setting it turns on the read set validation of the transactions.
+	RedisReaderAddr string `yaml:"redis_reader_addr"`
 
 	MongoDBAddr1    string `yaml:"mongodb_addr1"`
 	MongoDBAddr2    string `yaml:"mongodb_addr2"`
 	MongoDBUsername string `yaml:"mongodb_username"`
 	MongoDBPassword string `yaml:"mongodb_password"`
+	// MongoDBReadFromSecondary serves the reads of the records
+	// from a secondary of the replica set when one is available.
+	// Setting it turns on the read set validation of the transactions.
+	MongoDBReadFromSecondary bool `yaml:"mongodb_read_from_secondary"`
 
 	KVRocksAddr     string `yaml:"kvrocks_addr"`
 	KVRocksPassword string `yaml:"kvrocks_password"`
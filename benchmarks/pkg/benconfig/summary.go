@@ -0,0 +1,94 @@
+package benconfig
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StartupSummary is what an executor prints on startup to confirm
+// that it is configured as intended.
+type StartupSummary struct {
+	ListenAddr                  string
+	PoolSize                    int
+	WorkloadType                string
+	DBCombination               []string
+	AsyncLevel                  int
+	ConcurrentOptimizationLevel int
+	TimeOracleUrl               string
+	// Datastores are the names of the datastores with their addresses, in order.
+	Datastores [][2]string
+}
+
+// DatastoreAddress returns the configured address of dsName,
+// or an empty string if dsName is unknown.
+func (c *BenchmarkConfig) DatastoreAddress(dsName string) string {
+	switch dsName {
+	case "Redis":
+		if c.RedisMode != "" && c.RedisMode != "single" {
+			return fmt.Sprintf("%s (%s)", strings.Join(c.RedisAddrs, ","), c.RedisMode)
+		}
+		return c.RedisAddr
+	case "MongoDB", "MongoDB1":
+		return c.MongoDBAddr1
+	case "MongoDB2":
+		return c.MongoDBAddr2
+	case "KVRocks":
+		return c.KVRocksAddr
+	case "CouchDB":
+		return c.CouchDBAddr
+	case "Cassandra":
+		return strings.Join(c.CassandraAddr, ",")
+	case "DynamoDB":
+		return c.DynamoDBAddr
+	case "TiKV":
+		return strings.Join(c.TiKVAddr, ",")
+	default:
+		return ""
+	}
+}
+
+// ResolveDatastores fills the datastores of summary from its workload type.
+func (c *BenchmarkConfig) ResolveDatastores(summary *StartupSummary) {
+	summary.TimeOracleUrl = c.TimeOracleUrl
+	summary.Datastores = nil
+	for _, dsName := range Datastores(summary.WorkloadType, summary.DBCombination) {
+		summary.Datastores = append(summary.Datastores, [2]string{dsName, c.DatastoreAddress(dsName)})
+	}
+}
+
+// Write prints the summary, one setting per line.
+func (s *StartupSummary) Write(w io.Writer) {
+	fmt.Fprintf(w, "Listen address : %s\n", s.ListenAddr)
+	fmt.Fprintf(w, "Pool size      : %d\n", s.PoolSize)
+	fmt.Fprintf(w, "Workload       : %s\n", s.WorkloadType)
+	if len(s.DBCombination) > 0 {
+		fmt.Fprintf(w, "DB combination : %s\n", strings.Join(s.DBCombination, ","))
+	}
+	fmt.Fprintf(w, "Async level    : %d\n", s.AsyncLevel)
+	fmt.Fprintf(w, "Concurrency    : %d\n", s.ConcurrentOptimizationLevel)
+	fmt.Fprintf(w, "Time oracle    : %s\n", s.TimeOracleUrl)
+	fmt.Fprintln(w, "Datastores     :")
+	for _, ds := range s.Datastores {
+		fmt.Fprintf(w, "  %-10s %s\n", ds[0], ds[1])
+	}
+}
+
+// ShutdownSummary is what a benchmark prints once its transactions have
+// returned, to tell whether the run has left work unfinished.
+type ShutdownSummary struct {
+	// PendingAsyncCommits and PendingTSRDeletes are the commit phases and
+	// the TSR deletions still running in the background.
+	PendingAsyncCommits int64
+	PendingTSRDeletes   int64
+	// PendingRecoveries are the transactions whose records are still
+	// being recommitted after their commit phase failed.
+	PendingRecoveries int64
+}
+
+// Write prints the summary, one statistic per line.
+func (s *ShutdownSummary) Write(w io.Writer) {
+	fmt.Fprintf(w, "Pending async commits : %d\n", s.PendingAsyncCommits)
+	fmt.Fprintf(w, "Pending TSR deletes   : %d\n", s.PendingTSRDeletes)
+	fmt.Fprintf(w, "Pending recoveries    : %d\n", s.PendingRecoveries)
+}
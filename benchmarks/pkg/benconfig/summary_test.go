@@ -0,0 +1,56 @@
+package benconfig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStartupSummary(t *testing.T) {
+	bc := BenchmarkConfig{
+		TimeOracleUrl: "http://localhost:8010",
+		RedisAddr:     "localhost:6379",
+		MongoDBAddr1:  "mongodb://localhost:27017",
+		CassandraAddr: []string{"cass1", "cass2"},
+	}
+
+	t.Run("workload datastores", func(t *testing.T) {
+		summary := StartupSummary{ListenAddr: ":8000", PoolSize: 60, WorkloadType: "social"}
+		bc.ResolveDatastores(&summary)
+		var buf bytes.Buffer
+		summary.Write(&buf)
+
+		out := buf.String()
+		for _, expected := range []string{
+			"Listen address : :8000",
+			"Pool size      : 60",
+			"MongoDB    mongodb://localhost:27017",
+			"Redis      localhost:6379",
+			"Cassandra  cass1,cass2",
+		} {
+			if !strings.Contains(out, expected) {
+				t.Errorf("expected the summary to contain %q, got:\n%s", expected, out)
+			}
+		}
+	})
+
+	t.Run("ycsb datastores", func(t *testing.T) {
+		summary := StartupSummary{WorkloadType: "ycsb", DBCombination: []string{"Redis", "TiKV"}}
+		bc.ResolveDatastores(&summary)
+		var buf bytes.Buffer
+		summary.Write(&buf)
+
+		out := buf.String()
+		if !strings.Contains(out, "DB combination : Redis,TiKV") {
+			t.Errorf("expected the summary to contain the db combination, got:\n%s", out)
+		}
+		for _, dsName := range []string{"Redis", "TiKV"} {
+			if !strings.Contains(out, "  "+dsName+" ") {
+				t.Errorf("expected the summary to contain %s, got:\n%s", dsName, out)
+			}
+		}
+		if strings.Contains(out, "MongoDB") {
+			t.Errorf("expected only the ycsb datastores, got:\n%s", out)
+		}
+	})
+}
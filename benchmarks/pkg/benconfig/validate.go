@@ -0,0 +1,89 @@
+package benconfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// workloadDatastores are the datastores the executor connects to for each workload.
+// The ycsb workload connects to the datastores given by the -db flag instead.
+var workloadDatastores = map[string][]string{
+	"iot":    {"MongoDB", "Redis"},
+	"social": {"MongoDB", "Redis", "Cassandra"},
+	"order":  {"MongoDB", "KVRocks", "Redis", "Cassandra"},
+}
+
+// Datastores returns the datastores the executor connects to for workloadType.
+func Datastores(workloadType string, dbCombination []string) []string {
+	if workloadType == "ycsb" {
+		return dbCombination
+	}
+	return workloadDatastores[workloadType]
+}
+
+// Validate checks that every field needed to connect to the datastores
+// of workloadType is set, so that a typo in the configuration is reported
+// before any connection is attempted. dbCombination lists the datastores
+// of the ycsb workload. All the missing fields are reported in one error.
+func (c *BenchmarkConfig) Validate(workloadType string, dbCombination []string) error {
+	var errs []error
+	require := func(set bool, field string, dsName string) {
+		if set {
+			return
+		}
+		if dsName == "" {
+			errs = append(errs, fmt.Errorf("%s is required", field))
+			return
+		}
+		errs = append(errs, fmt.Errorf("%s is required by %s", field, dsName))
+	}
+
+	require(c.TimeOracleUrl != "", "time_oracle_url", "")
+	if c.ReadRepairRate < 0 || c.ReadRepairRate > 1 {
+		errs = append(errs, fmt.Errorf("read_repair_rate %v is out of [0, 1]", c.ReadRepairRate))
+	}
+	if err := c.Chaos.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("chaos: %w", err))
+	}
+	if err := c.HotKeys.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("hot_keys: %w", err))
+	}
+	if err := c.AutoWarmUp.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("auto_warm_up: %w", err))
+	}
+
+	for _, dsName := range Datastores(workloadType, dbCombination) {
+		switch dsName {
+		case "":
+		case "Redis":
+			switch c.RedisMode {
+			case "", "single":
+				require(c.RedisAddr != "", "redis_addr", dsName)
+			case "sentinel":
+				require(c.RedisMasterName != "", "redis_master_name", dsName)
+				fallthrough
+			case "cluster":
+				require(len(c.RedisAddrs) > 0 || c.RedisAddr != "", "redis_addrs", dsName)
+			default:
+				errs = append(errs, fmt.Errorf("unsupported redis_mode %q, expect single, cluster or sentinel", c.RedisMode))
+			}
+		case "MongoDB", "MongoDB1":
+			require(c.MongoDBAddr1 != "", "mongodb_addr1", dsName)
+		case "MongoDB2":
+			require(c.MongoDBAddr2 != "", "mongodb_addr2", dsName)
+		case "KVRocks":
+			require(c.KVRocksAddr != "", "kvrocks_addr", dsName)
+		case "CouchDB":
+			require(c.CouchDBAddr != "", "couchdb_addr", dsName)
+		case "Cassandra":
+			require(len(c.CassandraAddr) > 0, "cassandra_addr", dsName)
+		case "DynamoDB":
+			require(c.DynamoDBAddr != "", "dynamodb_addr", dsName)
+		case "TiKV":
+			require(len(c.TiKVAddr) > 0, "tikv_addr", dsName)
+		default:
+			errs = append(errs, fmt.Errorf("unsupported datastore %q", dsName))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,141 @@
+package benconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	full := BenchmarkConfig{
+		TimeOracleUrl: "http://localhost:8010",
+		RedisAddr:     "localhost:6379",
+		MongoDBAddr1:  "mongodb://localhost:27017",
+		MongoDBAddr2:  "mongodb://localhost:27018",
+		KVRocksAddr:   "localhost:6666",
+		CouchDBAddr:   "http://localhost:5984",
+		CassandraAddr: []string{"localhost"},
+		DynamoDBAddr:  "http://localhost:8000",
+		TiKVAddr:      []string{"localhost:2379"},
+	}
+
+	tests := []struct {
+		name          string
+		modify        func(c *BenchmarkConfig)
+		workloadType  string
+		dbCombination []string
+		// expected lists the lines of the error, none if valid
+		expected []string
+	}{
+		{
+			name:          "ycsb with every datastore",
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis", "MongoDB1", "MongoDB2", "KVRocks", "CouchDB", "Cassandra", "DynamoDB", "TiKV"},
+		},
+		{
+			name:          "ycsb ignores the unused datastores",
+			modify:        func(c *BenchmarkConfig) { *c = BenchmarkConfig{TimeOracleUrl: c.TimeOracleUrl, RedisAddr: c.RedisAddr} },
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis"},
+		},
+		{
+			name: "ycsb reports every missing field",
+			modify: func(c *BenchmarkConfig) {
+				c.TimeOracleUrl = ""
+				c.MongoDBAddr2 = ""
+				c.TiKVAddr = nil
+			},
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis", "MongoDB2", "TiKV"},
+			expected: []string{
+				"time_oracle_url is required",
+				"mongodb_addr2 is required by MongoDB2",
+				"tikv_addr is required by TiKV",
+			},
+		},
+		{
+			name:          "ycsb with an unknown datastore",
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis", "Mongo"},
+			expected:      []string{`unsupported datastore "Mongo"`},
+		},
+		{
+			name:         "iot",
+			modify:       func(c *BenchmarkConfig) { c.RedisAddr = "" },
+			workloadType: "iot",
+			expected:     []string{"redis_addr is required by Redis"},
+		},
+		{
+			name: "social",
+			modify: func(c *BenchmarkConfig) {
+				c.MongoDBAddr1 = ""
+				c.CassandraAddr = nil
+			},
+			workloadType: "social",
+			expected: []string{
+				"mongodb_addr1 is required by MongoDB",
+				"cassandra_addr is required by Cassandra",
+			},
+		},
+		{
+			name:         "order",
+			modify:       func(c *BenchmarkConfig) { c.KVRocksAddr = "" },
+			workloadType: "order",
+			expected:     []string{"kvrocks_addr is required by KVRocks"},
+		},
+		{
+			name: "redis sentinel",
+			modify: func(c *BenchmarkConfig) {
+				c.RedisMode = "sentinel"
+				c.RedisAddr = ""
+			},
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis"},
+			expected: []string{
+				"redis_master_name is required by Redis",
+				"redis_addrs is required by Redis",
+			},
+		},
+		{
+			name:          "read repair rate out of range",
+			modify:        func(c *BenchmarkConfig) { c.ReadRepairRate = 1.5 },
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis"},
+			expected:      []string{"read_repair_rate 1.5 is out of [0, 1]"},
+		},
+		{
+			name:          "chaos error rate out of range",
+			modify:        func(c *BenchmarkConfig) { c.Chaos.ErrorRate = 2 },
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis"},
+			expected:      []string{"chaos: error rate 2 is out of [0, 1]"},
+		},
+		{
+			name:          "redis cluster",
+			modify:        func(c *BenchmarkConfig) { c.RedisMode = "cluster"; c.RedisAddrs = []string{"localhost:7000"} },
+			workloadType:  "ycsb",
+			dbCombination: []string{"Redis"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := full
+			if tt.modify != nil {
+				tt.modify(&c)
+			}
+			err := c.Validate(tt.workloadType, tt.dbCombination)
+			if len(tt.expected) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected %v, got no error", tt.expected)
+			}
+			if got := strings.Split(err.Error(), "\n"); strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
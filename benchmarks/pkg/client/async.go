@@ -0,0 +1,31 @@
+package client
+
+import (
+	"benchmark/pkg/benconfig"
+	"io"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// PendingAsync returns the summary of the operations the transactions
+// of the benchmark have left running in the background.
+func PendingAsync() benconfig.ShutdownSummary {
+	pending := txn.PendingAsync()
+	return benconfig.ShutdownSummary{
+		PendingAsyncCommits: pending.Commits,
+		PendingTSRDeletes:   pending.TSRDeletes,
+		PendingRecoveries:   pending.Recoveries,
+	}
+}
+
+// DrainAsync prints the summary of the pending operations to w and waits
+// up to timeout for them to finish, reporting whether they have.
+func DrainAsync(w io.Writer, timeout time.Duration) bool {
+	summary := PendingAsync()
+	summary.Write(w)
+	if summary.PendingAsyncCommits+summary.PendingTSRDeletes+summary.PendingRecoveries == 0 {
+		return true
+	}
+	return txn.WaitAsync(timeout)
+}
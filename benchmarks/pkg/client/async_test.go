@@ -0,0 +1,191 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// slowCommitConnector keeps the records in memory and blocks their
+// commits until release is closed.
+type slowCommitConnector struct {
+	mu      sync.Mutex
+	items   map[string]txn.ItemOptions
+	kv      map[string]string
+	release chan struct{}
+}
+
+func newSlowCommitConnector() *slowCommitConnector {
+	return &slowCommitConnector{
+		items:   make(map[string]txn.ItemOptions),
+		kv:      make(map[string]string),
+		release: make(chan struct{}),
+	}
+}
+
+func (c *slowCommitConnector) Connect() error { return nil }
+
+func (c *slowCommitConnector) Close() error { return nil }
+
+func (c *slowCommitConnector) GetItem(key string) (txn.DataItem, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	opts, ok := c.items[key]
+	if !ok {
+		return redis.NewRedisItem(txn.ItemOptions{}), txn.KeyNotFound
+	}
+	return redis.NewRedisItem(opts), nil
+}
+
+func (c *slowCommitConnector) PutItem(key string, value txn.DataItem) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = optionsOf(value)
+	return "", nil
+}
+
+func (c *slowCommitConnector) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
+	if value.TxnState() == config.COMMITTED {
+		<-c.release
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	opts, ok := c.items[key]
+	if ok == doCreate || (ok && opts.Version != value.Version()) {
+		return "", txn.VersionMismatch
+	}
+	opts = optionsOf(value)
+	opts.Version = nextVersion(value.Version())
+	c.items[key] = opts
+	return opts.Version, nil
+}
+
+func (c *slowCommitConnector) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
+	<-c.release
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	opts, ok := c.items[key]
+	if !ok || opts.Version != version {
+		return "", txn.VersionMismatch
+	}
+	opts.TxnState, opts.TValid, opts.Version = config.COMMITTED, tCommit, nextVersion(version)
+	c.items[key] = opts
+	return opts.Version, nil
+}
+
+func (c *slowCommitConnector) Get(name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.kv[name]
+	if !ok {
+		return "", txn.KeyNotFound
+	}
+	return value, nil
+}
+
+func (c *slowCommitConnector) Put(name string, value any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.kv[name] = fmt.Sprint(value)
+	return nil
+}
+
+func (c *slowCommitConnector) Delete(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, name)
+	delete(c.kv, name)
+	return nil
+}
+
+func (c *slowCommitConnector) AtomicCreate(name string, value any) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.kv[name]; ok {
+		return old, txn.KeyExists
+	}
+	c.kv[name] = fmt.Sprint(value)
+	return "", nil
+}
+
+func nextVersion(version string) string {
+	v, _ := strconv.Atoi(version)
+	return strconv.Itoa(v + 1)
+}
+
+func optionsOf(item txn.DataItem) txn.ItemOptions {
+	return txn.ItemOptions{
+		Key:          item.Key(),
+		Value:        item.Value(),
+		GroupKeyList: item.GroupKeyList(),
+		TxnState:     item.TxnState(),
+		TValid:       item.TValid(),
+		TLease:       item.TLease(),
+		Prev:         item.Prev(),
+		LinkedLen:    item.LinkedLen(),
+		IsDeleted:    item.IsDeleted(),
+		Version:      item.Version(),
+	}
+}
+
+func TestDrainAsync(t *testing.T) {
+	asyncLevel, ablationLevel := config.Config.AsyncLevel, config.Config.AblationLevel
+	defer func() {
+		config.Config.AsyncLevel, config.Config.AblationLevel = asyncLevel, ablationLevel
+	}()
+	config.Config.AsyncLevel = config.AsyncLevelTwo
+	config.Config.AblationLevel = 3
+
+	conn := newSlowCommitConnector()
+	tx := txn.NewTransaction()
+	ds := txn.NewDatastore("Redis", conn, &redis.RedisItemFactory{})
+	if err := tx.AddDatastore(ds); err != nil {
+		t.Fatal(err)
+	}
+	tx.SetGlobalDatastore(ds)
+	if err := tx.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Write("Redis", "item1", "value1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// the commit phase goes on in the background
+	if !tx.Stats().AsyncCommit {
+		t.Fatal("the commit phase should run in the background")
+	}
+
+	var buf bytes.Buffer
+	if DrainAsync(&buf, 10*time.Millisecond) {
+		t.Error("the async commit should still be pending")
+	}
+	if !strings.Contains(buf.String(), "Pending async commits : 1") {
+		t.Errorf("unexpected summary:\n%s", buf.String())
+	}
+
+	close(conn.release)
+	if !DrainAsync(io.Discard, time.Second) {
+		t.Fatal("the async commit should have finished")
+	}
+	if pending := PendingAsync(); pending.PendingAsyncCommits != 0 {
+		t.Errorf("pending async commits = %d, want 0", pending.PendingAsyncCommits)
+	}
+	item, err := conn.GetItem("item1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.TxnState() != config.COMMITTED {
+		t.Errorf("item1 is %v, want COMMITTED", item.TxnState())
+	}
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"benchmark/pkg/measurement"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/backoff"
+	"github.com/oreo-dtx-lab/oreo/pkg/network"
+)
+
+const (
+	// backpressureRetries is how many times a benchmark thread retries
+	// an operation rejected by overloaded executors before it fails.
+	backpressureRetries = 8
+	// backpressureInitialWait and backpressureMaxWait bound the
+	// backoff of a thread between two retries.
+	backpressureInitialWait = 10 * time.Millisecond
+	backpressureMaxWait     = time.Second
+)
+
+// IsBackpressure reports whether err comes from executors rejecting
+// requests because they are overloaded, rather than from a failure of
+// the operation. The error is matched as text as well, since it may
+// reach the benchmark as a message only.
+func IsBackpressure(err error) bool {
+	return err != nil && (errors.Is(err, network.ErrOverloaded) ||
+		strings.Contains(err.Error(), network.ErrOverloaded.Error()))
+}
+
+// throttle backs a benchmark thread off while the executors push back,
+// so that the closed loop runs at the throughput they can sustain
+// instead of counting their rejections as failures.
+// A nil throttle never retries.
+type throttle struct {
+	b       backoff.Backoff
+	retries int
+}
+
+func newThrottle() *throttle {
+	return &throttle{
+		b:       backoff.WithCap(backoff.NewExponentialJitter(backpressureInitialWait), backpressureMaxWait),
+		retries: backpressureRetries,
+	}
+}
+
+// wait sleeps for the next backoff.
+func (t *throttle) wait() {
+	time.Sleep(t.b.Next())
+}
+
+// withBackpressure runs op, retrying it after a backoff while it fails
+// by backpressure. Every rejected attempt retried is measured as
+// <name>_BACKPRESSURE. Only the operations that are rejected before
+// they take effect may be retried, a commit may not.
+func withBackpressure[T any](t *throttle, name string, op func() (T, error)) (T, error) {
+	for i := 0; ; i++ {
+		start := time.Now()
+		val, err := op()
+		if t == nil {
+			return val, err
+		}
+		if !IsBackpressure(err) {
+			t.b.Reset()
+			return val, err
+		}
+		if i >= t.retries {
+			return val, err
+		}
+		measurement.Measure(fmt.Sprintf("%s_BACKPRESSURE", name), start, time.Since(start))
+		t.wait()
+	}
+}
+
+// runWithBackpressure is withBackpressure for the operations returning only an error.
+func runWithBackpressure(t *throttle, name string, op func() error) error {
+	_, err := withBackpressure(t, name, func() (struct{}, error) {
+		return struct{}{}, op()
+	})
+	return err
+}
+
+// pushedBack backs the thread off if err, returned by an operation that
+// can't be retried, is backpressure, so that the next one is delayed.
+func (t *throttle) pushedBack(err error) {
+	if t != nil && IsBackpressure(err) {
+		t.wait()
+	}
+}
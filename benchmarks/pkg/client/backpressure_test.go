@@ -0,0 +1,152 @@
+package client
+
+import (
+	"benchmark/pkg/measurement"
+	"benchmark/pkg/workload"
+	"benchmark/ycsb"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/backoff"
+	"github.com/oreo-dtx-lab/oreo/pkg/network"
+)
+
+// overloadedErr is the error of the network client
+// for a request an executor kept rejecting.
+var overloadedErr = fmt.Errorf("%w after 3 retries", network.ErrOverloaded)
+
+// pushbackDB rejects every rejectEvery-th read and update of a memDB
+// like an overloaded executor, and counts the transactions.
+type pushbackDB struct {
+	*memDB
+	rejectEvery int
+	calls       *int
+	rejected    *int
+	starts      *int
+	commits     *int
+}
+
+func (db *pushbackDB) pushBack() error {
+	*db.calls++
+	if *db.calls%db.rejectEvery == 0 {
+		*db.rejected++
+		return overloadedErr
+	}
+	return nil
+}
+
+func (db *pushbackDB) Read(ctx context.Context, table string, key string) (string, error) {
+	if err := db.pushBack(); err != nil {
+		return "", err
+	}
+	return db.memDB.Read(ctx, table, key)
+}
+
+func (db *pushbackDB) Update(ctx context.Context, table string, key string, value string) error {
+	if err := db.pushBack(); err != nil {
+		return err
+	}
+	return db.memDB.Update(ctx, table, key, value)
+}
+
+func (db *pushbackDB) Start() error {
+	*db.starts++
+	return db.memDB.Start()
+}
+
+func (db *pushbackDB) Commit() error {
+	err := db.memDB.Commit()
+	if err == nil {
+		*db.commits++
+	}
+	return err
+}
+
+func TestWorkerBacksOffUnderBackpressure(t *testing.T) {
+	measurement.InitMeasure()
+	ctx := context.Background()
+	wp := &workload.WorkloadParameter{
+		DBName:                "memory",
+		TableName:             "table",
+		RecordCount:           10,
+		OperationCount:        30,
+		ThreadCount:           1,
+		DoBenchmark:           true,
+		KeyDistribution:       workload.Uniform,
+		Seed:                  1,
+		InitialAmountPerKey:   100,
+		TransferAmountPerTxn:  7,
+		TotalAmount:           1000,
+		PostCheckWorkerThread: 1,
+	}
+	mem := newMemDB()
+	wl := workload.NewDataConsistencyWorkload(wp)
+	wl.Load(ctx, wp.RecordCount, mem)
+
+	db := &pushbackDB{memDB: mem, rejectEvery: 3,
+		calls: new(int), rejected: new(int), starts: new(int), commits: new(int)}
+	w := newWorker(wl, wp, 0, 1, map[string]ycsb.DB{"memory": db})
+	w.RunBenchmark(ctx, "memory")
+
+	if *db.rejected == 0 {
+		t.Fatalf("expected some requests to be pushed back")
+	}
+	// every transfer goes through once its pushed back requests are retried
+	if *db.starts == 0 || *db.commits != *db.starts {
+		t.Errorf("expected the %d transfers to commit, got %d commits", *db.starts, *db.commits)
+	}
+	total := 0
+	for _, value := range mem.records {
+		var balance int
+		fmt.Sscan(value, &balance)
+		total += balance
+	}
+	if total != wp.TotalAmount {
+		t.Errorf("expected the total amount to be %d, got %d", wp.TotalAmount, total)
+	}
+}
+
+func TestWithBackpressure(t *testing.T) {
+	th := &throttle{b: backoff.NewConstant(time.Millisecond), retries: 2}
+
+	calls := 0
+	val, err := withBackpressure(th, "READ", func() (string, error) {
+		calls++
+		if calls < 3 {
+			return "", overloadedErr
+		}
+		return "value", nil
+	})
+	if err != nil || val != "value" || calls != 3 {
+		t.Errorf("expected the read to succeed at the third call, got %q, %v after %d calls", val, err, calls)
+	}
+
+	calls = 0
+	err = runWithBackpressure(th, "UPDATE", func() error {
+		calls++
+		return overloadedErr
+	})
+	if !errors.Is(err, network.ErrOverloaded) || calls != 3 {
+		t.Errorf("expected the update to fail after 2 retries, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	failure := errors.New("key not found")
+	err = runWithBackpressure(th, "UPDATE", func() error {
+		calls++
+		return failure
+	})
+	if err != failure || calls != 1 {
+		t.Errorf("expected a failure not to be retried, got %v after %d calls", err, calls)
+	}
+
+	if !IsBackpressure(errors.New("prepare phase failed: executor is overloaded after 3 retries")) {
+		t.Errorf("expected the backpressure to be recognized from its message")
+	}
+	if IsBackpressure(nil) || IsBackpressure(failure) {
+		t.Errorf("expected only the backpressure to be recognized")
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"benchmark/pkg/workload"
 	"benchmark/ycsb"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"sync"
 	"time"
@@ -23,6 +25,16 @@ type Client struct {
 	table        string
 
 	wl workload.Workload
+	// newWorkload creates a workload for each thread of the run phase
+	// so that every thread draws from its own seeded random source.
+	newWorkload func(wp *workload.WorkloadParameter) workload.Workload
+
+	// manifestPath is where the load phase writes its manifest
+	// and the run phase reads it, none if empty.
+	manifestPath string
+
+	// opRecorder logs the operations of the run phase, if not nil.
+	opRecorder *OpRecorder
 }
 
 func NewClient(workload *workload.Workload, wp *workload.WorkloadParameter, dbCreatorMap map[string]ycsb.DBCreator) *Client {
@@ -36,9 +48,102 @@ func NewClient(workload *workload.Workload, wp *workload.WorkloadParameter, dbCr
 
 }
 
+// SetWorkloadFactory makes every thread of the run phase use its own
+// workload created by f, seeded with wp.Seed plus the thread id.
+func (c *Client) SetWorkloadFactory(f func(wp *workload.WorkloadParameter) workload.Workload) {
+	c.newWorkload = f
+}
+
+// SetManifestPath makes the load phase describe the key space it has
+// written in a manifest, stored in the databases next to the data and
+// copied to the file at path, which the run phase checks against.
+func (c *Client) SetManifestPath(path string) {
+	c.manifestPath = path
+}
+
+// SetOpRecorder makes the run phase record its operations with r
+// for a later RunReplay.
+func (c *Client) SetOpRecorder(r *OpRecorder) {
+	c.opRecorder = r
+}
+
+// manifest describes the key space of the databases of the client.
+func (c *Client) manifest() workload.Manifest {
+	dbNames := make([]string, 0, len(c.dbCreatorMap))
+	for dbName := range c.dbCreatorMap {
+		dbNames = append(dbNames, dbName)
+	}
+	return workload.NewManifest(c.wp, dbNames)
+}
+
+// loadFailures returns the number of operations of the load phase
+// that have failed so far, which leave keys missing.
+func loadFailures() int {
+	return errrecord.Count("INSERT") + errrecord.Count("COMMIT") + errrecord.Count("BULK_LOAD")
+}
+
+// CheckManifest returns an error if the manifests stored by the load phase
+// in the databases do not describe the key space the run phase accesses,
+// in which case the run would be skewed by reads of missing keys.
+// A database holding no manifest is an error if the file at the manifest
+// path shows that the data has been loaded with one, since the data has
+// been dropped since. It is only reported otherwise, for the data loaded
+// without a manifest.
+func (c *Client) CheckManifest() error {
+	if c.manifestPath == "" {
+		return nil
+	}
+	expected := c.manifest()
+	loaded, err := workload.ReadManifest(c.manifestPath)
+	hasFile := !errors.Is(err, fs.ErrNotExist)
+	if err != nil && hasFile {
+		return err
+	}
+	if hasFile {
+		if err := loaded.Check(expected); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	for _, dbName := range expected.DBNames {
+		db, err := c.dbCreatorMap[dbName].Create()
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %v", dbName, err)
+		}
+		for _, table := range expected.Tables() {
+			stored, err := workload.LoadManifest(ctx, db, table)
+			if err != nil && hasFile {
+				return fmt.Errorf("%s holds no load manifest in %s, the loaded data is missing: %v", dbName, table, err)
+			}
+			if err != nil {
+				fmt.Printf("No load manifest in %s of %s, skip checking the key space: %v\n", table, dbName, err)
+				continue
+			}
+			if err := stored.Check(expected); err != nil {
+				return fmt.Errorf("%s: %w", dbName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// threadWorkload returns the workload used by the thread in the run phase.
+// Workloads needing a post check keep a single instance since the check
+// relies on the state collected by every thread.
+func (c *Client) threadWorkload(threadID int) workload.Workload {
+	if c.newWorkload == nil || c.wp.Seed == 0 || c.wl.NeedPostCheck() {
+		return c.wl
+	}
+	wp := *c.wp
+	wp.Seed = c.wp.Seed + int64(threadID)
+	return c.newWorkload(&wp)
+}
+
 func (c *Client) RunLoad() {
 
 	ctx := context.Background()
+	failuresBefore := loadFailures()
 
 	for dbName, creator := range c.dbCreatorMap {
 		fmt.Printf("Loading data to %s\n", dbName)
@@ -60,6 +165,26 @@ func (c *Client) RunLoad() {
 		wg.Wait()
 	}
 
+	if c.manifestPath != "" {
+		manifest := c.manifest()
+		manifest.FailedOps = loadFailures() - failuresBefore
+		for dbName, creator := range c.dbCreatorMap {
+			db, err := creator.Create()
+			if err == nil {
+				err = workload.StoreManifest(ctx, db, manifest)
+			}
+			if err != nil {
+				// the run refuses a database holding no manifest
+				fmt.Printf("Failed to store the load manifest in %s: %v\n", dbName, err)
+			}
+		}
+		if err := workload.WriteManifest(c.manifestPath, manifest); err != nil {
+			fmt.Printf("Failed to write the load manifest: %v\n", err)
+		} else if manifest.FailedOps > 0 {
+			fmt.Printf("%d operations of the load phase have failed, the run will refuse the data\n", manifest.FailedOps)
+		}
+	}
+
 	// we need to load data to all the datastores
 	// if c.wp.DBName == "oreo" {
 	// 	var wg sync.WaitGroup
@@ -96,7 +221,10 @@ func (c *Client) RunLoad() {
 
 }
 
-func (c *Client) RunBenchmark() {
+// RunBenchmark runs the workload and its post-check if needed.
+// It returns the consistency report of the post-check,
+// or nil if the workload does not verify an invariant.
+func (c *Client) RunBenchmark() *workload.ConsistencyReport {
 	start := time.Now()
 	ctx := context.Background()
 
@@ -106,11 +234,21 @@ func (c *Client) RunBenchmark() {
 		go func(threadID int) {
 			defer wg.Done()
 			dbMap := c.genDBmap()
-			w := newWorker(c.wl, c.wp, threadID, c.wp.ThreadCount, dbMap)
+			if c.opRecorder != nil {
+				for dbName, db := range dbMap {
+					dbMap[dbName] = c.opRecorder.Wrap(db, dbName)
+				}
+			}
+			w := newWorker(c.threadWorkload(threadID), c.wp, threadID, c.wp.ThreadCount, dbMap)
 			w.RunBenchmark(ctx, c.wp.DBName)
 		}(i)
 	}
 	wg.Wait()
+	if c.opRecorder != nil {
+		if err := c.opRecorder.Flush(); err != nil {
+			fmt.Printf("Failed to write the operation log: %v\n", err)
+		}
+	}
 
 	fmt.Println("----------------------------------")
 	fmt.Printf("Run finished, takes %.8fs\n", time.Since(start).Seconds())
@@ -125,14 +263,53 @@ func (c *Client) RunBenchmark() {
 	// }
 	c.getCacheState()
 
+	return c.postCheck(ctx)
+}
+
+// RunReplay re-executes the operations of a recorded run with Replay,
+// then runs the post-check of the workload if needed.
+func (c *Client) RunReplay(ops []Op, timed bool) (*workload.ConsistencyReport, error) {
+	ctx := context.Background()
+	start := time.Now()
+	res, err := Replay(ctx, ops, func(dbName string) (ycsb.DB, error) {
+		creator, ok := c.dbCreatorMap[dbName]
+		if !ok {
+			return nil, fmt.Errorf("no creator for %s", dbName)
+		}
+		return creator.Create()
+	}, timed)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Replayed %d operations in %.8fs, %d differ from the recorded ones\n",
+		res.Ops, time.Since(start).Seconds(), len(res.Mismatches))
+	for i, op := range res.Mismatches {
+		if i == 10 {
+			fmt.Printf("...\n")
+			break
+		}
+		fmt.Printf("  %s\n", op)
+	}
+	return c.postCheck(ctx), nil
+}
+
+// postCheck runs the post-check of the workload if needed, returning
+// its consistency report or nil if the workload does not verify an invariant.
+func (c *Client) postCheck(ctx context.Context) *workload.ConsistencyReport {
 	if !c.wl.NeedPostCheck() {
-		return
+		return nil
 	}
 
 	time.Sleep(time.Duration(c.wp.PostCheckInterval) * time.Millisecond)
 	// amountMap := make(map[string]int)
 
 	for dbName, creator := range c.dbCreatorMap {
+		if checker, ok := c.wl.(workload.PooledPostChecker); ok {
+			if err := checker.RunPostCheck(ctx, creator.Create); err != nil {
+				fmt.Printf("Error when post-checking %s: %v\n", dbName, err)
+			}
+			continue
+		}
 		// reset the key sequence to scan the whole datastore
 		c.wl.ResetKeySequence()
 		resChan := make(chan int, c.wp.PostCheckWorkerThread)
@@ -154,6 +331,12 @@ func (c *Client) RunBenchmark() {
 
 	c.wl.DisplayCheckResult()
 
+	if checker, ok := c.wl.(workload.ConsistencyChecker); ok {
+		report := checker.ConsistencyReport()
+		return &report
+	}
+	return nil
+
 	// time.Sleep(5 * time.Second)
 	// if c.wp.DBName == "oreo" {
 	// 	// check Redis
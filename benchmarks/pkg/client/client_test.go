@@ -2,8 +2,14 @@ package client
 
 import (
 	"benchmark/pkg/generator"
+	"benchmark/pkg/workload"
+	"benchmark/ycsb"
+	"context"
+	"errors"
 	"math/rand"
+	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -56,3 +62,97 @@ func TestZipfian(t *testing.T) {
 	t.Fail()
 
 }
+
+// memDBCreator creates transactions on the same in-memory records.
+type memDBCreator struct {
+	db *memDB
+}
+
+func (c memDBCreator) Create() (ycsb.DB, error) {
+	return c.db.NewTransaction(), nil
+}
+
+func TestClientCheckManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	db := newMemDB()
+	dbCreatorMap := map[string]ycsb.DBCreator{"redis": memDBCreator{db}}
+	loadWP := &workload.WorkloadParameter{WorkloadName: "ycsb", TableName: "usertable", RecordCount: 1000}
+	manifest := workload.NewManifest(loadWP, []string{"redis"})
+	if err := workload.WriteManifest(path, manifest); err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+	if err := workload.StoreManifest(context.Background(), db.NewTransaction(), manifest); err != nil {
+		t.Fatalf("failed to store the manifest: %v", err)
+	}
+
+	runWP := *loadWP
+	c := &Client{dbCreatorMap: dbCreatorMap, wp: &runWP}
+	c.SetManifestPath(path)
+	if err := c.CheckManifest(); err != nil {
+		t.Errorf("expected the loaded data to match, got %v", err)
+	}
+
+	runWP.RecordCount = 2000
+	if err := c.CheckManifest(); err == nil {
+		t.Errorf("expected the run to refuse a record count mismatch")
+	}
+	runWP.RecordCount = loadWP.RecordCount
+
+	// the data dropped since the load is refused
+	// even though the manifest file still describes it
+	clear(db.records)
+	if err := c.CheckManifest(); err == nil || !strings.Contains(err.Error(), "no load manifest") {
+		t.Errorf("expected the run to refuse a database holding no manifest, got %v", err)
+	}
+
+	// the data loaded without a manifest is not checked
+	c.SetManifestPath(filepath.Join(t.TempDir(), "missing.json"))
+	if err := c.CheckManifest(); err != nil {
+		t.Errorf("expected a missing manifest to be skipped, got %v", err)
+	}
+}
+
+// failingBulkLoadDB fails every bulk load.
+type failingBulkLoadDB struct {
+	*memDB
+}
+
+func (db *failingBulkLoadDB) BulkLoad(ctx context.Context, table string, keys []string, values []string) error {
+	return errors.New("bulk load failed")
+}
+
+// failingBulkLoadCreator creates transactions failing their bulk loads.
+type failingBulkLoadCreator struct {
+	db *memDB
+}
+
+func (c failingBulkLoadCreator) Create() (ycsb.DB, error) {
+	return &failingBulkLoadDB{memDB: c.db.NewTransaction().(*memDB)}, nil
+}
+
+func TestClientRunLoadRecordsBulkLoadFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	db := newMemDB()
+	wp := &workload.WorkloadParameter{
+		WorkloadName: "ycsb",
+		TableName:    "usertable",
+		RecordCount:  100,
+		ThreadCount:  2,
+		BulkLoad:     true,
+	}
+	var wl workload.Workload = workload.NewYCSBWorkload(wp)
+	c := NewClient(&wl, wp, map[string]ycsb.DBCreator{"redis": failingBulkLoadCreator{db}})
+	c.SetManifestPath(path)
+	c.RunLoad()
+
+	manifest, err := workload.ReadManifest(path)
+	if err != nil {
+		t.Fatalf("failed to read the manifest: %v", err)
+	}
+	if manifest.FailedOps == 0 {
+		t.Errorf("expected the failed bulk loads to be counted in the manifest")
+	}
+	if err := c.CheckManifest(); err == nil {
+		t.Errorf("expected the run to refuse the incompletely loaded data")
+	}
+}
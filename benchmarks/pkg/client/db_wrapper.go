@@ -5,8 +5,11 @@ import (
 	"benchmark/pkg/measurement"
 	"benchmark/ycsb"
 	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
 )
 
 var _ ycsb.DB = (*DbWrapper)(nil)
@@ -14,10 +17,31 @@ var _ ycsb.DB = (*DbWrapper)(nil)
 // DbWrapper stores the pointer to a implementation of ycsb.DB.
 type DbWrapper struct {
 	DB ycsb.DB
+	// Timeout bounds each operation, no limit if non-positive.
+	Timeout time.Duration
+	// throttle backs the thread off while the executors push back.
+	throttle *throttle
+	// clock corrects the latencies measured in the open loop, if not nil.
+	clock *opClock
 }
 
 func measure(start time.Time, op string, err error) {
 	lan := time.Since(start)
+	if errors.Is(err, ErrOperationTimeout) {
+		measurement.Measure(fmt.Sprintf("%s_TIMEOUT", op), start, lan)
+		return
+	}
+	// the rejections of overloaded executors are not failures of the operation
+	if IsBackpressure(err) {
+		measurement.Measure(fmt.Sprintf("%s_BACKPRESSURE", op), start, lan)
+		return
+	}
+	// a transaction aborted by a conflict is counted apart from the
+	// failures of the datastores or the network
+	if txn.IsConflict(err) {
+		measurement.Measure(fmt.Sprintf("%s_CONFLICT", op), start, lan)
+		return
+	}
 	if err != nil {
 		measurement.Measure(fmt.Sprintf("%s_ERROR", op), start, lan)
 		return
@@ -40,19 +64,23 @@ func (db DbWrapper) CleanupThread(ctx context.Context) {
 }
 
 func (db DbWrapper) Read(ctx context.Context, table string, key string) (_ string, err error) {
-	start := time.Now()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "READ", err)
 		errrecord.Record("READ", err)
 	}()
 
-	return db.DB.Read(ctx, table, key)
+	return withBackpressure(db.throttle, "READ", func() (string, error) {
+		return withTimeout(ctx, db.Timeout, func(ctx context.Context) (string, error) {
+			return db.DB.Read(ctx, table, key)
+		})
+	})
 }
 
 func (db DbWrapper) BatchRead(ctx context.Context, table string, keys []string, fields []string) (_ []map[string][]byte, err error) {
 	batchDB, ok := db.DB.(ycsb.BatchDB)
 	if ok {
-		start := time.Now()
+		start := db.clock.now()
 		defer func() {
 			measure(start, "BATCH_READ", err)
 		}()
@@ -77,13 +105,17 @@ func (db DbWrapper) BatchRead(ctx context.Context, table string, keys []string,
 // }
 
 func (db DbWrapper) Update(ctx context.Context, table string, key string, value string) (err error) {
-	start := time.Now()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "UPDATE", err)
 		errrecord.Record("UPDATE", err)
 	}()
 
-	return db.DB.Update(ctx, table, key, value)
+	return runWithBackpressure(db.throttle, "UPDATE", func() error {
+		return runWithTimeout(ctx, db.Timeout, func(ctx context.Context) error {
+			return db.DB.Update(ctx, table, key, value)
+		})
+	})
 }
 
 func (db DbWrapper) BatchUpdate(ctx context.Context, table string, keys []string, values []string) (err error) {
@@ -105,13 +137,17 @@ func (db DbWrapper) BatchUpdate(ctx context.Context, table string, keys []string
 }
 
 func (db DbWrapper) Insert(ctx context.Context, table string, key string, value string) (err error) {
-	start := time.Now()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "INSERT", err)
 		errrecord.Record("INSERT", err)
 	}()
 
-	return db.DB.Insert(ctx, table, key, value)
+	return runWithBackpressure(db.throttle, "INSERT", func() error {
+		return runWithTimeout(ctx, db.Timeout, func(ctx context.Context) error {
+			return db.DB.Insert(ctx, table, key, value)
+		})
+	})
 }
 
 func (db DbWrapper) BatchInsert(ctx context.Context, table string, keys []string, values []string) (err error) {
@@ -133,19 +169,23 @@ func (db DbWrapper) BatchInsert(ctx context.Context, table string, keys []string
 }
 
 func (db DbWrapper) Delete(ctx context.Context, table string, key string) (err error) {
-	start := time.Now()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "DELETE", err)
 		errrecord.Record("DELETE", err)
 	}()
 
-	return db.DB.Delete(ctx, table, key)
+	return runWithBackpressure(db.throttle, "DELETE", func() error {
+		return runWithTimeout(ctx, db.Timeout, func(ctx context.Context) error {
+			return db.DB.Delete(ctx, table, key)
+		})
+	})
 }
 
 func (db DbWrapper) BatchDelete(ctx context.Context, table string, keys []string) (err error) {
 	batchDB, ok := db.DB.(ycsb.BatchDB)
 	if ok {
-		start := time.Now()
+		start := db.clock.now()
 		defer func() {
 			measure(start, "BATCH_DELETE", err)
 		}()
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"benchmark/pkg/measurement"
+	"benchmark/pkg/workload"
+	"time"
+)
+
+// pacer issues operations at a fixed rate regardless of how long they take.
+//
+// The i-th operation is scheduled at start + i*interval. An operation that
+// cannot be issued on time because the previous one is still running is
+// queued and issued as soon as possible, but the schedule is not shifted,
+// so the thread catches up instead of silently lowering the rate.
+type pacer struct {
+	start    time.Time
+	interval time.Duration
+	issued   int64
+}
+
+// newPacer creates a pacer issuing rate operations per second from now on.
+func newPacer(rate float64) *pacer {
+	return &pacer{
+		start:    time.Now(),
+		interval: time.Duration(float64(time.Second) / rate),
+	}
+}
+
+// wait blocks until the next operation is due and returns its intended start.
+func (p *pacer) wait() time.Time {
+	intended := p.start.Add(time.Duration(p.issued) * p.interval)
+	p.issued++
+	if d := time.Until(intended); d > 0 {
+		time.Sleep(d)
+	}
+	return intended
+}
+
+// opClock is shared by the DB wrappers of a thread running in the open
+// loop. It holds how long the current operation has been queued behind
+// the previous one, by which the starts they measure from are moved back.
+type opClock struct {
+	queued time.Duration
+}
+
+// now returns the start to measure an operation from: the current time,
+// moved back by the time the operation of the thread has been queued.
+// A nil clock, for the closed loop, returns the current time.
+func (c *opClock) now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return time.Now().Add(-c.queued)
+}
+
+// openLoop returns a pacer running the transactions at rate per second.
+//
+// The latency of each transaction is measured as OPEN_LOOP from its
+// intended start rather than from when it was actually issued, which
+// corrects the coordinated omission of a closed loop: the time a
+// transaction spends queued behind a slow one is counted instead of
+// being hidden. The latencies of the reads, writes and transactions
+// measured through clock while it runs are corrected the same way.
+func openLoop(rate float64, clock *opClock) workload.Pacer {
+	p := newPacer(rate)
+	return func(txn func()) {
+		intended := p.wait()
+		if clock != nil {
+			clock.queued = max(time.Since(intended), 0)
+		}
+		txn()
+		measurement.Measure("OPEN_LOOP", intended, time.Since(intended))
+		if clock != nil {
+			clock.queued = 0
+		}
+	}
+}
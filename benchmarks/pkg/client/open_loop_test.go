@@ -0,0 +1,188 @@
+package client
+
+import (
+	"benchmark/pkg/measurement"
+	"benchmark/pkg/workload"
+	"benchmark/ycsb"
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOpenLoopTracksTargetRate(t *testing.T) {
+	measurement.InitMeasure()
+	const (
+		rate     = 200.0
+		opCount  = 40
+		interval = 5 * time.Millisecond
+	)
+
+	start := time.Now()
+	var issued []time.Time
+	pace := openLoop(rate, nil)
+	for i := 0; i < opCount; i++ {
+		pace(func() {
+			issued = append(issued, time.Now())
+		})
+	}
+
+	if len(issued) != opCount {
+		t.Fatalf("expected %d operations, got %d", opCount, len(issued))
+	}
+	for i, at := range issued {
+		expected := start.Add(time.Duration(i) * interval)
+		if drift := at.Sub(expected); drift < 0 || drift > 3*interval {
+			t.Errorf("operation %d is issued %v away from its schedule", i, drift)
+		}
+	}
+	elapsed := issued[opCount-1].Sub(issued[0])
+	if expected := (opCount - 1) * interval; elapsed < expected || elapsed > expected+5*interval {
+		t.Errorf("expected the operations to span about %v, got %v", expected, elapsed)
+	}
+}
+
+func TestOpenLoopKeepsScheduleAfterSlowOperation(t *testing.T) {
+	measurement.InitMeasure()
+	p := newPacer(1000)
+
+	first := p.wait()
+	// the slow operation overruns the next 10 slots
+	time.Sleep(10 * time.Millisecond)
+
+	// the queued operations are due at once but keep their intended starts,
+	// so that their latencies include the time spent queued
+	for i := 1; i <= 5; i++ {
+		before := time.Now()
+		intended := p.wait()
+		if expected := first.Add(time.Duration(i) * time.Millisecond); !intended.Equal(expected) {
+			t.Errorf("expected operation %d to be intended at %v, got %v", i, expected, intended)
+		}
+		if waited := time.Since(before); waited > time.Millisecond {
+			t.Errorf("expected queued operation %d to be issued at once, waited %v", i, waited)
+		}
+	}
+}
+
+// latencySink keeps the latencies measured for each operation.
+type latencySink struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+}
+
+func (s *latencySink) RecordLatency(op string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[op] = append(s.latencies[op], d)
+}
+
+func (s *latencySink) Flush() {}
+
+// slowFirstDB takes slow to serve its first read and serves the others at once.
+type slowFirstDB struct {
+	ycsb.DB
+	slow  time.Duration
+	reads int
+}
+
+func (db *slowFirstDB) Read(ctx context.Context, table string, key string) (string, error) {
+	db.reads++
+	if db.reads == 1 {
+		time.Sleep(db.slow)
+	}
+	return "value", nil
+}
+
+func TestOpenLoopCorrectsOperationLatencies(t *testing.T) {
+	sink := &latencySink{latencies: make(map[string][]time.Duration)}
+	measurement.InitMeasure()
+	measurement.SetSink(sink)
+	defer measurement.SetSink(nil)
+
+	const slow = 20 * time.Millisecond
+	clock := &opClock{}
+	db := &DbWrapper{DB: &slowFirstDB{slow: slow}, clock: clock}
+	pace := openLoop(1000, clock)
+	for i := 0; i < 3; i++ {
+		pace(func() {
+			_, _ = db.Read(context.Background(), "table", "key1")
+		})
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	reads, ops := sink.latencies["READ"], sink.latencies["OPEN_LOOP"]
+	if len(reads) != 3 || len(ops) != 3 {
+		t.Fatalf("expected 3 reads and 3 operations, got %v and %v", reads, ops)
+	}
+	// the reads queued behind the slow one count the time spent queued,
+	// like the operations they belong to
+	for i := 1; i < 3; i++ {
+		if reads[i] < slow/2 {
+			t.Errorf("expected the queued read %d to take at least %v, got %v", i, slow/2, reads[i])
+		}
+		if reads[i] > ops[i] {
+			t.Errorf("expected the read %d to take at most its operation %v, got %v", i, ops[i], reads[i])
+		}
+	}
+}
+
+// txnCountingDB counts the transactions started and committed on it.
+type txnCountingDB struct {
+	*memDB
+	starts, commits int
+}
+
+func (db *txnCountingDB) Start() error {
+	db.starts++
+	return db.memDB.Start()
+}
+
+func (db *txnCountingDB) Commit() error {
+	err := db.memDB.Commit()
+	if err == nil {
+		db.commits++
+	}
+	return err
+}
+
+func TestOpenLoopPacesTransactions(t *testing.T) {
+	measurement.InitMeasure()
+	ctx := context.Background()
+	wp := &workload.WorkloadParameter{
+		DBName:            "memory",
+		TableName:         "table",
+		RecordCount:       10,
+		OperationCount:    20,
+		TxnOperationGroup: 4,
+		ThreadCount:       1,
+		DoBenchmark:       true,
+		KeyDistribution:   workload.Uniform,
+		Seed:              1,
+		UpdateProportion:  1,
+		OpenLoop:          true,
+		TargetRate:        1000,
+	}
+	mem := newMemDB()
+	wl := workload.NewYCSBWorkload(wp)
+	wl.Load(ctx, wp.RecordCount, mem)
+	loaded := make(map[string]string, len(mem.records))
+	for k, v := range mem.records {
+		loaded[k] = v
+	}
+
+	db := &txnCountingDB{memDB: mem}
+	w := newWorker(wl, wp, 0, 1, map[string]ycsb.DB{"memory": db})
+	w.RunBenchmark(ctx, "memory")
+
+	// every tick of the open loop runs a whole group of operations
+	expected := wp.OperationCount / wp.TxnOperationGroup
+	if db.starts != expected || db.commits != expected {
+		t.Fatalf("expected %d transactions to start and commit, got %d starts and %d commits",
+			expected, db.starts, db.commits)
+	}
+	if reflect.DeepEqual(mem.records, loaded) {
+		t.Errorf("expected the updates of the transactions to be committed")
+	}
+}
@@ -0,0 +1,329 @@
+package client
+
+import (
+	"benchmark/ycsb"
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// opLogHeader is the first line of an operation log, naming its format.
+const opLogHeader = "# oplog v1"
+
+// OpKind is the kind of an operation in an operation log.
+type OpKind byte
+
+const (
+	OpStart  OpKind = 'S'
+	OpCommit OpKind = 'C'
+	OpAbort  OpKind = 'A'
+	OpRead   OpKind = 'R'
+	OpUpdate OpKind = 'U'
+	OpInsert OpKind = 'I'
+	OpDelete OpKind = 'D'
+)
+
+// Op is an operation of an operation log.
+//
+// An operation log has a line per operation, in the order they returned:
+//
+//	<at µs> <session> <db> <kind> <ok|err> <table> <key> <value>
+//
+// separated by tabs, where table, key and value are quoted Go strings.
+// A session is a DB handle of a thread or a transaction it created,
+// so that the operations of a session are replayed on the same handle.
+type Op struct {
+	// At is when the operation returned since the recording started.
+	At      time.Duration
+	Session int
+	DB      string
+	Kind    OpKind
+	Failed  bool
+	Table   string
+	Key     string
+	// Value is the written value, or the value read.
+	Value string
+}
+
+func (op Op) String() string {
+	status := "ok"
+	if op.Failed {
+		status = "err"
+	}
+	return strings.Join([]string{
+		strconv.FormatInt(op.At.Microseconds(), 10),
+		strconv.Itoa(op.Session),
+		op.DB,
+		string(op.Kind),
+		status,
+		strconv.Quote(op.Table),
+		strconv.Quote(op.Key),
+		strconv.Quote(op.Value),
+	}, "\t")
+}
+
+// parseOp parses a line of an operation log.
+func parseOp(line string) (Op, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 8 {
+		return Op{}, fmt.Errorf("expected 8 fields, got %d", len(fields))
+	}
+	var op Op
+	at, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return Op{}, fmt.Errorf("invalid time %q", fields[0])
+	}
+	op.At = time.Duration(at) * time.Microsecond
+	if op.Session, err = strconv.Atoi(fields[1]); err != nil {
+		return Op{}, fmt.Errorf("invalid session %q", fields[1])
+	}
+	op.DB = fields[2]
+	if len(fields[3]) != 1 || !strings.Contains("SCARUID", fields[3]) {
+		return Op{}, fmt.Errorf("invalid operation kind %q", fields[3])
+	}
+	op.Kind = OpKind(fields[3][0])
+	switch fields[4] {
+	case "ok":
+	case "err":
+		op.Failed = true
+	default:
+		return Op{}, fmt.Errorf("invalid status %q", fields[4])
+	}
+	for i, s := range []*string{&op.Table, &op.Key, &op.Value} {
+		if *s, err = strconv.Unquote(fields[5+i]); err != nil {
+			return Op{}, fmt.Errorf("invalid string %s", fields[5+i])
+		}
+	}
+	return op, nil
+}
+
+// ReadOpLog reads the operations of an operation log.
+func ReadOpLog(r io.Reader) ([]Op, error) {
+	var ops []Op
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		op, err := parseOp(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of the operation log: %w", lineNo, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// OpRecorder writes the operations issued on the DBs it wraps
+// to an operation log.
+type OpRecorder struct {
+	mu          sync.Mutex
+	w           *bufio.Writer
+	start       time.Time
+	nextSession int
+	err         error
+}
+
+// NewOpRecorder returns a recorder writing the operation log to w.
+func NewOpRecorder(w io.Writer) *OpRecorder {
+	r := &OpRecorder{w: bufio.NewWriter(w), start: time.Now()}
+	_, r.err = fmt.Fprintln(r.w, opLogHeader)
+	return r
+}
+
+// Wrap returns db recording its operations in a new session.
+// The optional interfaces of db other than ycsb.TransactionDB are hidden,
+// so that a batch is recorded as the operations it falls back to.
+func (r *OpRecorder) Wrap(db ycsb.DB, dbName string) ycsb.DB {
+	r.mu.Lock()
+	r.nextSession++
+	rdb := &recordingDB{DB: db, rec: r, session: r.nextSession, dbName: dbName}
+	r.mu.Unlock()
+	if txnDB, ok := db.(ycsb.TransactionDB); ok {
+		return &recordingTxnDB{recordingDB: rdb, txnDB: txnDB}
+	}
+	return rdb
+}
+
+// Flush writes the buffered operations, returning the first error
+// met since the recording started.
+func (r *OpRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.w.Flush(); r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+func (r *OpRecorder) record(op Op) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	op.At = time.Since(r.start)
+	_, r.err = fmt.Fprintln(r.w, op.String())
+}
+
+type recordingDB struct {
+	ycsb.DB
+	rec     *OpRecorder
+	session int
+	dbName  string
+}
+
+func (db *recordingDB) record(kind OpKind, err error, table, key, value string) {
+	db.rec.record(Op{
+		Session: db.session,
+		DB:      db.dbName,
+		Kind:    kind,
+		Failed:  err != nil,
+		Table:   table,
+		Key:     key,
+		Value:   value,
+	})
+}
+
+func (db *recordingDB) Read(ctx context.Context, table string, key string) (string, error) {
+	value, err := db.DB.Read(ctx, table, key)
+	db.record(OpRead, err, table, key, value)
+	return value, err
+}
+
+func (db *recordingDB) Update(ctx context.Context, table string, key string, value string) error {
+	err := db.DB.Update(ctx, table, key, value)
+	db.record(OpUpdate, err, table, key, value)
+	return err
+}
+
+func (db *recordingDB) Insert(ctx context.Context, table string, key string, value string) error {
+	err := db.DB.Insert(ctx, table, key, value)
+	db.record(OpInsert, err, table, key, value)
+	return err
+}
+
+func (db *recordingDB) Delete(ctx context.Context, table string, key string) error {
+	err := db.DB.Delete(ctx, table, key)
+	db.record(OpDelete, err, table, key, "")
+	return err
+}
+
+type recordingTxnDB struct {
+	*recordingDB
+	txnDB ycsb.TransactionDB
+}
+
+func (db *recordingTxnDB) NewTransaction() ycsb.TransactionDB {
+	return db.rec.Wrap(db.txnDB.NewTransaction(), db.dbName).(ycsb.TransactionDB)
+}
+
+func (db *recordingTxnDB) Start() error {
+	err := db.txnDB.Start()
+	db.record(OpStart, err, "", "", "")
+	return err
+}
+
+func (db *recordingTxnDB) Commit() error {
+	err := db.txnDB.Commit()
+	db.record(OpCommit, err, "", "", "")
+	return err
+}
+
+func (db *recordingTxnDB) Abort() error {
+	err := db.txnDB.Abort()
+	db.record(OpAbort, err, "", "", "")
+	return err
+}
+
+// ReplayResult is the outcome of Replay.
+type ReplayResult struct {
+	Ops int
+	// Mismatches are the operations whose outcome or read value
+	// differs from the recorded one.
+	Mismatches []Op
+}
+
+// Replay re-executes ops in their order on a single goroutine, each
+// session on its own DB created by newDB for the DB it was recorded on.
+// If timed, every operation waits for its recorded time.
+//
+// A write that failed when recorded is skipped and a commit that failed
+// is replayed as an abort, so that the replay reaches the recorded state.
+func Replay(ctx context.Context, ops []Op, newDB func(dbName string) (ycsb.DB, error),
+	timed bool) (ReplayResult, error) {
+	var res ReplayResult
+	sessions := make(map[int]ycsb.DB)
+	start := time.Now()
+	for _, op := range ops {
+		if timed {
+			if wait := time.Until(start.Add(op.At)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return res, ctx.Err()
+				}
+			}
+		}
+
+		db, ok := sessions[op.Session]
+		if !ok {
+			var err error
+			if db, err = newDB(op.DB); err != nil {
+				return res, fmt.Errorf("failed to create %s for session %d: %w", op.DB, op.Session, err)
+			}
+			sessions[op.Session] = db
+		}
+
+		var err error
+		value := op.Value
+		switch op.Kind {
+		case OpStart, OpCommit, OpAbort:
+			txnDB, ok := db.(ycsb.TransactionDB)
+			if !ok {
+				return res, fmt.Errorf("%s does not support transactions", op.DB)
+			}
+			switch {
+			case op.Kind == OpStart:
+				err = txnDB.Start()
+			case op.Kind == OpCommit && !op.Failed:
+				err = txnDB.Commit()
+			default:
+				err = txnDB.Abort()
+			}
+		case OpRead:
+			value, err = db.Read(ctx, op.Table, op.Key)
+		case OpUpdate, OpInsert, OpDelete:
+			if op.Failed {
+				res.Ops++
+				continue
+			}
+			switch op.Kind {
+			case OpUpdate:
+				err = db.Update(ctx, op.Table, op.Key, op.Value)
+			case OpInsert:
+				err = db.Insert(ctx, op.Table, op.Key, op.Value)
+			default:
+				err = db.Delete(ctx, op.Table, op.Key)
+			}
+		}
+		res.Ops++
+
+		failed := err != nil
+		if op.Kind == OpCommit && op.Failed {
+			// the recorded commit is expected to fail, not the abort replacing it
+			failed = !failed
+		}
+		if failed != op.Failed || (!failed && value != op.Value) {
+			res.Mismatches = append(res.Mismatches, op)
+		}
+	}
+	return res, nil
+}
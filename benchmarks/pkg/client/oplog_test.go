@@ -0,0 +1,190 @@
+package client
+
+import (
+	"benchmark/pkg/workload"
+	"benchmark/ycsb"
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// memDB keeps the committed records in memory and
+// buffers the writes of the running transaction until it commits.
+type memDB struct {
+	mu      *sync.Mutex
+	records map[string]string
+	writes  map[string]string
+	// failCommits is the number of commits left to fail.
+	failCommits *int
+}
+
+func newMemDB() *memDB {
+	return &memDB{mu: &sync.Mutex{}, records: make(map[string]string), failCommits: new(int)}
+}
+
+func (db *memDB) Close() error { return nil }
+
+func (db *memDB) InitThread(ctx context.Context, _ int, _ int) context.Context { return ctx }
+
+func (db *memDB) CleanupThread(context.Context) {}
+
+func (db *memDB) Read(ctx context.Context, table string, key string) (string, error) {
+	if value, ok := db.writes[table+key]; ok {
+		return value, nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	value, ok := db.records[table+key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return value, nil
+}
+
+func (db *memDB) Update(ctx context.Context, table string, key string, value string) error {
+	db.writes[table+key] = value
+	return nil
+}
+
+func (db *memDB) Insert(ctx context.Context, table string, key string, value string) error {
+	return db.Update(ctx, table, key, value)
+}
+
+func (db *memDB) Delete(ctx context.Context, table string, key string) error {
+	return errors.New("not supported")
+}
+
+func (db *memDB) NewTransaction() ycsb.TransactionDB {
+	return &memDB{mu: db.mu, records: db.records, failCommits: db.failCommits}
+}
+
+func (db *memDB) Start() error {
+	db.writes = make(map[string]string)
+	return nil
+}
+
+func (db *memDB) Commit() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	defer func() { db.writes = nil }()
+	if *db.failCommits > 0 {
+		*db.failCommits--
+		return errors.New("conflict")
+	}
+	for k, v := range db.writes {
+		db.records[k] = v
+	}
+	return nil
+}
+
+func (db *memDB) Abort() error {
+	db.writes = nil
+	return nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	wp := &workload.WorkloadParameter{
+		DBName:                "memory",
+		TableName:             "table",
+		RecordCount:           10,
+		KeyDistribution:       workload.Uniform,
+		Seed:                  1,
+		InitialAmountPerKey:   100,
+		TransferAmountPerTxn:  7,
+		TotalAmount:           1000,
+		PostCheckWorkerThread: 1,
+	}
+	load := func() *memDB {
+		db := newMemDB()
+		workload.NewDataConsistencyWorkload(wp).Load(ctx, wp.RecordCount, db)
+		return db
+	}
+
+	recorded := load()
+	// the recorded run loses some transfers to failed commits
+	*recorded.failCommits = 3
+	var buf bytes.Buffer
+	rec := NewOpRecorder(&buf)
+	wl := workload.NewDataConsistencyWorkload(wp)
+	wl.Run(ctx, 20, rec.Wrap(recorded, "memory"))
+	if err := rec.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err := ReadOpLog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := 0
+	for _, op := range ops {
+		if op.Kind == OpCommit && op.Failed {
+			failed++
+		}
+	}
+	if failed != 3 {
+		t.Fatalf("expected 3 failed commits in the log, got %d", failed)
+	}
+
+	replayed := load()
+	res, err := Replay(ctx, ops, func(dbName string) (ycsb.DB, error) {
+		if dbName != "memory" {
+			t.Errorf("expected the recorded DB name, got %s", dbName)
+		}
+		return replayed.NewTransaction(), nil
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Ops != len(ops) {
+		t.Errorf("expected %d replayed operations, got %d", len(ops), res.Ops)
+	}
+	if len(res.Mismatches) != 0 {
+		t.Errorf("expected the replay to match the log, got %v", res.Mismatches)
+	}
+	if !reflect.DeepEqual(recorded.records, replayed.records) {
+		t.Errorf("expected the replay to reach the recorded state\nrecorded: %v\nreplayed: %v",
+			recorded.records, replayed.records)
+	}
+}
+
+func TestReplayReportsMismatches(t *testing.T) {
+	ctx := context.Background()
+	ops := []Op{
+		{Session: 1, DB: "memory", Kind: OpStart},
+		{Session: 1, DB: "memory", Kind: OpRead, Table: "t", Key: "k", Value: "1"},
+		{Session: 1, DB: "memory", Kind: OpUpdate, Table: "t", Key: "k", Value: "2"},
+		{Session: 1, DB: "memory", Kind: OpCommit},
+	}
+	db := newMemDB()
+	db.records["tk"] = "0"
+	res, err := Replay(ctx, ops, func(string) (ycsb.DB, error) {
+		return db.NewTransaction(), nil
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Mismatches) != 1 || res.Mismatches[0].Kind != OpRead {
+		t.Errorf("expected the read to mismatch, got %v", res.Mismatches)
+	}
+	if db.records["tk"] != "2" {
+		t.Errorf("expected the update to be replayed, got %q", db.records["tk"])
+	}
+}
+
+func TestParseOp(t *testing.T) {
+	op := Op{Session: 3, DB: "redis", Kind: OpUpdate, Table: "t", Key: "a\tb", Value: "line\n"}
+	got, err := parseOp(op.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != op {
+		t.Errorf("expected %+v, got %+v", op, got)
+	}
+	if _, err := parseOp("0\t1\tredis\tX\tok\t\"\"\t\"\"\t\"\""); err == nil {
+		t.Errorf("expected an unknown operation kind to be rejected")
+	}
+}
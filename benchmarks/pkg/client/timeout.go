@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrOperationTimeout is returned by the wrapped DBs
+// for an operation that does not finish within the operation timeout.
+var ErrOperationTimeout = errors.New("operation timeout")
+
+// withTimeout runs op and stops waiting for it after timeout, so that
+// a stalled datastore does not stall the whole benchmark thread.
+// The context of op is canceled on expiry, but a DB ignoring it keeps
+// running the abandoned op in the background.
+// A non-positive timeout runs op directly.
+func withTimeout[T any](ctx context.Context, timeout time.Duration,
+	op func(ctx context.Context) (T, error)) (T, error) {
+	val, _, err := trackedWithTimeout(ctx, timeout, op)
+	return val, err
+}
+
+// trackedWithTimeout is withTimeout that also returns, if op has been
+// abandoned, a channel closed once op returns, so that the caller can
+// wait for it before touching what op uses again.
+func trackedWithTimeout[T any](ctx context.Context, timeout time.Duration,
+	op func(ctx context.Context) (T, error)) (T, <-chan struct{}, error) {
+	if timeout <= 0 {
+		val, err := op(ctx)
+		return val, nil, err
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		val T
+		err error
+	}
+	done := make(chan result, 1)
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		val, err := op(opCtx)
+		done <- result{val, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.val, nil, res.err
+	case <-opCtx.Done():
+		var zero T
+		if ctx.Err() != nil {
+			return zero, returned, ctx.Err()
+		}
+		return zero, returned, ErrOperationTimeout
+	}
+}
+
+// runWithTimeout is withTimeout for the operations returning only an error.
+func runWithTimeout(ctx context.Context, timeout time.Duration, op func(ctx context.Context) error) error {
+	_, err := withTimeout(ctx, timeout, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, op(ctx)
+	})
+	return err
+}
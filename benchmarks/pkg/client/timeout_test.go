@@ -0,0 +1,212 @@
+package client
+
+import (
+	"benchmark/pkg/measurement"
+	"benchmark/ycsb"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// blockingDB blocks every operation until release is closed,
+// ignoring the context like a stalled datastore would.
+type blockingDB struct {
+	ycsb.DB
+	release chan struct{}
+}
+
+func (db *blockingDB) Read(ctx context.Context, table string, key string) (string, error) {
+	<-db.release
+	return "value", nil
+}
+
+// opSink keeps the names of the measured operations.
+type opSink struct {
+	mu  sync.Mutex
+	ops []string
+}
+
+func (s *opSink) RecordLatency(op string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops = append(s.ops, op)
+}
+
+func (s *opSink) Flush() {}
+
+func TestOperationTimeout(t *testing.T) {
+	sink := &opSink{}
+	measurement.InitMeasure()
+	measurement.SetSink(sink)
+	defer measurement.SetSink(nil)
+
+	stub := &blockingDB{release: make(chan struct{})}
+	defer close(stub.release)
+	db := &DbWrapper{DB: stub, Timeout: 20 * time.Millisecond}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.Read(context.Background(), "table", "key1")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrOperationTimeout) {
+			t.Fatalf("expected a timeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the operation hangs past the timeout")
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.ops) != 1 || sink.ops[0] != "READ_TIMEOUT" {
+		t.Errorf("expected the read to be counted as READ_TIMEOUT, got %v", sink.ops)
+	}
+}
+
+func TestOperationWithinTimeout(t *testing.T) {
+	measurement.InitMeasure()
+
+	stub := &blockingDB{release: make(chan struct{})}
+	close(stub.release)
+	db := &DbWrapper{DB: stub, Timeout: time.Second}
+
+	value, err := db.Read(context.Background(), "table", "key1")
+	if err != nil || value != "value" {
+		t.Errorf("expected the value, got %q and %v", value, err)
+	}
+}
+
+// committingDB fails every commit with err.
+type committingDB struct {
+	ycsb.TransactionDB
+	err error
+}
+
+func (db *committingDB) Commit() error {
+	return db.err
+}
+
+func TestCommitConflictMeasured(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{"conflict", &txn.ConflictError{Err: errors.New("version mismatch")}, []string{"COMMIT_CONFLICT", "TXN_CONFLICT"}},
+		{"other", errors.New("connection refused"), []string{"COMMIT_ERROR", "TXN_ERROR"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &opSink{}
+			measurement.InitMeasure()
+			measurement.SetSink(sink)
+			defer measurement.SetSink(nil)
+
+			db := &TxnDbWrapper{DB: &committingDB{err: tt.err}, TxnStart: time.Now()}
+			if err := db.Commit(); err != tt.err {
+				t.Fatalf("expected the commit error to pass through, got %v", err)
+			}
+
+			sink.mu.Lock()
+			defer sink.mu.Unlock()
+			if len(sink.ops) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, sink.ops)
+			}
+			for i, op := range tt.want {
+				if sink.ops[i] != op {
+					t.Errorf("expected %v, got %v", tt.want, sink.ops)
+				}
+			}
+		})
+	}
+}
+
+// slowTxnDB logs its calls without synchronization, like a transaction
+// used by a single thread, and blocks the operations in slow until
+// release is closed.
+type slowTxnDB struct {
+	ycsb.TransactionDB
+	slow    map[string]bool
+	release chan struct{}
+	calls   []string
+}
+
+func (db *slowTxnDB) call(op string) {
+	if db.slow[op] {
+		<-db.release
+	}
+	db.calls = append(db.calls, op)
+}
+
+func (db *slowTxnDB) Start() error  { db.call("Start"); return nil }
+func (db *slowTxnDB) Commit() error { db.call("Commit"); return nil }
+func (db *slowTxnDB) Abort() error  { db.call("Abort"); return nil }
+
+func (db *slowTxnDB) Read(ctx context.Context, table string, key string) (string, error) {
+	db.call("Read")
+	return "value", nil
+}
+
+func (db *slowTxnDB) Update(ctx context.Context, table string, key string, value string) error {
+	db.call("Update")
+	return nil
+}
+
+// TestTxnOperationTimeout tests that the transaction is not used again
+// until the operation given up on by a timeout returns, and that it is
+// aborted instead of committed. Run it with -race.
+func TestTxnOperationTimeout(t *testing.T) {
+	measurement.InitMeasure()
+
+	tests := []struct {
+		name      string
+		slow      string
+		wantCalls []string
+	}{
+		{"read", "Read", []string{"Start", "Read", "Update", "Abort", "Start"}},
+		{"update", "Update", []string{"Start", "Read", "Update", "Abort", "Start"}},
+		{"commit", "Commit", []string{"Start", "Read", "Update", "Commit", "Start"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &slowTxnDB{slow: map[string]bool{tt.slow: true}, release: make(chan struct{})}
+			db := &TxnDbWrapper{DB: stub, Timeout: 20 * time.Millisecond}
+			time.AfterFunc(100*time.Millisecond, func() { close(stub.release) })
+
+			ctx := context.Background()
+			if err := db.Start(); err != nil {
+				t.Fatalf("Error starting: %v", err)
+			}
+			_, readErr := db.Read(ctx, "table", "key1")
+			updateErr := db.Update(ctx, "table", "key1", "value")
+			commitErr := db.Commit()
+			if err := errors.Join(readErr, updateErr, commitErr); !errors.Is(err, ErrOperationTimeout) {
+				t.Errorf("expected a timeout, got %v", err)
+			}
+			if tt.slow != "Commit" && !errors.Is(commitErr, ErrOperationTimeout) {
+				t.Errorf("expected the commit to fail after a timeout, got %v", commitErr)
+			}
+			// the next transaction waits for the last one to be over
+			if err := db.Start(); err != nil {
+				t.Fatalf("Error starting: %v", err)
+			}
+
+			if len(stub.calls) != len(tt.wantCalls) {
+				t.Fatalf("expected the calls %v, got %v", tt.wantCalls, stub.calls)
+			}
+			for i, call := range tt.wantCalls {
+				if stub.calls[i] != call {
+					t.Errorf("expected the calls %v, got %v", tt.wantCalls, stub.calls)
+					break
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"benchmark/pkg/errrecord"
 	"benchmark/ycsb"
 	"context"
+	"errors"
 	"time"
 )
 
@@ -14,20 +15,68 @@ var _ ycsb.TransactionDB = (*TxnDbWrapper)(nil)
 type TxnDbWrapper struct {
 	DB       ycsb.TransactionDB
 	TxnStart time.Time
+	// Timeout bounds each operation, no limit if non-positive.
+	Timeout time.Duration
+	// throttle backs the thread off while the executors push back.
+	throttle *throttle
+	// clock corrects the latencies measured in the open loop, if not nil.
+	clock *opClock
+	// abandoned is closed once the operation given up on by the last
+	// timeout returns. It still uses the transaction of DB until then.
+	abandoned <-chan struct{}
+	// timedOut is set once an operation of the current transaction has
+	// timed out, so that the transaction is aborted instead of committed.
+	timedOut bool
+}
+
+// settle waits for the operation abandoned by a timeout, if any,
+// before the transaction is used again.
+func (db *TxnDbWrapper) settle() {
+	if db.abandoned != nil {
+		<-db.abandoned
+		db.abandoned = nil
+	}
+}
+
+// txnWithTimeout is withTimeout for the operations of the transaction
+// of db, which remembers the operation it gives up on.
+func txnWithTimeout[T any](db *TxnDbWrapper, ctx context.Context,
+	op func(ctx context.Context) (T, error)) (T, error) {
+	val, abandoned, err := trackedWithTimeout(ctx, db.Timeout, op)
+	if abandoned != nil {
+		db.abandoned = abandoned
+		db.timedOut = true
+	}
+	return val, err
+}
+
+// runTxnWithTimeout is txnWithTimeout for the operations returning only an error.
+func runTxnWithTimeout(db *TxnDbWrapper, ctx context.Context, op func(ctx context.Context) error) error {
+	_, err := txnWithTimeout(db, ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, op(ctx)
+	})
+	return err
 }
 
 func (db *TxnDbWrapper) Start() (err error) {
-	db.TxnStart = time.Now()
-	start := time.Now()
+	db.settle()
+	db.timedOut = false
+	db.TxnStart = db.clock.now()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "Start", err)
 		errrecord.Record("Start", err)
 	}()
-	return db.DB.Start()
+	return runWithBackpressure(db.throttle, "Start", func() error {
+		return runTxnWithTimeout(db, context.Background(), func(context.Context) error {
+			return db.DB.Start()
+		})
+	})
 }
 
 func (db *TxnDbWrapper) Commit() (err error) {
-	start := time.Now()
+	db.settle()
+	start := db.clock.now()
 	defer func() {
 		// if err != nil {
 		// 	fmt.Println("Error in Commit(): ", err)
@@ -35,11 +84,20 @@ func (db *TxnDbWrapper) Commit() (err error) {
 		measure(start, "COMMIT", err)
 		measure(db.TxnStart, "TXN", err)
 		errrecord.Record("COMMIT", err)
+		// the transaction is over, so its next one is delayed instead
+		db.throttle.pushedBack(err)
 	}()
-	return db.DB.Commit()
+	// an operation given up on may or may not have taken effect
+	if db.timedOut {
+		return errors.Join(ErrOperationTimeout, db.DB.Abort())
+	}
+	return runTxnWithTimeout(db, context.Background(), func(context.Context) error {
+		return db.DB.Commit()
+	})
 }
 
 func (db *TxnDbWrapper) Abort() error {
+	db.settle()
 	return db.DB.Abort()
 }
 
@@ -48,6 +106,7 @@ func (db *TxnDbWrapper) NewTransaction() ycsb.TransactionDB {
 }
 
 func (db *TxnDbWrapper) Close() error {
+	db.settle()
 	return db.DB.Close()
 }
 
@@ -60,7 +119,8 @@ func (db *TxnDbWrapper) CleanupThread(ctx context.Context) {
 }
 
 func (db *TxnDbWrapper) Read(ctx context.Context, table string, key string) (_ string, err error) {
-	start := time.Now()
+	db.settle()
+	start := db.clock.now()
 	defer func() {
 		// if err != nil {
 		// 	fmt.Println("Error in Read: ", err)
@@ -69,13 +129,18 @@ func (db *TxnDbWrapper) Read(ctx context.Context, table string, key string) (_ s
 		errrecord.Record("READ", err)
 	}()
 
-	return db.DB.Read(ctx, table, key)
+	return withBackpressure(db.throttle, "READ", func() (string, error) {
+		return txnWithTimeout(db, ctx, func(ctx context.Context) (string, error) {
+			return db.DB.Read(ctx, table, key)
+		})
+	})
 }
 
 func (db *TxnDbWrapper) BatchRead(ctx context.Context, table string, keys []string, fields []string) (_ []map[string][]byte, err error) {
+	db.settle()
 	batchDB, ok := db.DB.(ycsb.BatchDB)
 	if ok {
-		start := time.Now()
+		start := db.clock.now()
 		defer func() {
 			measure(start, "BATCH_READ", err)
 		}()
@@ -100,16 +165,22 @@ func (db *TxnDbWrapper) BatchRead(ctx context.Context, table string, keys []stri
 // }
 
 func (db *TxnDbWrapper) Update(ctx context.Context, table string, key string, value string) (err error) {
-	start := time.Now()
+	db.settle()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "UPDATE", err)
 		errrecord.Record("UPDATE", err)
 	}()
 
-	return db.DB.Update(ctx, table, key, value)
+	return runWithBackpressure(db.throttle, "UPDATE", func() error {
+		return runTxnWithTimeout(db, ctx, func(ctx context.Context) error {
+			return db.DB.Update(ctx, table, key, value)
+		})
+	})
 }
 
 func (db *TxnDbWrapper) BatchUpdate(ctx context.Context, table string, keys []string, values []string) (err error) {
+	db.settle()
 	// batchDB, ok := db.DB.(ycsb.BatchDB)
 	// if ok {
 	// 	start := time.Now()
@@ -128,16 +199,22 @@ func (db *TxnDbWrapper) BatchUpdate(ctx context.Context, table string, keys []st
 }
 
 func (db *TxnDbWrapper) Insert(ctx context.Context, table string, key string, value string) (err error) {
-	start := time.Now()
+	db.settle()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "INSERT", err)
 		errrecord.Record("INSERT", err)
 	}()
 
-	return db.DB.Insert(ctx, table, key, value)
+	return runWithBackpressure(db.throttle, "INSERT", func() error {
+		return runTxnWithTimeout(db, ctx, func(ctx context.Context) error {
+			return db.DB.Insert(ctx, table, key, value)
+		})
+	})
 }
 
 func (db *TxnDbWrapper) BatchInsert(ctx context.Context, table string, keys []string, values []string) (err error) {
+	db.settle()
 	// batchDB, ok := db.DB.(ycsb.BatchDB)
 	// if ok {
 	// 	start := time.Now()
@@ -156,18 +233,24 @@ func (db *TxnDbWrapper) BatchInsert(ctx context.Context, table string, keys []st
 }
 
 func (db *TxnDbWrapper) Delete(ctx context.Context, table string, key string) (err error) {
-	start := time.Now()
+	db.settle()
+	start := db.clock.now()
 	defer func() {
 		measure(start, "DELETE", err)
 	}()
 
-	return db.DB.Delete(ctx, table, key)
+	return runWithBackpressure(db.throttle, "DELETE", func() error {
+		return runTxnWithTimeout(db, ctx, func(ctx context.Context) error {
+			return db.DB.Delete(ctx, table, key)
+		})
+	})
 }
 
 func (db *TxnDbWrapper) BatchDelete(ctx context.Context, table string, keys []string) (err error) {
+	db.settle()
 	batchDB, ok := db.DB.(ycsb.BatchDB)
 	if ok {
-		start := time.Now()
+		start := db.clock.now()
 		defer func() {
 			measure(start, "BATCH_DELETE", err)
 		}()
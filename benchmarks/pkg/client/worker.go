@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 )
 
 type worker struct {
@@ -14,6 +15,9 @@ type worker struct {
 	threadID     int
 	wrappedDBMap map[string]ycsb.DB
 	originDBMap  map[string]ycsb.DB
+	// clock corrects the latencies measured by the wrapped DBs
+	// in the open loop, nil in the closed loop.
+	clock *opClock
 
 	opCount int
 }
@@ -32,12 +36,18 @@ func newWorker(
 		wrappedDBMap: make(map[string]ycsb.DB),
 	}
 
+	if wp.OpenLoop {
+		w.clock = &opClock{}
+	}
+	timeout := time.Duration(wp.OperationTimeout) * time.Millisecond
+	// the thread backs off as a whole, whichever DB is pushed back
+	th := newThrottle()
 	for name, workDB := range workDBMap {
 		switch db := workDB.(type) {
 		case ycsb.TransactionDB:
-			w.wrappedDBMap[name] = &TxnDbWrapper{DB: db}
+			w.wrappedDBMap[name] = &TxnDbWrapper{DB: db, Timeout: timeout, throttle: th, clock: w.clock}
 		case ycsb.DB:
-			w.wrappedDBMap[name] = &DbWrapper{DB: db}
+			w.wrappedDBMap[name] = &DbWrapper{DB: db, Timeout: timeout, throttle: th, clock: w.clock}
 		default:
 			fmt.Printf("unknown db type: %T", workDB)
 			os.Exit(-1)
@@ -70,6 +80,11 @@ func newWorker(
 }
 
 func (w *worker) RunLoad(ctx context.Context, dbName string) {
+	// the bulk load bypasses the transactions, so there is nothing to measure
+	if _, ok := w.originDBMap[dbName].(ycsb.BulkLoadDB); ok && w.wp.BulkLoad {
+		w.wl.Load(ctx, w.opCount, w.originDBMap[dbName])
+		return
+	}
 	w.wl.Load(ctx, w.opCount, w.wrappedDBMap[dbName])
 }
 
@@ -80,6 +95,11 @@ func (w *worker) RunBenchmark(ctx context.Context, dbName string) {
 	} else {
 		db = w.wrappedDBMap[dbName]
 	}
+	if w.wp.OpenLoop {
+		// every thread takes an equal share of the target rate
+		rate := w.wp.TargetRate / float64(w.wp.ThreadCount)
+		ctx = workload.WithPacer(ctx, openLoop(rate, w.clock))
+	}
 	w.wl.Run(ctx, w.opCount, db)
 }
 
@@ -25,3 +25,10 @@ func Record(op string, err error) {
 func Summary() {
 	errRecorder.Summary()
 }
+
+// Count returns the number of errors recorded for op.
+func Count(op string) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return errRecorder.Count(op)
+}
@@ -27,6 +27,15 @@ func (er *ErrRecorder) Record(op string, err error) {
 	er.opMap[op][err.Error()]++
 }
 
+// Count returns the number of errors recorded for op.
+func (er *ErrRecorder) Count(op string) int {
+	count := 0
+	for _, cnt := range er.opMap[op] {
+		count += cnt
+	}
+	return count
+}
+
 func (er *ErrRecorder) Summary() {
 	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
 
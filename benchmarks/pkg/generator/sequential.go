@@ -0,0 +1,31 @@
+package generator
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// Sequential generates a sequence of integers from start to end inclusive,
+// and starts over from start once end is reached.
+type Sequential struct {
+	Number
+	counter  int64
+	start    int64
+	interval int64
+}
+
+// NewSequential creates the Sequential generator.
+func NewSequential(start int64, end int64) *Sequential {
+	return &Sequential{
+		counter:  0,
+		start:    start,
+		interval: end - start + 1,
+	}
+}
+
+// Next implements the Generator Next interface.
+func (s *Sequential) Next(_ *rand.Rand) int64 {
+	n := s.start + (atomic.AddInt64(&s.counter, 1)-1)%s.interval
+	s.SetLastValue(n)
+	return n
+}
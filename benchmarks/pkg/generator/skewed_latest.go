@@ -0,0 +1,32 @@
+package generator
+
+import (
+	"benchmark/ycsb"
+	"math/rand"
+)
+
+// SkewedLatest generates a popularity distribution of items,
+// skewed to favor recent items significantly more than older items.
+// The most recent item is the last value of the basis generator.
+type SkewedLatest struct {
+	Number
+	basis   ycsb.Generator
+	zipfian *Zipfian
+}
+
+// NewSkewedLatest creates the SkewedLatest generator.
+// basis is a Counter or AcknowledgedCounter.
+func NewSkewedLatest(basis ycsb.Generator, zipfianConstant float64) *SkewedLatest {
+	return &SkewedLatest{
+		basis:   basis,
+		zipfian: NewZipfianWithItems(basis.Last(), zipfianConstant),
+	}
+}
+
+// Next implements the Generator Next interface.
+func (s *SkewedLatest) Next(r *rand.Rand) int64 {
+	max := s.basis.Last()
+	next := max - s.zipfian.next(r, max)
+	s.SetLastValue(next)
+	return next
+}
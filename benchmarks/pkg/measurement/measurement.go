@@ -84,8 +84,10 @@ func Summary() {
 }
 
 // EnableWarmUp sets whether to enable warm-up.
+// A warm-up enabled by it only ends by calling it again.
 func EnableWarmUp(b bool) {
 	if b {
+		autoWarmUp.Store(nil)
 		atomic.StoreInt32(&warmUp, 1)
 	} else {
 		atomic.StoreInt32(&warmUp, 0)
@@ -97,12 +99,51 @@ func IsWarmUpFinished() bool {
 	return atomic.LoadInt32(&warmUp) == 0
 }
 
+// SetSink sets the sink that receives every measured latency.
+// It should be called before the benchmark starts.
+func SetSink(s MeasurementSink) {
+	if s == nil {
+		s = NewNoopSink()
+	}
+	globalSink = s
+}
+
+// FlushSink flushes the latencies buffered in the sink.
+func FlushSink() {
+	globalSink.Flush()
+}
+
+// FlushSinkEvery flushes the sink every interval until the returned function is called.
+func FlushSinkEvery(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				FlushSink()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+		FlushSink()
+	}
+}
+
 // Measure measures the operation.
 func Measure(op string, start time.Time, lan time.Duration) {
-	if IsWarmUpFinished() {
-		globalMeasure.measure(op, start, lan)
+	if !IsWarmUpFinished() {
+		observeWarmUp(op, lan)
+		return
 	}
+	globalMeasure.measure(op, start, lan)
+	globalSink.RecordLatency(op, lan)
 }
 
 var globalMeasure *measurement
+var globalSink MeasurementSink = NewNoopSink()
 var warmUp int32 // use as bool, 1 means in warmup progress, 0 means warmup finished.
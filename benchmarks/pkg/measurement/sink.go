@@ -0,0 +1,156 @@
+package measurement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MeasurementSink receives every measured latency as it happens,
+// so that long benchmarks can be watched while they are running.
+// Implementations must be safe for concurrent use.
+type MeasurementSink interface {
+	RecordLatency(op string, d time.Duration)
+
+	// Flush pushes the latencies recorded since the last flush.
+	Flush()
+}
+
+var (
+	_ MeasurementSink = (*noopSink)(nil)
+	_ MeasurementSink = (*stdoutSink)(nil)
+	_ MeasurementSink = (*statsdSink)(nil)
+)
+
+// NewSink creates the sink selected by name, one of none, stdout and statsd.
+// statsdAddr is the address of the statsd server used by the statsd sink.
+func NewSink(name string, statsdAddr string) (MeasurementSink, error) {
+	switch name {
+	case "", "none":
+		return NewNoopSink(), nil
+	case "stdout":
+		return NewStdoutSink(os.Stdout), nil
+	case "statsd":
+		return NewStatsdSink(statsdAddr)
+	default:
+		return nil, fmt.Errorf("unsupported measurement sink %q, expect none, stdout or statsd", name)
+	}
+}
+
+type noopSink struct{}
+
+func NewNoopSink() MeasurementSink {
+	return &noopSink{}
+}
+
+func (s *noopSink) RecordLatency(op string, d time.Duration) {}
+
+func (s *noopSink) Flush() {}
+
+type sinkStat struct {
+	count int64
+	total time.Duration
+	max   time.Duration
+}
+
+// stdoutSink prints the count and latency of every operation
+// recorded since the last flush.
+type stdoutSink struct {
+	mu    sync.Mutex
+	w     io.Writer
+	stats map[string]*sinkStat
+}
+
+func NewStdoutSink(w io.Writer) MeasurementSink {
+	return &stdoutSink{
+		w:     w,
+		stats: make(map[string]*sinkStat),
+	}
+}
+
+func (s *stdoutSink) RecordLatency(op string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat, ok := s.stats[op]
+	if !ok {
+		stat = &sinkStat{}
+		s.stats[op] = stat
+	}
+	stat.count++
+	stat.total += d
+	stat.max = max(stat.max, d)
+}
+
+func (s *stdoutSink) Flush() {
+	s.mu.Lock()
+	stats := s.stats
+	s.stats = make(map[string]*sinkStat)
+	s.mu.Unlock()
+
+	ops := make([]string, 0, len(stats))
+	for op := range stats {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	now := time.Now().Format(time.TimeOnly)
+	for _, op := range ops {
+		stat := stats[op]
+		fmt.Fprintf(s.w, "%s %-6s - Count: %d, Avg(us): %d, Max(us): %d\n", now, op,
+			stat.count, (stat.total / time.Duration(stat.count)).Microseconds(), stat.max.Microseconds())
+	}
+}
+
+// statsdMaxPacketSize keeps a statsd packet within a single Ethernet frame.
+const statsdMaxPacketSize = 1400
+
+// statsdSink sends the latencies as statsd timers over UDP.
+// The timers are batched into packets which are sent when full or flushed.
+type statsdSink struct {
+	mu   sync.Mutex
+	conn net.Conn
+	buf  bytes.Buffer
+}
+
+func NewStatsdSink(addr string) (MeasurementSink, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("statsd address should be specified")
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd at %s: %v", addr, err)
+	}
+	return &statsdSink{conn: conn}, nil
+}
+
+func (s *statsdSink) RecordLatency(op string, d time.Duration) {
+	line := fmt.Sprintf("oreo.%s:%.3f|ms\n", op, float64(d.Microseconds())/1000)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buf.Len()+len(line) > statsdMaxPacketSize {
+		s.send()
+	}
+	s.buf.WriteString(line)
+}
+
+func (s *statsdSink) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.send()
+}
+
+// send writes the buffered timers as one packet.
+// The caller must hold mu.
+func (s *statsdSink) send() {
+	if s.buf.Len() == 0 {
+		return
+	}
+	// metrics are best-effort, a lost packet only leaves a gap in the dashboard
+	_, _ = s.conn.Write(s.buf.Bytes())
+	s.buf.Reset()
+}
@@ -0,0 +1,109 @@
+package measurement
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureSink keeps every latency it receives.
+type captureSink struct {
+	mu      sync.Mutex
+	ops     []string
+	flushes int
+}
+
+func (s *captureSink) RecordLatency(op string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops = append(s.ops, op)
+}
+
+func (s *captureSink) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+}
+
+func TestMeasureRecordsToSink(t *testing.T) {
+	sink := &captureSink{}
+	InitMeasure()
+	SetSink(sink)
+	defer SetSink(nil)
+
+	// nothing is pushed during the warm-up
+	EnableWarmUp(true)
+	Measure("READ", time.Now(), time.Millisecond)
+
+	EnableWarmUp(false)
+	Measure("READ", time.Now(), time.Millisecond)
+	Measure("UPDATE", time.Now(), 2*time.Millisecond)
+	FlushSink()
+
+	expected := []string{"READ", "UPDATE"}
+	if strings.Join(sink.ops, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the sink to receive %v, got %v", expected, sink.ops)
+	}
+	if sink.flushes != 1 {
+		t.Errorf("expected the sink to be flushed once, got %d", sink.flushes)
+	}
+}
+
+func TestStdoutSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewStdoutSink(&buf)
+	sink.RecordLatency("READ", time.Millisecond)
+	sink.RecordLatency("READ", 3*time.Millisecond)
+	sink.Flush()
+
+	out := buf.String()
+	if !strings.Contains(out, "Count: 2, Avg(us): 2000, Max(us): 3000") {
+		t.Errorf("unexpected output: %q", out)
+	}
+
+	// the stats are reset after a flush
+	buf.Reset()
+	sink.Flush()
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be printed, got %q", buf.String())
+	}
+}
+
+func TestStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSink("statsd", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to create the sink: %v", err)
+	}
+	sink.RecordLatency("READ", 1500*time.Microsecond)
+	sink.RecordLatency("COMMIT", 2*time.Millisecond)
+	sink.Flush()
+
+	packet := make([]byte, statsdMaxPacketSize)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(packet)
+	if err != nil {
+		t.Fatalf("failed to read the packet: %v", err)
+	}
+	expected := "oreo.READ:1.500|ms\noreo.COMMIT:2.000|ms\n"
+	if string(packet[:n]) != expected {
+		t.Errorf("expected packet %q, got %q", expected, packet[:n])
+	}
+}
+
+func TestNewSinkUnsupported(t *testing.T) {
+	if _, err := NewSink("influx", ""); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := NewSink("statsd", ""); err == nil {
+		t.Errorf("expected an error without the statsd address")
+	}
+}
@@ -0,0 +1,156 @@
+package measurement
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// steadyWindows is how many consecutive windows must have close mean
+// latencies for the benchmark to be in a steady state.
+const steadyWindows = 3
+
+// DefaultWarmUpOp is the operation observed by the auto warm-up if
+// AutoWarmUpConfig.Op is unset: the transactions, whose latencies do not
+// depend on the mix of the operations they run from one window to another.
+const DefaultWarmUpOp = "TXN"
+
+// AutoWarmUpConfig describes how the end of the warm-up is detected
+// from the latencies of the operations, instead of a fixed period.
+type AutoWarmUpConfig struct {
+	// Op is the operation whose latencies are observed, DefaultWarmUpOp
+	// if unset. The benchmarks of databases without transactions set it
+	// to one of the operations they measure, such as READ.
+	Op string `yaml:"op"`
+	// Window is the number of Op operations whose mean latency is compared
+	// to that of the windows before it. The auto warm-up is off if unset.
+	Window int `yaml:"window"`
+	// Threshold is the highest coefficient of variation, i.e. the standard
+	// deviation over the mean, of the mean latencies of the last windows
+	// for the latency to be considered stable.
+	Threshold float64 `yaml:"threshold"`
+	// MaxOps is the number of Op operations after which the measurement
+	// starts even if the latency has not stabilized, no limit if unset.
+	MaxOps int64 `yaml:"max_ops"`
+}
+
+// Enabled reports whether the end of the warm-up is detected automatically.
+func (c AutoWarmUpConfig) Enabled() bool {
+	return c.Window > 0
+}
+
+// ObservedOp returns the operation observed by the auto warm-up.
+func (c AutoWarmUpConfig) ObservedOp() string {
+	if c.Op == "" {
+		return DefaultWarmUpOp
+	}
+	return c.Op
+}
+
+// Validate checks that the steady state can be detected as described by c.
+func (c AutoWarmUpConfig) Validate() error {
+	if c.Window < 0 {
+		return fmt.Errorf("window %d is negative", c.Window)
+	}
+	if c.Enabled() && c.Threshold <= 0 {
+		return fmt.Errorf("threshold %v is not positive", c.Threshold)
+	}
+	if c.MaxOps < 0 {
+		return fmt.Errorf("max ops %d is negative", c.MaxOps)
+	}
+	return nil
+}
+
+// steadyStateDetector tells when the latencies of the operations have
+// stabilized: the mean latencies of the last steadyWindows windows of
+// operations vary less than the threshold.
+type steadyStateDetector struct {
+	cfg AutoWarmUpConfig
+
+	mu       sync.Mutex
+	observed int64
+	count    int
+	sum      time.Duration
+	means    []float64
+}
+
+func newSteadyStateDetector(cfg AutoWarmUpConfig) *steadyStateDetector {
+	return &steadyStateDetector{cfg: cfg, means: make([]float64, 0, steadyWindows)}
+}
+
+// observe adds the latency of an operation run during the warm-up
+// and reports whether the steady state has been reached.
+func (d *steadyStateDetector) observe(latency time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.observed++
+	if d.cfg.MaxOps > 0 && d.observed >= d.cfg.MaxOps {
+		return true
+	}
+	d.count++
+	d.sum += latency
+	if d.count < d.cfg.Window {
+		return false
+	}
+
+	if len(d.means) == steadyWindows {
+		d.means = d.means[1:]
+	}
+	d.means = append(d.means, float64(d.sum)/float64(d.count))
+	d.count, d.sum = 0, 0
+	return len(d.means) == steadyWindows && variation(d.means) <= d.cfg.Threshold
+}
+
+// variation returns the coefficient of variation of values.
+func variation(values []float64) float64 {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance/float64(len(values))) / mean
+}
+
+// EnableAutoWarmUp starts a warm-up that ends by itself once the latencies
+// of the operations of type cfg.Op have stabilized as described by cfg.
+// The operations of every type measured until then are discarded.
+func EnableAutoWarmUp(cfg AutoWarmUpConfig) {
+	EnableWarmUp(true)
+	autoWarmUp.Store(newSteadyStateDetector(cfg))
+}
+
+// WarmUpOps returns the number of operations of the observed type
+// discarded by the last auto warm-up so far.
+func WarmUpOps() int64 {
+	d := autoWarmUp.Load()
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.observed
+}
+
+// observeWarmUp ends the auto warm-up, if any, once the steady state
+// is reached. Only the latencies of the observed operation count.
+func observeWarmUp(op string, latency time.Duration) {
+	d := autoWarmUp.Load()
+	if d == nil || op != d.cfg.ObservedOp() {
+		return
+	}
+	if d.observe(latency) {
+		EnableWarmUp(false)
+	}
+}
+
+var autoWarmUp atomic.Pointer[steadyStateDetector]
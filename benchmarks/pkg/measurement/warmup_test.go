@@ -0,0 +1,119 @@
+package measurement
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestAutoWarmUpStartsAtSteadyState(t *testing.T) {
+	const (
+		stableAfter = 1000
+		window      = 100
+	)
+	sink := &captureSink{}
+	InitMeasure()
+	SetSink(sink)
+	defer SetSink(nil)
+	defer EnableWarmUp(false)
+
+	EnableAutoWarmUp(AutoWarmUpConfig{Window: window, Threshold: 0.05})
+	r := rand.New(rand.NewSource(1))
+	started := -1
+	for i := 0; i < 3000; i++ {
+		// the latency falls from 11ms to 1ms, then jitters by 2% around 1ms
+		latency := time.Millisecond + time.Duration(r.NormFloat64()*20)*time.Microsecond
+		if i < stableAfter {
+			latency += time.Duration(stableAfter-i) * 10 * time.Microsecond
+		}
+		Measure("TXN", time.Now(), latency)
+		if started < 0 && IsWarmUpFinished() {
+			started = i + 1
+		}
+	}
+
+	if started < stableAfter || started > stableAfter+steadyWindows*window {
+		t.Fatalf("expected the measurement to start within %d ops after op %d, started after op %d",
+			steadyWindows*window, stableAfter, started)
+	}
+	if got := WarmUpOps(); got != int64(started) {
+		t.Errorf("expected %d ops to be discarded, got %d", started, got)
+	}
+	if len(sink.ops) != 3000-started {
+		t.Errorf("expected %d ops to be measured, got %d", 3000-started, len(sink.ops))
+	}
+}
+
+func TestAutoWarmUpMaxOps(t *testing.T) {
+	InitMeasure()
+	defer EnableWarmUp(false)
+
+	EnableAutoWarmUp(AutoWarmUpConfig{Window: 10, Threshold: 0.01, MaxOps: 50})
+	for i := 0; i < 49; i++ {
+		// never stable
+		Measure("TXN", time.Now(), time.Duration(i+1)*time.Millisecond)
+	}
+	if IsWarmUpFinished() {
+		t.Fatal("expected the warm-up to go on while the latency is unstable")
+	}
+	Measure("TXN", time.Now(), time.Millisecond)
+	if !IsWarmUpFinished() {
+		t.Error("expected the warm-up to end after max ops")
+	}
+
+	// a manual warm-up is not ended by the latencies
+	EnableWarmUp(true)
+	for i := 0; i < 100; i++ {
+		Measure("TXN", time.Now(), time.Millisecond)
+	}
+	if IsWarmUpFinished() {
+		t.Error("expected the manual warm-up to go on")
+	}
+}
+
+func TestAutoWarmUpObservesOneOperation(t *testing.T) {
+	InitMeasure()
+	defer EnableWarmUp(false)
+
+	EnableAutoWarmUp(AutoWarmUpConfig{Window: 10, Threshold: 0.01, MaxOps: 20})
+	for i := 0; i < 100; i++ {
+		// the operations of a transaction do not count
+		Measure("READ", time.Now(), time.Millisecond)
+		Measure("COMMIT", time.Now(), time.Millisecond)
+	}
+	if IsWarmUpFinished() || WarmUpOps() != 0 {
+		t.Fatalf("expected only the transactions to be observed, observed %d ops", WarmUpOps())
+	}
+	for i := 0; i < 20; i++ {
+		Measure("TXN", time.Now(), time.Duration(i+1)*time.Millisecond)
+	}
+	if !IsWarmUpFinished() {
+		t.Error("expected the warm-up to end after max ops")
+	}
+
+	EnableAutoWarmUp(AutoWarmUpConfig{Op: "READ", Window: 10, Threshold: 0.01})
+	for i := 0; i < steadyWindows*10; i++ {
+		Measure("TXN", time.Now(), time.Duration(i+1)*time.Millisecond)
+		Measure("READ", time.Now(), time.Millisecond)
+	}
+	if !IsWarmUpFinished() {
+		t.Error("expected the steady reads to end the warm-up")
+	}
+}
+
+func TestAutoWarmUpConfigValidate(t *testing.T) {
+	for _, tt := range []struct {
+		cfg   AutoWarmUpConfig
+		valid bool
+	}{
+		{AutoWarmUpConfig{}, true},
+		{AutoWarmUpConfig{Window: 100, Threshold: 0.05}, true},
+		{AutoWarmUpConfig{Window: 100}, false},
+		{AutoWarmUpConfig{Window: -1}, false},
+		{AutoWarmUpConfig{Window: 100, Threshold: 0.05, MaxOps: -1}, false},
+	} {
+		if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%+v) = %v, expected valid %v", tt.cfg, err, tt.valid)
+		}
+	}
+}
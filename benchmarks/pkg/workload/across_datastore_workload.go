@@ -73,11 +73,13 @@ func (wl *AcrossDatastoreWorkload) Load(ctx context.Context, opCount int,
 func (wl *AcrossDatastoreWorkload) Run(ctx context.Context, opCount int,
 	db ycsb.DB) {
 	for i := 0; i < opCount; i++ {
-		if wl.wp.DBName == "oreo" {
-			_ = wl.doInOreo(ctx, db)
-		} else {
-			_ = wl.doInOthers(ctx, db)
-		}
+		runTxn(ctx, func() {
+			if wl.wp.DBName == "oreo" {
+				_ = wl.doInOreo(ctx, db)
+			} else {
+				_ = wl.doInOthers(ctx, db)
+			}
+		})
 	}
 }
 
@@ -5,19 +5,86 @@ import (
 	"benchmark/ycsb"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
+// ConsistencyReport is the outcome of the post-check of a workload
+// whose transactions must keep the total amount unchanged.
+type ConsistencyReport struct {
+	DBName string
+	// Committed and Aborted count the transfer transactions.
+	Committed int64
+	Aborted   int64
+	// ReadErrors counts the keys the post-check failed to read.
+	ReadErrors int64
+	// FailedKeys are the keys the post-check failed to read or parse,
+	// whose balances are missing from MeasuredTotal.
+	FailedKeys    []string
+	ExpectedTotal int
+	MeasuredTotal int
+}
+
+// Passed reports whether every key was read and the total amount is kept.
+func (r ConsistencyReport) Passed() bool {
+	return r.ReadErrors == 0 && r.MeasuredTotal == r.ExpectedTotal
+}
+
+func (r ConsistencyReport) String() string {
+	result := "PASS"
+	if !r.Passed() {
+		result = "FAIL"
+	}
+	out := fmt.Sprintf("%s: %s\nCommitted: %d, Aborted: %d, Read Errors: %d\n"+
+		"Expected Amount: %v\nCurrent  Amount: %v\n",
+		r.DBName, result, r.Committed, r.Aborted, r.ReadErrors,
+		r.ExpectedTotal, r.MeasuredTotal)
+	if diff := r.MeasuredTotal - r.ExpectedTotal; diff != 0 {
+		out += fmt.Sprintf("Difference     : %+d\n", diff)
+	}
+	if len(r.FailedKeys) > 0 {
+		keys := r.FailedKeys
+		if len(keys) > maxReportedKeys {
+			keys = keys[:maxReportedKeys]
+		}
+		out += fmt.Sprintf("Failed Keys    : %v", keys)
+		if more := len(r.FailedKeys) - len(keys); more > 0 {
+			out += fmt.Sprintf(" and %d more", more)
+		}
+		out += "\n"
+	}
+	return out
+}
+
+// maxReportedKeys is how many failed keys a ConsistencyReport prints.
+const maxReportedKeys = 10
+
+// ConsistencyChecker is implemented by the workloads
+// whose post-check verifies an invariant.
+type ConsistencyChecker interface {
+	ConsistencyReport() ConsistencyReport
+}
+
 type DataConsistencyWorkload struct {
 	mu                  sync.Mutex
 	currentTotalAmount  int
 	expectedTotalAmount int
 
+	committed  atomic.Int64
+	aborted    atomic.Int64
+	readErrors atomic.Int64
+	failedKeys []string
+
 	Randomizer
 	wp *WorkloadParameter
 }
 
-var _ Workload = (*DataConsistencyWorkload)(nil)
+var (
+	_ Workload           = (*DataConsistencyWorkload)(nil)
+	_ ConsistencyChecker = (*DataConsistencyWorkload)(nil)
+	_ PooledPostChecker  = (*DataConsistencyWorkload)(nil)
+)
 
 func NewDataConsistencyWorkload(wp *WorkloadParameter) *DataConsistencyWorkload {
 	return &DataConsistencyWorkload{
@@ -57,7 +124,9 @@ func (wl *DataConsistencyWorkload) Load(ctx context.Context, opCount int,
 func (wl *DataConsistencyWorkload) Run(ctx context.Context, opCount int,
 	db ycsb.DB) {
 	for i := 0; i <= opCount; i++ {
-		_ = wl.doAccountTransaction(ctx, db)
+		runTxn(ctx, func() {
+			_ = wl.doAccountTransaction(ctx, db)
+		})
 	}
 }
 
@@ -80,6 +149,11 @@ func (wl *DataConsistencyWorkload) PostCheck(ctx context.Context, db ycsb.DB,
 		valueStr, err := db.Read(ctx, wl.wp.TableName, dbKey)
 		if err != nil {
 			fmt.Printf("Error when reading data: %v\n", err)
+			wl.readErrors.Add(1)
+			wl.mu.Lock()
+			wl.failedKeys = append(wl.failedKeys, dbKey)
+			wl.mu.Unlock()
+			continue
 		}
 		value := util.ToInt(valueStr)
 		totalAmount += int(value)
@@ -93,10 +167,43 @@ func (wl *DataConsistencyWorkload) PostCheck(ctx context.Context, db ycsb.DB,
 	wl.mu.Unlock()
 }
 
+// RunPostCheck sums the balances of all the RecordCount keys with a pool
+// of PostCheckWorkerThread workers, the keys being shared out as the
+// workers get through them instead of being split up front.
+func (wl *DataConsistencyWorkload) RunPostCheck(ctx context.Context,
+	newDB func() (ycsb.DB, error)) error {
+	wl.ResetKeySequence()
+	keys := make([]string, wl.wp.RecordCount)
+	for i := range keys {
+		keys[i] = wl.NextKeyNameFromSequence()
+	}
+	sum, err := SumBalances(ctx, wl.wp.TableName, keys, wl.wp.PostCheckWorkerThread, newDB)
+
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.currentTotalAmount += sum.Total
+	wl.readErrors.Add(int64(len(sum.FailedKeys)))
+	wl.failedKeys = append(wl.failedKeys, sum.FailedKeys...)
+	return err
+}
+
 func (wl *DataConsistencyWorkload) DisplayCheckResult() {
 	fmt.Println("---------------")
-	fmt.Printf("%s:\nExpected Amount: %v\nCurrent  Amount: %v\n",
-		wl.wp.DBName, wl.expectedTotalAmount, wl.currentTotalAmount)
+	fmt.Print(wl.ConsistencyReport())
+}
+
+func (wl *DataConsistencyWorkload) ConsistencyReport() ConsistencyReport {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	return ConsistencyReport{
+		DBName:        wl.wp.DBName,
+		Committed:     wl.committed.Load(),
+		Aborted:       wl.aborted.Load(),
+		ReadErrors:    wl.readErrors.Load(),
+		FailedKeys:    slices.Clone(wl.failedKeys),
+		ExpectedTotal: wl.expectedTotalAmount,
+		MeasuredTotal: wl.currentTotalAmount,
+	}
 }
 
 func (wl *DataConsistencyWorkload) doAccountTransaction(ctx context.Context, db ycsb.DB) error {
@@ -108,6 +215,22 @@ func (wl *DataConsistencyWorkload) doAccountTransaction(ctx context.Context, db
 		return nil
 	}
 
+	err := wl.transfer(ctx, db, key1, key2, transferAmount)
+	if err != nil {
+		wl.aborted.Add(1)
+		if txnDB, ok := db.(ycsb.TransactionDB); ok {
+			_ = txnDB.Abort()
+		}
+		return err
+	}
+	wl.committed.Add(1)
+	return nil
+}
+
+// transfer moves transferAmount from the poorer key to the richer one,
+// from key2 if both hold the same amount.
+func (wl *DataConsistencyWorkload) transfer(ctx context.Context, db ycsb.DB,
+	key1 string, key2 string, transferAmount int) error {
 	if txnDB, ok := db.(ycsb.TransactionDB); ok {
 		txnDB.Start()
 	}
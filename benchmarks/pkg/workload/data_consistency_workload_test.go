@@ -0,0 +1,216 @@
+package workload
+
+import (
+	"benchmark/ycsb"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	dcRecordCount   = 10
+	dcInitialAmount = 100
+	dcTransfer      = 7
+)
+
+func newTestDataConsistencyWorkload() *DataConsistencyWorkload {
+	return NewDataConsistencyWorkload(&WorkloadParameter{
+		DBName:                "memory",
+		TableName:             "table",
+		RecordCount:           dcRecordCount,
+		KeyDistribution:       Uniform,
+		Seed:                  1,
+		InitialAmountPerKey:   dcInitialAmount,
+		TransferAmountPerTxn:  dcTransfer,
+		TotalAmount:           dcRecordCount * dcInitialAmount,
+		PostCheckWorkerThread: 1,
+	})
+}
+
+// lostUpdateDB drops the second write of its first transaction,
+// so that the amount moved by that transfer is lost.
+type lostUpdateDB struct {
+	*memTxnDB
+	updates int
+}
+
+func (db *lostUpdateDB) Update(ctx context.Context, table string, key string, value string) error {
+	db.updates++
+	if db.updates == 2 {
+		return nil
+	}
+	return db.memTxnDB.Update(ctx, table, key, value)
+}
+
+func TestDataConsistencyReport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("the total amount is kept", func(t *testing.T) {
+		wl := newTestDataConsistencyWorkload()
+		db := newMemTxnDB()
+		wl.Load(ctx, dcRecordCount, db)
+		wl.Run(ctx, 20, db)
+		wl.ResetKeySequence()
+		wl.PostCheck(ctx, db, nil)
+
+		report := wl.ConsistencyReport()
+		if !report.Passed() {
+			t.Errorf("expected the check to pass, got %s", report)
+		}
+		if report.Committed == 0 {
+			t.Errorf("expected some transfers to commit")
+		}
+		if report.Aborted != 0 {
+			t.Errorf("expected no transfer to abort, got %d", report.Aborted)
+		}
+	})
+
+	t.Run("a lost update is flagged", func(t *testing.T) {
+		wl := newTestDataConsistencyWorkload()
+		mem := newMemTxnDB()
+		wl.Load(ctx, dcRecordCount, mem)
+		wl.Run(ctx, 20, &lostUpdateDB{memTxnDB: mem})
+		wl.ResetKeySequence()
+		wl.PostCheck(ctx, mem, nil)
+
+		report := wl.ConsistencyReport()
+		if report.Passed() {
+			t.Fatalf("expected the check to fail, got %s", report)
+		}
+		diff := report.MeasuredTotal - report.ExpectedTotal
+		if diff != dcTransfer && diff != -dcTransfer {
+			t.Errorf("expected the total to be off by %d, got %d", dcTransfer, diff)
+		}
+	})
+
+	t.Run("an unreadable key is flagged", func(t *testing.T) {
+		wl := newTestDataConsistencyWorkload()
+		db := newMemTxnDB()
+		wl.Load(ctx, dcRecordCount-1, db)
+		wl.ResetKeySequence()
+		wl.PostCheck(ctx, db, nil)
+
+		report := wl.ConsistencyReport()
+		if report.Passed() {
+			t.Fatalf("expected the check to fail, got %s", report)
+		}
+		if report.ReadErrors != 1 {
+			t.Errorf("expected 1 read error, got %d", report.ReadErrors)
+		}
+	})
+}
+
+func TestDataConsistencyTransfer(t *testing.T) {
+	ctx := context.Background()
+	wl := newTestDataConsistencyWorkload()
+	db := newMemTxnDB()
+	db.records["tablepoor"] = "10"
+	db.records["tablerich"] = "20"
+
+	if err := wl.transfer(ctx, db, "poor", "rich", dcTransfer); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if db.records["tablepoor"] != "3" || db.records["tablerich"] != "27" {
+		t.Errorf("expected the amount to move from the poorer key to the richer one, got %v", db.records)
+	}
+
+	if err := wl.transfer(ctx, db, "rich", "poor", dcTransfer); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if db.records["tablepoor"] != "-4" || db.records["tablerich"] != "34" {
+		t.Errorf("expected the amount to move from the poorer key to the richer one, got %v", db.records)
+	}
+}
+
+func TestDataConsistencyRunPostCheck(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("the pool reports a match", func(t *testing.T) {
+		wl := newTestDataConsistencyWorkload()
+		// the keys are not evenly divisible among the workers
+		wl.wp.PostCheckWorkerThread = 3
+		db := newMemTxnDB()
+		wl.Load(ctx, dcRecordCount, db)
+		wl.Run(ctx, 20, db)
+
+		var workers atomic.Int32
+		err := wl.RunPostCheck(ctx, func() (ycsb.DB, error) {
+			workers.Add(1)
+			return db.NewTransaction(), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := workers.Load(); n != 3 {
+			t.Errorf("expected 3 workers, got %d", n)
+		}
+		report := wl.ConsistencyReport()
+		if !report.Passed() {
+			t.Errorf("expected the check to pass, got %s", report)
+		}
+	})
+
+	t.Run("a worker failing to start leaves its keys to the others", func(t *testing.T) {
+		wl := newTestDataConsistencyWorkload()
+		wl.wp.PostCheckWorkerThread = 3
+		db := newMemTxnDB()
+		wl.Load(ctx, dcRecordCount, db)
+
+		var workers atomic.Int32
+		err := wl.RunPostCheck(ctx, func() (ycsb.DB, error) {
+			if workers.Add(1) == 1 {
+				return nil, errors.New("no connection")
+			}
+			return db.NewTransaction(), nil
+		})
+		if err == nil || !strings.Contains(err.Error(), "no connection") {
+			t.Errorf("expected the start failure to be returned, got %v", err)
+		}
+		report := wl.ConsistencyReport()
+		if report.ReadErrors != 0 || report.MeasuredTotal != report.ExpectedTotal {
+			t.Errorf("expected every key to be read by the other workers, got %s", report)
+		}
+	})
+
+	t.Run("the check returns if no worker starts", func(t *testing.T) {
+		wl := newTestDataConsistencyWorkload()
+		wl.wp.PostCheckWorkerThread = 2
+		err := wl.RunPostCheck(ctx, func() (ycsb.DB, error) {
+			return nil, errors.New("no connection")
+		})
+		if err == nil {
+			t.Errorf("expected the start failure to be returned")
+		}
+	})
+
+	t.Run("the failed keys are reported", func(t *testing.T) {
+		wl := newTestDataConsistencyWorkload()
+		wl.wp.PostCheckWorkerThread = 4
+		db := newMemTxnDB()
+		wl.Load(ctx, dcRecordCount-1, db)
+
+		err := wl.RunPostCheck(ctx, func() (ycsb.DB, error) {
+			return db.NewTransaction(), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		report := wl.ConsistencyReport()
+		if report.Passed() {
+			t.Fatalf("expected the check to fail, got %s", report)
+		}
+		missing := wl.buildKeyName(dcRecordCount - 1)
+		if !reflect.DeepEqual(report.FailedKeys, []string{missing}) {
+			t.Errorf("expected %s to be reported, got %v", missing, report.FailedKeys)
+		}
+		if want := (dcRecordCount - 1) * dcInitialAmount; report.MeasuredTotal != want {
+			t.Errorf("expected a measured total of %d, got %d", want, report.MeasuredTotal)
+		}
+		if !strings.Contains(report.String(), missing) {
+			t.Errorf("expected the report to name %s, got %s", missing, report)
+		}
+	})
+}
@@ -8,7 +8,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math/rand"
 	"sync"
 )
 
@@ -121,16 +120,18 @@ func (wl *IotWorkload) Run(ctx context.Context, opCount int,
 		return
 	}
 	for i := 0; i < opCount; i++ {
-		switch wl.NextTask() {
-		case 1:
-			wl.DataIngestion(ctx, txnDB)
-		case 2:
-			wl.DataProcessing(ctx, txnDB)
-		case 3:
-			wl.DataQuery(ctx, txnDB)
-		default:
-			panic("Invalid task")
-		}
+		runTxn(ctx, func() {
+			switch wl.NextTask() {
+			case 1:
+				wl.DataIngestion(ctx, txnDB)
+			case 2:
+				wl.DataProcessing(ctx, txnDB)
+			case 3:
+				wl.DataQuery(ctx, txnDB)
+			default:
+				panic("Invalid task")
+			}
+		})
 	}
 
 }
@@ -171,7 +172,6 @@ func (wl *IotWorkload) NextTask() int64 {
 }
 
 func (wl *IotWorkload) RandomValue() string {
-	// value := wl.r.Intn(10000)
-	value := rand.Intn(10000)
+	value := wl.Intn(10000)
 	return util.ToString(value)
 }
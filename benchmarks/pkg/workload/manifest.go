@@ -0,0 +1,194 @@
+package workload
+
+import (
+	"benchmark/ycsb"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+)
+
+// ManifestKey is the key under which the load phase stores its manifest
+// in every table it has written, next to the records it describes, so that
+// the run phase finds it missing if the data has been dropped since.
+const ManifestKey = "__load_manifest__"
+
+// Manifest describes the key space written by the load phase, so that the
+// run phase can check that it accesses the keys the load has written
+// instead of re-deriving them from its own parameters.
+type Manifest struct {
+	WorkloadName string `json:"workload_name"`
+	TableName    string `json:"table_name"`
+	RecordCount  int    `json:"record_count"`
+	// KeyStart and KeyEnd bound the numbers of the loaded keys, KeyEnd excluded.
+	KeyStart int64 `json:"key_start"`
+	KeyEnd   int64 `json:"key_end"`
+	// DBNames are the databases loaded.
+	DBNames []string `json:"db_names"`
+	// Distribution is the share of the keys of each datastore
+	// for the workloads spreading them over several datastores.
+	Distribution map[string]float64 `json:"distribution,omitempty"`
+	// FailedOps is the number of inserts, commits and bulk loads
+	// that failed during the load phase, which leave keys missing.
+	FailedOps int `json:"failed_ops"`
+}
+
+// NewManifest describes the key space that the load phase
+// of wp writes to the databases dbNames.
+func NewManifest(wp *WorkloadParameter, dbNames []string) Manifest {
+	dbNames = slices.Clone(dbNames)
+	slices.Sort(dbNames)
+	return Manifest{
+		WorkloadName: wp.WorkloadName,
+		TableName:    wp.TableName,
+		RecordCount:  wp.RecordCount,
+		KeyStart:     0,
+		KeyEnd:       int64(wp.RecordCount),
+		DBNames:      dbNames,
+		Distribution: datastoreDistribution(wp),
+	}
+}
+
+// datastoreDistribution returns the positive datastore proportions of wp,
+// keyed by the datastore name in the `oreo` tag of each field.
+func datastoreDistribution(wp *WorkloadParameter) map[string]float64 {
+	value := reflect.ValueOf(*wp)
+	typ := value.Type()
+	res := make(map[string]float64)
+	for i := 0; i < value.NumField(); i++ {
+		dsName, ok := typ.Field(i).Tag.Lookup("oreo")
+		if !ok || dsName == "" {
+			continue
+		}
+		if proportion := value.Field(i).Float(); proportion > 0 {
+			res[dsName] = proportion
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// Tables returns the tables holding the keys described by m: the datastores
+// the keys are spread over if any, the table of the workload otherwise.
+func (m Manifest) Tables() []string {
+	if len(m.Distribution) == 0 {
+		return []string{m.TableName}
+	}
+	tables := make([]string, 0, len(m.Distribution))
+	for dsName := range m.Distribution {
+		tables = append(tables, dsName)
+	}
+	slices.Sort(tables)
+	return tables
+}
+
+// StoreManifest writes m under ManifestKey to every table of m in db,
+// in a transaction if db supports them.
+func StoreManifest(ctx context.Context, db ycsb.DB, m Manifest) error {
+	bs, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	txnDB, isTxn := db.(ycsb.TransactionDB)
+	if isTxn {
+		if err := txnDB.Start(); err != nil {
+			return err
+		}
+	}
+	for _, table := range m.Tables() {
+		if err := db.Insert(ctx, table, ManifestKey, string(bs)); err != nil {
+			if isTxn {
+				_ = txnDB.Abort()
+			}
+			return err
+		}
+	}
+	if isTxn {
+		return txnDB.Commit()
+	}
+	return nil
+}
+
+// LoadManifest reads the manifest stored under ManifestKey in table of db.
+func LoadManifest(ctx context.Context, db ycsb.DB, table string) (Manifest, error) {
+	var m Manifest
+	txnDB, isTxn := db.(ycsb.TransactionDB)
+	if isTxn {
+		if err := txnDB.Start(); err != nil {
+			return m, err
+		}
+	}
+	value, err := db.Read(ctx, table, ManifestKey)
+	if isTxn {
+		if err != nil {
+			_ = txnDB.Abort()
+		} else {
+			err = txnDB.Commit()
+		}
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return m, fmt.Errorf("invalid manifest in table %s: %v", table, err)
+	}
+	return m, nil
+}
+
+// WriteManifest writes m to the file at path.
+func WriteManifest(path string, m Manifest) error {
+	bs, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, bs, 0o644)
+}
+
+// ReadManifest reads the manifest written to the file at path.
+func ReadManifest(path string) (Manifest, error) {
+	var m Manifest
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return m, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// Check returns an error if the key space loaded as described by m
+// is not the one expected by the run phase, or is incomplete.
+func (m Manifest) Check(expected Manifest) error {
+	if m.FailedOps > 0 {
+		return fmt.Errorf("the load phase has failed %d operations, reload the data", m.FailedOps)
+	}
+	if m.WorkloadName != expected.WorkloadName {
+		return fmt.Errorf("the data is loaded by workload %s, not %s", m.WorkloadName, expected.WorkloadName)
+	}
+	if m.TableName != expected.TableName {
+		return fmt.Errorf("the data is loaded to table %s, not %s", m.TableName, expected.TableName)
+	}
+	if m.RecordCount != expected.RecordCount {
+		return fmt.Errorf("%d records are loaded, but recordcount is %d", m.RecordCount, expected.RecordCount)
+	}
+	if m.KeyStart != expected.KeyStart || m.KeyEnd != expected.KeyEnd {
+		return fmt.Errorf("the keys [%d, %d) are loaded, but the run accesses [%d, %d)",
+			m.KeyStart, m.KeyEnd, expected.KeyStart, expected.KeyEnd)
+	}
+	for _, dbName := range expected.DBNames {
+		if !slices.Contains(m.DBNames, dbName) {
+			return fmt.Errorf("%s is not loaded, only %v are", dbName, m.DBNames)
+		}
+	}
+	if !maps.Equal(m.Distribution, expected.Distribution) {
+		return fmt.Errorf("the data is loaded with the datastore distribution %v, not %v",
+			m.Distribution, expected.Distribution)
+	}
+	return nil
+}
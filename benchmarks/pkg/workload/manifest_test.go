@@ -0,0 +1,112 @@
+package workload
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	wp := &WorkloadParameter{
+		WorkloadName:     "ycsb",
+		TableName:        "usertable",
+		RecordCount:      1000,
+		Redis1Proportion: 0.5,
+		Mongo1Proportion: 0.5,
+	}
+	m := NewManifest(wp, []string{"oreo-redis", "oreo-mongo"})
+	if m.KeyEnd != 1000 {
+		t.Errorf("expected the keys to end at 1000, got %d", m.KeyEnd)
+	}
+	if want := map[string]float64{"Redis": 0.5, "MongoDB1": 0.5}; !reflect.DeepEqual(m.Distribution, want) {
+		t.Errorf("expected the distribution %v, got %v", want, m.Distribution)
+	}
+
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := WriteManifest(path, m); err != nil {
+		t.Fatalf("failed to write the manifest: %v", err)
+	}
+	got, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("failed to read the manifest: %v", err)
+	}
+	if !reflect.DeepEqual(m, got) {
+		t.Errorf("expected %+v, got %+v", m, got)
+	}
+	if err := got.Check(NewManifest(wp, []string{"oreo-mongo", "oreo-redis"})); err != nil {
+		t.Errorf("expected the manifest to match, got %v", err)
+	}
+}
+
+func TestManifestCheck(t *testing.T) {
+	wp := &WorkloadParameter{WorkloadName: "ycsb", TableName: "usertable", RecordCount: 1000}
+	loaded := NewManifest(wp, []string{"redis"})
+
+	withRecordCount := *wp
+	withRecordCount.RecordCount = 2000
+	withDistribution := *wp
+	withDistribution.Redis1Proportion = 1
+	failed := loaded
+	failed.FailedOps = 3
+
+	testCases := []struct {
+		name     string
+		loaded   Manifest
+		expected Manifest
+		errMsg   string
+	}{
+		{"the record count mismatches", loaded, NewManifest(&withRecordCount, []string{"redis"}),
+			"1000 records are loaded, but recordcount is 2000"},
+		{"a database is not loaded", loaded, NewManifest(wp, []string{"mongo"}), "mongo is not loaded"},
+		{"the distribution mismatches", loaded, NewManifest(&withDistribution, []string{"redis"}),
+			"datastore distribution"},
+		{"the load has failed", failed, loaded, "the load phase has failed 3 operations"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.loaded.Check(tc.expected)
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Errorf("expected an error containing %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+
+	if _, err := ReadManifest(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected an error reading a missing manifest")
+	}
+}
+
+func TestManifestStoredInDatabase(t *testing.T) {
+	ctx := context.Background()
+	wp := &WorkloadParameter{
+		WorkloadName:     "ycsb",
+		TableName:        "usertable",
+		RecordCount:      1000,
+		Redis1Proportion: 0.5,
+		Mongo1Proportion: 0.5,
+	}
+	m := NewManifest(wp, []string{"oreo-ycsb"})
+	if want := []string{"MongoDB1", "Redis"}; !reflect.DeepEqual(m.Tables(), want) {
+		t.Errorf("expected the tables %v, got %v", want, m.Tables())
+	}
+
+	db := newMemTxnDB()
+	if err := StoreManifest(ctx, db.NewTransaction(), m); err != nil {
+		t.Fatalf("failed to store the manifest: %v", err)
+	}
+	// the manifest is stored next to the keys of every datastore
+	for _, table := range m.Tables() {
+		got, err := LoadManifest(ctx, db.NewTransaction(), table)
+		if err != nil {
+			t.Fatalf("failed to load the manifest from %s: %v", table, err)
+		}
+		if !reflect.DeepEqual(m, got) {
+			t.Errorf("expected %+v in %s, got %+v", m, table, got)
+		}
+	}
+	if _, err := LoadManifest(ctx, db.NewTransaction(), "usertable"); err == nil {
+		t.Errorf("expected no manifest in a table the keys are not loaded to")
+	}
+}
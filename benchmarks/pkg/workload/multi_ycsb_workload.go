@@ -34,32 +34,24 @@ func (wl *MultiYCSBWorkload) Load(ctx context.Context, opCount int,
 }
 
 func (wl *MultiYCSBWorkload) Run(ctx context.Context, opCount int, db ycsb.DB) {
-	var startTime time.Time
-	for i := 0; i <= opCount; i++ {
-		if i%wl.wp.TxnOperationGroup == 0 {
-			if i != 0 {
-				// txnDB.Commit()
-				measure(startTime, "TxnGroup", nil)
-			}
-			if i != opCount {
-				// txnDB.Start()
-				startTime = time.Now()
-			}
-		}
-		if txnDB, ok := db.(ycsb.TransactionDB); ok {
-			if i%wl.wp.TxnOperationGroup == 0 {
-				if i != 0 {
-					txnDB.Commit()
-				}
-				if i != opCount {
-					txnDB.Start()
-				}
-			}
-		}
-		if i == opCount {
-			break
-		}
+	// every group of TxnOperationGroup operations is one transaction
+	txnSize := max(wl.wp.TxnOperationGroup, 1)
+	for done := 0; done < opCount; done += txnSize {
+		groupSize := min(txnSize, opCount-done)
+		runTxn(ctx, func() {
+			wl.runTxnGroup(ctx, groupSize, db)
+		})
+	}
+}
 
+// runTxnGroup runs groupSize operations in one transaction if db supports them.
+func (wl *MultiYCSBWorkload) runTxnGroup(ctx context.Context, groupSize int, db ycsb.DB) {
+	startTime := time.Now()
+	txnDB, isTxnDB := db.(ycsb.TransactionDB)
+	if isTxnDB {
+		txnDB.Start()
+	}
+	for i := 0; i < groupSize; i++ {
 		dsType := wl.NextDatastore()
 		dsName := wl.datastoreTypeToName(dsType)
 		operation := wl.NextOperation()
@@ -74,10 +66,16 @@ func (wl *MultiYCSBWorkload) Run(ctx context.Context, opCount int, db ycsb.DB) {
 			continue
 		case readModifyWrite:
 			_ = wl.doReadModifyWrite(ctx, db, dsName)
+		case doubleSeqCommit:
+			_ = wl.doDoubleSeqCommit(ctx, db, dsName)
 		default:
 			panic("Unknown operation")
 		}
 	}
+	measure(startTime, "TxnGroup", nil)
+	if isTxnDB {
+		txnDB.Commit()
+	}
 }
 
 func (wl *MultiYCSBWorkload) NeedPostCheck() bool {
@@ -163,55 +161,9 @@ func (wl *MultiYCSBWorkload) doReadModifyWrite(ctx context.Context, db ycsb.DB,
 	return nil
 }
 
-func (wl *MultiYCSBWorkload) doDoubleSeqCommit(ctx context.Context, db ycsb.DB) error {
-	if txnDB, ok := db.(ycsb.TransactionDB); ok {
-		newDB := txnDB.NewTransaction()
-		keyName := "benchmark211"
-		value := wl.BuildRandomValue()
-
-		err := newDB.Start()
-		if err != nil {
-			return err
-		}
-
-		_, err = db.Read(ctx, wl.wp.TableName, keyName)
-		if err != nil {
-			return err
-		}
-
-		err = db.Update(ctx, wl.wp.TableName, keyName, value)
-		if err != nil {
-			return err
-		}
-
-		err = newDB.Commit()
-		if err != nil {
-			return err
-		}
-
-		// Second commit
-		err = newDB.Start()
-		if err != nil {
-			return err
-		}
-
-		_, err = db.Read(ctx, wl.wp.TableName, keyName)
-		if err != nil {
-			return err
-		}
-
-		err = db.Update(ctx, wl.wp.TableName, keyName, value)
-		if err != nil {
-			return err
-		}
-
-		err = newDB.Commit()
-		if err != nil {
-			return err
-		}
-		return nil
-	}
-	return fmt.Errorf("DB does not support transaction")
+func (wl *MultiYCSBWorkload) doDoubleSeqCommit(ctx context.Context, db ycsb.DB, dsName string) error {
+	return runDoubleSeqCommit(ctx, db, dsName, wl.NextKeyName(),
+		wl.BuildRandomValue(), wl.BuildRandomValue())
 }
 
 func (wl *MultiYCSBWorkload) NextKeyName() string {
@@ -178,20 +178,22 @@ func (wl *OrderWorkload) Run(ctx context.Context, opCount int,
 		return
 	}
 	for i := 0; i < opCount; i++ {
-		switch wl.NextTask() {
-		case 1:
-			wl.ProductBrowsing(ctx, txnDB)
-		case 2:
-			wl.OrderPlacement(ctx, txnDB)
-		case 3:
-			wl.InventoryRestocking(ctx, txnDB)
-		case 4:
-			wl.OrderTracking(ctx, txnDB)
-		case 5:
-			wl.CustomerReview(ctx, txnDB)
-		default:
-			panic("Invalid task")
-		}
+		runTxn(ctx, func() {
+			switch wl.NextTask() {
+			case 1:
+				wl.ProductBrowsing(ctx, txnDB)
+			case 2:
+				wl.OrderPlacement(ctx, txnDB)
+			case 3:
+				wl.InventoryRestocking(ctx, txnDB)
+			case 4:
+				wl.OrderTracking(ctx, txnDB)
+			case 5:
+				wl.CustomerReview(ctx, txnDB)
+			default:
+				panic("Invalid task")
+			}
+		})
 	}
 
 }
@@ -2,6 +2,7 @@ package workload
 
 import (
 	"benchmark/pkg/benconfig"
+	"benchmark/pkg/errrecord"
 	"benchmark/ycsb"
 	"context"
 	"fmt"
@@ -39,6 +40,12 @@ func (wl *OreoYCSBWorkload) Load(ctx context.Context, opCount int,
 	}
 
 	dbList := getDatabases(*wl.wp)
+	if bulkDB, ok := db.(ycsb.BulkLoadDB); ok && wl.wp.BulkLoad {
+		if err := wl.doBulkLoad(ctx, bulkDB, dbList, opCount); err != nil {
+			fmt.Printf("Error in Oreo YCSB bulk load: %v\n", err)
+		}
+		return
+	}
 	err := wl.doLoad(ctx, txnDB, dbList, opCount)
 	if err != nil {
 		fmt.Printf("Error in Oreo YCSB Load: %v\n", err)
@@ -103,6 +110,30 @@ func (wl *OreoYCSBWorkload) doLoad(ctx context.Context, db ycsb.TransactionDB, d
 	return aErr
 }
 
+// doBulkLoad loads the same records as doLoad, in batches of
+// MaxLoadBatchSize written directly to every datastore in dbList.
+func (wl *OreoYCSBWorkload) doBulkLoad(ctx context.Context, db ycsb.BulkLoadDB, dbList []string, opCount int) error {
+	var aErr error
+	for loaded := 0; loaded < opCount; loaded += benconfig.MaxLoadBatchSize {
+		batchSize := min(benconfig.MaxLoadBatchSize, opCount-loaded)
+		keys := make([]string, 0, batchSize)
+		values := make([]string, 0, batchSize)
+		for j := 0; j < batchSize; j++ {
+			keys = append(keys, wl.NextKeyNameFromSequence())
+			values = append(values, wl.BuildRandomValue())
+		}
+		for _, dsName := range dbList {
+			if err := db.BulkLoad(ctx, dsName, keys, values); err != nil {
+				// the bulk load bypasses the wrappers recording the failed inserts
+				errrecord.Record("BULK_LOAD", err)
+				aErr = err
+				fmt.Printf("Error in Oreo YCSB bulk load to %s: %v\n", dsName, err)
+			}
+		}
+	}
+	return aErr
+}
+
 func (wl *OreoYCSBWorkload) Run(ctx context.Context, opCount int, db ycsb.DB) {
 
 	txnDB, ok := db.(ycsb.TransactionDB)
@@ -112,7 +143,9 @@ func (wl *OreoYCSBWorkload) Run(ctx context.Context, opCount int, db ycsb.DB) {
 	}
 
 	for i := 0; i < opCount; i++ {
-		wl.doTxn(ctx, txnDB)
+		runTxn(ctx, func() {
+			wl.doTxn(ctx, txnDB)
+		})
 	}
 }
 
@@ -131,6 +164,10 @@ func (wl *OreoYCSBWorkload) doTxn(ctx context.Context, db ycsb.TransactionDB) {
 			_ = wl.doInsert(ctx, db, dsName)
 		case readModifyWrite:
 			_ = wl.doReadModifyWrite(ctx, db, dsName)
+		case doubleSeqCommit:
+			// runs in transactions of its own
+			_ = runDoubleSeqCommit(ctx, db, dsName, wl.NextKeyName(),
+				wl.BuildRandomValue(), wl.BuildRandomValue())
 		case scan:
 			continue
 		default:
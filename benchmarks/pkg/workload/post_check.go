@@ -0,0 +1,137 @@
+package workload
+
+import (
+	"benchmark/ycsb"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// postCheckProgressSteps is how many times the progress of a post-check
+// over at least postCheckProgressMinKeys keys is reported.
+const (
+	postCheckProgressSteps   = 10
+	postCheckProgressMinKeys = 10000
+)
+
+// PooledPostChecker is implemented by the workloads whose post-check runs
+// on a pool of exactly PostCheckWorkerThread workers over the whole key
+// space, each worker reading through its own DB created by newDB.
+type PooledPostChecker interface {
+	RunPostCheck(ctx context.Context, newDB func() (ycsb.DB, error)) error
+}
+
+// BalanceSum is the outcome of SumBalances.
+type BalanceSum struct {
+	Total int
+	// Read is the number of keys whose balance has been summed.
+	Read int
+	// FailedKeys are the keys that could not be read or parsed.
+	FailedKeys []string
+}
+
+// SumBalances sums the integer balances stored under keys in table with
+// a pool of exactly workers goroutines. Every worker reads its share of
+// the keys in one transaction if its DB supports them, so that a worker
+// pool never opens more transactions than it has workers.
+func SumBalances(ctx context.Context, table string, keys []string, workers int,
+	newDB func() (ycsb.DB, error)) (BalanceSum, error) {
+	if workers <= 0 {
+		return BalanceSum{}, fmt.Errorf("the post-check needs at least one worker, got %d", workers)
+	}
+
+	// the keys left once every worker has returned are no longer sent
+	feedCtx, stopFeed := context.WithCancel(ctx)
+	defer stopFeed()
+	keyChan := make(chan string)
+	go func() {
+		defer close(keyChan)
+		for _, key := range keys {
+			select {
+			case keyChan <- key:
+			case <-feedCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		mu    sync.Mutex
+		sum   BalanceSum
+		errs  []error
+		done  atomic.Int64
+		wg    sync.WaitGroup
+		every = int64(len(keys) / postCheckProgressSteps)
+	)
+	if len(keys) < postCheckProgressMinKeys {
+		every = 0
+	}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			total, read, failed, err := sumWorker(ctx, table, keyChan, newDB, func() {
+				if n := done.Add(1); every > 0 && n%every == 0 {
+					fmt.Printf("Post-check progress: %d/%d keys\n", n, len(keys))
+				}
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			sum.Total += total
+			sum.Read += read
+			sum.FailedKeys = append(sum.FailedKeys, failed...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return sum, errs[0]
+	}
+	return sum, ctx.Err()
+}
+
+// sumWorker sums the balances of the keys received from keyChan
+// until it is closed, calling onKey after every key. A worker failing to
+// start returns at once and leaves the keys to the others.
+func sumWorker(ctx context.Context, table string, keyChan <-chan string,
+	newDB func() (ycsb.DB, error), onKey func()) (int, int, []string, error) {
+	db, err := newDB()
+	txnDB, isTxn := db.(ycsb.TransactionDB)
+	if err == nil && isTxn {
+		err = txnDB.Start()
+	}
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("post-check worker failed to start: %w", err)
+	}
+
+	total, read := 0, 0
+	var failed []string
+	for key := range keyChan {
+		onKey()
+		valueStr, err := db.Read(ctx, table, key)
+		if err != nil {
+			fmt.Printf("Error when reading data: %v\n", err)
+			failed = append(failed, key)
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil {
+			fmt.Printf("Error when parsing the balance of %s: %v\n", key, err)
+			failed = append(failed, key)
+			continue
+		}
+		total += value
+		read++
+	}
+
+	if isTxn {
+		if err := txnDB.Commit(); err != nil {
+			return total, read, failed, err
+		}
+	}
+	return total, read, failed, nil
+}
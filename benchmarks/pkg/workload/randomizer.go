@@ -33,41 +33,66 @@ func NewRandomizer(wp *WorkloadParameter) *Randomizer {
 	// fmt.Println("Start NewRandomizer")
 	r := &Randomizer{
 		mu:               sync.Mutex{},
-		r:                rand.New(rand.NewSource(time.Now().UnixNano())),
+		r:                rand.New(rand.NewSource(newSeed(wp.Seed))),
 		operationChooser: createOperationGenerator(wp),
 		datastoreChooser: createDatastoreGenerator(wp),
 		keySequence:      generator.NewCounter(insertStart),
-		keyChooser: generator.NewScrambledZipfian(
-			keyrangeLowerBound,
-			keyrangeUpperBound,
-			benconfig.ZipfianConstant),
+		keyChooser:       createKeyGenerator(wp.KeyDistribution, keyrangeLowerBound, keyrangeUpperBound),
 	}
 	// fmt.Println("NewRandomizer")
 	return r
 }
 
+// newSeed returns seed if it is set, otherwise a time based one.
+func newSeed(seed int64) int64 {
+	if seed != 0 {
+		return seed
+	}
+	return time.Now().UnixNano()
+}
+
 func (r *Randomizer) ResetKeySequence() {
 	r.mu.Lock()
 	r.keySequence = generator.NewCounter(0)
 	r.mu.Unlock()
 }
 
+// createKeyGenerator creates the generator choosing keys from lb to ub inclusive.
+func createKeyGenerator(kd KeyDistribution, lb int64, ub int64) ycsb.Generator {
+	switch kd {
+	case Uniform:
+		return generator.NewUniform(lb, ub)
+	case Latest:
+		// the keys up to ub have been loaded
+		return generator.NewSkewedLatest(generator.NewCounter(ub+1), benconfig.ZipfianConstant)
+	case Sequential:
+		return generator.NewSequential(lb, ub)
+	default:
+		return generator.NewScrambledZipfian(lb, ub, benconfig.ZipfianConstant)
+	}
+}
+
 func createDatastoreGenerator(wp *WorkloadParameter) *generator.Discrete {
-	proportions := map[int64]float64{
-		int64(kvrocksDatastore1):   wp.KVRocksProportion,
-		int64(redisDatastore1):     wp.Redis1Proportion,
-		int64(mongoDatastore1):     wp.Mongo1Proportion,
-		int64(mongoDatastore2):     wp.Mongo2Proportion,
-		int64(couchDatastore1):     wp.CouchDBProportion,
-		int64(cassandraDatastore1): wp.CassandraProportion,
-		int64(dynamodbDatastore1):  wp.DynamoDBProportion,
-		int64(tikvDatastore1):      wp.TiKVProportion,
+	// the datastores are added in a fixed order,
+	// so that the same seed draws the same datastores
+	proportions := []struct {
+		datastore  int64
+		proportion float64
+	}{
+		{int64(kvrocksDatastore1), wp.KVRocksProportion},
+		{int64(redisDatastore1), wp.Redis1Proportion},
+		{int64(mongoDatastore1), wp.Mongo1Proportion},
+		{int64(mongoDatastore2), wp.Mongo2Proportion},
+		{int64(couchDatastore1), wp.CouchDBProportion},
+		{int64(cassandraDatastore1), wp.CassandraProportion},
+		{int64(dynamodbDatastore1), wp.DynamoDBProportion},
+		{int64(tikvDatastore1), wp.TiKVProportion},
 	}
 
 	datastoreChooser := generator.NewDiscrete()
-	for datastore, proportion := range proportions {
-		if proportion > 0 {
-			datastoreChooser.Add(proportion, datastore)
+	for _, p := range proportions {
+		if p.proportion > 0 {
+			datastoreChooser.Add(p.proportion, p.datastore)
 		}
 	}
 
@@ -175,3 +200,10 @@ func (r *Randomizer) BuildRandomValue() string {
 	util.RandBytes(r.r, buf)
 	return string(buf)
 }
+
+// Intn returns a random number in [0, n).
+func (r *Randomizer) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Intn(n)
+}
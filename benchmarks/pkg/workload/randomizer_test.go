@@ -0,0 +1,136 @@
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const (
+	testKeyCount = 1000
+	testDraws    = 200000
+)
+
+// drawKeys draws keys from the distribution and counts how often each key is chosen.
+func drawKeys(t *testing.T, kd KeyDistribution) []int {
+	t.Helper()
+	gen := createKeyGenerator(kd, 0, testKeyCount-1)
+	r := rand.New(rand.NewSource(1))
+	counts := make([]int, testKeyCount)
+	for i := 0; i < testDraws; i++ {
+		key := gen.Next(r)
+		if key < 0 || key >= testKeyCount {
+			t.Fatalf("%s: key %d is out of range", kd, key)
+		}
+		counts[key]++
+	}
+	return counts
+}
+
+func TestKeyDistributionZipfian(t *testing.T) {
+	counts := drawKeys(t, Zipfian)
+	sorted := append([]int(nil), counts...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	// the hottest 10% of keys should take most of the accesses
+	hot := 0
+	for _, c := range sorted[:testKeyCount/10] {
+		hot += c
+	}
+	if float64(hot) < 0.5*testDraws {
+		t.Errorf("expected the hottest 10%% keys to take over half of the draws, got %d/%d", hot, testDraws)
+	}
+
+	// an empty distribution defaults to zipfian
+	sorted = drawKeys(t, "")
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	if sorted[0] < 10*testDraws/testKeyCount {
+		t.Errorf("expected the default distribution to be skewed, hottest key got %d draws", sorted[0])
+	}
+}
+
+func TestKeyDistributionUniform(t *testing.T) {
+	counts := drawKeys(t, Uniform)
+	expected := float64(testDraws) / testKeyCount
+	for key, c := range counts {
+		if float64(c) < 0.6*expected || float64(c) > 1.4*expected {
+			t.Errorf("key %d is drawn %d times, expected about %.0f", key, c, expected)
+		}
+	}
+}
+
+func TestKeyDistributionLatest(t *testing.T) {
+	counts := drawKeys(t, Latest)
+	latest := 0
+	for _, c := range counts[testKeyCount-testKeyCount/10:] {
+		latest += c
+	}
+	if float64(latest) < 0.5*testDraws {
+		t.Errorf("expected the latest 10%% keys to take over half of the draws, got %d/%d", latest, testDraws)
+	}
+	if counts[testKeyCount-1] < counts[0] {
+		t.Errorf("expected the latest key to be hotter than the oldest one")
+	}
+}
+
+func TestKeyDistributionSequential(t *testing.T) {
+	gen := createKeyGenerator(Sequential, 0, testKeyCount-1)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2*testKeyCount; i++ {
+		if key := gen.Next(r); key != int64(i%testKeyCount) {
+			t.Fatalf("draw %d: expected key %d, got %d", i, i%testKeyCount, key)
+		}
+	}
+
+	counts := drawKeys(t, Sequential)
+	for key, c := range counts {
+		if c != testDraws/testKeyCount {
+			t.Errorf("key %d is drawn %d times, expected %d", key, c, testDraws/testKeyCount)
+		}
+	}
+}
+
+func TestKeyDistributionValidate(t *testing.T) {
+	for _, kd := range []KeyDistribution{"", Zipfian, Uniform, Latest, Sequential} {
+		if err := kd.Validate(); err != nil {
+			t.Errorf("expected %q to be valid, got %v", kd, err)
+		}
+	}
+	if err := KeyDistribution("hotspot").Validate(); err == nil {
+		t.Errorf("expected an error for an unknown distribution")
+	}
+}
+
+// drawOperations records the operations and keys chosen by a randomizer.
+func drawOperations(wp *WorkloadParameter, n int) []string {
+	r := NewRandomizer(wp)
+	ops := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ops = append(ops, fmt.Sprintf("%d:%d:%s", r.NextOperation(), r.NextDatastore(), r.NextKeyName()))
+	}
+	return ops
+}
+
+func TestRandomizerSeed(t *testing.T) {
+	wp := &WorkloadParameter{
+		RecordCount:      testKeyCount,
+		ReadProportion:   0.5,
+		UpdateProportion: 0.3,
+		InsertProportion: 0.2,
+		Redis1Proportion: 0.5,
+		Mongo1Proportion: 0.5,
+		Seed:             42,
+	}
+	first := drawOperations(wp, 1000)
+	second := drawOperations(wp, 1000)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same operation sequence with the same seed")
+	}
+
+	wp.Seed = 43
+	if reflect.DeepEqual(first, drawOperations(wp, 1000)) {
+		t.Errorf("expected different operation sequences with different seeds")
+	}
+}
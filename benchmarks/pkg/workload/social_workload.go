@@ -131,18 +131,20 @@ func (wl *SocialWorkload) Run(ctx context.Context, opCount int,
 		return
 	}
 	for i := 0; i < opCount; i++ {
-		switch wl.NextTask() {
-		case 1:
-			wl.ContentFeedRetrieval(ctx, txnDB)
-		case 2:
-			wl.ContentCreation(ctx, txnDB)
-		case 3:
-			wl.ProfileUpdate(ctx, txnDB)
-		case 4:
-			wl.UserInteraction(ctx, txnDB)
-		default:
-			panic("Invalid task")
-		}
+		runTxn(ctx, func() {
+			switch wl.NextTask() {
+			case 1:
+				wl.ContentFeedRetrieval(ctx, txnDB)
+			case 2:
+				wl.ContentCreation(ctx, txnDB)
+			case 3:
+				wl.ProfileUpdate(ctx, txnDB)
+			case 4:
+				wl.UserInteraction(ctx, txnDB)
+			default:
+				panic("Invalid task")
+			}
+		})
 	}
 
 }
@@ -61,7 +61,9 @@ func (wl *TxnPerformanceWorkload) Load(ctx context.Context, opCount int,
 func (wl *TxnPerformanceWorkload) Run(ctx context.Context, opCount int,
 	db ycsb.DB) {
 	for i := 0; i < opCount; i++ {
-		_ = wl.doTxnPerformanceTest(ctx, db)
+		runTxn(ctx, func() {
+			_ = wl.doTxnPerformanceTest(ctx, db)
+		})
 	}
 }
 
@@ -3,6 +3,7 @@ package workload
 import (
 	"benchmark/ycsb"
 	"context"
+	"fmt"
 )
 
 type operationType int64
@@ -42,3 +43,68 @@ type Workload interface {
 	PostCheck(ctx context.Context, db ycsb.DB, resChan chan int)
 	DisplayCheckResult()
 }
+
+// Pacer runs one transaction of a workload, after waiting for it to be due.
+// The client paces the transactions of Run with it in the open loop.
+type Pacer func(txn func())
+
+type pacerKey struct{}
+
+// WithPacer returns a copy of ctx whose transactions are run through pace.
+func WithPacer(ctx context.Context, pace Pacer) context.Context {
+	return context.WithValue(ctx, pacerKey{}, pace)
+}
+
+// runTxn runs one transaction through the pacer of ctx, if any.
+func runTxn(ctx context.Context, txn func()) {
+	if pace, ok := ctx.Value(pacerKey{}).(Pacer); ok {
+		pace(txn)
+		return
+	}
+	txn()
+}
+
+// runDoubleSeqCommit commits first to key in a fresh transaction, then
+// immediately starts a second transaction that must read first back
+// before overwriting it with second.
+//
+// The second transaction races with the asynchronous cleanup of the
+// first one's TSR, so a stale read here is reported as an error.
+func runDoubleSeqCommit(ctx context.Context, db ycsb.DB, table string,
+	key string, first string, second string) error {
+	txnDB, ok := db.(ycsb.TransactionDB)
+	if !ok {
+		return fmt.Errorf("DB does not support transaction")
+	}
+	newDB := txnDB.NewTransaction()
+
+	if err := newDB.Start(); err != nil {
+		return err
+	}
+	if err := newDB.Update(ctx, table, key, first); err != nil {
+		_ = newDB.Abort()
+		return err
+	}
+	if err := newDB.Commit(); err != nil {
+		return err
+	}
+
+	// the dependent transaction
+	if err := newDB.Start(); err != nil {
+		return err
+	}
+	value, err := newDB.Read(ctx, table, key)
+	if err != nil {
+		_ = newDB.Abort()
+		return err
+	}
+	if value != first {
+		_ = newDB.Abort()
+		return fmt.Errorf("key %s: read %q right after the commit of %q", key, value, first)
+	}
+	if err := newDB.Update(ctx, table, key, second); err != nil {
+		_ = newDB.Abort()
+		return err
+	}
+	return newDB.Commit()
+}
@@ -1,5 +1,13 @@
 package workload
 
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
 type WorkloadParameter struct {
 	DBName       string
 	TableName    string
@@ -14,6 +22,28 @@ type WorkloadParameter struct {
 	TxnOperationGroup int `yaml:"txnoperationgroup"`
 	MaxLoadBatchSize  int `yaml:"max_load_batch_size"`
 
+	// OperationTimeout is the number of milliseconds an operation may take
+	// before it is recorded as a timeout and the thread moves on.
+	// A non-positive value means no timeout.
+	OperationTimeout int `yaml:"operationtimeout"`
+
+	// OpenLoop issues the transactions at TargetRate transactions per
+	// second in total, regardless of how long each of them takes, instead
+	// of issuing the next one as soon as the previous one returns.
+	// For the YCSB workloads a transaction is a group of TxnOperationGroup
+	// operations. Each transaction is measured as OPEN_LOOP from its
+	// scheduled start. The reads, writes and commits it runs are measured
+	// from their starts moved back by the time it has been queued behind
+	// the previous one, correcting every latency for the coordinated omission.
+	OpenLoop   bool    `yaml:"openloop"`
+	TargetRate float64 `yaml:"targetrate"`
+
+	// BulkLoad writes the records of the load phase directly to the
+	// datastores as committed records, in batches of MaxLoadBatchSize,
+	// instead of inserting them in transactions. Only the databases
+	// implementing ycsb.BulkLoadDB support it.
+	BulkLoad bool `yaml:"bulkload"`
+
 	ReadProportion            float64 `yaml:"readproportion"`
 	UpdateProportion          float64 `yaml:"updateproportion"`
 	InsertProportion          float64 `yaml:"insertproportion"`
@@ -38,6 +68,14 @@ type WorkloadParameter struct {
 	DynamoDBProportion  float64 `yaml:"dynamodbproportion" oreo:"DynamoDB"`
 	TiKVProportion      float64 `yaml:"tikvproportion" oreo:"TiKV"`
 
+	// KeyDistribution is the distribution of the keys chosen by the workload
+	KeyDistribution KeyDistribution `yaml:"keydistribution"`
+
+	// Seed makes the random choices of the workload reproducible if positive.
+	// Thread i of the run phase draws from seed+i. Use the same seed for the
+	// load and the run phase so that a rerun also starts from the same data.
+	Seed int64 `yaml:"seed"`
+
 	Task1Proportion float64 `yaml:"task1proportion"`
 	Task2Proportion float64 `yaml:"task2proportion"`
 	Task3Proportion float64 `yaml:"task3proportion"`
@@ -46,3 +84,104 @@ type WorkloadParameter struct {
 }
 
 // ----------------------------------------------------------------------------
+
+// KeyDistribution decides which keys are accessed by the workload.
+type KeyDistribution string
+
+const (
+	// Zipfian favors a small set of hot keys scattered over the key space.
+	Zipfian KeyDistribution = "zipfian"
+	// Uniform accesses every key with the same probability.
+	Uniform KeyDistribution = "uniform"
+	// Latest favors the keys with the largest numbers, i.e. the latest inserted ones.
+	Latest KeyDistribution = "latest"
+	// Sequential accesses the keys one after another.
+	Sequential KeyDistribution = "sequential"
+)
+
+// Validate returns an error if kd is not a known key distribution.
+// An empty KeyDistribution stands for Zipfian.
+func (kd KeyDistribution) Validate() error {
+	switch kd {
+	case "", Zipfian, Uniform, Latest, Sequential:
+		return nil
+	default:
+		return fmt.Errorf("unknown key distribution %q, expect one of zipfian, uniform, latest and sequential", string(kd))
+	}
+}
+
+// ValidateLoadMode returns an error if the open loop mode has no target rate.
+func (wp *WorkloadParameter) ValidateLoadMode() error {
+	if wp.OpenLoop && wp.TargetRate <= 0 {
+		return errors.New("targetrate should be positive in the open loop mode")
+	}
+	return nil
+}
+
+// ParseDatastoreWeights pairs each datastore in dbList with the
+// corresponding comma separated weight in weightStr.
+// An empty weightStr gives every datastore the same weight.
+func ParseDatastoreWeights(dbList []string, weightStr string) (map[string]float64, error) {
+	weights := make(map[string]float64, len(dbList))
+	if weightStr == "" {
+		for _, dsName := range dbList {
+			weights[dsName] = 1.0 / float64(len(dbList))
+		}
+		return weights, nil
+	}
+
+	tokens := strings.Split(weightStr, ",")
+	if len(tokens) != len(dbList) {
+		return nil, fmt.Errorf("got %d weights for %d datastores", len(tokens), len(dbList))
+	}
+
+	sum := 0.0
+	for i, token := range tokens {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(token), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight %q for %s: %v", token, dbList[i], err)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("weight for %s should not be negative", dbList[i])
+		}
+		weights[dbList[i]] += weight
+		sum += weight
+	}
+	if sum == 0 {
+		return nil, errors.New("at least one datastore weight should be positive")
+	}
+	for dsName := range weights {
+		weights[dsName] /= sum
+	}
+	return weights, nil
+}
+
+// SetDatastoreProportions overwrites the datastore proportions with weights,
+// which is keyed by the datastore name in the `oreo` tag of each field.
+// Datastores absent from weights get a zero proportion.
+func (wp *WorkloadParameter) SetDatastoreProportions(weights map[string]float64) error {
+	value := reflect.ValueOf(wp).Elem()
+	typ := value.Type()
+
+	found := make(map[string]bool, len(weights))
+	for i := 0; i < value.NumField(); i++ {
+		dsName, ok := typ.Field(i).Tag.Lookup("oreo")
+		if !ok || dsName == "" {
+			continue
+		}
+		value.Field(i).SetFloat(weights[dsName])
+		found[dsName] = true
+	}
+
+	for dsName := range weights {
+		if !found[dsName] {
+			return fmt.Errorf("unknown datastore %s", dsName)
+		}
+	}
+	return nil
+}
+
+// HasDatastoreProportion reports whether any datastore proportion is set.
+func (wp *WorkloadParameter) HasDatastoreProportion() bool {
+	return len(getDatabases(*wp)) > 0
+}
@@ -0,0 +1,102 @@
+package workload
+
+import (
+	"math"
+	"testing"
+)
+
+func empiricalDatastoreDistribution(wp *WorkloadParameter, n int) map[datastoreType]float64 {
+	r := NewRandomizer(wp)
+	counts := make(map[datastoreType]int)
+	for i := 0; i < n; i++ {
+		counts[r.NextDatastore()]++
+	}
+	dist := make(map[datastoreType]float64, len(counts))
+	for ds, cnt := range counts {
+		dist[ds] = float64(cnt) / float64(n)
+	}
+	return dist
+}
+
+func TestDatastoreWeightsDistribution(t *testing.T) {
+	const n = 100000
+	const tolerance = 0.01
+
+	tests := []struct {
+		name     string
+		dbList   []string
+		weights  string
+		expected map[datastoreType]float64
+	}{
+		{
+			name:    "uniform by default",
+			dbList:  []string{"Redis", "MongoDB1"},
+			weights: "",
+			expected: map[datastoreType]float64{
+				redisDatastore1: 0.5,
+				mongoDatastore1: 0.5,
+			},
+		},
+		{
+			name:    "skewed two stores",
+			dbList:  []string{"Redis", "MongoDB1"},
+			weights: "70,30",
+			expected: map[datastoreType]float64{
+				redisDatastore1: 0.7,
+				mongoDatastore1: 0.3,
+			},
+		},
+		{
+			name:    "unnormalized weights",
+			dbList:  []string{"Redis", "Cassandra", "TiKV"},
+			weights: "1,2,1",
+			expected: map[datastoreType]float64{
+				redisDatastore1:     0.25,
+				cassandraDatastore1: 0.5,
+				tikvDatastore1:      0.25,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp := &WorkloadParameter{
+				RecordCount:    1000,
+				ReadProportion: 1,
+				// stale proportions from the workload file should be overwritten
+				CouchDBProportion: 0.5,
+			}
+			weights, err := ParseDatastoreWeights(tt.dbList, tt.weights)
+			if err != nil {
+				t.Fatalf("ParseDatastoreWeights() error = %v", err)
+			}
+			if err := wp.SetDatastoreProportions(weights); err != nil {
+				t.Fatalf("SetDatastoreProportions() error = %v", err)
+			}
+
+			dist := empiricalDatastoreDistribution(wp, n)
+			if len(dist) != len(tt.expected) {
+				t.Fatalf("got datastores %v, want %v", dist, tt.expected)
+			}
+			for ds, want := range tt.expected {
+				if got := dist[ds]; math.Abs(got-want) > tolerance {
+					t.Errorf("datastore %v: got proportion %.4f, want %.4f", ds, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDatastoreWeightsInvalid(t *testing.T) {
+	dbList := []string{"Redis", "MongoDB1"}
+	for _, weights := range []string{"70", "70,30,10", "a,30", "-1,2", "0,0"} {
+		if _, err := ParseDatastoreWeights(dbList, weights); err == nil {
+			t.Errorf("ParseDatastoreWeights(%q) should fail", weights)
+		}
+	}
+
+	wp := &WorkloadParameter{}
+	if err := wp.SetDatastoreProportions(map[string]float64{"Unknown": 1}); err == nil {
+		t.Errorf("SetDatastoreProportions() should fail on unknown datastore")
+	}
+}
@@ -0,0 +1,183 @@
+package workload
+
+import (
+	"benchmark/pkg/benconfig"
+	"benchmark/ycsb"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// memTxnDB keeps the committed records in memory and
+// buffers the writes of the running transaction until it commits.
+type memTxnDB struct {
+	mu      *sync.Mutex
+	records map[string]string
+	writes  map[string]string
+}
+
+var _ ycsb.TransactionDB = (*memTxnDB)(nil)
+
+func newMemTxnDB() *memTxnDB {
+	return &memTxnDB{
+		mu:      &sync.Mutex{},
+		records: make(map[string]string),
+	}
+}
+
+func (db *memTxnDB) Close() error { return nil }
+
+func (db *memTxnDB) InitThread(ctx context.Context, _ int, _ int) context.Context { return ctx }
+
+func (db *memTxnDB) CleanupThread(context.Context) {}
+
+func (db *memTxnDB) Read(ctx context.Context, table string, key string) (string, error) {
+	if value, ok := db.writes[table+key]; ok {
+		return value, nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	value, ok := db.records[table+key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return value, nil
+}
+
+func (db *memTxnDB) Update(ctx context.Context, table string, key string, value string) error {
+	db.writes[table+key] = value
+	return nil
+}
+
+func (db *memTxnDB) Insert(ctx context.Context, table string, key string, value string) error {
+	return db.Update(ctx, table, key, value)
+}
+
+func (db *memTxnDB) Delete(ctx context.Context, table string, key string) error {
+	return errors.New("not supported")
+}
+
+func (db *memTxnDB) NewTransaction() ycsb.TransactionDB {
+	return &memTxnDB{mu: db.mu, records: db.records}
+}
+
+func (db *memTxnDB) Start() error {
+	db.writes = make(map[string]string)
+	return nil
+}
+
+func (db *memTxnDB) Commit() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for k, v := range db.writes {
+		db.records[k] = v
+	}
+	db.writes = nil
+	return nil
+}
+
+func (db *memTxnDB) Abort() error {
+	db.writes = nil
+	return nil
+}
+
+// staleDB serves the reads from a snapshot taken before the last commit.
+type staleDB struct {
+	*memTxnDB
+	snapshot map[string]string
+}
+
+func (db *staleDB) NewTransaction() ycsb.TransactionDB {
+	return &staleDB{memTxnDB: db.memTxnDB.NewTransaction().(*memTxnDB), snapshot: db.snapshot}
+}
+
+func (db *staleDB) Read(ctx context.Context, table string, key string) (string, error) {
+	return db.snapshot[table+key], nil
+}
+
+func TestRunDoubleSeqCommit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("the second transaction reads the first's value", func(t *testing.T) {
+		db := newMemTxnDB()
+		err := runDoubleSeqCommit(ctx, db, "table", "key1", "first", "second")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := db.records["tablekey1"]; got != "second" {
+			t.Errorf("expected the second value to be committed, got %q", got)
+		}
+	})
+
+	t.Run("a stale read is reported", func(t *testing.T) {
+		db := &staleDB{memTxnDB: newMemTxnDB(), snapshot: map[string]string{"tablekey1": "old"}}
+		err := runDoubleSeqCommit(ctx, db, "table", "key1", "first", "second")
+		if err == nil {
+			t.Fatalf("expected the stale read to be reported")
+		}
+		if got := db.records["tablekey1"]; got != "first" {
+			t.Errorf("expected only the first value to be committed, got %q", got)
+		}
+	})
+
+	t.Run("the DB does not support transactions", func(t *testing.T) {
+		var db ycsb.DB = struct{ ycsb.DB }{}
+		if err := runDoubleSeqCommit(ctx, db, "table", "key1", "first", "second"); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+// batchCountingDB records the size of each batch inserted into it.
+type batchCountingDB struct {
+	ycsb.DB
+	ycsb.BatchDB
+	batches []int
+}
+
+func (db *batchCountingDB) BatchInsert(ctx context.Context, table string, keys []string, values []map[string][]byte) error {
+	db.batches = append(db.batches, len(keys))
+	return nil
+}
+
+// commitCountingDB records the number of records committed by each transaction.
+type commitCountingDB struct {
+	*memTxnDB
+	commits []int
+}
+
+func (db *commitCountingDB) Commit() error {
+	db.commits = append(db.commits, len(db.writes))
+	return db.memTxnDB.Commit()
+}
+
+func TestYCSBLoadInBatches(t *testing.T) {
+	maxLoadBatchSize := benconfig.MaxLoadBatchSize
+	benconfig.MaxLoadBatchSize = 100
+	defer func() { benconfig.MaxLoadBatchSize = maxLoadBatchSize }()
+
+	ctx := context.Background()
+	wp := &WorkloadParameter{TableName: "table", RecordCount: 250}
+	expected := []int{100, 100, 50}
+
+	t.Run("batch insert", func(t *testing.T) {
+		db := &batchCountingDB{}
+		NewYCSBWorkload(wp).Load(ctx, wp.RecordCount, db)
+		if !reflect.DeepEqual(db.batches, expected) {
+			t.Errorf("expected batches of %v, got %v", expected, db.batches)
+		}
+	})
+
+	t.Run("one transaction per batch", func(t *testing.T) {
+		db := &commitCountingDB{memTxnDB: newMemTxnDB()}
+		NewYCSBWorkload(wp).Load(ctx, wp.RecordCount, db)
+		if !reflect.DeepEqual(db.commits, expected) {
+			t.Errorf("expected transactions of %v records, got %v", expected, db.commits)
+		}
+		if len(db.records) != wp.RecordCount {
+			t.Errorf("expected %d records to be loaded, got %d", wp.RecordCount, len(db.records))
+		}
+	})
+}
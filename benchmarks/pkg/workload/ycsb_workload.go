@@ -1,15 +1,21 @@
 package workload
 
 import (
+	"benchmark/pkg/benconfig"
+	"benchmark/pkg/errrecord"
 	"benchmark/pkg/measurement"
 	"benchmark/ycsb"
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
 	"time"
 )
 
+// loadField is the field holding the value of a record inserted by ycsb.BatchDB.
+const loadField = "value"
+
 type YCSBWorkload struct {
 	Randomizer
 	wp *WorkloadParameter
@@ -29,59 +35,80 @@ func NewYCSBWorkload(wp *WorkloadParameter) *YCSBWorkload {
 	}
 }
 
+// Load inserts opCount records in batches of benconfig.MaxLoadBatchSize.
 func (wl *YCSBWorkload) Load(ctx context.Context, opCount int,
 	db ycsb.DB) {
-	if txnDB, ok := db.(ycsb.TransactionDB); ok {
-		err := txnDB.Start()
-		if err != nil {
+	batchSize := max(benconfig.MaxLoadBatchSize, 1)
+	for loaded := 0; loaded < opCount; loaded += batchSize {
+		n := min(batchSize, opCount-loaded)
+		keys := make([]string, 0, n)
+		values := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			keys = append(keys, wl.NextKeyNameFromSequence())
+			values = append(values, wl.BuildRandomValue())
+		}
+		if err := loadBatch(ctx, db, wl.wp.TableName, keys, values); err != nil {
 			fmt.Printf("Error when loading data: %v\n", err)
-			return
 		}
 	}
-	for i := 0; i < opCount; i++ {
-		dbKey := wl.NextKeyNameFromSequence()
-		value := wl.BuildRandomValue()
+}
 
-		err := db.Insert(context.Background(), wl.wp.TableName, dbKey, value)
-		if err != nil {
-			fmt.Printf("Error when loading data: %v\n", err)
-		}
+// loadBatch inserts a batch of records with the cheapest primitive db supports:
+// a bulk load, a batch insert, one transaction per batch, or one insert per record.
+func loadBatch(ctx context.Context, db ycsb.DB, table string, keys []string, values []string) error {
+	if bulkDB, ok := db.(ycsb.BulkLoadDB); ok {
+		// the bulk load bypasses the wrappers recording the failed inserts
+		err := bulkDB.BulkLoad(ctx, table, keys, values)
+		errrecord.Record("BULK_LOAD", err)
+		return err
 	}
-	if txnDB, ok := db.(ycsb.TransactionDB); ok {
-		err := txnDB.Commit()
-		if err != nil {
-			fmt.Printf("Error when committing transaction: %v\n", err)
+	if batchDB, ok := db.(ycsb.BatchDB); ok {
+		fieldValues := make([]map[string][]byte, 0, len(values))
+		for _, value := range values {
+			fieldValues = append(fieldValues, map[string][]byte{loadField: []byte(value)})
 		}
+		return batchDB.BatchInsert(ctx, table, keys, fieldValues)
 	}
-}
 
-func (wl *YCSBWorkload) Run(ctx context.Context, opCount int, db ycsb.DB) {
-	var startTime time.Time
-	for i := 0; i <= opCount; i++ {
-		if i%wl.wp.TxnOperationGroup == 0 {
-			if i != 0 {
-				// txnDB.Commit()
-				measure(startTime, "TxnGroup", nil)
-			}
-			if i != opCount {
-				// txnDB.Start()
-				startTime = time.Now()
-			}
+	txnDB, isTxnDB := db.(ycsb.TransactionDB)
+	if isTxnDB {
+		if err := txnDB.Start(); err != nil {
+			return err
 		}
-		if txnDB, ok := db.(ycsb.TransactionDB); ok {
-			if i%wl.wp.TxnOperationGroup == 0 {
-				if i != 0 {
-					txnDB.Commit()
-				}
-				if i != opCount {
-					txnDB.Start()
-				}
-			}
+	}
+	var errs []error
+	for i, key := range keys {
+		if err := db.Insert(ctx, table, key, values[i]); err != nil {
+			errs = append(errs, err)
 		}
-		if i == opCount {
-			break
+	}
+	if isTxnDB {
+		if err := txnDB.Commit(); err != nil {
+			errs = append(errs, err)
 		}
+	}
+	return errors.Join(errs...)
+}
+
+func (wl *YCSBWorkload) Run(ctx context.Context, opCount int, db ycsb.DB) {
+	// every group of TxnOperationGroup operations is one transaction
+	txnSize := max(wl.wp.TxnOperationGroup, 1)
+	for done := 0; done < opCount; done += txnSize {
+		groupSize := min(txnSize, opCount-done)
+		runTxn(ctx, func() {
+			wl.runTxnGroup(ctx, groupSize, db)
+		})
+	}
+}
 
+// runTxnGroup runs groupSize operations in one transaction if db supports them.
+func (wl *YCSBWorkload) runTxnGroup(ctx context.Context, groupSize int, db ycsb.DB) {
+	startTime := time.Now()
+	txnDB, isTxnDB := db.(ycsb.TransactionDB)
+	if isTxnDB {
+		txnDB.Start()
+	}
+	for i := 0; i < groupSize; i++ {
 		operation := wl.NextOperation()
 		switch operation {
 		case read:
@@ -100,6 +127,10 @@ func (wl *YCSBWorkload) Run(ctx context.Context, opCount int, db ycsb.DB) {
 			panic("Unknown operation")
 		}
 	}
+	measure(startTime, "TxnGroup", nil)
+	if isTxnDB {
+		txnDB.Commit()
+	}
 }
 
 func (wl *YCSBWorkload) NeedPostCheck() bool {
@@ -184,54 +215,8 @@ func (wl *YCSBWorkload) doReadModifyWrite(ctx context.Context, db ycsb.DB) error
 }
 
 func (wl *YCSBWorkload) doDoubleSeqCommit(ctx context.Context, db ycsb.DB) error {
-	if txnDB, ok := db.(ycsb.TransactionDB); ok {
-		newDB := txnDB.NewTransaction()
-		keyName := "benchmark211"
-		value := wl.BuildRandomValue()
-
-		err := newDB.Start()
-		if err != nil {
-			return err
-		}
-
-		_, err = db.Read(ctx, wl.wp.TableName, keyName)
-		if err != nil {
-			return err
-		}
-
-		err = db.Update(ctx, wl.wp.TableName, keyName, value)
-		if err != nil {
-			return err
-		}
-
-		err = newDB.Commit()
-		if err != nil {
-			return err
-		}
-
-		// Second commit
-		err = newDB.Start()
-		if err != nil {
-			return err
-		}
-
-		_, err = db.Read(ctx, wl.wp.TableName, keyName)
-		if err != nil {
-			return err
-		}
-
-		err = db.Update(ctx, wl.wp.TableName, keyName, value)
-		if err != nil {
-			return err
-		}
-
-		err = newDB.Commit()
-		if err != nil {
-			return err
-		}
-		return nil
-	}
-	return fmt.Errorf("DB does not support transaction")
+	return runDoubleSeqCommit(ctx, db, wl.wp.TableName, wl.NextKeyName(),
+		wl.BuildRandomValue(), wl.BuildRandomValue())
 }
 
 func (wl *YCSBWorkload) NextKeyName() string {
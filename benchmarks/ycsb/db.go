@@ -67,6 +67,13 @@ type TransactionDB interface {
 	Abort() error
 }
 
+// BulkLoadDB is implemented by the databases that can load records
+// directly into the datastores, bypassing the transaction layer.
+type BulkLoadDB interface {
+	// BulkLoad writes values[i] under keys[i] to the table as committed records.
+	BulkLoad(ctx context.Context, table string, keys []string, values []string) error
+}
+
 type BatchDB interface {
 	// BatchInsert inserts batch records in the database.
 	// table: The name of the table.
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"benchmark/pkg/benconfig"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// ConnectorFactory connects to a datastore as configured by cfg.
+type ConnectorFactory func(cfg benconfig.BenchmarkConfig) (txn.Connector, error)
+
+// connectorFactories maps the datastore names to the factories
+// of their connectors.
+var connectorFactories = struct {
+	mu        sync.RWMutex
+	factories map[string]ConnectorFactory
+}{
+	factories: make(map[string]ConnectorFactory),
+}
+
+// Register makes the datastore name servable by the executor, whose
+// connector is created by factory. The built-in datastores are registered
+// by the init function of connectors.go. Registering a name again
+// replaces its factory.
+func Register(name string, factory ConnectorFactory) {
+	connectorFactories.mu.Lock()
+	defer connectorFactories.mu.Unlock()
+	connectorFactories.factories[name] = factory
+}
+
+// connectorFactory returns the factory registered for the datastore name.
+func connectorFactory(name string) (ConnectorFactory, bool) {
+	connectorFactories.mu.RLock()
+	defer connectorFactories.mu.RUnlock()
+	factory, ok := connectorFactories.factories[name]
+	return factory, ok
+}
+
+// registeredDatastores returns the names of the registered datastores, sorted.
+func registeredDatastores() []string {
+	connectorFactories.mu.RLock()
+	defer connectorFactories.mu.RUnlock()
+	names := make([]string, 0, len(connectorFactories.factories))
+	for name := range connectorFactories.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildConnMap connects to the datastores of dsNames concurrently, with the
+// factory registered for each, so that the startup takes as long as the
+// slowest of them. Every failure is reported at once, and the connectors
+// already created are closed if any fails.
+func buildConnMap(cfg benconfig.BenchmarkConfig, dsNames []string) (map[string]txn.Connector, error) {
+	conns := make([]txn.Connector, len(dsNames))
+	errs := make([]error, len(dsNames))
+	var wg sync.WaitGroup
+	for i, dsName := range dsNames {
+		factory, ok := connectorFactory(dsName)
+		if !ok {
+			errs[i] = fmt.Errorf("unsupported datastore %q, expect one of %v", dsName, registeredDatastores())
+			continue
+		}
+		wg.Add(1)
+		go func(i int, dsName string) {
+			defer wg.Done()
+			conn, err := factory(cfg)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to connect to %s: %w", dsName, err)
+				return
+			}
+			conns[i] = conn
+		}(i, dsName)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		for _, conn := range conns {
+			if conn != nil {
+				_ = conn.Close()
+			}
+		}
+		return nil, err
+	}
+	connMap := make(map[string]txn.Connector, len(dsNames))
+	for i, dsName := range dsNames {
+		connMap[dsName] = conns[i]
+	}
+	return connMap, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"benchmark/pkg/benconfig"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// closeCounter counts the connectors closed.
+type closeCounter struct {
+	txn.Connector
+	closed *int
+}
+
+func (c closeCounter) Close() error {
+	*c.closed++
+	return nil
+}
+
+func TestBuildConnMap(t *testing.T) {
+	closed := 0
+	var got benconfig.BenchmarkConfig
+	Register("Fake", func(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+		got = cfg
+		return closeCounter{memkv.NewConnection(&redis.RedisItemFactory{}), &closed}, nil
+	})
+	Register("Broken", func(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+		return nil, errors.New("connection refused")
+	})
+	cfg := benconfig.BenchmarkConfig{KeyNamespace: "test"}
+
+	t.Run("the registered datastores are connected", func(t *testing.T) {
+		connMap, err := buildConnMap(cfg, benconfig.Datastores("ycsb", strings.Split("Fake", ",")))
+		assert.NoError(t, err)
+		assert.Len(t, connMap, 1)
+		assert.IsType(t, closeCounter{}, connMap["Fake"])
+		assert.Equal(t, cfg, got)
+	})
+
+	t.Run("an unknown datastore is reported", func(t *testing.T) {
+		_, err := buildConnMap(cfg, strings.Split("Fake,Unknown", ","))
+		assert.ErrorContains(t, err, `unsupported datastore "Unknown"`)
+		assert.ErrorContains(t, err, "Fake")
+		assert.Equal(t, 1, closed)
+	})
+
+	t.Run("a failed connection closes the others", func(t *testing.T) {
+		closed = 0
+		_, err := buildConnMap(cfg, strings.Split("Fake,Broken", ","))
+		assert.EqualError(t, err, "failed to connect to Broken: connection refused")
+		assert.Equal(t, 1, closed)
+	})
+
+	t.Run("every failure is reported", func(t *testing.T) {
+		closed = 0
+		_, err := buildConnMap(cfg, strings.Split("Broken,Fake,Unknown", ","))
+		assert.ErrorContains(t, err, "failed to connect to Broken: connection refused")
+		assert.ErrorContains(t, err, `unsupported datastore "Unknown"`)
+		assert.Equal(t, 1, closed)
+	})
+
+	t.Run("the datastores are connected concurrently", func(t *testing.T) {
+		delays := map[string]time.Duration{
+			"Slow1": 100 * time.Millisecond,
+			"Slow2": 200 * time.Millisecond,
+			"Slow3": 300 * time.Millisecond,
+		}
+		for name, delay := range delays {
+			delay := delay
+			Register(name, func(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+				time.Sleep(delay)
+				return memkv.NewConnection(&redis.RedisItemFactory{}), nil
+			})
+		}
+		start := time.Now()
+		connMap, err := buildConnMap(cfg, strings.Split("Slow1,Slow2,Slow3", ","))
+		elapsed := time.Since(start)
+		assert.NoError(t, err)
+		assert.Len(t, connMap, 3)
+		// about the slowest connection, far from the 600ms of their sum
+		assert.GreaterOrEqual(t, elapsed, 300*time.Millisecond)
+		assert.Less(t, elapsed, 450*time.Millisecond)
+	})
+
+	t.Run("the built-in datastores are registered", func(t *testing.T) {
+		for _, dsName := range []string{"Redis", "KVRocks", "MongoDB", "MongoDB1", "MongoDB2",
+			"CouchDB", "Cassandra", "DynamoDB", "TiKV"} {
+			_, ok := connectorFactory(dsName)
+			assert.True(t, ok, dsName)
+		}
+	})
+}
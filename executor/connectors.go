@@ -0,0 +1,148 @@
+package main
+
+import (
+	"benchmark/pkg/benconfig"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/cassandra"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/couchdb"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/dynamodb"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/mongo"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/tikv"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+func init() {
+	Register("Redis", getRedisConn)
+	Register("KVRocks", getKVRocksConn)
+	// the iot, social and order workloads name the first MongoDB after the ycsb one
+	Register("MongoDB", getMongoConn1)
+	Register("MongoDB1", getMongoConn1)
+	Register("MongoDB2", getMongoConn2)
+	Register("CouchDB", getCouchConn)
+	Register("Cassandra", getCassandraConn)
+	Register("DynamoDB", getDynamoConn)
+	Register("TiKV", getTiKVConn)
+}
+
+// connect waits for the datastore to come up,
+// which may still be starting in an orchestrated deployment,
+// and sets up its schema first if configured.
+func connect(cfg benconfig.BenchmarkConfig, name string, conn txn.Connector) error {
+	if cfg.EnsureSchema {
+		if err := txn.EnsureSchemaWithRetry(name, conn, cfg.ConnectAttempts, cfg.ConnectInterval); err != nil {
+			return err
+		}
+	}
+	return txn.ConnectWithRetry(name, conn, cfg.ConnectAttempts, cfg.ConnectInterval)
+}
+
+func getKVRocksConn(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	kvConn := redis.NewRedisConnection(&redis.ConnectionOptions{
+		Address:     cfg.KVRocksAddr,
+		Password:    cfg.RedisPassword,
+		PoolSize:    poolSize,
+		MinPoolSize: minPoolSize,
+		MaxPoolSize: maxPoolSize,
+		Namespace:   txn.Namespace(cfg.KeyNamespace),
+	})
+	if err := connect(cfg, "KVRocks", kvConn); err != nil {
+		return nil, err
+	}
+	if err := txn.WarmUp(kvConn, 1, 100); err != nil {
+		return nil, err
+	}
+	return kvConn, nil
+}
+
+func getCouchConn(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	couchConn := couchdb.NewCouchDBConnection(&couchdb.ConnectionOptions{
+		Address: cfg.CouchDBAddr,
+		// Username: CouchUsername,
+		// Password: CouchPassword,
+		DBName:    "oreo",
+		Namespace: txn.Namespace(cfg.KeyNamespace),
+	})
+	if err := connect(cfg, "CouchDB", couchConn); err != nil {
+		return nil, err
+	}
+	return couchConn, nil
+}
+
+func getMongoConn1(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	return getMongoConn(cfg, cfg.MongoDBAddr1)
+}
+
+func getMongoConn2(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	return getMongoConn(cfg, cfg.MongoDBAddr2)
+}
+
+func getMongoConn(cfg benconfig.BenchmarkConfig, address string) (txn.Connector, error) {
+	mongoConn := mongo.NewMongoConnection(&mongo.ConnectionOptions{
+		Address:           address,
+		DBName:            "oreo",
+		CollectionName:    "benchmark",
+		Username:          cfg.MongoDBUsername,
+		Password:          cfg.MongoDBPassword,
+		ReadFromSecondary: cfg.MongoDBReadFromSecondary,
+		Namespace:         txn.Namespace(cfg.KeyNamespace),
+	})
+	if err := connect(cfg, "MongoDB", mongoConn); err != nil {
+		return nil, err
+	}
+	return mongoConn, nil
+}
+
+func getRedisConn(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	redisConn := redis.NewRedisConnection(&redis.ConnectionOptions{
+		Mode:          redis.Mode(cfg.RedisMode),
+		Address:       cfg.RedisAddr,
+		Addresses:     cfg.RedisAddrs,
+		MasterName:    cfg.RedisMasterName,
+		ReaderAddress: cfg.RedisReaderAddr,
+		Password:      cfg.RedisPassword,
+		PoolSize:      poolSize,
+		MinPoolSize:   minPoolSize,
+		MaxPoolSize:   maxPoolSize,
+		Namespace:     txn.Namespace(cfg.KeyNamespace),
+	})
+	if err := connect(cfg, "Redis", redisConn); err != nil {
+		return nil, err
+	}
+	return redisConn, nil
+}
+
+func getCassandraConn(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	cassConn := cassandra.NewCassandraConnection(&cassandra.ConnectionOptions{
+		Hosts:     cfg.CassandraAddr,
+		Keyspace:  "oreo",
+		Namespace: txn.Namespace(cfg.KeyNamespace),
+	})
+	if err := connect(cfg, "Cassandra", cassConn); err != nil {
+		return nil, err
+	}
+	return cassConn, nil
+}
+
+func getDynamoConn(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	dynamoConn := dynamodb.NewDynamoDBConnection(&dynamodb.ConnectionOptions{
+		TableName: "oreo",
+		Endpoint:  cfg.DynamoDBAddr,
+		Namespace: txn.Namespace(cfg.KeyNamespace),
+	})
+	if err := connect(cfg, "DynamoDB", dynamoConn); err != nil {
+		return nil, err
+	}
+	return dynamoConn, nil
+}
+
+func getTiKVConn(cfg benconfig.BenchmarkConfig) (txn.Connector, error) {
+	tikvConn := tikv.NewTiKVConnection(&tikv.ConnectionOptions{
+		PDAddrs:   cfg.TiKVAddr,
+		Namespace: txn.Namespace(cfg.KeyNamespace),
+	})
+	if err := connect(cfg, "TiKV", tikvConn); err != nil {
+		return nil, err
+	}
+	return tikvConn, nil
+}
@@ -17,25 +17,17 @@ import (
 
 	"github.com/cristalhq/aconfig"
 	"github.com/cristalhq/aconfig/aconfigyaml"
-	jsoniter "github.com/json-iterator/go"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/cassandra"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/couchdb"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/dynamodb"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/mongo"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/tikv"
 	"github.com/oreo-dtx-lab/oreo/pkg/network"
 	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
 	"github.com/oreo-dtx-lab/oreo/pkg/timesource"
+	"github.com/oreo-dtx-lab/oreo/pkg/tracing"
 	"github.com/oreo-dtx-lab/oreo/pkg/txn"
 	"github.com/valyala/fasthttp"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var json2 = jsoniter.ConfigCompatibleWithStandardLibrary
-
 var Banner = `
  ____  _        _       _               
 / ___|| |_ __ _| |_ ___| | ___  ___ ___ 
@@ -48,47 +40,132 @@ type Server struct {
 	port      int
 	reader    network.Reader
 	committer network.Committer
+
+	// connMap holds the connectors of the datastores served,
+	// which /health pings.
+	connMap map[string]txn.Connector
+
+	// startTimes rejects the reads and prepares of a transaction
+	// whose start time goes back.
+	startTimes *network.StartTimes
+
+	// hotKeys counts the keys prepared and committed, which /stats
+	// reports, nil unless benConfig.HotKeys is enabled.
+	hotKeys *network.HotKeys
+
+	// membership holds the executors registered with this one,
+	// which /peers lists to the clients using it as their seed.
+	membership *network.Membership
+
+	// routes maps the path of every endpoint to its handler.
+	routes map[string]fasthttp.RequestHandler
 }
 
-func NewServer(port int, connMap map[string]txn.Connector, factory txn.DataItemFactory, timeSource timesource.TimeSourcer) *Server {
-	reader := *network.NewReader(connMap, factory, serializer.NewJSON2Serializer(), network.NewCacher())
-	return &Server{
-		port:      port,
-		reader:    reader,
-		committer: *network.NewCommitter(connMap, reader, serializer.NewJSON2Serializer(), factory, timeSource),
-	}
+// NewServer creates an executor serving the datastores in connMap.
+// The items of each datastore are created by the factory registered for its name.
+// The states of the transactions are not cached if config.Config.DisableCache is set.
+// The prepares of the transactions older than benConfig.MaxTxnLifetime are rejected.
+// The hot keys are tracked as described by benConfig.HotKeys.
+func NewServer(port int, connMap map[string]txn.Connector, timeSource timesource.TimeSourcer) *Server {
+	var cacher network.Cacher = network.NewCacher()
+	if config.Config.DisableCache {
+		cacher = network.NoopCacher{}
+	}
+	reader := *network.NewReader(connMap, nil, serializer.NewJSON2Serializer(), cacher)
+	s := &Server{
+		port:       port,
+		reader:     reader,
+		committer:  *network.NewCommitter(connMap, reader, serializer.NewJSON2Serializer(), nil, timeSource),
+		connMap:    connMap,
+		startTimes: network.NewStartTimes(network.DefaultStartTimeTTL),
+		hotKeys:    network.NewHotKeys(benConfig.HotKeys),
+		membership: network.NewMembership(3 * heartbeatInterval()),
+	}
+	s.committer.SetMaxTxnLifetime(benConfig.MaxTxnLifetime)
+	s.committer.SetHotKeys(s.hotKeys)
+	s.committer.SetStartTimes(s.startTimes)
+	s.routes = map[string]fasthttp.RequestHandler{
+		"/ping":         s.pingHandler,
+		"/health":       s.healthHandler,
+		"/read":         s.readHandler,
+		"/prepare":      s.prepareHandler,
+		"/prepareBatch": s.prepareBatchHandler,
+		"/commit":       s.commitHandler,
+		"/abort":        s.abortHandler,
+		"/renewLease":   s.renewLeaseHandler,
+		"/abortGroup":   s.abortGroupHandler,
+		"/cache":        s.cacheHandler,
+		"/tsr":          s.tsrHandler,
+		"/peers":        s.peersHandler,
+		"/register":     s.registerHandler,
+		"/stats":        s.statsHandler,
+	}
+	return s
 }
 
-func (s *Server) Run() {
-	router := func(ctx *fasthttp.RequestCtx) {
-		switch string(ctx.Path()) {
-		case "/ping":
-			s.pingHandler(ctx)
-		case "/read":
-			s.readHandler(ctx)
-		case "/prepare":
-			s.prepareHandler(ctx)
-		case "/commit":
-			s.commitHandler(ctx)
-		case "/abort":
-			s.abortHandler(ctx)
-		case "/cache":
-			s.cacheHandler(ctx)
-		default:
-			ctx.Error("Unsupported path", fasthttp.StatusNotFound)
-		}
+// RegisterRoute serves the requests to path with handler, in place of
+// the handler already registered for path if any.
+// The routes must be registered before Run is called.
+func (s *Server) RegisterRoute(path string, handler fasthttp.RequestHandler) {
+	s.routes[path] = handler
+}
+
+// route passes a request to the handler registered for its path.
+func (s *Server) route(ctx *fasthttp.RequestCtx) {
+	handler, ok := s.routes[string(ctx.Path())]
+	if !ok {
+		ctx.Error("Unsupported path", fasthttp.StatusNotFound)
+		return
 	}
+	handler(ctx)
+}
 
+func (s *Server) Run() {
 	address := fmt.Sprintf(":%d", s.port)
 	// fmt.Println(banner)
-	Log.Infow("Server running", "address", address)
-	log.Fatalf("Server failed: %v", fasthttp.ListenAndServe(address, router))
+	Log.Infow("Server running", "address", address, "maxInFlight", benConfig.MaxInFlight,
+		"chaos", benConfig.Chaos.Enabled)
+	// the injected faults are behind the limiter, so that a delayed
+	// request keeps its slot as a slow datastore would
+	server := &fasthttp.Server{
+		Handler:            network.LimitInFlight(network.Chaos(s.route, benConfig.Chaos), benConfig.MaxInFlight),
+		MaxRequestBodySize: benConfig.MaxBodySize,
+	}
+	log.Fatalf("Server failed: %v", server.ListenAndServe(address))
 }
 
 func (s *Server) pingHandler(ctx *fasthttp.RequestCtx) {
 	ctx.WriteString("pong")
 }
 
+// healthHandler pings every datastore served, answering 503 unless all
+// of them are up, with the status and the latency of each of them as JSON.
+// Unlike /ping, it is meant for the readiness probes.
+func (s *Server) healthHandler(ctx *fasthttp.RequestCtx) {
+	resp := network.CheckHealth(s.connMap)
+	body, err := json.Marshal(resp)
+	if err != nil {
+		ctx.Error("failed to encode the response: "+err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+	if resp.Status != "OK" {
+		ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+	}
+	ctx.SetContentType("application/json")
+	ctx.SetBody(body)
+}
+
+// statsHandler reports the hottest keys as JSON.
+func (s *Server) statsHandler(ctx *fasthttp.RequestCtx) {
+	body, err := json.Marshal(network.StatsResponse{HotKeys: s.hotKeys.Top()})
+	if err != nil {
+		ctx.Error("failed to encode the response: "+err.Error(), fasthttp.StatusInternalServerError)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.SetBody(body)
+}
+
 func (s *Server) cacheHandler(ctx *fasthttp.RequestCtx) {
 
 	method := string(ctx.Method())
@@ -115,20 +192,31 @@ func (s *Server) cacheHandler(ctx *fasthttp.RequestCtx) {
 
 func (s *Server) readHandler(ctx *fasthttp.RequestCtx) {
 	startTime := time.Now()
-	defer func() {
-		Log.Debugw("Read request", "latency", time.Since(startTime))
-	}()
+	defer network.LogSlowRequest(Log, "Read", startTime)
 
 	var req network.ReadRequest
-	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
-		errMsg := fmt.Sprintf("Invalid timestamp parameter: %s", err.Error())
-		ctx.Error(errMsg, fasthttp.StatusBadRequest)
+	if !network.DecodeRequest(ctx, "read", &req) {
 		return
 	}
 
 	Log.Infow("Read request", "dsName", req.DsName, "key", req.Key, "startTime", req.StartTime, "config", req.Config)
+	if err := s.startTimes.Check(req.Config.TxnId, req.StartTime); err != nil {
+		Log.Warnw("Read rejected", "cause", err)
+		network.WriteResponse(ctx, network.ReadResponse{Status: "Error", ErrMsg: err.Error()})
+		return
+	}
 
-	item, dataType, gk, err := s.reader.Read(req.DsName, req.Key, req.StartTime, req.Config, true)
+	ts := req.StartTime
+	if req.ReadTs != 0 {
+		// unlike at a start time, the versions committed at ReadTs are visible
+		ts = req.ReadTs + 1
+	}
+	_, span := tracing.Start(tracing.Extract(ctx), "executor.Read")
+	item, dataType, gk, err := s.reader.Read(req.DsName, req.Key, ts, req.Config, true)
+	tracing.End(span, err)
+	if err == nil && len(req.Fields) > 0 {
+		projectItem(item, req.Fields)
+	}
 
 	var response network.ReadResponse
 	if err != nil {
@@ -160,34 +248,134 @@ func (s *Server) readHandler(ctx *fasthttp.RequestCtx) {
 		}
 		// fmt.Printf("Read response: %v\n", response)
 	}
-	respBytes, _ := json.Marshal(response)
-	ctx.Write(respBytes)
+	network.WriteResponse(ctx, response)
+}
+
+// projectItem keeps the fields of the value of item named by fields only.
+// The previous versions are dropped as well, since the client does not
+// cache a projected record. The item is sent back whole if its value
+// cannot be projected, which the client projects itself.
+func projectItem(item txn.DataItem, fields []string) {
+	value, err := txn.ProjectValue(config.Config.Serializer, []byte(item.Value()), fields)
+	if err != nil {
+		Log.Debugw("failed to project the value", "key", item.Key(), "cause", err)
+		return
+	}
+	item.SetValue(string(value))
+	item.SetPrev("")
+}
+
+func (s *Server) tsrHandler(ctx *fasthttp.RequestCtx) {
+	var req network.TSRRequest
+	if !network.DecodeRequest(ctx, "tsr", &req) {
+		return
+	}
+
+	Log.Infow("TSR request", "dsName", req.DsName, "txnId", req.TxnId)
+
+	state, err := s.reader.ReadTSR(req.DsName, req.TxnId)
+	var resp network.Response[config.State]
+	if err != nil {
+		resp = network.Response[config.State]{
+			Status: "Error",
+			ErrMsg: err.Error(),
+		}
+	} else {
+		resp = network.Response[config.State]{
+			Status: "OK",
+			Data:   state,
+		}
+	}
+	network.WriteResponse(ctx, resp)
+}
+
+// peersHandler lists the live executors of each datastore, those that
+// have registered with this one, so that clients bootstrapped from this
+// executor can find the others.
+func (s *Server) peersHandler(ctx *fasthttp.RequestCtx) {
+	network.WriteResponse(ctx, network.PeersResponse{
+		Status: "OK",
+		Peers:  s.membership.Peers(),
+	})
+}
+
+// registerHandler adds or renews an executor in the membership of this one.
+func (s *Server) registerHandler(ctx *fasthttp.RequestCtx) {
+	var req network.RegisterRequest
+	if !network.DecodeRequest(ctx, "register", &req) {
+		return
+	}
+	if req.Addr == "" || len(req.DsNames) == 0 {
+		ctx.Error("invalid register request: missing address or datastores", fasthttp.StatusBadRequest)
+		return
+	}
+	s.membership.Register(req.Addr, req.DsNames)
+	network.WriteResponse(ctx, network.Response[string]{Status: "OK"})
+}
+
+// heartbeatInterval is how often the executor registers with the seed.
+func heartbeatInterval() time.Duration {
+	if benConfig.HeartbeatInterval > 0 {
+		return benConfig.HeartbeatInterval
+	}
+	return network.DefaultHeartbeatInterval
+}
+
+// heartbeat registers the executor serving connMap, reachable at
+// benConfig.AdvertiseAddr, with the seed executor every heartbeat
+// until stop is closed.
+func heartbeat(connMap map[string]txn.Connector, stop <-chan struct{}) {
+	req := network.RegisterRequest{Addr: benConfig.AdvertiseAddr}
+	for dsName := range connMap {
+		req.DsNames = append(req.DsNames, dsName)
+	}
+	interval := heartbeatInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := network.Register(benConfig.ExecutorSeedAddr, req, interval); err != nil {
+			Log.Warnw("Failed to register with the seed executor", "seed", benConfig.ExecutorSeedAddr, "err", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 func (s *Server) prepareHandler(ctx *fasthttp.RequestCtx) {
 	startTime := time.Now()
-	defer func() {
-		Log.Debugw("Prepare request", "latency", time.Since(startTime), "Topic", "CheckPoint")
-	}()
+	defer network.LogSlowRequest(Log, "Prepare", startTime)
 
 	var req network.PrepareRequest
 	// body := ctx.PostBody()
 	// Log.Infow("Prepare request", "body", string(body))
-	if err := json2.Unmarshal(ctx.PostBody(), &req); err != nil {
-		errMsg := fmt.Sprintf("Invalid prepare request body, error: %s\n Body: %v\n", err.Error(), string(ctx.PostBody()))
-		ctx.Error(errMsg, fasthttp.StatusBadRequest)
+	if !network.DecodeRequest(ctx, "prepare", &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		ctx.Error(fmt.Sprintf("Invalid prepare request: %s", err.Error()), fasthttp.StatusBadRequest)
 		return
 	}
 
 	Log.Infow("Prepare request", "dsName", req.DsName, "itemList", req.ItemList, "startTime", req.StartTime, "config", req.Config, "validationMap", req.ValidationMap)
+	if err := s.startTimes.Check(req.Config.TxnId, req.StartTime); err != nil {
+		Log.Warnw("Prepare rejected", "cause", err)
+		network.WriteResponse(ctx, network.PrepareResponse{Status: "Error", ErrMsg: err.Error()})
+		return
+	}
 
+	_, span := tracing.Start(tracing.Extract(ctx), "executor.Prepare")
 	verMap, tCommit, err := s.committer.Prepare(req.DsName, req.ItemList,
 		req.StartTime, req.Config, req.ValidationMap)
+	tracing.End(span, err)
 	var resp network.PrepareResponse
 	if err != nil {
 		resp = network.PrepareResponse{
-			Status: "Error",
-			ErrMsg: err.Error(),
+			Status:  "Error",
+			ErrMsg:  err.Error(),
+			ErrCode: network.ErrCode(err),
 		}
 	} else {
 		resp = network.PrepareResponse{
@@ -196,64 +384,129 @@ func (s *Server) prepareHandler(ctx *fasthttp.RequestCtx) {
 			TCommit: tCommit,
 		}
 	}
-	respBytes, _ := json2.Marshal(resp)
-	ctx.Write(respBytes)
+	network.WriteResponse(ctx, resp)
+}
+
+func (s *Server) prepareBatchHandler(ctx *fasthttp.RequestCtx) {
+	startTime := time.Now()
+	defer network.LogSlowRequest(Log, "PrepareBatch", startTime)
+
+	var req network.PrepareBatchRequest
+	if !network.DecodeRequest(ctx, "prepare batch", &req) {
+		return
+	}
+
+	_, span := tracing.Start(tracing.Extract(ctx), "executor.PrepareBatch")
+	resps := s.committer.PrepareBatch(req.Requests)
+	tracing.End(span, nil)
+	network.WriteResponse(ctx, network.PrepareBatchResponse{
+		Status:    "OK",
+		Responses: resps,
+	})
 }
 
 func (s *Server) commitHandler(ctx *fasthttp.RequestCtx) {
 	startTime := time.Now()
-	defer func() {
-		Log.Debugw("Commit request", "latency", time.Since(startTime))
-	}()
+	defer network.LogSlowRequest(Log, "Commit", startTime)
 
 	var req network.CommitRequest
-	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
-		ctx.Error("Invalid commit request body.", fasthttp.StatusBadRequest)
+	if !network.DecodeRequest(ctx, "commit", &req) {
 		return
 	}
 
-	err := s.committer.Commit(req.DsName, req.List, req.TCommit)
-	var resp network.Response[string]
+	_, span := tracing.Start(tracing.Extract(ctx), "executor.Commit")
+	tCommit, err := s.committer.Commit(req.DsName, req.List, req.TCommit)
+	tracing.End(span, err)
+	var resp network.CommitResponse
 	if err != nil {
-		resp = network.Response[string]{
+		resp = network.CommitResponse{
 			Status: "Error",
 			ErrMsg: err.Error(),
 		}
 	} else {
-		resp = network.Response[string]{
-			Status: "OK",
+		resp = network.CommitResponse{
+			Status:  "OK",
+			TCommit: tCommit,
 		}
 	}
-	respBytes, _ := json.Marshal(resp)
-	ctx.Write(respBytes)
+	network.WriteResponse(ctx, resp)
 }
 
 func (s *Server) abortHandler(ctx *fasthttp.RequestCtx) {
 	startTime := time.Now()
-	defer func() {
-		Log.Debugw("Abort request", "latency", time.Since(startTime))
-	}()
+	defer network.LogSlowRequest(Log, "Abort", startTime)
 
 	var req network.AbortRequest
-	if err := json.Unmarshal(ctx.PostBody(), &req); err != nil {
-		ctx.Error("Invalid abort request body.", fasthttp.StatusBadRequest)
+	if !network.DecodeRequest(ctx, "abort", &req) {
+		return
+	}
+
+	_, span := tracing.Start(tracing.Extract(ctx), "executor.Abort")
+	rolledBack, err := s.committer.Abort(req.DsName, req.KeyList, req.GroupKeyList)
+	tracing.End(span, err)
+	var resp network.AbortResponse
+	if err != nil {
+		resp = network.AbortResponse{
+			Status: "Error",
+			ErrMsg: err.Error(),
+		}
+	} else {
+		resp = network.AbortResponse{
+			Status:     "OK",
+			RolledBack: rolledBack,
+		}
+	}
+	network.WriteResponse(ctx, resp)
+}
+
+func (s *Server) renewLeaseHandler(ctx *fasthttp.RequestCtx) {
+	startTime := time.Now()
+	defer network.LogSlowRequest(Log, "RenewLease", startTime)
+
+	var req network.RenewLeaseRequest
+	if !network.DecodeRequest(ctx, "renew lease", &req) {
 		return
 	}
 
-	err := s.committer.Abort(req.DsName, req.KeyList, req.GroupKeyList)
-	var resp network.Response[string]
+	_, span := tracing.Start(tracing.Extract(ctx), "executor.RenewLease")
+	verMap, err := s.committer.RenewLease(req.DsName, req.List)
+	tracing.End(span, err)
+	var resp network.Response[map[string]string]
 	if err != nil {
-		resp = network.Response[string]{
+		resp = network.Response[map[string]string]{
 			Status: "Error",
 			ErrMsg: err.Error(),
 		}
 	} else {
-		resp = network.Response[string]{
+		resp = network.Response[map[string]string]{
 			Status: "OK",
+			Data:   verMap,
 		}
 	}
-	respBytes, _ := json.Marshal(resp)
-	ctx.Write(respBytes)
+	network.WriteResponse(ctx, resp)
+}
+
+func (s *Server) abortGroupHandler(ctx *fasthttp.RequestCtx) {
+	startTime := time.Now()
+	defer network.LogSlowRequest(Log, "AbortGroup", startTime)
+
+	var req network.AbortGroupRequest
+	if !network.DecodeRequest(ctx, "abort group", &req) {
+		return
+	}
+
+	_, span := tracing.Start(tracing.Extract(ctx), "executor.AbortGroup")
+	keys, err := s.committer.AbortByGroup(req.DsName, req.GroupKey)
+	tracing.End(span, err)
+	resp := network.Response[[]string]{
+		Status: "OK",
+		Data:   keys,
+	}
+	if err != nil {
+		resp.Status = "Error"
+		resp.ErrMsg = err.Error()
+	}
+	network.WriteResponse(ctx, resp)
 }
 
 // const (
@@ -266,12 +519,14 @@ func (s *Server) abortHandler(ctx *fasthttp.RequestCtx) {
 
 var port = 8000
 var poolSize = 60
+var minPoolSize = 0
+var maxPoolSize = 0
 var traceFlag = false
 var pprofFlag = false
 var workloadType = ""
 var db_combination = ""
 var benConfigPath = ""
-var cg = false
+var bannerFlag = false
 
 var Log *zap.SugaredLogger
 
@@ -321,27 +576,62 @@ func main() {
 		}
 		defer trace.Stop()
 	}
-	if cg {
-		fmt.Printf("Running under Cherry Garcia Mode")
-		config.Debug.CherryGarciaMode = true
-	}
-
 	config.Debug.DebugMode = false
 
+	if bannerFlag {
+		printBanner()
+	}
+
 	connMap := getConnMap()
 
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
 	oracle := timesource.NewGlobalTimeSource(benConfig.TimeOracleUrl)
-	server := NewServer(port, connMap, &redis.RedisItemFactory{}, oracle)
+	for dsName := range connMap {
+		if txn.ItemFactoryFor(dsName) == nil {
+			Log.Fatalw("No item type is registered for the datastore", "dsName", dsName)
+		}
+	}
+	server := NewServer(port, connMap, oracle)
 	go server.Run()
+	stopHeartbeat := make(chan struct{})
+	if benConfig.ExecutorSeedAddr != "" && benConfig.AdvertiseAddr != "" {
+		go heartbeat(connMap, stopHeartbeat)
+	}
 
 	<-sigs
 
 	Log.Info("Shutting down server")
+	close(stopHeartbeat)
 	fmt.Printf("Cache: %v\n", server.reader.GetCacheStatistic())
+	if hot := server.hotKeys.Top(); len(hot) > 0 {
+		Log.Infow("Hot keys", "top", hot)
+	}
+
+	for dsName, conn := range connMap {
+		if err := conn.Close(); err != nil {
+			Log.Errorw("Failed to close connection", "dsName", dsName, "err", err)
+		}
+	}
+}
 
+// printBanner prints the banner and the resolved configuration
+// before any datastore is connected.
+func printBanner() {
+	fmt.Print(Banner)
+	summary := benconfig.StartupSummary{
+		ListenAddr:                  fmt.Sprintf(":%d", port),
+		PoolSize:                    poolSize,
+		WorkloadType:                workloadType,
+		AsyncLevel:                  config.Config.AsyncLevel,
+		ConcurrentOptimizationLevel: config.Config.ConcurrentOptimizationLevel,
+	}
+	if db_combination != "" {
+		summary.DBCombination = strings.Split(db_combination, ",")
+	}
+	benConfig.ResolveDatastores(&summary)
+	summary.Write(os.Stdout)
 }
 
 func loadConfig() error {
@@ -360,21 +650,65 @@ func loadConfig() error {
 		log.Fatalf("Error when loading benchmark configuration: %v\n", err)
 	}
 
-	if benConfig.TimeOracleUrl == "" {
-		Log.Fatal("Time Oracle URL must be specified")
+	var dbList []string
+	if db_combination != "" {
+		dbList = strings.Split(db_combination, ",")
+	}
+	if err := benConfig.Validate(workloadType, dbList); err != nil {
+		Log.Fatalf("Invalid benchmark configuration:\n%v", err)
+	}
+
+	if benConfig.MaxBodySize <= 0 {
+		benConfig.MaxBodySize = network.DefaultMaxBodySize
 	}
+
+	if benConfig.ConnectAttempts <= 0 {
+		benConfig.ConnectAttempts = 10
+	}
+	if benConfig.ConnectInterval <= 0 {
+		benConfig.ConnectInterval = time.Second
+	}
+
+	if benConfig.SlowRequestThreshold > 0 {
+		config.Config.SlowRequestThreshold = benConfig.SlowRequestThreshold
+	}
+
+	codec, err := network.NewCodec(benConfig.Codec, benConfig.CodecUseNumber)
+	if err != nil {
+		Log.Fatal(err)
+	}
+	config.Config.Codec = codec
+
+	tsrEncoding, err := txn.TSREncodingFromName(benConfig.TSREncoding)
+	if err != nil {
+		Log.Fatal(err)
+	}
+	config.Config.TSREncoding = tsrEncoding
+
+	compression, err := txn.CompressionFromName(benConfig.ValueCompression)
+	if err != nil {
+		Log.Fatal(err)
+	}
+	config.Config.ValueCompression = compression
+	if benConfig.ValueCompressionThreshold > 0 {
+		config.Config.ValueCompressionThreshold = benConfig.ValueCompressionThreshold
+	}
+	config.Config.ReadRepairRate = benConfig.ReadRepairRate
+	config.Config.DisableCache = benConfig.DisableCache
 	return nil
 }
 
 func parseFlag() {
 	flag.IntVar(&port, "p", 8000, "Server Port")
 	flag.IntVar(&poolSize, "s", 60, "Pool Size")
+	flag.IntVar(&minPoolSize, "min-s", 0, "Min Pool Size of the adaptive Redis pool")
+	flag.IntVar(&maxPoolSize, "max-s", 0, "Max Pool Size of the adaptive Redis pool, 0 keeps the pool size fixed")
 	flag.BoolVar(&traceFlag, "trace", false, "Enable trace")
 	flag.BoolVar(&pprofFlag, "pprof", false, "Enable pprof")
 	flag.StringVar(&workloadType, "w", "", "Workload Type")
 	flag.StringVar(&db_combination, "db", "", "Database Combination")
-	flag.BoolVar(&cg, "cg", false, "Enable Cherry Garcia Mode")
 	flag.StringVar(&benConfigPath, "bc", "", "Benchmark Configuration Path")
+	flag.BoolVar(&bannerFlag, "banner", false, "Print the banner and the startup summary")
 	flag.Parse()
 
 	newLogger()
@@ -389,75 +723,15 @@ func parseFlag() {
 
 }
 
+// getConnMap connects to the datastores of the workload.
 func getConnMap() map[string]txn.Connector {
-	connMap := make(map[string]txn.Connector)
-	switch workloadType {
-	case "iot":
-		// if kvRocksAddr == "" || mongoAddr1 == "" {
-		// 	Log.Fatal("IOT Datastore address must be specified")
-		// }
-		mongoConn1 := getMongoConn(1)
-		redisConn := getRedisConn(1)
-		connMap["MongoDB"] = mongoConn1
-		connMap["Redis"] = redisConn
-	case "social":
-		// if mongoAddr1 == "" || couchAddr == "" || redisAddr1 == "" {
-		// 	Log.Fatal("SOCIAL Datastore address must be specified")
-		// }
-		mongoConn1 := getMongoConn(1)
-		redisConn := getRedisConn(1)
-		cassandraConn := getCassandraConn()
-		connMap["MongoDB"] = mongoConn1
-		connMap["Redis"] = redisConn
-		connMap["Cassandra"] = cassandraConn
-	case "order":
-		// if mongoAddr1 == "" || couchAddr == "" || redisAddr1 == "" || kvRocksAddr == "" {
-		// 	Log.Fatal("ORDER Datastore address must be specified")
-		// }
-		mongoConn1 := getMongoConn(1)
-		kvrocksConn := getKVRocksConn()
-		redisConn := getRedisConn(1)
-		cassandraConn := getCassandraConn()
-		connMap["MongoDB"] = mongoConn1
-		connMap["KVRocks"] = kvrocksConn
-		connMap["Redis"] = redisConn
-		connMap["Cassandra"] = cassandraConn
-
-	case "ycsb":
-		// if redisAddr1 == "" && mongoAddr1 == "" && mongoAddr2 == "" {
-		// 	Log.Fatal("No datastore address specified")
-		// }
-		dbList := strings.Split(db_combination, ",")
-		for _, db := range dbList {
-			switch db {
-			case "Redis":
-				redisConn := getRedisConn(1)
-				connMap["Redis"] = redisConn
-			case "MongoDB1":
-				mongoConn1 := getMongoConn(1)
-				connMap["MongoDB1"] = mongoConn1
-			case "MongoDB2":
-				mongoConn2 := getMongoConn(2)
-				connMap["MongoDB2"] = mongoConn2
-			case "KVRocks":
-				kvConn := getKVRocksConn()
-				connMap["KVRocks"] = kvConn
-			case "CouchDB":
-				couchConn := getCouchConn()
-				connMap["CouchDB"] = couchConn
-			case "Cassandra":
-				cassConn := getCassandraConn()
-				connMap["Cassandra"] = cassConn
-			case "DynamoDB":
-				dynamoConn := getDynamoConn()
-				connMap["DynamoDB"] = dynamoConn
-			case "TiKV":
-				tikvConn := getTiKVConn()
-				connMap["TiKV"] = tikvConn
-			default:
-				Log.Fatal("Invalid database combination")
-			}
-		}
+	var dbList []string
+	if db_combination != "" {
+		dbList = strings.Split(db_combination, ",")
+	}
+	connMap, err := buildConnMap(benConfig, benconfig.Datastores(workloadType, dbList))
+	if err != nil {
+		Log.Fatal(err)
 	}
 	return connMap
 }
@@ -488,114 +762,3 @@ func newLogger() {
 	logger, _ := conf.Build()
 	Log = logger.Sugar()
 }
-
-func getKVRocksConn() *redis.RedisConnection {
-	kvConn := redis.NewRedisConnection(&redis.ConnectionOptions{
-		Address:  benConfig.KVRocksAddr,
-		Password: benConfig.RedisPassword,
-		PoolSize: poolSize,
-	})
-	err := kvConn.Connect()
-	if err != nil {
-		Log.Fatal(err)
-	}
-	for i := 0; i < 100; i++ {
-		_, _ = kvConn.Get("ping")
-	}
-	return kvConn
-}
-
-func getCouchConn() *couchdb.CouchDBConnection {
-	couchConn := couchdb.NewCouchDBConnection(&couchdb.ConnectionOptions{
-		Address: benConfig.CouchDBAddr,
-		// Username: CouchUsername,
-		// Password: CouchPassword,
-		DBName: "oreo",
-	})
-	err := couchConn.Connect()
-	if err != nil {
-		Log.Fatal(err)
-	}
-	return couchConn
-}
-
-func getMongoConn(id int) *mongo.MongoConnection {
-	address := ""
-	switch id {
-	case 1:
-		address = benConfig.MongoDBAddr1
-	case 2:
-		address = benConfig.MongoDBAddr2
-	default:
-		Log.Fatal("Invalid mongo id")
-	}
-	mongoConn := mongo.NewMongoConnection(&mongo.ConnectionOptions{
-		Address:        address,
-		DBName:         "oreo",
-		CollectionName: "benchmark",
-		Username:       benConfig.MongoDBUsername,
-		Password:       benConfig.MongoDBPassword,
-	})
-	err := mongoConn.Connect()
-	if err != nil {
-		Log.Fatal(err)
-	}
-	return mongoConn
-}
-
-func getRedisConn(id int) *redis.RedisConnection {
-
-	address := ""
-	switch id {
-	case 1:
-		address = benConfig.RedisAddr
-	default:
-		Log.Fatal("Invalid redis id")
-	}
-
-	redisConn := redis.NewRedisConnection(&redis.ConnectionOptions{
-		Address:  address,
-		Password: benConfig.RedisPassword,
-		PoolSize: poolSize,
-	})
-	err := redisConn.Connect()
-	if err != nil {
-		Log.Fatal(err)
-	}
-	return redisConn
-}
-
-func getCassandraConn() *cassandra.CassandraConnection {
-	cassConn := cassandra.NewCassandraConnection(&cassandra.ConnectionOptions{
-		Hosts:    benConfig.CassandraAddr,
-		Keyspace: "oreo",
-	})
-	err := cassConn.Connect()
-	if err != nil {
-		Log.Fatal(err)
-	}
-	return cassConn
-}
-
-func getDynamoConn() *dynamodb.DynamoDBConnection {
-	dynamoConn := dynamodb.NewDynamoDBConnection(&dynamodb.ConnectionOptions{
-		TableName: "oreo",
-		Endpoint:  benConfig.DynamoDBAddr,
-	})
-	err := dynamoConn.Connect()
-	if err != nil {
-		Log.Fatal(err)
-	}
-	return dynamoConn
-}
-
-func getTiKVConn() *tikv.TiKVConnection {
-	tikvConn := tikv.NewTiKVConnection(&tikv.ConnectionOptions{
-		PDAddrs: benConfig.TiKVAddr,
-	})
-	err := tikvConn.Connect()
-	if err != nil {
-		Log.Fatal(err)
-	}
-	return tikvConn
-}
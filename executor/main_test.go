@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/network"
+	"github.com/oreo-dtx-lab/oreo/pkg/timesource"
+	"github.com/oreo-dtx-lab/oreo/pkg/tracing"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+// serve passes a request to path through the router of s.
+func serve(s *Server, path string) *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI(path)
+	s.route(&ctx)
+	return &ctx
+}
+
+func TestServerRegisterRoute(t *testing.T) {
+	s := NewServer(0, map[string]txn.Connector{}, nil)
+
+	ctx := serve(s, "/custom")
+	assert.Equal(t, fasthttp.StatusNotFound, ctx.Response.StatusCode())
+
+	s.RegisterRoute("/custom", func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("custom")
+	})
+	ctx = serve(s, "/custom")
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Equal(t, "custom", string(ctx.Response.Body()))
+
+	// the built-in routes are still served
+	ctx = serve(s, "/ping")
+	assert.Equal(t, "pong", string(ctx.Response.Body()))
+
+	// a route registered again replaces the previous handler
+	s.RegisterRoute("/ping", func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("PONG")
+	})
+	ctx = serve(s, "/ping")
+	assert.Equal(t, "PONG", string(ctx.Response.Body()))
+}
+
+// newFuzzServer returns an executor serving an in-memory Redis.
+func newFuzzServer() *Server {
+	Log = zap.NewNop().Sugar()
+	connMap := map[string]txn.Connector{"Redis": memkv.NewConnection(&redis.RedisItemFactory{})}
+	return NewServer(0, connMap, timesource.NewSimpleTimeSource())
+}
+
+// post passes a request with body to path through the router of s.
+func post(s *Server, path string, body []byte) *fasthttp.RequestCtx {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(fasthttp.MethodPost)
+	ctx.Request.SetRequestURI(path)
+	ctx.Request.SetBody(body)
+	s.route(&ctx)
+	return &ctx
+}
+
+// assertCleanResponse checks that a request is either served
+// or rejected as a bad request, the fuzzer catching the panics.
+func assertCleanResponse(t *testing.T, ctx *fasthttp.RequestCtx) {
+	status := ctx.Response.StatusCode()
+	if status != fasthttp.StatusOK && status != fasthttp.StatusBadRequest {
+		t.Fatalf("unexpected status %d: %s", status, ctx.Response.Body())
+	}
+}
+
+func encodeRequest(f *testing.F, req any) []byte {
+	bs, err := config.Config.Codec.Serialize(req)
+	if err != nil {
+		f.Fatal(err)
+	}
+	return bs
+}
+
+func FuzzPrepareHandler(f *testing.F) {
+	cfg := txn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.Pessimistic, AblationLevel: 4}
+	item := &redis.RedisItem{RKey: "item1", RValue: "value1", RGroupKeyList: "Redis:txn1", RTxnState: config.PREPARED}
+	f.Add(encodeRequest(f, network.PrepareRequest{
+		DsName:   "Redis",
+		ItemType: txn.RedisItem,
+		ItemList: []txn.DataItem{item},
+		Config:   cfg,
+	}))
+	f.Add(encodeRequest(f, network.PrepareRequest{
+		DsName: "Redis",
+		ValidationMap: map[string]txn.PredicateInfo{
+			"item1": {ItemKey: "item1", ReadVersion: true},
+		},
+		Config: cfg,
+	}))
+	f.Add([]byte(`{"DsName":"Redis","ItemType":"redis","ItemList":[null]}`))
+	f.Add([]byte(`{"DsName":"redis1","ItemType":"redis","ItemList":[{}],"Config":{"AblationLevel":4}}`))
+
+	s := newFuzzServer()
+	f.Fuzz(func(t *testing.T, body []byte) {
+		assertCleanResponse(t, post(s, "/prepare", body))
+	})
+}
+
+func FuzzReadHandler(f *testing.F) {
+	f.Add(encodeRequest(f, network.ReadRequest{
+		DsName:    "Redis",
+		Key:       "item1",
+		StartTime: 1,
+		Config:    txn.RecordConfig{MaxRecordLen: 2},
+	}))
+	f.Add(encodeRequest(f, network.ReadRequest{
+		DsName: "Redis",
+		Key:    "item1",
+		Fields: []string{"name"},
+	}))
+	f.Add([]byte(`{"DsName":"MongoDB","Key":""}`))
+
+	s := newFuzzServer()
+	f.Fuzz(func(t *testing.T, body []byte) {
+		assertCleanResponse(t, post(s, "/read", body))
+	})
+}
+
+func TestServerDisableCache(t *testing.T) {
+	disableCache := config.Config.DisableCache
+	defer func() { config.Config.DisableCache = disableCache }()
+	config.Config.DisableCache = true
+
+	Log = zap.NewNop().Sugar()
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	s := NewServer(0, map[string]txn.Connector{"Redis": conn}, timesource.NewSimpleTimeSource())
+
+	// the item is prepared by a transaction whose TSR is committed
+	_, err := conn.PutItem("item1", &redis.RedisItem{
+		RKey: "item1", RValue: "value1", RGroupKeyList: "Redis:txn1",
+		RTxnState: config.PREPARED, RTValid: 10, RVersion: "1",
+	})
+	assert.NoError(t, err)
+	tsr, err := txn.EncodeGroupKeyItem(txn.NewGroupKeyItem(config.COMMITTED, 10))
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Put("Redis:txn1", tsr))
+
+	for i := 0; i < 2; i++ {
+		body, err := config.Config.Codec.Serialize(network.ReadRequest{
+			DsName:    "Redis",
+			Key:       "item1",
+			StartTime: 100,
+			Config:    txn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.Pessimistic, AblationLevel: 4},
+		})
+		assert.NoError(t, err)
+		ctx := post(s, "/read", body)
+		var resp network.ReadResponse
+		assert.NoError(t, config.Config.Codec.Deserialize(ctx.Response.Body(), &resp))
+		assert.Equal(t, "OK", resp.Status, resp.ErrMsg)
+		assert.Equal(t, "value1", resp.Data.Value())
+	}
+
+	ctx := serve(s, "/cache")
+	assert.Equal(t, network.NoopCacherStatistic, string(ctx.Response.Body()))
+}
+
+func TestServerStartTimeRegression(t *testing.T) {
+	s := newFuzzServer()
+	cfg := txn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.Pessimistic, AblationLevel: 4, TxnId: "txn1"}
+	encode := func(req any) []byte {
+		body, err := config.Config.Codec.Serialize(req)
+		assert.NoError(t, err)
+		return body
+	}
+
+	ctx := post(s, "/prepare", encode(network.PrepareRequest{
+		DsName:    "Redis",
+		ItemType:  txn.RedisItem,
+		ItemList:  []txn.DataItem{&redis.RedisItem{RKey: "item1", RValue: "value1", RGroupKeyList: "Redis:txn1"}},
+		StartTime: 100,
+		Config:    cfg,
+	}))
+	var prepareResp network.PrepareResponse
+	assert.NoError(t, config.Config.Codec.Deserialize(ctx.Response.Body(), &prepareResp))
+	assert.Equal(t, "OK", prepareResp.Status, prepareResp.ErrMsg)
+
+	read := func(startTime int64, cfg txn.RecordConfig) network.ReadResponse {
+		ctx := post(s, "/read", encode(network.ReadRequest{
+			DsName: "Redis", Key: "item1", StartTime: startTime, Config: cfg,
+		}))
+		var resp network.ReadResponse
+		assert.NoError(t, config.Config.Codec.Deserialize(ctx.Response.Body(), &resp))
+		return resp
+	}
+	resp := read(50, cfg)
+	assert.Equal(t, "Error", resp.Status)
+	assert.Contains(t, resp.ErrMsg, network.ErrStartTimeRegression.Error())
+
+	// the other transactions are not affected
+	other := cfg
+	other.TxnId = "txn2"
+	resp = read(50, other)
+	assert.NotContains(t, resp.ErrMsg, network.ErrStartTimeRegression.Error())
+
+	// nor are the batched prepares of a transaction
+	ctx = post(s, "/prepareBatch", encode(network.PrepareBatchRequest{
+		Requests: map[string]network.PrepareRequest{
+			"Redis": {
+				DsName:    "Redis",
+				ItemType:  txn.RedisItem,
+				ItemList:  []txn.DataItem{&redis.RedisItem{RKey: "item2", RValue: "value2", RGroupKeyList: "Redis:txn1"}},
+				StartTime: 50,
+				Config:    cfg,
+			},
+		},
+	}))
+	var batchResp network.PrepareBatchResponse
+	assert.NoError(t, config.Config.Codec.Deserialize(ctx.Response.Body(), &batchResp))
+	assert.Equal(t, "Error", batchResp.Responses["Redis"].Status)
+	assert.Contains(t, batchResp.Responses["Redis"].ErrMsg, network.ErrStartTimeRegression.Error())
+	_, err := s.connMap["Redis"].GetItem("item2")
+	assert.Error(t, err)
+}
+
+func TestServerHealth(t *testing.T) {
+	Log = zap.NewNop().Sugar()
+	up := memkv.NewConnection(&redis.RedisItemFactory{})
+	down := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]txn.Connector{
+		"Redis":   up,
+		"MongoDB": down,
+	}
+	s := NewServer(0, connMap, timesource.NewSimpleTimeSource())
+
+	health := func() (int, network.HealthResponse) {
+		ctx := serve(s, "/health")
+		var resp network.HealthResponse
+		assert.NoError(t, json.Unmarshal(ctx.Response.Body(), &resp))
+		return ctx.Response.StatusCode(), resp
+	}
+
+	code, resp := health()
+	assert.Equal(t, fasthttp.StatusOK, code)
+	assert.Equal(t, "OK", resp.Status)
+
+	assert.NoError(t, down.Close())
+	code, resp = health()
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, code)
+	assert.Equal(t, "Error", resp.Status)
+	assert.Len(t, resp.Datastores, 2)
+	assert.Equal(t, network.HealthUp, resp.Datastores["Redis"].Status)
+	assert.Equal(t, network.HealthDown, resp.Datastores["MongoDB"].Status)
+	assert.Equal(t, memkv.ErrClosed.Error(), resp.Datastores["MongoDB"].ErrMsg)
+
+	// the liveness probe ignores the datastores
+	assert.Equal(t, "pong", string(serve(s, "/ping").Response.Body()))
+
+	// a datastore whose connector can't be pinged is not known to be up
+	s = NewServer(0, map[string]txn.Connector{
+		"Redis":   memkv.NewConnection(&redis.RedisItemFactory{}),
+		"CouchDB": struct{ txn.Connector }{memkv.NewConnection(&redis.RedisItemFactory{})},
+	}, timesource.NewSimpleTimeSource())
+	code, resp = health()
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, code)
+	assert.Equal(t, "Error", resp.Status)
+	assert.Equal(t, network.HealthUp, resp.Datastores["Redis"].Status)
+	assert.Equal(t, network.HealthUnchecked, resp.Datastores["CouchDB"].Status)
+}
+
+func TestServerMaxTxnLifetime(t *testing.T) {
+	defer func(max time.Duration) { benConfig.MaxTxnLifetime = max }(benConfig.MaxTxnLifetime)
+	benConfig.MaxTxnLifetime = 200 * time.Millisecond
+
+	Log = zap.NewNop().Sugar()
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	clock := timesource.NewFrozenTimeSource(time.Unix(1700000000, 0))
+	s := NewServer(0, map[string]txn.Connector{"Redis": conn}, clock)
+
+	prepare := func(key string, startTime int64) network.PrepareResponse {
+		body, err := config.Config.Codec.Serialize(network.PrepareRequest{
+			DsName:    "Redis",
+			ItemType:  txn.RedisItem,
+			ItemList:  []txn.DataItem{&redis.RedisItem{RKey: key, RValue: "value1", RGroupKeyList: "Redis:" + key}},
+			StartTime: startTime,
+			Config:    txn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.Pessimistic, AblationLevel: 4},
+		})
+		assert.NoError(t, err)
+		var resp network.PrepareResponse
+		assert.NoError(t, config.Config.Codec.Deserialize(post(s, "/prepare", body).Response.Body(), &resp))
+		return resp
+	}
+
+	started, _ := clock.GetTime("start")
+	clock.Advance(10 * time.Millisecond)
+	before := time.Now()
+	resp := prepare("item1", started)
+	assert.Equal(t, "OK", resp.Status, resp.ErrMsg)
+	item, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	// the lease is capped at the max lifetime
+	assert.False(t, item.TLease().Before(before.Add(benConfig.MaxTxnLifetime)))
+	assert.True(t, item.TLease().Before(time.Now().Add(config.Config.LeaseTime)))
+
+	clock.Advance(time.Second)
+	resp = prepare("item2", started)
+	assert.Equal(t, "Error", resp.Status)
+	assert.Contains(t, resp.ErrMsg, network.ErrTxnTooOld.Error())
+	_, err = conn.GetItem("item2")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+
+	now, _ := clock.GetTime("start")
+	resp = prepare("item3", now)
+	assert.Equal(t, "OK", resp.Status, resp.ErrMsg)
+}
+
+// TestServerPrepareInvalidRequest tests that the executor rejects as a bad
+// request a prepare whose item type is not the one of its datastore, and
+// leaves the records untouched.
+func TestServerPrepareInvalidRequest(t *testing.T) {
+	Log = zap.NewNop().Sugar()
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	s := NewServer(0, map[string]txn.Connector{"Redis": conn}, timesource.NewSimpleTimeSource())
+
+	prepare := func(dsName string, itemType txn.ItemType) *fasthttp.RequestCtx {
+		body, err := config.Config.Codec.Serialize(network.PrepareRequest{
+			DsName:   dsName,
+			ItemType: itemType,
+			ItemList: []txn.DataItem{&redis.RedisItem{RKey: "item1", RValue: "value1", RGroupKeyList: dsName + ":txn1"}},
+			Config:   txn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.Pessimistic, AblationLevel: 4},
+		})
+		assert.NoError(t, err)
+		return post(s, "/prepare", body)
+	}
+
+	ctx := prepare("Redis", txn.MongoItem)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+	assert.Contains(t, string(ctx.Response.Body()), "item type mongo does not match datastore Redis")
+
+	ctx = prepare("unknown", txn.RedisItem)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+	assert.Contains(t, string(ctx.Response.Body()), "datastore unknown is not registered")
+
+	_, err := conn.GetItem("item1")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+
+	ctx = prepare("Redis", txn.RedisItem)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	_, err = conn.GetItem("item1")
+	assert.NoError(t, err)
+}
+
+func TestServerStats(t *testing.T) {
+	defer func(cfg network.HotKeyConfig) { benConfig.HotKeys = cfg }(benConfig.HotKeys)
+
+	s := newFuzzServer()
+	var resp network.StatsResponse
+	assert.NoError(t, json.Unmarshal(serve(s, "/stats").Response.Body(), &resp))
+	assert.Empty(t, resp.HotKeys)
+
+	benConfig.HotKeys = network.HotKeyConfig{Enabled: true, SampleRate: 1, TopK: 1}
+	s = newFuzzServer()
+	cfg := txn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.Pessimistic, AblationLevel: 4}
+	for i, key := range []string{"item1", "item2", "item1"} {
+		body, err := config.Config.Codec.Serialize(network.PrepareRequest{
+			DsName:   "Redis",
+			ItemType: txn.RedisItem,
+			ItemList: []txn.DataItem{&redis.RedisItem{RKey: key, RValue: "value1", RGroupKeyList: fmt.Sprintf("Redis:txn%d", i)}},
+			Config:   cfg,
+		})
+		assert.NoError(t, err)
+		post(s, "/prepare", body)
+	}
+	assert.NoError(t, json.Unmarshal(serve(s, "/stats").Response.Body(), &resp))
+	assert.Equal(t, []network.HotKey{{Key: "Redis:item1", Count: 2}}, resp.HotKeys)
+}
+
+func TestServerPeers(t *testing.T) {
+	s := newFuzzServer()
+
+	var resp network.PeersResponse
+	assert.NoError(t, json.Unmarshal(serve(s, "/peers").Response.Body(), &resp))
+	assert.Empty(t, resp.Peers)
+
+	body, err := json.Marshal(network.RegisterRequest{Addr: "http://a:8000", DsNames: []string{"Redis"}})
+	assert.NoError(t, err)
+	ctx := post(s, "/register", body)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	// an executor without datastores is rejected
+	body, err = json.Marshal(network.RegisterRequest{Addr: "http://b:8000"})
+	assert.NoError(t, err)
+	ctx = post(s, "/register", body)
+	assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+
+	// the registered executors are listed, not the configured ones
+	assert.NoError(t, json.Unmarshal(serve(s, "/peers").Response.Body(), &resp))
+	assert.Equal(t, map[string][]string{"Redis": {"http://a:8000"}}, resp.Peers)
+}
+
+// TestServerTSR tests that the client reads the TSRs through the /tsr route.
+func TestServerTSR(t *testing.T) {
+	Log = zap.NewNop().Sugar()
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	for txnId, state := range map[string]config.State{"txn1": config.COMMITTED, "txn2": config.ABORTED} {
+		tsr, err := txn.EncodeGroupKeyItem(txn.NewGroupKeyItem(state, 10))
+		assert.NoError(t, err)
+		assert.NoError(t, conn.Put("Redis:"+txnId, tsr))
+	}
+	s := NewServer(0, map[string]txn.Connector{"Redis": conn}, timesource.NewSimpleTimeSource())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &fasthttp.Server{Handler: s.route}
+	go func() { _ = server.Serve(ln) }()
+	defer func() { _ = server.Shutdown() }()
+	client := network.NewClient(map[string][]string{network.ALL: {"http://" + ln.Addr().String()}})
+
+	testCases := []struct {
+		txnId    string
+		expected config.State
+	}{
+		{"txn1", config.COMMITTED},
+		{"txn2", config.ABORTED},
+		{"txn3", config.EMPTY},
+	}
+	for _, tc := range testCases {
+		state, err := client.ReadTSR("Redis", tc.txnId)
+		assert.NoError(t, err, tc.txnId)
+		assert.Equal(t, tc.expected, state, tc.txnId)
+	}
+
+	_, err = client.ReadTSR("MongoDB", "txn1")
+	assert.ErrorContains(t, err, "connector to MongoDB is not found")
+}
+
+// TestServerTracePropagation tests that the spans of the executor join
+// the trace of the transaction whose requests it serves.
+func TestServerTracePropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+
+	s := newFuzzServer()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &fasthttp.Server{Handler: s.route}
+	go func() { _ = server.Serve(ln) }()
+	defer func() { _ = server.Shutdown() }()
+
+	client := network.NewClient(map[string][]string{"Redis": {"http://" + ln.Addr().String()}})
+	tx := txn.NewTransactionWithRemote(client, timesource.NewSimpleTimeSource())
+	_ = tx.AddDatastore(txn.NewDatastore("Redis", memkv.NewConnection(&redis.RedisItemFactory{}), &redis.RedisItemFactory{}))
+	assert.NoError(t, tx.Start())
+	var value string
+	assert.ErrorContains(t, tx.Read("Redis", "item1", &value), "key not found")
+	assert.NoError(t, tx.Write("Redis", "item1", "value1"))
+	assert.NoError(t, tx.Commit())
+
+	var root tracetest.SpanStub
+	executorSpans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		switch span.Name {
+		case "Transaction":
+			root = span
+		case "executor.Read", "executor.Prepare":
+			executorSpans[span.Name] = span
+		}
+	}
+	if !assert.True(t, root.SpanContext.IsValid(), "no transaction span") {
+		return
+	}
+	for _, name := range []string{"executor.Read", "executor.Prepare"} {
+		span, ok := executorSpans[name]
+		if !assert.True(t, ok, "no %s span", name) {
+			continue
+		}
+		assert.Equal(t, root.SpanContext.TraceID(), span.SpanContext.TraceID(), name)
+		assert.Equal(t, root.SpanContext.SpanID(), span.Parent.SpanID(), name)
+		assert.True(t, span.Parent.IsRemote(), name)
+	}
+}
@@ -0,0 +1,88 @@
+// Command inspect prints the item stored under a key in Redis,
+// together with the versions linked through its Prev field,
+// so that a key stuck in PREPARED can be looked into without writing code.
+//
+//	go build -o oreo-inspect ./inspect
+//	oreo-inspect -addr localhost:6379 -key user1 -depth 5
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// historyReader is implemented by the connectors that can walk
+// the previous versions of an item.
+type historyReader interface {
+	GetItemHistory(key string, maxDepth int) ([]txn.DataItem, error)
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:6379", "Redis address")
+	password := flag.String("password", "", "Redis password")
+	namespace := flag.String("namespace", "", "namespace the keys are stored in")
+	key := flag.String("key", "", "key of the item to inspect")
+	depth := flag.Int("depth", 0, "number of versions to print, 0 for all")
+	flag.Parse()
+
+	if *key == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	conn := redis.NewRedisConnection(&redis.ConnectionOptions{
+		Address:   *addr,
+		Password:  *password,
+		PoolSize:  1,
+		Namespace: txn.Namespace(*namespace),
+	})
+	if err := conn.Connect(); err != nil {
+		log.Fatalf("Connect to %s failed: %v", *addr, err)
+	}
+	defer conn.Close()
+
+	if err := inspect(os.Stdout, conn, *key, *depth); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// inspect writes the versions of the item stored under key to w,
+// newest first. The versions read before a Prev field fails to
+// decode are written before the error is returned.
+func inspect(w io.Writer, conn historyReader, key string, depth int) error {
+	history, err := conn.GetItemHistory(key, depth)
+	for i, item := range history {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		dumpItem(w, i, item)
+	}
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", key, err)
+	}
+	return nil
+}
+
+// dumpItem writes the fields of the i-th version of an item to w.
+func dumpItem(w io.Writer, i int, item txn.DataItem) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "[%d]\tKey:\t%s\n", i, item.Key())
+	fmt.Fprintf(tw, "\tTxnState:\t%s\n", txn.StateName(item.TxnState()))
+	fmt.Fprintf(tw, "\tTValid:\t%d (%s)\n", item.TValid(),
+		time.UnixMicro(item.TValid()).UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(tw, "\tTLease:\t%s\n", item.TLease().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(tw, "\tVersion:\t%s\n", item.Version())
+	fmt.Fprintf(tw, "\tGroupKeyList:\t%s\n", item.GroupKeyList())
+	fmt.Fprintf(tw, "\tIsDeleted:\t%t\n", item.IsDeleted())
+	fmt.Fprintf(tw, "\tLinkedLen:\t%d\n", item.LinkedLen())
+	fmt.Fprintf(tw, "\tValue:\t%s\n", item.Value())
+	tw.Flush()
+}
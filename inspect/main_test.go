@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// plantedHistory returns the planted versions of a key, up to maxDepth,
+// and err once they are exhausted.
+type plantedHistory struct {
+	items []txn.DataItem
+	err   error
+}
+
+func (p *plantedHistory) GetItemHistory(key string, maxDepth int) ([]txn.DataItem, error) {
+	if maxDepth > 0 && maxDepth < len(p.items) {
+		return p.items[:maxDepth], nil
+	}
+	return p.items, p.err
+}
+
+func plantedItems() []txn.DataItem {
+	tValid := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []txn.DataItem{
+		redis.NewRedisItem(txn.ItemOptions{
+			Key:          "user1",
+			Value:        `{"name":"new"}`,
+			GroupKeyList: "redis1:txn2",
+			TxnState:     config.PREPARED,
+			TValid:       tValid.Add(time.Second).UnixMicro(),
+			TLease:       tValid.Add(2 * time.Second),
+			LinkedLen:    2,
+			Version:      "5",
+		}),
+		redis.NewRedisItem(txn.ItemOptions{
+			Key:          "user1",
+			Value:        `{"name":"old"}`,
+			GroupKeyList: "redis1:txn1",
+			TxnState:     config.COMMITTED,
+			TValid:       tValid.UnixMicro(),
+			TLease:       tValid,
+			LinkedLen:    1,
+			IsDeleted:    true,
+			Version:      "3",
+		}),
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var out bytes.Buffer
+	err := inspect(&out, &plantedHistory{items: plantedItems()}, "user1", 0)
+	if err != nil {
+		t.Fatalf("Error inspecting a planted item: %v", err)
+	}
+
+	expected := []string{
+		"[0] Key:          user1",
+		"TxnState:     PREPARED",
+		"TValid:       1714564801000000 (2024-05-01T12:00:01Z)",
+		"TLease:       2024-05-01T12:00:02Z",
+		"Version:      5",
+		"GroupKeyList: redis1:txn2",
+		"IsDeleted:    false",
+		`Value:        {"name":"new"}`,
+		"[1] Key:          user1",
+		"TxnState:     COMMITTED",
+		"Version:      3",
+		"GroupKeyList: redis1:txn1",
+		"IsDeleted:    true",
+	}
+	dump := out.String()
+	last := 0
+	for _, line := range expected {
+		i := strings.Index(dump[last:], line)
+		if i < 0 {
+			t.Fatalf("Expected %q after offset %d in the dump:\n%s", line, last, dump)
+		}
+		last += i + len(line)
+	}
+}
+
+func TestInspectDepth(t *testing.T) {
+	var out bytes.Buffer
+	err := inspect(&out, &plantedHistory{items: plantedItems()}, "user1", 1)
+	if err != nil {
+		t.Fatalf("Error inspecting a planted item: %v", err)
+	}
+	if strings.Contains(out.String(), "[1]") {
+		t.Errorf("Expected only the latest version, got:\n%s", out.String())
+	}
+}
+
+// TestInspectBrokenPrev tests that the versions decoded
+// before a broken Prev field are still printed.
+func TestInspectBrokenPrev(t *testing.T) {
+	var out bytes.Buffer
+	planted := &plantedHistory{items: plantedItems()[:1], err: txn.DeserializeError}
+	err := inspect(&out, planted, "user1", 0)
+	if !errors.Is(err, txn.DeserializeError) {
+		t.Errorf("Expected a DeserializeError, got %v", err)
+	}
+	if !strings.Contains(out.String(), "TxnState:     PREPARED") {
+		t.Errorf("Expected the latest version before the error, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,51 @@
+// Package conntest holds the tests every txn.Connector should pass,
+// run by the tests of each connector against its own database.
+package conntest
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// Namespace tests that two connectors created by newConn with different
+// namespaces on the same database do not see each other's keys.
+// The items are created by factory.
+func Namespace(t *testing.T, newConn func(ns txn.Namespace) txn.Connector, factory txn.DataItemFactory) {
+	exp1, exp2 := newConn("exp1"), newConn("exp2")
+	key := "ns-item-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	_, err := exp1.PutItem(key, factory.NewDataItem(txn.ItemOptions{
+		Key:      key,
+		Value:    "exp1",
+		TxnState: config.COMMITTED,
+	}))
+	assert.NoError(t, err)
+	_, err = exp2.GetItem(key)
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+	// the key is read back without the namespace
+	item, err := exp1.GetItem(key)
+	assert.NoError(t, err)
+	assert.Equal(t, key, item.Key())
+	assert.Equal(t, "exp1", item.Value())
+
+	tsr := key + "-tsr"
+	_, err = exp1.AtomicCreate(tsr, config.COMMITTED)
+	assert.NoError(t, err)
+	defer func() { _ = exp1.Delete(tsr) }()
+	_, err = exp2.Get(tsr)
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+
+	// the same key is created again in the other namespace
+	_, err = exp2.AtomicCreate(tsr, config.ABORTED)
+	assert.NoError(t, err)
+	defer func() { _ = exp2.Delete(tsr) }()
+	value, err := exp1.Get(tsr)
+	assert.NoError(t, err)
+	assert.Equal(t, util.ToString(config.COMMITTED), value)
+}
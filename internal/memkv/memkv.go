@@ -0,0 +1,289 @@
+// Package memkv provides an in-memory txn.Connector for the datastore
+// and transaction tests, so that they can run without a database server.
+//
+// The connector follows the semantics of the Redis connector: the same
+// errors are returned for missing keys and failed conditions, and the
+// versions are bumped the same way by the conditional operations.
+package memkv
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+var (
+	_ txn.Connector       = (*Connection)(nil)
+	_ txn.GroupKeyScanner = (*Connection)(nil)
+	_ txn.Pinger          = (*Connection)(nil)
+	_ txn.BatchConnector  = (*Connection)(nil)
+)
+
+// ErrClosed is returned by the operations issued after Close.
+var ErrClosed = errors.Errorf("memkv: connection is closed")
+
+// Connection is a map-backed txn.Connector.
+// It is safe for concurrent use.
+type Connection struct {
+	factory txn.DataItemFactory
+	ns      txn.Namespace
+
+	*store
+	closed bool
+}
+
+// store holds the records shared by a Connection and its namespaced views.
+type store struct {
+	mu    sync.Mutex
+	items map[string]txn.ItemOptions
+	kv    map[string]string
+}
+
+// NewConnection creates an empty Connection whose items are
+// created by factory, e.g. &redis.RedisItemFactory{}.
+func NewConnection(factory txn.DataItemFactory) *Connection {
+	return &Connection{
+		factory: factory,
+		store: &store{
+			items: make(map[string]txn.ItemOptions),
+			kv:    make(map[string]string),
+		},
+	}
+}
+
+// WithNamespace returns a new Connection to the records of c
+// whose keys are stored in ns, like two connectors configured
+// with different namespaces sharing a database.
+func (c *Connection) WithNamespace(ns txn.Namespace) *Connection {
+	return &Connection{
+		factory: c.factory,
+		ns:      ns,
+		store:   c.store,
+	}
+}
+
+// Connect reopens a closed Connection. The stored records are kept.
+func (c *Connection) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = false
+	return nil
+}
+
+// Close makes any operation issued afterwards return ErrClosed.
+func (c *Connection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+// Ping returns ErrClosed once the Connection is closed.
+func (c *Connection) Ping() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// GetItem returns a copy of the item stored under key,
+// or KeyNotFound if there is none.
+func (c *Connection) GetItem(key string) (txn.DataItem, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return c.factory.NewDataItem(txn.ItemOptions{}), ErrClosed
+	}
+	opts, ok := c.items[c.ns.Key(key)]
+	if !ok {
+		return c.factory.NewDataItem(txn.ItemOptions{}), errors.New(txn.KeyNotFound)
+	}
+	return c.factory.NewDataItem(opts), nil
+}
+
+// PutItem stores value under key as it is, overwriting any existing item.
+func (c *Connection) PutItem(key string, value txn.DataItem) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return "", ErrClosed
+	}
+	c.items[c.ns.Key(key)] = optionsOf(value)
+	return "", nil
+}
+
+// ConditionalUpdate stores value under key with its version bumped by one
+// and returns the new version.
+//
+// If doCreate is true, the key must not exist; otherwise the stored
+// item must have the version of value. VersionMismatch is returned
+// when the condition fails.
+func (c *Connection) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return "", ErrClosed
+	}
+	current, ok := c.items[c.ns.Key(key)]
+	if doCreate {
+		if ok {
+			return "", errors.New(txn.VersionMismatch)
+		}
+	} else if !ok || current.Version != value.Version() {
+		return "", errors.New(txn.VersionMismatch)
+	}
+
+	newVer := util.AddToString(value.Version(), 1)
+	opts := optionsOf(value)
+	opts.Version = newVer
+	c.items[c.ns.Key(key)] = opts
+	return newVer, nil
+}
+
+// ConditionalUpdateBatch applies the conditional updates of reqs
+// like ConditionalUpdate, all of them or none: VersionMismatch is
+// returned and nothing is stored if the condition of any fails.
+func (c *Connection) ConditionalUpdateBatch(reqs []txn.ConditionalUpdateRequest) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	for _, req := range reqs {
+		current, ok := c.items[c.ns.Key(req.Key)]
+		if req.DoCreate {
+			if ok {
+				return nil, errors.New(txn.VersionMismatch)
+			}
+		} else if !ok || current.Version != req.Item.Version() {
+			return nil, errors.New(txn.VersionMismatch)
+		}
+	}
+
+	versions := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		newVer := util.AddToString(req.Item.Version(), 1)
+		opts := optionsOf(req.Item)
+		opts.Version = newVer
+		c.items[c.ns.Key(req.Key)] = opts
+		versions = append(versions, newVer)
+	}
+	return versions, nil
+}
+
+// ConditionalCommit marks the item stored under key as committed at tCommit
+// if it has version, bumps the version by one and returns it.
+// VersionMismatch is returned when the condition fails.
+func (c *Connection) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return "", ErrClosed
+	}
+	current, ok := c.items[c.ns.Key(key)]
+	if !ok || current.Version != version {
+		return "", errors.New(txn.VersionMismatch)
+	}
+
+	newVer := util.AddToString(version, 1)
+	current.TxnState = config.COMMITTED
+	current.TValid = tCommit
+	current.Version = newVer
+	c.items[c.ns.Key(key)] = current
+	return newVer, nil
+}
+
+// AtomicCreate stores value under name if it does not exist yet.
+// Otherwise it returns the existing value along with KeyExists.
+func (c *Connection) AtomicCreate(name string, value any) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return "", ErrClosed
+	}
+	if old, ok := c.kv[c.ns.Key(name)]; ok {
+		return old, errors.New(txn.KeyExists)
+	}
+	c.kv[c.ns.Key(name)] = util.ToString(value)
+	return "", nil
+}
+
+// Get returns the value stored under name, or KeyNotFound if there is none.
+func (c *Connection) Get(name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return "", ErrClosed
+	}
+	value, ok := c.kv[c.ns.Key(name)]
+	if !ok {
+		return "", errors.New(txn.KeyNotFound)
+	}
+	return value, nil
+}
+
+// Put stores value under name, overwriting any existing value.
+func (c *Connection) Put(name string, value any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	c.kv[c.ns.Key(name)] = util.ToString(value)
+	return nil
+}
+
+// Delete removes the item or the value stored under name.
+// It allows for the deletion of a key that does not exist.
+func (c *Connection) Delete(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	delete(c.items, c.ns.Key(name))
+	delete(c.kv, c.ns.Key(name))
+	return nil
+}
+
+// KeysByGroupKey returns the sorted keys of the items in the namespace
+// of c whose group key list contains groupKey.
+func (c *Connection) KeysByGroupKey(groupKey string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	var keys []string
+	for stored, item := range c.items {
+		key, ok := c.ns.Strip(stored)
+		if ok && txn.HasGroupKey(item.GroupKeyList, groupKey) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// optionsOf copies the fields of item, so that the stored item
+// is not changed through the caller's reference.
+func optionsOf(item txn.DataItem) txn.ItemOptions {
+	return txn.ItemOptions{
+		Key:          item.Key(),
+		Value:        item.Value(),
+		GroupKeyList: item.GroupKeyList(),
+		TxnState:     item.TxnState(),
+		TValid:       item.TValid(),
+		TLease:       item.TLease(),
+		Prev:         item.Prev(),
+		LinkedLen:    item.LinkedLen(),
+		IsDeleted:    item.IsDeleted(),
+		Version:      item.Version(),
+	}
+}
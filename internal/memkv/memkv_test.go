@@ -0,0 +1,389 @@
+package memkv
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/conntest"
+	"github.com/oreo-dtx-lab/oreo/internal/testutil"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func newConnection() *Connection {
+	return NewConnection(&redis.RedisItemFactory{})
+}
+
+func newItem(key string, value string, version string) *redis.RedisItem {
+	return &redis.RedisItem{
+		RKey:          key,
+		RValue:        util.ToJSONString(testutil.NewTestItem(value)),
+		RGroupKeyList: "1",
+		RTxnState:     config.COMMITTED,
+		RTValid:       time.Now().Add(-3 * time.Second).UnixMicro(),
+		RTLease:       time.Now().Add(-2 * time.Second),
+		RLinkedLen:    1,
+		RVersion:      version,
+	}
+}
+
+func TestConnectionPutAndGetItem(t *testing.T) {
+	conn := newConnection()
+	item := newItem("item1", "item1", "2")
+	_, err := conn.PutItem(item.Key(), item)
+	assert.NoError(t, err)
+
+	got, err := conn.GetItem(item.Key())
+	assert.NoError(t, err)
+	assert.True(t, item.Equal(got), "expect: %v, actual: %v", item, got)
+
+	// the stored item is a copy
+	item.RValue = "changed"
+	got.SetValue("changed")
+	stored, _ := conn.GetItem(item.Key())
+	assert.NotEqual(t, "changed", stored.Value())
+}
+
+func TestConnectionGetItemNoExist(t *testing.T) {
+	conn := newConnection()
+	_, err := conn.GetItem("item1")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+}
+
+func TestConnectionConditionalUpdate(t *testing.T) {
+	t.Run("the versions match", func(t *testing.T) {
+		conn := newConnection()
+		_, _ = conn.PutItem("item1", newItem("item1", "older", "2"))
+
+		newer := newItem("item1", "newer", "2")
+		newVer, err := conn.ConditionalUpdate("item1", newer, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "3", newVer)
+
+		got, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		newer.RVersion = "3"
+		assert.True(t, newer.Equal(got), "expect: %v, actual: %v", newer, got)
+	})
+
+	t.Run("the versions mismatch", func(t *testing.T) {
+		conn := newConnection()
+		older := newItem("item1", "older", "2")
+		_, _ = conn.PutItem("item1", older)
+
+		_, err := conn.ConditionalUpdate("item1", newItem("item1", "newer", "3"), false)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+		got, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		assert.True(t, older.Equal(got), "expect: %v, actual: %v", older, got)
+	})
+
+	t.Run("only one of the concurrent updates succeeds", func(t *testing.T) {
+		conn := newConnection()
+		_, _ = conn.PutItem("item1", newItem("item1", "older", "2"))
+
+		var mu sync.Mutex
+		successes := 0
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := conn.ConditionalUpdate("item1", newItem("item1", "newer", "2"), false)
+				if err == nil {
+					mu.Lock()
+					successes++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, successes)
+	})
+}
+
+// TestConnectionConditionalUpdateDoCreate mirrors
+// TestRedisConnectionConditionalUpdateDoCreate.
+func TestConnectionConditionalUpdateDoCreate(t *testing.T) {
+	dbItem := newItem("item1", "item1-db", "1")
+	cacheItem := newItem("item1", "item1-cache", "1")
+	cacheItem.RGroupKeyList = "2"
+	cacheItem.RPrev = util.ToJSONString(dbItem)
+	cacheItem.RLinkedLen = 2
+
+	t.Run("there is no item and doCreate is true", func(t *testing.T) {
+		conn := newConnection()
+		_, err := conn.ConditionalUpdate(cacheItem.Key(), cacheItem, true)
+		assert.NoError(t, err)
+
+		got, err := conn.GetItem(cacheItem.Key())
+		assert.NoError(t, err)
+		assert.Equal(t, "2", got.Version())
+		assert.Equal(t, cacheItem.RPrev, got.Prev())
+		assert.Equal(t, 2, got.LinkedLen())
+	})
+
+	t.Run("there is an item and doCreate is true", func(t *testing.T) {
+		conn := newConnection()
+		_, _ = conn.PutItem(dbItem.Key(), dbItem)
+		_, err := conn.ConditionalUpdate(cacheItem.Key(), cacheItem, true)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+	})
+
+	t.Run("there is no item and doCreate is false", func(t *testing.T) {
+		conn := newConnection()
+		_, err := conn.ConditionalUpdate(cacheItem.Key(), cacheItem, false)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+	})
+
+	t.Run("there is an item and doCreate is false", func(t *testing.T) {
+		conn := newConnection()
+		_, _ = conn.PutItem(dbItem.Key(), dbItem)
+		_, err := conn.ConditionalUpdate(cacheItem.Key(), cacheItem, false)
+		assert.NoError(t, err)
+	})
+}
+
+func TestConnectionConditionalCommit(t *testing.T) {
+	conn := newConnection()
+	item := newItem("item1", "item1", "1")
+	item.RTxnState = config.PREPARED
+	_, _ = conn.PutItem(item.Key(), item)
+
+	_, err := conn.ConditionalCommit(item.Key(), "2", 100)
+	assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+	newVer, err := conn.ConditionalCommit(item.Key(), "1", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", newVer)
+
+	got, err := conn.GetItem(item.Key())
+	assert.NoError(t, err)
+	item.RVersion = "2"
+	item.RTxnState = config.COMMITTED
+	item.RTValid = 100
+	assert.True(t, item.Equal(got), "expect: %v, actual: %v", item, got)
+
+	_, err = conn.ConditionalCommit("no-exist", "1", 100)
+	assert.EqualError(t, err, txn.VersionMismatch.Error())
+}
+
+func TestConnectionPutAndGet(t *testing.T) {
+	conn := newConnection()
+	_, err := conn.Get("key")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+
+	assert.NoError(t, conn.Put("key", "value"))
+	assert.NoError(t, conn.Put("key", 42))
+	value, err := conn.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", value)
+}
+
+func TestConnectionAtomicCreate(t *testing.T) {
+	conn := newConnection()
+	old, err := conn.AtomicCreate("key", config.COMMITTED)
+	assert.NoError(t, err)
+	assert.Equal(t, "", old)
+
+	old, err = conn.AtomicCreate("key", config.ABORTED)
+	assert.EqualError(t, err, txn.KeyExists.Error())
+	assert.Equal(t, util.ToString(config.COMMITTED), old)
+}
+
+func TestConnectionDeleteTwice(t *testing.T) {
+	conn := newConnection()
+	_, _ = conn.PutItem("item1", newItem("item1", "item1", "1"))
+	_ = conn.Put("key", "value")
+
+	assert.NoError(t, conn.Delete("item1"))
+	assert.NoError(t, conn.Delete("item1"))
+	assert.NoError(t, conn.Delete("key"))
+
+	_, err := conn.GetItem("item1")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+	_, err = conn.Get("key")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+}
+
+func TestConnectionKeysByGroupKey(t *testing.T) {
+	conn := newConnection()
+	for key, groupKeyList := range map[string]string{
+		"item1": "redis1:txn1,mongo1:txn1",
+		"item2": "redis1:txn1",
+		"item3": "redis1:txn10",
+	} {
+		item := newItem(key, key, "1")
+		item.RGroupKeyList = groupKeyList
+		_, _ = conn.PutItem(key, item)
+	}
+	_ = conn.Put("redis1:txn1", "tsr")
+
+	keys, err := conn.KeysByGroupKey("redis1:txn1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"item1", "item2"}, keys)
+
+	keys, err = conn.KeysByGroupKey("redis1:txn2")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+// TestConnectionNamespace tests that two connectors with different
+// namespaces on the same database do not see each other's keys.
+func TestConnectionNamespace(t *testing.T) {
+	conn := newConnection()
+	newConn := func(ns txn.Namespace) txn.Connector {
+		return conn.WithNamespace(ns)
+	}
+	conntest.Namespace(t, newConn, &redis.RedisItemFactory{})
+
+	exp1 := conn.WithNamespace("exp1")
+	exp2 := conn.WithNamespace("exp2")
+	item := newItem("item1", "exp1", "1")
+	item.RGroupKeyList = "redis1:txn1"
+	_, _ = exp1.PutItem("item1", item)
+	keys, err := exp2.KeysByGroupKey("redis1:txn1")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// the same key is created again in the other namespace
+	_, err = exp2.ConditionalUpdate("item1", newItem("item1", "exp2", ""), true)
+	assert.NoError(t, err)
+	keys, err = exp1.KeysByGroupKey("redis1:txn1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"item1"}, keys)
+
+	assert.NoError(t, exp2.Delete("item1"))
+	_, err = exp1.GetItem("item1")
+	assert.NoError(t, err)
+	_, err = conn.GetItem("item1")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+}
+
+func TestConnectionClose(t *testing.T) {
+	conn := newConnection()
+	_ = conn.Put("key", "value")
+	assert.NoError(t, conn.Close())
+	_, err := conn.Get("key")
+	assert.ErrorIs(t, err, ErrClosed)
+
+	assert.NoError(t, conn.Connect())
+	value, err := conn.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
+
+// TestConnectionTransaction tests that a Connection is a drop-in
+// for the connection of a datastore.
+func TestConnectionTransaction(t *testing.T) {
+	conn := newConnection()
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		ds := txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{})
+		_ = tx.AddDatastore(ds)
+		tx.SetGlobalDatastore(ds)
+		return tx
+	}
+
+	writer := newTxn()
+	assert.NoError(t, writer.Start())
+	assert.NoError(t, writer.Write("memkv", "John", testutil.NewDefaultPerson()))
+	assert.NoError(t, writer.Commit())
+	// the commit phase may complete asynchronously
+	time.Sleep(100 * time.Millisecond)
+
+	reader := newTxn()
+	assert.NoError(t, reader.Start())
+	var person testutil.Person
+	assert.NoError(t, reader.Read("memkv", "John", &person))
+	assert.Equal(t, testutil.NewDefaultPerson(), person)
+	assert.NoError(t, reader.Commit())
+}
+
+// TestConnectionTransactionReadFields tests that ReadFields reads
+// the requested fields of a record and leaves the others zero-valued.
+func TestConnectionTransactionReadFields(t *testing.T) {
+	type profile struct {
+		Name      string
+		Age       int
+		Bio       string
+		Followers []string
+	}
+	conn := newConnection()
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		ds := txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{})
+		_ = tx.AddDatastore(ds)
+		return tx
+	}
+
+	writer := newTxn()
+	assert.NoError(t, writer.Start())
+	assert.NoError(t, writer.Write("memkv", "John", profile{
+		Name: "John", Age: 30, Bio: "a long story", Followers: []string{"Alice", "Bob"},
+	}))
+	// the writes of the transaction are read back as well
+	var own profile
+	assert.NoError(t, writer.ReadFields("memkv", "John", []string{"Age"}, &own))
+	assert.Equal(t, profile{Age: 30}, own)
+	assert.NoError(t, writer.Commit())
+	time.Sleep(100 * time.Millisecond)
+
+	reader := newTxn()
+	assert.NoError(t, reader.Start())
+	var got profile
+	assert.NoError(t, reader.ReadFields("memkv", "John", []string{"Name", "Followers"}, &got))
+	assert.Equal(t, profile{Name: "John", Followers: []string{"Alice", "Bob"}}, got)
+
+	// a field absent from the record is left zero-valued
+	var absent profile
+	assert.NoError(t, reader.ReadFields("memkv", "John", []string{"Age", "Email"}, &absent))
+	assert.Equal(t, profile{Age: 30}, absent)
+
+	err := reader.ReadFields("memkv", "Jane", []string{"Name"}, &got)
+	assert.ErrorContains(t, err, txn.KeyNotFound.Error())
+	assert.NoError(t, reader.Commit())
+}
+
+func TestConnectionTransactionMaxWriteSetSize(t *testing.T) {
+	defer func(limit int) { config.Config.MaxWriteSetSize = limit }(config.Config.MaxWriteSetSize)
+	config.Config.MaxWriteSetSize = 3
+
+	conn := newConnection()
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		ds := txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{})
+		_ = tx.AddDatastore(ds)
+		return tx
+	}
+
+	// a transaction exactly at the limit commits
+	full := newTxn()
+	assert.NoError(t, full.Start())
+	for _, key := range []string{"item1", "item2", "item3"} {
+		assert.NoError(t, full.Write("memkv", key, "value"))
+	}
+	// rewriting a key of the write set does not grow it
+	assert.NoError(t, full.Write("memkv", "item1", "new value"))
+	assert.NoError(t, full.Delete("memkv", "item2"))
+	assert.NoError(t, full.Commit())
+	time.Sleep(100 * time.Millisecond)
+
+	over := newTxn()
+	assert.NoError(t, over.Start())
+	for _, key := range []string{"item4", "item5", "item6"} {
+		assert.NoError(t, over.Write("memkv", key, "value"))
+	}
+	err := over.Write("memkv", "item7", "value")
+	assert.ErrorIs(t, err, txn.ErrWriteSetTooLarge)
+	assert.ErrorIs(t, over.Delete("memkv", "item1"), txn.ErrWriteSetTooLarge)
+	assert.NoError(t, over.Abort())
+
+	_, err = conn.GetItem("item7")
+	assert.ErrorContains(t, err, txn.KeyNotFound.Error())
+}
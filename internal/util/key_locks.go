@@ -0,0 +1,38 @@
+package util
+
+import (
+	"slices"
+	"sync"
+)
+
+// KeyLocks serializes the holders of the same keys while the holders
+// of different keys go on concurrently. The keys are spread over a fixed
+// number of stripes, so two keys may happen to share a lock.
+type KeyLocks struct {
+	stripes []sync.Mutex
+}
+
+// NewKeyLocks returns key locks spread over n stripes.
+func NewKeyLocks(n int) *KeyLocks {
+	return &KeyLocks{stripes: make([]sync.Mutex, max(n, 1))}
+}
+
+// Lock locks every one of keys and returns the function unlocking them.
+// The stripes are locked in the same order whatever the order of keys,
+// so that two holders of overlapping keys never wait for each other.
+func (l *KeyLocks) Lock(keys []string) (unlock func()) {
+	idx := make([]int, 0, len(keys))
+	for _, key := range keys {
+		idx = append(idx, int(fnv32(key)%uint32(len(l.stripes))))
+	}
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+	for _, i := range idx {
+		l.stripes[i].Lock()
+	}
+	return func() {
+		for i := len(idx) - 1; i >= 0; i-- {
+			l.stripes[idx[i]].Unlock()
+		}
+	}
+}
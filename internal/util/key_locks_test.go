@@ -0,0 +1,49 @@
+package util
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyLocks(t *testing.T) {
+	t.Run("overlapping keys are held one at a time", func(t *testing.T) {
+		locks := NewKeyLocks(64)
+		var holders atomic.Int32
+		var wg sync.WaitGroup
+		// the keys are locked in opposite orders, which must not deadlock
+		for _, keys := range [][]string{{"a", "b", "c"}, {"c", "b", "a"}, {"b", "d"}} {
+			wg.Add(1)
+			go func(keys []string) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					unlock := locks.Lock(keys)
+					if n := holders.Add(1); n != 1 {
+						t.Errorf("Expected a single holder of the shared keys, got %d", n)
+					}
+					holders.Add(-1)
+					unlock()
+				}
+			}(keys)
+		}
+		wg.Wait()
+	})
+
+	t.Run("disjoint keys are held at once", func(t *testing.T) {
+		locks := NewKeyLocks(64)
+		unlock := locks.Lock([]string{"a"})
+		defer unlock()
+		done := make(chan struct{})
+		go func() {
+			// "a" and "b" do not share a stripe out of 64
+			locks.Lock([]string{"b"})()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the lock of a disjoint key not to wait")
+		}
+	})
+}
@@ -0,0 +1,121 @@
+// Package backoff provides the waits between the retries of a failed
+// operation, so that the client, commit, connect and oracle retries
+// share the same tunable strategies.
+//
+// A Backoff is not safe for concurrent use; every retry loop creates its own.
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff yields the sequence of waits between the retries of an operation.
+type Backoff interface {
+	// Next returns the wait before the next retry.
+	Next() time.Duration
+	// Reset restarts the sequence from its first wait.
+	Reset()
+}
+
+// Constant waits the same interval before every retry.
+type Constant struct {
+	Interval time.Duration
+}
+
+var _ Backoff = (*Constant)(nil)
+
+// NewConstant creates a Constant waiting interval before every retry.
+func NewConstant(interval time.Duration) *Constant {
+	return &Constant{Interval: interval}
+}
+
+func (c *Constant) Next() time.Duration {
+	return c.Interval
+}
+
+func (c *Constant) Reset() {}
+
+// DefaultMultiplier is the growth factor of NewExponential.
+const DefaultMultiplier = 2
+
+// Exponential waits Initial before the first retry and multiplies
+// the wait by Multiplier after every retry.
+type Exponential struct {
+	Initial    time.Duration
+	Multiplier float64
+
+	current time.Duration
+}
+
+var _ Backoff = (*Exponential)(nil)
+
+// NewExponential creates an Exponential doubling its wait from initial.
+func NewExponential(initial time.Duration) *Exponential {
+	return &Exponential{Initial: initial, Multiplier: DefaultMultiplier}
+}
+
+func (e *Exponential) Next() time.Duration {
+	if e.current == 0 {
+		e.current = e.Initial
+		return e.current
+	}
+	// the wait saturates instead of overflowing
+	next := float64(e.current) * e.Multiplier
+	if next >= math.MaxInt64 {
+		e.current = math.MaxInt64
+	} else {
+		e.current = time.Duration(next)
+	}
+	return e.current
+}
+
+func (e *Exponential) Reset() {
+	e.current = 0
+}
+
+// Capped limits the waits of a Backoff to Max.
+type Capped struct {
+	Backoff
+	Max time.Duration
+}
+
+var _ Backoff = (*Capped)(nil)
+
+// WithCap limits the waits of b to max.
+func WithCap(b Backoff, max time.Duration) *Capped {
+	return &Capped{Backoff: b, Max: max}
+}
+
+func (c *Capped) Next() time.Duration {
+	return min(c.Backoff.Next(), c.Max)
+}
+
+// Jitter randomizes the waits of a Backoff to a value between half
+// and all of them, so that clients failing together do not retry together.
+type Jitter struct {
+	Backoff
+}
+
+var _ Backoff = (*Jitter)(nil)
+
+// WithJitter randomizes the waits of b.
+func WithJitter(b Backoff) *Jitter {
+	return &Jitter{Backoff: b}
+}
+
+func (j *Jitter) Next() time.Duration {
+	d := j.Backoff.Next()
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return d - half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// NewExponentialJitter creates an Exponential doubling its wait
+// from initial, whose waits are randomized by Jitter.
+func NewExponentialJitter(initial time.Duration) *Jitter {
+	return WithJitter(NewExponential(initial))
+}
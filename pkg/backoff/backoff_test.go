@@ -0,0 +1,92 @@
+package backoff
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waits(b Backoff, n int) []time.Duration {
+	res := make([]time.Duration, n)
+	for i := range res {
+		res[i] = b.Next()
+	}
+	return res
+}
+
+func TestConstant(t *testing.T) {
+	b := NewConstant(10 * time.Millisecond)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond}, waits(b, 3))
+	b.Reset()
+	assert.Equal(t, 10*time.Millisecond, b.Next())
+}
+
+func TestExponential(t *testing.T) {
+	b := NewExponential(time.Millisecond)
+	assert.Equal(t, []time.Duration{
+		time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 8 * time.Millisecond,
+	}, waits(b, 4))
+
+	b.Reset()
+	assert.Equal(t, time.Millisecond, b.Next())
+
+	t.Run("a custom multiplier", func(t *testing.T) {
+		b := &Exponential{Initial: 10 * time.Millisecond, Multiplier: 1.5}
+		assert.Equal(t, []time.Duration{
+			10 * time.Millisecond, 15 * time.Millisecond, 22500 * time.Microsecond,
+		}, waits(b, 3))
+	})
+
+	t.Run("the wait saturates", func(t *testing.T) {
+		b := NewExponential(time.Hour)
+		for i := 0; i < 100; i++ {
+			b.Next()
+		}
+		assert.Equal(t, time.Duration(math.MaxInt64), b.Next())
+	})
+}
+
+func TestCapped(t *testing.T) {
+	b := WithCap(NewExponential(time.Millisecond), 5*time.Millisecond)
+	assert.Equal(t, []time.Duration{
+		time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond,
+	}, waits(b, 5))
+
+	b.Reset()
+	assert.Equal(t, time.Millisecond, b.Next())
+}
+
+func TestJitter(t *testing.T) {
+	b := NewExponentialJitter(time.Millisecond)
+	for round := 0; round < 100; round++ {
+		b.Reset()
+		for i, d := range waits(b, 8) {
+			base := time.Millisecond << i
+			assert.GreaterOrEqual(t, d, base/2, "wait %d below its bounds", i)
+			assert.LessOrEqual(t, d, base, "wait %d above its bounds", i)
+		}
+	}
+
+	t.Run("the waits are randomized", func(t *testing.T) {
+		b := WithJitter(NewConstant(time.Second))
+		seen := make(map[time.Duration]bool)
+		for _, d := range waits(b, 20) {
+			seen[d] = true
+		}
+		assert.Greater(t, len(seen), 1)
+	})
+
+	t.Run("a tiny wait is kept", func(t *testing.T) {
+		b := WithJitter(NewConstant(1))
+		assert.Equal(t, time.Duration(1), b.Next())
+	})
+
+	t.Run("a capped jitter stays below the cap", func(t *testing.T) {
+		b := WithJitter(WithCap(NewExponential(time.Millisecond), 3*time.Millisecond))
+		for _, d := range waits(b, 10) {
+			assert.LessOrEqual(t, d, 3*time.Millisecond)
+		}
+	})
+}
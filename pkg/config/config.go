@@ -15,6 +15,18 @@ type Mode string
 
 type ReadStrategy string
 
+// TSREncoding is the encoding of the transaction state records.
+type TSREncoding string
+
+// Compression is the algorithm compressing the values stored by the connectors.
+type Compression string
+
+// IsolationLevel decides which committed version of a record a read returns.
+type IsolationLevel string
+
+// Protocol is the protocol committing a transaction.
+type Protocol string
+
 const (
 	REMOTE Mode = "remote"
 	LOCAL  Mode = "local"
@@ -47,14 +59,55 @@ const (
 	Pessimistic  ReadStrategy = "pessimistic"
 	AssumeCommit ReadStrategy = "commit"
 	AssumeAbort  ReadStrategy = "abort"
+
+	// WaitThenResolve blocks for at most ReadWaitTime waiting for the TSR
+	// of a PREPARED record to appear, then resolves the record accordingly
+	WaitThenResolve ReadStrategy = "wait"
+
+	// TSRJSON encodes a TSR as a JSON object
+	TSRJSON TSREncoding = "json"
+
+	// TSRCompact encodes a TSR as "state:tCommit",
+	// which is cheaper to write and parse on the commit path
+	TSRCompact TSREncoding = "compact"
+
+	// Snapshot reads the versions committed before the transaction
+	// started, so that all its reads see one snapshot. It is the default.
+	Snapshot IsolationLevel = ""
+
+	// ReadCommitted reads the latest committed version of a record,
+	// so that reading a record twice may return different versions
+	ReadCommitted IsolationLevel = "read-committed"
+
+	// Oreo2PC prepares the records in every datastore in parallel,
+	// then creates the TSRs and commits the records. It is the default.
+	Oreo2PC Protocol = ""
+
+	// CherryGarcia prepares the datastores one after the other, creates a
+	// single TSR and commits before returning, and a read of a record
+	// prepared by a concurrent transaction fails instead of looking at
+	// its previous versions.
+	CherryGarcia Protocol = "cherry-garcia"
+
+	// OnePhase writes the only record written by the transaction as
+	// committed with a single conditional update, without any TSR.
+	OnePhase Protocol = "one-phase"
+
+	// NoCompression stores the values as they are
+	NoCompression Compression = ""
+
+	// GzipCompression compresses the values with gzip
+	GzipCompression Compression = "gzip"
+
+	// SnappyCompression compresses the values with snappy,
+	// which is faster but compresses less than gzip
+	SnappyCompression Compression = "snappy"
 )
 
 type debug struct {
 	// DebugMode specifies whether to enable debug mode
 	DebugMode bool
 
-	CherryGarciaMode bool
-
 	NativeMode bool
 
 	HTTPAdditionalLatency time.Duration
@@ -62,6 +115,10 @@ type debug struct {
 	ConnAdditionalLatency time.Duration
 
 	AssumptionCount int32
+
+	// CheckLinkedLen makes the connectors that support it verify the
+	// LinkedLen of every item they read or write against its Prev chain.
+	CheckLinkedLen bool
 }
 
 type config struct {
@@ -73,6 +130,10 @@ type config struct {
 	// LeaseTime specifies the duration of time for which a record is leased.
 	LeaseTime time.Duration
 
+	// LeaseRenewalThreshold specifies how close to expiring the leases of the
+	// prepared records may get before the coordinator renews them at commit time.
+	LeaseRenewalThreshold time.Duration
+
 	// MaxRecordLength specifies the maximum length of a linked record.
 	MaxRecordLength int
 
@@ -82,6 +143,9 @@ type config struct {
 	// Serializer serializes and deserializes records.
 	Serializer serializer.Serializer
 
+	// Codec encodes the messages exchanged between the client and the executor.
+	Codec serializer.Serializer
+
 	// LogLevel specifies the logging level for the application.
 	LogLevel zapcore.Level
 
@@ -97,25 +161,144 @@ type config struct {
 
 	ReadStrategy ReadStrategy
 
+	// ReadWaitTime specifies how long a read waits for the TSR
+	// when ReadStrategy is WaitThenResolve
+	ReadWaitTime time.Duration
+
+	// ReadParallelism specifies the maximum number of datastores
+	// read concurrently by Transaction.ReadMany
+	ReadParallelism int
+
+	// TimeOracleRetries specifies how many times a failed fetch
+	// from the global time oracle is retried
+	TimeOracleRetries int
+
+	// TimeOracleRetryDelay specifies the delay before each retry
+	// of a failed fetch from the global time oracle
+	TimeOracleRetryDelay time.Duration
+
+	// CommitParallelism specifies the maximum number of datastores
+	// prepared or committed concurrently by a transaction.
+	// A non-positive value means no limit.
+	CommitParallelism int
+
+	// CommitRecoveryAttempts specifies how many times the records whose
+	// commit failed are recommitted in the background before giving up
+	// and leaving them to the readers
+	CommitRecoveryAttempts int
+
+	// CommitRecoveryInterval specifies the delay before each recommit
+	// of the records whose commit failed
+	CommitRecoveryInterval time.Duration
+
+	// SlowRequestThreshold specifies how long an executor request may take
+	// before it is logged at WARN level. A non-positive value disables the log.
+	SlowRequestThreshold time.Duration
+
+	// NativeTxnCommit specifies whether a transaction writing a single
+	// datastore is committed in a native transaction of the datastore
+	// instead of the two-phase commit, if its connector supports it
+	NativeTxnCommit bool
+
+	// SingleStoreSkipTSR specifies whether a transaction writing a single
+	// datastore is committed without a TSR, writing its records as
+	// committed at once, if the connector can write them atomically
+	SingleStoreSkipTSR bool
+
+	// ReadSetValidation specifies whether Commit checks that every record
+	// read by the transaction still has the version it observed,
+	// which makes the transactions serializable
+	ReadSetValidation bool
+
+	// TSREncoding specifies how the transaction state records are written.
+	// The records are read back whichever encoding they were written in.
+	TSREncoding TSREncoding
+
+	// ValueCompression specifies how the connectors compress the value
+	// and the previous versions of a record before storing it.
+	// The records are read back whichever way they were stored.
+	ValueCompression Compression
+
+	// ValueCompressionThreshold specifies the size in bytes from which
+	// a value is compressed
+	ValueCompressionThreshold int
+
+	// ReadForUpdateLease specifies how long a key locked by
+	// Transaction.ReadForUpdate stays locked if the transaction
+	// neither commits nor aborts
+	ReadForUpdateLease time.Duration
+
+	// ReadForUpdateWait specifies how long Transaction.ReadForUpdate
+	// waits for a key locked by another transaction
+	ReadForUpdateWait time.Duration
+
+	// PrepareTimeout specifies how long the prepare phase of a single
+	// datastore may take before the transaction is aborted without
+	// waiting for it. A non-positive value means no limit.
+	PrepareTimeout time.Duration
+
+	// Protocol is the commit protocol of the transactions
+	// that do not choose theirs with SetProtocol.
+	Protocol Protocol
+
+	// CommitWait specifies whether Commit waits, once the commit time
+	// of the transaction is decided, until every client is sure to read
+	// a later time from its time source before returning. A transaction
+	// started after another has committed then always sees its writes.
+	// It adds ClockUncertainty to the latency of every commit.
+	CommitWait bool
+
+	// ClockUncertainty specifies how far apart the time sources of two
+	// clients may be at most, which Commit waits out if CommitWait is on
+	ClockUncertainty time.Duration
+
+	// MaxWriteSetSize specifies how many records a transaction may write
+	// or delete at most, a record written several times counting once.
+	// Write and Delete fail past it instead of sending an oversized
+	// prepare request. A non-positive value means no limit.
+	MaxWriteSetSize int
+
+	// ReadRepairRate specifies the fraction of the cache hits on a TSR
+	// that are checked against the datastore by the executor, which
+	// refreshes the cached entry if it is stale. 0 disables the check.
+	ReadRepairRate float64
+
+	// DisableCache makes the executor read every TSR from the datastore
+	// instead of caching the states of the transactions it has seen.
+	DisableCache bool
+
 	AblationLevel int
 }
 
 var Config = config{
 	LeaseTime:                   1000 * time.Millisecond,
+	LeaseRenewalThreshold:       200 * time.Millisecond,
 	MaxRecordLength:             2,
 	IdGenerator:                 generator.NewUUIDGenerator(),
 	Serializer:                  serializer.NewJSON2Serializer(),
+	Codec:                       serializer.NewJSON2Serializer(),
 	LogLevel:                    zapcore.InfoLevel,
 	ConcurrentOptimizationLevel: DEFAULT,
 	AsyncLevel:                  AsyncLevelZero,
 	MaxOutstandingRequest:       5,
 	ReadStrategy:                Pessimistic,
+	ReadWaitTime:                20 * time.Millisecond,
+	ReadParallelism:             8,
+	TimeOracleRetries:           2,
+	TimeOracleRetryDelay:        5 * time.Millisecond,
+	CommitRecoveryAttempts:      10,
+	CommitRecoveryInterval:      100 * time.Millisecond,
+	SlowRequestThreshold:        100 * time.Millisecond,
+	TSREncoding:                 TSRJSON,
+	ValueCompression:            NoCompression,
+	ValueCompressionThreshold:   1024,
+	ReadForUpdateLease:          1000 * time.Millisecond,
+	ReadForUpdateWait:           500 * time.Millisecond,
 	AblationLevel:               4,
 }
 
 var Debug = debug{
 	DebugMode:             false,
-	CherryGarciaMode:      false,
 	NativeMode:            false,
 	HTTPAdditionalLatency: 0,
 	ConnAdditionalLatency: 0,
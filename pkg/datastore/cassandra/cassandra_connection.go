@@ -1,6 +1,7 @@
 package cassandra
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -13,6 +14,7 @@ import (
 )
 
 var _ txn.Connector = (*CassandraConnection)(nil)
+var _ txn.Pinger = (*CassandraConnection)(nil)
 
 type CassandraConnection struct {
 	session      *gocql.Session
@@ -25,6 +27,9 @@ type ConnectionOptions struct {
 	Keyspace string
 	Username string
 	Password string
+	// Namespace prefixes the key of every row
+	// stored through the connection.
+	Namespace txn.Namespace
 }
 
 func NewCassandraConnection(config *ConnectionOptions) *CassandraConnection {
@@ -45,22 +50,24 @@ func (c *CassandraConnection) Connect() error {
 		return nil
 	}
 
-	cluster := gocql.NewCluster(c.config.Hosts...)
+	cluster := c.newCluster()
 	cluster.Keyspace = c.config.Keyspace
-	cluster.Consistency = gocql.Quorum
-	cluster.ProtoVersion = 4
-
-	if c.config.Username != "" {
-		cluster.Authenticator = gocql.PasswordAuthenticator{
-			Username: c.config.Username,
-			Password: c.config.Password,
-		}
-	}
 
 	session, err := cluster.CreateSession()
 	if err != nil {
 		return err
 	}
+	// refuse a table written with other column types, such as the
+	// timestamp t_lease of the older deployments, rather than
+	// misreading every lease
+	existing, err := itemColumnTypes(session, c.config.Keyspace)
+	if err == nil {
+		err = checkColumnTypes(c.config.Keyspace, existing)
+	}
+	if err != nil {
+		session.Close()
+		return err
+	}
 
 	c.session = session
 	c.hasConnected = true
@@ -79,6 +86,49 @@ func (c *CassandraConnection) Connect() error {
 	return nil
 }
 
+// newCluster returns the configuration of the sessions to the cluster.
+func (c *CassandraConnection) newCluster() *gocql.ClusterConfig {
+	cluster := gocql.NewCluster(c.config.Hosts...)
+	cluster.Consistency = gocql.Quorum
+	cluster.ProtoVersion = 4
+
+	if c.config.Username != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: c.config.Username,
+			Password: c.config.Password,
+		}
+	}
+	return cluster
+}
+
+// pingTimeout bounds the wait of Ping.
+const pingTimeout = 2 * time.Second
+
+// Ping checks that the cluster answers a query within pingTimeout.
+func (c *CassandraConnection) Ping() error {
+	if !c.hasConnected {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	return c.session.Query("SELECT release_version FROM system.local").WithContext(ctx).Exec()
+}
+
+// Close closes the Cassandra session.
+func (c *CassandraConnection) Close() error {
+	if !c.hasConnected {
+		return nil
+	}
+	c.hasConnected = false
+	c.session.Close()
+	return nil
+}
+
+// rowKey returns the key of the row under which key is stored.
+func (c *CassandraConnection) rowKey(key string) string {
+	return c.config.Namespace.Key(key)
+}
+
 func (c *CassandraConnection) GetItem(key string) (txn.DataItem, error) {
 	if !c.hasConnected {
 		return &CassandraItem{}, fmt.Errorf("not connected to Cassandra")
@@ -88,11 +138,12 @@ func (c *CassandraConnection) GetItem(key string) (txn.DataItem, error) {
 	}
 
 	var item CassandraItem
+	var tLease int64
 	err := c.session.Query(`
         SELECT key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version 
-        FROM items WHERE key = ?`, key).Scan(
+        FROM items WHERE key = ?`, c.rowKey(key)).Scan(
 		&item.CKey, &item.CValue, &item.CGroupKeyList, &item.CTxnState,
-		&item.CTValid, &item.CTLease, &item.CPrev, &item.CLinkedLen,
+		&item.CTValid, &tLease, &item.CPrev, &item.CLinkedLen,
 		&item.CIsDeleted, &item.CVersion)
 
 	if err == gocql.ErrNotFound {
@@ -101,6 +152,8 @@ func (c *CassandraConnection) GetItem(key string) (txn.DataItem, error) {
 	if err != nil {
 		return &CassandraItem{}, errors.New("version mismatch")
 	}
+	item.CKey, _ = c.config.Namespace.Strip(item.CKey)
+	item.CTLease = txn.DecodeLease(tLease)
 	return &item, nil
 }
 
@@ -120,8 +173,8 @@ func (c *CassandraConnection) PutItem(key string, value txn.DataItem) (string, e
 	err := c.session.Query(`
         INSERT INTO items (key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version)
         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		key, item.CValue, item.CGroupKeyList, item.CTxnState,
-		item.CTValid, item.CTLease, item.CPrev, item.CLinkedLen,
+		c.rowKey(key), item.CValue, item.CGroupKeyList, item.CTxnState,
+		item.CTValid, txn.EncodeLease(item.CTLease), item.CPrev, item.CLinkedLen,
 		item.CIsDeleted, item.CVersion).Exec()
 
 	if err != nil {
@@ -146,35 +199,33 @@ func (c *CassandraConnection) ConditionalUpdate(key string, value txn.DataItem,
 		}
 
 		// 使用 Cassandra 的轻量级事务(LWT)确保原子性
-		applied, err := c.session.Query(`
+		applied, err := scanCAS(c.session.Query(`
             INSERT INTO items (key, value, group_key_list, txn_state, t_valid, t_lease, prev, linked_len, is_deleted, version)
             VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
             IF NOT EXISTS`,
-			key, value.Value(), value.GroupKeyList(), value.TxnState(),
-			value.TValid(), value.TLease(), value.Prev(), value.LinkedLen(),
-			value.IsDeleted(), newVer).ScanCAS()
+			c.rowKey(key), value.Value(), value.GroupKeyList(), value.TxnState(),
+			value.TValid(), txn.EncodeLease(value.TLease()), value.Prev(), value.LinkedLen(),
+			value.IsDeleted(), newVer))
 
 		if err != nil {
 			return "", errors.New(fmt.Sprintf("ConditionalUpdate(doCreate) key %s failed, err: %v", key, err))
 		}
 		if !applied {
-			return "", errors.New("key exists")
+			return "", errors.New(txn.VersionMismatch)
 		}
 		return newVer, nil
 	}
 
 	// 更新现有记录，使用 LWT 确保版本匹配
-	applied, err := c.session.Query(`
+	applied, err := scanCAS(c.session.Query(`
         UPDATE items 
         SET value = ?, group_key_list = ?, txn_state = ?, t_valid = ?, 
             t_lease = ?, prev = ?, linked_len = ?, is_deleted = ?, version = ?
         WHERE key = ?
         IF version = ?`,
 		value.Value(), value.GroupKeyList(), value.TxnState(), value.TValid(),
-		value.TLease(), value.Prev(), value.LinkedLen(), value.IsDeleted(),
-		newVer, key, value.Version()).ScanCAS()
-	// gocql: not enough columns to scan into: have 1 want 2
-	// this is ok because it only occurs when the conditional update fails
+		txn.EncodeLease(value.TLease()), value.Prev(), value.LinkedLen(), value.IsDeleted(),
+		newVer, c.rowKey(key), value.Version()))
 
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("ConditionalUpdate key %s failed, err: %v", key, err))
@@ -186,6 +237,14 @@ func (c *CassandraConnection) ConditionalUpdate(key string, value txn.DataItem,
 	return newVer, nil
 }
 
+// scanCAS executes a lightweight transaction and reports whether it was applied.
+// When the condition fails, Cassandra returns the current values of the row
+// along with [applied], so they are scanned into a throwaway map instead of
+// making ScanCAS fail with "not enough columns to scan into".
+func scanCAS(q *gocql.Query) (bool, error) {
+	return q.MapScanCAS(make(map[string]interface{}))
+}
+
 func (c *CassandraConnection) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
 	if !c.hasConnected {
 		return "", fmt.Errorf("not connected to Cassandra")
@@ -194,12 +253,12 @@ func (c *CassandraConnection) ConditionalCommit(key string, version string, tCom
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	applied, err := c.session.Query(`
+	applied, err := scanCAS(c.session.Query(`
         UPDATE items 
         SET txn_state = ?, t_valid = ?
         WHERE key = ?
         IF version = ?`,
-		config.COMMITTED, tCommit, key, version).ScanCAS()
+		config.COMMITTED, tCommit, c.rowKey(key), version))
 
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("ConditionalCommit key %s failed, err: %v", key, err))
@@ -220,18 +279,18 @@ func (c *CassandraConnection) AtomicCreate(name string, value any) (string, erro
 	}
 
 	strValue := util.ToString(value)
-	applied, err := c.session.Query(`
+	applied, err := scanCAS(c.session.Query(`
         INSERT INTO kv (key, value)
         VALUES (?, ?)
         IF NOT EXISTS`,
-		name, strValue).ScanCAS()
+		c.rowKey(name), strValue))
 
 	if err != nil {
 		return "", err
 	}
 	if !applied {
 		var existingValue string
-		err = c.session.Query(`SELECT value FROM kv WHERE key = ?`, name).Scan(&existingValue)
+		err = c.session.Query(`SELECT value FROM kv WHERE key = ?`, c.rowKey(name)).Scan(&existingValue)
 		if err != nil {
 			return "", errors.New(fmt.Sprintf("get key %s failed, err: %v", name, err))
 		}
@@ -250,7 +309,7 @@ func (c *CassandraConnection) Get(name string) (string, error) {
 	}
 
 	var value string
-	err := c.session.Query(`SELECT value FROM kv WHERE key = ?`, name).Scan(&value)
+	err := c.session.Query(`SELECT value FROM kv WHERE key = ?`, c.rowKey(name)).Scan(&value)
 	if err == gocql.ErrNotFound {
 		return "", errors.New(txn.KeyNotFound)
 	}
@@ -272,7 +331,7 @@ func (c *CassandraConnection) Put(name string, value interface{}) error {
 	err := c.session.Query(`
         INSERT INTO kv (key, value)
         VALUES (?, ?)`,
-		name, strValue).Exec()
+		c.rowKey(name), strValue).Exec()
 
 	if err != nil {
 		return errors.New(fmt.Sprintf("put key %s failed, err: %v", name, err))
@@ -288,7 +347,7 @@ func (c *CassandraConnection) Delete(name string) error {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	err := c.session.Query(`DELETE FROM kv WHERE key = ?`, name).Exec()
+	err := c.session.Query(`DELETE FROM kv WHERE key = ?`, c.rowKey(name)).Exec()
 	if err != nil {
 		return errors.New(fmt.Sprintf("delete key %s failed, err: %v", name, err))
 	}
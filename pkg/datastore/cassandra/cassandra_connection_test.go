@@ -0,0 +1,223 @@
+package cassandra
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/conntest"
+	"github.com/oreo-dtx-lab/oreo/internal/testutil"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewDefaultCassandraConnection(t *testing.T) *CassandraConnection {
+	conn := NewCassandraConnection(nil)
+	err := conn.Connect()
+	assert.NoError(t, err)
+	return conn
+}
+
+func deleteItem(t *testing.T, conn *CassandraConnection, key string) {
+	err := conn.session.Query(`DELETE FROM items WHERE key = ?`, key).Exec()
+	assert.NoError(t, err)
+}
+
+func TestCassandraConnectionConditionalUpdateDoCreate(t *testing.T) {
+	dbItem := &CassandraItem{
+		CKey:          "item1",
+		CValue:        util.ToJSONString(testutil.NewTestItem("item1-db")),
+		CGroupKeyList: "1",
+		CTxnState:     config.COMMITTED,
+		CTValid:       time.Now().Add(-3 * time.Second).UnixMicro(),
+		CTLease:       time.Now().Add(-2 * time.Second),
+		CLinkedLen:    1,
+		CVersion:      "1",
+	}
+
+	cacheItem := &CassandraItem{
+		CKey:          "item1",
+		CValue:        util.ToJSONString(testutil.NewTestItem("item1-cache")),
+		CGroupKeyList: "2",
+		CTxnState:     config.PREPARED,
+		CTValid:       time.Now().Add(-2 * time.Second).UnixMicro(),
+		CTLease:       time.Now().Add(-1 * time.Second),
+		CLinkedLen:    1,
+	}
+
+	conn := NewDefaultCassandraConnection(t)
+	defer conn.Close()
+
+	t.Run("there is no item and doCreate is true", func(t *testing.T) {
+		deleteItem(t, conn, cacheItem.Key())
+
+		_, err := conn.ConditionalUpdate(cacheItem.Key(), cacheItem, true)
+		assert.NoError(t, err)
+	})
+
+	t.Run("there is an item and doCreate is true", func(t *testing.T) {
+		_, err := conn.PutItem(dbItem.Key(), dbItem)
+		assert.NoError(t, err)
+
+		_, err = conn.ConditionalUpdate(cacheItem.Key(), cacheItem, true)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+	})
+
+	t.Run("there is an item with another version", func(t *testing.T) {
+		_, err := conn.PutItem(dbItem.Key(), dbItem)
+		assert.NoError(t, err)
+
+		item := *cacheItem
+		item.CVersion = "2"
+		_, err = conn.ConditionalUpdate(item.Key(), &item, false)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+	})
+}
+
+func TestCassandraConnectionConditionalUpdateConcurrently(t *testing.T) {
+	conn := NewDefaultCassandraConnection(t)
+	defer conn.Close()
+
+	newItem := func(id int, version string) *CassandraItem {
+		return &CassandraItem{
+			CKey:          "item1",
+			CValue:        util.ToJSONString(testutil.NewTestItem("item1-" + strconv.Itoa(id))),
+			CGroupKeyList: strconv.Itoa(id),
+			CTxnState:     config.PREPARED,
+			CTValid:       time.Now().UnixMicro(),
+			CTLease:       time.Now().Add(time.Second),
+			CLinkedLen:    1,
+			CVersion:      version,
+		}
+	}
+
+	// runConcurrently issues the conditional update from every goroutine
+	// and returns the ids of those that succeeded.
+	runConcurrently := func(version string, doCreate bool) []int {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		winners := make([]int, 0)
+		for i := 1; i <= 20; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				_, err := conn.ConditionalUpdate("item1", newItem(id, version), doCreate)
+				if err == nil {
+					mu.Lock()
+					winners = append(winners, id)
+					mu.Unlock()
+					return
+				}
+				assert.EqualError(t, err, txn.VersionMismatch.Error())
+			}(i)
+		}
+		wg.Wait()
+		return winners
+	}
+
+	t.Run("this is an update", func(t *testing.T) {
+		_, err := conn.PutItem("item1", newItem(0, "1"))
+		assert.NoError(t, err)
+
+		winners := runConcurrently("1", false)
+		assert.Len(t, winners, 1)
+
+		item, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(winners[0]), item.GroupKeyList())
+		assert.Equal(t, "2", item.Version())
+	})
+
+	t.Run("this is a create", func(t *testing.T) {
+		deleteItem(t, conn, "item1")
+
+		winners := runConcurrently("", true)
+		assert.Len(t, winners, 1)
+
+		item, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(winners[0]), item.GroupKeyList())
+	})
+}
+
+func TestCassandraConnection_EnsureSchema(t *testing.T) {
+	conn := NewCassandraConnection(&ConnectionOptions{
+		Hosts:    []string{"localhost"},
+		Keyspace: "schema_" + strconv.FormatInt(time.Now().UnixNano(), 10),
+	})
+	assert.NoError(t, conn.EnsureSchema())
+	assert.NoError(t, conn.Connect())
+	defer func() {
+		_ = conn.session.Query("DROP KEYSPACE " + conn.config.Keyspace).Exec()
+	}()
+
+	// a column dropped from the items table is added back
+	assert.NoError(t, conn.session.Query("ALTER TABLE items DROP version").Exec())
+	assert.NoError(t, conn.EnsureSchema())
+
+	var count int
+	err := conn.session.Query(`SELECT COUNT(*) FROM system_schema.columns
+        WHERE keyspace_name = ? AND table_name = 'items'`, conn.config.Keyspace).Scan(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, len(itemColumns), count)
+}
+
+// TestCassandraConnection_ConnectRefusesTimestampLease tests that Connect
+// refuses an items table whose t_lease is still a timestamp.
+func TestCassandraConnection_ConnectRefusesTimestampLease(t *testing.T) {
+	conn := NewCassandraConnection(&ConnectionOptions{
+		Hosts:    []string{"localhost"},
+		Keyspace: "schema_" + strconv.FormatInt(time.Now().UnixNano(), 10),
+	})
+	assert.NoError(t, conn.EnsureSchema())
+	assert.NoError(t, conn.Connect())
+	defer func() {
+		_ = conn.session.Query("DROP KEYSPACE " + conn.config.Keyspace).Exec()
+	}()
+
+	// an items table created by an older deployment
+	assert.NoError(t, conn.session.Query("DROP TABLE items").Exec())
+	assert.NoError(t, conn.session.Query(`CREATE TABLE items (
+    key text, value text, group_key_list text, txn_state int, t_valid bigint,
+    t_lease timestamp, prev text, linked_len int, is_deleted boolean, version text,
+    PRIMARY KEY (key))`).Exec())
+
+	old := NewCassandraConnection(&conn.config)
+	err := old.Connect()
+	assert.ErrorContains(t, err, "column t_lease")
+	assert.Error(t, old.EnsureSchema())
+}
+
+func TestCheckColumnTypes(t *testing.T) {
+	existing := make(map[string]string, len(itemColumns))
+	for _, col := range itemColumns {
+		existing[col.name] = col.cqlType
+	}
+	assert.NoError(t, checkColumnTypes("oreo", existing))
+
+	// the columns missing are left to EnsureSchema
+	delete(existing, "version")
+	assert.NoError(t, checkColumnTypes("oreo", existing))
+
+	existing["t_lease"] = "timestamp"
+	assert.EqualError(t, checkColumnTypes("oreo", existing),
+		"column t_lease of oreo.items is a timestamp, expect bigint")
+}
+
+// TestCassandraConnection_Namespace tests that two connections with different
+// namespaces on the same database do not see each other's keys.
+func TestCassandraConnection_Namespace(t *testing.T) {
+	newConn := func(ns txn.Namespace) txn.Connector {
+		conn := NewCassandraConnection(&ConnectionOptions{
+			Hosts:     []string{"localhost"},
+			Keyspace:  "oreo",
+			Namespace: ns,
+		})
+		assert.NoError(t, conn.Connect())
+		return conn
+	}
+	conntest.Namespace(t, newConn, &CassandraItemFactory{})
+}
@@ -1,6 +1,7 @@
 package cassandra
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -42,7 +43,7 @@ CREATE TABLE IF NOT EXISTS items (
     group_key_list text,
     txn_state int,
     t_valid bigint,
-    t_lease timestamp,
+    t_lease bigint,
     prev text,
     linked_len int,
     is_deleted boolean,
@@ -183,3 +184,31 @@ func (c *CassandraItem) String() string {
 		c.CTValid, c.CTLease.Format(time.RFC3339),
 		c.CPrev, c.CLinkedLen, c.CIsDeleted, c.Version())
 }
+
+// cassandraItemFields has the fields of CassandraItem without its methods.
+type cassandraItemFields CassandraItem
+
+// MarshalJSON encodes TLease as a number by txn.EncodeLease.
+func (c CassandraItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		cassandraItemFields
+		CTLease int64 `json:"TLease"`
+	}{cassandraItemFields(c), txn.EncodeLease(c.CTLease)})
+}
+
+// UnmarshalJSON accepts TLease encoded as a number or in RFC 3339.
+func (c *CassandraItem) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*cassandraItemFields
+		CTLease json.RawMessage `json:"TLease"`
+	}{cassandraItemFields: (*cassandraItemFields)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	lease, err := txn.UnmarshalLease(aux.CTLease)
+	if err != nil {
+		return err
+	}
+	c.CTLease = lease
+	return nil
+}
@@ -4,6 +4,10 @@ import "github.com/oreo-dtx-lab/oreo/pkg/txn"
 
 var _ txn.DataItemFactory = (*CassandraItemFactory)(nil)
 
+func init() {
+	txn.RegisterItemFactory(txn.CassandraItem, &CassandraItemFactory{})
+}
+
 type CassandraItemFactory struct{}
 
 func (c *CassandraItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
@@ -0,0 +1,119 @@
+package cassandra
+
+import (
+	"fmt"
+
+	"github.com/go-errors/errors"
+	"github.com/gocql/gocql"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+var _ txn.SchemaEnsurer = (*CassandraConnection)(nil)
+
+// itemColumns are the columns of the items table and their CQL types.
+var itemColumns = []struct{ name, cqlType string }{
+	{"key", "text"},
+	{"value", "text"},
+	{"group_key_list", "text"},
+	{"txn_state", "int"},
+	{"t_valid", "bigint"},
+	// the lease is stored by txn.EncodeLease, as a timestamp column
+	// would truncate it to milliseconds
+	{"t_lease", "bigint"},
+	{"prev", "text"},
+	{"linked_len", "int"},
+	{"is_deleted", "boolean"},
+	{"version", "text"},
+}
+
+// EnsureSchema creates the keyspace, with a replication factor of 1,
+// the items table and the kv table if they do not exist. The columns
+// missing from an existing items table, such as the version column of
+// the older deployments, are added to it, while a column of another
+// type fails, as it does in Connect.
+//
+// It connects without a keyspace, so it is called before Connect,
+// which fails if the keyspace does not exist.
+func (c *CassandraConnection) EnsureSchema() error {
+	session, err := c.newCluster().CreateSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	ks := c.config.Keyspace
+	statements := []string{
+		fmt.Sprintf(`CREATE KEYSPACE IF NOT EXISTS %s
+    WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}`, ks),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.items (
+    key text,
+    value text,
+    group_key_list text,
+    txn_state int,
+    t_valid bigint,
+    t_lease bigint,
+    prev text,
+    linked_len int,
+    is_deleted boolean,
+    version text,
+    PRIMARY KEY (key)
+) WITH gc_grace_seconds = 172800`, ks),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.kv (
+    key text,
+    value text,
+    PRIMARY KEY (key)
+)`, ks),
+	}
+	for _, stmt := range statements {
+		if err := session.Query(stmt).Exec(); err != nil {
+			return err
+		}
+	}
+
+	existing, err := itemColumnTypes(session, ks)
+	if err != nil {
+		return err
+	}
+	for _, col := range itemColumns {
+		if _, ok := existing[col.name]; ok {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s.items ADD %s %s", ks, col.name, col.cqlType)
+		if err := session.Query(stmt).Exec(); err != nil {
+			return err
+		}
+	}
+	return checkColumnTypes(ks, existing)
+}
+
+// itemColumnTypes returns the CQL types of the columns of the items table
+// in keyspace ks, which is empty if the table does not exist.
+func itemColumnTypes(session *gocql.Session, ks string) (map[string]string, error) {
+	existing := make(map[string]string, len(itemColumns))
+	iter := session.Query(`SELECT column_name, type FROM system_schema.columns
+        WHERE keyspace_name = ? AND table_name = 'items'`, ks).Iter()
+	var name, cqlType string
+	for iter.Scan(&name, &cqlType) {
+		existing[name] = cqlType
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// checkColumnTypes returns an error if a column of existing is not of the
+// type the connection reads and writes it as. The t_lease column of the
+// older deployments is a timestamp, into which gocql would silently
+// truncate the encoded leases to milliseconds, and which can only be
+// fixed by recreating the table, as Cassandra cannot change its type.
+func checkColumnTypes(ks string, existing map[string]string) error {
+	for _, col := range itemColumns {
+		cqlType, ok := existing[col.name]
+		if ok && cqlType != col.cqlType {
+			return errors.Errorf("column %s of %s.items is a %s, expect %s",
+				col.name, ks, cqlType, col.cqlType)
+		}
+	}
+	return nil
+}
@@ -24,7 +24,7 @@ var createStatements = []string{
         group_key_list text,
         txn_state int,
         t_valid bigint,
-        t_lease timestamp,
+        t_lease bigint,
         prev text,
         linked_len int,
         is_deleted boolean,
@@ -18,6 +18,8 @@ import (
 )
 
 var _ txn.Connector = (*CouchDBConnection)(nil)
+var _ txn.BulkConnector = (*CouchDBConnection)(nil)
+var _ txn.Pinger = (*CouchDBConnection)(nil)
 
 var httpClient = &http.Client{
 	Transport: &http.Transport{
@@ -41,6 +43,9 @@ type ConnectionOptions struct {
 	Username string
 	Password string
 	DBName   string
+	// Namespace prefixes the id of every document
+	// stored through the connection.
+	Namespace txn.Namespace
 }
 
 func NewCouchDBConnection(config *ConnectionOptions) *CouchDBConnection {
@@ -103,6 +108,40 @@ func (r *CouchDBConnection) Connect() error {
 	return nil
 }
 
+// docID returns the id of the document under which key is stored.
+func (r *CouchDBConnection) docID(key string) string {
+	return r.config.Namespace.Key(key)
+}
+
+// pingTimeout bounds the wait of Ping.
+const pingTimeout = 2 * time.Second
+
+// Ping checks that the server answers within pingTimeout.
+func (r *CouchDBConnection) Ping() error {
+	if !r.hasConnected {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	up, err := r.client.Ping(ctx)
+	if err != nil {
+		return err
+	}
+	if !up {
+		return errors.New("CouchDB is not up")
+	}
+	return nil
+}
+
+// Close closes the CouchDB client.
+func (r *CouchDBConnection) Close() error {
+	if !r.hasConnected {
+		return nil
+	}
+	r.hasConnected = false
+	return r.client.Close()
+}
+
 func (r *CouchDBConnection) GetItem(key string) (txn.DataItem, error) {
 	if !r.hasConnected {
 		return &CouchDBItem{}, fmt.Errorf("not connected to CouchDB")
@@ -111,7 +150,7 @@ func (r *CouchDBConnection) GetItem(key string) (txn.DataItem, error) {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	row := r.db.Get(context.Background(), key)
+	row := r.db.Get(context.Background(), r.docID(key))
 	var value CouchDBItem
 	err := row.ScanDoc(&value)
 	if err != nil {
@@ -132,7 +171,7 @@ func (r *CouchDBConnection) PutItem(key string, value txn.DataItem) (string, err
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	rev, err := r.db.Put(context.Background(), key, value, nil)
+	rev, err := r.db.Put(context.Background(), r.docID(key), value, nil)
 	if err != nil {
 		return "", err
 	}
@@ -153,10 +192,10 @@ func (r *CouchDBConnection) ConditionalUpdate(key string, value txn.DataItem, do
 			return "", errors.New(txn.VersionMismatch)
 		}
 		// 创建模式，直接尝试创建文档
-		newVer, err := r.db.Put(context.Background(), key, value)
+		newVer, err := r.db.Put(context.Background(), r.docID(key), value)
 		if err != nil {
 			if kivik.HTTPStatus(err) == http.StatusConflict {
-				return "", errors.New("key exists")
+				return "", errors.New(txn.KeyExists)
 			}
 			return "", err
 		}
@@ -164,7 +203,7 @@ func (r *CouchDBConnection) ConditionalUpdate(key string, value txn.DataItem, do
 	}
 
 	// Update the document
-	newVer, err := r.db.Put(context.Background(), key, value)
+	newVer, err := r.db.Put(context.Background(), r.docID(key), value)
 	if err != nil {
 		if kivik.HTTPStatus(err) == http.StatusConflict {
 			return "", errors.New(txn.VersionMismatch)
@@ -175,6 +214,64 @@ func (r *CouchDBConnection) ConditionalUpdate(key string, value txn.DataItem, do
 	return newVer, nil
 }
 
+// bulkDoc carries the document id required by _bulk_docs.
+type bulkDoc struct {
+	ID string `json:"_id"`
+	*CouchDBItem
+}
+
+// ConditionalUpdateBulk writes the items in a single _bulk_docs request.
+// CouchDB applies each document independently, so a conflict only fails
+// the request of that document with VersionMismatch.
+func (r *CouchDBConnection) ConditionalUpdateBulk(reqs []txn.ConditionalUpdateRequest) ([]txn.ConditionalUpdateResult, error) {
+	if !r.hasConnected {
+		return nil, fmt.Errorf("not connected to CouchDB")
+	}
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	results := make([]txn.ConditionalUpdateResult, len(reqs))
+	docs := make([]interface{}, 0, len(reqs))
+	// docIndex maps the index in docs to the index in reqs
+	docIndex := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		item, ok := req.Item.(*CouchDBItem)
+		if !ok {
+			return nil, errors.Errorf("unexpected item type %T", req.Item)
+		}
+		if req.DoCreate && item.Version() != "" {
+			results[i].Err = errors.New(txn.VersionMismatch)
+			continue
+		}
+		docs = append(docs, bulkDoc{ID: r.docID(req.Key), CouchDBItem: item})
+		docIndex = append(docIndex, i)
+	}
+	if len(docs) == 0 {
+		return results, nil
+	}
+
+	bulkResults, err := r.db.BulkDocs(context.Background(), docs)
+	if err != nil {
+		return nil, err
+	}
+	if len(bulkResults) != len(docs) {
+		return nil, errors.Errorf("got %d results for %d documents", len(bulkResults), len(docs))
+	}
+	for j, res := range bulkResults {
+		i := docIndex[j]
+		switch {
+		case res.Error == nil:
+			results[i].Version = res.Rev
+		case kivik.HTTPStatus(res.Error) == http.StatusConflict:
+			results[i].Err = errors.New(txn.VersionMismatch)
+		default:
+			results[i].Err = res.Error
+		}
+	}
+	return results, nil
+}
+
 func (r *CouchDBConnection) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
 	if !r.hasConnected {
 		return "", fmt.Errorf("not connected to CouchDB")
@@ -184,7 +281,7 @@ func (r *CouchDBConnection) ConditionalCommit(key string, version string, tCommi
 	}
 
 	var existing CouchDBItem
-	err := r.db.Get(context.Background(), key).ScanDoc(&existing)
+	err := r.db.Get(context.Background(), r.docID(key)).ScanDoc(&existing)
 
 	if err != nil {
 		return "", errors.New(txn.VersionMismatch)
@@ -193,7 +290,7 @@ func (r *CouchDBConnection) ConditionalCommit(key string, version string, tCommi
 	existing.SetTxnState(config.COMMITTED)
 	existing.SetTValid(tCommit)
 	// Update the document
-	newVer, err := r.db.Put(context.Background(), key, existing)
+	newVer, err := r.db.Put(context.Background(), r.docID(key), existing)
 	if err != nil {
 		return "", txn.VersionMismatch
 	}
@@ -213,7 +310,7 @@ func (r *CouchDBConnection) AtomicCreate(name string, value any) (string, error)
 		"value": util.ToString(value),
 	}
 
-	_, err := r.db.Put(context.Background(), name, value)
+	_, err := r.db.Put(context.Background(), r.docID(name), value)
 	if err != nil {
 		oldValue, _ := r.Get(name)
 		return oldValue, errors.New(txn.KeyExists)
@@ -230,7 +327,7 @@ func (r *CouchDBConnection) Get(name string) (string, error) {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	row := r.db.Get(context.Background(), name)
+	row := r.db.Get(context.Background(), r.docID(name))
 	var value map[string]string
 	if err := row.ScanDoc(&value); err != nil {
 		if kivik.HTTPStatus(err) == http.StatusNotFound {
@@ -261,7 +358,7 @@ func (r *CouchDBConnection) Put(name string, value interface{}) error {
 		}
 	}
 
-	_, err := r.db.Put(context.Background(), name, value)
+	_, err := r.db.Put(context.Background(), r.docID(name), value)
 	if err != nil {
 		return err
 	}
@@ -281,13 +378,13 @@ func (r *CouchDBConnection) Delete(name string) error {
 		Rev string `json:"_rev,omitempty"`
 	}
 
-	row := r.db.Get(context.Background(), name)
+	row := r.db.Get(context.Background(), r.docID(name))
 	var rev Item
 
 	if err := row.ScanDoc(&rev); err != nil {
 		return err
 	}
-	_, err := r.db.Delete(context.Background(), name, rev.Rev)
+	_, err := r.db.Delete(context.Background(), r.docID(name), rev.Rev)
 	if err != nil {
 		return err
 	}
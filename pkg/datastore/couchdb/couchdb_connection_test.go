@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/oreo-dtx-lab/oreo/internal/conntest"
 	"github.com/oreo-dtx-lab/oreo/internal/testutil"
 	"github.com/oreo-dtx-lab/oreo/internal/util"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
@@ -665,3 +666,81 @@ func TestMaxConnections(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestCouchDBConnection_ConditionalUpdateBulk(t *testing.T) {
+	conn := NewDefaultConnection()
+
+	newItem := func(key string, value string, version string) *CouchDBItem {
+		return &CouchDBItem{
+			CKey:          key,
+			CValue:        util.ToJSONString(testutil.NewTestItem(value)),
+			CGroupKeyList: "txn1",
+			CTxnState:     config.PREPARED,
+			CTValid:       time.Now().UnixMicro(),
+			CTLease:       time.Now().Add(time.Second),
+			CLinkedLen:    1,
+			CVersion:      version,
+		}
+	}
+
+	for _, key := range []string{"item1", "item2", "item3", "item4"} {
+		_ = conn.Delete(key)
+	}
+	rev1, err := conn.PutItem("item1", newItem("item1", "item1-db", ""))
+	assert.NoError(t, err)
+	rev2, err := conn.PutItem("item2", newItem("item2", "item2-db", ""))
+	assert.NoError(t, err)
+	// item2 is then updated by another transaction
+	_, err = conn.PutItem("item2", newItem("item2", "item2-db", rev2))
+	assert.NoError(t, err)
+	_, err = conn.PutItem("item4", newItem("item4", "item4-db", ""))
+	assert.NoError(t, err)
+
+	reqs := []txn.ConditionalUpdateRequest{
+		{Key: "item1", Item: newItem("item1", "item1-txn", rev1)},
+		{Key: "item2", Item: newItem("item2", "item2-txn", rev2)},
+		{Key: "item3", Item: newItem("item3", "item3-txn", ""), DoCreate: true},
+		// item4 has been created by another transaction
+		{Key: "item4", Item: newItem("item4", "item4-txn", ""), DoCreate: true},
+	}
+	results, err := conn.ConditionalUpdateBulk(reqs)
+	assert.NoError(t, err)
+	assert.Len(t, results, len(reqs))
+
+	assert.NoError(t, results[0].Err)
+	assert.NotEqual(t, rev1, results[0].Version)
+	assert.EqualError(t, results[1].Err, txn.VersionMismatch.Error())
+	assert.NoError(t, results[2].Err)
+	assert.NotEmpty(t, results[2].Version)
+	assert.EqualError(t, results[3].Err, txn.VersionMismatch.Error())
+
+	// the succeeded documents are written while the conflicting ones are kept
+	expected := map[string]string{
+		"item1": "item1-txn",
+		"item2": "item2-db",
+		"item3": "item3-txn",
+		"item4": "item4-db",
+	}
+	for key, value := range expected {
+		item, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		assert.Equal(t, util.ToJSONString(testutil.NewTestItem(value)), item.Value())
+	}
+	item, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, results[0].Version, item.Version())
+}
+
+// TestCouchDBConnection_Namespace tests that two connections with different
+// namespaces on the same database do not see each other's keys.
+func TestCouchDBConnection_Namespace(t *testing.T) {
+	newConn := func(ns txn.Namespace) txn.Connector {
+		conn := NewCouchDBConnection(&ConnectionOptions{
+			DBName:    "oreo",
+			Namespace: ns,
+		})
+		assert.NoError(t, conn.Connect())
+		return conn
+	}
+	conntest.Namespace(t, newConn, &CouchDBItemFactory{})
+}
@@ -1,6 +1,7 @@
 package couchdb
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -157,3 +158,31 @@ func (c *CouchDBItem) String() string {
 		c.CTValid, c.CTLease.Format(time.RFC3339),
 		c.CPrev, c.CLinkedLen, c.CIsDeleted, c.CVersion)
 }
+
+// couchDBItemFields has the fields of CouchDBItem without its methods.
+type couchDBItemFields CouchDBItem
+
+// MarshalJSON encodes TLease as a number by txn.EncodeLease.
+func (c CouchDBItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		couchDBItemFields
+		CTLease int64 `json:"TLease"`
+	}{couchDBItemFields(c), txn.EncodeLease(c.CTLease)})
+}
+
+// UnmarshalJSON accepts TLease encoded as a number or in RFC 3339.
+func (c *CouchDBItem) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*couchDBItemFields
+		CTLease json.RawMessage `json:"TLease"`
+	}{couchDBItemFields: (*couchDBItemFields)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	lease, err := txn.UnmarshalLease(aux.CTLease)
+	if err != nil {
+		return err
+	}
+	c.CTLease = lease
+	return nil
+}
@@ -4,6 +4,10 @@ import "github.com/oreo-dtx-lab/oreo/pkg/txn"
 
 var _ txn.DataItemFactory = (*CouchDBItemFactory)(nil)
 
+func init() {
+	txn.RegisterItemFactory(txn.CouchItem, &CouchDBItemFactory{})
+}
+
 type CouchDBItemFactory struct{}
 
 func (m *CouchDBItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
@@ -21,6 +21,8 @@ import (
 )
 
 var _ txn.Connector = (*DynamoDBConnection)(nil)
+var _ txn.FallibleBatchConnector = (*DynamoDBConnection)(nil)
+var _ txn.Pinger = (*DynamoDBConnection)(nil)
 
 type KeyValueItem struct {
 	ID    string `dynamodbav:"ID"`
@@ -39,6 +41,9 @@ type ConnectionOptions struct {
 	TableName   string
 	Endpoint    string
 	Credentials aws.CredentialsProvider
+	// Namespace prefixes the ID of every item
+	// stored through the connection.
+	Namespace txn.Namespace
 }
 
 func NewDynamoDBConnection(config *ConnectionOptions) *DynamoDBConnection {
@@ -89,11 +94,30 @@ func (d *DynamoDBConnection) Connect() error {
 	return nil
 }
 
+// pingTimeout bounds the wait of Ping.
+const pingTimeout = 2 * time.Second
+
+// Ping checks that the table can be described within pingTimeout.
+func (d *DynamoDBConnection) Ping() error {
+	if !d.hasConnected {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	_, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(d.tableName)})
+	return err
+}
+
 func (d *DynamoDBConnection) Close() error {
 	d.hasConnected = false
 	return nil
 }
 
+// itemID returns the ID of the item under which key is stored.
+func (d *DynamoDBConnection) itemID(key string) string {
+	return d.config.Namespace.Key(key)
+}
+
 func (d *DynamoDBConnection) GetItem(key string) (txn.DataItem, error) {
 	if !d.hasConnected {
 		return &DynamoDBItem{}, errors.Errorf("not connected to DynamoDB")
@@ -106,7 +130,7 @@ func (d *DynamoDBConnection) GetItem(key string) (txn.DataItem, error) {
 	result, err := d.client.GetItem(context.Background(), &dynamodb.GetItemInput{
 		TableName: aws.String(d.tableName),
 		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: key},
+			"ID": &types.AttributeValueMemberS{Value: d.itemID(key)},
 		},
 	})
 	if err != nil {
@@ -122,6 +146,7 @@ func (d *DynamoDBConnection) GetItem(key string) (txn.DataItem, error) {
 	if err != nil {
 		return &DynamoDBItem{}, err
 	}
+	item.DKey, _ = d.config.Namespace.Strip(item.DKey)
 
 	return &item, nil
 }
@@ -140,6 +165,7 @@ func (d *DynamoDBConnection) PutItem(key string, value txn.DataItem) (string, er
 		logger.Log.Errorw("failed to marshal data item", "error", err)
 		return "", err
 	}
+	av["ID"] = &types.AttributeValueMemberS{Value: d.itemID(key)}
 
 	_, err = d.client.PutItem(context.Background(), &dynamodb.PutItemInput{
 		TableName: aws.String(d.tableName),
@@ -167,7 +193,31 @@ func (d *DynamoDBConnection) ConditionalUpdate(key string, value txn.DataItem, d
 	}
 
 	newVer := util.AddToString(value.Version(), 1)
+	update := d.buildConditionalUpdate(key, value, newVer)
+
+	_, err := d.client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ExpressionAttributeNames:  update.ExpressionAttributeNames,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+		ConditionExpression:       update.ConditionExpression,
+	})
+
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return "", errors.New(txn.VersionMismatch)
+		}
+		return "", err
+	}
+
+	return newVer, nil
+}
 
+// buildConditionalUpdate builds an update of the record under key
+// that only succeeds if the record still has the version of value.
+func (d *DynamoDBConnection) buildConditionalUpdate(key string, value txn.DataItem, newVer string) *types.Update {
 	updateExpr := "SET #val = :val, #gkl = :gkl, #ts = :ts, #tv = :tv, " +
 		"#tl = :tl, #prev = :prev, #ll = :ll, #id = :id, #ver = :ver"
 
@@ -188,7 +238,7 @@ func (d *DynamoDBConnection) ConditionalUpdate(key string, value txn.DataItem, d
 		":gkl":    &types.AttributeValueMemberS{Value: value.GroupKeyList()},
 		":ts":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.TxnState())},
 		":tv":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.TValid())},
-		":tl":     &types.AttributeValueMemberS{Value: value.TLease().Format(time.RFC3339Nano)},
+		":tl":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", txn.EncodeLease(value.TLease()))},
 		":prev":   &types.AttributeValueMemberS{Value: value.Prev()},
 		":ll":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", value.LinkedLen())},
 		":id":     &types.AttributeValueMemberBOOL{Value: value.IsDeleted()},
@@ -196,26 +246,141 @@ func (d *DynamoDBConnection) ConditionalUpdate(key string, value txn.DataItem, d
 		":oldver": &types.AttributeValueMemberS{Value: value.Version()},
 	}
 
-	_, err := d.client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+	return &types.Update{
 		TableName: aws.String(d.tableName),
 		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: key},
+			"ID": &types.AttributeValueMemberS{Value: d.itemID(key)},
 		},
 		UpdateExpression:          aws.String(updateExpr),
 		ExpressionAttributeNames:  exprAttrNames,
 		ExpressionAttributeValues: exprAttrValues,
 		ConditionExpression:       aws.String("#ver = :oldver"),
+	}
+}
+
+// MaxTransactItems is the maximum number of items in a single TransactWriteItems call.
+const MaxTransactItems = 100
+
+// errBatchThrottled is returned by transactUpdate when DynamoDB rejects
+// the batch for lack of capacity.
+var errBatchThrottled = errors.New("batched conditional update is throttled")
+
+// ConditionalUpdateBatch applies the conditional updates atomically with TransactWriteItems.
+// If the batch is too large for a single transaction or DynamoDB rejects it for lack
+// of capacity, it falls back to updating the items one by one,
+// in which case the updates are no longer atomic.
+func (d *DynamoDBConnection) ConditionalUpdateBatch(reqs []txn.ConditionalUpdateRequest) ([]string, error) {
+	if !d.hasConnected {
+		return nil, errors.Errorf("not connected to DynamoDB")
+	}
+
+	if len(reqs) > MaxTransactItems {
+		return d.conditionalUpdateOneByOne(reqs)
+	}
+
+	versions, err := d.transactUpdate(reqs)
+	if errors.Is(err, errBatchThrottled) {
+		logger.Log.Warnw("batched conditional update is throttled, falling back to one by one",
+			"size", len(reqs))
+		return d.conditionalUpdateOneByOne(reqs)
+	}
+	return versions, err
+}
+
+// MaxAtomicBatch returns MaxTransactItems, the largest batch
+// a single TransactWriteItems call accepts.
+func (d *DynamoDBConnection) MaxAtomicBatch() int {
+	return MaxTransactItems
+}
+
+// ConditionalUpdateAtomicBatch applies the conditional updates with a single
+// TransactWriteItems call, all of them or none. Unlike ConditionalUpdateBatch,
+// it fails instead of falling back to updating the items one by one.
+func (d *DynamoDBConnection) ConditionalUpdateAtomicBatch(reqs []txn.ConditionalUpdateRequest) ([]string, error) {
+	if !d.hasConnected {
+		return nil, errors.Errorf("not connected to DynamoDB")
+	}
+
+	if len(reqs) > MaxTransactItems {
+		return nil, errors.Errorf("cannot update %d items atomically, the limit is %d",
+			len(reqs), MaxTransactItems)
+	}
+	return d.transactUpdate(reqs)
+}
+
+// transactUpdate applies the conditional updates with TransactWriteItems.
+// It returns VersionMismatch if the condition of any request fails,
+// or errBatchThrottled if DynamoDB rejects the batch for lack of capacity.
+func (d *DynamoDBConnection) transactUpdate(reqs []txn.ConditionalUpdateRequest) ([]string, error) {
+	if oreoconfig.Debug.DebugMode {
+		time.Sleep(oreoconfig.Debug.ConnAdditionalLatency)
+	}
+
+	versions := make([]string, len(reqs))
+	transactItems := make([]types.TransactWriteItem, 0, len(reqs))
+	for i, req := range reqs {
+		versions[i] = util.AddToString(req.Item.Version(), 1)
+		if !req.DoCreate {
+			transactItems = append(transactItems, types.TransactWriteItem{
+				Update: d.buildConditionalUpdate(req.Key, req.Item, versions[i]),
+			})
+			continue
+		}
+
+		av, err := attributevalue.MarshalMap(d.newDynamoItem(req.Key, req.Item, versions[i]))
+		if err != nil {
+			return nil, err
+		}
+		transactItems = append(transactItems, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           aws.String(d.tableName),
+				Item:                av,
+				ConditionExpression: aws.String("attribute_not_exists(ID)"),
+			},
+		})
+	}
+
+	_, err := d.client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
 	})
+	if err == nil {
+		return versions, nil
+	}
 
-	if err != nil {
-		var ccf *types.ConditionalCheckFailedException
-		if errors.As(err, &ccf) {
-			return "", errors.New(txn.VersionMismatch)
+	var tce *types.TransactionCanceledException
+	if errors.As(err, &tce) {
+		for _, reason := range tce.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return nil, errors.New(txn.VersionMismatch)
+			}
 		}
-		return "", err
+		for _, reason := range tce.CancellationReasons {
+			if aws.ToString(reason.Code) == "ProvisionedThroughputExceeded" ||
+				aws.ToString(reason.Code) == "ThrottlingError" {
+				return nil, errBatchThrottled
+			}
+		}
+		return nil, err
 	}
 
-	return newVer, nil
+	var pte *types.ProvisionedThroughputExceededException
+	var rle *types.RequestLimitExceeded
+	if errors.As(err, &pte) || errors.As(err, &rle) {
+		return nil, errBatchThrottled
+	}
+	return nil, err
+}
+
+func (d *DynamoDBConnection) conditionalUpdateOneByOne(reqs []txn.ConditionalUpdateRequest) ([]string, error) {
+	versions := make([]string, len(reqs))
+	for i, req := range reqs {
+		ver, err := d.ConditionalUpdate(req.Key, req.Item, req.DoCreate)
+		if err != nil {
+			return nil, err
+		}
+		versions[i] = ver
+	}
+	return versions, nil
 }
 
 func (d *DynamoDBConnection) ConditionalCommit(key string, version string, tCommit int64) (string, error) {
@@ -246,7 +411,7 @@ func (d *DynamoDBConnection) ConditionalCommit(key string, version string, tComm
 	_, err := d.client.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
 		TableName: aws.String(d.tableName),
 		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: key},
+			"ID": &types.AttributeValueMemberS{Value: d.itemID(key)},
 		},
 		UpdateExpression:          aws.String(updateExpr),
 		ExpressionAttributeNames:  exprAttrNames,
@@ -278,7 +443,7 @@ func (d *DynamoDBConnection) AtomicCreate(key string, value any) (string, error)
 	_, err := d.client.PutItem(context.Background(), &dynamodb.PutItemInput{
 		TableName: aws.String(d.tableName),
 		Item: map[string]types.AttributeValue{
-			"ID":    &types.AttributeValueMemberS{Value: key},
+			"ID":    &types.AttributeValueMemberS{Value: d.itemID(key)},
 			"Value": &types.AttributeValueMemberS{Value: str},
 		},
 		ConditionExpression: aws.String("attribute_not_exists(ID)"),
@@ -300,11 +465,9 @@ func (d *DynamoDBConnection) AtomicCreate(key string, value any) (string, error)
 	return "", nil
 }
 
-func (d *DynamoDBConnection) atomicCreateDynamoItem(key string, value txn.DataItem) (string, error) {
-	newVer := util.AddToString(value.Version(), 1)
-
-	av, err := attributevalue.MarshalMap(DynamoDBItem{
-		DKey:          key,
+func (d *DynamoDBConnection) newDynamoItem(key string, value txn.DataItem, version string) DynamoDBItem {
+	return DynamoDBItem{
+		DKey:          d.itemID(key),
 		DValue:        value.Value(),
 		DGroupKeyList: value.GroupKeyList(),
 		DTxnState:     value.TxnState(),
@@ -313,8 +476,14 @@ func (d *DynamoDBConnection) atomicCreateDynamoItem(key string, value txn.DataIt
 		DPrev:         value.Prev(),
 		DLinkedLen:    value.LinkedLen(),
 		DIsDeleted:    value.IsDeleted(),
-		DVersion:      newVer,
-	})
+		DVersion:      version,
+	}
+}
+
+func (d *DynamoDBConnection) atomicCreateDynamoItem(key string, value txn.DataItem) (string, error) {
+	newVer := util.AddToString(value.Version(), 1)
+
+	av, err := attributevalue.MarshalMap(d.newDynamoItem(key, value, newVer))
 	if err != nil {
 		return "", err
 	}
@@ -348,7 +517,7 @@ func (d *DynamoDBConnection) Get(key string) (string, error) {
 	result, err := d.client.GetItem(context.Background(), &dynamodb.GetItemInput{
 		TableName: aws.String(d.tableName),
 		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: key},
+			"ID": &types.AttributeValueMemberS{Value: d.itemID(key)},
 		},
 	})
 	if err != nil {
@@ -381,7 +550,7 @@ func (d *DynamoDBConnection) Put(key string, value any) error {
 	_, err := d.client.PutItem(context.Background(), &dynamodb.PutItemInput{
 		TableName: aws.String(d.tableName),
 		Item: map[string]types.AttributeValue{
-			"ID":    &types.AttributeValueMemberS{Value: key},
+			"ID":    &types.AttributeValueMemberS{Value: d.itemID(key)},
 			"Value": &types.AttributeValueMemberS{Value: str},
 		},
 	})
@@ -401,34 +570,27 @@ func (d *DynamoDBConnection) Delete(key string) error {
 	_, err := d.client.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
 		TableName: aws.String(d.tableName),
 		Key: map[string]types.AttributeValue{
-			"ID": &types.AttributeValueMemberS{Value: key},
+			"ID": &types.AttributeValueMemberS{Value: d.itemID(key)},
 		},
 	})
 
 	return err
 }
 
+// buildCreateTableInput describes the table of the records, keyed by ID.
 func buildCreateTableInput(tableName string) *dynamodb.CreateTableInput {
 	return &dynamodb.CreateTableInput{
 		AttributeDefinitions: []types.AttributeDefinition{
 			{
-				AttributeName: aws.String("PK"),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-			{
-				AttributeName: aws.String("SK"),
+				AttributeName: aws.String("ID"),
 				AttributeType: types.ScalarAttributeTypeS,
 			},
 		},
 		KeySchema: []types.KeySchemaElement{
 			{
-				AttributeName: aws.String("PK"),
+				AttributeName: aws.String("ID"),
 				KeyType:       types.KeyTypeHash,
 			},
-			{
-				AttributeName: aws.String("SK"),
-				KeyType:       types.KeyTypeRange,
-			},
 		},
 		TableName:   aws.String(tableName),
 		BillingMode: types.BillingModePayPerRequest,
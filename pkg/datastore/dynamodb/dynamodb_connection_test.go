@@ -0,0 +1,135 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+
+	"github.com/oreo-dtx-lab/oreo/internal/conntest"
+	"github.com/oreo-dtx-lab/oreo/internal/testutil"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewDefaultDynamoDBConnection(t *testing.T) *DynamoDBConnection {
+	conn := NewDynamoDBConnection(nil)
+	err := conn.Connect()
+	assert.NoError(t, err)
+	return conn
+}
+
+func newTestDynamoDBItem(key string, value string, version string) *DynamoDBItem {
+	return &DynamoDBItem{
+		DKey:          key,
+		DValue:        util.ToJSONString(testutil.NewTestItem(value)),
+		DGroupKeyList: "txn1",
+		DTxnState:     config.COMMITTED,
+		DTValid:       time.Now().Add(-3 * time.Second).UnixMicro(),
+		DTLease:       time.Now().Add(-2 * time.Second),
+		DLinkedLen:    1,
+		DVersion:      version,
+	}
+}
+
+func TestDynamoDBConnection_ConditionalUpdateBatch(t *testing.T) {
+	conn := NewDefaultDynamoDBConnection(t)
+
+	setup := func() {
+		for _, key := range []string{"item1", "item2", "item3"} {
+			_ = conn.Delete(key)
+		}
+		_, err := conn.PutItem("item1", newTestDynamoDBItem("item1", "item1-db", "1"))
+		assert.NoError(t, err)
+		_, err = conn.PutItem("item2", newTestDynamoDBItem("item2", "item2-db", "1"))
+		assert.NoError(t, err)
+	}
+
+	t.Run("all conditions hold", func(t *testing.T) {
+		setup()
+		reqs := []txn.ConditionalUpdateRequest{
+			{Key: "item1", Item: newTestDynamoDBItem("item1", "item1-txn", "1")},
+			{Key: "item2", Item: newTestDynamoDBItem("item2", "item2-txn", "1")},
+			{Key: "item3", Item: newTestDynamoDBItem("item3", "item3-txn", ""), DoCreate: true},
+		}
+		versions, err := conn.ConditionalUpdateBatch(reqs)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2", "2", "1"}, versions)
+
+		for _, req := range reqs {
+			item, err := conn.GetItem(req.Key)
+			assert.NoError(t, err)
+			assert.Equal(t, req.Item.Value(), item.Value())
+		}
+	})
+
+	t.Run("one condition fails", func(t *testing.T) {
+		setup()
+		reqs := []txn.ConditionalUpdateRequest{
+			{Key: "item1", Item: newTestDynamoDBItem("item1", "item1-txn", "1")},
+			// item2 has been updated by another transaction
+			{Key: "item2", Item: newTestDynamoDBItem("item2", "item2-txn", "0")},
+		}
+		_, err := conn.ConditionalUpdateBatch(reqs)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+		// none of the items is updated
+		item, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", item.Version())
+		assert.Equal(t, util.ToJSONString(testutil.NewTestItem("item1-db")), item.Value())
+	})
+
+	t.Run("creating an existing item fails", func(t *testing.T) {
+		setup()
+		reqs := []txn.ConditionalUpdateRequest{
+			{Key: "item1", Item: newTestDynamoDBItem("item1", "item1-txn", ""), DoCreate: true},
+			{Key: "item2", Item: newTestDynamoDBItem("item2", "item2-txn", "1")},
+		}
+		_, err := conn.ConditionalUpdateBatch(reqs)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+		item, err := conn.GetItem("item2")
+		assert.NoError(t, err)
+		assert.Equal(t, "1", item.Version())
+	})
+}
+
+func TestDynamoDBConnection_EnsureSchema(t *testing.T) {
+	conn := NewDynamoDBConnection(&ConnectionOptions{
+		Region:    "us-west-2",
+		TableName: "schema_" + strconv.FormatInt(time.Now().UnixNano(), 10),
+		Endpoint:  "http://localhost:8000",
+	})
+	assert.NoError(t, conn.EnsureSchema())
+	defer func() {
+		_, _ = conn.client.DeleteTable(context.Background(),
+			&dynamodb.DeleteTableInput{TableName: aws.String(conn.tableName)})
+	}()
+
+	// a second run checks the table created by the first one
+	assert.NoError(t, conn.EnsureSchema())
+	_, err := conn.PutItem("item1", newTestDynamoDBItem("item1", "item1-db", "1"))
+	assert.NoError(t, err)
+}
+
+// TestDynamoDBConnection_Namespace tests that two connections with different
+// namespaces on the same database do not see each other's keys.
+func TestDynamoDBConnection_Namespace(t *testing.T) {
+	newConn := func(ns txn.Namespace) txn.Connector {
+		conn := NewDynamoDBConnection(&ConnectionOptions{
+			Region:    "us-west-2",
+			TableName: "oreo",
+			Endpoint:  "http://localhost:8000",
+			Namespace: ns,
+		})
+		assert.NoError(t, conn.Connect())
+		return conn
+	}
+	conntest.Namespace(t, newConn, &DynamoDBItemFactory{})
+}
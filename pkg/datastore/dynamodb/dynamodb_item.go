@@ -1,9 +1,13 @@
 package dynamodb
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/oreo-dtx-lab/oreo/internal/util"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/oreo-dtx-lab/oreo/pkg/txn"
@@ -156,3 +160,76 @@ func (d *DynamoDBItem) String() string {
 		d.DTValid, d.DTLease.Format(time.RFC3339),
 		d.DPrev, d.DLinkedLen, d.DIsDeleted, d.DVersion)
 }
+
+// dynamoDBItemFields has the fields of DynamoDBItem without its methods.
+type dynamoDBItemFields DynamoDBItem
+
+// MarshalJSON encodes TLease as a number by txn.EncodeLease.
+func (d DynamoDBItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		dynamoDBItemFields
+		DTLease int64 `json:"TLease"`
+	}{dynamoDBItemFields(d), txn.EncodeLease(d.DTLease)})
+}
+
+// UnmarshalJSON accepts TLease encoded as a number or in RFC 3339.
+func (d *DynamoDBItem) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*dynamoDBItemFields
+		DTLease json.RawMessage `json:"TLease"`
+	}{dynamoDBItemFields: (*dynamoDBItemFields)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	lease, err := txn.UnmarshalLease(aux.DTLease)
+	if err != nil {
+		return err
+	}
+	d.DTLease = lease
+	return nil
+}
+
+// MarshalDynamoDBAttributeValue stores TLease as a number by txn.EncodeLease.
+func (d DynamoDBItem) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return attributevalue.Marshal(struct {
+		dynamoDBItemFields
+		DTLease int64 `dynamodbav:"TLease"`
+	}{dynamoDBItemFields(d), txn.EncodeLease(d.DTLease)})
+}
+
+// UnmarshalDynamoDBAttributeValue accepts TLease stored as a number
+// or in RFC 3339, as the older items store it.
+func (d *DynamoDBItem) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	m, ok := av.(*types.AttributeValueMemberM)
+	if !ok {
+		return fmt.Errorf("an item is stored as a map, not as %T", av)
+	}
+	fields := make(map[string]types.AttributeValue, len(m.Value))
+	for name, value := range m.Value {
+		if name != "TLease" {
+			fields[name] = value
+		}
+	}
+	if err := attributevalue.UnmarshalMap(fields, (*dynamoDBItemFields)(d)); err != nil {
+		return err
+	}
+	var lease time.Time
+	switch tl := m.Value["TLease"].(type) {
+	case *types.AttributeValueMemberN:
+		n, err := strconv.ParseInt(tl.Value, 10, 64)
+		if err != nil {
+			return err
+		}
+		lease = txn.DecodeLease(n)
+	case *types.AttributeValueMemberS:
+		var err error
+		if lease, err = txn.ParseLease(tl.Value); err != nil {
+			return err
+		}
+	case nil, *types.AttributeValueMemberNULL:
+	default:
+		return fmt.Errorf("unexpected lease of type %T", tl)
+	}
+	d.DTLease = lease
+	return nil
+}
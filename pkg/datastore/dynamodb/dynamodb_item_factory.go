@@ -4,6 +4,10 @@ import "github.com/oreo-dtx-lab/oreo/pkg/txn"
 
 var _ txn.DataItemFactory = (*DynamoDBItemFactory)(nil)
 
+func init() {
+	txn.RegisterItemFactory(txn.DynamoDBItem, &DynamoDBItemFactory{})
+}
+
 type DynamoDBItemFactory struct{}
 
 func (d *DynamoDBItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
@@ -0,0 +1,59 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+var _ txn.SchemaEnsurer = (*DynamoDBConnection)(nil)
+
+// tableCreationTimeout is how long EnsureSchema waits for a table it has
+// created to become active.
+const tableCreationTimeout = 2 * time.Minute
+
+// EnsureSchema creates the table of the records if it does not exist,
+// and checks that an existing one is keyed by the ID string attribute.
+// The other attributes of the records, such as Version, need no declaration.
+func (d *DynamoDBConnection) EnsureSchema() error {
+	if err := d.Connect(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	describe := &dynamodb.DescribeTableInput{TableName: aws.String(d.tableName)}
+	out, err := d.client.DescribeTable(ctx, describe)
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		_, err = d.client.CreateTable(ctx, buildCreateTableInput(d.tableName))
+		// the table may have been created by another executor meanwhile
+		var inUse *types.ResourceInUseException
+		if err != nil && !errors.As(err, &inUse) {
+			return err
+		}
+		return dynamodb.NewTableExistsWaiter(d.client).Wait(ctx, describe, tableCreationTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	return checkTable(out.Table)
+}
+
+// checkTable checks that table is keyed by the ID string attribute alone.
+func checkTable(table *types.TableDescription) error {
+	name := aws.ToString(table.TableName)
+	if len(table.KeySchema) != 1 || aws.ToString(table.KeySchema[0].AttributeName) != "ID" ||
+		table.KeySchema[0].KeyType != types.KeyTypeHash {
+		return errors.Errorf("table %s must be keyed by the ID hash key alone", name)
+	}
+	for _, def := range table.AttributeDefinitions {
+		if aws.ToString(def.AttributeName) == "ID" && def.AttributeType != types.ScalarAttributeTypeS {
+			return errors.Errorf("the ID attribute of table %s must be a string, not %s", name, def.AttributeType)
+		}
+	}
+	return nil
+}
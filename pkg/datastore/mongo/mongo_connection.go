@@ -3,6 +3,7 @@ package mongo
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/go-errors/errors"
@@ -12,9 +13,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 var _ txn.Connector = (*MongoConnection)(nil)
+var _ txn.NativeTxnConnector = (*MongoConnection)(nil)
+var _ txn.GroupKeyScanner = (*MongoConnection)(nil)
+var _ txn.PrimaryReader = (*MongoConnection)(nil)
+var _ txn.Pinger = (*MongoConnection)(nil)
 
 type KeyValueItem struct {
 	Key   string `bson:"_id"`
@@ -25,6 +31,7 @@ type MongoConnection struct {
 	client       *mongo.Client
 	db           *mongo.Database
 	coll         *mongo.Collection
+	readColl     *mongo.Collection
 	Address      string
 	config       ConnectionOptions
 	hasConnected bool
@@ -36,6 +43,19 @@ type ConnectionOptions struct {
 	Password       string
 	DBName         string
 	CollectionName string
+	// Namespace prefixes the _id of every document
+	// stored through the connection.
+	Namespace txn.Namespace
+	// ReadFromSecondary serves GetItem and Get from a secondary of the
+	// replica set when one is available, so that the reads do not load
+	// the primary. A secondary may lag behind the primary and return an
+	// outdated record. The conditional update of the prepare phase catches
+	// it for a record the transaction writes, but a record it only reads
+	// is checked against the primary only if config.Config.ReadSetValidation
+	// is on in the process running the transaction. Without it, a transaction
+	// may read a stale snapshot from a lagging secondary. The writes and
+	// GetItemFromPrimary always go to the primary.
+	ReadFromSecondary bool
 }
 
 // NewMongoConnection creates a new MongoDB connection using the provided configuration options.
@@ -97,24 +117,53 @@ func (m *MongoConnection) Connect() error {
 		return err
 	}
 
+	m.client = client
 	m.db = client.Database(m.config.DBName)
 	m.coll = m.db.Collection(m.config.CollectionName)
+	m.readColl = m.coll
+	if m.config.ReadFromSecondary {
+		m.readColl = m.db.Collection(m.config.CollectionName,
+			options.Collection().SetReadPreference(readpref.SecondaryPreferred()))
+	}
 	m.hasConnected = true
 	return nil
 }
 
+// Ping checks that the primary answers within 2 seconds,
+// the timeout of the ping issued by Connect.
+func (m *MongoConnection) Ping() error {
+	if !m.hasConnected {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return m.client.Ping(ctx, nil)
+}
+
 // Close closes the MongoDB connection.
 // It's important to defer this function after creating a new connection.
 func (m *MongoConnection) Close() error {
 	if !m.hasConnected {
 		return nil
 	}
+	m.hasConnected = false
 	return m.client.Disconnect(context.Background())
 }
 
 // GetItem retrieves a txn.DataItem from the MongoDB database based on the specified key.
 // If the key is not found, it returns an empty txn.DataItem and an error.
+// It is read from a secondary if ReadFromSecondary is set.
 func (m *MongoConnection) GetItem(key string) (txn.DataItem, error) {
+	return m.getItem(m.readColl, key)
+}
+
+// GetItemFromPrimary retrieves the item of key like GetItem,
+// from the primary whatever ReadFromSecondary.
+func (m *MongoConnection) GetItemFromPrimary(key string) (txn.DataItem, error) {
+	return m.getItem(m.coll, key)
+}
+
+func (m *MongoConnection) getItem(coll *mongo.Collection, key string) (txn.DataItem, error) {
 	if !m.hasConnected {
 		return &MongoItem{}, errors.Errorf("not connected to MongoDB")
 	}
@@ -124,13 +173,14 @@ func (m *MongoConnection) GetItem(key string) (txn.DataItem, error) {
 	}
 
 	var item MongoItem
-	err := m.coll.FindOne(context.Background(), bson.M{"_id": key}).Decode(&item)
+	err := coll.FindOne(context.Background(), bson.M{"_id": m.config.Namespace.Key(key)}).Decode(&item)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return &MongoItem{}, errors.New(txn.KeyNotFound)
 		}
 		return &MongoItem{}, err
 	}
+	item.MKey, _ = m.config.Namespace.Strip(item.MKey)
 	return &item, nil
 }
 
@@ -147,7 +197,7 @@ func (m *MongoConnection) PutItem(key string, value txn.DataItem) (string, error
 
 	_, err := m.coll.UpdateOne(
 		context.Background(),
-		bson.M{"_id": key},
+		bson.M{"_id": m.config.Namespace.Key(key)},
 		bson.D{
 			{Key: "$set", Value: value},
 		},
@@ -179,14 +229,14 @@ func (m *MongoConnection) ConditionalUpdate(key string, value txn.DataItem, doCr
 
 	newVer := util.AddToString(value.Version(), 1)
 
-	filter := bson.M{"_id": key, "Version": value.Version()}
+	filter := bson.M{"_id": m.config.Namespace.Key(key), "Version": value.Version()}
 	update := bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "Value", Value: value.Value()},
 			{Key: "GroupKeyList", Value: value.GroupKeyList()},
 			{Key: "TxnState", Value: value.TxnState()},
 			{Key: "TValid", Value: value.TValid()},
-			{Key: "TLease", Value: value.TLease().Format(time.RFC3339Nano)},
+			{Key: "TLease", Value: txn.EncodeLease(value.TLease())},
 			{Key: "Prev", Value: value.Prev()},
 			{Key: "LinkedLen", Value: value.LinkedLen()},
 			{Key: "IsDeleted", Value: value.IsDeleted()},
@@ -228,7 +278,7 @@ func (m *MongoConnection) ConditionalCommit(key string, version string, tCommit
 
 	newVer := util.AddToString(version, 1)
 
-	filter := bson.M{"_id": key, "Version": version}
+	filter := bson.M{"_id": m.config.Namespace.Key(key), "Version": version}
 	update := bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "TxnState", Value: config.COMMITTED},
@@ -262,7 +312,8 @@ func (m *MongoConnection) AtomicCreate(key string, value any) (string, error) {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	filter := bson.M{"_id": key}
+	id := m.config.Namespace.Key(key)
+	filter := bson.M{"_id": id}
 	var result KeyValueItem
 	err := m.coll.FindOne(context.Background(), filter).Decode(&result)
 
@@ -271,7 +322,7 @@ func (m *MongoConnection) AtomicCreate(key string, value any) (string, error) {
 			// we can safely create the item
 			str := util.ToString(value)
 			_, err := m.coll.InsertOne(context.Background(), bson.D{
-				{Key: "_id", Value: key},
+				{Key: "_id", Value: id},
 				{Key: "Value", Value: str},
 			})
 			if err != nil {
@@ -287,7 +338,8 @@ func (m *MongoConnection) AtomicCreate(key string, value any) (string, error) {
 
 func (m *MongoConnection) atomicCreateMongoItem(key string, value txn.DataItem) (string, error) {
 
-	filter := bson.M{"_id": key}
+	id := m.config.Namespace.Key(key)
+	filter := bson.M{"_id": id}
 	var result MongoItem
 	err := m.coll.FindOne(context.Background(), filter).Decode(&result)
 
@@ -296,12 +348,12 @@ func (m *MongoConnection) atomicCreateMongoItem(key string, value txn.DataItem)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			_, err := m.coll.InsertOne(context.Background(), bson.D{
-				{Key: "_id", Value: key},
+				{Key: "_id", Value: id},
 				{Key: "Value", Value: value.Value()},
 				{Key: "GroupKeyList", Value: value.GroupKeyList()},
 				{Key: "TxnState", Value: value.TxnState()},
 				{Key: "TValid", Value: value.TValid()},
-				{Key: "TLease", Value: value.TLease().Format(time.RFC3339Nano)},
+				{Key: "TLease", Value: txn.EncodeLease(value.TLease())},
 				{Key: "Prev", Value: value.Prev()},
 				{Key: "LinkedLen", Value: value.LinkedLen()},
 				{Key: "IsDeleted", Value: value.IsDeleted()},
@@ -318,10 +370,78 @@ func (m *MongoConnection) atomicCreateMongoItem(key string, value txn.DataItem)
 	return "", errors.New(txn.VersionMismatch)
 }
 
+// CommitInTxn writes items in one multi-document transaction,
+// which requires MongoDB to run as a replica set.
+// An item with an empty version is inserted, the others replace the
+// document only if it still has the item's version.
+// If any write fails, the transaction is aborted and nothing is written.
+func (m *MongoConnection) CommitInTxn(items []txn.DataItem) error {
+	if !m.hasConnected {
+		return errors.Errorf("not connected to MongoDB")
+	}
+
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	ctx := context.Background()
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		for _, item := range items {
+			if err := m.writeInTxn(sc, item); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// writeInTxn conditionally writes item in the transaction of sc.
+func (m *MongoConnection) writeInTxn(sc mongo.SessionContext, item txn.DataItem) error {
+	newVer := util.AddToString(item.Version(), 1)
+	fields := bson.D{
+		{Key: "Value", Value: item.Value()},
+		{Key: "GroupKeyList", Value: item.GroupKeyList()},
+		{Key: "TxnState", Value: item.TxnState()},
+		{Key: "TValid", Value: item.TValid()},
+		{Key: "TLease", Value: txn.EncodeLease(item.TLease())},
+		{Key: "Prev", Value: item.Prev()},
+		{Key: "LinkedLen", Value: item.LinkedLen()},
+		{Key: "IsDeleted", Value: item.IsDeleted()},
+		{Key: "Version", Value: newVer},
+	}
+
+	if item.Version() == "" {
+		_, err := m.coll.InsertOne(sc, append(bson.D{{Key: "_id", Value: m.config.Namespace.Key(item.Key())}}, fields...))
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New(txn.VersionMismatch)
+		}
+		return err
+	}
+
+	res, err := m.coll.UpdateOne(sc,
+		bson.M{"_id": m.config.Namespace.Key(item.Key()), "Version": item.Version()},
+		bson.D{{Key: "$set", Value: fields}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New(txn.VersionMismatch)
+	}
+	return nil
+}
+
 // Get retrieves the value associated with the given key from the MongoDB database.
 // If the key is not found, it returns an empty string and an error indicating the key was not found.
 // If an error occurs during the retrieval, it returns an empty string and the error.
 // Otherwise, it returns the retrieved value and nil error.
+// It is read from a secondary if ReadFromSecondary is set.
 func (m *MongoConnection) Get(key string) (string, error) {
 	if !m.hasConnected {
 		return "", fmt.Errorf("not connected to MongoDB")
@@ -332,7 +452,7 @@ func (m *MongoConnection) Get(key string) (string, error) {
 	}
 
 	var result KeyValueItem
-	err := m.coll.FindOne(context.Background(), bson.M{"_id": key}).Decode(&result)
+	err := m.readColl.FindOne(context.Background(), bson.M{"_id": m.config.Namespace.Key(key)}).Decode(&result)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return "", errors.New(txn.KeyNotFound)
@@ -358,7 +478,7 @@ func (m *MongoConnection) Put(key string, value any) error {
 
 	_, err := m.coll.UpdateOne(
 		context.Background(),
-		bson.M{"_id": key},
+		bson.M{"_id": m.config.Namespace.Key(key)},
 		bson.D{
 			{Key: "$set", Value: bson.D{
 				{Key: "Value", Value: str},
@@ -383,9 +503,46 @@ func (m *MongoConnection) Delete(key string) error {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	_, err := m.coll.DeleteOne(context.Background(), bson.M{"_id": key})
+	_, err := m.coll.DeleteOne(context.Background(), bson.M{"_id": m.config.Namespace.Key(key)})
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// KeysByGroupKey returns the keys of the records whose group key list
+// contains groupKey in the namespace of the connection.
+// The GroupKeyList field is not indexed, so the query scans the whole collection.
+func (m *MongoConnection) KeysByGroupKey(groupKey string) ([]string, error) {
+	if !m.hasConnected {
+		return nil, errors.Errorf("not connected to MongoDB")
+	}
+
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.ConnAdditionalLatency)
+	}
+
+	filter := bson.M{"GroupKeyList": bson.M{
+		"$regex": "(^|,)" + regexp.QuoteMeta(groupKey) + "(,|$)",
+	}}
+	if m.config.Namespace != "" {
+		filter["_id"] = bson.M{"$regex": "^" + regexp.QuoteMeta(m.config.Namespace.Key(""))}
+	}
+	cursor, err := m.coll.Find(context.Background(), filter,
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		Key string `bson:"_id"`
+	}
+	if err := cursor.All(context.Background(), &docs); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		key, _ := m.config.Namespace.Strip(doc.Key)
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
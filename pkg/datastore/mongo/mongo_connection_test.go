@@ -1,16 +1,19 @@
 package mongo
 
 import (
+	"context"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/oreo-dtx-lab/oreo/internal/conntest"
 	"github.com/oreo-dtx-lab/oreo/internal/testutil"
 	"github.com/oreo-dtx-lab/oreo/internal/util"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
 	"github.com/oreo-dtx-lab/oreo/pkg/txn"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestNewMongoConnection_DefaultNilArgument(t *testing.T) {
@@ -648,3 +651,63 @@ func TestMaxConnections(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestMongoConnection_EnsureSchema(t *testing.T) {
+	conn := NewMongoConnection(&ConnectionOptions{
+		DBName:         "oreo",
+		CollectionName: "schema_" + strconv.FormatInt(time.Now().UnixNano(), 10),
+	})
+	assert.NoError(t, conn.EnsureSchema())
+	defer func() { _ = conn.coll.Drop(context.Background()) }()
+
+	names, err := conn.db.ListCollectionNames(context.Background(), bson.M{"name": conn.config.CollectionName})
+	assert.NoError(t, err)
+	assert.Len(t, names, 1)
+
+	// a second run finds the collection
+	assert.NoError(t, conn.EnsureSchema())
+}
+
+// TestMongoConnection_ReadFromSecondary tests that GetItem and Get prefer
+// a secondary when ReadFromSecondary is set, while GetItemFromPrimary does
+// not. A standalone server serves both, so the items read are the same.
+func TestMongoConnection_ReadFromSecondary(t *testing.T) {
+	conn := NewMongoConnection(&ConnectionOptions{
+		DBName:            "oreo",
+		CollectionName:    "records",
+		ReadFromSecondary: true,
+	})
+	assert.NoError(t, conn.Connect())
+	// the reads go through a handle with its own read preference
+	assert.NotSame(t, conn.coll, conn.readColl)
+
+	key := "secondary-item-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	_, err := conn.PutItem(key, &MongoItem{MKey: key, MValue: "v1", MVersion: "1"})
+	assert.NoError(t, err)
+	defer func() { _ = conn.Delete(key) }()
+
+	item, err := conn.GetItemFromPrimary(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", item.Value())
+
+	// a secondary catches up with the write eventually
+	assert.Eventually(t, func() bool {
+		item, err := conn.GetItem(key)
+		return err == nil && item.Value() == "v1"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+// TestMongoConnection_Namespace tests that two connections with different
+// namespaces on the same database do not see each other's keys.
+func TestMongoConnection_Namespace(t *testing.T) {
+	newConn := func(ns txn.Namespace) txn.Connector {
+		conn := NewMongoConnection(&ConnectionOptions{
+			DBName:         "oreo",
+			CollectionName: "records",
+			Namespace:      ns,
+		})
+		assert.NoError(t, conn.Connect())
+		return conn
+	}
+	conntest.Namespace(t, newConn, &MongoItemFactory{})
+}
@@ -167,7 +167,7 @@ func (mi MongoItem) MarshalBSONValue() (bsontype.Type, []byte, error) {
 		"GroupKeyList": mi.MGroupKeyList,
 		"TxnState":     mi.MTxnState,
 		"TValid":       mi.MTValid,
-		"TLease":       mi.MTLease.Format(time.RFC3339Nano),
+		"TLease":       txn.EncodeLease(mi.MTLease),
 		"Prev":         mi.MPrev,
 		"LinkedLen":    mi.MLinkedLen,
 		"IsDeleted":    mi.MIsDeleted,
@@ -204,7 +204,7 @@ func (mi *MongoItem) UnmarshalBSONValue(t bsontype.Type, raw []byte) error {
 		mi.MTValid = value.(int64)
 	}
 	if value, ok := m["TLease"]; ok {
-		mi.MTLease, err = time.Parse(time.RFC3339Nano, value.(string))
+		mi.MTLease, err = decodeLease(value)
 		if err != nil {
 			return err
 		}
@@ -224,3 +224,45 @@ func (mi *MongoItem) UnmarshalBSONValue(t bsontype.Type, raw []byte) error {
 
 	return nil
 }
+
+// decodeLease decodes the lease stored as value, a number by
+// txn.EncodeLease or an RFC 3339 string as the older documents store it.
+func decodeLease(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case int64:
+		return txn.DecodeLease(v), nil
+	case string:
+		return txn.ParseLease(v)
+	case nil:
+		return time.Time{}, nil
+	}
+	return time.Time{}, fmt.Errorf("unexpected lease of type %T", value)
+}
+
+// mongoItemFields has the fields of MongoItem without its methods.
+type mongoItemFields MongoItem
+
+// MarshalJSON encodes TLease as a number by txn.EncodeLease.
+func (mi MongoItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		mongoItemFields
+		MTLease int64 `json:"TLease"`
+	}{mongoItemFields(mi), txn.EncodeLease(mi.MTLease)})
+}
+
+// UnmarshalJSON accepts TLease encoded as a number or in RFC 3339.
+func (mi *MongoItem) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*mongoItemFields
+		MTLease json.RawMessage `json:"TLease"`
+	}{mongoItemFields: (*mongoItemFields)(mi)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	lease, err := txn.UnmarshalLease(aux.MTLease)
+	if err != nil {
+		return err
+	}
+	mi.MTLease = lease
+	return nil
+}
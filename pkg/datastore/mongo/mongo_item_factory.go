@@ -4,6 +4,10 @@ import "github.com/oreo-dtx-lab/oreo/pkg/txn"
 
 var _ txn.DataItemFactory = (*MongoItemFactory)(nil)
 
+func init() {
+	txn.RegisterItemFactory(txn.MongoItem, &MongoItemFactory{})
+}
+
 type MongoItemFactory struct{}
 
 func (m *MongoItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
@@ -0,0 +1,76 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/oreo-dtx-lab/oreo/internal/testutil"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	trxn "github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// runNativeTxnScenario runs the same transactions on a fresh key
+// and returns the committed record and the errors of the two commits
+// racing on the record.
+func runNativeTxnScenario(t *testing.T, nativeTxn bool) (trxn.DataItem, error, error) {
+	oldNativeTxn := config.Config.NativeTxnCommit
+	config.Config.NativeTxnCommit = nativeTxn
+	defer func() { config.Config.NativeTxnCommit = oldNativeTxn }()
+
+	conn := NewDefaultConnection()
+	key := "native-txn-item"
+	_ = conn.Delete(key)
+
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransaction()
+		txn.AddDatastore(NewMongoDatastore("mongo", conn))
+		return txn
+	}
+
+	// create the record
+	txn1 := newTxn()
+	assert.NoError(t, txn1.Start())
+	assert.NoError(t, txn1.Write("mongo", key, testutil.NewTestItem("value1")))
+	assert.NoError(t, txn1.Commit())
+
+	// two transactions read the record and then update it
+	txn2, txn3 := newTxn(), newTxn()
+	assert.NoError(t, txn2.Start())
+	assert.NoError(t, txn3.Start())
+	var item testutil.TestItem
+	assert.NoError(t, txn2.Read("mongo", key, &item))
+	assert.NoError(t, txn3.Read("mongo", key, &item))
+	assert.NoError(t, txn2.Write("mongo", key, testutil.NewTestItem("value2")))
+	assert.NoError(t, txn3.Write("mongo", key, testutil.NewTestItem("value3")))
+	err2 := txn2.Commit()
+	err3 := txn3.Commit()
+
+	dbItem, err := conn.GetItem(key)
+	assert.NoError(t, err)
+	return dbItem, err2, err3
+}
+
+func TestNativeTxnCommitMatchesTwoPhaseCommit(t *testing.T) {
+	// the async commit of the two-phase commit would race with the reads below
+	oldAblationLevel := config.Config.AblationLevel
+	config.Config.AblationLevel = 3
+	defer func() { config.Config.AblationLevel = oldAblationLevel }()
+
+	twoPCItem, twoPCErr2, twoPCErr3 := runNativeTxnScenario(t, false)
+	nativeItem, nativeErr2, nativeErr3 := runNativeTxnScenario(t, true)
+
+	// the first update wins and the second one is rejected in both paths
+	assert.NoError(t, twoPCErr2)
+	assert.Error(t, twoPCErr3)
+	assert.NoError(t, nativeErr2)
+	assert.Error(t, nativeErr3)
+
+	assert.Equal(t, twoPCItem.Value(), nativeItem.Value())
+	assert.Equal(t, config.COMMITTED, twoPCItem.TxnState())
+	assert.Equal(t, config.COMMITTED, nativeItem.TxnState())
+	assert.Equal(t, twoPCItem.LinkedLen(), nativeItem.LinkedLen())
+	assert.Equal(t, twoPCItem.IsDeleted(), nativeItem.IsDeleted())
+
+	// the native path creates no TSR
+	assert.Empty(t, nativeItem.GroupKeyList())
+}
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ txn.SchemaEnsurer = (*MongoConnection)(nil)
+
+// codeNamespaceExists is the error code of creating a collection that exists.
+const codeNamespaceExists = 48
+
+// EnsureSchema creates the collection of the records if it does not exist.
+// The documents need no other structure, their fields are set by each write.
+func (m *MongoConnection) EnsureSchema() error {
+	if err := m.Connect(); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	names, err := m.db.ListCollectionNames(ctx, bson.M{"name": m.config.CollectionName})
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return nil
+	}
+	err = m.db.CreateCollection(ctx, m.config.CollectionName)
+	// the collection may have been created by another executor meanwhile
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == codeNamespaceExists {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPoolAdjustInterval is how often an adaptive pool is resized
+// if ConnectionOptions.PoolAdjustInterval is unset.
+const DefaultPoolAdjustInterval = time.Second
+
+// DefaultPoolTimeout is how long a command waits for a connection
+// if ConnectionOptions.PoolTimeout is unset, the default of go-redis.
+const DefaultPoolTimeout = 4 * time.Second
+
+// errPoolTimeout is returned by a command that waited for a connection
+// longer than the pool timeout, as the pool of go-redis does.
+var errPoolTimeout = errors.New("redis: connection pool timeout")
+
+// adaptivePool bounds the number of commands in flight on a client,
+// and so the number of connections they use, by a size that is resized
+// between minSize and maxSize according to the load observed.
+//
+// It is installed as a hook of the client, whose own pool is created
+// with maxSize connections, because go-redis cannot resize a pool.
+type adaptivePool struct {
+	minSize int
+	maxSize int
+	timeout time.Duration
+
+	mu sync.Mutex
+	// wake is closed and replaced to wake the commands waiting in acquire
+	wake     chan struct{}
+	closed   bool
+	size     int
+	inUse    int
+	peak     int
+	waits    int
+	blocked  int
+	timedOut int
+	timeouts uint32
+}
+
+var _ redis.Hook = (*adaptivePool)(nil)
+
+// newAdaptivePool creates an adaptivePool starting at size, clamped
+// between minSize and maxSize, whose commands wait at most timeout.
+func newAdaptivePool(size, minSize, maxSize int, timeout time.Duration) *adaptivePool {
+	return &adaptivePool{
+		minSize: minSize,
+		maxSize: maxSize,
+		timeout: timeout,
+		wake:    make(chan struct{}),
+		size:    min(max(size, minSize), maxSize),
+	}
+}
+
+// Size returns the number of commands allowed in flight.
+func (p *adaptivePool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// acquire blocks until a command is allowed in flight. It gives up with
+// the error of ctx once ctx is done, with errPoolTimeout once the command
+// has waited longer than the pool timeout, and with redis.ErrClosed once
+// the pool is closed.
+func (p *adaptivePool) acquire(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inUse >= p.size && !p.closed {
+		p.waits++
+		p.blocked++
+		defer func() { p.blocked-- }()
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+		for p.inUse >= p.size && !p.closed {
+			wake := p.wake
+			p.mu.Unlock()
+			select {
+			case <-wake:
+				p.mu.Lock()
+			case <-ctx.Done():
+				p.mu.Lock()
+				return ctx.Err()
+			case <-timer.C:
+				p.mu.Lock()
+				p.timedOut++
+				return errPoolTimeout
+			}
+		}
+	}
+	if p.closed {
+		return redis.ErrClosed
+	}
+	p.inUse++
+	p.peak = max(p.peak, p.inUse)
+	return nil
+}
+
+// release lets the commands waiting in acquire proceed.
+func (p *adaptivePool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inUse--
+	p.broadcast()
+}
+
+// close makes the commands waiting in acquire, and the later ones, fail.
+func (p *adaptivePool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.broadcast()
+}
+
+// broadcast wakes the commands waiting in acquire. p.mu must be held.
+func (p *adaptivePool) broadcast() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+// adjust resizes the pool from the load observed since the last call
+// and returns the new size. The pool doubles if a command had to wait
+// or is still waiting for a connection, or gave up waiting, either here
+// or in the pool of the client as reported by the timeouts of stats,
+// and halves if less than half of it was used.
+func (p *adaptivePool) adjust(stats *redis.PoolStats) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	timedOut := p.timedOut > 0
+	if stats != nil {
+		timedOut = timedOut || stats.Timeouts > p.timeouts
+		p.timeouts = stats.Timeouts
+	}
+	switch {
+	case p.waits > 0 || p.blocked > 0 || timedOut:
+		p.size = min(p.size*2, p.maxSize)
+		p.broadcast()
+	case p.peak < p.size/2:
+		p.size = max(p.size/2, p.minSize)
+	}
+	p.waits = 0
+	p.timedOut = 0
+	p.peak = p.inUse
+	return p.size
+}
+
+func (p *adaptivePool) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (p *adaptivePool) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := p.acquire(ctx); err != nil {
+			return err
+		}
+		defer p.release()
+		return next(ctx, cmd)
+	}
+}
+
+func (p *adaptivePool) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := p.acquire(ctx); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		defer p.release()
+		return next(ctx, cmds)
+	}
+}
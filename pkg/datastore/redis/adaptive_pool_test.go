@@ -0,0 +1,179 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// burst starts n commands holding their connection until the returned
+// function is called, which waits for all of them to finish.
+func burst(p *adaptivePool, n int) (end func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.acquire(context.Background()); err != nil {
+				return
+			}
+			defer p.release()
+			<-done
+		}()
+	}
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (p *adaptivePool) inFlight() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inUse
+}
+
+func (p *adaptivePool) waiting() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.blocked > 0
+}
+
+func TestAdaptivePoolBurstyLoad(t *testing.T) {
+	p := newAdaptivePool(2, 2, 16, time.Minute)
+
+	for round := 0; round < 2; round++ {
+		// a burst of 12 commands grows the pool up to the max
+		end := burst(p, 12)
+		sizes := []int{}
+		for i := 0; i < 5; i++ {
+			size := p.Size()
+			waitFor(t, func() bool {
+				return p.inFlight() == min(size, 12) && (size >= 12 || p.waiting())
+			})
+			sizes = append(sizes, p.adjust(nil))
+		}
+		for i, size := range sizes {
+			if size < 2 || size > 16 {
+				t.Fatalf("Expected the size within [2, 16], got %v", sizes)
+			}
+			if i > 0 && size < sizes[i-1] {
+				t.Fatalf("Expected the size to grow under load, got %v", sizes)
+			}
+		}
+		if p.Size() != 16 {
+			t.Errorf("Expected the size to reach 16, got %v", sizes)
+		}
+		waitFor(t, func() bool { return p.inFlight() == 12 })
+		end()
+
+		// the pool shrinks back to the min once idle
+		p.adjust(nil)
+		sizes = sizes[:0]
+		for i := 0; i < 5; i++ {
+			sizes = append(sizes, p.adjust(nil))
+		}
+		for i, size := range sizes {
+			if size < 2 || (i > 0 && size > sizes[i-1]) {
+				t.Fatalf("Expected the size to shrink to 2 when idle, got %v", sizes)
+			}
+		}
+		if p.Size() != 2 {
+			t.Errorf("Expected the size to reach 2, got %v", sizes)
+		}
+	}
+}
+
+func TestAdaptivePoolKeepsUsedSize(t *testing.T) {
+	p := newAdaptivePool(8, 1, 16, time.Minute)
+	end := burst(p, 6)
+	defer end()
+	waitFor(t, func() bool { return p.inFlight() == 6 })
+
+	// 6 commands in flight use more than half of the pool
+	for i := 0; i < 3; i++ {
+		if size := p.adjust(nil); size != 8 {
+			t.Fatalf("Expected the size to stay at 8, got %d", size)
+		}
+	}
+}
+
+func TestAdaptivePoolTimeouts(t *testing.T) {
+	p := newAdaptivePool(4, 1, 16, time.Minute)
+	end := burst(p, 4)
+	defer end()
+	waitFor(t, func() bool { return p.inFlight() == 4 })
+
+	if size := p.adjust(&redis.PoolStats{Timeouts: 1}); size != 8 {
+		t.Errorf("Expected a timeout in the client pool to grow the size to 8, got %d", size)
+	}
+	if size := p.adjust(&redis.PoolStats{Timeouts: 1}); size != 8 {
+		t.Errorf("Expected the size to stay at 8 without new timeouts, got %d", size)
+	}
+}
+
+func TestAdaptivePoolAcquireGivesUp(t *testing.T) {
+	p := newAdaptivePool(1, 1, 16, 20*time.Millisecond)
+	end := burst(p, 1)
+	defer end()
+	waitFor(t, func() bool { return p.inFlight() == 1 })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled command to give up, got %v", err)
+	}
+	if err := p.acquire(context.Background()); !errors.Is(err, errPoolTimeout) {
+		t.Errorf("Expected the command to time out, got %v", err)
+	}
+	if p.waiting() || p.inFlight() != 1 {
+		t.Errorf("Expected the commands that gave up to leave the pool")
+	}
+	if size := p.adjust(nil); size != 2 {
+		t.Errorf("Expected a timeout to grow the size to 2, got %d", size)
+	}
+}
+
+func TestAdaptivePoolClose(t *testing.T) {
+	p := newAdaptivePool(1, 1, 16, time.Minute)
+	end := burst(p, 1)
+	defer end()
+	waitFor(t, func() bool { return p.inFlight() == 1 })
+
+	errs := make(chan error)
+	go func() { errs <- p.acquire(context.Background()) }()
+	waitFor(t, p.waiting)
+	p.close()
+	if err := <-errs; !errors.Is(err, redis.ErrClosed) {
+		t.Errorf("Expected the waiting command to fail once closed, got %v", err)
+	}
+	if err := p.acquire(context.Background()); !errors.Is(err, redis.ErrClosed) {
+		t.Errorf("Expected a command to fail once closed, got %v", err)
+	}
+}
+
+func TestNewAdaptivePoolClampsSize(t *testing.T) {
+	if size := newAdaptivePool(60, 2, 16, time.Minute).Size(); size != 16 {
+		t.Errorf("Expected the size clamped to 16, got %d", size)
+	}
+	if size := newAdaptivePool(0, 2, 16, time.Minute).Size(); size != 2 {
+		t.Errorf("Expected the size clamped to 2, got %d", size)
+	}
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisConnection_Modes(t *testing.T) {
+	conn := NewRedisConnection(&ConnectionOptions{Address: "127.0.0.1:1234"})
+	assert.IsType(t, &redis.Client{}, conn.rdb)
+	assert.Equal(t, "127.0.0.1:1234", conn.Address)
+
+	conn = NewRedisConnection(&ConnectionOptions{
+		Mode:      Cluster,
+		Addresses: []string{"127.0.0.1:7000", "127.0.0.1:7001"},
+	})
+	assert.IsType(t, &redis.ClusterClient{}, conn.rdb)
+	assert.Equal(t, "127.0.0.1:7000,127.0.0.1:7001", conn.Address)
+
+	conn = NewRedisConnection(&ConnectionOptions{
+		Mode:       Sentinel,
+		Addresses:  []string{"127.0.0.1:26379"},
+		MasterName: "mymaster",
+	})
+	assert.IsType(t, &redis.Client{}, conn.rdb)
+	assert.Equal(t, "127.0.0.1:26379", conn.Address)
+}
+
+// TestRedisClusterConditionalUpdate runs against the local cluster
+// whose seed nodes are listed in REDIS_CLUSTER_ADDRS, e.g. "localhost:7000,localhost:7001".
+func TestRedisClusterConditionalUpdate(t *testing.T) {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_CLUSTER_ADDRS is not set")
+	}
+	conn := NewRedisConnection(&ConnectionOptions{
+		Mode:      Cluster,
+		Addresses: strings.Split(addrs, ","),
+	})
+	err := conn.Connect()
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// the keys are spread over the slots of different nodes
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("cluster-item-%d", i)
+		_ = conn.Delete(key)
+		item := &RedisItem{
+			RKey:      key,
+			RValue:    "value1",
+			RTxnState: config.PREPARED,
+			RTValid:   time.Now().UnixMicro(),
+			RTLease:   time.Now(),
+			RVersion:  "",
+		}
+
+		ver, err := conn.ConditionalUpdate(key, item, true)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", ver)
+
+		// creating it again is rejected
+		_, err = conn.ConditionalUpdate(key, item, true)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+		// an update on a stale version is rejected
+		item.RVersion = "0"
+		item.RValue = "value2"
+		_, err = conn.ConditionalUpdate(key, item, false)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+		item.RVersion = ver
+		ver, err = conn.ConditionalUpdate(key, item, false)
+		assert.NoError(t, err)
+
+		_, err = conn.ConditionalCommit(key, ver, 100)
+		assert.NoError(t, err)
+		_, err = conn.ConditionalCommit(key, ver, 100)
+		assert.EqualError(t, err, txn.VersionMismatch.Error())
+
+		got, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		assert.Equal(t, "value2", got.Value())
+		assert.Equal(t, config.COMMITTED, got.TxnState())
+
+		gk := fmt.Sprintf("cluster-tsr-%d", i)
+		_ = conn.Delete(gk)
+		_, err = conn.AtomicCreate(gk, config.COMMITTED)
+		assert.NoError(t, err)
+		_, err = conn.AtomicCreate(gk, config.ABORTED)
+		assert.EqualError(t, err, txn.KeyExists.Error())
+	}
+}
@@ -2,6 +2,8 @@ package redis
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-errors/errors"
@@ -16,9 +18,13 @@ import (
 
 // RedisConnection implements the txn.Connector interface.
 var _ txn.Connector = (*RedisConnection)(nil)
+var _ txn.GroupKeyScanner = (*RedisConnection)(nil)
+var _ txn.PrimaryReader = (*RedisConnection)(nil)
+var _ txn.Pinger = (*RedisConnection)(nil)
 
 type RedisConnection struct {
-	rdb                  *redis.Client
+	rdb                  redis.UniversalClient
+	reader               redis.UniversalClient
 	Address              string
 	se                   serializer.Serializer
 	connected            bool
@@ -26,18 +32,72 @@ type RedisConnection struct {
 	atomicCreateItemSHA  string
 	conditionalUpdateSHA string
 	conditionalCommitSHA string
+	ns                   txn.Namespace
+	poolSize             int
+	pool                 *adaptivePool
+	adjustInterval       time.Duration
+	stopAdjust           chan struct{}
 }
 
+// Mode specifies how the Redis deployment is reached.
+type Mode string
+
+const (
+	// Single connects to a standalone Redis server at Address.
+	Single Mode = "single"
+
+	// Cluster connects to a Redis Cluster through the seed nodes in Addresses.
+	Cluster Mode = "cluster"
+
+	// Sentinel connects to the master named MasterName
+	// through the sentinels in Addresses.
+	Sentinel Mode = "sentinel"
+)
+
 type ConnectionOptions struct {
-	Address  string
-	Password string
-	se       serializer.Serializer
-	PoolSize int
+	// Mode defaults to Single.
+	Mode Mode
+	// Address is the server address in Single mode.
+	// It is used when Addresses is empty in the other modes.
+	Address string
+	// Addresses are the seed nodes in Cluster mode
+	// and the sentinels in Sentinel mode.
+	Addresses []string
+	// MasterName is the name of the master monitored by the sentinels.
+	MasterName string
+	Password   string
+	se         serializer.Serializer
+	PoolSize   int
+	// MaxPoolSize enables the adaptive pool when it is greater than zero.
+	// The number of connections in use then starts at PoolSize and is
+	// resized every PoolAdjustInterval, 1s if unset, between MinPoolSize,
+	// 1 if unset, and MaxPoolSize: it grows when commands wait for a
+	// connection and shrinks when the pool is mostly idle.
+	MinPoolSize        int
+	MaxPoolSize        int
+	PoolAdjustInterval time.Duration
+	// PoolTimeout is how long a command waits for a connection before
+	// failing, DefaultPoolTimeout if unset.
+	PoolTimeout time.Duration
+	// Namespace prefixes every key stored through the connection.
+	Namespace txn.Namespace
+	// ReaderAddress is the address of a read replica or a reader endpoint
+	// serving GetItem and Get, which are served by the primary if it is empty.
+	// A replica lags behind the primary, so a read may return an older
+	// version of a record. The conditional updates of the prepare phase
+	// catch it for the records a transaction writes, but a record it only
+	// reads is checked against the primary only if config.Config.ReadSetValidation
+	// is on in the process running the transaction. Without it, a transaction
+	// may read a stale snapshot from a lagging replica.
+	ReaderAddress string
 }
 
+// Every script only touches KEYS[1], so in Cluster mode it runs
+// on the node owning the slot of that key and needs no hash tag.
+
 const AtomicCreateScript = `
 if redis.call('EXISTS', KEYS[1]) == 0 then
-	return redis.call('SET',ARGV[1] , ARGV[2])
+	return redis.call('SET', KEYS[1], ARGV[2])
 else
 	return redis.error_reply('already exists')
 end
@@ -93,8 +153,9 @@ end
 // NewRedisConnection creates a new Redis connection using the provided configuration options.
 // If the config parameter is nil, default values will be used.
 //
-// The Redis connection is established using the specified address and password.
+// In Single mode the connection is established using the specified address and password.
 // The address format should be in the form "host:port".
+// In Cluster and Sentinel modes the addresses of the seed nodes or the sentinels are used.
 //
 // The se parameter is used for data serialization and deserialization.
 // If se is nil, a default JSON serializer will be used.
@@ -106,9 +167,15 @@ func NewRedisConnection(config *ConnectionOptions) *RedisConnection {
 			Address: "localhost:6379",
 		}
 	}
+	if config.Mode == "" {
+		config.Mode = Single
+	}
 	if config.Address == "" {
 		config.Address = "localhost:6379"
 	}
+	if len(config.Addresses) == 0 {
+		config.Addresses = []string{config.Address}
+	}
 
 	if config.se == nil {
 		config.se = serializer.NewJSONSerializer()
@@ -118,14 +185,105 @@ func NewRedisConnection(config *ConnectionOptions) *RedisConnection {
 		config.PoolSize = 60
 	}
 
-	return &RedisConnection{
-		rdb: redis.NewClient(&redis.Options{
-			Addr:     config.Address,
+	address := config.Address
+	if config.Mode != Single {
+		address = strings.Join(config.Addresses, ",")
+	}
+
+	conn := &RedisConnection{
+		Address:  address,
+		se:       config.se,
+		ns:       config.Namespace,
+		poolSize: config.PoolSize,
+	}
+	if config.MaxPoolSize > 0 {
+		if config.MinPoolSize <= 0 {
+			config.MinPoolSize = 1
+		}
+		if config.PoolAdjustInterval <= 0 {
+			config.PoolAdjustInterval = DefaultPoolAdjustInterval
+		}
+		if config.PoolTimeout <= 0 {
+			config.PoolTimeout = DefaultPoolTimeout
+		}
+		conn.pool = newAdaptivePool(config.PoolSize, config.MinPoolSize, config.MaxPoolSize, config.PoolTimeout)
+		conn.adjustInterval = config.PoolAdjustInterval
+	}
+	conn.rdb = newClient(config)
+	if conn.pool != nil {
+		conn.rdb.AddHook(conn.pool)
+	}
+	if config.ReaderAddress != "" {
+		conn.reader = redis.NewClient(&redis.Options{
+			Addr:     config.ReaderAddress,
 			Password: config.Password,
 			PoolSize: config.PoolSize,
-		}),
-		Address: config.Address,
-		se:      config.se,
+		})
+	}
+	return conn
+}
+
+func newClient(config *ConnectionOptions) redis.UniversalClient {
+	// the pool of an adaptive connection is bounded by its hook,
+	// and the connections left idle when it shrinks are closed
+	poolSize, idleTime := config.PoolSize, time.Duration(0)
+	if config.MaxPoolSize > 0 {
+		poolSize, idleTime = config.MaxPoolSize, 4*config.PoolAdjustInterval
+	}
+	switch config.Mode {
+	case Cluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:           config.Addresses,
+			Password:        config.Password,
+			PoolSize:        poolSize,
+			PoolTimeout:     config.PoolTimeout,
+			ConnMaxIdleTime: idleTime,
+		})
+	case Sentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:      config.MasterName,
+			SentinelAddrs:   config.Addresses,
+			Password:        config.Password,
+			PoolSize:        poolSize,
+			PoolTimeout:     config.PoolTimeout,
+			ConnMaxIdleTime: idleTime,
+		})
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:            config.Address,
+			Password:        config.Password,
+			PoolSize:        poolSize,
+			PoolTimeout:     config.PoolTimeout,
+			ConnMaxIdleTime: idleTime,
+		})
+	}
+}
+
+// PoolSize returns the number of connections the commands can use,
+// which changes over time if the adaptive pool is enabled.
+func (r *RedisConnection) PoolSize() int {
+	if r.pool != nil {
+		return r.pool.Size()
+	}
+	return r.poolSize
+}
+
+// adjustPool resizes the adaptive pool every interval until stop is closed.
+func (r *RedisConnection) adjustPool(stop chan struct{}) {
+	ticker := time.NewTicker(r.adjustInterval)
+	defer ticker.Stop()
+	size := r.pool.Size()
+	for {
+		select {
+		case <-ticker.C:
+			newSize := r.pool.adjust(r.rdb.PoolStats())
+			if newSize != size {
+				logger.Log.Debugw("Resize pool", "address", r.Address, "from", size, "to", newSize)
+				size = newSize
+			}
+		case <-stop:
+			return
+		}
 	}
 }
 
@@ -137,8 +295,6 @@ func (r *RedisConnection) Connect() error {
 		return nil
 	}
 
-	r.connected = true
-
 	logger.Log.Debugw("Start Connect", "address", r.Address)
 	defer logger.Log.Debugw("End   Connect", "address", r.Address)
 
@@ -181,29 +337,158 @@ func (r *RedisConnection) Connect() error {
 		return nil
 	})
 
-	return eg.Wait()
+	// a failed Connect can be retried once the server is up
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	r.connected = true
+	if r.pool != nil {
+		r.stopAdjust = make(chan struct{})
+		go r.adjustPool(r.stopAdjust)
+	}
+	return nil
+}
+
+// evalSha runs the loaded script by its SHA. The script cache of a node
+// promoted by a failover or added to the cluster after Connect may not have
+// the script, in which case the script is sent along.
+func (r *RedisConnection) evalSha(ctx context.Context, sha string, script string,
+	keys []string, args ...interface{}) *redis.Cmd {
+	cmd := r.rdb.EvalSha(ctx, sha, keys, args...)
+	if redis.HasErrorPrefix(cmd.Err(), "NOSCRIPT") {
+		return r.rdb.Eval(ctx, script, keys, args...)
+	}
+	return cmd
+}
+
+// pingTimeout bounds the wait of Ping.
+const pingTimeout = 2 * time.Second
+
+// Ping checks that the server answers a PING within pingTimeout.
+func (r *RedisConnection) Ping() error {
+	if !r.connected {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	return r.rdb.Ping(ctx).Err()
+}
+
+// Close closes the Redis client and releases all the pooled connections.
+// Any operation issued after Close returns an error.
+func (r *RedisConnection) Close() error {
+	r.connected = false
+	if r.stopAdjust != nil {
+		close(r.stopAdjust)
+		r.stopAdjust = nil
+	}
+	if r.pool != nil {
+		r.pool.close()
+	}
+	if r.reader != nil {
+		if err := r.reader.Close(); err != nil {
+			return err
+		}
+	}
+	return r.rdb.Close()
+}
+
+// readClient returns the client serving GetItem and Get.
+func (r *RedisConnection) readClient() redis.UniversalClient {
+	if r.reader != nil {
+		return r.reader
+	}
+	return r.rdb
 }
 
 // GetItem retrieves a txn.DataItem from the Redis database based on the specified key.
+// It is read from the replica at ReaderAddress if one is set.
 // If the key is not found, it returns an empty txn.DataItem and an error.
+// An item failing txn.CheckLinkedLen is returned as an error.
 func (r *RedisConnection) GetItem(key string) (txn.DataItem, error) {
+	return r.getItem(r.readClient(), key)
+}
+
+// GetItemFromPrimary retrieves the item of key from the primary,
+// even if GetItem is served by a replica.
+func (r *RedisConnection) GetItemFromPrimary(key string) (txn.DataItem, error) {
+	return r.getItem(r.rdb, key)
+}
+
+func (r *RedisConnection) getItem(rdb redis.UniversalClient, key string) (txn.DataItem, error) {
 
 	if config.Debug.DebugMode {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
 	var value RedisItem
-	err := r.rdb.HGetAll(context.Background(), key).Scan(&value)
+	cmd := rdb.HGetAll(context.Background(), r.ns.Key(key))
+	err := cmd.Scan(&value)
 	if err != nil {
 		return &RedisItem{}, err
 	}
+	// TLease is skipped by Scan, it is encoded by txn.EncodeLease
+	if value.RTLease, err = txn.ParseLease(cmd.Val()["TLease"]); err != nil {
+		return &RedisItem{}, err
+	}
 	// Check if returned value is an empty struct
 	if value.Empty() {
 		return &RedisItem{}, errors.New(txn.KeyNotFound)
 	}
+	if value.RValue, err = txn.DecompressValue(value.RValue); err != nil {
+		return &RedisItem{}, err
+	}
+	if value.RPrev, err = txn.DecompressValue(value.RPrev); err != nil {
+		return &RedisItem{}, err
+	}
+	if err := txn.CheckLinkedLen(&value, &RedisItemFactory{}); err != nil {
+		return &RedisItem{}, err
+	}
 	return &value, nil
 }
 
+// compressFields returns the value and the previous versions of item
+// compressed as configured by config.Config.ValueCompression.
+func compressFields(item txn.DataItem) (string, string, error) {
+	value, err := txn.CompressValue(item.Value())
+	if err != nil {
+		return "", "", err
+	}
+	prev, err := txn.CompressValue(item.Prev())
+	if err != nil {
+		return "", "", err
+	}
+	return value, prev, nil
+}
+
+// GetItemHistory retrieves the item stored under key together with the previous
+// versions linked through its Prev field, ordered from newest to oldest.
+// At most maxDepth versions are returned; a non-positive maxDepth means no limit.
+// If a Prev field cannot be deserialized, the versions collected so far are
+// returned along with an error wrapping txn.DeserializeError.
+func (r *RedisConnection) GetItemHistory(key string, maxDepth int) ([]txn.DataItem, error) {
+	item, err := r.GetItem(key)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []txn.DataItem{item}
+	for maxDepth <= 0 || len(history) < maxDepth {
+		prev := history[len(history)-1].Prev()
+		if prev == "" {
+			break
+		}
+		var prevItem RedisItem
+		err := r.se.Deserialize([]byte(prev), &prevItem)
+		if err != nil {
+			return history, errors.Join(txn.DeserializeError,
+				errors.Errorf("version %d of %s: %w", len(history), key, err))
+		}
+		history = append(history, &prevItem)
+	}
+	return history, nil
+}
+
 // PutItem puts an item into the Redis database with the specified key and value.
 // It sets various fields of the txn.DataItem struct as hash fields in the Redis hash.
 // The function returns an error if there was a problem executing the Redis commands.
@@ -213,15 +498,21 @@ func (r *RedisConnection) PutItem(key string, value txn.DataItem) (string, error
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
+	val, prev, err := compressFields(value)
+	if err != nil {
+		return "", err
+	}
+
 	ctx := context.Background()
-	_, err := r.rdb.Pipelined(ctx, func(rdb redis.Pipeliner) error {
+	key = r.ns.Key(key)
+	_, err = r.rdb.Pipelined(ctx, func(rdb redis.Pipeliner) error {
 		rdb.HSet(ctx, key, "Key", value.Key())
-		rdb.HSet(ctx, key, "Value", value.Value())
+		rdb.HSet(ctx, key, "Value", val)
 		rdb.HSet(ctx, key, "GroupKeyList", value.GroupKeyList())
 		rdb.HSet(ctx, key, "TxnState", value.TxnState())
 		rdb.HSet(ctx, key, "TValid", value.TValid())
-		rdb.HSet(ctx, key, "TLease", value.TLease().Format(time.RFC3339Nano))
-		rdb.HSet(ctx, key, "Prev", value.Prev())
+		rdb.HSet(ctx, key, "TLease", txn.EncodeLease(value.TLease()))
+		rdb.HSet(ctx, key, "Prev", prev)
 		rdb.HSet(ctx, key, "LinkedLen", value.LinkedLen())
 		rdb.HSet(ctx, key, "IsDeleted", value.IsDeleted())
 		rdb.HSet(ctx, key, "Version", value.Version())
@@ -238,6 +529,7 @@ func (r *RedisConnection) PutItem(key string, value txn.DataItem) (string, error
 // It takes a key string and a txn.DataItem value as parameters.
 // If the item's version does not match, it returns a version mismatch error.
 // Otherwise, it updates the item with the provided values and returns the updated item.
+// An item failing txn.CheckLinkedLen is refused before being written.
 func (r *RedisConnection) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
 
 	debugStart := time.Now()
@@ -251,13 +543,22 @@ func (r *RedisConnection) ConditionalUpdate(key string, value txn.DataItem, doCr
 		logger.Log.Debugw("End    ConditionalUpdate", "key", key, "LatencyInFunc", time.Since(debugStart), "Topic", "CheckPoint")
 	}()
 
+	if err := txn.CheckLinkedLen(value, &RedisItemFactory{}); err != nil {
+		return "", err
+	}
+
+	val, prev, err := compressFields(value)
+	if err != nil {
+		return "", err
+	}
+
 	if doCreate {
 		ctx := context.Background()
 		newVer := util.AddToString(value.Version(), 1)
 
-		_, err := r.rdb.EvalSha(ctx, r.atomicCreateItemSHA, []string{value.Key()}, value.Version(), value.Key(),
-			value.Value(), value.GroupKeyList(), value.TxnState(), value.TValid(), value.TLease(),
-			newVer, value.Prev(), value.LinkedLen(), value.IsDeleted()).Result()
+		_, err := r.evalSha(ctx, r.atomicCreateItemSHA, AtomicCreateItemScript, []string{r.ns.Key(value.Key())}, value.Version(), value.Key(),
+			val, value.GroupKeyList(), value.TxnState(), value.TValid(), txn.EncodeLease(value.TLease()),
+			newVer, prev, value.LinkedLen(), value.IsDeleted()).Result()
 		if err != nil {
 			if err.Error() == "version mismatch" {
 				logger.Log.Debugw("Version mismatch", "expected version", value.Version(), "current version", value.Version())
@@ -271,9 +572,9 @@ func (r *RedisConnection) ConditionalUpdate(key string, value txn.DataItem, doCr
 	ctx := context.Background()
 	newVer := util.AddToString(value.Version(), 1)
 
-	_, err := r.rdb.EvalSha(ctx, r.conditionalUpdateSHA, []string{value.Key()}, value.Version(), value.Key(),
-		value.Value(), value.GroupKeyList(), value.TxnState(), value.TValid(), value.TLease(),
-		newVer, value.Prev(), value.LinkedLen(), value.IsDeleted()).Result()
+	_, err = r.evalSha(ctx, r.conditionalUpdateSHA, ConditionalUpdateScript, []string{r.ns.Key(value.Key())}, value.Version(), value.Key(),
+		val, value.GroupKeyList(), value.TxnState(), value.TValid(), txn.EncodeLease(value.TLease()),
+		newVer, prev, value.LinkedLen(), value.IsDeleted()).Result()
 	if err != nil {
 		if err.Error() == "version mismatch" {
 			return "", errors.New(txn.VersionMismatch)
@@ -300,8 +601,8 @@ func (r *RedisConnection) ConditionalCommit(key string, version string, tCommit
 	ctx := context.Background()
 	newVer := util.AddToString(version, 1)
 
-	_, err := r.rdb.EvalSha(ctx, r.conditionalCommitSHA,
-		[]string{key}, version, config.COMMITTED, newVer, tCommit).Result()
+	_, err := r.evalSha(ctx, r.conditionalCommitSHA, ConditionalCommitScript,
+		[]string{r.ns.Key(key)}, version, config.COMMITTED, newVer, tCommit).Result()
 	if err != nil {
 		if err.Error() == "version mismatch" {
 			return "", errors.New(txn.VersionMismatch)
@@ -318,8 +619,8 @@ func (r *RedisConnection) AtomicCreate(name string, value any) (string, error) {
 	}
 
 	ctx := context.Background()
-	_, err := r.rdb.
-		EvalSha(ctx, r.atomicCreateSHA, []string{name}, name, value).Result()
+	_, err := r.
+		evalSha(ctx, r.atomicCreateSHA, AtomicCreateScript, []string{r.ns.Key(name)}, name, value).Result()
 	if err != nil {
 		if err.Error() == "already exists" {
 			old, err := r.Get(name)
@@ -337,13 +638,14 @@ func (r *RedisConnection) AtomicCreate(name string, value any) (string, error) {
 // If the key is not found, it returns an empty string and an error indicating the key was not found.
 // If an error occurs during the retrieval, it returns an empty string and the error.
 // Otherwise, it returns the retrieved value and nil error.
+// It is read from the replica at ReaderAddress if one is set.
 func (r *RedisConnection) Get(name string) (string, error) {
 
 	if config.Debug.DebugMode {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	str, err := r.rdb.Get(context.Background(), name).Result()
+	str, err := r.readClient().Get(context.Background(), r.ns.Key(name)).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return "", errors.New(txn.KeyNotFound)
@@ -362,7 +664,7 @@ func (r *RedisConnection) Put(name string, value any) error {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	return r.rdb.Set(context.Background(), name, value, 0).Err()
+	return r.rdb.Set(context.Background(), r.ns.Key(name), value, 0).Err()
 }
 
 // Delete removes the specified key from Redis.
@@ -373,5 +675,135 @@ func (r *RedisConnection) Delete(name string) error {
 		time.Sleep(config.Debug.ConnAdditionalLatency)
 	}
 
-	return r.rdb.Del(context.Background(), name).Err()
+	return r.rdb.Del(context.Background(), r.ns.Key(name)).Err()
+}
+
+// keyScanCount is the number of keys hinted to each SCAN.
+const keyScanCount = 1000
+
+// forEachNode calls fn with the client of every node holding keys,
+// which is each master node in Cluster mode.
+func (r *RedisConnection) forEachNode(ctx context.Context, fn func(ctx context.Context, rdb redis.Cmdable) error) error {
+	cluster, ok := r.rdb.(*redis.ClusterClient)
+	if !ok {
+		return fn(ctx, r.rdb)
+	}
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return fn(ctx, node)
+	})
+}
+
+// KeysByGroupKey returns the keys of the records whose group key list
+// contains groupKey. Redis has no secondary index, so every hash of the
+// namespace is scanned, on each master node in Cluster mode.
+func (r *RedisConnection) KeysByGroupKey(groupKey string) ([]string, error) {
+	var mu sync.Mutex
+	var keys []string
+	err := r.forEachNode(context.Background(), func(ctx context.Context, rdb redis.Cmdable) error {
+		nodeKeys, err := scanGroupKey(ctx, rdb, r.ns, groupKey)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, nodeKeys...)
+		return nil
+	})
+	return keys, err
+}
+
+// Keys returns the keys of the namespace matching the glob pattern.
+// They are listed with SCAN, which does not block the server like KEYS.
+func (r *RedisConnection) Keys(pattern string) ([]string, error) {
+	var mu sync.Mutex
+	var keys []string
+	err := r.forEachNode(context.Background(), func(ctx context.Context, rdb redis.Cmdable) error {
+		return scanKeys(ctx, rdb, r.ns.Key(pattern), func(page []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, stored := range page {
+				if key, ok := r.ns.Strip(stored); ok {
+					keys = append(keys, key)
+				}
+			}
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// FlushNamespace removes every key of the namespace starting with prefix,
+// a page of SCAN at a time with UNLINK, which frees the values in the
+// background. An empty prefix is refused rather than flushing everything.
+func (r *RedisConnection) FlushNamespace(prefix string) error {
+	if prefix == "" {
+		return errors.New("refusing to flush the keys without a prefix")
+	}
+	stored := r.ns.Key(prefix)
+	return r.forEachNode(context.Background(), func(ctx context.Context, rdb redis.Cmdable) error {
+		return scanKeys(ctx, rdb, globEscape(stored)+"*", func(page []string) error {
+			// the keys of a page may span slots, so they are unlinked one by one
+			pipe := rdb.Pipeline()
+			for _, key := range page {
+				if strings.HasPrefix(key, stored) {
+					pipe.Unlink(ctx, key)
+				}
+			}
+			if pipe.Len() == 0 {
+				return nil
+			}
+			_, err := pipe.Exec(ctx)
+			return err
+		})
+	})
+}
+
+// scanKeys calls fn with every page of the keys of rdb matching match.
+func scanKeys(ctx context.Context, rdb redis.Cmdable, match string, fn func(page []string) error) error {
+	var cursor uint64
+	for {
+		page, next, err := rdb.Scan(ctx, cursor, match, keyScanCount).Result()
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// globEscape escapes the special characters of a Redis glob pattern in s.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// scanGroupKey scans the hashes of rdb in ns for the records tagged with groupKey.
+func scanGroupKey(ctx context.Context, rdb redis.Cmdable, ns txn.Namespace, groupKey string) ([]string, error) {
+	var keys []string
+	iter := rdb.ScanType(ctx, 0, ns.Pattern(), keyScanCount, "hash").Iterator()
+	for iter.Next(ctx) {
+		groupKeyList, err := rdb.HGet(ctx, iter.Val(), "GroupKeyList").Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, ok := ns.Strip(iter.Val())
+		if ok && txn.HasGroupKey(groupKeyList, groupKey) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, iter.Err()
 }
@@ -1,13 +1,16 @@
 package redis
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-redis/redismock/v9"
+	"github.com/oreo-dtx-lab/oreo/internal/conntest"
 	"github.com/oreo-dtx-lab/oreo/internal/testutil"
 	"github.com/oreo-dtx-lab/oreo/internal/util"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
@@ -45,6 +48,23 @@ func TestRedisConnection_Connect(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestRedisConnection_Close(t *testing.T) {
+	conn := NewRedisConnection(nil)
+	err := conn.Connect()
+	assert.NoError(t, err)
+
+	err = conn.Put("close-test", "value")
+	assert.NoError(t, err)
+
+	err = conn.Close()
+	assert.NoError(t, err)
+
+	_, err = conn.Get("close-test")
+	assert.EqualError(t, err, "redis: client is closed")
+	_, err = conn.GetItem("close-test")
+	assert.EqualError(t, err, "redis: client is closed")
+}
+
 func TestTimestamp(t *testing.T) {
 	tValid := time.Now().Add(-3 * time.Second)
 	// tValidStr :=
@@ -144,6 +164,127 @@ func TestRedisConnectionPutItemAndGetItem(t *testing.T) {
 	}
 }
 
+func TestRedisConnectionPutItemAndGetItemCompressed(t *testing.T) {
+	compression := config.Config.ValueCompression
+	defer func() { config.Config.ValueCompression = compression }()
+
+	for _, c := range []config.Compression{config.GzipCompression, config.SnappyCompression} {
+		t.Run(string(c), func(t *testing.T) {
+			config.Config.ValueCompression = c
+			conn := NewRedisConnection(nil)
+			key := "compressed_key"
+			conn.Delete(key)
+
+			prevItem := &RedisItem{
+				RKey:      key,
+				RValue:    util.ToJSONString(testutil.NewTestItem(strings.Repeat("v1", 4096))),
+				RTxnState: config.COMMITTED,
+				RTValid:   time.Now().Add(-10 * time.Second).UnixMicro(),
+				RVersion:  "1",
+			}
+			expectedItem := &RedisItem{
+				RKey:          key,
+				RValue:        util.ToJSONString(testutil.NewTestItem(strings.Repeat("v2", 4096))),
+				RGroupKeyList: "1",
+				RTxnState:     config.COMMITTED,
+				RTValid:       time.Now().Add(-3 * time.Second).UnixMicro(),
+				RTLease:       time.Now().Add(-2 * time.Second),
+				RPrev:         util.ToJSONString(prevItem),
+				RLinkedLen:    2,
+				RVersion:      "2",
+			}
+
+			_, err := conn.PutItem(key, expectedItem)
+			assert.NoError(t, err)
+
+			// the value is stored compressed
+			stored, err := conn.rdb.HGet(context.Background(), key, "Value").Result()
+			assert.NoError(t, err)
+			assert.Less(t, len(stored), len(expectedItem.RValue))
+
+			item, err := conn.GetItem(key)
+			assert.NoError(t, err)
+			if !item.Equal(expectedItem) {
+				t.Errorf("\nexpect: \n%v, \nactual: \n%v", expectedItem, item)
+			}
+		})
+	}
+}
+
+func TestRedisConnectionGetItemHistory(t *testing.T) {
+	conn := NewRedisConnection(nil)
+	key := "history_key"
+	conn.Delete(key)
+
+	now := time.Now()
+	item1 := &RedisItem{
+		RKey:          key,
+		RValue:        util.ToJSONString(testutil.NewTestItem("v1")),
+		RGroupKeyList: "txn1",
+		RTxnState:     config.COMMITTED,
+		RTValid:       now.Add(-30 * time.Second).UnixMicro(),
+		RTLease:       now.Add(-29 * time.Second),
+		RVersion:      "1",
+		RLinkedLen:    1,
+	}
+	item2 := &RedisItem{
+		RKey:          key,
+		RValue:        util.ToJSONString(testutil.NewTestItem("v2")),
+		RGroupKeyList: "txn2",
+		RTxnState:     config.COMMITTED,
+		RTValid:       now.Add(-20 * time.Second).UnixMicro(),
+		RTLease:       now.Add(-19 * time.Second),
+		RVersion:      "2",
+		RPrev:         util.ToJSONString(item1),
+		RLinkedLen:    2,
+	}
+	item3 := &RedisItem{
+		RKey:          key,
+		RValue:        util.ToJSONString(testutil.NewTestItem("v3")),
+		RGroupKeyList: "txn3",
+		RTxnState:     config.COMMITTED,
+		RTValid:       now.Add(-10 * time.Second).UnixMicro(),
+		RTLease:       now.Add(-9 * time.Second),
+		RVersion:      "3",
+		RPrev:         util.ToJSONString(item2),
+		RLinkedLen:    3,
+	}
+	_, err := conn.PutItem(key, item3)
+	assert.NoError(t, err)
+
+	t.Run("full chain", func(t *testing.T) {
+		history, err := conn.GetItemHistory(key, 0)
+		assert.NoError(t, err)
+		assert.Len(t, history, 3)
+		for i, expected := range []string{"v3", "v2", "v1"} {
+			var value testutil.TestItem
+			err := json.Unmarshal([]byte(history[i].Value()), &value)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, value.Value)
+		}
+		assert.Equal(t, "3", history[0].Version())
+		assert.Equal(t, "1", history[2].Version())
+	})
+
+	t.Run("bounded by maxDepth", func(t *testing.T) {
+		history, err := conn.GetItemHistory(key, 2)
+		assert.NoError(t, err)
+		assert.Len(t, history, 2)
+		assert.Equal(t, "2", history[1].Version())
+	})
+
+	t.Run("malformed chain", func(t *testing.T) {
+		broken := *item3
+		broken.RPrev = "{not json"
+		_, err := conn.PutItem(key, &broken)
+		assert.NoError(t, err)
+
+		history, err := conn.GetItemHistory(key, 0)
+		assert.Len(t, history, 1)
+		assert.ErrorIs(t, err, txn.DeserializeError)
+	})
+}
+
 func TestRedisConnectionReplaceAndGetItem(t *testing.T) {
 	conn := NewRedisConnection(nil)
 
@@ -642,3 +783,17 @@ func TestRedisConnectionConditionalCommit(t *testing.T) {
 	}
 
 }
+
+// TestRedisConnection_Namespace tests that two connections with different
+// namespaces on the same database do not see each other's keys.
+func TestRedisConnection_Namespace(t *testing.T) {
+	newConn := func(ns txn.Namespace) txn.Connector {
+		conn := NewRedisConnection(&ConnectionOptions{
+			Address:   "localhost:6379",
+			Namespace: ns,
+		})
+		assert.NoError(t, conn.Connect())
+		return conn
+	}
+	conntest.Namespace(t, newConn, &RedisItemFactory{})
+}
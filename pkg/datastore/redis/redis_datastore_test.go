@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -1081,6 +1082,72 @@ func TestRedisDatastore_ConcurrentWriteConflicts(t *testing.T) {
 
 }
 
+func TestTxnValidate(t *testing.T) {
+	conn := NewDefaultRedisConnection()
+	dbItem := &RedisItem{
+		RKey:          "item1",
+		RValue:        util.ToJSONString(testutil.NewTestItem("item1-db")),
+		RGroupKeyList: "txn0",
+		RTxnState:     config.COMMITTED,
+		RTValid:       time.Now().Add(-10 * time.Second).UnixMicro(),
+		RTLease:       time.Now().Add(-9 * time.Second),
+		RVersion:      "1",
+		RLinkedLen:    1,
+	}
+
+	t.Run("would succeed", func(t *testing.T) {
+		_, err := conn.PutItem("item1", dbItem)
+		assert.NoError(t, err)
+
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err = txn.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+		txn.Write("redis", "item1", testutil.NewTestItem("item1-txn"))
+		err = txn.Validate()
+		assert.NoError(t, err)
+
+		// the record is released and left untouched
+		res, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		assert.Equal(t, dbItem.RValue, res.Value())
+		assert.Equal(t, config.COMMITTED, res.TxnState())
+
+		// the transaction can not be committed after validation
+		err = txn.Commit()
+		assert.Error(t, err)
+	})
+
+	t.Run("would conflict", func(t *testing.T) {
+		_, err := conn.PutItem("item1", dbItem)
+		assert.NoError(t, err)
+
+		txn1 := NewTransactionWithSetup()
+		txn1.Start()
+		var item testutil.TestItem
+		err = txn1.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+		txn1.Write("redis", "item1", testutil.NewTestItem("item1-txn1"))
+
+		txn2 := NewTransactionWithSetup()
+		txn2.Start()
+		err = txn2.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+		txn2.Write("redis", "item1", testutil.NewTestItem("item1-txn2"))
+		err = txn2.Commit()
+		assert.NoError(t, err)
+
+		err = txn1.Validate()
+		assert.Error(t, err)
+
+		// the committed value of txn2 is kept
+		res, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		assert.Equal(t, util.ToJSONString(testutil.NewTestItem("item1-txn2")), res.Value())
+	})
+}
+
 func TestTxnWriteMultiRecord(t *testing.T) {
 
 	// clear the test data
@@ -1212,6 +1279,95 @@ func TestLinkedReadAsCommitted(t *testing.T) {
 	})
 }
 
+func TestReadAtSnapshot(t *testing.T) {
+	now := time.Now()
+
+	item1_1 := testutil.NewTestItem("item1_1")
+	memItem1_1 := &RedisItem{
+		RKey:          "item1",
+		RValue:        util.ToJSONString(item1_1),
+		RGroupKeyList: "txn1",
+		RTxnState:     config.COMMITTED,
+		RTValid:       now.Add(-30 * time.Second).UnixMicro(),
+		RTLease:       now.Add(-29 * time.Second),
+		RVersion:      "1",
+		RLinkedLen:    1,
+	}
+
+	item1_2 := testutil.NewTestItem("item1_2")
+	memItem1_2 := &RedisItem{
+		RKey:          "item1",
+		RValue:        util.ToJSONString(item1_2),
+		RGroupKeyList: "txn2",
+		RTxnState:     config.COMMITTED,
+		RTValid:       now.Add(-20 * time.Second).UnixMicro(),
+		RTLease:       now.Add(-19 * time.Second),
+		RVersion:      "2",
+		RPrev:         util.ToJSONString(memItem1_1),
+		RLinkedLen:    2,
+	}
+
+	item1_3 := testutil.NewTestItem("item1_3")
+	memItem1_3 := &RedisItem{
+		RKey:          "item1",
+		RValue:        util.ToJSONString(item1_3),
+		RGroupKeyList: "txn3",
+		RTxnState:     config.COMMITTED,
+		RTValid:       now.Add(-10 * time.Second).UnixMicro(),
+		RTLease:       now.Add(-9 * time.Second),
+		RVersion:      "3",
+		RPrev:         util.ToJSONString(memItem1_2),
+		RLinkedLen:    3,
+	}
+
+	conn := NewDefaultRedisConnection()
+	_, err := conn.PutItem("item1", memItem1_3)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		ts       int64
+		expected string
+	}{
+		{"latest version", now.UnixMicro(), "item1_3"},
+		{"exactly at TValid", memItem1_3.RTValid, "item1_3"},
+		{"middle version", now.Add(-15 * time.Second).UnixMicro(), "item1_2"},
+		{"oldest version", now.Add(-25 * time.Second).UnixMicro(), "item1_1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			txn := NewTransactionWithSetup()
+			txn.Start()
+			var item testutil.TestItem
+			err := txn.ReadAt("redis", "item1", tc.ts, &item)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, item.Value)
+		})
+	}
+
+	t.Run("ts predates all versions", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err := txn.ReadAt("redis", "item1", now.Add(-40*time.Second).UnixMicro(), &item)
+		assert.EqualError(t, err, "key not found")
+	})
+
+	t.Run("ReadAt does not populate the read set", func(t *testing.T) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err := txn.ReadAt("redis", "item1", now.Add(-15*time.Second).UnixMicro(), &item)
+		assert.NoError(t, err)
+		assert.Equal(t, "item1_2", item.Value)
+
+		err = txn.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+		assert.Equal(t, "item1_3", item.Value)
+	})
+}
+
 func TestLinkedTruncate(t *testing.T) {
 
 	t.Cleanup(func() {
@@ -1841,3 +1997,373 @@ func TestItemVersionUpdate(t *testing.T) {
 		assert.Equal(t, util.AddToString(dbItem.Version(), 2), res.Version())
 	})
 }
+
+// TestTxnReadOnlySnapshot tests that a read-only transaction sees the snapshot
+// at its start on every datastore even if a writer commits between its reads.
+func TestTxnReadOnlySnapshot(t *testing.T) {
+	conn := NewDefaultRedisConnection()
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransaction()
+		rds1 := NewRedisDatastore("redis1", conn)
+		rds2 := NewRedisDatastore("redis2", conn)
+		txn.AddDatastore(rds1)
+		txn.AddDatastore(rds2)
+		txn.SetGlobalDatastore(rds1)
+		return txn
+	}
+
+	for _, key := range []string{"item1", "item2"} {
+		dbItem := &RedisItem{
+			RKey:          key,
+			RValue:        util.ToJSONString(testutil.NewTestItem(key + "-db")),
+			RGroupKeyList: "txn0",
+			RTxnState:     config.COMMITTED,
+			RTValid:       time.Now().Add(-10 * time.Second).UnixMicro(),
+			RTLease:       time.Now().Add(-9 * time.Second),
+			RVersion:      "1",
+			RLinkedLen:    1,
+		}
+		_, err := conn.PutItem(key, dbItem)
+		assert.NoError(t, err)
+	}
+
+	roTxn := newTxn()
+	err := roTxn.StartReadOnly()
+	assert.NoError(t, err)
+	assert.Equal(t, roTxn.TxnStartTime, roTxn.SnapshotTime())
+
+	var item1 testutil.TestItem
+	err = roTxn.Read("redis1", "item1", &item1)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.NewTestItem("item1-db"), item1)
+
+	// a concurrent writer updates both datastores
+	wTxn := newTxn()
+	wTxn.Start()
+	wTxn.Write("redis1", "item1", testutil.NewTestItem("item1-txn"))
+	wTxn.Write("redis2", "item2", testutil.NewTestItem("item2-txn"))
+	err = wTxn.Commit()
+	assert.NoError(t, err)
+
+	var item2 testutil.TestItem
+	err = roTxn.Read("redis2", "item2", &item2)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.NewTestItem("item2-db"), item2)
+
+	err = roTxn.Write("redis1", "item1", testutil.NewTestItem("item1-ro"))
+	assert.Error(t, err)
+	err = roTxn.Commit()
+	assert.NoError(t, err)
+
+	// a transaction started afterwards sees the new values
+	txn := newTxn()
+	txn.Start()
+	err = txn.Read("redis2", "item2", &item2)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.NewTestItem("item2-txn"), item2)
+}
+
+func TestTxnRefresh(t *testing.T) {
+	conn := NewDefaultRedisConnection()
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransaction()
+		rds := NewRedisDatastore("redis1", conn)
+		txn.AddDatastore(rds)
+		txn.SetGlobalDatastore(rds)
+		return txn
+	}
+
+	dbItem := &RedisItem{
+		RKey:          "item1",
+		RValue:        util.ToJSONString(testutil.NewTestItem("item1-db")),
+		RGroupKeyList: "txn0",
+		RTxnState:     config.COMMITTED,
+		RTValid:       time.Now().Add(-10 * time.Second).UnixMicro(),
+		RTLease:       time.Now().Add(-9 * time.Second),
+		RVersion:      "1",
+		RLinkedLen:    1,
+	}
+	_, err := conn.PutItem("item1", dbItem)
+	assert.NoError(t, err)
+
+	roTxn := newTxn()
+	err = roTxn.StartReadOnly()
+	assert.NoError(t, err)
+	startTime := roTxn.SnapshotTime()
+
+	var item1 testutil.TestItem
+	err = roTxn.Read("redis1", "item1", &item1)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.NewTestItem("item1-db"), item1)
+
+	// a writer commits after the read-only transaction has started
+	wTxn := newTxn()
+	wTxn.Start()
+	wTxn.Write("redis1", "item1", testutil.NewTestItem("item1-txn"))
+	err = wTxn.Commit()
+	assert.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	err = roTxn.Read("redis1", "item1", &item1)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.NewTestItem("item1-db"), item1)
+
+	err = roTxn.Refresh()
+	assert.NoError(t, err)
+	assert.Greater(t, roTxn.SnapshotTime(), startTime)
+	err = roTxn.Read("redis1", "item1", &item1)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.NewTestItem("item1-txn"), item1)
+	err = roTxn.Commit()
+	assert.NoError(t, err)
+
+	// a read-write transaction cannot be refreshed
+	txn := newTxn()
+	txn.Start()
+	err = txn.Refresh()
+	assert.EqualError(t, err, "refresh is only supported by a transaction started by StartReadOnly")
+	err = txn.Commit()
+	assert.NoError(t, err)
+}
+
+func TestTxnReadSetValidation(t *testing.T) {
+	config.Config.ReadSetValidation = true
+	defer func() { config.Config.ReadSetValidation = false }()
+
+	conn := NewDefaultRedisConnection()
+	resetItems := func() {
+		for _, key := range []string{"item1", "item2"} {
+			dbItem := &RedisItem{
+				RKey:          key,
+				RValue:        util.ToJSONString(testutil.NewTestItem(key + "-db")),
+				RGroupKeyList: "txn0",
+				RTxnState:     config.COMMITTED,
+				RTValid:       time.Now().Add(-10 * time.Second).UnixMicro(),
+				RTLease:       time.Now().Add(-9 * time.Second),
+				RVersion:      "1",
+				RLinkedLen:    1,
+			}
+			_, err := conn.PutItem(key, dbItem)
+			assert.NoError(t, err)
+		}
+		_ = conn.Delete("item3")
+	}
+	// concurrentWrite commits a write to key in another transaction
+	concurrentWrite := func(key string) {
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		txn.Write("redis", key, testutil.NewTestItem(key+"-concurrent"))
+		err := txn.Commit()
+		assert.NoError(t, err)
+	}
+
+	t.Run("commit with an unchanged read set", func(t *testing.T) {
+		resetItems()
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err := txn.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+		txn.Write("redis", "item2", testutil.NewTestItem("item2-txn"))
+		err = txn.Commit()
+		assert.NoError(t, err)
+	})
+
+	t.Run("abort when a read key is changed", func(t *testing.T) {
+		resetItems()
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err := txn.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+		txn.Write("redis", "item2", testutil.NewTestItem("item2-txn"))
+
+		concurrentWrite("item1")
+
+		err = txn.Commit()
+		assert.ErrorContains(t, err, "read set validation failed: item1 has changed since it was read")
+	})
+
+	t.Run("abort a read-only transaction when a read key is changed", func(t *testing.T) {
+		resetItems()
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err := txn.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+
+		concurrentWrite("item1")
+
+		err = txn.Commit()
+		assert.ErrorContains(t, err, "read set validation failed")
+	})
+
+	t.Run("abort when a key read as absent is created", func(t *testing.T) {
+		resetItems()
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err := txn.Read("redis", "item3", &item)
+		assert.Error(t, err)
+		txn.Write("redis", "item2", testutil.NewTestItem("item2-txn"))
+
+		concurrentWrite("item3")
+
+		err = txn.Commit()
+		assert.ErrorContains(t, err, "read set validation failed: item3")
+	})
+
+	t.Run("a read key that is also written is checked by the write", func(t *testing.T) {
+		resetItems()
+		txn := NewTransactionWithSetup()
+		txn.Start()
+		var item testutil.TestItem
+		err := txn.Read("redis", "item1", &item)
+		assert.NoError(t, err)
+		txn.Write("redis", "item1", testutil.NewTestItem("item1-txn"))
+
+		concurrentWrite("item1")
+
+		err = txn.Commit()
+		assert.Error(t, err)
+		assert.NotContains(t, err.Error(), "read set validation failed")
+	})
+}
+
+func TestBulkLoad(t *testing.T) {
+	conn := NewDefaultRedisConnection()
+	keys := []string{"bulk-item1", "bulk-item2", "bulk-item3"}
+	values := []any{
+		testutil.NewTestItem("bulk-item1"),
+		testutil.NewTestItem("bulk-item2"),
+		testutil.NewTestItem("bulk-item3"),
+	}
+	err := trxn.BulkLoad(conn, &RedisItemFactory{}, keys, values)
+	assert.NoError(t, err)
+
+	txn := NewTransactionWithSetup()
+	txn.Start()
+	for i, key := range keys {
+		var item testutil.TestItem
+		err := txn.Read("redis", key, &item)
+		assert.NoError(t, err)
+		assert.Equal(t, values[i], item)
+	}
+	// the loaded records can be updated by a transaction
+	txn.Write("redis", "bulk-item1", testutil.NewTestItem("bulk-item1-txn"))
+	err = txn.Commit()
+	assert.NoError(t, err)
+	// wait for the asynchronous commit phase
+	time.Sleep(100 * time.Millisecond)
+
+	txn = NewTransactionWithSetup()
+	txn.Start()
+	var item testutil.TestItem
+	err = txn.Read("redis", "bulk-item1", &item)
+	assert.NoError(t, err)
+	assert.Equal(t, testutil.NewTestItem("bulk-item1-txn"), item)
+	err = txn.Commit()
+	assert.NoError(t, err)
+}
+
+// rendezvousConnection holds the first conditional update of two concurrent
+// prepares until both have arrived, and records the keys they update.
+type rendezvousConnection struct {
+	trxn.Connector
+	mu        sync.Mutex
+	firstKeys []string
+	arrived   chan struct{}
+}
+
+func newRendezvousConnection(conn trxn.Connector) *rendezvousConnection {
+	return &rendezvousConnection{Connector: conn, arrived: make(chan struct{})}
+}
+
+func (c *rendezvousConnection) ConditionalUpdate(key string, value trxn.DataItem, doCreate bool) (string, error) {
+	c.mu.Lock()
+	first := len(c.firstKeys) < 2
+	if first {
+		c.firstKeys = append(c.firstKeys, key)
+		if len(c.firstKeys) == 2 {
+			close(c.arrived)
+		}
+	}
+	c.mu.Unlock()
+	if first {
+		select {
+		case <-c.arrived:
+		case <-time.After(time.Second):
+		}
+	}
+	return c.Connector.ConditionalUpdate(key, value, doCreate)
+}
+
+// TestPrepareInCanonicalOrder tests that two transactions writing the same
+// keys in opposite orders prepare them in the same order, so that only one
+// of them aborts instead of both preparing some keys and aborting each other.
+func TestPrepareInCanonicalOrder(t *testing.T) {
+	conn := NewDefaultRedisConnection()
+	keys := []string{"order1", "order2", "order3"}
+	const rounds = 5
+
+	// rmw reads and then writes keys in the given order
+	rmw := func(c trxn.Connector, keys []string) *trxn.Transaction {
+		txn := trxn.NewTransaction()
+		txn.AddDatastore(NewRedisDatastore("redis", c))
+		txn.Start()
+		for _, key := range keys {
+			var item testutil.TestItem
+			err := txn.Read("redis", key, &item)
+			assert.NoError(t, err)
+			txn.Write("redis", key, testutil.NewTestItem(key+"-"+txn.TxnId))
+		}
+		return txn
+	}
+
+	retries := 0
+	for round := 0; round < rounds; round++ {
+		for _, key := range keys {
+			dbItem := &RedisItem{
+				RKey:          key,
+				RValue:        util.ToJSONString(testutil.NewTestItem(key + "-db")),
+				RGroupKeyList: "txn0",
+				RTxnState:     config.COMMITTED,
+				RTValid:       time.Now().Add(-10 * time.Second).UnixMicro(),
+				RTLease:       time.Now().Add(-9 * time.Second),
+				RVersion:      "1",
+				RLinkedLen:    1,
+			}
+			_, err := conn.PutItem(key, dbItem)
+			assert.NoError(t, err)
+		}
+
+		rc := newRendezvousConnection(conn)
+		reversed := []string{keys[2], keys[1], keys[0]}
+		txns := []*trxn.Transaction{rmw(rc, keys), rmw(rc, reversed)}
+		errs := make([]error, len(txns))
+		var wg sync.WaitGroup
+		for i, txn := range txns {
+			wg.Add(1)
+			go func(i int, txn *trxn.Transaction) {
+				defer wg.Done()
+				errs[i] = txn.Commit()
+			}(i, txn)
+		}
+		wg.Wait()
+		assert.Equal(t, []string{keys[0], keys[0]}, rc.firstKeys)
+
+		// the commit phase of the winner runs in the background
+		time.Sleep(100 * time.Millisecond)
+		for _, err := range errs {
+			if err == nil {
+				continue
+			}
+			retries++
+			retry := rmw(conn, keys)
+			assert.NoError(t, retry.Commit())
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	// a mutual abort would take two retries in a round
+	assert.Equal(t, rounds, retries)
+}
@@ -18,7 +18,7 @@ type RedisItem struct {
 	RGroupKeyList string       `redis:"GroupKeyList" json:"GroupKeyList"`
 	RTxnState     config.State `redis:"TxnState" json:"TxnState"`
 	RTValid       int64        `redis:"TValid" json:"TValid"`
-	RTLease       time.Time    `redis:"TLease" json:"TLease"`
+	RTLease       time.Time    `redis:"-" json:"TLease"`
 	RPrev         string       `redis:"Prev" json:"Prev"`
 	RLinkedLen    int          `redis:"LinkedLen" json:"LinkedLen"`
 	RIsDeleted    bool         `redis:"IsDeleted" json:"IsDeleted"`
@@ -157,3 +157,34 @@ func (r *RedisItem) Equal(other txn.DataItem) bool {
 func (r RedisItem) MarshalBinary() (data []byte, err error) {
 	return json.Marshal(r)
 }
+
+// redisItemFields has the fields of RedisItem without its methods,
+// so that they are encoded as usual by MarshalJSON and UnmarshalJSON.
+type redisItemFields RedisItem
+
+// MarshalJSON encodes TLease as a number by txn.EncodeLease,
+// so that the lease is not truncated on the way.
+func (r RedisItem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		redisItemFields
+		RTLease int64 `json:"TLease"`
+	}{redisItemFields(r), txn.EncodeLease(r.RTLease)})
+}
+
+// UnmarshalJSON decodes an item encoded by MarshalJSON
+// or by the older versions, which encoded TLease in RFC 3339.
+func (r *RedisItem) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*redisItemFields
+		RTLease json.RawMessage `json:"TLease"`
+	}{redisItemFields: (*redisItemFields)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	lease, err := txn.UnmarshalLease(aux.RTLease)
+	if err != nil {
+		return err
+	}
+	r.RTLease = lease
+	return nil
+}
@@ -4,6 +4,10 @@ import "github.com/oreo-dtx-lab/oreo/pkg/txn"
 
 var _ txn.DataItemFactory = (*RedisItemFactory)(nil)
 
+func init() {
+	txn.RegisterItemFactory(txn.RedisItem, &RedisItemFactory{})
+}
+
 type RedisItemFactory struct{}
 
 func (r *RedisItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
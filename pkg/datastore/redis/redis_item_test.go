@@ -0,0 +1,161 @@
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisItemLeaseJSON(t *testing.T) {
+	lease := time.Now().Add(3*time.Second + 789*time.Nanosecond)
+	item := &RedisItem{RKey: "item1", RTxnState: config.PREPARED, RTLease: lease, RVersion: "1"}
+
+	data, err := json.Marshal(item)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"TLease":`+strconv.FormatInt(lease.UnixNano(), 10))
+
+	var got RedisItem
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, txn.EncodeLease(lease), txn.EncodeLease(got.TLease()))
+	assert.Equal(t, item.Key(), got.Key())
+	assert.Equal(t, item.TxnState(), got.TxnState())
+	assert.Equal(t, item.Version(), got.Version())
+
+	// an item encoded by an older version
+	legacy := `{"Key":"item1","TLease":"` + lease.Format(time.RFC3339Nano) + `"}`
+	assert.NoError(t, json.Unmarshal([]byte(legacy), &got))
+	assert.True(t, lease.Equal(got.TLease()))
+
+	// an item without a lease
+	assert.NoError(t, json.Unmarshal([]byte(`{"Key":"item1","TLease":0}`), &got))
+	assert.True(t, got.TLease().IsZero())
+}
+
+func TestRedisConnection_GetItemLease(t *testing.T) {
+	lease := time.Now().Add(3*time.Second + 789*time.Nanosecond)
+	for name, stored := range map[string]string{
+		"nanos":   strconv.FormatInt(txn.EncodeLease(lease), 10),
+		"RFC3339": lease.Format(time.RFC3339Nano),
+	} {
+		t.Run(name, func(t *testing.T) {
+			rdb, mock := redismock.NewClientMock()
+			connection := &RedisConnection{rdb: rdb}
+			mock.ExpectHGetAll("item1").SetVal(map[string]string{
+				"Key":      "item1",
+				"TxnState": strconv.Itoa(int(config.PREPARED)),
+				"TLease":   stored,
+				"Version":  "1",
+			})
+
+			item, err := connection.GetItem("item1")
+			assert.NoError(t, err)
+			assert.Equal(t, txn.EncodeLease(lease), txn.EncodeLease(item.TLease()))
+			assert.Equal(t, config.PREPARED, item.TxnState())
+		})
+	}
+}
+
+func TestRedisConnection_ReaderAddress(t *testing.T) {
+	rdb, primary := redismock.NewClientMock()
+	reader, replica := redismock.NewClientMock()
+	connection := &RedisConnection{rdb: rdb, reader: reader}
+
+	replica.ExpectHGetAll("item1").SetVal(map[string]string{"Key": "item1", "Version": "1"})
+	replica.ExpectGet("tsr1").SetVal("1")
+	primary.ExpectHGetAll("item1").SetVal(map[string]string{"Key": "item1", "Version": "2"})
+
+	item, err := connection.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", item.Version())
+	value, err := connection.Get("tsr1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", value)
+
+	item, err = connection.GetItemFromPrimary("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", item.Version())
+
+	assert.NoError(t, primary.ExpectationsWereMet())
+	assert.NoError(t, replica.ExpectationsWereMet())
+}
+
+func TestRedisConnection_FlushNamespace(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	connection := &RedisConnection{rdb: rdb, ns: "bench"}
+
+	assert.Error(t, connection.FlushNamespace(""))
+
+	// SCAN is only a hint, a key outside the prefix must be left alone
+	mock.ExpectScan(0, "bench:user\\**", keyScanCount).
+		SetVal([]string{"bench:user*1", "bench:user*2"}, 7)
+	mock.ExpectUnlink("bench:user*1").SetVal(1)
+	mock.ExpectUnlink("bench:user*2").SetVal(1)
+	mock.ExpectScan(7, "bench:user\\**", keyScanCount).
+		SetVal([]string{"bench:user*3", "bench:order1"}, 0)
+	mock.ExpectUnlink("bench:user*3").SetVal(1)
+	assert.NoError(t, connection.FlushNamespace("user*"))
+
+	mock.ExpectScan(0, "bench:order*", keyScanCount).
+		SetVal([]string{"bench:order1", "other:order2"}, 0)
+	keys, err := connection.Keys("order*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"order1"}, keys)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisConnection_CheckLinkedLen(t *testing.T) {
+	checkLinkedLen := config.Debug.CheckLinkedLen
+	defer func() { config.Debug.CheckLinkedLen = checkLinkedLen }()
+	config.Debug.CheckLinkedLen = true
+
+	v1 := &RedisItem{RKey: "item1", RValue: "v1", RVersion: "1", RLinkedLen: 1}
+	bs, err := config.Config.Serializer.Serialize(v1)
+	assert.NoError(t, err)
+	v2 := &RedisItem{RKey: "item1", RValue: "v2", RVersion: "2", RPrev: string(bs), RLinkedLen: 2}
+	assert.NoError(t, txn.CheckLinkedLen(v2, &RedisItemFactory{}))
+
+	rdb, mock := redismock.NewClientMock()
+	connection := &RedisConnection{rdb: rdb}
+
+	// the item claims one more version than it chains
+	mock.ExpectHGetAll("item1").SetVal(map[string]string{
+		"Key": "item1", "Value": "v2", "Version": "2", "Prev": string(bs), "LinkedLen": "3",
+	})
+	_, err = connection.GetItem("item1")
+	assert.ErrorIs(t, err, txn.ErrLinkedLenMismatch)
+
+	v2.RLinkedLen = 1
+	_, err = connection.ConditionalUpdate("item1", v2, false)
+	assert.ErrorIs(t, err, txn.ErrLinkedLenMismatch)
+
+	// the check is off by default
+	config.Debug.CheckLinkedLen = false
+	mock.ExpectHGetAll("item1").SetVal(map[string]string{
+		"Key": "item1", "Value": "v2", "Version": "2", "Prev": string(bs), "LinkedLen": "3",
+	})
+	item, err := connection.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, item.LinkedLen())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisConnection_Ping(t *testing.T) {
+	rdb, mock := redismock.NewClientMock()
+	connection := &RedisConnection{rdb: rdb}
+	assert.Error(t, connection.Ping())
+
+	connection.connected = true
+	mock.ExpectPing().SetVal("PONG")
+	assert.NoError(t, connection.Ping())
+	mock.ExpectPing().SetErr(errors.New("connection refused"))
+	assert.EqualError(t, connection.Ping(), "connection refused")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
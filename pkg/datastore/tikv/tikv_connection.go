@@ -15,6 +15,7 @@ import (
 )
 
 var _ txn.Connector = (*TiKVConnection)(nil)
+var _ txn.Pinger = (*TiKVConnection)(nil)
 
 type TiKVConnection struct {
 	client       *rawkv.Client
@@ -24,6 +25,8 @@ type TiKVConnection struct {
 
 type ConnectionOptions struct {
 	PDAddrs []string
+	// Namespace prefixes every key stored through the connection.
+	Namespace txn.Namespace
 }
 
 func NewTiKVConnection(config *ConnectionOptions) *TiKVConnection {
@@ -68,6 +71,34 @@ func (c *TiKVConnection) Connect() error {
 	return nil
 }
 
+// rawKey returns the TiKV key under which key is stored.
+func (c *TiKVConnection) rawKey(key string) []byte {
+	return []byte(c.config.Namespace.Key(key))
+}
+
+// pingTimeout bounds the wait of Ping.
+const pingTimeout = 2 * time.Second
+
+// Ping checks that a key can be read within pingTimeout.
+func (c *TiKVConnection) Ping() error {
+	if !c.hasConnected {
+		return errors.New("not connected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	_, err := c.client.Get(ctx, c.rawKey("ping"))
+	return err
+}
+
+// Close closes the TiKV raw client.
+func (c *TiKVConnection) Close() error {
+	if !c.hasConnected {
+		return nil
+	}
+	c.hasConnected = false
+	return c.client.Close()
+}
+
 func (c *TiKVConnection) GetItem(key string) (txn.DataItem, error) {
 	if !c.hasConnected {
 		return &TiKVItem{}, fmt.Errorf("not connected to TiKV")
@@ -76,7 +107,7 @@ func (c *TiKVConnection) GetItem(key string) (txn.DataItem, error) {
 		time.Sleep(oreoconfig.Debug.ConnAdditionalLatency)
 	}
 
-	value, err := c.client.Get(context.Background(), []byte(key))
+	value, err := c.client.Get(context.Background(), c.rawKey(key))
 	if err != nil {
 		return &TiKVItem{}, err
 	}
@@ -105,7 +136,7 @@ func (c *TiKVConnection) PutItem(key string, value txn.DataItem) (string, error)
 		return "", errors.New("failed to marshal item")
 	}
 
-	err = c.client.Put(context.Background(), []byte(key), data)
+	err = c.client.Put(context.Background(), c.rawKey(key), data)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("PutItem key %s failed, err: %v", key, err))
 	}
@@ -134,19 +165,19 @@ func (c *TiKVConnection) ConditionalUpdate(key string, value txn.DataItem, doCre
 		}
 
 		// 使用 CompareAndSwap 确保键不存在时才创建
-		_, ok, err := c.client.CompareAndSwap(ctx, []byte(key), nil, newData)
+		_, ok, err := c.client.CompareAndSwap(ctx, c.rawKey(key), nil, newData)
 		if err != nil {
 			return "", errors.New(fmt.Sprintf("ConditionalUpdate(doCreate) key %s failed, err: %v", key, err))
 		}
 		if ok {
 			return newVer, nil
 		} else {
-			return "", errors.New("key exists")
+			return "", errors.New(txn.KeyExists)
 		}
 	}
 
 	// 使用 CompareAndSwap 确保原子更新
-	_, ok, err := c.client.CompareAndSwap(ctx, []byte(key), []byte(value.Prev()), newData)
+	_, ok, err := c.client.CompareAndSwap(ctx, c.rawKey(key), []byte(value.Prev()), newData)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("ConditionalUpdate key %s failed, err: %v", key, err))
 	}
@@ -169,7 +200,7 @@ func (c *TiKVConnection) ConditionalCommit(key string, version string, tCommit i
 	ctx := context.Background()
 
 	// 获取当前值
-	currentValue, err := c.client.Get(ctx, []byte(key))
+	currentValue, err := c.client.Get(ctx, c.rawKey(key))
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("failed to get current value: %v", err))
 	}
@@ -198,7 +229,7 @@ func (c *TiKVConnection) ConditionalCommit(key string, version string, tCommit i
 	}
 
 	// 原子更新
-	_, ok, err := c.client.CompareAndSwap(ctx, []byte(key), currentValue, newData)
+	_, ok, err := c.client.CompareAndSwap(ctx, c.rawKey(key), currentValue, newData)
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("ConditionalCommit key %s failed, err: %v", key, err))
 	}
@@ -221,7 +252,7 @@ func (c *TiKVConnection) AtomicCreate(name string, value any) (string, error) {
 	strValue := util.ToString(value)
 
 	// 使用 CompareAndSwap 确保原子创建
-	_, ok, err := c.client.CompareAndSwap(ctx, []byte(name), nil, []byte(strValue))
+	_, ok, err := c.client.CompareAndSwap(ctx, c.rawKey(name), nil, []byte(strValue))
 	if err != nil {
 		return "", err
 	}
@@ -240,7 +271,7 @@ func (c *TiKVConnection) Get(name string) (string, error) {
 		time.Sleep(oreoconfig.Debug.ConnAdditionalLatency)
 	}
 
-	value, err := c.client.Get(context.Background(), []byte(name))
+	value, err := c.client.Get(context.Background(), c.rawKey(name))
 	if err != nil {
 		return "", errors.New(fmt.Sprintf("get key %s failed, err: %v", name, err))
 	}
@@ -259,7 +290,7 @@ func (c *TiKVConnection) Put(name string, value interface{}) error {
 	}
 
 	strValue := util.ToString(value)
-	err := c.client.Put(context.Background(), []byte(name), []byte(strValue))
+	err := c.client.Put(context.Background(), c.rawKey(name), []byte(strValue))
 	if err != nil {
 		return errors.New(fmt.Sprintf("put key %s failed, err: %v", name, err))
 	}
@@ -274,7 +305,7 @@ func (c *TiKVConnection) Delete(name string) error {
 		time.Sleep(oreoconfig.Debug.ConnAdditionalLatency)
 	}
 
-	err := c.client.Delete(context.Background(), []byte(name))
+	err := c.client.Delete(context.Background(), c.rawKey(name))
 	if err != nil {
 		return errors.New(fmt.Sprintf("delete key %s failed, err: %v", name, err))
 	}
@@ -0,0 +1,23 @@
+package tikv
+
+import (
+	"testing"
+
+	"github.com/oreo-dtx-lab/oreo/internal/conntest"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTiKVConnection_Namespace tests that two connections with different
+// namespaces on the same database do not see each other's keys.
+func TestTiKVConnection_Namespace(t *testing.T) {
+	newConn := func(ns txn.Namespace) txn.Connector {
+		conn := NewTiKVConnection(&ConnectionOptions{
+			PDAddrs:   []string{"127.0.0.1:2379"},
+			Namespace: ns,
+		})
+		assert.NoError(t, conn.Connect())
+		return conn
+	}
+	conntest.Namespace(t, newConn, &TiKVItemFactory{})
+}
@@ -1,6 +1,7 @@
 package tikv
 
 import (
+    "encoding/json"
     "fmt"
     "time"
 
@@ -155,4 +156,32 @@ func (k *TiKVItem) String() string {
 }`, k.KKey, k.KValue, k.KGroupKeyList, util.ToString(k.KTxnState),
         k.KTValid, k.KTLease.Format(time.RFC3339),
         k.KPrev, k.KLinkedLen, k.KIsDeleted, k.KVersion)
-}
\ No newline at end of file
+}
+
+// tiKVItemFields has the fields of TiKVItem without its methods.
+type tiKVItemFields TiKVItem
+
+// MarshalJSON encodes TLease as a number by txn.EncodeLease.
+func (k TiKVItem) MarshalJSON() ([]byte, error) {
+    return json.Marshal(struct {
+        tiKVItemFields
+        KTLease int64 `json:"TLease"`
+    }{tiKVItemFields(k), txn.EncodeLease(k.KTLease)})
+}
+
+// UnmarshalJSON accepts TLease encoded as a number or in RFC 3339.
+func (k *TiKVItem) UnmarshalJSON(data []byte) error {
+    aux := struct {
+        *tiKVItemFields
+        KTLease json.RawMessage `json:"TLease"`
+    }{tiKVItemFields: (*tiKVItemFields)(k)}
+    if err := json.Unmarshal(data, &aux); err != nil {
+        return err
+    }
+    lease, err := txn.UnmarshalLease(aux.KTLease)
+    if err != nil {
+        return err
+    }
+    k.KTLease = lease
+    return nil
+}
@@ -4,6 +4,10 @@ import "github.com/oreo-dtx-lab/oreo/pkg/txn"
 
 var _ txn.DataItemFactory = (*TiKVItemFactory)(nil)
 
+func init() {
+	txn.RegisterItemFactory(txn.TiKVItem, &TiKVItemFactory{})
+}
+
 type TiKVItemFactory struct{}
 
 func (t *TiKVItemFactory) NewDataItem(options txn.ItemOptions) txn.DataItem {
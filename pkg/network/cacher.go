@@ -4,18 +4,48 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/oreo-dtx-lab/oreo/pkg/txn"
 )
 
-type Cacher struct {
+// Cacher caches the states of the transactions read by a Reader,
+// keyed by the group key of their TSR. A group key is <dsName>:<TxnId>,
+// so that the TSRs of a transaction in different datastores, which may
+// hold different states, are cached apart. The state of a TSR does not
+// depend on the time it is read at, so the key has no timestamp.
+type Cacher interface {
+	Get(key string) (txn.GroupKeyItem, bool)
+	Set(key string, item txn.GroupKeyItem)
+	SampleRepair() bool
+	Repair(key string, item txn.GroupKeyItem)
+	Delete(key string)
+	Statistic() string
+	Clear()
+}
+
+var (
+	_ Cacher = (*MapCacher)(nil)
+	_ Cacher = NoopCacher{}
+)
+
+// MapCacher is the Cacher keeping every state in memory.
+type MapCacher struct {
 	mu           sync.RWMutex
 	cache        map[string]txn.GroupKeyItem
 	CacheRequest int
 	CacheHit     int
+
+	// RepairCheck is the number of cache hits checked against the
+	// datastore, and Repaired the number of those found stale.
+	RepairCheck int
+	Repaired    int
+	// repairCredit accumulates config.Config.ReadRepairRate on each hit,
+	// so that exactly that fraction of the hits is checked.
+	repairCredit float64
 }
 
-func NewCacher() *Cacher {
-	return &Cacher{
+func NewCacher() *MapCacher {
+	return &MapCacher{
 		mu:           sync.RWMutex{},
 		cache:        make(map[string]txn.GroupKeyItem),
 		CacheRequest: 0,
@@ -23,7 +53,7 @@ func NewCacher() *Cacher {
 	}
 }
 
-func (c *Cacher) Get(key string) (txn.GroupKeyItem, bool) {
+func (c *MapCacher) Get(key string) (txn.GroupKeyItem, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	item, ok := c.cache[key]
@@ -34,24 +64,74 @@ func (c *Cacher) Get(key string) (txn.GroupKeyItem, bool) {
 	return item, ok
 }
 
-func (c *Cacher) Set(key string, item txn.GroupKeyItem) {
+func (c *MapCacher) Set(key string, item txn.GroupKeyItem) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.cache[key] = item
 }
 
-func (c *Cacher) Delete(key string) {
+// SampleRepair reports whether the current cache hit
+// should be checked against the datastore for read repair.
+func (c *MapCacher) SampleRepair() bool {
+	rate := config.Config.ReadRepairRate
+	if rate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.repairCredit += rate
+	if c.repairCredit < 1 {
+		return false
+	}
+	c.repairCredit--
+	c.RepairCheck++
+	return true
+}
+
+// Repair replaces the stale cached item of key with item.
+func (c *MapCacher) Repair(key string, item txn.GroupKeyItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache[key] = item
+	c.Repaired++
+}
+
+func (c *MapCacher) Delete(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.cache, key)
 }
 
-func (c *Cacher) Statistic() string {
+func (c *MapCacher) Statistic() string {
 	return fmt.Sprintf("CacheRequest: %d, CacheHit: %d, HitRate: %.2f", c.CacheRequest, c.CacheHit, float64(c.CacheHit)/float64(c.CacheRequest))
 }
 
-func (c *Cacher) Clear() {
+func (c *MapCacher) Clear() {
 	c.cache = make(map[string]txn.GroupKeyItem)
 	c.CacheRequest = 0
 	c.CacheHit = 0
+	c.RepairCheck = 0
+	c.Repaired = 0
+	c.repairCredit = 0
 }
+
+// NoopCacherStatistic is the statistic reported by a NoopCacher.
+const NoopCacherStatistic = "Cache disabled"
+
+// NoopCacher is the Cacher caching nothing,
+// so that every read of a TSR goes to the datastore.
+type NoopCacher struct{}
+
+func (NoopCacher) Get(string) (txn.GroupKeyItem, bool) { return txn.GroupKeyItem{}, false }
+
+func (NoopCacher) Set(string, txn.GroupKeyItem) {}
+
+func (NoopCacher) SampleRepair() bool { return false }
+
+func (NoopCacher) Repair(string, txn.GroupKeyItem) {}
+
+func (NoopCacher) Delete(string) {}
+
+func (NoopCacher) Statistic() string { return NoopCacherStatistic }
+
+func (NoopCacher) Clear() {}
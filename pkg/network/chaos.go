@@ -0,0 +1,144 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// The distributions of the latency injected by Chaos.
+const (
+	NoLatency          = ""
+	UniformLatency     = "uniform"
+	NormalLatency      = "normal"
+	ExponentialLatency = "exponential"
+)
+
+// LatencyDistribution is the distribution the injected latency is drawn from.
+type LatencyDistribution struct {
+	// Kind is one of NoLatency, UniformLatency, NormalLatency
+	// and ExponentialLatency.
+	Kind string `yaml:"kind"`
+	// Min and Max bound the latency. A uniform latency is drawn between them,
+	// the others are clamped to them, Max being ignored if zero.
+	Min time.Duration `yaml:"min"`
+	Max time.Duration `yaml:"max"`
+	// Mean is the mean of a normal or an exponential latency.
+	Mean time.Duration `yaml:"mean"`
+	// StdDev is the standard deviation of a normal latency.
+	StdDev time.Duration `yaml:"std_dev"`
+}
+
+// Sample draws a latency from the distribution.
+func (d LatencyDistribution) Sample(r *rand.Rand) time.Duration {
+	var latency time.Duration
+	switch d.Kind {
+	case UniformLatency:
+		if d.Max <= d.Min {
+			return d.Min
+		}
+		return d.Min + time.Duration(r.Int63n(int64(d.Max-d.Min)+1))
+	case NormalLatency:
+		latency = d.Mean + time.Duration(r.NormFloat64()*float64(d.StdDev))
+	case ExponentialLatency:
+		latency = time.Duration(r.ExpFloat64() * float64(d.Mean))
+	default:
+		return 0
+	}
+	if latency < d.Min {
+		latency = d.Min
+	}
+	if d.Max > 0 && latency > d.Max {
+		latency = d.Max
+	}
+	return latency
+}
+
+// ChaosConfig describes the faults Chaos injects in the requests.
+type ChaosConfig struct {
+	// Enabled gates every fault, none is injected unless it is set.
+	Enabled bool `yaml:"enabled"`
+	// Paths are the request paths the faults are injected in,
+	// every path if empty.
+	Paths []string `yaml:"paths"`
+	// Latency is added to every request before it is handled.
+	Latency LatencyDistribution `yaml:"latency"`
+	// ErrorRate is the fraction of the requests answered with ErrorStatus
+	// instead of being handled.
+	ErrorRate float64 `yaml:"error_rate"`
+	// ErrorStatus is 500 or 503, 503 if unset.
+	ErrorStatus int `yaml:"error_status"`
+	// Seed seeds the random faults, which differ at every run if unset.
+	Seed int64 `yaml:"seed"`
+}
+
+// Validate checks that the faults described by c can be injected.
+func (c ChaosConfig) Validate() error {
+	switch c.Latency.Kind {
+	case NoLatency, UniformLatency, NormalLatency, ExponentialLatency:
+	default:
+		return fmt.Errorf("unknown latency distribution %q", c.Latency.Kind)
+	}
+	if c.Latency.Min < 0 || c.Latency.Max < 0 || c.Latency.Mean < 0 || c.Latency.StdDev < 0 {
+		return fmt.Errorf("the latency distribution has a negative parameter")
+	}
+	if math.IsNaN(c.ErrorRate) || c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return fmt.Errorf("error rate %v is out of [0, 1]", c.ErrorRate)
+	}
+	switch c.ErrorStatus {
+	case 0, fasthttp.StatusInternalServerError, fasthttp.StatusServiceUnavailable:
+	default:
+		return fmt.Errorf("error status %d is neither 500 nor 503", c.ErrorStatus)
+	}
+	return nil
+}
+
+// Chaos wraps handler so that the requests to the paths of cfg are delayed
+// by a latency drawn from cfg.Latency, then fail with cfg.ErrorStatus at
+// cfg.ErrorRate. It returns handler as is unless cfg.Enabled is set.
+func Chaos(handler fasthttp.RequestHandler, cfg ChaosConfig) fasthttp.RequestHandler {
+	if !cfg.Enabled {
+		return handler
+	}
+	status := cfg.ErrorStatus
+	if status == 0 {
+		status = fasthttp.StatusServiceUnavailable
+	}
+	var paths map[string]bool
+	if len(cfg.Paths) > 0 {
+		paths = make(map[string]bool, len(cfg.Paths))
+		for _, path := range cfg.Paths {
+			paths[path] = true
+		}
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	var mu sync.Mutex
+	r := rand.New(rand.NewSource(seed))
+
+	return func(ctx *fasthttp.RequestCtx) {
+		if paths != nil && !paths[string(ctx.Path())] {
+			handler(ctx)
+			return
+		}
+		mu.Lock()
+		latency := cfg.Latency.Sample(r)
+		fail := r.Float64() < cfg.ErrorRate
+		mu.Unlock()
+
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		if fail {
+			ctx.Error("injected fault", status)
+			return
+		}
+		handler(ctx)
+	}
+}
@@ -0,0 +1,95 @@
+package network
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func serveChaos(handler fasthttp.RequestHandler, path string) int {
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetRequestURI(path)
+	handler(&ctx)
+	return ctx.Response.StatusCode()
+}
+
+func TestChaosErrorRate(t *testing.T) {
+	ok := func(ctx *fasthttp.RequestCtx) { ctx.SetStatusCode(fasthttp.StatusOK) }
+	const (
+		n    = 20000
+		rate = 0.2
+	)
+	handler := Chaos(ok, ChaosConfig{
+		Enabled:     true,
+		Paths:       []string{"/read"},
+		ErrorRate:   rate,
+		ErrorStatus: fasthttp.StatusInternalServerError,
+		Seed:        42,
+	})
+
+	failed := 0
+	for i := 0; i < n; i++ {
+		switch code := serveChaos(handler, "/read"); code {
+		case fasthttp.StatusOK:
+		case fasthttp.StatusInternalServerError:
+			failed++
+		default:
+			t.Fatalf("unexpected status %d", code)
+		}
+	}
+	// within 5 standard deviations of the expected number of failures
+	stdDev := math.Sqrt(n * rate * (1 - rate))
+	assert.InDelta(t, n*rate, float64(failed), 5*stdDev)
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, fasthttp.StatusOK, serveChaos(handler, "/commit"))
+	}
+}
+
+func TestChaosDisabled(t *testing.T) {
+	ok := func(ctx *fasthttp.RequestCtx) { ctx.SetStatusCode(fasthttp.StatusOK) }
+	handler := Chaos(ok, ChaosConfig{ErrorRate: 1})
+	assert.Equal(t, fasthttp.StatusOK, serveChaos(handler, "/read"))
+
+	handler = Chaos(ok, ChaosConfig{Enabled: true, ErrorRate: 1})
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, serveChaos(handler, "/read"))
+}
+
+func TestChaosLatency(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	dists := []LatencyDistribution{
+		{Kind: UniformLatency, Min: time.Millisecond, Max: 5 * time.Millisecond},
+		{Kind: NormalLatency, Min: time.Millisecond, Max: 5 * time.Millisecond,
+			Mean: 3 * time.Millisecond, StdDev: 2 * time.Millisecond},
+		{Kind: ExponentialLatency, Min: time.Millisecond, Max: 5 * time.Millisecond,
+			Mean: 2 * time.Millisecond},
+	}
+	for _, d := range dists {
+		for i := 0; i < 1000; i++ {
+			latency := d.Sample(r)
+			assert.GreaterOrEqual(t, latency, d.Min, d.Kind)
+			assert.LessOrEqual(t, latency, d.Max, d.Kind)
+		}
+	}
+	assert.Equal(t, time.Duration(0), LatencyDistribution{}.Sample(r))
+
+	ok := func(ctx *fasthttp.RequestCtx) { ctx.SetStatusCode(fasthttp.StatusOK) }
+	handler := Chaos(ok, ChaosConfig{
+		Enabled: true,
+		Latency: LatencyDistribution{Kind: UniformLatency, Min: 20 * time.Millisecond, Max: 20 * time.Millisecond},
+	})
+	start := time.Now()
+	assert.Equal(t, fasthttp.StatusOK, serveChaos(handler, "/read"))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestChaosConfigValidate(t *testing.T) {
+	assert.NoError(t, ChaosConfig{}.Validate())
+	assert.Error(t, ChaosConfig{ErrorRate: 1.5}.Validate())
+	assert.Error(t, ChaosConfig{ErrorStatus: fasthttp.StatusNotFound}.Validate())
+	assert.Error(t, ChaosConfig{Latency: LatencyDistribution{Kind: "pareto"}}.Validate())
+}
@@ -1,76 +1,313 @@
 package network
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/oreo-dtx-lab/oreo/pkg/backoff"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/oreo-dtx-lab/oreo/pkg/logger"
+	"github.com/oreo-dtx-lab/oreo/pkg/tracing"
 	"github.com/oreo-dtx-lab/oreo/pkg/txn"
 	"github.com/valyala/fasthttp"
 )
 
 var _ txn.RemoteClient = (*Client)(nil)
+var _ txn.FieldReadClient = (*Client)(nil)
+var _ txn.ContextClient = (*Client)(nil)
+var _ txn.PrepareBatchClient = (*Client)(nil)
 
 type Client struct {
+	// ExecutorAddrMap is the executor address map the client is created with.
+	// Executors returns the current one of a client bootstrapped from a seed.
 	ExecutorAddrMap map[string][]string
-	mutex           sync.Mutex
-	curIndexMap     map[string]int
+	topology        *topology
+	stopDiscovery   func()
+	// prober probes the executors marked down after a failed request
+	prober *prober
+
+	maxRequestBodySize int
+	maxRetries         int
+	newBackoff         func() backoff.Backoff
+	httpClient         *fasthttp.Client
+	// disableKeepAlive closes the connection after every request
+	disableKeepAlive bool
+
+	// metrics counts the requests sent to each executor
+	metrics *requestMetrics
+
+	// ctx carries the trace context injected into every request
+	ctx context.Context
 }
 
 const ALL = "ALL"
 
+// DefaultMaxBodySize is the default limit on the size of the request
+// and response bodies exchanged between the client and the executor.
+const DefaultMaxBodySize = 16 * 1024 * 1024
+
+type ClientOptions struct {
+	// MaxRequestBodySize limits the size of a request body sent to the executor.
+	// Requests exceeding it fail before being sent.
+	MaxRequestBodySize int
+
+	// MaxResponseBodySize limits the size of a response body read from the executor.
+	MaxResponseBodySize int
+
+	// LoadBalancer creates the load balancer used to select among
+	// the executors of each datastore. Defaults to NewRoundRobinBalancer.
+	LoadBalancer LoadBalancerFactory
+
+	// MaxRetries is the number of times a request rejected by an overloaded
+	// executor (503) is retried. Defaults to DefaultMaxRetries,
+	// a negative value disables retrying.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry.
+	// It doubles on every retry, up to the Retry-After sent by the executor.
+	RetryBackoff time.Duration
+
+	// NewBackoff creates the backoff between the retries of a request,
+	// whose waits are still capped by the Retry-After sent by the executor.
+	// Defaults to an exponential backoff starting at RetryBackoff.
+	NewBackoff func() backoff.Backoff
+
+	// SeedAddr is the address of an executor whose /peers endpoint lists
+	// the executors of each datastore. If set, the client fetches that list
+	// on creation and refreshes it every PeerRefreshInterval, so executors
+	// can be added or removed without reconfiguring the client.
+	// The executor address map passed to the client is only used if the
+	// seed cannot be reached. Defaults to the static executor address map.
+	SeedAddr string
+
+	// PeerRefreshInterval is how often the executor addresses are fetched
+	// from SeedAddr. Defaults to DefaultPeerRefreshInterval.
+	PeerRefreshInterval time.Duration
+
+	// MaxIdleConnDuration is how long a keep-alive connection to an executor
+	// may stay idle before it is closed. Defaults to DefaultMaxIdleConnDuration,
+	// which is below the idle timeout of the usual proxies and load balancers,
+	// so that the client does not send a request on a connection they have reset.
+	MaxIdleConnDuration time.Duration
+
+	// MaxConnDuration is how long a connection to an executor is kept alive
+	// at most, after which it is closed once its current request is done and
+	// the next request dials again. It spreads the connections over the
+	// executors added since they were opened. Defaults to no limit.
+	MaxConnDuration time.Duration
+
+	// DisableKeepAlive closes the connection after every request.
+	DisableKeepAlive bool
+
+	// Regions maps the executor addresses to the regions they run in.
+	// If LocalRegion is set too, the requests go to the executors of
+	// LocalRegion, selected by LoadBalancer, and only go to the other
+	// executors once all of them are marked down.
+	Regions map[string]string
+
+	// LocalRegion is the region the client runs in.
+	LocalRegion string
+
+	// ProbeInterval is how often an executor that a request has failed to
+	// reach is probed. It is left out of the selection until it answers.
+	// Defaults to DefaultProbeInterval.
+	ProbeInterval time.Duration
+}
+
+const (
+	DefaultMaxRetries   = 5
+	DefaultRetryBackoff = 5 * time.Millisecond
+
+	// DefaultMaxIdleConnDuration is the default ClientOptions.MaxIdleConnDuration.
+	DefaultMaxIdleConnDuration = 5 * time.Second
+)
+
 func NewClient(executorAddrMap map[string][]string) *Client {
+	return NewClientWithOptions(executorAddrMap, ClientOptions{})
+}
+
+func NewClientWithOptions(executorAddrMap map[string][]string, opts ClientOptions) *Client {
+	if opts.MaxRequestBodySize <= 0 {
+		opts.MaxRequestBodySize = DefaultMaxBodySize
+	}
+	if opts.MaxResponseBodySize <= 0 {
+		opts.MaxResponseBodySize = DefaultMaxBodySize
+	}
+	if opts.LoadBalancer == nil {
+		opts.LoadBalancer = NewRoundRobinBalancer
+	}
+	if opts.LocalRegion != "" && len(opts.Regions) > 0 {
+		opts.LoadBalancer = NewRegionalBalancerFactory(opts.Regions, opts.LocalRegion, opts.LoadBalancer)
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	if opts.PeerRefreshInterval <= 0 {
+		opts.PeerRefreshInterval = DefaultPeerRefreshInterval
+	}
+	if opts.MaxIdleConnDuration <= 0 {
+		opts.MaxIdleConnDuration = DefaultMaxIdleConnDuration
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = DefaultProbeInterval
+	}
+	if opts.NewBackoff == nil {
+		initial := opts.RetryBackoff
+		opts.NewBackoff = func() backoff.Backoff {
+			return backoff.NewExponential(initial)
+		}
+	}
+
 	// addrList := make([]string, 0)
 
 	// for _, serverAddr := range serverAddrList {
 	// 	serverAddr = "http://" + serverAddr
 	// 	addrList = append(addrList, serverAddr)
 	// }
-	curIndexMap := make(map[string]int)
-	for dsName := range executorAddrMap {
-		curIndexMap[dsName] = 0
+	c := &Client{
+		ExecutorAddrMap:    executorAddrMap,
+		topology:           newTopology(executorAddrMap, opts.LoadBalancer),
+		prober:             newProber(opts.ProbeInterval),
+		maxRequestBodySize: opts.MaxRequestBodySize,
+		maxRetries:         opts.MaxRetries,
+		newBackoff:         opts.NewBackoff,
+		metrics:            newRequestMetrics(),
+		disableKeepAlive:   opts.DisableKeepAlive,
+		httpClient: &fasthttp.Client{
+			MaxResponseBodySize: opts.MaxResponseBodySize,
+			MaxIdleConnDuration: opts.MaxIdleConnDuration,
+			MaxConnDuration:     opts.MaxConnDuration,
+		},
 	}
-	return &Client{
-		ExecutorAddrMap: executorAddrMap,
-		curIndexMap:     curIndexMap,
+	if opts.SeedAddr != "" {
+		c.startDiscovery(opts.SeedAddr, opts.PeerRefreshInterval)
 	}
+	return c
 }
 
-func (c *Client) GetServerAddr(dsName string) string {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// checkRequestSize fails early if the request body exceeds the limit,
+// instead of letting the executor reject a truncated body.
+func (c *Client) checkRequestSize(op string, body []byte) error {
+	if len(body) > c.maxRequestBodySize {
+		return fmt.Errorf("%s request body of %d bytes exceeds the limit of %d bytes",
+			op, len(body), c.maxRequestBodySize)
+	}
+	return nil
+}
 
-	executorAddrList, ok := c.ExecutorAddrMap[dsName]
-	if !ok {
-		if alt, ok := c.ExecutorAddrMap[ALL]; ok {
-			executorAddrList = alt
-		} else {
-			log.Fatalf("GetExecutorAddr: dsName %v not found in ExecutorAddrMap", dsName)
+// ErrOverloaded is returned for a request that an executor has kept
+// rejecting with 503 Service Unavailable or 429 Too Many Requests.
+var ErrOverloaded = errors.New("executor is overloaded")
+
+// do sends the request to the executor.
+// Requests rejected by an overloaded executor are retried with exponential backoff.
+// Oversized bodies are reported as errors. An executor that cannot be reached
+// is marked down, so that the next requests go to the others, until it
+// answers a probe.
+func (c *Client) do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	if c.disableKeepAlive {
+		req.SetConnectionClose()
+	}
+	b := c.newBackoff()
+	for i := 0; ; i++ {
+		err := c.httpClient.Do(req, resp)
+		if errors.Is(err, fasthttp.ErrBodyTooLarge) {
+			return fmt.Errorf("response body exceeds the limit of %d bytes", c.httpClient.MaxResponseBodySize)
+		}
+		if err != nil {
+			addr := executorAddr(req)
+			c.markDown(addr)
+			return fmt.Errorf("executor %s is unreachable: %w", addr, err)
 		}
+		switch resp.StatusCode() {
+		case fasthttp.StatusRequestEntityTooLarge:
+			return errors.New("request body exceeds the limit of the executor")
+		case fasthttp.StatusServiceUnavailable, fasthttp.StatusTooManyRequests:
+			if i >= c.maxRetries {
+				return fmt.Errorf("%w after %d retries", ErrOverloaded, c.maxRetries)
+			}
+			wait := b.Next()
+			if retryAfter, err := strconv.Atoi(string(resp.Header.Peek(fasthttp.HeaderRetryAfter))); err == nil {
+				wait = min(wait, time.Duration(retryAfter)*time.Second)
+			}
+			time.Sleep(wait)
+			continue
+		}
+		return nil
 	}
+}
 
-	curIndex, ok := c.curIndexMap[dsName]
+// WithContext returns a copy of the client that propagates
+// the trace context in ctx to the executors.
+func (c *Client) WithContext(ctx context.Context) txn.RemoteClient {
+	cc := *c
+	cc.ctx = ctx
+	return &cc
+}
+
+// GetLoadBalancer returns the load balancer of the executors serving dsName.
+func (c *Client) GetLoadBalancer(dsName string) LoadBalancer {
+	balancer, ok := c.topology.balancer(dsName)
 	if !ok {
-		if alt, ok := c.curIndexMap[ALL]; ok {
-			curIndex = alt
-		} else {
-			log.Fatalf("GetExecutorAddr: dsName %v not found in curIndexMap", dsName)
+		if balancer, ok = c.topology.balancer(ALL); !ok {
+			log.Fatalf("GetExecutorAddr: dsName %v not found in ExecutorAddrMap", dsName)
 		}
 	}
+	return balancer
+}
 
-	if curIndex >= len(executorAddrList) {
-		curIndex = 0
+// GetServerAddr returns the address of the executor that the next request
+// on dsName should be sent to.
+func (c *Client) GetServerAddr(dsName string) string {
+	return c.getServerAddr(dsName, "")
+}
+
+func (c *Client) getServerAddr(dsName string, key string) string {
+	return c.GetLoadBalancer(dsName).Next(key)
+}
+
+// done notifies the load balancer that the op request sent to addr
+// at start has finished, and records its latency.
+func (c *Client) done(dsName string, addr string, op string, start time.Time) {
+	c.metrics.record(addr, op, time.Since(start))
+	c.release(dsName, addr)
+}
+
+// release notifies the load balancer of dsName that a request sent to addr has finished.
+func (c *Client) release(dsName string, addr string) {
+	if tracker, ok := c.GetLoadBalancer(dsName).(RequestTracker); ok {
+		tracker.Done(addr)
 	}
-	addr := executorAddrList[curIndex]
-	c.curIndexMap[dsName] = curIndex + 1
-	return addr
 }
 
 func (c *Client) Read(dsName string, key string, ts int64, cfg txn.RecordConfig) (txn.DataItem, txn.RemoteDataStrategy, string, error) {
+	return c.read(dsName, key, ts, cfg, nil, 0)
+}
+
+// ReadAt reads the version of a record visible at readTs for the
+// transaction started at ts.
+func (c *Client) ReadAt(dsName string, key string, ts int64, readTs int64, cfg txn.RecordConfig) (txn.DataItem, txn.RemoteDataStrategy, string, error) {
+	return c.read(dsName, key, ts, cfg, nil, readTs)
+}
+
+// ReadFields reads a record like Read, with its value projected
+// on fields by the executor.
+func (c *Client) ReadFields(dsName string, key string, ts int64, cfg txn.RecordConfig, fields []string) (txn.DataItem, txn.RemoteDataStrategy, string, error) {
+	return c.read(dsName, key, ts, cfg, fields, 0)
+}
+
+func (c *Client) read(dsName string, key string, ts int64, cfg txn.RecordConfig, fields []string, readTs int64) (txn.DataItem, txn.RemoteDataStrategy, string, error) {
 	if config.Debug.DebugMode {
 		time.Sleep(config.Debug.HTTPAdditionalLatency)
 	}
@@ -80,10 +317,14 @@ func (c *Client) Read(dsName string, key string, ts int64, cfg txn.RecordConfig)
 		Key:       key,
 		StartTime: ts,
 		Config:    cfg,
+		Fields:    fields,
+		ReadTs:    readTs,
 	}
-	jsonData, _ := json2.Marshal(data)
+	jsonData, _ := config.Config.Codec.Serialize(data)
 
-	reqUrl := c.GetServerAddr(dsName) + "/read"
+	addr := c.getServerAddr(dsName, key)
+	defer c.done(dsName, addr, "read", time.Now())
+	reqUrl := addr + "/read"
 
 	// Create a new POST request using fasthttp
 	req := fasthttp.AcquireRequest()
@@ -91,15 +332,16 @@ func (c *Client) Read(dsName string, key string, ts int64, cfg txn.RecordConfig)
 
 	req.SetRequestURI(reqUrl)
 	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
 	req.Header.SetContentType("application/json")
 	req.SetBody(jsonData)
 
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	err := fasthttp.Do(req, resp)
+	err := c.do(req, resp)
 	if err != nil {
-		log.Fatal(err)
+		return nil, txn.Normal, "", err
 	}
 
 	if resp.StatusCode() != fasthttp.StatusOK {
@@ -109,7 +351,7 @@ func (c *Client) Read(dsName string, key string, ts int64, cfg txn.RecordConfig)
 	body := resp.Body()
 
 	var response ReadResponse
-	err = json2.Unmarshal(body, &response)
+	err = config.Config.Codec.Deserialize(body, &response)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -140,20 +382,26 @@ func (c *Client) Prepare(dsName string, itemList []txn.DataItem,
 		ValidationMap: validationMap,
 	}
 
-	jsonData, err := json2.Marshal(data)
+	jsonData, err := config.Config.Codec.Serialize(data)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := c.checkRequestSize("Prepare", jsonData); err != nil {
+		return nil, 0, err
+	}
 
 	// fmt.Printf("Prepare request(JSON DATA): %v\n", string(jsonData))
 
-	reqUrl := c.GetServerAddr(dsName) + "/prepare"
+	addr := c.GetServerAddr(dsName)
+	defer c.done(dsName, addr, "prepare", time.Now())
+	reqUrl := addr + "/prepare"
 
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
 	req.SetRequestURI(reqUrl)
 	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
 	req.Header.SetContentType("application/json")
 	req.SetBody(jsonData)
 
@@ -162,10 +410,10 @@ func (c *Client) Prepare(dsName string, itemList []txn.DataItem,
 
 	debugMsg := fmt.Sprintf("HttpClient.Do(Prepare) in %v", dsName)
 	logger.Log.Debugw("Before "+debugMsg, "LatencyInFunc", time.Since(debugStart), "Topic", "CheckPoint")
-	err = fasthttp.Do(req, resp)
+	err = c.do(req, resp)
 	logger.Log.Debugw("After "+debugMsg, "LatencyInFunc", time.Since(debugStart), "Topic", "CheckPoint")
 	if err != nil {
-		log.Fatal(err)
+		return nil, 0, err
 	}
 
 	if resp.StatusCode() != fasthttp.StatusOK {
@@ -175,7 +423,7 @@ func (c *Client) Prepare(dsName string, itemList []txn.DataItem,
 	body := resp.Body()
 
 	var response PrepareResponse
-	err = json2.Unmarshal(body, &response)
+	err = config.Config.Codec.Deserialize(body, &response)
 	if err != nil {
 		log.Fatalf("Prepare call resp Unmarshal error: %v\nbody:\n%v", err, string(body))
 	}
@@ -183,12 +431,130 @@ func (c *Client) Prepare(dsName string, itemList []txn.DataItem,
 	if response.Status == "OK" {
 		return response.VerMap, response.TCommit, nil
 	} else {
-		errMsg := response.ErrMsg
-		return nil, 0, errors.New(errMsg)
+		return nil, 0, responseError(response.ErrMsg, response.ErrCode)
+	}
+}
+
+// PrepareResult is the outcome of preparing one datastore in PrepareBatch.
+type PrepareResult = txn.RemotePrepareResult
+
+// PrepareDatastores prepares the datastores of reqs by PrepareBatch,
+// so that the ones served by the same executor share a single request.
+func (c *Client) PrepareDatastores(startTime int64, cfg txn.RecordConfig,
+	reqs map[string]txn.RemotePrepare) map[string]txn.RemotePrepareResult {
+	batch := make(map[string]PrepareRequest, len(reqs))
+	for dsName, req := range reqs {
+		batch[dsName] = PrepareRequest{
+			ItemList:      req.ItemList,
+			StartTime:     startTime,
+			Config:        cfg,
+			ValidationMap: req.ValidationMap,
+		}
+	}
+	return c.PrepareBatch(batch)
+}
+
+// PrepareBatch prepares several datastores, keyed by their names, and
+// returns the outcome of each of them.
+//
+// The datastores served by the same executor are prepared in a single
+// /prepareBatch call instead of one round-trip each, and the executors
+// are called concurrently. A datastore failing to prepare does not fail
+// the others; a failed call fails every datastore it carried.
+func (c *Client) PrepareBatch(reqs map[string]PrepareRequest) map[string]PrepareResult {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.HTTPAdditionalLatency)
+	}
+
+	groups := make(map[string]map[string]PrepareRequest)
+	for dsName, req := range reqs {
+		req.DsName = dsName
+		req.ItemType = GetItemType(dsName)
+		addr := c.GetServerAddr(dsName)
+		if groups[addr] == nil {
+			groups[addr] = make(map[string]PrepareRequest)
+		}
+		groups[addr][dsName] = req
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]PrepareResult, len(reqs))
+	for addr, group := range groups {
+		wg.Add(1)
+		go func(addr string, group map[string]PrepareRequest) {
+			defer wg.Done()
+			start := time.Now()
+			resps, err := c.prepareBatchAt(addr, group)
+			c.metrics.record(addr, "prepareBatch", time.Since(start))
+
+			mu.Lock()
+			defer mu.Unlock()
+			for dsName := range group {
+				c.release(dsName, addr)
+				if err != nil {
+					results[dsName] = PrepareResult{Err: err}
+					continue
+				}
+				resp, ok := resps[dsName]
+				switch {
+				case !ok:
+					results[dsName] = PrepareResult{Err: fmt.Errorf("no prepare response for %s", dsName)}
+				case resp.Status == "OK":
+					results[dsName] = PrepareResult{VerMap: resp.VerMap, TCommit: resp.TCommit}
+				default:
+					results[dsName] = PrepareResult{Err: responseError(resp.ErrMsg, resp.ErrCode)}
+				}
+			}
+		}(addr, group)
+	}
+	wg.Wait()
+	return results
+}
+
+// prepareBatchAt sends the prepare requests of group to the executor at addr
+// and returns its responses, keyed by the datastore names.
+func (c *Client) prepareBatchAt(addr string, group map[string]PrepareRequest) (map[string]PrepareResponse, error) {
+	jsonData, err := config.Config.Codec.Serialize(PrepareBatchRequest{Requests: group})
+	if err != nil {
+		log.Fatal(err)
 	}
+	if err := c.checkRequestSize("PrepareBatch", jsonData); err != nil {
+		return nil, err
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(addr + "/prepareBatch")
+	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
+	req.Header.SetContentType("application/json")
+	req.SetBody(jsonData)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err := c.do(req, resp); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, errors.New("unexpected status code")
+	}
+
+	var response PrepareBatchResponse
+	if err := config.Config.Codec.Deserialize(resp.Body(), &response); err != nil {
+		return nil, fmt.Errorf("PrepareBatch call resp Unmarshal error: %v", err)
+	}
+	if response.Status != "OK" {
+		return nil, errors.New(response.ErrMsg)
+	}
+	return response.Responses, nil
 }
 
-func (c *Client) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64) error {
+// Commit commits the records in infoList with tCommit.
+// It returns the commit timestamp reported by the executor.
+func (c *Client) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64) (int64, error) {
 	if config.Debug.DebugMode {
 		time.Sleep(config.Debug.HTTPAdditionalLatency)
 	}
@@ -198,47 +564,55 @@ func (c *Client) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64)
 		List:    infoList,
 		TCommit: tCommit,
 	}
-	jsonData, _ := json2.Marshal(data)
+	jsonData, _ := config.Config.Codec.Serialize(data)
+	if err := c.checkRequestSize("Commit", jsonData); err != nil {
+		return 0, err
+	}
 
-	reqUrl := c.GetServerAddr(dsName) + "/commit"
+	addr := c.GetServerAddr(dsName)
+	defer c.done(dsName, addr, "commit", time.Now())
+	reqUrl := addr + "/commit"
 
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
 	req.SetRequestURI(reqUrl)
 	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
 	req.Header.SetContentType("application/json")
 	req.SetBody(jsonData)
 
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	err := fasthttp.Do(req, resp)
+	err := c.do(req, resp)
 	if err != nil {
-		log.Fatal(err)
+		return 0, err
 	}
 
 	if resp.StatusCode() != fasthttp.StatusOK {
-		return errors.New("unexpected status code")
+		return 0, errors.New("unexpected status code")
 	}
 
 	body := resp.Body()
 
-	var response Response[string]
-	err = json2.Unmarshal(body, &response)
+	var response CommitResponse
+	err = config.Config.Codec.Deserialize(body, &response)
 	if err != nil {
 		log.Fatalf("Commit call resp Unmarshal error: %v\nbody: %v", err, string(body))
 	}
 
 	if response.Status == "OK" {
-		return nil
+		return response.TCommit, nil
 	} else {
 		errMsg := response.ErrMsg
-		return errors.New(errMsg)
+		return 0, errors.New(errMsg)
 	}
 }
 
-func (c *Client) Abort(dsName string, keyList []string, groupKeyList string) error {
+// Abort asks the executor to roll back the records of keyList written by
+// the transaction of groupKeyList and returns the number rolled back.
+func (c *Client) Abort(dsName string, keyList []string, groupKeyList string) (int, error) {
 	if config.Debug.DebugMode {
 		time.Sleep(config.Debug.HTTPAdditionalLatency)
 	}
@@ -248,42 +622,202 @@ func (c *Client) Abort(dsName string, keyList []string, groupKeyList string) err
 		KeyList:      keyList,
 		GroupKeyList: groupKeyList,
 	}
-	jsonData, _ := json2.Marshal(data)
+	jsonData, _ := config.Config.Codec.Serialize(data)
+	if err := c.checkRequestSize("Abort", jsonData); err != nil {
+		return 0, err
+	}
 
-	reqUrl := c.GetServerAddr(dsName) + "/abort"
+	addr := c.GetServerAddr(dsName)
+	defer c.done(dsName, addr, "abort", time.Now())
+	reqUrl := addr + "/abort"
 
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
 	req.SetRequestURI(reqUrl)
 	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
 	req.Header.SetContentType("application/json")
 	req.SetBody(jsonData)
 
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	err := fasthttp.Do(req, resp)
+	err := c.do(req, resp)
 	if err != nil {
-		log.Fatal(err)
+		return 0, err
 	}
 
 	if resp.StatusCode() != fasthttp.StatusOK {
-		return errors.New("unexpected status code")
+		return 0, errors.New("unexpected status code")
 	}
 
 	body := resp.Body()
 
-	var response Response[string]
-	err = json2.Unmarshal(body, &response)
+	var response AbortResponse
+	err = config.Config.Codec.Deserialize(body, &response)
 	if err != nil {
 		log.Fatalf("Abort call resp Unmarshal error: %v\nbody: %v", err, string(body))
 	}
 
 	if response.Status == "OK" {
-		return nil
+		return response.RolledBack, nil
+	} else {
+		errMsg := response.ErrMsg
+		return 0, errors.New(errMsg)
+	}
+}
+
+// RenewLease asks the executor to extend the leases of the records
+// of infoList, prepared with their versions, and returns their new versions.
+func (c *Client) RenewLease(dsName string, infoList []txn.CommitInfo) (map[string]string, error) {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.HTTPAdditionalLatency)
+	}
+
+	data := RenewLeaseRequest{
+		DsName: dsName,
+		List:   infoList,
+	}
+	jsonData, _ := config.Config.Codec.Serialize(data)
+	if err := c.checkRequestSize("RenewLease", jsonData); err != nil {
+		return nil, err
+	}
+
+	addr := c.GetServerAddr(dsName)
+	defer c.done(dsName, addr, "renewLease", time.Now())
+	reqUrl := addr + "/renewLease"
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(reqUrl)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
+	req.Header.SetContentType("application/json")
+	req.SetBody(jsonData)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err := c.do(req, resp); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, errors.New("unexpected status code")
+	}
+
+	var response Response[map[string]string]
+	if err := config.Config.Codec.Deserialize(resp.Body(), &response); err != nil {
+		return nil, fmt.Errorf("renew lease call resp Unmarshal error: %w", err)
+	}
+	if response.Status != "OK" {
+		return nil, errors.New(response.ErrMsg)
+	}
+	return response.Data, nil
+}
+
+// AbortByGroup asks the executor to roll back every record of dsName
+// tagged with groupKey, the TSR url of a transaction, and returns the keys
+// rolled back. See Committer.AbortByGroup for a transaction already committed.
+func (c *Client) AbortByGroup(dsName string, groupKey string) ([]string, error) {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.HTTPAdditionalLatency)
+	}
+
+	data := AbortGroupRequest{
+		DsName:   dsName,
+		GroupKey: groupKey,
+	}
+	jsonData, _ := config.Config.Codec.Serialize(data)
+
+	addr := c.GetServerAddr(dsName)
+	defer c.done(dsName, addr, "abortGroup", time.Now())
+	reqUrl := addr + "/abortGroup"
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(reqUrl)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
+	req.Header.SetContentType("application/json")
+	req.SetBody(jsonData)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	err := c.do(req, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, errors.New("unexpected status code")
+	}
+
+	var response Response[[]string]
+	if err := config.Config.Codec.Deserialize(resp.Body(), &response); err != nil {
+		return nil, fmt.Errorf("AbortByGroup call resp Unmarshal error: %v", err)
+	}
+
+	if response.Status != "OK" {
+		return response.Data, errors.New(response.ErrMsg)
+	}
+	return response.Data, nil
+}
+
+// ReadTSR returns the state of the TSR of txnId stored in the datastore globalName.
+// If the TSR does not exist, config.EMPTY is returned with a nil error.
+func (c *Client) ReadTSR(globalName string, txnId string) (config.State, error) {
+	if config.Debug.DebugMode {
+		time.Sleep(config.Debug.HTTPAdditionalLatency)
+	}
+
+	data := TSRRequest{
+		DsName: globalName,
+		TxnId:  txnId,
+	}
+	jsonData, _ := config.Config.Codec.Serialize(data)
+
+	addr := c.GetServerAddr(globalName)
+	defer c.done(globalName, addr, "tsr", time.Now())
+	reqUrl := addr + "/tsr"
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	req.SetRequestURI(reqUrl)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	tracing.Inject(c.ctx, &req.Header)
+	req.Header.SetContentType("application/json")
+	req.SetBody(jsonData)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	err := c.do(req, resp)
+	if err != nil {
+		return config.EMPTY, err
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return config.EMPTY, errors.New("unexpected status code")
+	}
+
+	body := resp.Body()
+
+	var response Response[config.State]
+	err = config.Config.Codec.Deserialize(body, &response)
+	if err != nil {
+		log.Fatalf("ReadTSR call resp Unmarshal error: %v\nbody: %v", err, string(body))
+	}
+
+	if response.Status == "OK" {
+		return response.Data, nil
 	} else {
 		errMsg := response.ErrMsg
-		return errors.New(errMsg)
+		return config.EMPTY, errors.New(errMsg)
 	}
 }
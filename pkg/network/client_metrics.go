@@ -0,0 +1,87 @@
+package network
+
+import (
+	"sync"
+	"time"
+)
+
+// OpMetrics accumulates the requests of one operation sent to one executor.
+type OpMetrics struct {
+	Count        int64
+	TotalLatency time.Duration
+	MinLatency   time.Duration
+	MaxLatency   time.Duration
+}
+
+// MeanLatency returns the average latency of the requests.
+func (m OpMetrics) MeanLatency() time.Duration {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.TotalLatency / time.Duration(m.Count)
+}
+
+func (m *OpMetrics) add(latency time.Duration) {
+	if m.Count == 0 || latency < m.MinLatency {
+		m.MinLatency = latency
+	}
+	m.MaxLatency = max(m.MaxLatency, latency)
+	m.Count++
+	m.TotalLatency += latency
+}
+
+// ClientMetrics is a snapshot of the requests sent by a Client,
+// keyed by the executor address and then by the operation
+// ("read", "prepare", "prepareBatch", "commit", "abort", "abortGroup" or "tsr").
+type ClientMetrics map[string]map[string]OpMetrics
+
+// Count returns the number of op requests sent to addr.
+func (m ClientMetrics) Count(addr string, op string) int64 {
+	return m[addr][op].Count
+}
+
+// requestMetrics is shared by a Client and its copies made by WithContext.
+type requestMetrics struct {
+	mu  sync.Mutex
+	ops map[string]map[string]*OpMetrics
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{ops: make(map[string]map[string]*OpMetrics)}
+}
+
+func (rm *requestMetrics) record(addr string, op string, latency time.Duration) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	byOp, ok := rm.ops[addr]
+	if !ok {
+		byOp = make(map[string]*OpMetrics)
+		rm.ops[addr] = byOp
+	}
+	m, ok := byOp[op]
+	if !ok {
+		m = &OpMetrics{}
+		byOp[op] = m
+	}
+	m.add(latency)
+}
+
+func (rm *requestMetrics) snapshot() ClientMetrics {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	snapshot := make(ClientMetrics, len(rm.ops))
+	for addr, byOp := range rm.ops {
+		snapshot[addr] = make(map[string]OpMetrics, len(byOp))
+		for op, m := range byOp {
+			snapshot[addr][op] = *m
+		}
+	}
+	return snapshot
+}
+
+// Metrics returns a snapshot of the number and latency of the requests
+// sent to each executor, which shows how evenly the load balancers
+// spread the load.
+func (c *Client) Metrics() ClientMetrics {
+	return c.metrics.snapshot()
+}
@@ -0,0 +1,395 @@
+package network
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/timesource"
+	trxn "github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// startTestServer serves handler on a random local port and returns its address.
+func startTestServer(t *testing.T, handler fasthttp.RequestHandler) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &fasthttp.Server{Handler: handler}
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+	return "http://" + ln.Addr().String()
+}
+
+func TestClientPrepareOversizedRequest(t *testing.T) {
+	called := false
+	addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		called = true
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+
+	client := NewClientWithOptions(map[string][]string{ALL: {addr}}, ClientOptions{
+		MaxRequestBodySize: 1024,
+	})
+
+	item := &redis.RedisItem{
+		RKey:      "item1",
+		RValue:    util.ToJSONString(strings.Repeat("x", 4096)),
+		RTxnState: config.COMMITTED,
+		RTLease:   time.Now(),
+	}
+	_, _, err := client.Prepare("redis1", []trxn.DataItem{item}, time.Now().UnixMicro(),
+		trxn.RecordConfig{}, map[string]trxn.PredicateInfo{})
+	assert.ErrorContains(t, err, "Prepare request body of")
+	assert.ErrorContains(t, err, "exceeds the limit of 1024 bytes")
+	assert.False(t, called, "oversized request should not be sent")
+}
+
+func TestClientOversizedResponse(t *testing.T) {
+	addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		ctx.SetBodyString(strings.Repeat("x", 4096))
+	})
+
+	client := NewClientWithOptions(map[string][]string{ALL: {addr}}, ClientOptions{
+		MaxResponseBodySize: 1024,
+	})
+
+	_, _, _, err := client.Read("redis1", "item1", time.Now().UnixMicro(), trxn.RecordConfig{})
+	assert.EqualError(t, err, "response body exceeds the limit of 1024 bytes")
+}
+
+func TestClientAbortByGroup(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	_, _ = conn.PutItem("item1", &redis.RedisItem{RKey: "item1", RGroupKeyList: "redis1:txn1",
+		RTxnState: config.PREPARED, RLinkedLen: 1, RVersion: "1"})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	committer := NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, nil)
+
+	// same as the /abortGroup handler of the executor
+	addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		var req AbortGroupRequest
+		if !DecodeRequest(ctx, "abort group", &req) {
+			return
+		}
+		keys, err := committer.AbortByGroup(req.DsName, req.GroupKey)
+		resp := Response[[]string]{Status: "OK", Data: keys}
+		if err != nil {
+			resp = Response[[]string]{Status: "Error", ErrMsg: err.Error()}
+		}
+		WriteResponse(ctx, resp)
+	})
+	client := NewClient(map[string][]string{ALL: {addr}})
+
+	keys, err := client.AbortByGroup("redis1", "redis1:txn1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"item1"}, keys)
+	item, _ := conn.GetItem("item1")
+	assert.True(t, item.IsDeleted())
+
+	_, err = client.AbortByGroup("redis2", "redis2:txn1")
+	assert.EqualError(t, err, "datastore redis2 is not registered")
+}
+
+func TestClientCommitReturnsCommitTime(t *testing.T) {
+	ablationLevel := config.Config.AblationLevel
+	defer func() { config.Config.AblationLevel = ablationLevel }()
+	// the executor assigns the commit timestamp on prepare
+	config.Config.AblationLevel = 3
+
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	clock := timesource.NewFrozenTimeSource(time.UnixMicro(1234))
+	committer := NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, clock)
+	var mu sync.Mutex
+	addr := startTestServer(t, executorHandler(committer, &mu, make(map[string]int)))
+	client := NewClient(map[string][]string{ALL: {addr}})
+
+	prepare := func(key string) map[string]string {
+		item := &redis.RedisItem{RKey: key, RValue: util.ToJSONString("value"), RTLease: time.Now()}
+		verMap, tCommit, err := client.Prepare("redis1", []trxn.DataItem{item}, time.Now().UnixMicro(),
+			trxn.RecordConfig{AblationLevel: 3}, map[string]trxn.PredicateInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1234), tCommit)
+		return verMap
+	}
+	tValid := func(key string) int64 {
+		item, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		return item.TValid()
+	}
+
+	// the timestamp given by the client is applied
+	verMap := prepare("item1")
+	committed, err := client.Commit("redis1",
+		[]trxn.CommitInfo{{Key: "item1", Version: verMap["item1"]}}, 1234)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234), committed)
+	assert.Equal(t, int64(1234), tValid("item1"))
+
+	// without one, the executor takes it from its time source
+	verMap = prepare("item2")
+	clock.Advance(time.Millisecond)
+	committed, err = client.Commit("redis1",
+		[]trxn.CommitInfo{{Key: "item2", Version: verMap["item2"]}}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2234), committed)
+	assert.Equal(t, int64(2234), tValid("item2"))
+}
+
+// TestClientLeaseRoundTrip tests that the lease of an item sent by the client,
+// stored by the executor and read back compares exactly equal.
+func TestClientLeaseRoundTrip(t *testing.T) {
+	defaultCodec := config.Config.Codec
+	defer func() {
+		config.Config.Codec = defaultCodec
+	}()
+
+	for _, name := range []string{"json", "jsoniter"} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := CodecFromName(name)
+			assert.NoError(t, err)
+			config.Config.Codec = codec
+
+			conn := memkv.NewConnection(&redis.RedisItemFactory{})
+			reader := NewReader(map[string]trxn.Connector{"redis1": conn},
+				&redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+
+			// the items of a prepare request are stored as they are decoded
+			addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+				switch string(ctx.Path()) {
+				case "/prepare":
+					var req PrepareRequest
+					if !DecodeRequest(ctx, "prepare", &req) {
+						return
+					}
+					verMap := make(map[string]string)
+					for _, item := range req.ItemList {
+						_, _ = conn.PutItem(item.Key(), item)
+						verMap[item.Key()] = item.Version()
+					}
+					WriteResponse(ctx, PrepareResponse{Status: "OK", VerMap: verMap})
+				case "/read":
+					var req ReadRequest
+					if !DecodeRequest(ctx, "read", &req) {
+						return
+					}
+					item, strategy, gk, err := reader.Read(req.DsName, req.Key, req.StartTime, req.Config, true)
+					resp := ReadResponse{Status: "OK", DataStrategy: strategy, Data: item,
+						GroupKey: gk, ItemType: GetItemType(req.DsName)}
+					if err != nil {
+						resp = ReadResponse{Status: "Error", ErrMsg: err.Error()}
+					}
+					WriteResponse(ctx, resp)
+				}
+			})
+			client := NewClient(map[string][]string{ALL: {addr}})
+
+			// a lease in the local time zone, with a monotonic reading
+			// and a precision finer than a microsecond
+			lease := time.Now().Add(3*time.Second + 789*time.Nanosecond)
+			item := &redis.RedisItem{RKey: "item1", RValue: util.ToJSONString("value"),
+				RTxnState: config.COMMITTED, RTValid: time.Now().Add(-time.Second).UnixMicro(),
+				RTLease: lease, RLinkedLen: 1, RVersion: "1"}
+			_, _, err = client.Prepare("redis1", []trxn.DataItem{item}, time.Now().UnixMicro(),
+				trxn.RecordConfig{}, map[string]trxn.PredicateInfo{})
+			assert.NoError(t, err)
+
+			got, _, _, err := client.Read("redis1", "item1", time.Now().UnixMicro(),
+				trxn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.AssumeCommit})
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, trxn.EncodeLease(lease), trxn.EncodeLease(got.TLease()))
+			assert.True(t, lease.Equal(got.TLease()), "expected %v, got %v", lease, got.TLease())
+			assert.Equal(t, got.TLease(), trxn.DecodeLease(trxn.EncodeLease(got.TLease())))
+		})
+	}
+}
+
+func TestClientCommitError(t *testing.T) {
+	addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		respBytes, _ := config.Config.Codec.Serialize(CommitResponse{Status: "Error", ErrMsg: "version mismatch"})
+		ctx.Write(respBytes)
+	})
+	client := NewClient(map[string][]string{ALL: {addr}})
+
+	committed, err := client.Commit("redis1", []trxn.CommitInfo{{Key: "item1", Version: "v1"}}, 100)
+	assert.EqualError(t, err, "version mismatch")
+	assert.Equal(t, int64(0), committed)
+}
+
+func TestClientMetrics(t *testing.T) {
+	// answers every request with an OK response
+	handler := func(ctx *fasthttp.RequestCtx) {
+		var respBytes []byte
+		switch string(ctx.Path()) {
+		case "/prepare":
+			respBytes, _ = config.Config.Codec.Serialize(PrepareResponse{Status: "OK"})
+		case "/commit":
+			respBytes, _ = config.Config.Codec.Serialize(CommitResponse{Status: "OK"})
+		default:
+			respBytes, _ = config.Config.Codec.Serialize(Response[string]{Status: "OK"})
+		}
+		ctx.Write(respBytes)
+	}
+	addr1 := startTestServer(t, handler)
+	addr2 := startTestServer(t, handler)
+	client := NewClient(map[string][]string{ALL: {addr1, addr2}})
+
+	item := &redis.RedisItem{RKey: "item1", RValue: util.ToJSONString("value"), RTLease: time.Now()}
+	// the round robin balancer alternates between the two executors
+	for i := 0; i < 3; i++ {
+		_, _, err := client.Prepare("redis1", []trxn.DataItem{item}, time.Now().UnixMicro(),
+			trxn.RecordConfig{}, map[string]trxn.PredicateInfo{})
+		assert.NoError(t, err)
+	}
+	_, err := client.Commit("redis1", []trxn.CommitInfo{{Key: "item1", Version: "v1"}}, 100)
+	assert.NoError(t, err)
+	// copies made by WithContext share the metrics
+	_, err = client.WithContext(context.Background()).Abort("redis1", []string{"item1"}, "txn1")
+	assert.NoError(t, err)
+
+	metrics := client.Metrics()
+	assert.Equal(t, int64(2), metrics.Count(addr1, "prepare"))
+	assert.Equal(t, int64(1), metrics.Count(addr2, "prepare"))
+	assert.Equal(t, int64(1), metrics.Count(addr2, "commit"))
+	assert.Equal(t, int64(0), metrics.Count(addr1, "commit"))
+	assert.Equal(t, int64(1), metrics.Count(addr1, "abort"))
+	assert.Equal(t, int64(0), metrics.Count(addr2, "read"))
+
+	prepare := metrics[addr1]["prepare"]
+	assert.True(t, prepare.MinLatency > 0 && prepare.MinLatency <= prepare.MaxLatency)
+	assert.Equal(t, prepare.TotalLatency/2, prepare.MeanLatency())
+}
+
+// countingListener counts the connections accepted by a net.Listener.
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
+
+func TestClientRedialsClosedConnections(t *testing.T) {
+	// startServer serves /tsr, closing the connections idle for idleTimeout
+	startServer := func(idleTimeout time.Duration) (string, *countingListener) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		cl := &countingListener{Listener: ln}
+		server := &fasthttp.Server{
+			Handler: func(ctx *fasthttp.RequestCtx) {
+				WriteResponse(ctx, Response[config.State]{Status: "OK", Data: config.COMMITTED})
+			},
+			IdleTimeout: idleTimeout,
+		}
+		go func() {
+			_ = server.Serve(cl)
+		}()
+		t.Cleanup(func() {
+			_ = server.Shutdown()
+		})
+		return "http://" + ln.Addr().String(), cl
+	}
+	// readTwice sends two requests wait apart
+	readTwice := func(client *Client, wait time.Duration) {
+		for i := 0; i < 2; i++ {
+			state, err := client.ReadTSR("redis1", "txn1")
+			assert.NoError(t, err)
+			assert.Equal(t, config.COMMITTED, state)
+			time.Sleep(wait)
+		}
+	}
+
+	t.Run("a connection closed by the executor is dialed again", func(t *testing.T) {
+		addr, ln := startServer(20 * time.Millisecond)
+		client := NewClient(map[string][]string{ALL: {addr}})
+
+		readTwice(client, 100*time.Millisecond)
+		assert.Equal(t, int32(2), ln.accepted.Load())
+	})
+
+	t.Run("a connection is recycled after MaxConnDuration", func(t *testing.T) {
+		addr, ln := startServer(0)
+		client := NewClientWithOptions(map[string][]string{ALL: {addr}}, ClientOptions{
+			MaxConnDuration: 20 * time.Millisecond,
+		})
+
+		readTwice(client, 50*time.Millisecond)
+		assert.Equal(t, int32(1), ln.accepted.Load())
+		readTwice(client, 0)
+		assert.Equal(t, int32(2), ln.accepted.Load())
+	})
+
+	t.Run("a connection is kept alive by default", func(t *testing.T) {
+		addr, ln := startServer(0)
+		client := NewClient(map[string][]string{ALL: {addr}})
+
+		readTwice(client, 50*time.Millisecond)
+		assert.Equal(t, int32(1), ln.accepted.Load())
+	})
+
+	t.Run("every request dials without keep-alive", func(t *testing.T) {
+		addr, ln := startServer(0)
+		client := NewClientWithOptions(map[string][]string{ALL: {addr}}, ClientOptions{
+			DisableKeepAlive: true,
+		})
+
+		readTwice(client, 0)
+		readTwice(client, 0)
+		assert.Equal(t, int32(4), ln.accepted.Load())
+	})
+}
+
+// TestClientPrepareConflict tests that a conflict reported by an executor
+// is told apart from the other errors with errors.Is on the client.
+func TestClientPrepareConflict(t *testing.T) {
+	connMap := map[string]trxn.Connector{"redis1": memkv.NewConnection(&redis.RedisItemFactory{})}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	committer := NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, offsetTimeSource{})
+	var mu sync.Mutex
+	addr := startTestServer(t, executorHandler(committer, &mu, make(map[string]int)))
+	client := NewClient(map[string][]string{ALL: {addr}})
+
+	// the record has changed since the version was read
+	stale := &redis.RedisItem{RKey: "item1", RValue: util.ToJSONString("value"), RVersion: "5", RTLease: time.Now()}
+	_, _, err := client.Prepare("redis1", []trxn.DataItem{stale}, time.Now().UnixMicro(),
+		trxn.RecordConfig{}, map[string]trxn.PredicateInfo{})
+	assert.ErrorIs(t, err, trxn.VersionMismatch)
+
+	results := client.PrepareBatch(map[string]PrepareRequest{
+		"redis1": {DsName: "redis1", ItemList: []trxn.DataItem{stale}, StartTime: time.Now().UnixMicro()},
+		"redis3": {DsName: "redis3", ItemList: []trxn.DataItem{stale}, StartTime: time.Now().UnixMicro()},
+	})
+	assert.ErrorIs(t, results["redis1"].Err, trxn.VersionMismatch)
+
+	// an unknown datastore is not a conflict
+	assert.Error(t, results["redis3"].Err)
+	for _, target := range []error{trxn.VersionMismatch, trxn.KeyExists, trxn.DirtyRead, trxn.ReadSetChanged, trxn.FalseAssumption} {
+		assert.NotErrorIs(t, results["redis3"].Err, target)
+	}
+}
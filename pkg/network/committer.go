@@ -1,10 +1,13 @@
 package network
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alitto/pond/v2"
@@ -24,6 +27,17 @@ type Committer struct {
 	itemFactory txn.DataItemFactory
 	timeSource  timesource.TimeSourcer
 	pool        pond.Pool
+
+	// lifetime rejects the prepares of the stale transactions,
+	// none if nil.
+	lifetime *TxnLifetime
+	// hotKeys counts the keys prepared and committed, none if nil.
+	hotKeys *HotKeys
+	// startTimes rejects the batched prepares of a transaction
+	// whose start time goes back, none if nil.
+	startTimes *StartTimes
+	// keyLocks serializes the prepares writing the same keys.
+	keyLocks *util.KeyLocks
 }
 
 func NewCommitter(connMap map[string]txn.Connector, reader Reader, se serializer.Serializer, itemFactory txn.DataItemFactory, timeSource timesource.TimeSourcer) *Committer {
@@ -38,7 +52,50 @@ func NewCommitter(connMap map[string]txn.Connector, reader Reader, se serializer
 		itemFactory: itemFactory,
 		timeSource:  timeSource,
 		pool:        pool,
+		keyLocks:    util.NewKeyLocks(256),
+	}
+}
+
+// SetMaxTxnLifetime makes Prepare reject the transactions started longer
+// than max ago according to the time source, and caps the leases of the
+// records prepared at max, so that the records of an abandoned transaction
+// are recovered once it can no longer commit. No limit if max is not positive.
+func (c *Committer) SetMaxTxnLifetime(max time.Duration) {
+	if max <= 0 || c.timeSource == nil {
+		c.lifetime = nil
+		return
+	}
+	c.lifetime = NewTxnLifetime(max, c.timeSource)
+}
+
+// SetHotKeys makes the committer count the keys it prepares and commits
+// in hotKeys, which may be nil.
+func (c *Committer) SetHotKeys(hotKeys *HotKeys) {
+	c.hotKeys = hotKeys
+}
+
+// SetStartTimes makes PrepareBatch check the start time of every request
+// with startTimes, which may be nil.
+func (c *Committer) SetStartTimes(startTimes *StartTimes) {
+	c.startTimes = startTimes
+}
+
+// leaseTime returns how long the records prepared are leased.
+func (c *Committer) leaseTime() time.Duration {
+	if c.lifetime != nil && c.lifetime.Max() < config.Config.LeaseTime {
+		return c.lifetime.Max()
 	}
+	return config.Config.LeaseTime
+}
+
+// conn returns the connector of dsName, which a request may name
+// without the executor serving it.
+func (c *Committer) conn(dsName string) (txn.Connector, error) {
+	conn, ok := c.connMap[dsName]
+	if !ok {
+		return nil, fmt.Errorf("datastore %s is not registered", dsName)
+	}
+	return conn, nil
 }
 
 func (c *Committer) validate(dsName string, cfg txn.RecordConfig,
@@ -54,6 +111,14 @@ func (c *Committer) validate(dsName string, cfg txn.RecordConfig,
 		if pred.ItemKey == "" {
 			return errors.New("validation failed due to predicate item's empty key")
 		}
+		// checked by validateReadVersions after the prepare
+		if pred.ReadVersion {
+			continue
+		}
+		// checked by txn.EvaluateConditions before the validation
+		if pred.IsCondition() {
+			continue
+		}
 		eg.Go(func() error {
 			urlList := strings.Split(gk, ",")
 			groupKey, err := c.reader.getGroupKey(urlList)
@@ -84,7 +149,7 @@ func (c *Committer) validate(dsName string, cfg txn.RecordConfig,
 					fmt.Printf("all group keys are committed, key: %v\n", pred.ItemKey)
 					return nil
 				} else {
-					return errors.New("validation failed due to false assumption")
+					return txn.FalseAssumption
 				}
 			}
 
@@ -93,7 +158,7 @@ func (c *Committer) validate(dsName string, cfg txn.RecordConfig,
 				if txn.AtLeastOneAborted(groupKey) {
 					return nil
 				} else {
-					return errors.New("validation failed due to false assumption")
+					return txn.FalseAssumption
 				}
 			}
 
@@ -104,13 +169,54 @@ func (c *Committer) validate(dsName string, cfg txn.RecordConfig,
 	return eg.Wait()
 }
 
+// validateReadVersions checks that the records read but not written
+// by the transaction still have the versions it observed.
+func (c *Committer) validateReadVersions(dsName string,
+	validationMap map[string]txn.PredicateInfo) error {
+	var eg errgroup.Group
+	for _, predicate := range validationMap {
+		pred := predicate
+		if !pred.ReadVersion {
+			continue
+		}
+		eg.Go(func() error {
+			return txn.CheckReadVersion(c.connMap[dsName], pred.ItemKey, pred.Version)
+		})
+	}
+	return eg.Wait()
+}
+
 func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 	startTime int64, cfg txn.RecordConfig,
 	validateMap map[string]txn.PredicateInfo) (map[string]string, int64, error) {
 
 	debugStart := time.Now()
 
-	err := c.validate(dsName, cfg, validateMap)
+	conn, err := c.conn(dsName)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.lifetime != nil {
+		if err := c.lifetime.Check(startTime); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := txn.EvaluateConditions(conn, c.se, validateMap); err != nil {
+		return nil, 0, err
+	}
+
+	if c.hotKeys != nil {
+		for _, item := range itemList {
+			c.hotKeys.Observe(dsName, item.Key())
+		}
+	}
+
+	// the records are prepared in the same canonical order as by the clients
+	slices.SortFunc(itemList, func(i, j txn.DataItem) int {
+		return cmp.Compare(i.Key(), j.Key())
+	})
+
+	err = c.validate(dsName, cfg, validateMap)
 	logger.Log.Debugw("After validation", "LatencyInFunc", time.Since(debugStart), "Topic", "CheckPoint", "cfg.ConcurrentOptimizationLevel", cfg.ConcurrentOptimizationLevel)
 	if err != nil {
 		return nil, 0, err
@@ -125,6 +231,38 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 		}
 	}
 
+	// prepareItem determines whether the item should be created
+	// and sets its metadata accordingly
+	prepareItem := func(item txn.DataItem) (txn.DataItem, bool, error) {
+		var doCreate bool
+		// if this item follows the read-modify-write pattern
+		if item.Version() != "" {
+			doCreate = false
+		} else {
+			// else we do a txn Read to determine its version
+			dbItem, _, _, err := c.reader.Read(dsName, item.Key(), startTime, cfg, false)
+			if err != nil && err.Error() != "key not found" {
+				logger.Log.Errorw("Read error", "error", err)
+				return nil, false, err
+			}
+			if dbItem == nil {
+				doCreate = true
+			} else {
+				doCreate = false
+			}
+			// logger.Log.Debugw("do a txn Read to determine the record version", "dbItem", dbItem)
+			item, _ = c.updateMetadata(dsName, item, dbItem, 0, cfg)
+		}
+
+		// add TCommit to the item
+		item.SetTValid(tCommit)
+		if c.lifetime != nil {
+			// the lease set by the client may outlive the transaction
+			item.SetTLease(time.Now().Add(c.leaseTime()))
+		}
+		return item, doCreate, nil
+	}
+
 	var mu sync.Mutex
 	// var eg errgroup.Group
 	versionMap := make(map[string]string)
@@ -132,42 +270,90 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 	subPool := c.pool.NewSubpool(5)
 	taskGroup := subPool.NewGroup()
 
-	for _, it := range itemList {
-		item := it
-		taskGroup.SubmitErr(func() error {
-			var doCreate bool
-			// if this item follows the read-modify-write pattern
-			if item.Version() != "" {
-				doCreate = false
-			} else {
-				// else we do a txn Read to determine its version
-				dbItem, _, _, err := c.reader.Read(dsName, item.Key(), startTime, cfg, false)
-				if err != nil && err.Error() != "key not found" {
-					logger.Log.Errorw("Read error", "error", err)
+	// prepareAll prepares every item and builds the requests of a batch
+	prepareAll := func() ([]txn.ConditionalUpdateRequest, error) {
+		reqs := make([]txn.ConditionalUpdateRequest, len(itemList))
+		for i, it := range itemList {
+			idx, item := i, it
+			taskGroup.SubmitErr(func() error {
+				item, doCreate, err := prepareItem(item)
+				if err != nil {
 					return err
 				}
-				if dbItem == nil {
-					doCreate = true
-				} else {
-					doCreate = false
+				reqs[idx] = txn.ConditionalUpdateRequest{Key: item.Key(), Item: item, DoCreate: doCreate}
+				return nil
+			})
+		}
+		return reqs, taskGroup.Wait()
+	}
+
+	if cfg.ConcurrentOptimizationLevel < config.PARALLELIZE_ON_UPDATE {
+		// the transactions writing the same keys are prepared one after
+		// the other, so that one of them succeeds instead of both failing
+		// on different keys
+		keys := make([]string, len(itemList))
+		for i, item := range itemList {
+			keys[i] = dsName + ":" + item.Key()
+		}
+		unlock := c.keyLocks.Lock(keys)
+		defer unlock()
+	}
+
+	batchConn, canBatch := c.connMap[dsName].(txn.BatchConnector)
+	bulkConn, canBulk := c.connMap[dsName].(txn.BulkConnector)
+	switch {
+	case canBatch && len(itemList) > 1:
+		var reqs []txn.ConditionalUpdateRequest
+		reqs, err = prepareAll()
+		if err == nil {
+			var versions []string
+			versions, err = batchConn.ConditionalUpdateBatch(reqs)
+			for i, ver := range versions {
+				versionMap[reqs[i].Key] = ver
+			}
+		}
+	case canBulk && len(itemList) > 1:
+		var reqs []txn.ConditionalUpdateRequest
+		reqs, err = prepareAll()
+		if err == nil {
+			var results []txn.ConditionalUpdateResult
+			results, err = bulkConn.ConditionalUpdateBulk(reqs)
+			// report the first failed item; Abort only rolls back the items
+			// written by this transaction, i.e. the succeeded ones
+			for i, res := range results {
+				if res.Err != nil {
+					if err == nil {
+						err = res.Err
+					}
+					continue
 				}
-				// logger.Log.Debugw("do a txn Read to determine the record version", "dbItem", dbItem)
-				item, _ = c.updateMetadata(item, dbItem, 0, cfg)
+				versionMap[reqs[i].Key] = res.Version
 			}
+		}
+	default:
+		for _, it := range itemList {
+			item := it
+			taskGroup.SubmitErr(func() error {
+				item, doCreate, err := prepareItem(item)
+				if err != nil {
+					return err
+				}
+				ver, err := c.connMap[dsName].ConditionalUpdate(item.Key(), item, doCreate)
 
-			// add TCommit to the item
-			item.SetTValid(tCommit)
-			ver, err := c.connMap[dsName].ConditionalUpdate(item.Key(), item, doCreate)
-
-			mu.Lock()
-			defer mu.Unlock()
-			versionMap[item.Key()] = ver
-			return err
-		})
+				mu.Lock()
+				defer mu.Unlock()
+				versionMap[item.Key()] = ver
+				return err
+			})
+		}
+		err = taskGroup.Wait()
+	}
+	if err == nil {
+		// the read set is validated once the written records are prepared
+		err = c.validateReadVersions(dsName, validateMap)
 	}
-	err = taskGroup.Wait()
 	if err != nil {
-		if cfg.AblationLevel >= 4 {
+		if cfg.AblationLevel >= 4 && len(itemList) > 0 {
 			_ = c.createGroupKey(dsName, itemList[0], config.ABORTED, tCommit)
 		}
 		return nil, 0, err
@@ -188,47 +374,249 @@ func (c *Committer) Prepare(dsName string, itemList []txn.DataItem,
 	return versionMap, tCommit, nil
 }
 
+// PrepareBatch prepares the datastores of reqs concurrently and returns
+// the response of each of them, keyed by the datastore names.
+// A datastore failing to validate or prepare does not fail the others.
+func (c *Committer) PrepareBatch(reqs map[string]PrepareRequest) map[string]PrepareResponse {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	resps := make(map[string]PrepareResponse, len(reqs))
+	for dsName, req := range reqs {
+		wg.Add(1)
+		go func(dsName string, req PrepareRequest) {
+			defer wg.Done()
+			var verMap map[string]string
+			var tCommit int64
+			err := req.Validate()
+			if err == nil && req.DsName != dsName {
+				err = fmt.Errorf("request of %s is keyed by %s", req.DsName, dsName)
+			}
+			if err == nil && c.startTimes != nil {
+				err = c.startTimes.Check(req.Config.TxnId, req.StartTime)
+			}
+			if err == nil {
+				verMap, tCommit, err = c.Prepare(dsName, req.ItemList,
+					req.StartTime, req.Config, req.ValidationMap)
+			}
+
+			resp := PrepareResponse{Status: "OK", VerMap: verMap, TCommit: tCommit}
+			if err != nil {
+				resp = PrepareResponse{Status: "Error", ErrMsg: err.Error(), ErrCode: ErrCode(err)}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			resps[dsName] = resp
+		}(dsName, req)
+	}
+	wg.Wait()
+	return resps
+}
+
 func (c *Committer) createGroupKey(dsName string, item txn.DataItem, state config.State, tCommit int64) error {
 	singleGK := strings.Split(item.GroupKeyList(), ",")[0]
-	txnId := strings.Split(singleGK, ":")[1]
+	_, txnId, ok := strings.Cut(singleGK, ":")
+	if !ok {
+		return fmt.Errorf("malformed group key list %q of %s", item.GroupKeyList(), item.Key())
+	}
 	url := dsName + ":" + txnId
 	return c.reader.createSingleGroupKey(url, state, tCommit)
 }
 
-func (c *Committer) Abort(dsName string, keyList []string, groupKeyList string) error {
+// Abort rolls back the records of keyList still written by the transaction
+// of groupKeyList and returns the number of records rolled back.
+func (c *Committer) Abort(dsName string, keyList []string, groupKeyList string) (int, error) {
+	conn, err := c.conn(dsName)
+	if err != nil {
+		return 0, err
+	}
+	var rolledBack atomic.Int32
 	// var eg errgroup.Group
 	subPool := c.pool.NewSubpool(5)
 	taskGroup := subPool.NewGroup()
 	for _, k := range keyList {
 		key := k
 		taskGroup.SubmitErr(func() error {
-			item, err := c.connMap[dsName].GetItem(key)
+			item, err := txn.GetLatestItem(conn, key)
 			if err != nil {
 				return err
 			}
 			if item.GroupKeyList() == groupKeyList {
-				_, err = c.rollback(dsName, item)
-				return err
-			} else {
-				return nil
+				if _, err = c.rollback(dsName, item); err != nil {
+					return err
+				}
+				rolledBack.Add(1)
 			}
+			return nil
 		})
 	}
-	return taskGroup.Wait()
+	err = taskGroup.Wait()
+	return int(rolledBack.Load()), err
 }
 
-func (c *Committer) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64) error {
+// ErrGroupCommitted is returned by AbortByGroup for a transaction
+// that has reached its commit point, whose records cannot be rolled back.
+var ErrGroupCommitted = errors.New("the transaction of the group key has committed")
+
+// AbortByGroup rolls back every record of dsName tagged with groupKey,
+// the TSR url "dsName:txnId" of a transaction, without knowing the keys
+// the transaction has written. It returns the keys rolled back.
+//
+// The transaction is first decided as aborted by creating its ABORTED TSR,
+// so that the readers resolve its records the same way. Nothing is rolled
+// back if it has committed instead: its TSR is COMMITTED or, the TSR being
+// already deleted, one of its records is COMMITTED. Once the TSR is ABORTED,
+// only the PREPARED records are rolled back, the COMMITTED ones being those
+// an earlier call has rolled back to a tombstone.
+func (c *Committer) AbortByGroup(dsName string, groupKey string) ([]string, error) {
+	conn, err := c.conn(dsName)
+	if err != nil {
+		return nil, err
+	}
+	scanner, ok := conn.(txn.GroupKeyScanner)
+	if !ok {
+		return nil, fmt.Errorf("the connector of %s cannot find records by group key", dsName)
+	}
+	keys, err := scanner.KeysByGroupKey(groupKey)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]txn.DataItem, 0, len(keys))
+	for _, key := range keys {
+		item, err := txn.GetLatestItem(conn, key)
+		if err != nil {
+			if err.Error() == txn.KeyNotFound.Error() {
+				continue
+			}
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := c.decideAborted(groupKey, items); err != nil {
+		return nil, err
+	}
+
+	var rolledBack []string
+	var errs []error
+	for _, item := range items {
+		// the records may have been written again by a later transaction since the scan
+		if item.TxnState() != config.PREPARED || !txn.HasGroupKey(item.GroupKeyList(), groupKey) {
+			continue
+		}
+		if _, err := c.rollback(dsName, item); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item.Key(), err))
+			continue
+		}
+		rolledBack = append(rolledBack, item.Key())
+	}
+	return rolledBack, errors.Join(errs...)
+}
+
+// decideAborted makes sure the TSR at groupKey is ABORTED, creating it if
+// it does not exist, unless the transaction of items has committed.
+func (c *Committer) decideAborted(groupKey string, items []txn.DataItem) error {
+	tsr, err := c.reader.fetchGroupKeyItem(groupKey)
+	if err != nil {
+		if err.Error() != txn.KeyNotFound.Error() {
+			return err
+		}
+		for _, item := range items {
+			if item.TxnState() == config.COMMITTED {
+				return fmt.Errorf("%w: %s is committed", ErrGroupCommitted, item.Key())
+			}
+		}
+		err = c.reader.createSingleGroupKey(groupKey, config.ABORTED, 0)
+		if err == nil {
+			return nil
+		}
+		if err.Error() != txn.KeyExists.Error() {
+			return err
+		}
+		// the TSR has been created by the transaction or a reader meanwhile
+		if tsr, err = c.reader.fetchGroupKeyItem(groupKey); err != nil {
+			return err
+		}
+	}
+	if tsr.TxnState == config.COMMITTED {
+		return fmt.Errorf("%w: its TSR is committed", ErrGroupCommitted)
+	}
+	return nil
+}
+
+// Commit commits the records of infoList, prepared with their versions,
+// with tCommit, or with a commit timestamp taken from the time source of
+// the committer if tCommit is 0. It returns the timestamp applied.
+func (c *Committer) Commit(dsName string, infoList []txn.CommitInfo, tCommit int64) (int64, error) {
+	conn, err := c.conn(dsName)
+	if err != nil {
+		return 0, err
+	}
+	if tCommit == 0 {
+		if c.timeSource == nil {
+			return 0, errors.New("no commit timestamp is given and the committer has no time source")
+		}
+		if tCommit, err = c.timeSource.GetTime("commit"); err != nil {
+			return 0, errors.New("GetTime error: " + err.Error())
+		}
+	}
+	if c.hotKeys != nil {
+		for _, info := range infoList {
+			c.hotKeys.Observe(dsName, info.Key)
+		}
+	}
 	// var eg errgroup.Group
 	subPool := c.pool.NewSubpool(5)
 	taskGroup := subPool.NewGroup()
 	for _, info := range infoList {
 		item := info
 		taskGroup.SubmitErr(func() error {
-			_, err := c.connMap[dsName].ConditionalCommit(item.Key, item.Version, tCommit)
+			_, err := conn.ConditionalCommit(item.Key, item.Version, tCommit)
 			return err
 		})
 	}
-	return taskGroup.Wait()
+	if err := taskGroup.Wait(); err != nil {
+		return 0, err
+	}
+	return tCommit, nil
+}
+
+// RenewLease extends the leases of the records of infoList, prepared with
+// their versions, and returns their new versions by key. It fails with
+// VersionMismatch if a record has been modified since, e.g. rolled back
+// because its lease had expired.
+func (c *Committer) RenewLease(dsName string, infoList []txn.CommitInfo) (map[string]string, error) {
+	conn, err := c.conn(dsName)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	verMap := make(map[string]string, len(infoList))
+	subPool := c.pool.NewSubpool(5)
+	taskGroup := subPool.NewGroup()
+	for _, info := range infoList {
+		info := info
+		taskGroup.SubmitErr(func() error {
+			item, err := txn.GetLatestItem(conn, info.Key)
+			if err != nil {
+				return err
+			}
+			// the update only applies to the record as prepared
+			item.SetVersion(info.Version)
+			item.SetTLease(time.Now().Add(c.leaseTime()))
+			newVer, err := conn.ConditionalUpdate(info.Key, item, false)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			verMap[info.Key] = newVer
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := taskGroup.Wait(); err != nil {
+		return nil, err
+	}
+	return verMap, nil
 }
 
 // truncate truncates the linked list of DataItems
@@ -241,7 +629,7 @@ func (c *Committer) Commit(dsName string, infoList []txn.CommitInfo, tCommit int
 // Finally, it returns the last popped DataItem as the truncated DataItem.
 //
 // If the length of the linked list is less than or equal to the maximum record length, it returns the input DataItem as is.
-func (c *Committer) truncate(newItem txn.DataItem, cfg txn.RecordConfig) (txn.DataItem, error) {
+func (c *Committer) truncate(dsName string, newItem txn.DataItem, cfg txn.RecordConfig) (txn.DataItem, error) {
 	maxLen := cfg.MaxRecordLen
 
 	if newItem.LinkedLen() > maxLen {
@@ -249,7 +637,7 @@ func (c *Committer) truncate(newItem txn.DataItem, cfg txn.RecordConfig) (txn.Da
 		stack.Push(newItem)
 		curItem := &newItem
 		for i := 1; i <= maxLen-1; i++ {
-			preItem, err := c.getPrevItem(*curItem)
+			preItem, err := c.getPrevItem(dsName, *curItem)
 			if err != nil {
 				return nil, errors.New("Unmarshal error: " + err.Error())
 			}
@@ -290,7 +678,7 @@ func (c *Committer) truncate(newItem txn.DataItem, cfg txn.RecordConfig) (txn.Da
 //
 // It then truncates the record using the truncate method and sets the TxnState, TValid, and TLease fields of the newItem.
 // Finally, it returns the updated newItem and any error that occurred during the process.
-func (c *Committer) updateMetadata(newItem txn.DataItem,
+func (c *Committer) updateMetadata(dsName string, newItem txn.DataItem,
 	oldItem txn.DataItem, commitTime int64, cfg txn.RecordConfig) (txn.DataItem, error) {
 	if oldItem == nil {
 		newItem.SetLinkedLen(1)
@@ -305,7 +693,7 @@ func (c *Committer) updateMetadata(newItem txn.DataItem,
 	}
 
 	// truncate the record
-	newItem, err := c.truncate(newItem, cfg)
+	newItem, err := c.truncate(dsName, newItem, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -313,12 +701,19 @@ func (c *Committer) updateMetadata(newItem txn.DataItem,
 	newItem.SetTxnState(config.PREPARED)
 	newItem.SetTValid(commitTime)
 	// TODO: time.Now() is temporary
-	newItem.SetTLease(time.Now().Add(config.Config.LeaseTime))
+	newItem.SetTLease(time.Now().Add(c.leaseTime()))
 	return newItem, nil
 }
 
-func (c *Committer) getPrevItem(item txn.DataItem) (txn.DataItem, error) {
-	preItem := c.itemFactory.NewDataItem(txn.ItemOptions{})
+func (c *Committer) getPrevItem(dsName string, item txn.DataItem) (txn.DataItem, error) {
+	factory := txn.ItemFactoryFor(dsName)
+	if factory == nil {
+		factory = c.itemFactory
+	}
+	if factory == nil {
+		return nil, fmt.Errorf("no item type is registered for datastore %s", dsName)
+	}
+	preItem := factory.NewDataItem(txn.ItemOptions{})
 	err := c.se.Deserialize([]byte(item.Prev()), &preItem)
 	if err != nil {
 		return nil, err
@@ -342,7 +737,7 @@ func (c *Committer) rollback(dsName string, item txn.DataItem) (txn.DataItem, er
 		return item, err
 	}
 
-	newItem, err := c.getPrevItem(item)
+	newItem, err := c.getPrevItem(dsName, item)
 	if err != nil {
 		return nil, errors.Join(errors.New("rollback failed"), err)
 	}
@@ -361,7 +756,7 @@ func (c *Committer) rollback(dsName string, item txn.DataItem) (txn.DataItem, er
 
 func (c *Committer) rollbackFromConn(dsName string, key string) error {
 
-	item, err := c.connMap[dsName].GetItem(key)
+	item, err := txn.GetLatestItem(c.connMap[dsName], key)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,891 @@
+package network
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	trxn "github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// newBatchCommitter serves redis1 and mongo1, each holding item1 at version 1.
+func newBatchCommitter() *Committer {
+	item := &redis.RedisItem{RKey: "item1", RTxnState: config.COMMITTED, RVersion: "1"}
+	connMap := map[string]trxn.Connector{
+		"redis1": &itemConnector{items: map[string]trxn.DataItem{"item1": item}},
+		"mongo1": &itemConnector{items: map[string]trxn.DataItem{"item1": item}},
+	}
+	reader := NewReader(connMap, nil, config.Config.Serializer, NewCacher())
+	return NewCommitter(connMap, *reader, config.Config.Serializer, nil, nil)
+}
+
+// readSetRequest prepares no record and validates that item1 is at version.
+func readSetRequest(dsName string, version string) PrepareRequest {
+	return PrepareRequest{
+		DsName:   dsName,
+		ItemType: GetItemType(dsName),
+		Config:   trxn.RecordConfig{ReadStrategy: config.Pessimistic},
+		ValidationMap: map[string]trxn.PredicateInfo{
+			"read:item1": {ItemKey: "item1", ReadVersion: true, Version: version},
+		},
+	}
+}
+
+// prepareBatchHandler serves /prepareBatch like the executor.
+func prepareBatchHandler(c *Committer, calls *int) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		*calls++
+		if string(ctx.Path()) != "/prepareBatch" {
+			ctx.Error("Unsupported path", fasthttp.StatusNotFound)
+			return
+		}
+		var req PrepareBatchRequest
+		if err := config.Config.Codec.Deserialize(ctx.PostBody(), &req); err != nil {
+			ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+			return
+		}
+		WriteResponse(ctx, PrepareBatchResponse{Status: "OK", Responses: c.PrepareBatch(req.Requests)})
+	}
+}
+
+func TestCommitterPrepareBatch(t *testing.T) {
+	c := newBatchCommitter()
+	resps := c.PrepareBatch(map[string]PrepareRequest{
+		"redis1":  readSetRequest("redis1", "1"),
+		"mongo1":  readSetRequest("mongo1", "0"),
+		"unknown": readSetRequest("unknown", "1"),
+	})
+
+	assert.Len(t, resps, 3)
+	assert.Equal(t, "OK", resps["redis1"].Status)
+	assert.Equal(t, "Error", resps["mongo1"].Status)
+	assert.Contains(t, resps["mongo1"].ErrMsg, "item1 has changed since it was read")
+	assert.Equal(t, "Error", resps["unknown"].Status)
+	assert.Equal(t, "datastore unknown is not registered", resps["unknown"].ErrMsg)
+}
+
+func TestClientPrepareBatch(t *testing.T) {
+	c := newBatchCommitter()
+	reqs := map[string]PrepareRequest{
+		"redis1": readSetRequest("", "1"),
+		"mongo1": readSetRequest("", "0"),
+	}
+
+	t.Run("co-located datastores are prepared in one call", func(t *testing.T) {
+		calls := 0
+		addr := startTestServer(t, prepareBatchHandler(c, &calls))
+		client := NewClient(map[string][]string{ALL: {addr}})
+
+		results := client.PrepareBatch(reqs)
+		assert.Equal(t, 1, calls)
+		assert.Len(t, results, 2)
+		assert.NoError(t, results["redis1"].Err)
+		assert.ErrorContains(t, results["mongo1"].Err, "item1 has changed since it was read")
+		assert.Equal(t, int64(1), client.Metrics().Count(addr, "prepareBatch"))
+	})
+
+	t.Run("every executor is called once", func(t *testing.T) {
+		redisCalls, mongoCalls := 0, 0
+		redisAddr := startTestServer(t, prepareBatchHandler(c, &redisCalls))
+		mongoAddr := startTestServer(t, prepareBatchHandler(c, &mongoCalls))
+		client := NewClient(map[string][]string{"redis1": {redisAddr}, "mongo1": {mongoAddr}})
+
+		results := client.PrepareBatch(reqs)
+		assert.Equal(t, 1, redisCalls)
+		assert.Equal(t, 1, mongoCalls)
+		assert.NoError(t, results["redis1"].Err)
+		assert.Error(t, results["mongo1"].Err)
+	})
+
+	t.Run("a failed call fails its datastores", func(t *testing.T) {
+		addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+			ctx.Error("Unsupported path", fasthttp.StatusNotFound)
+		})
+		client := NewClient(map[string][]string{ALL: {addr}})
+
+		results := client.PrepareBatch(reqs)
+		assert.EqualError(t, results["redis1"].Err, "unexpected status code")
+		assert.EqualError(t, results["mongo1"].Err, "unexpected status code")
+	})
+}
+
+// executorHandler serves the prepare and commit routes with c like the
+// executor, and counts the requests to each route in calls.
+func executorHandler(c *Committer, mu *sync.Mutex, calls map[string]int) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		mu.Lock()
+		calls[string(ctx.Path())]++
+		mu.Unlock()
+		switch string(ctx.Path()) {
+		case "/prepare":
+			var req PrepareRequest
+			if !DecodeRequest(ctx, "prepare", &req) {
+				return
+			}
+			verMap, tCommit, err := c.Prepare(req.DsName, req.ItemList, req.StartTime, req.Config, req.ValidationMap)
+			resp := PrepareResponse{Status: "OK", VerMap: verMap, TCommit: tCommit}
+			if err != nil {
+				resp = PrepareResponse{Status: "Error", ErrMsg: err.Error(), ErrCode: ErrCode(err)}
+			}
+			WriteResponse(ctx, resp)
+		case "/prepareBatch":
+			var req PrepareBatchRequest
+			if !DecodeRequest(ctx, "prepareBatch", &req) {
+				return
+			}
+			WriteResponse(ctx, PrepareBatchResponse{Status: "OK", Responses: c.PrepareBatch(req.Requests)})
+		case "/commit":
+			var req CommitRequest
+			if !DecodeRequest(ctx, "commit", &req) {
+				return
+			}
+			tCommit, err := c.Commit(req.DsName, req.List, req.TCommit)
+			resp := CommitResponse{Status: "OK", TCommit: tCommit}
+			if err != nil {
+				resp = CommitResponse{Status: "Error", ErrMsg: err.Error()}
+			}
+			WriteResponse(ctx, resp)
+		default:
+			ctx.Error("Unsupported path", fasthttp.StatusNotFound)
+		}
+	}
+}
+
+// TestTxnPrepareBatchRemote tests that a remote transaction prepares
+// its datastores in one request per executor.
+func TestTxnPrepareBatchRemote(t *testing.T) {
+	ablationLevel := config.Config.AblationLevel
+	defer func() { config.Config.AblationLevel = ablationLevel }()
+	config.Config.AblationLevel = 3
+
+	connMap := map[string]trxn.Connector{
+		"redis1":  memkv.NewConnection(&redis.RedisItemFactory{}),
+		"Redis":   memkv.NewConnection(&redis.RedisItemFactory{}),
+		"KVRocks": memkv.NewConnection(&redis.RedisItemFactory{}),
+	}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	committer := NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, offsetTimeSource{})
+
+	var mu sync.Mutex
+	shared, alone := make(map[string]int), make(map[string]int)
+	sharedAddr := startTestServer(t, executorHandler(committer, &mu, shared))
+	aloneAddr := startTestServer(t, executorHandler(committer, &mu, alone))
+	client := NewClient(map[string][]string{
+		"redis1":  {sharedAddr},
+		"Redis":   {sharedAddr},
+		"KVRocks": {aloneAddr},
+	})
+
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransactionWithRemote(client, offsetTimeSource{})
+		for name, conn := range connMap {
+			_ = txn.AddDatastore(trxn.NewDatastore(name, conn, &redis.RedisItemFactory{}))
+		}
+		return txn
+	}
+
+	txn := newTxn()
+	assert.NoError(t, txn.Start())
+	for name := range connMap {
+		assert.NoError(t, txn.Write(name, "item1", "v1"))
+	}
+	assert.NoError(t, txn.Commit())
+	mu.Lock()
+	assert.Equal(t, map[string]int{"/prepareBatch": 1, "/commit": 2}, shared)
+	assert.Equal(t, map[string]int{"/prepareBatch": 1, "/commit": 1}, alone)
+	mu.Unlock()
+	for name, conn := range connMap {
+		item, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		assert.Equal(t, config.COMMITTED, item.TxnState(), name)
+	}
+
+	// a datastore with nothing to prepare does not hold the others back
+	clear(shared)
+	clear(alone)
+	txn = newTxn()
+	assert.NoError(t, txn.Start())
+	assert.NoError(t, txn.Write("redis1", "item2", "v1"))
+	assert.NoError(t, txn.Write("KVRocks", "item2", "v1"))
+	assert.NoError(t, txn.Commit())
+	mu.Lock()
+	assert.Equal(t, 1, shared["/prepareBatch"])
+	assert.Equal(t, 1, alone["/prepareBatch"])
+	assert.Zero(t, shared["/prepare"]+alone["/prepare"])
+	mu.Unlock()
+}
+
+// versionedConnector applies the conditional updates on the items kept
+// in memory and records the keys it updates.
+type versionedConnector struct {
+	itemConnector
+	mu      sync.Mutex
+	updated []string
+}
+
+func (c *versionedConnector) ConditionalUpdate(key string, value trxn.DataItem, doCreate bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updated = append(c.updated, key)
+	if cur, ok := c.items[key]; !ok || cur.Version() != value.Version() {
+		return "", trxn.VersionMismatch
+	}
+	ver := util.AddToString(value.Version(), 1)
+	value.SetVersion(ver)
+	c.items[key] = value
+	return ver, nil
+}
+
+// rendezvousConnector holds each conditional update until a transaction
+// other than its writer, named by the value written, is updating too, or
+// a short timeout, then applies it after a random latency, so that the
+// prepares running at once interleave their updates.
+type rendezvousConnector struct {
+	versionedConnector
+	inFlight    map[string]int
+	maxInFlight int
+}
+
+func (c *rendezvousConnector) ConditionalUpdate(key string, value trxn.DataItem, doCreate bool) (string, error) {
+	c.mu.Lock()
+	c.inFlight[value.Value()]++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight[value.Value()])
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight[value.Value()]--
+		c.mu.Unlock()
+	}()
+
+	deadline := time.Now().Add(20 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		others := len(c.inFlight) > 1 && c.inFlight[value.Value()] < c.inFlightTotal()
+		c.mu.Unlock()
+		if others {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Duration(rand.Intn(5000)) * time.Microsecond)
+	return c.versionedConnector.ConditionalUpdate(key, value, doCreate)
+}
+
+// inFlightTotal returns the number of updates in flight, under c.mu.
+func (c *rendezvousConnector) inFlightTotal() int {
+	total := 0
+	for _, n := range c.inFlight {
+		total += n
+	}
+	return total
+}
+
+// TestCommitterPrepareConflictingKeys tests that two transactions writing
+// the same keys in opposite orders are prepared one after the other by
+// default, so that exactly one of them succeeds, while the records of each
+// of them are still updated in parallel.
+func TestCommitterPrepareConflictingKeys(t *testing.T) {
+	const rounds = 20
+	newItem := func(key string, writer string) trxn.DataItem {
+		return &redis.RedisItem{RKey: key, RValue: writer, RTxnState: config.PREPARED, RVersion: "1"}
+	}
+	// run prepares two transactions at once and returns the number of
+	// rounds in which both aborted, and the most updates of a transaction
+	// in flight at once
+	run := func(level int) (int, int) {
+		bothAborted, maxInFlight := 0, 0
+		for i := 0; i < rounds; i++ {
+			conn := &rendezvousConnector{
+				versionedConnector: versionedConnector{itemConnector: itemConnector{items: map[string]trxn.DataItem{
+					"key1": newItem("key1", ""),
+					"key2": newItem("key2", ""),
+					"key3": newItem("key3", ""),
+				}}},
+				inFlight: make(map[string]int),
+			}
+			connMap := map[string]trxn.Connector{"redis1": conn}
+			reader := NewReader(connMap, nil, config.Config.Serializer, NewCacher())
+			c := NewCommitter(connMap, *reader, config.Config.Serializer, nil, nil)
+			cfg := trxn.RecordConfig{ReadStrategy: config.Pessimistic, ConcurrentOptimizationLevel: level}
+
+			var wg sync.WaitGroup
+			errs := make([]error, 2)
+			for j, keys := range [][]string{{"key1", "key2", "key3"}, {"key3", "key2", "key1"}} {
+				itemList := make([]trxn.DataItem, len(keys))
+				for k, key := range keys {
+					itemList[k] = newItem(key, strings.Repeat("t", j+1))
+				}
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					_, _, errs[j] = c.Prepare("redis1", itemList, 0, cfg, nil)
+				}(j)
+			}
+			wg.Wait()
+
+			if errs[0] == nil && errs[1] == nil {
+				t.Fatalf("Expected at most one of the conflicting transactions to be prepared")
+			}
+			if errs[0] != nil && errs[1] != nil {
+				bothAborted++
+			}
+			maxInFlight = max(maxInFlight, conn.maxInFlight)
+		}
+		return bothAborted, maxInFlight
+	}
+
+	unordered, _ := run(config.PARALLELIZE_ON_UPDATE)
+	assert.NotZero(t, unordered, "the interleaved prepares should abort each other")
+
+	serialized, maxInFlight := run(config.DEFAULT)
+	assert.Zero(t, serialized)
+	assert.Greater(t, maxInFlight, 1, "the records of a transaction should be updated in parallel")
+	t.Logf("mutual aborts out of %d rounds: %d interleaved, %d serialized", rounds, unordered, serialized)
+}
+
+func TestCommitterAbortByGroup(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	c := NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, nil)
+	cfg := trxn.RecordConfig{ReadStrategy: config.Pessimistic, MaxRecordLen: 3}
+
+	// prepare prepares a record of each key tagged with the TSR url groupKey
+	prepare := func(groupKey string, keys ...string) map[string]string {
+		var items []trxn.DataItem
+		for _, key := range keys {
+			items = append(items, &redis.RedisItem{RKey: key, RValue: groupKey, RGroupKeyList: groupKey})
+		}
+		verMap, _, err := c.Prepare("redis1", items, time.Now().UnixMicro(), cfg, nil)
+		assert.NoError(t, err)
+		return verMap
+	}
+	valueOf := func(key string) string {
+		item, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		return item.Value()
+	}
+
+	// txn1 commits item1 and item2
+	var infoList []trxn.CommitInfo
+	for key, ver := range prepare("redis1:txn1", "item1", "item2") {
+		infoList = append(infoList, trxn.CommitInfo{Key: key, Version: ver})
+	}
+	_, err := c.Commit("redis1", infoList, 100)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.createSingleGroupKey("redis1:txn1", config.COMMITTED, 100))
+
+	// txn2 crashes after preparing item1, item2 and a new item3
+	prepare("redis1:txn2", "item1", "item2", "item3")
+	assert.Equal(t, "redis1:txn2", valueOf("item1"))
+
+	t.Run("the prepared records are rolled back", func(t *testing.T) {
+		keys, err := c.AbortByGroup("redis1", "redis1:txn2")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"item1", "item2", "item3"}, keys)
+
+		assert.Equal(t, "redis1:txn1", valueOf("item1"))
+		assert.Equal(t, "redis1:txn1", valueOf("item2"))
+		item3, err := conn.GetItem("item3")
+		assert.NoError(t, err)
+		assert.True(t, item3.IsDeleted())
+
+		state, err := reader.ReadTSR("redis1", "txn2")
+		assert.NoError(t, err)
+		assert.Equal(t, config.ABORTED, state)
+	})
+
+	t.Run("aborting again rolls nothing back", func(t *testing.T) {
+		keys, err := c.AbortByGroup("redis1", "redis1:txn2")
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+		assert.Equal(t, "redis1:txn1", valueOf("item1"))
+	})
+
+	t.Run("a committed transaction is not rolled back", func(t *testing.T) {
+		_, err := c.AbortByGroup("redis1", "redis1:txn1")
+		assert.ErrorIs(t, err, ErrGroupCommitted)
+
+		// nor once its TSR is deleted
+		assert.NoError(t, conn.Delete("redis1:txn1"))
+		reader.ClearCache()
+		_, err = c.AbortByGroup("redis1", "redis1:txn1")
+		assert.ErrorIs(t, err, ErrGroupCommitted)
+		assert.Equal(t, "redis1:txn1", valueOf("item1"))
+		_, err = conn.Get("redis1:txn1")
+		assert.Error(t, err)
+	})
+
+	t.Run("the connector must find the records", func(t *testing.T) {
+		c := newBatchCommitter()
+		_, err := c.AbortByGroup("redis1", "redis1:txn2")
+		assert.EqualError(t, err, "the connector of redis1 cannot find records by group key")
+	})
+}
+
+// localExecutor serves the requests of a client in process,
+// the way an executor would serve them over the network.
+type localExecutor struct {
+	reader    *Reader
+	committer *Committer
+	sent      []string
+	renewed   []string
+}
+
+func (e *localExecutor) Read(dsName string, key string, ts int64, cfg trxn.RecordConfig) (trxn.DataItem, trxn.RemoteDataStrategy, string, error) {
+	return e.reader.Read(dsName, key, ts, cfg, true)
+}
+
+// ReadFields projects the value read like the /read handler of the executor,
+// and records the value sent back in sent.
+func (e *localExecutor) ReadFields(dsName string, key string, ts int64, cfg trxn.RecordConfig, fields []string) (trxn.DataItem, trxn.RemoteDataStrategy, string, error) {
+	item, strategy, gk, err := e.reader.Read(dsName, key, ts, cfg, true)
+	if err != nil {
+		return item, strategy, gk, err
+	}
+	value, err := trxn.ProjectValue(config.Config.Serializer, []byte(item.Value()), fields)
+	if err != nil {
+		return nil, strategy, gk, err
+	}
+	item.SetValue(string(value))
+	e.sent = append(e.sent, item.Value())
+	return item, strategy, gk, nil
+}
+
+// ReadAt reads like the /read handler of the executor given a ReadTs.
+func (e *localExecutor) ReadAt(dsName string, key string, ts int64, readTs int64, cfg trxn.RecordConfig) (trxn.DataItem, trxn.RemoteDataStrategy, string, error) {
+	return e.reader.Read(dsName, key, readTs+1, cfg, true)
+}
+
+func (e *localExecutor) Prepare(dsName string, itemList []trxn.DataItem, startTime int64,
+	cfg trxn.RecordConfig, validationMap map[string]trxn.PredicateInfo) (map[string]string, int64, error) {
+	return e.committer.Prepare(dsName, itemList, startTime, cfg, validationMap)
+}
+
+func (e *localExecutor) Commit(dsName string, infoList []trxn.CommitInfo, tCommit int64) (int64, error) {
+	return e.committer.Commit(dsName, infoList, tCommit)
+}
+
+func (e *localExecutor) Abort(dsName string, keyList []string, groupKeyList string) (int, error) {
+	return e.committer.Abort(dsName, keyList, groupKeyList)
+}
+
+// RenewLease renews the leases like the /renewLease handler of the
+// executor, and records the keys renewed in renewed.
+func (e *localExecutor) RenewLease(dsName string, infoList []trxn.CommitInfo) (map[string]string, error) {
+	for _, info := range infoList {
+		e.renewed = append(e.renewed, info.Key)
+	}
+	return e.committer.RenewLease(dsName, infoList)
+}
+
+// TestAbortRemoteRolledBackCount tests that a remote transaction failing
+// to prepare reports the records the executor has actually rolled back.
+func TestAbortRemoteRolledBackCount(t *testing.T) {
+	ablationLevel := config.Config.AblationLevel
+	defer func() { config.Config.AblationLevel = ablationLevel }()
+	config.Config.AblationLevel = 3
+
+	conn1 := memkv.NewConnection(&redis.RedisItemFactory{})
+	conn2 := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn1, "redis2": conn2}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	executor := &localExecutor{
+		reader:    reader,
+		committer: NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, offsetTimeSource{}),
+	}
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransactionWithRemote(executor, offsetTimeSource{})
+		_ = txn.AddDatastore(trxn.NewDatastore("redis1", conn1, &redis.RedisItemFactory{}))
+		_ = txn.AddDatastore(trxn.NewDatastore("redis2", conn2, &redis.RedisItemFactory{}))
+		return txn
+	}
+	write := func(dsName string, key string, value string) {
+		txn := newTxn()
+		assert.NoError(t, txn.Start())
+		assert.NoError(t, txn.Write(dsName, key, value))
+		assert.NoError(t, txn.Commit())
+	}
+	write("redis1", "item1", "v1")
+	write("redis2", "item2", "v1")
+
+	txn := newTxn()
+	assert.NoError(t, txn.Start())
+	var value string
+	assert.NoError(t, txn.Read("redis2", "item2", &value))
+	// item2 is updated since it has been read
+	write("redis2", "item2", "v2")
+	assert.NoError(t, txn.Write("redis1", "item1", "v3"))
+	assert.NoError(t, txn.Write("redis1", "item3", "v3"))
+	assert.NoError(t, txn.Write("redis2", "item2", "v3"))
+	assert.Error(t, txn.Commit())
+
+	// both records of redis1 are prepared and rolled back,
+	// while item2 is left to the transaction that has updated it
+	assert.Equal(t, map[string]int{"redis1": 2, "redis2": 0}, txn.Stats().RolledBack)
+	item, err := conn1.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	item, err = conn2.GetItem("item2")
+	assert.NoError(t, err)
+	assert.Equal(t, util.ToJSONString("v2"), item.Value())
+}
+
+// offsetTimeSource is a local clock off by offset.
+type offsetTimeSource struct {
+	offset time.Duration
+}
+
+func (s offsetTimeSource) GetTime(mode string) (int64, error) {
+	return time.Now().Add(s.offset).UnixMicro(), nil
+}
+
+// TestCommitWaitExternalConsistency tests that a transaction started after
+// another has committed with commit-wait reads its writes, even though the
+// clock of its client is behind the clock the commit time was taken from.
+func TestCommitWaitExternalConsistency(t *testing.T) {
+	ablationLevel, uncertainty := config.Config.AblationLevel, config.Config.ClockUncertainty
+	defer func() {
+		config.Config.AblationLevel, config.Config.ClockUncertainty = ablationLevel, uncertainty
+	}()
+	// the commit time is taken by the executor,
+	// and the records are committed before Commit returns
+	config.Config.AblationLevel = 3
+	config.Config.ClockUncertainty = 40 * time.Millisecond
+
+	// the clock of the executor and of the writer is 20ms ahead,
+	// the clock of the reader 20ms behind
+	ahead, behind := offsetTimeSource{20 * time.Millisecond}, offsetTimeSource{-20 * time.Millisecond}
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	executor := &localExecutor{
+		reader:    reader,
+		committer: NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, ahead),
+	}
+	newTxn := func(oracle offsetTimeSource) *trxn.Transaction {
+		txn := trxn.NewTransactionWithRemote(executor, oracle)
+		ds := trxn.NewDatastore("redis1", conn, &redis.RedisItemFactory{})
+		_ = txn.AddDatastore(ds)
+		txn.SetGlobalDatastore(ds)
+		return txn
+	}
+	write := func(commitWait bool, value string) {
+		writer := newTxn(ahead)
+		writer.SetCommitWait(commitWait)
+		assert.NoError(t, writer.Start())
+		assert.NoError(t, writer.Write("redis1", "item1", value))
+		assert.NoError(t, writer.Commit())
+		if commitWait {
+			assert.GreaterOrEqual(t, writer.Stats().CommitWaitDuration, config.Config.ClockUncertainty)
+		}
+	}
+	read := func() string {
+		reader := newTxn(behind)
+		assert.NoError(t, reader.Start())
+		var value string
+		assert.NoError(t, reader.Read("redis1", "item1", &value))
+		assert.NoError(t, reader.Commit())
+		return value
+	}
+
+	write(true, "v1")
+	assert.Equal(t, "v1", read())
+
+	// without commit-wait the reader starts before the commit time
+	write(false, "v2")
+	assert.Equal(t, "v1", read())
+
+	write(true, "v3")
+	assert.Equal(t, "v3", read())
+}
+
+// TestReadFieldsRemote tests that the executor sends back
+// only the fields of a record read by ReadFields.
+func TestReadFieldsRemote(t *testing.T) {
+	type profile struct {
+		Name string
+		Age  int
+		Bio  string
+	}
+	ablationLevel := config.Config.AblationLevel
+	defer func() { config.Config.AblationLevel = ablationLevel }()
+	config.Config.AblationLevel = 3
+
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	executor := &localExecutor{
+		reader:    reader,
+		committer: NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, offsetTimeSource{}),
+	}
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransactionWithRemote(executor, offsetTimeSource{})
+		_ = txn.AddDatastore(trxn.NewDatastore("redis1", conn, &redis.RedisItemFactory{}))
+		return txn
+	}
+
+	writer := newTxn()
+	assert.NoError(t, writer.Start())
+	assert.NoError(t, writer.Write("redis1", "item1", profile{Name: "John", Age: 30, Bio: "a long story"}))
+	assert.NoError(t, writer.Commit())
+
+	txn := newTxn()
+	assert.NoError(t, txn.Start())
+	var got profile
+	assert.NoError(t, txn.ReadFields("redis1", "item1", []string{"Name", "Email"}, &got))
+	assert.Equal(t, profile{Name: "John"}, got)
+	assert.Equal(t, []string{`{"Name":"John"}`}, executor.sent)
+
+	// the projected record is not cached, so a read gets the whole record
+	var whole profile
+	assert.NoError(t, txn.Read("redis1", "item1", &whole))
+	assert.Equal(t, profile{Name: "John", Age: 30, Bio: "a long story"}, whole)
+	assert.NoError(t, txn.Commit())
+}
+
+// TestReadCommitted tests that a read-committed transaction reads the
+// values committed after it has started, which a snapshot does not see.
+func TestReadCommitted(t *testing.T) {
+	ablationLevel := config.Config.AblationLevel
+	defer func() { config.Config.AblationLevel = ablationLevel }()
+	config.Config.AblationLevel = 3
+
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	executor := &localExecutor{
+		reader:    reader,
+		committer: NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, offsetTimeSource{}),
+	}
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransactionWithRemote(executor, offsetTimeSource{})
+		ds := trxn.NewDatastore("redis1", conn, &redis.RedisItemFactory{})
+		_ = txn.AddDatastore(ds)
+		txn.SetGlobalDatastore(ds)
+		return txn
+	}
+	write := func(value string) {
+		writer := newTxn()
+		assert.NoError(t, writer.Start())
+		assert.NoError(t, writer.Write("redis1", "item1", value))
+		assert.NoError(t, writer.Commit())
+		time.Sleep(10 * time.Millisecond)
+	}
+	read := func(txn *trxn.Transaction) string {
+		var value string
+		assert.NoError(t, txn.Read("redis1", "item1", &value))
+		return value
+	}
+
+	write("v1")
+	snapshot, readCommitted := newTxn(), newTxn()
+	readCommitted.SetIsolationLevel(config.ReadCommitted)
+	assert.NoError(t, snapshot.Start())
+	assert.NoError(t, readCommitted.Start())
+	time.Sleep(10 * time.Millisecond)
+
+	write("v2")
+	assert.Equal(t, "v1", read(snapshot))
+	assert.Equal(t, "v2", read(readCommitted))
+
+	// every read of a read-committed transaction returns the latest version
+	write("v3")
+	assert.Equal(t, "v1", read(snapshot))
+	assert.Equal(t, "v3", read(readCommitted))
+
+	assert.NoError(t, snapshot.Commit())
+	assert.NoError(t, readCommitted.Commit())
+}
+
+// TestReadAtRemote tests that a remote transaction reads
+// the version of a record visible at a timestamp.
+func TestReadAtRemote(t *testing.T) {
+	ablationLevel := config.Config.AblationLevel
+	defer func() { config.Config.AblationLevel = ablationLevel }()
+	config.Config.AblationLevel = 3
+
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	executor := &localExecutor{
+		reader:    reader,
+		committer: NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, offsetTimeSource{}),
+	}
+	newTxn := func() *trxn.Transaction {
+		txn := trxn.NewTransactionWithRemote(executor, offsetTimeSource{})
+		_ = txn.AddDatastore(trxn.NewDatastore("redis1", conn, &redis.RedisItemFactory{}))
+		return txn
+	}
+	write := func(value string) int64 {
+		writer := newTxn()
+		assert.NoError(t, writer.Start())
+		assert.NoError(t, writer.Write("redis1", "item1", value))
+		assert.NoError(t, writer.Commit())
+		item, err := conn.GetItem("item1")
+		assert.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+		return item.TValid()
+	}
+
+	before := time.Now().UnixMicro()
+	time.Sleep(10 * time.Millisecond)
+	v1 := write("v1")
+	v2 := write("v2")
+
+	txn := newTxn()
+	// the version visible at a timestamp does not depend on the isolation level
+	txn.SetIsolationLevel(config.ReadCommitted)
+	assert.NoError(t, txn.Start())
+	var value string
+	assert.NoError(t, txn.ReadAt("redis1", "item1", v1, &value))
+	assert.Equal(t, "v1", value)
+	assert.NoError(t, txn.ReadAt("redis1", "item1", v2, &value))
+	assert.Equal(t, "v2", value)
+	assert.Error(t, txn.ReadAt("redis1", "item1", before, &value))
+	assert.NoError(t, txn.Commit())
+}
+
+func TestCommitterPrepareConditions(t *testing.T) {
+	account := &redis.RedisItem{
+		RKey:      "account",
+		RValue:    util.ToJSONString(map[string]any{"Owner": "John", "Balance": 30}),
+		RTxnState: config.COMMITTED,
+		RVersion:  "2",
+	}
+	closed := &redis.RedisItem{RKey: "closed", RTxnState: config.COMMITTED, RIsDeleted: true, RVersion: "3"}
+	pending := &redis.RedisItem{RKey: "pending", RValue: util.ToJSONString("v1"), RTxnState: config.PREPARED, RVersion: "1"}
+	conn := &itemConnector{items: map[string]trxn.DataItem{
+		"account": account, "closed": closed, "pending": pending,
+	}}
+	se := config.Config.Serializer
+
+	cases := []struct {
+		name  string
+		pred  trxn.PredicateInfo
+		holds bool
+	}{
+		{"a string field equals", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateEquals, Field: "Owner", Value: `"John"`}, true},
+		{"a string field differs", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateEquals, Field: "Owner", Value: `"Jane"`}, false},
+		{"a number field equals", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateEquals, Field: "Balance", Value: `30`}, true},
+		{"a missing field equals nothing", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateEquals, Field: "Email", Value: `null`}, false},
+		{"a field is greater", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateGreaterThan, Field: "Balance", Value: "20"}, true},
+		{"a field is not greater", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateGreaterThan, Field: "Balance", Value: "30"}, false},
+		{"the version equals", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateVersionEquals, Version: "2"}, true},
+		{"the version differs", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateVersionEquals, Version: "1"}, false},
+		{"an absent record has no version", trxn.PredicateInfo{ItemKey: "missing", Op: trxn.PredicateVersionEquals}, true},
+		{"a record exists", trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateNotDeleted}, true},
+		{"a record is deleted", trxn.PredicateInfo{ItemKey: "closed", Op: trxn.PredicateNotDeleted}, false},
+		{"a record is absent", trxn.PredicateInfo{ItemKey: "missing", Op: trxn.PredicateNotDeleted}, false},
+		{"a record is being written", trxn.PredicateInfo{ItemKey: "pending", Op: trxn.PredicateNotDeleted}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := trxn.EvaluatePredicate(conn, se, tc.pred)
+			if tc.holds {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, trxn.ErrConditionFailed)
+			}
+		})
+	}
+
+	t.Run("an operand of the wrong type is an error", func(t *testing.T) {
+		err := trxn.EvaluatePredicate(conn, se,
+			trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateGreaterThan, Field: "Owner", Value: "20"})
+		assert.ErrorContains(t, err, "is not a number")
+		assert.NotErrorIs(t, err, trxn.ErrConditionFailed)
+	})
+
+	t.Run("a registered operator is evaluated", func(t *testing.T) {
+		const hasPrefix trxn.PredicateOp = "has_prefix"
+		trxn.RegisterPredicate(hasPrefix, func(_ trxn.DataItem, field json.RawMessage, pred trxn.PredicateInfo) (bool, error) {
+			var s string
+			_ = json.Unmarshal(field, &s)
+			return strings.HasPrefix(s, pred.Value), nil
+		})
+		pred := trxn.PredicateInfo{ItemKey: "account", Op: hasPrefix, Field: "Owner", Value: "Jo"}
+		assert.NoError(t, trxn.EvaluatePredicate(conn, se, pred))
+		pred.Value = "Ja"
+		assert.ErrorIs(t, trxn.EvaluatePredicate(conn, se, pred), trxn.ErrConditionFailed)
+		assert.ErrorContains(t, trxn.EvaluatePredicate(conn, se,
+			trxn.PredicateInfo{ItemKey: "account", Op: "unknown"}), "unknown predicate operator")
+	})
+
+	t.Run("a condition that does not hold aborts the prepare", func(t *testing.T) {
+		prepare := func(condition trxn.PredicateInfo) (*versionedConnector, error) {
+			conn := &versionedConnector{itemConnector: itemConnector{items: map[string]trxn.DataItem{
+				"account": account,
+				"key1":    &redis.RedisItem{RKey: "key1", RTxnState: config.COMMITTED, RVersion: "1"},
+			}}}
+			connMap := map[string]trxn.Connector{"redis1": conn}
+			reader := NewReader(connMap, nil, se, NewCacher())
+			c := NewCommitter(connMap, *reader, se, nil, nil)
+			_, _, err := c.Prepare("redis1",
+				[]trxn.DataItem{&redis.RedisItem{RKey: "key1", RTxnState: config.PREPARED, RVersion: "1"}}, 0,
+				trxn.RecordConfig{ReadStrategy: config.Pessimistic},
+				map[string]trxn.PredicateInfo{"cond:0": condition})
+			return conn, err
+		}
+
+		conn, err := prepare(trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateGreaterThan, Field: "Balance", Value: "10"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key1"}, conn.updated)
+
+		conn, err = prepare(trxn.PredicateInfo{ItemKey: "account", Op: trxn.PredicateGreaterThan, Field: "Balance", Value: "50"})
+		assert.ErrorIs(t, err, trxn.ErrConditionFailed)
+		assert.Empty(t, conn.updated)
+	})
+}
+
+// TestRenewLeaseRemote tests that the executor renews the leases of the
+// records prepared by a remote transaction, which then commits them with
+// their new versions.
+func TestRenewLeaseRemote(t *testing.T) {
+	ablationLevel, threshold := config.Config.AblationLevel, config.Config.LeaseRenewalThreshold
+	defer func() {
+		config.Config.AblationLevel, config.Config.LeaseRenewalThreshold = ablationLevel, threshold
+	}()
+	config.Config.AblationLevel = 3
+	// the leases are renewed however fast the commit
+	config.Config.LeaseRenewalThreshold = config.Config.LeaseTime
+
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	connMap := map[string]trxn.Connector{"redis1": conn}
+	reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+	executor := &localExecutor{
+		reader:    reader,
+		committer: NewCommitter(connMap, *reader, config.Config.Serializer, &redis.RedisItemFactory{}, offsetTimeSource{}),
+	}
+	txn := trxn.NewTransactionWithRemote(executor, offsetTimeSource{})
+	_ = txn.AddDatastore(trxn.NewDatastore("redis1", conn, &redis.RedisItemFactory{}))
+	assert.NoError(t, txn.Start())
+	assert.NoError(t, txn.Write("redis1", "item1", "v1"))
+	assert.NoError(t, txn.Write("redis1", "item2", "v1"))
+	assert.NoError(t, txn.Commit())
+
+	assert.ElementsMatch(t, []string{"item1", "item2"}, executor.renewed)
+	for _, key := range []string{"item1", "item2"} {
+		item, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		assert.Equal(t, config.COMMITTED, item.TxnState())
+	}
+
+	// a record modified since it was prepared is not renewed
+	item, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	_, err = executor.committer.RenewLease("redis1", []trxn.CommitInfo{{Key: "item1", Version: "0"}})
+	assert.ErrorContains(t, err, trxn.VersionMismatch.Error())
+	after, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, item.Version(), after.Version())
+}
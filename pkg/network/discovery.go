@@ -0,0 +1,189 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/logger"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultPeerRefreshInterval is how often a client bootstrapped
+// from a seed executor refreshes the executor addresses.
+const DefaultPeerRefreshInterval = 10 * time.Second
+
+// PeersResponse carries the executor addresses of each datastore
+// known to the executor serving /peers, keyed like the executor
+// address map of a client.
+type PeersResponse struct {
+	Status string
+	ErrMsg string
+	Peers  map[string][]string
+}
+
+// topology holds the executors of each datastore and their load balancers.
+// It is shared by a Client and its copies made by WithContext.
+type topology struct {
+	mu          sync.RWMutex
+	addrMap     map[string][]string
+	balancers   map[string]LoadBalancer
+	newBalancer LoadBalancerFactory
+}
+
+func newTopology(addrMap map[string][]string, newBalancer LoadBalancerFactory) *topology {
+	t := &topology{
+		addrMap:     make(map[string][]string),
+		balancers:   make(map[string]LoadBalancer),
+		newBalancer: newBalancer,
+	}
+	t.update(addrMap)
+	return t
+}
+
+func (t *topology) balancer(dsName string) (LoadBalancer, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	balancer, ok := t.balancers[dsName]
+	return balancer, ok
+}
+
+// update replaces the executor addresses with addrMap. Only the datastores
+// whose addresses have changed get a new load balancer, so the others keep
+// their rotation and the executors marked down.
+func (t *topology) update(addrMap map[string][]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for dsName := range t.addrMap {
+		if _, ok := addrMap[dsName]; !ok {
+			delete(t.addrMap, dsName)
+			delete(t.balancers, dsName)
+		}
+	}
+	for dsName, addrs := range addrMap {
+		if slices.Equal(t.addrMap[dsName], addrs) {
+			continue
+		}
+		addrs = slices.Clone(addrs)
+		t.addrMap[dsName] = addrs
+		t.balancers[dsName] = t.newBalancer(addrs)
+	}
+}
+
+// markDown marks addr down in the load balancers of the datastores it serves.
+func (t *topology) markDown(addr string) {
+	t.forEachBalancerOf(addr, LoadBalancer.MarkDown)
+}
+
+// markUp marks addr up in the load balancers of the datastores it serves.
+func (t *topology) markUp(addr string) {
+	t.forEachBalancerOf(addr, LoadBalancer.MarkUp)
+}
+
+func (t *topology) forEachBalancerOf(addr string, f func(b LoadBalancer, addr string)) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for dsName, addrs := range t.addrMap {
+		if slices.Contains(addrs, addr) {
+			f(t.balancers[dsName], addr)
+		}
+	}
+}
+
+func (t *topology) snapshot() map[string][]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return maps.Clone(t.addrMap)
+}
+
+// Executors returns the executor addresses of each datastore the client
+// currently sends its requests to.
+func (c *Client) Executors() map[string][]string {
+	return c.topology.snapshot()
+}
+
+// RefreshPeers fetches the executor addresses from the /peers endpoint
+// of the executor at seedAddr and sends the following requests to them.
+// An empty list is rejected so that the client always has an executor.
+func (c *Client) RefreshPeers(seedAddr string) error {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(seedAddr + "/peers")
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	// an unreachable seed is not fatal, the known executors are kept
+	if err := c.httpClient.Do(req, resp); err != nil {
+		return err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode(), seedAddr)
+	}
+	var response PeersResponse
+	if err := config.Config.Codec.Deserialize(resp.Body(), &response); err != nil {
+		return err
+	}
+	if response.Status != "OK" {
+		return errors.New(response.ErrMsg)
+	}
+	for _, addrs := range response.Peers {
+		if len(addrs) == 0 {
+			return fmt.Errorf("%s has returned a datastore without executors", seedAddr)
+		}
+	}
+	if len(response.Peers) == 0 {
+		return fmt.Errorf("%s has returned no executor", seedAddr)
+	}
+	c.topology.update(response.Peers)
+	return nil
+}
+
+// discoverPeers refreshes the executor addresses from seedAddr
+// every interval until stop is closed.
+func (c *Client) discoverPeers(seedAddr string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.RefreshPeers(seedAddr); err != nil {
+				logger.Log.Warnw("failed to refresh the executor addresses", "seed", seedAddr, "error", err)
+			}
+		}
+	}
+}
+
+// startDiscovery bootstraps the executor addresses from seedAddr and keeps
+// refreshing them in the background until the client is closed.
+func (c *Client) startDiscovery(seedAddr string, interval time.Duration) {
+	if err := c.RefreshPeers(seedAddr); err != nil {
+		if len(c.topology.snapshot()) == 0 {
+			log.Fatalf("failed to discover the executors from %s: %v", seedAddr, err)
+		}
+		logger.Log.Warnw("failed to discover the executors, using the configured ones", "seed", seedAddr, "error", err)
+	}
+	stop := make(chan struct{})
+	var once sync.Once
+	c.stopDiscovery = func() {
+		once.Do(func() { close(stop) })
+	}
+	go c.discoverPeers(seedAddr, interval, stop)
+}
+
+// Close stops refreshing the executor addresses of a client
+// bootstrapped from a seed executor.
+func (c *Client) Close() {
+	if c.stopDiscovery != nil {
+		c.stopDiscovery()
+	}
+	c.prober.close()
+}
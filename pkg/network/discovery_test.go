@@ -0,0 +1,111 @@
+package network
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// peersServer is a stub seed executor whose /peers lists peers.
+type peersServer struct {
+	mu    sync.Mutex
+	peers map[string][]string
+}
+
+func (s *peersServer) set(peers map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peers = peers
+}
+
+func (s *peersServer) handler(ctx *fasthttp.RequestCtx) {
+	if string(ctx.Path()) != "/peers" {
+		ctx.Error("Unsupported path", fasthttp.StatusNotFound)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	WriteResponse(ctx, PeersResponse{Status: "OK", Peers: s.peers})
+}
+
+// rotation returns the addresses the next n requests on dsName are sent to.
+func rotation(client *Client, dsName string, n int) map[string]bool {
+	seen := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		seen[client.GetServerAddr(dsName)] = true
+	}
+	return seen
+}
+
+func TestClientDiscoversPeers(t *testing.T) {
+	stub := &peersServer{peers: map[string][]string{ALL: {"http://a", "http://b"}}}
+	seed := startTestServer(t, stub.handler)
+
+	client := NewClientWithOptions(map[string][]string{ALL: {"http://static"}}, ClientOptions{
+		SeedAddr:            seed,
+		PeerRefreshInterval: 10 * time.Millisecond,
+	})
+	defer client.Close()
+
+	// the seed replaces the static addresses on creation
+	assert.Equal(t, map[string]bool{"http://a": true, "http://b": true}, rotation(client, "redis1", 4))
+
+	// executors are added and removed
+	stub.set(map[string][]string{
+		ALL:      {"http://b", "http://c", "http://d"},
+		"redis1": {"http://e"},
+	})
+	assert.Eventually(t, func() bool {
+		return len(client.Executors()) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, map[string]bool{"http://b": true, "http://c": true, "http://d": true},
+		rotation(client, "mongo1", 6))
+	assert.Equal(t, map[string]bool{"http://e": true}, rotation(client, "redis1", 2))
+
+	t.Run("an empty list is ignored", func(t *testing.T) {
+		stub.set(map[string][]string{})
+		assert.Error(t, client.RefreshPeers(seed))
+		assert.Len(t, client.Executors(), 2)
+	})
+
+	t.Run("the refresh stops when the client is closed", func(t *testing.T) {
+		client.Close()
+		time.Sleep(20 * time.Millisecond)
+		stub.set(map[string][]string{ALL: {"http://f"}})
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, map[string]bool{"http://c": true, "http://d": true, "http://b": true},
+			rotation(client, "mongo1", 6))
+	})
+}
+
+func TestClientUnreachableSeed(t *testing.T) {
+	client := NewClientWithOptions(map[string][]string{ALL: {"http://static"}}, ClientOptions{
+		SeedAddr: "http://127.0.0.1:1",
+	})
+	defer client.Close()
+
+	// the static addresses are kept
+	assert.Equal(t, "http://static", client.GetServerAddr("redis1"))
+}
+
+func TestTopologyKeepsUnchangedBalancers(t *testing.T) {
+	topo := newTopology(map[string][]string{"redis1": {"http://a", "http://b"}, "mongo1": {"http://c"}},
+		NewRoundRobinBalancer)
+	redisBalancer, _ := topo.balancer("redis1")
+	redisBalancer.MarkDown("http://a")
+
+	topo.update(map[string][]string{"redis1": {"http://a", "http://b"}, "mongo1": {"http://d"}})
+	balancer, _ := topo.balancer("redis1")
+	assert.Same(t, redisBalancer, balancer)
+	assert.Equal(t, "http://b", balancer.Next(""))
+
+	balancer, _ = topo.balancer("mongo1")
+	assert.Equal(t, "http://d", balancer.Next(""))
+
+	topo.update(map[string][]string{"redis1": {"http://a"}})
+	_, ok := topo.balancer("mongo1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,81 @@
+package network
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// The statuses of a datastore in a HealthResponse.
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+	// HealthUnchecked is the status of a datastore whose connector does
+	// not implement txn.Pinger. It is not known to be up, so the executor
+	// is not ready.
+	HealthUnchecked = "unchecked"
+)
+
+// DatastoreHealth is the outcome of pinging a datastore.
+type DatastoreHealth struct {
+	Status string
+	// LatencyMs is how long the ping took, in milliseconds.
+	LatencyMs float64
+	ErrMsg    string `json:",omitempty"`
+}
+
+// HealthResponse is the body of the /health endpoint of an executor.
+type HealthResponse struct {
+	// Status is "OK" if every datastore is up, and "Error" otherwise.
+	Status     string
+	Datastores map[string]DatastoreHealth
+}
+
+// CheckHealth pings the datastores of connMap concurrently.
+func CheckHealth(connMap map[string]txn.Connector) HealthResponse {
+	resp := HealthResponse{
+		Status:     "OK",
+		Datastores: make(map[string]DatastoreHealth, len(connMap)),
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for dsName, conn := range connMap {
+		pinger, ok := conn.(txn.Pinger)
+		if !ok {
+			// the pings started above may be writing resp already
+			mu.Lock()
+			resp.Datastores[dsName] = DatastoreHealth{
+				Status: HealthUnchecked,
+				ErrMsg: "the connector cannot be pinged",
+			}
+			resp.Status = "Error"
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(dsName string) {
+			defer wg.Done()
+			start := time.Now()
+			err := pinger.Ping()
+			health := DatastoreHealth{
+				Status:    HealthUp,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				health.Status = HealthDown
+				health.ErrMsg = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Datastores[dsName] = health
+			if err != nil {
+				resp.Status = "Error"
+			}
+		}(dsName)
+	}
+	wg.Wait()
+	return resp
+}
@@ -0,0 +1,152 @@
+package network
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The defaults of HotKeyConfig.
+const (
+	DefaultHotKeySampleRate = 0.01
+	DefaultHotKeyTopK       = 20
+)
+
+// hotKeyCapacityFactor is how many keys are counted per key reported,
+// so that a hot key is not evicted by the cold keys seen in between.
+const hotKeyCapacityFactor = 10
+
+// HotKeyConfig describes the tracking of the keys the executor
+// prepares and commits most often.
+type HotKeyConfig struct {
+	// Enabled turns the tracking on, it is off unless set.
+	Enabled bool `yaml:"enabled"`
+	// SampleRate is the fraction of the keys counted,
+	// DefaultHotKeySampleRate if unset.
+	SampleRate float64 `yaml:"sample_rate"`
+	// TopK is how many keys are reported, DefaultHotKeyTopK if unset.
+	TopK int `yaml:"top_k"`
+	// Seed seeds the sampling, which differs at every run if unset.
+	Seed int64 `yaml:"seed"`
+}
+
+// Validate checks that the keys can be tracked as described by c.
+func (c HotKeyConfig) Validate() error {
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("sample rate %v is out of [0, 1]", c.SampleRate)
+	}
+	if c.TopK < 0 {
+		return fmt.Errorf("top k %d is negative", c.TopK)
+	}
+	return nil
+}
+
+// HotKey is a key with the estimated number of times it has been seen.
+type HotKey struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// StatsResponse is the response of the /stats endpoint of the executor.
+type StatsResponse struct {
+	// HotKeys are the keys seen most often, hottest first,
+	// empty unless the tracking is enabled.
+	HotKeys []HotKey `json:"hot_keys"`
+}
+
+// HotKeys tracks the keys seen most often in a sample of the keys,
+// with the Space-Saving algorithm: at most a fixed number of keys are
+// counted, and a key seen while all of them are taken replaces the key
+// with the lowest count, inheriting it. The counts of the hot keys are
+// thus overestimated by at most the count of the keys they replaced.
+//
+// A nil *HotKeys tracks nothing.
+type HotKeys struct {
+	rate     float64
+	topK     int
+	capacity int
+
+	mu     sync.Mutex
+	r      *rand.Rand
+	counts map[string]int64
+}
+
+// NewHotKeys returns a tracker as described by cfg,
+// nil unless cfg.Enabled is set.
+func NewHotKeys(cfg HotKeyConfig) *HotKeys {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = DefaultHotKeySampleRate
+	}
+	if cfg.TopK <= 0 {
+		cfg.TopK = DefaultHotKeyTopK
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &HotKeys{
+		rate:     cfg.SampleRate,
+		topK:     cfg.TopK,
+		capacity: cfg.TopK * hotKeyCapacityFactor,
+		r:        rand.New(rand.NewSource(seed)),
+		counts:   make(map[string]int64, cfg.TopK*hotKeyCapacityFactor),
+	}
+}
+
+// Observe counts the keys of dsName if they are sampled.
+func (h *HotKeys) Observe(dsName string, keys ...string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range keys {
+		if h.rate < 1 && h.r.Float64() >= h.rate {
+			continue
+		}
+		name := dsName + ":" + key
+		if _, ok := h.counts[name]; ok || len(h.counts) < h.capacity {
+			h.counts[name]++
+			continue
+		}
+		coldest, lowest := "", int64(-1)
+		for k, count := range h.counts {
+			if lowest < 0 || count < lowest {
+				coldest, lowest = k, count
+			}
+		}
+		delete(h.counts, coldest)
+		h.counts[name] = lowest + 1
+	}
+}
+
+// Top returns the hottest keys, named "dsName:key", hottest first,
+// with their counts scaled up by the sample rate.
+func (h *HotKeys) Top() []HotKey {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	hot := make([]HotKey, 0, len(h.counts))
+	for key, count := range h.counts {
+		hot = append(hot, HotKey{Key: key, Count: int64(float64(count) / h.rate)})
+	}
+	h.mu.Unlock()
+
+	slices.SortFunc(hot, func(a, b HotKey) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	if len(hot) > h.topK {
+		hot = hot[:h.topK]
+	}
+	return hot
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotKeysSkewed(t *testing.T) {
+	h := NewHotKeys(HotKeyConfig{Enabled: true, SampleRate: 0.2, TopK: 5, Seed: 1})
+	const n = 200000
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.2, 1, 9999)
+	hottest := 0
+	for i := 0; i < n; i++ {
+		k := zipf.Uint64()
+		if k == 0 {
+			hottest++
+		}
+		h.Observe("Redis", fmt.Sprintf("key%d", k))
+	}
+
+	top := h.Top()
+	assert.Len(t, top, 5)
+	for i, hot := range top {
+		assert.Equal(t, fmt.Sprintf("Redis:key%d", i), hot.Key)
+	}
+	// the count of the hottest key is estimated from the sample
+	assert.InEpsilon(t, hottest, top[0].Count, 0.05)
+	assert.Greater(t, top[0].Count, top[1].Count)
+}
+
+func TestHotKeysDisabled(t *testing.T) {
+	h := NewHotKeys(HotKeyConfig{SampleRate: 1})
+	assert.Nil(t, h)
+	h.Observe("Redis", "key1")
+	assert.Empty(t, h.Top())
+
+	h = NewHotKeys(HotKeyConfig{Enabled: true, SampleRate: 1, TopK: 2})
+	h.Observe("Redis", "key1", "key2", "key1")
+	h.Observe("MongoDB", "key1")
+	assert.Equal(t, []HotKey{{Key: "Redis:key1", Count: 2}, {Key: "MongoDB:key1", Count: 1}}, h.Top())
+}
+
+func TestHotKeyConfigValidate(t *testing.T) {
+	assert.NoError(t, HotKeyConfig{}.Validate())
+	assert.Error(t, HotKeyConfig{SampleRate: 1.5}.Validate())
+	assert.Error(t, HotKeyConfig{TopK: -1}.Validate())
+}
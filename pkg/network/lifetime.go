@@ -0,0 +1,94 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/timesource"
+)
+
+// ErrTxnTooOld is returned by TxnLifetime.Check for a transaction
+// started longer than the max lifetime ago.
+var ErrTxnTooOld = errors.New("transaction is older than the max lifetime")
+
+// lifetimeSamplesPerPeriod is how many times the time source is sampled
+// per max lifetime, at most. The lifetime enforced is at most 1/10th
+// longer than the max.
+const lifetimeSamplesPerPeriod = 10
+
+// TxnLifetime rejects the transactions started longer than a max
+// lifetime ago, whose clients are stale, e.g. have vanished after
+// preparing some records and come back.
+//
+// The start times are compared to the timestamps returned by the time
+// source, whatever their unit: the time source is sampled as the requests
+// come, and a start time is too old if it is older than the sample taken
+// the max lifetime ago. Nothing is rejected until the executor has been
+// sampling the time source for the max lifetime.
+type TxnLifetime struct {
+	max        time.Duration
+	timeSource timesource.TimeSourcer
+
+	mu sync.Mutex
+	// samples are the timestamps returned by the time source, oldest first.
+	samples []timeSample
+}
+
+type timeSample struct {
+	at        time.Time
+	timestamp int64
+}
+
+// NewTxnLifetime returns a TxnLifetime rejecting the transactions
+// started longer than max ago according to timeSource.
+func NewTxnLifetime(max time.Duration, timeSource timesource.TimeSourcer) *TxnLifetime {
+	return &TxnLifetime{max: max, timeSource: timeSource}
+}
+
+// Max returns the max lifetime of the transactions.
+func (l *TxnLifetime) Max() time.Duration {
+	return l.max
+}
+
+// now returns the time of the time source if it is a timesource.Clock.
+func (l *TxnLifetime) now() time.Time {
+	if clock, ok := l.timeSource.(timesource.Clock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
+// Check returns ErrTxnTooOld if a transaction with startTime has
+// started longer than the max lifetime ago.
+func (l *TxnLifetime) Check(startTime int64) error {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n := len(l.samples); n == 0 || now.Sub(l.samples[n-1].at) >= l.max/lifetimeSamplesPerPeriod {
+		// an unavailable time source only delays the next sample
+		if timestamp, err := l.timeSource.GetTime("lifetime"); err == nil {
+			l.samples = append(l.samples, timeSample{at: now, timestamp: timestamp})
+		}
+	}
+
+	// the newest sample taken at least the max lifetime ago
+	cutoff := -1
+	for i, sample := range l.samples {
+		if now.Sub(sample.at) < l.max {
+			break
+		}
+		cutoff = i
+	}
+	if cutoff < 0 {
+		return nil
+	}
+	l.samples = l.samples[cutoff:]
+	if startTime < l.samples[0].timestamp {
+		return fmt.Errorf("%w: start time %d is before %d, the time %v ago",
+			ErrTxnTooOld, startTime, l.samples[0].timestamp, l.max)
+	}
+	return nil
+}
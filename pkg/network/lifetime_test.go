@@ -0,0 +1,46 @@
+package network
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/timesource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxnLifetime(t *testing.T) {
+	clock := timesource.NewFrozenTimeSource(time.Unix(1700000000, 0))
+	l := NewTxnLifetime(time.Second, clock)
+	started, _ := clock.GetTime("start")
+
+	// nothing is rejected before the time source has been sampled for a lifetime
+	assert.NoError(t, l.Check(0))
+	clock.Advance(500 * time.Millisecond)
+	assert.NoError(t, l.Check(started-1))
+
+	clock.Advance(600 * time.Millisecond)
+	assert.ErrorIs(t, l.Check(started-1), ErrTxnTooOld)
+	assert.NoError(t, l.Check(started))
+
+	// the cutoff follows the time source
+	clock.Advance(time.Second)
+	assert.ErrorIs(t, l.Check(started), ErrTxnTooOld)
+	recent := started + (1100 * time.Millisecond).Microseconds()
+	assert.NoError(t, l.Check(recent))
+	assert.Len(t, l.samples, 2)
+}
+
+// failingTimeSource is a time source that is unavailable.
+type failingTimeSource struct{}
+
+func (failingTimeSource) GetTime(string) (int64, error) {
+	return 0, errors.New("time oracle is down")
+}
+
+func TestTxnLifetimeTimeSourceDown(t *testing.T) {
+	l := NewTxnLifetime(time.Millisecond, failingTimeSource{})
+	assert.NoError(t, l.Check(0))
+	time.Sleep(2 * time.Millisecond)
+	assert.NoError(t, l.Check(0))
+}
@@ -0,0 +1,33 @@
+package network
+
+import (
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RetryAfterSeconds is the Retry-After value sent along with
+// a 503 response when the executor is overloaded.
+const RetryAfterSeconds = 1
+
+// LimitInFlight wraps handler so that at most maxInFlight requests are
+// handled at the same time. Excess requests are rejected immediately with
+// 503 Service Unavailable instead of being queued.
+// A non-positive maxInFlight disables the limit.
+func LimitInFlight(handler fasthttp.RequestHandler, maxInFlight int) fasthttp.RequestHandler {
+	if maxInFlight <= 0 {
+		return handler
+	}
+	sem := make(chan struct{}, maxInFlight)
+	return func(ctx *fasthttp.RequestCtx) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			handler(ctx)
+		default:
+			// ctx.Error resets the response headers, so Retry-After is set afterwards
+			ctx.Error("executor is overloaded", fasthttp.StatusServiceUnavailable)
+			ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, strconv.Itoa(RetryAfterSeconds))
+		}
+	}
+}
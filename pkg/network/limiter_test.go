@@ -0,0 +1,108 @@
+package network
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	trxn "github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestLimitInFlight(t *testing.T) {
+	release := make(chan struct{})
+	var handled atomic.Int32
+	slowHandler := func(ctx *fasthttp.RequestCtx) {
+		handled.Add(1)
+		<-release
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	}
+	addr := startTestServer(t, LimitInFlight(slowHandler, 2))
+
+	// saturate the limiter
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, _, err := fasthttp.Get(nil, addr)
+			assert.NoError(t, err)
+			assert.Equal(t, fasthttp.StatusOK, code)
+		}()
+	}
+	assert.Eventually(t, func() bool { return handled.Load() == 2 },
+		time.Second, 5*time.Millisecond)
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(addr)
+	err := fasthttp.Do(req, resp)
+	assert.NoError(t, err)
+	assert.Equal(t, fasthttp.StatusServiceUnavailable, resp.StatusCode())
+	assert.Equal(t, "1", string(resp.Header.Peek(fasthttp.HeaderRetryAfter)))
+	assert.Equal(t, int32(2), handled.Load())
+
+	close(release)
+	wg.Wait()
+}
+
+func TestClientRetriesOverloadedExecutor(t *testing.T) {
+	okBody, _ := config.Config.Codec.Serialize(Response[string]{Status: "OK"})
+
+	t.Run("succeeds once the executor recovers", func(t *testing.T) {
+		var calls atomic.Int32
+		addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+			if calls.Add(1) <= 2 {
+				ctx.Response.Header.Set(fasthttp.HeaderRetryAfter, "1")
+				ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+				return
+			}
+			ctx.SetBody(okBody)
+		})
+		client := NewClientWithOptions(map[string][]string{ALL: {addr}}, ClientOptions{
+			RetryBackoff: time.Millisecond,
+		})
+
+		_, err := client.Commit("redis1", []trxn.CommitInfo{}, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("gives up after the max retries", func(t *testing.T) {
+		var calls atomic.Int32
+		addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+			calls.Add(1)
+			ctx.SetStatusCode(fasthttp.StatusServiceUnavailable)
+		})
+		client := NewClientWithOptions(map[string][]string{ALL: {addr}}, ClientOptions{
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+		})
+
+		_, err := client.Commit("redis1", []trxn.CommitInfo{}, 0)
+		assert.EqualError(t, err, "executor is overloaded after 2 retries")
+		assert.ErrorIs(t, err, ErrOverloaded)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("retries the requests rejected as too many", func(t *testing.T) {
+		var calls atomic.Int32
+		addr := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+			calls.Add(1)
+			ctx.SetStatusCode(fasthttp.StatusTooManyRequests)
+		})
+		client := NewClientWithOptions(map[string][]string{ALL: {addr}}, ClientOptions{
+			MaxRetries:   1,
+			RetryBackoff: time.Millisecond,
+		})
+
+		_, err := client.Commit("redis1", []trxn.CommitInfo{}, 0)
+		assert.ErrorIs(t, err, ErrOverloaded)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
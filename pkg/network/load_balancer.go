@@ -0,0 +1,196 @@
+package network
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+)
+
+// LoadBalancer selects the executor that a request is sent to.
+// Implementations must be safe for concurrent use.
+type LoadBalancer interface {
+	// Next returns the address of the executor for a request on key.
+	// key is empty if the request does not target a single record.
+	Next(key string) string
+
+	// MarkDown excludes addr from the selection until it is marked up again.
+	MarkDown(addr string)
+
+	// MarkUp puts addr back into the selection.
+	MarkUp(addr string)
+}
+
+// RequestTracker is implemented by load balancers that need to know
+// when a request sent to the selected executor has finished.
+type RequestTracker interface {
+	Done(addr string)
+}
+
+// LoadBalancerFactory creates a LoadBalancer over the given executor addresses.
+type LoadBalancerFactory func(addrs []string) LoadBalancer
+
+var (
+	_ LoadBalancer   = (*RoundRobinBalancer)(nil)
+	_ LoadBalancer   = (*RandomBalancer)(nil)
+	_ LoadBalancer   = (*LeastConnectionsBalancer)(nil)
+	_ RequestTracker = (*LeastConnectionsBalancer)(nil)
+	_ LoadBalancer   = (*KeyAffinityBalancer)(nil)
+)
+
+// addrSet keeps the executor addresses and the ones marked down.
+// The caller must hold mu when calling the lowercase methods.
+type addrSet struct {
+	mu    sync.Mutex
+	addrs []string
+	down  map[string]bool
+}
+
+func newAddrSet(addrs []string) addrSet {
+	return addrSet{
+		addrs: addrs,
+		down:  make(map[string]bool),
+	}
+}
+
+func (s *addrSet) MarkDown(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down[addr] = true
+}
+
+func (s *addrSet) MarkUp(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.down, addr)
+}
+
+// available returns the addresses that are not marked down.
+// If every address is down, all of them are returned so that
+// requests still have somewhere to go.
+func (s *addrSet) available() []string {
+	if len(s.down) == 0 {
+		return s.addrs
+	}
+	res := make([]string, 0, len(s.addrs))
+	for _, addr := range s.addrs {
+		if !s.down[addr] {
+			res = append(res, addr)
+		}
+	}
+	if len(res) == 0 {
+		return s.addrs
+	}
+	return res
+}
+
+// RoundRobinBalancer cycles through the executors in order.
+type RoundRobinBalancer struct {
+	addrSet
+	curIndex int
+}
+
+func NewRoundRobinBalancer(addrs []string) LoadBalancer {
+	return &RoundRobinBalancer{addrSet: newAddrSet(addrs)}
+}
+
+func (b *RoundRobinBalancer) Next(key string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := 0; i < len(b.addrs); i++ {
+		addr := b.addrs[b.curIndex%len(b.addrs)]
+		b.curIndex = (b.curIndex + 1) % len(b.addrs)
+		if !b.down[addr] {
+			return addr
+		}
+	}
+	// every executor is down
+	addr := b.addrs[b.curIndex]
+	b.curIndex = (b.curIndex + 1) % len(b.addrs)
+	return addr
+}
+
+// RandomBalancer picks an executor uniformly at random.
+type RandomBalancer struct {
+	addrSet
+}
+
+func NewRandomBalancer(addrs []string) LoadBalancer {
+	return &RandomBalancer{addrSet: newAddrSet(addrs)}
+}
+
+func (b *RandomBalancer) Next(key string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	available := b.available()
+	return available[rand.Intn(len(available))]
+}
+
+// LeastConnectionsBalancer picks the executor with the fewest outstanding requests.
+// Ties are broken by the order of the addresses.
+type LeastConnectionsBalancer struct {
+	addrSet
+	inflight map[string]int
+}
+
+func NewLeastConnectionsBalancer(addrs []string) LoadBalancer {
+	return &LeastConnectionsBalancer{
+		addrSet:  newAddrSet(addrs),
+		inflight: make(map[string]int),
+	}
+}
+
+func (b *LeastConnectionsBalancer) Next(key string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	available := b.available()
+	res := available[0]
+	for _, addr := range available[1:] {
+		if b.inflight[addr] < b.inflight[res] {
+			res = addr
+		}
+	}
+	b.inflight[res]++
+	return res
+}
+
+// Done marks a request sent to addr as finished.
+func (b *LeastConnectionsBalancer) Done(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inflight[addr] > 0 {
+		b.inflight[addr]--
+	}
+}
+
+// KeyAffinityBalancer sends the requests on the same key to the same executor.
+// If that executor is down, the next available one in order is used.
+// Requests without a key are distributed in a round-robin manner.
+type KeyAffinityBalancer struct {
+	addrSet
+	curIndex int
+}
+
+func NewKeyAffinityBalancer(addrs []string) LoadBalancer {
+	return &KeyAffinityBalancer{addrSet: newAddrSet(addrs)}
+}
+
+func (b *KeyAffinityBalancer) Next(key string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	available := b.available()
+	if key == "" {
+		b.curIndex = (b.curIndex + 1) % len(available)
+		return available[b.curIndex]
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	start := int(h.Sum32() % uint32(len(b.addrs)))
+	for i := 0; i < len(b.addrs); i++ {
+		addr := b.addrs[(start+i)%len(b.addrs)]
+		if !b.down[addr] {
+			return addr
+		}
+	}
+	return b.addrs[start]
+}
@@ -0,0 +1,102 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testAddrs = []string{"http://a", "http://b", "http://c"}
+
+func TestRoundRobinBalancer(t *testing.T) {
+	b := NewRoundRobinBalancer(testAddrs)
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, testAddrs[i%3], b.Next(""))
+	}
+
+	t.Run("skip the executor marked down", func(t *testing.T) {
+		b := NewRoundRobinBalancer(testAddrs)
+		b.MarkDown("http://b")
+		res := []string{b.Next(""), b.Next(""), b.Next(""), b.Next("")}
+		assert.Equal(t, []string{"http://a", "http://c", "http://a", "http://c"}, res)
+
+		// the cycle goes on from a and no longer skips b
+		b.MarkUp("http://b")
+		assert.Equal(t, []string{"http://a", "http://b"}, []string{b.Next(""), b.Next("")})
+	})
+
+	t.Run("all executors are down", func(t *testing.T) {
+		b := NewRoundRobinBalancer(testAddrs)
+		for _, addr := range testAddrs {
+			b.MarkDown(addr)
+		}
+		assert.Contains(t, testAddrs, b.Next(""))
+	})
+}
+
+func TestRandomBalancer(t *testing.T) {
+	b := NewRandomBalancer(testAddrs)
+	b.MarkDown("http://a")
+	seen := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		seen[b.Next("")]++
+	}
+	assert.Zero(t, seen["http://a"])
+	assert.NotZero(t, seen["http://b"])
+	assert.NotZero(t, seen["http://c"])
+}
+
+func TestLeastConnectionsBalancer(t *testing.T) {
+	b := NewLeastConnectionsBalancer(testAddrs)
+	tracker := b.(RequestTracker)
+
+	// every executor gets one outstanding request
+	assert.Equal(t, "http://a", b.Next(""))
+	assert.Equal(t, "http://b", b.Next(""))
+	assert.Equal(t, "http://c", b.Next(""))
+
+	// b becomes the least loaded one
+	tracker.Done("http://b")
+	assert.Equal(t, "http://b", b.Next(""))
+
+	tracker.Done("http://c")
+	b.MarkDown("http://c")
+	assert.Equal(t, "http://a", b.Next(""))
+}
+
+func TestKeyAffinityBalancer(t *testing.T) {
+	b := NewKeyAffinityBalancer(testAddrs)
+
+	addr := b.Next("item1")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, addr, b.Next("item1"))
+	}
+
+	b.MarkDown(addr)
+	fallback := b.Next("item1")
+	assert.NotEqual(t, addr, fallback)
+	assert.Equal(t, fallback, b.Next("item1"))
+
+	b.MarkUp(addr)
+	assert.Equal(t, addr, b.Next("item1"))
+
+	t.Run("requests without a key are spread", func(t *testing.T) {
+		seen := make(map[string]bool)
+		for i := 0; i < 3; i++ {
+			seen[b.Next("")] = true
+		}
+		assert.Len(t, seen, 3)
+	})
+}
+
+func TestClientUsesLoadBalancer(t *testing.T) {
+	client := NewClientWithOptions(map[string][]string{ALL: testAddrs}, ClientOptions{
+		LoadBalancer: NewKeyAffinityBalancer,
+	})
+	_, ok := client.GetLoadBalancer("redis1").(*KeyAffinityBalancer)
+	assert.True(t, ok)
+
+	client = NewClient(map[string][]string{"redis1": testAddrs})
+	assert.Equal(t, "http://a", client.GetServerAddr("redis1"))
+	assert.Equal(t, "http://b", client.GetServerAddr("redis1"))
+}
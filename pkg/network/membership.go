@@ -0,0 +1,116 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultHeartbeatInterval is how often an executor registers
+// itself with the seed executor.
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// RegisterRequest announces the executor at Addr, serving the datastores
+// DsNames, to the seed executor. It is sent again every heartbeat.
+type RegisterRequest struct {
+	Addr    string
+	DsNames []string
+}
+
+// Membership holds the executors that have registered with the seed
+// executor, which /peers lists. An executor is dropped once it has not
+// registered again for the TTL, so that the clients stop sending their
+// requests to the executors that are gone.
+type Membership struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	members map[string]member
+}
+
+type member struct {
+	dsNames []string
+	expires time.Time
+}
+
+// NewMembership returns a membership dropping an executor ttl after its
+// last registration, three heartbeats if ttl is not positive.
+func NewMembership(ttl time.Duration) *Membership {
+	if ttl <= 0 {
+		ttl = 3 * DefaultHeartbeatInterval
+	}
+	return &Membership{
+		ttl:     ttl,
+		members: make(map[string]member),
+	}
+}
+
+// Register adds the executor at addr serving dsNames,
+// or renews it if it has already registered.
+func (m *Membership) Register(addr string, dsNames []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members[addr] = member{
+		dsNames: slices.Clone(dsNames),
+		expires: time.Now().Add(m.ttl),
+	}
+}
+
+// Peers returns the addresses of the live executors of each datastore,
+// keyed like the executor address map of a client.
+func (m *Membership) Peers() map[string][]string {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	peers := make(map[string][]string)
+	for addr, mb := range m.members {
+		if now.After(mb.expires) {
+			delete(m.members, addr)
+			continue
+		}
+		for _, dsName := range mb.dsNames {
+			peers[dsName] = append(peers[dsName], addr)
+		}
+	}
+	// the clients only rebuild the load balancers of the lists that change
+	for _, addrs := range peers {
+		slices.Sort(addrs)
+	}
+	return peers
+}
+
+// Register announces the executor described by req
+// to the /register endpoint of the seed executor at seedAddr.
+func Register(seedAddr string, req RegisterRequest, timeout time.Duration) error {
+	body, err := config.Config.Codec.Serialize(req)
+	if err != nil {
+		return err
+	}
+	httpReq := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(httpReq)
+	httpReq.SetRequestURI(seedAddr + "/register")
+	httpReq.Header.SetMethod(fasthttp.MethodPost)
+	httpReq.Header.SetContentType("application/json")
+	httpReq.SetBody(body)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	if err := fasthttp.DoTimeout(httpReq, resp, timeout); err != nil {
+		return err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode(), seedAddr)
+	}
+	var response Response[string]
+	if err := config.Config.Codec.Deserialize(resp.Body(), &response); err != nil {
+		return err
+	}
+	if response.Status != "OK" {
+		return errors.New(response.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestMembershipPeers(t *testing.T) {
+	m := NewMembership(50 * time.Millisecond)
+	assert.Empty(t, m.Peers())
+
+	m.Register("http://b", []string{"redis1", "mongo1"})
+	m.Register("http://a", []string{"redis1"})
+	assert.Equal(t, map[string][]string{
+		"redis1": {"http://a", "http://b"},
+		"mongo1": {"http://b"},
+	}, m.Peers())
+
+	// an executor not registering again is dropped after the TTL
+	time.Sleep(30 * time.Millisecond)
+	m.Register("http://a", []string{"redis1"})
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, map[string][]string{"redis1": {"http://a"}}, m.Peers())
+}
+
+func TestClientDiscoversRegisteredExecutors(t *testing.T) {
+	// a seed serving the executors registered with it
+	m := NewMembership(time.Minute)
+	seed := startTestServer(t, func(ctx *fasthttp.RequestCtx) {
+		switch string(ctx.Path()) {
+		case "/register":
+			var req RegisterRequest
+			if !DecodeRequest(ctx, "register", &req) {
+				return
+			}
+			m.Register(req.Addr, req.DsNames)
+			WriteResponse(ctx, Response[string]{Status: "OK"})
+		case "/peers":
+			WriteResponse(ctx, PeersResponse{Status: "OK", Peers: m.Peers()})
+		}
+	})
+
+	assert.NoError(t, Register(seed, RegisterRequest{Addr: "http://a", DsNames: []string{"redis1"}}, time.Second))
+	client := NewClientWithOptions(map[string][]string{ALL: {"http://static"}}, ClientOptions{
+		SeedAddr:            seed,
+		PeerRefreshInterval: 10 * time.Millisecond,
+	})
+	defer client.Close()
+	assert.Equal(t, map[string][]string{"redis1": {"http://a"}}, client.Executors())
+
+	// an executor joining is picked up on the next refresh
+	assert.NoError(t, Register(seed, RegisterRequest{Addr: "http://b", DsNames: []string{"redis1"}}, time.Second))
+	assert.Eventually(t, func() bool {
+		return len(rotation(client, "redis1", 4)) == 2
+	}, time.Second, 10*time.Millisecond)
+}
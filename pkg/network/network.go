@@ -1,19 +1,66 @@
 package network
 
 import (
+	"encoding/json"
 	"fmt"
 
-	jsoniter "github.com/json-iterator/go"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/cassandra"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/couchdb"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/dynamodb"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/mongo"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
-	"github.com/oreo-dtx-lab/oreo/pkg/datastore/tikv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
 	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+
+	// the built-in datastores register their item factories
+	_ "github.com/oreo-dtx-lab/oreo/pkg/datastore/cassandra"
+	_ "github.com/oreo-dtx-lab/oreo/pkg/datastore/couchdb"
+	_ "github.com/oreo-dtx-lab/oreo/pkg/datastore/dynamodb"
+	_ "github.com/oreo-dtx-lab/oreo/pkg/datastore/mongo"
+	_ "github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	_ "github.com/oreo-dtx-lab/oreo/pkg/datastore/tikv"
 )
 
-var json2 = jsoniter.ConfigCompatibleWithStandardLibrary
+// defaultDatastores are the datastore names used by the benchmarks.
+// Other datastores are registered with txn.RegisterDatastore at startup.
+var defaultDatastores = map[string]txn.ItemType{
+	"redis1":    txn.RedisItem,
+	"Redis":     txn.RedisItem,
+	"KVRocks":   txn.RedisItem,
+	"mongo1":    txn.MongoItem,
+	"mongo2":    txn.MongoItem,
+	"MongoDB":   txn.MongoItem,
+	"MongoDB1":  txn.MongoItem,
+	"MongoDB2":  txn.MongoItem,
+	"CouchDB":   txn.CouchItem,
+	"Cassandra": txn.CassandraItem,
+	"DynamoDB":  txn.DynamoDBItem,
+	"TiKV":      txn.TiKVItem,
+}
+
+func init() {
+	for dsName, itemType := range defaultDatastores {
+		txn.RegisterDatastore(dsName, itemType)
+	}
+}
+
+// CodecFromName returns the codec that encodes the messages exchanged
+// between the client and the executor.
+// Both codecs honor the custom UnmarshalJSON methods below.
+func CodecFromName(name string) (serializer.Serializer, error) {
+	return NewCodec(name, false)
+}
+
+// NewCodec returns the codec named name, decoding the numbers it finds
+// in place of an interface value as json.Number if useNumber is set.
+// The timestamps and versions of the messages are typed fields,
+// which both codecs decode exactly whatever useNumber.
+func NewCodec(name string, useNumber bool) (serializer.Serializer, error) {
+	switch name {
+	case "json":
+		return &serializer.JSONSerializer{UseNumber: useNumber}, nil
+	case "", "jsoniter":
+		return &serializer.JSON2Serializer{UseNumber: useNumber}, nil
+	default:
+		return nil, fmt.Errorf("unsupported codec %q, expect json or jsoniter", name)
+	}
+}
 
 type Response[T any] struct {
 	Status string
@@ -35,6 +82,12 @@ type ReadRequest struct {
 	Key       string
 	StartTime int64
 	Config    txn.RecordConfig
+	// Fields are the fields the value of the record is projected on
+	// before it is sent back. The whole value is sent back if empty.
+	Fields []string
+	// ReadTs is the timestamp the record is read as of, with the
+	// versions committed at ReadTs, as of StartTime if zero.
+	ReadTs int64
 }
 
 type PrepareRequest struct {
@@ -46,19 +99,66 @@ type PrepareRequest struct {
 	Config        txn.RecordConfig
 }
 
+// Validate checks that the declared ItemType is the one registered for DsName,
+// so that a client and an executor with different datastore maps fail fast
+// instead of preparing items of the wrong type.
+func (p *PrepareRequest) Validate() error {
+	expected := txn.ItemTypeOf(p.DsName)
+	if expected == txn.NoneItem {
+		return fmt.Errorf("datastore %s is not registered", p.DsName)
+	}
+	if p.ItemType != txn.NoneItem && p.ItemType != expected {
+		return fmt.Errorf("item type %s does not match datastore %s, expect %s",
+			p.ItemType, p.DsName, expected)
+	}
+	return nil
+}
+
 type PrepareResponse struct {
-	Status  string
-	ErrMsg  string
+	Status string
+	ErrMsg string
+	// ErrCode identifies the error of txn the prepare has failed with,
+	// e.g. a conflict, as returned by ErrCode.
+	ErrCode string
 	TCommit int64
 	VerMap  map[string]string
 }
 
+// PrepareBatchRequest carries the prepare requests of several datastores
+// served by the same executor, keyed by the datastore names.
+type PrepareBatchRequest struct {
+	Requests map[string]PrepareRequest
+}
+
+// PrepareBatchResponse carries the outcome of each request of a
+// PrepareBatchRequest. Status is not OK only if the batch as a whole
+// could not be handled.
+type PrepareBatchResponse struct {
+	Status    string
+	ErrMsg    string
+	Responses map[string]PrepareResponse
+}
+
 type CommitRequest struct {
 	DsName  string
 	List    []txn.CommitInfo
 	TCommit int64
 }
 
+// CommitResponse carries the timestamp the records are committed with.
+type CommitResponse struct {
+	Status  string
+	ErrMsg  string
+	TCommit int64
+}
+
+// RenewLeaseRequest asks to extend the leases of the records of List
+// in the datastore DsName, prepared with their versions.
+type RenewLeaseRequest struct {
+	DsName string
+	List   []txn.CommitInfo
+}
+
 type AbortRequest struct {
 	DsName  string
 	KeyList []string
@@ -66,17 +166,38 @@ type AbortRequest struct {
 	GroupKeyList string
 }
 
+// AbortResponse carries the number of records rolled back.
+type AbortResponse struct {
+	Status     string
+	ErrMsg     string
+	RolledBack int
+}
+
+// AbortGroupRequest asks to roll back every record of the datastore DsName
+// tagged with GroupKey, the TSR url of the transaction.
+type AbortGroupRequest struct {
+	DsName   string
+	GroupKey string
+}
+
+// TSRRequest asks for the state of the TSR of txnId in the datastore dsName.
+type TSRRequest struct {
+	DsName string
+	TxnId  string
+}
+
 func (r *ReadResponse) UnmarshalJSON(data []byte) error {
 	type TempResponse struct {
 		Status       string
 		ErrMsg       string
 		DataStrategy txn.RemoteDataStrategy
-		ItemType     txn.ItemType        `json:"ItemType"`
-		Data         jsoniter.RawMessage `json:"Data"`
+		ItemType     txn.ItemType    `json:"ItemType"`
+		Data         json.RawMessage `json:"Data"`
+		GroupKey     string
 	}
 
 	var aux TempResponse
-	if err := json2.Unmarshal(data, &aux); err != nil {
+	if err := config.Config.Codec.Deserialize(data, &aux); err != nil {
 		return fmt.Errorf("failed to unmarshal basic fields: %v", err)
 	}
 
@@ -84,50 +205,17 @@ func (r *ReadResponse) UnmarshalJSON(data []byte) error {
 	r.ErrMsg = aux.ErrMsg
 	r.DataStrategy = aux.DataStrategy
 	r.ItemType = aux.ItemType
+	r.GroupKey = aux.GroupKey
 
-	switch r.ItemType {
-	case txn.RedisItem:
-		var redisItem redis.RedisItem
-		if err := json2.Unmarshal(aux.Data, &redisItem); err != nil {
-			return err
-		}
-		r.Data = &redisItem
-	case txn.MongoItem:
-		var mongoItem mongo.MongoItem
-		if err := json2.Unmarshal(aux.Data, &mongoItem); err != nil {
-			return err
-		}
-		r.Data = &mongoItem
-	case txn.CouchItem:
-		var couchItem couchdb.CouchDBItem
-		if err := json2.Unmarshal(aux.Data, &couchItem); err != nil {
-			return err
-		}
-		r.Data = &couchItem
-	case txn.CassandraItem:
-		var cassandraItem cassandra.CassandraItem
-		if err := json2.Unmarshal(aux.Data, &cassandraItem); err != nil {
-			return err
-		}
-		r.Data = &cassandraItem
-	case txn.DynamoDBItem:
-		var dynamoDBItem dynamodb.DynamoDBItem
-		if err := json2.Unmarshal(aux.Data, &dynamoDBItem); err != nil {
-			return err
-		}
-		r.Data = &dynamoDBItem
-	case txn.TiKVItem:
-		var tikvItem tikv.TiKVItem
-		if err := json2.Unmarshal(aux.Data, &tikvItem); err != nil {
-			return err
-		}
-		r.Data = &tikvItem
-	case txn.NoneItem:
+	if r.ItemType == txn.NoneItem {
 		r.Data = nil
-	default:
-		return fmt.Errorf("[network.go - ReadResponse] unsupported data type: %v", r.ItemType)
+		return nil
 	}
-
+	item, err := decodeItem(r.ItemType, aux.Data)
+	if err != nil {
+		return fmt.Errorf("[network.go - ReadResponse] %v", err)
+	}
+	r.Data = item
 	return nil
 }
 
@@ -139,11 +227,11 @@ func (p *PrepareRequest) UnmarshalJSON(data []byte) error {
 		ItemType      txn.ItemType                 `json:"ItemType"`
 		StartTime     int64                        `json:"StartTime"`
 		Config        txn.RecordConfig             `json:"Config"`
-		ItemList      jsoniter.RawMessage          `json:"ItemList"`
+		ItemList      json.RawMessage              `json:"ItemList"`
 	}
 
 	var aux TempRequest
-	if err := json2.Unmarshal(data, &aux); err != nil {
+	if err := config.Config.Codec.Deserialize(data, &aux); err != nil {
 		return fmt.Errorf("failed to unmarshal basic fields: %v", err)
 	}
 
@@ -156,93 +244,40 @@ func (p *PrepareRequest) UnmarshalJSON(data []byte) error {
 	// fmt.Printf("Item Type: %v\n", p.ItemType)
 	// fmt.Printf("Item List: %v\n", string(aux.ItemList))
 
-	switch p.ItemType {
-	case txn.RedisItem:
-		var redisItemList []redis.RedisItem
-		if err := json2.Unmarshal(aux.ItemList, &redisItemList); err != nil {
-			return err
-		}
-		p.ItemList = make([]txn.DataItem, len(redisItemList))
-		for i, it := range redisItemList {
-			item := it
-			p.ItemList[i] = &item
-		}
-	case txn.MongoItem:
-		var mongoItemList []mongo.MongoItem
-		if err := json2.Unmarshal(aux.ItemList, &mongoItemList); err != nil {
-			return err
-		}
-		p.ItemList = make([]txn.DataItem, len(mongoItemList))
-		for i, it := range mongoItemList {
-			item := it
-			p.ItemList[i] = &item
-		}
-	case txn.CouchItem:
-		var couchItemList []couchdb.CouchDBItem
-		if err := json2.Unmarshal(aux.ItemList, &couchItemList); err != nil {
-			return err
-		}
-		p.ItemList = make([]txn.DataItem, len(couchItemList))
-		for i, it := range couchItemList {
-			item := it
-			p.ItemList[i] = &item
-		}
-	case txn.CassandraItem:
-		var cassandraItemList []cassandra.CassandraItem
-		if err := json2.Unmarshal(aux.ItemList, &cassandraItemList); err != nil {
-			return err
-		}
-		p.ItemList = make([]txn.DataItem, len(cassandraItemList))
-		for i, it := range cassandraItemList {
-			item := it
-			p.ItemList[i] = &item
-		}
-	case txn.DynamoDBItem:
-		var dynamoDBItemList []dynamodb.DynamoDBItem
-		if err := json2.Unmarshal(aux.ItemList, &dynamoDBItemList); err != nil {
-			return err
-		}
-		p.ItemList = make([]txn.DataItem, len(dynamoDBItemList))
-		for i, it := range dynamoDBItemList {
-			item := it
-			p.ItemList[i] = &item
-		}
-	case txn.TiKVItem:
-		var tikvItemList []tikv.TiKVItem
-		if err := json2.Unmarshal(aux.ItemList, &tikvItemList); err != nil {
-			return err
-		}
-		p.ItemList = make([]txn.DataItem, len(tikvItemList))
-		for i, it := range tikvItemList {
-			item := it
-			p.ItemList[i] = &item
-		}
-	case txn.NoneItem:
+	// a request validating the read set only carries no item
+	if p.ItemType == txn.NoneItem || len(aux.ItemList) == 0 || string(aux.ItemList) == "null" {
 		p.ItemList = nil
-	default:
-		return fmt.Errorf("[network.go - PrepareRequest]unsupported data type: %v", p.ItemType)
+		return nil
+	}
+	var rawList []json.RawMessage
+	if err := config.Config.Codec.Deserialize(aux.ItemList, &rawList); err != nil {
+		return err
+	}
+	p.ItemList = make([]txn.DataItem, len(rawList))
+	for i, raw := range rawList {
+		item, err := decodeItem(p.ItemType, raw)
+		if err != nil {
+			return fmt.Errorf("[network.go - PrepareRequest] %v", err)
+		}
+		p.ItemList[i] = item
 	}
-
 	return nil
 }
 
-func GetItemType(dsName string) txn.ItemType {
-	switch dsName {
-	case "redis1", "Redis":
-		return txn.RedisItem
-	case "mongo1", "mongo2", "MongoDB", "MongoDB1", "MongoDB2":
-		return txn.MongoItem
-	case "CouchDB":
-		return txn.CouchItem
-	case "KVRocks":
-		return txn.RedisItem
-	case "Cassandra":
-		return txn.CassandraItem
-	case "DynamoDB":
-		return txn.DynamoDBItem
-	case "TiKV":
-		return txn.TiKVItem
-	default:
-		return ""
+// decodeItem decodes data into a new item created by the factory of itemType.
+func decodeItem(itemType txn.ItemType, data []byte) (txn.DataItem, error) {
+	factory := txn.ItemFactoryOf(itemType)
+	if factory == nil {
+		return nil, fmt.Errorf("unsupported data type: %v", itemType)
 	}
+	item := factory.NewDataItem(txn.ItemOptions{})
+	if err := config.Config.Codec.Deserialize(data, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// GetItemType returns the item type stored in the datastore dsName.
+func GetItemType(dsName string) txn.ItemType {
+	return txn.ItemTypeOf(dsName)
 }
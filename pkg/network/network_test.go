@@ -0,0 +1,406 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
+	trxn "github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	defaultCodec := config.Config.Codec
+	defer func() {
+		config.Config.Codec = defaultCodec
+	}()
+
+	item := &redis.RedisItem{
+		RKey:          "item1",
+		RValue:        "value1",
+		RGroupKeyList: "redis1:txn1",
+		RTxnState:     config.PREPARED,
+		RTValid:       time.Now().UnixMicro(),
+		RTLease:       time.Now().Add(time.Second),
+		RVersion:      "1",
+	}
+
+	for _, name := range []string{"json", "jsoniter"} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := CodecFromName(name)
+			assert.NoError(t, err)
+			config.Config.Codec = codec
+
+			// a response encoded by the executor's read handler
+			readResp := ReadResponse{
+				Status:       "OK",
+				DataStrategy: trxn.AssumeCommit,
+				ItemType:     trxn.RedisItem,
+				Data:         item,
+				GroupKey:     "redis1:txn1",
+			}
+			bs, err := config.Config.Codec.Serialize(readResp)
+			assert.NoError(t, err)
+
+			var gotResp ReadResponse
+			err = config.Config.Codec.Deserialize(bs, &gotResp)
+			assert.NoError(t, err)
+			assert.Equal(t, readResp.Status, gotResp.Status)
+			assert.Equal(t, readResp.DataStrategy, gotResp.DataStrategy)
+			assert.Equal(t, readResp.GroupKey, gotResp.GroupKey)
+			assert.Equal(t, item.Key(), gotResp.Data.Key())
+			assert.Equal(t, item.Value(), gotResp.Data.Value())
+			assert.Equal(t, item.TxnState(), gotResp.Data.TxnState())
+
+			// a request encoded by the client
+			prepareReq := PrepareRequest{
+				DsName:        "redis1",
+				ValidationMap: map[string]trxn.PredicateInfo{},
+				ItemType:      trxn.RedisItem,
+				ItemList:      []trxn.DataItem{item},
+				StartTime:     100,
+				Config:        trxn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.AssumeCommit},
+			}
+			bs, err = config.Config.Codec.Serialize(prepareReq)
+			assert.NoError(t, err)
+
+			var gotReq PrepareRequest
+			err = config.Config.Codec.Deserialize(bs, &gotReq)
+			assert.NoError(t, err)
+			assert.Equal(t, prepareReq.DsName, gotReq.DsName)
+			assert.Equal(t, prepareReq.StartTime, gotReq.StartTime)
+			assert.Equal(t, prepareReq.Config, gotReq.Config)
+			assert.Len(t, gotReq.ItemList, 1)
+			assert.Equal(t, item.Key(), gotReq.ItemList[0].Key())
+			assert.Equal(t, item.Version(), gotReq.ItemList[0].Version())
+		})
+	}
+}
+
+// TestCodecLargeNumbers tests that the timestamps above 2^53, which a
+// float64 cannot hold, come back exactly equal whatever the codecs.
+func TestCodecLargeNumbers(t *testing.T) {
+	defaultCodec := config.Config.Codec
+	defer func() {
+		config.Config.Codec = defaultCodec
+	}()
+
+	const tValid = math.MaxInt64 - 1
+	item := &redis.RedisItem{
+		RKey:       "item1",
+		RValue:     "value1",
+		RTxnState:  config.COMMITTED,
+		RTValid:    tValid,
+		RTLease:    time.Now(),
+		RLinkedLen: 1,
+		RVersion:   "1",
+	}
+
+	for _, useNumber := range []bool{false, true} {
+		for _, client := range []string{"json", "jsoniter"} {
+			for _, handler := range []string{"json", "jsoniter"} {
+				clientCodec, err := NewCodec(client, useNumber)
+				assert.NoError(t, err)
+				handlerCodec, err := NewCodec(handler, useNumber)
+				assert.NoError(t, err)
+				codecs := [2]serializer.Serializer{clientCodec, handlerCodec}
+
+				t.Run(fmt.Sprintf("%s to %s, use number %v", client, handler, useNumber), func(t *testing.T) {
+					prepareReq := PrepareRequest{
+						DsName:    "redis1",
+						ItemType:  trxn.RedisItem,
+						ItemList:  []trxn.DataItem{item},
+						StartTime: tValid,
+					}
+					var gotPrepareReq PrepareRequest
+					transcode(t, codecs, prepareReq, &gotPrepareReq)
+					assert.Equal(t, int64(tValid), gotPrepareReq.StartTime)
+					if assert.Len(t, gotPrepareReq.ItemList, 1) {
+						assert.Equal(t, int64(tValid), gotPrepareReq.ItemList[0].TValid())
+					}
+
+					// the responses go the other way round
+					reversed := [2]serializer.Serializer{handlerCodec, clientCodec}
+					readResp := ReadResponse{Status: "OK", ItemType: trxn.RedisItem, Data: item}
+					var gotReadResp ReadResponse
+					transcode(t, reversed, readResp, &gotReadResp)
+					if assert.NotNil(t, gotReadResp.Data) {
+						assert.Equal(t, int64(tValid), gotReadResp.Data.TValid())
+					}
+
+					commitResp := CommitResponse{Status: "OK", TCommit: tValid}
+					var gotCommitResp CommitResponse
+					transcode(t, reversed, commitResp, &gotCommitResp)
+					assert.Equal(t, int64(tValid), gotCommitResp.TCommit)
+				})
+			}
+		}
+	}
+}
+
+func TestCodecFromNameUnsupported(t *testing.T) {
+	_, err := CodecFromName("msgpack")
+	assert.Error(t, err)
+}
+
+// fakeItem is an item type unknown to the network package.
+type fakeItem struct {
+	redis.RedisItem
+}
+
+type fakeItemFactory struct{}
+
+func (f *fakeItemFactory) NewDataItem(options trxn.ItemOptions) trxn.DataItem {
+	return &fakeItem{RedisItem: *redis.NewRedisItem(options)}
+}
+
+// itemConnector serves the items kept in memory.
+type itemConnector struct {
+	trxn.Connector
+	items map[string]trxn.DataItem
+}
+
+func (c *itemConnector) GetItem(key string) (trxn.DataItem, error) {
+	if item, ok := c.items[key]; ok {
+		return item, nil
+	}
+	return nil, trxn.KeyNotFound
+}
+
+func TestRegisteredItemType(t *testing.T) {
+	const fakeType trxn.ItemType = "fake"
+	trxn.RegisterItemFactory(fakeType, &fakeItemFactory{})
+	trxn.RegisterDatastore("fake1", fakeType)
+	assert.Equal(t, fakeType, GetItemType("fake1"))
+
+	older := &fakeItem{RedisItem: redis.RedisItem{
+		RKey:       "item1",
+		RValue:     "value1",
+		RTxnState:  config.COMMITTED,
+		RTValid:    100,
+		RLinkedLen: 1,
+		RVersion:   "1",
+	}}
+	prev, err := config.Config.Serializer.Serialize(older)
+	assert.NoError(t, err)
+	newer := &fakeItem{RedisItem: redis.RedisItem{
+		RKey:       "item1",
+		RValue:     "value2",
+		RTxnState:  config.COMMITTED,
+		RTValid:    300,
+		RPrev:      string(prev),
+		RLinkedLen: 2,
+		RVersion:   "2",
+	}}
+	conn := &itemConnector{items: map[string]trxn.DataItem{"item1": newer}}
+	reader := NewReader(map[string]trxn.Connector{"fake1": conn}, nil, config.Config.Serializer, NewCacher())
+
+	// the previous version is decoded by the registered factory
+	item, _, _, err := reader.Read("fake1", "item1", 200, trxn.RecordConfig{MaxRecordLen: 2}, false)
+	assert.NoError(t, err)
+	assert.IsType(t, &fakeItem{}, item)
+	assert.Equal(t, "value1", item.Value())
+
+	readResp := ReadResponse{
+		Status:   "OK",
+		ItemType: GetItemType("fake1"),
+		Data:     newer,
+	}
+	bs, err := config.Config.Codec.Serialize(readResp)
+	assert.NoError(t, err)
+	var gotResp ReadResponse
+	err = config.Config.Codec.Deserialize(bs, &gotResp)
+	assert.NoError(t, err)
+	assert.IsType(t, &fakeItem{}, gotResp.Data)
+	assert.Equal(t, "value2", gotResp.Data.Value())
+}
+
+func TestPrepareRequestValidate(t *testing.T) {
+	item := redis.NewRedisItem(trxn.ItemOptions{Key: "item1", Value: "value1"})
+
+	t.Run("redis items declared as mongo items", func(t *testing.T) {
+		req := PrepareRequest{
+			DsName:   "redis1",
+			ItemType: trxn.MongoItem,
+			ItemList: []trxn.DataItem{item},
+		}
+		bs, err := config.Config.Codec.Serialize(req)
+		assert.NoError(t, err)
+
+		var got PrepareRequest
+		err = config.Config.Codec.Deserialize(bs, &got)
+		assert.NoError(t, err)
+		assert.EqualError(t, got.Validate(),
+			"item type mongo does not match datastore redis1, expect redis")
+	})
+
+	t.Run("matching item type", func(t *testing.T) {
+		req := PrepareRequest{
+			DsName:   "redis1",
+			ItemType: GetItemType("redis1"),
+			ItemList: []trxn.DataItem{item},
+		}
+		assert.NoError(t, req.Validate())
+	})
+
+	t.Run("unknown datastore", func(t *testing.T) {
+		req := PrepareRequest{DsName: "unknown", ItemType: trxn.RedisItem}
+		assert.EqualError(t, req.Validate(), "datastore unknown is not registered")
+	})
+}
+
+// crossCodecs returns every pair of a client codec encoding the messages
+// and a handler codec decoding them.
+func crossCodecs(t *testing.T) map[string][2]serializer.Serializer {
+	pairs := make(map[string][2]serializer.Serializer)
+	for _, client := range []string{"json", "jsoniter"} {
+		for _, handler := range []string{"json", "jsoniter"} {
+			clientCodec, err := CodecFromName(client)
+			assert.NoError(t, err)
+			handlerCodec, err := CodecFromName(handler)
+			assert.NoError(t, err)
+			pairs[client+" to "+handler] = [2]serializer.Serializer{clientCodec, handlerCodec}
+		}
+	}
+	return pairs
+}
+
+// transcode encodes msg with the client codec and decodes it into
+// got with the handler codec, which the custom UnmarshalJSON methods
+// pick up from config.Config.Codec like in the executor.
+func transcode(t *testing.T, codecs [2]serializer.Serializer, msg any, got any) {
+	bs, err := codecs[0].Serialize(msg)
+	assert.NoError(t, err)
+	config.Config.Codec = codecs[1]
+	assert.NoError(t, codecs[1].Deserialize(bs, got))
+}
+
+// TestCodecCrossDecoding tests that every message is decoded field for
+// field by the handlers whatever codec the client encodes it with.
+func TestCodecCrossDecoding(t *testing.T) {
+	defaultCodec := config.Config.Codec
+	defer func() {
+		config.Config.Codec = defaultCodec
+	}()
+
+	lease := time.Date(2024, 5, 1, 12, 30, 15, 123456789, time.UTC)
+	items := make(map[trxn.ItemType]trxn.DataItem)
+	for _, itemType := range []trxn.ItemType{trxn.RedisItem, trxn.MongoItem, trxn.CouchItem,
+		trxn.CassandraItem, trxn.DynamoDBItem, trxn.TiKVItem} {
+		items[itemType] = trxn.ItemFactoryOf(itemType).NewDataItem(trxn.ItemOptions{
+			Key:          "item1",
+			Value:        `{"Name":"John","Note":"<&> é"}`,
+			GroupKeyList: "redis1:txn1,mongo1:txn1",
+			TxnState:     config.PREPARED,
+			TValid:       1714566615123456,
+			TLease:       lease,
+			Prev:         `{"Key":"item1","Version":"1"}`,
+			LinkedLen:    2,
+			IsDeleted:    true,
+			Version:      "18446744073709551615",
+		})
+	}
+	recordConfig := trxn.RecordConfig{
+		MaxRecordLen:                3,
+		ReadStrategy:                config.AssumeAbort,
+		ReadWaitTime:                25 * time.Millisecond,
+		ConcurrentOptimizationLevel: 2,
+		AblationLevel:               4,
+	}
+
+	for name, codecs := range crossCodecs(t) {
+		t.Run(name, func(t *testing.T) {
+			readReq := ReadRequest{DsName: "redis1", Key: "item1", StartTime: 1714566615123456, Config: recordConfig}
+			var gotReadReq ReadRequest
+			transcode(t, codecs, readReq, &gotReadReq)
+			assert.Equal(t, readReq, gotReadReq)
+
+			for itemType, item := range items {
+				readResp := ReadResponse{
+					Status:       "OK",
+					DataStrategy: trxn.AssumeCommit,
+					ItemType:     itemType,
+					Data:         item,
+					GroupKey:     "redis1:txn1",
+				}
+				var gotReadResp ReadResponse
+				transcode(t, codecs, readResp, &gotReadResp)
+				assert.Equal(t, readResp, gotReadResp, "read response of %s", itemType)
+
+				prepareReq := PrepareRequest{
+					DsName: "redis1",
+					ValidationMap: map[string]trxn.PredicateInfo{
+						"redis1:txn0": {State: config.COMMITTED, ItemKey: "item0", LeaseTime: lease},
+						"read:item2":  {ItemKey: "item2", ReadVersion: true, Version: "3"},
+					},
+					ItemType:  itemType,
+					ItemList:  []trxn.DataItem{item},
+					StartTime: 1714566615123456,
+					Config:    recordConfig,
+				}
+				var gotPrepareReq PrepareRequest
+				transcode(t, codecs, prepareReq, &gotPrepareReq)
+				assert.Equal(t, prepareReq, gotPrepareReq, "prepare request of %s", itemType)
+
+				batchReq := PrepareBatchRequest{Requests: map[string]PrepareRequest{"redis1": prepareReq}}
+				var gotBatchReq PrepareBatchRequest
+				transcode(t, codecs, batchReq, &gotBatchReq)
+				assert.Equal(t, batchReq, gotBatchReq, "prepare batch request of %s", itemType)
+			}
+
+			// a prepare request validating the read set only
+			readSetReq := PrepareRequest{
+				DsName:        "redis1",
+				ValidationMap: map[string]trxn.PredicateInfo{"read:item2": {ItemKey: "item2", ReadVersion: true}},
+				ItemType:      trxn.RedisItem,
+				Config:        recordConfig,
+			}
+			var gotReadSetReq PrepareRequest
+			transcode(t, codecs, readSetReq, &gotReadSetReq)
+			assert.Equal(t, readSetReq, gotReadSetReq)
+
+			prepareResp := PrepareResponse{Status: "OK", TCommit: 1714566615123456, VerMap: map[string]string{"item1": "19"}}
+			var gotPrepareResp PrepareResponse
+			transcode(t, codecs, prepareResp, &gotPrepareResp)
+			assert.Equal(t, prepareResp, gotPrepareResp)
+
+			batchResp := PrepareBatchResponse{Status: "OK", Responses: map[string]PrepareResponse{"redis1": prepareResp}}
+			var gotBatchResp PrepareBatchResponse
+			transcode(t, codecs, batchResp, &gotBatchResp)
+			assert.Equal(t, batchResp, gotBatchResp)
+
+			commitReq := CommitRequest{
+				DsName:  "redis1",
+				List:    []trxn.CommitInfo{{Key: "item1", Version: "19"}},
+				TCommit: 1714566615123456,
+			}
+			var gotCommitReq CommitRequest
+			transcode(t, codecs, commitReq, &gotCommitReq)
+			assert.Equal(t, commitReq, gotCommitReq)
+
+			commitResp := CommitResponse{Status: "OK", TCommit: 1714566615123456}
+			var gotCommitResp CommitResponse
+			transcode(t, codecs, commitResp, &gotCommitResp)
+			assert.Equal(t, commitResp, gotCommitResp)
+
+			abortReq := AbortRequest{DsName: "redis1", KeyList: []string{"item1", "item2"}, GroupKeyList: "redis1:txn1"}
+			var gotAbortReq AbortRequest
+			transcode(t, codecs, abortReq, &gotAbortReq)
+			assert.Equal(t, abortReq, gotAbortReq)
+
+			tsrReq := TSRRequest{DsName: "redis1", TxnId: "txn1"}
+			var gotTSRReq TSRRequest
+			transcode(t, codecs, tsrReq, &gotTSRReq)
+			assert.Equal(t, tsrReq, gotTSRReq)
+
+			tsrResp := Response[config.State]{Status: "OK", Data: config.COMMITTED}
+			var gotTSRResp Response[config.State]
+			transcode(t, codecs, tsrResp, &gotTSRResp)
+			assert.Equal(t, tsrResp, gotTSRResp)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package network
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultProbeInterval is how often an executor marked down
+// after a failed request is probed.
+const DefaultProbeInterval = time.Second
+
+// prober probes the executors marked down after a failed request on
+// their /ping endpoint until they answer. It is shared by a Client and
+// its copies made by WithContext.
+type prober struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	probing map[string]bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newProber(interval time.Duration) *prober {
+	return &prober{
+		interval: interval,
+		probing:  make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+}
+
+// start reports whether addr should be probed, false if it already is.
+func (p *prober) start(addr string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.probing[addr] {
+		return false
+	}
+	p.probing[addr] = true
+	return true
+}
+
+func (p *prober) done(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.probing, addr)
+}
+
+func (p *prober) close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// executorAddr returns the address of the executor req is sent to,
+// as in the executor address map.
+func executorAddr(req *fasthttp.Request) string {
+	return string(req.URI().Scheme()) + "://" + string(req.URI().Host())
+}
+
+// markDown excludes the executor at addr from the selection of every
+// datastore it serves, until it answers a probe.
+func (c *Client) markDown(addr string) {
+	c.topology.markDown(addr)
+	if c.prober.start(addr) {
+		go c.probe(addr)
+	}
+}
+
+// probe pings the executor at addr every probe interval
+// and marks it up once it answers, or the client is closed.
+func (c *Client) probe(addr string) {
+	defer c.prober.done(addr)
+	ticker := time.NewTicker(c.prober.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.prober.stop:
+			return
+		case <-ticker.C:
+			if c.ping(addr) == nil {
+				c.topology.markUp(addr)
+				return
+			}
+		}
+	}
+}
+
+// ping sends a request to the /ping endpoint of the executor at addr.
+func (c *Client) ping(addr string) error {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(addr + "/ping")
+	req.Header.SetMethod(fasthttp.MethodGet)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err := c.httpClient.DoTimeout(req, resp, c.prober.interval); err != nil {
+		return err
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode(), addr)
+	}
+	return nil
+}
@@ -1,14 +1,13 @@
 package network
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/oreo-dtx-lab/oreo/internal/util"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/oreo-dtx-lab/oreo/pkg/logger"
 	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
@@ -18,16 +17,20 @@ import (
 
 const (
 	ReadFailed = "read failed due to unknown txn status"
+
+	// WaitPollInterval is the interval between two TSR lookups
+	// under the WaitThenResolve read strategy
+	WaitPollInterval = 2 * time.Millisecond
 )
 
 type Reader struct {
 	connMap     map[string]txn.Connector
 	itemFactory txn.DataItemFactory
 	se          serializer.Serializer
-	Cacher      *Cacher
+	Cacher      Cacher
 }
 
-func NewReader(connMap map[string]txn.Connector, itemFactory txn.DataItemFactory, se serializer.Serializer, cacher *Cacher) *Reader {
+func NewReader(connMap map[string]txn.Connector, itemFactory txn.DataItemFactory, se serializer.Serializer, cacher Cacher) *Reader {
 	return &Reader{
 		connMap:     connMap,
 		itemFactory: itemFactory,
@@ -36,6 +39,15 @@ func NewReader(connMap map[string]txn.Connector, itemFactory txn.DataItemFactory
 	}
 }
 
+// itemFactoryFor returns the factory registered for dsName,
+// falling back to the one the reader was created with.
+func (r *Reader) itemFactoryFor(dsName string) txn.DataItemFactory {
+	if factory := txn.ItemFactoryFor(dsName); factory != nil {
+		return factory
+	}
+	return r.itemFactory
+}
+
 // If the record is marked as IsDeleted, this function will return it.
 //
 // Let the upper layer decide what to do with it
@@ -64,7 +76,7 @@ func (r *Reader) Read(dsName string, key string, ts int64, cfg txn.RecordConfig,
 		if resItem.Prev() == "" {
 			return nil, txn.AssumeAbort, "", errors.New("key not found in AssumeAbort")
 		}
-		targetItem, err = r.getPrevItem(resItem)
+		targetItem, err = r.getPrevItem(dsName, resItem)
 		if err != nil {
 			return nil, dataType, "", err
 		}
@@ -84,7 +96,11 @@ func (r *Reader) Read(dsName string, key string, ts int64, cfg txn.RecordConfig,
 		return curItem, nil
 	}
 
-	item, err = r.treatAsCommitted(targetItem, ts, logicFunc, cfg)
+	// a read-committed read returns the latest committed version
+	if cfg.IsolationLevel == config.ReadCommitted {
+		ts = math.MaxInt64
+	}
+	item, err = r.treatAsCommitted(dsName, targetItem, ts, logicFunc, cfg)
 	return item, dataType, resItem.GroupKeyList(), err
 	// return r.treatAsCommitted(resItem, ts, logicFunc, cfg)
 }
@@ -118,22 +134,26 @@ func (r *Reader) basicVisibilityProcessor(dsName string, item txn.DataItem,
 	if item.TxnState() == config.COMMITTED {
 		return item, txn.Normal, nil
 	}
+	// function to resolve the record according to its TSRs
+	resolveFunc := func(groupKeyList []txn.GroupKey) (txn.DataItem, txn.RemoteDataStrategy, error) {
+		if txn.CommittedForAll(groupKeyList) {
+			// if all the group keys are in COMMITTED state
+			// update its TCommit first
+			tCommit := int64(0)
+			for _, gk := range groupKeyList {
+				tCommit = max(tCommit, gk.TCommit)
+			}
+			item.SetTValid(tCommit)
+			return rollforwardFunc()
+		}
+		// or at least one of the group keys is in ABORTED state
+		return rollbackFunc()
+	}
+
 	if item.TxnState() == config.PREPARED {
 		groupKeyList, err := r.getGroupKey(strings.Split(item.GroupKeyList(), ","))
 		if err == nil {
-			if txn.CommittedForAll(groupKeyList) {
-				// if all the group keys are in COMMITTED state
-				// update its TCommit first
-				tCommit := int64(0)
-				for _, gk := range groupKeyList {
-					tCommit = max(tCommit, gk.TCommit)
-				}
-				item.SetTValid(tCommit)
-				return rollforwardFunc()
-			} else {
-				// or at least one of the group keys is in ABORTED state
-				return rollbackFunc()
-			}
+			return resolveFunc(groupKeyList)
 		}
 		// if TSR does not exist
 		// and if t_lease has expired
@@ -153,7 +173,7 @@ func (r *Reader) basicVisibilityProcessor(dsName string, item txn.DataItem,
 		if startTime < item.TValid() {
 
 			// Origin Cherry Garcia would do
-			if config.Debug.CherryGarciaMode {
+			if cfg.Protocol == config.CherryGarcia {
 				return nil, txn.Normal, errors.New(ReadFailed)
 			}
 
@@ -179,6 +199,12 @@ func (r *Reader) basicVisibilityProcessor(dsName string, item txn.DataItem,
 			return nil, txn.Normal, errors.New(ReadFailed)
 		} else {
 			switch cfg.ReadStrategy {
+			case config.WaitThenResolve:
+				groupKeyList, err := r.waitForGroupKey(item, cfg.ReadWaitTime)
+				if err != nil {
+					return nil, txn.Normal, errors.New(ReadFailed)
+				}
+				return resolveFunc(groupKeyList)
 			case config.AssumeCommit:
 				return item, txn.AssumeCommit, nil
 			case config.AssumeAbort:
@@ -195,6 +221,26 @@ func (r *Reader) basicVisibilityProcessor(dsName string, item txn.DataItem,
 	return nil, txn.Normal, errors.New("key not found(unreachable code in basicVisibilityProcessor)")
 }
 
+// waitForGroupKey polls the TSRs of a PREPARED record until all of them
+// are found or waitTime elapses. The wait never outlives the record's TLease.
+func (r *Reader) waitForGroupKey(item txn.DataItem, waitTime time.Duration) ([]txn.GroupKey, error) {
+	urls := strings.Split(item.GroupKeyList(), ",")
+	deadline := time.Now().Add(waitTime)
+	if item.TLease().Before(deadline) {
+		deadline = item.TLease()
+	}
+	for {
+		groupKeyList, err := r.getGroupKey(urls)
+		if err == nil {
+			return groupKeyList, nil
+		}
+		if !time.Now().Add(WaitPollInterval).Before(deadline) {
+			return nil, err
+		}
+		time.Sleep(WaitPollInterval)
+	}
+}
+
 // rollback overwrites the record with the application data
 // and metadata that found in field Prev.
 // if the `Prev` is empty, it simply deletes the record
@@ -211,7 +257,7 @@ func (r *Reader) rollback(dsName string, item txn.DataItem) (txn.DataItem, error
 		return item, err
 	}
 
-	newItem, err := r.getPrevItem(item)
+	newItem, err := r.getPrevItem(dsName, item)
 	if err != nil {
 		return nil, errors.Join(errors.New("rollback failed"), err)
 	}
@@ -240,8 +286,12 @@ func (r *Reader) rollForward(dsName string, item txn.DataItem) (txn.DataItem, er
 	return item, err
 }
 
-func (r *Reader) getPrevItem(item txn.DataItem) (txn.DataItem, error) {
-	preItem := r.itemFactory.NewDataItem(txn.ItemOptions{})
+func (r *Reader) getPrevItem(dsName string, item txn.DataItem) (txn.DataItem, error) {
+	factory := r.itemFactoryFor(dsName)
+	if factory == nil {
+		return nil, fmt.Errorf("no item type is registered for datastore %s", dsName)
+	}
+	preItem := factory.NewDataItem(txn.ItemOptions{})
 	err := r.se.Deserialize([]byte(item.Prev()), &preItem)
 	if err != nil {
 		return nil, err
@@ -298,31 +348,70 @@ func (r *Reader) getGroupKey(urls []string) ([]txn.GroupKey, error) {
 	return groupKeys, nil
 }
 
+// ReadTSR returns the state of the TSR of txnId stored in the datastore dsName.
+// If the TSR does not exist, config.EMPTY is returned with a nil error.
+func (r *Reader) ReadTSR(dsName string, txnId string) (config.State, error) {
+	groupKey, err := r.getSingleGroupKey(dsName + ":" + txnId)
+	if err != nil {
+		if err.Error() == txn.KeyNotFound.Error() {
+			return config.EMPTY, nil
+		}
+		return config.EMPTY, err
+	}
+	return groupKey.TxnState, nil
+}
+
 func (r *Reader) getSingleGroupKey(url string) (txn.GroupKey, error) {
 	cacheItem, ok := r.Cacher.Get(url)
 	if ok {
+		if r.Cacher.SampleRepair() {
+			cacheItem = r.repairGroupKey(url, cacheItem)
+		}
 		gk := txn.NewGroupKey(url, cacheItem.TxnState, cacheItem.TCommit)
 		return *gk, nil
 	}
 	// fmt.Printf("Cache not found: %v\n", url)
 
+	keyItem, err := r.fetchGroupKeyItem(url)
+	if err != nil {
+		return txn.GroupKey{}, err
+	}
+	r.Cacher.Set(url, keyItem)
+	return *txn.NewGroupKey(url, keyItem.TxnState, keyItem.TCommit), nil
+}
+
+// fetchGroupKeyItem reads the TSR at url from its datastore.
+func (r *Reader) fetchGroupKeyItem(url string) (txn.GroupKeyItem, error) {
 	tokens := strings.Split(url, ":")
 	conn, ok := r.connMap[tokens[0]]
 	if !ok {
-		return txn.GroupKey{}, fmt.Errorf("connector to %s is not found", tokens[0])
+		return txn.GroupKeyItem{}, fmt.Errorf("connector to %s is not found", tokens[0])
 	}
 	groupKeyStr, err := conn.Get(url)
 	// fmt.Printf("conn[%v].Get(%v) error: %v\n", tokens[0], url, err)
 	if err != nil {
-		return txn.GroupKey{}, err
+		return txn.GroupKeyItem{}, err
 	}
-	var keyItem txn.GroupKeyItem
-	err = json.Unmarshal([]byte(groupKeyStr), &keyItem)
+	return txn.DecodeGroupKeyItem(groupKeyStr)
+}
+
+// repairGroupKey checks the cached TSR at url against its datastore
+// and refreshes the cache if they differ.
+//
+// The cached item is kept if the TSR cannot be read, since a TSR is
+// deleted once its transaction has been committed or rolled back.
+func (r *Reader) repairGroupKey(url string, cached txn.GroupKeyItem) txn.GroupKeyItem {
+	keyItem, err := r.fetchGroupKeyItem(url)
 	if err != nil {
-		return txn.GroupKey{}, fmt.Errorf("failed to unmarshal group key item %s", groupKeyStr)
+		if err.Error() != txn.KeyNotFound.Error() {
+			logger.Log.Warnw("read repair failed", "key", url, "error", err)
+		}
+		return cached
 	}
-	r.Cacher.Set(url, keyItem)
-	return *txn.NewGroupKey(url, keyItem.TxnState, keyItem.TCommit), nil
+	if keyItem != cached {
+		r.Cacher.Repair(url, keyItem)
+	}
+	return keyItem
 }
 
 func (r *Reader) createGroupKey(urls []string, state config.State, tCommit int64) int {
@@ -355,12 +444,11 @@ func (r *Reader) createSingleGroupKey(url string, state config.State, tCommit in
 		return fmt.Errorf("connector to %s is not found", tokens[0])
 	}
 
-	groupKey := txn.NewGroupKey(url, state, tCommit)
-	groupKeyStr, err := json.Marshal(groupKey)
+	groupKeyStr, err := txn.EncodeGroupKeyItem(txn.NewGroupKeyItem(state, tCommit))
 	if err != nil {
-		return fmt.Errorf("failed to marshal group key item %s", groupKey)
+		return err
 	}
-	_, err = conn.AtomicCreate(url, util.ToString(groupKeyStr))
+	_, err = conn.AtomicCreate(url, groupKeyStr)
 	if err != nil {
 		return err
 	}
@@ -397,7 +485,7 @@ func (r *Reader) createSingleGroupKey(url string, state config.State, tCommit in
 
 // treatAsCommitted treats a DataItem as committed, finds a corresponding version
 // according to its timestamp, and performs the given logic function on it.
-func (r *Reader) treatAsCommitted(item txn.DataItem,
+func (r *Reader) treatAsCommitted(dsName string, item txn.DataItem,
 	startTime int64, logicFunc func(txn.DataItem, bool) (txn.DataItem, error),
 	cfg txn.RecordConfig) (txn.DataItem, error) {
 	curItem := item
@@ -417,7 +505,7 @@ func (r *Reader) treatAsCommitted(item txn.DataItem,
 		}
 
 		// get the previous record
-		preItem, err := r.getPrevItem(curItem)
+		preItem, err := r.getPrevItem(dsName, curItem)
 		if err != nil {
 			return nil, err
 		}
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
 	"github.com/oreo-dtx-lab/oreo/internal/testutil"
 	"github.com/oreo-dtx-lab/oreo/internal/util"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
@@ -1774,3 +1775,241 @@ func TestItemVersionUpdate(t *testing.T) {
 		assert.Equal(t, util.AddToString(dbItem.Version(), 2), res.Version())
 	})
 }
+
+func TestReaderReadStrategyOnPreparedItemWithoutTSR(t *testing.T) {
+	conn := NewDefaultRedisConnection()
+	connMap := map[string]trxn.Connector{"redis1": conn}
+
+	preItem := &redis.RedisItem{
+		RKey:          "item-strategy",
+		RValue:        util.ToJSONString(testutil.NewTestItem("item-strategy-pre")),
+		RGroupKeyList: "redis1:TestReaderReadStrategyPre",
+		RTxnState:     config.COMMITTED,
+		RTValid:       time.Now().Add(-3 * time.Second).UnixMicro(),
+		RTLease:       time.Now().Add(-2 * time.Second),
+		RLinkedLen:    1,
+		RVersion:      "1",
+	}
+
+	// plantPreparedItem writes a PREPARED record whose TSR does not exist
+	plantPreparedItem := func(groupKey string) {
+		_ = conn.Delete(groupKey)
+		dbItem := &redis.RedisItem{
+			RKey:          "item-strategy",
+			RValue:        util.ToJSONString(testutil.NewTestItem("item-strategy-cur")),
+			RGroupKeyList: groupKey,
+			RTxnState:     config.PREPARED,
+			RTValid:       time.Now().Add(-1 * time.Second).UnixMicro(),
+			RTLease:       time.Now().Add(2 * time.Second),
+			RPrev:         util.ToJSONString(preItem),
+			RLinkedLen:    2,
+			RVersion:      "2",
+		}
+		conn.PutItem("item-strategy", dbItem)
+	}
+
+	createGroupKey := func(groupKey string, state config.State) {
+		gk := trxn.NewGroupKey(groupKey, state, time.Now().Add(-500*time.Millisecond).UnixMicro())
+		gkStr, _ := json.Marshal(gk)
+		_, err := conn.AtomicCreate(groupKey, string(gkStr))
+		assert.NoError(t, err)
+	}
+
+	newConfig := func(strategy config.ReadStrategy) trxn.RecordConfig {
+		return trxn.RecordConfig{
+			MaxRecordLen: 2,
+			ReadStrategy: strategy,
+			ReadWaitTime: 50 * time.Millisecond,
+		}
+	}
+
+	t.Run("Pessimistic fails the read", func(t *testing.T) {
+		plantPreparedItem("redis1:TestReaderReadStrategyPessimistic")
+		reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+
+		_, _, _, err := reader.Read("redis1", "item-strategy", time.Now().UnixMicro(),
+			newConfig(config.Pessimistic), false)
+		assert.EqualError(t, err, ReadFailed)
+	})
+
+	t.Run("AssumeCommit returns the prepared version", func(t *testing.T) {
+		plantPreparedItem("redis1:TestReaderReadStrategyAssumeCommit")
+		reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+
+		item, dataType, _, err := reader.Read("redis1", "item-strategy", time.Now().UnixMicro(),
+			newConfig(config.AssumeCommit), false)
+		assert.NoError(t, err)
+		assert.Equal(t, trxn.AssumeCommit, dataType)
+		assert.Equal(t, util.ToJSONString(testutil.NewTestItem("item-strategy-cur")), item.Value())
+	})
+
+	t.Run("AssumeAbort returns the previous version", func(t *testing.T) {
+		plantPreparedItem("redis1:TestReaderReadStrategyAssumeAbort")
+		reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+
+		item, dataType, _, err := reader.Read("redis1", "item-strategy", time.Now().UnixMicro(),
+			newConfig(config.AssumeAbort), false)
+		assert.NoError(t, err)
+		assert.Equal(t, trxn.AssumeAbort, dataType)
+		assert.Equal(t, util.ToJSONString(testutil.NewTestItem("item-strategy-pre")), item.Value())
+	})
+
+	t.Run("WaitThenResolve fails after waiting when the TSR never shows up", func(t *testing.T) {
+		plantPreparedItem("redis1:TestReaderReadStrategyWaitNone")
+		reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+
+		start := time.Now()
+		_, _, _, err := reader.Read("redis1", "item-strategy", time.Now().UnixMicro(),
+			newConfig(config.WaitThenResolve), false)
+		assert.EqualError(t, err, ReadFailed)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond-WaitPollInterval)
+	})
+
+	t.Run("WaitThenResolve rolls forward when the TSR commits", func(t *testing.T) {
+		groupKey := "redis1:TestReaderReadStrategyWaitCommitted"
+		plantPreparedItem(groupKey)
+		reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			createGroupKey(groupKey, config.COMMITTED)
+		}()
+		item, dataType, _, err := reader.Read("redis1", "item-strategy", time.Now().UnixMicro(),
+			newConfig(config.WaitThenResolve), false)
+		assert.NoError(t, err)
+		assert.Equal(t, trxn.Normal, dataType)
+		assert.Equal(t, util.ToJSONString(testutil.NewTestItem("item-strategy-cur")), item.Value())
+
+		dbItem, err := conn.GetItem("item-strategy")
+		assert.NoError(t, err)
+		assert.Equal(t, config.COMMITTED, dbItem.TxnState())
+	})
+
+	t.Run("WaitThenResolve rolls back when the TSR aborts", func(t *testing.T) {
+		groupKey := "redis1:TestReaderReadStrategyWaitAborted"
+		plantPreparedItem(groupKey)
+		reader := NewReader(connMap, &redis.RedisItemFactory{}, config.Config.Serializer, NewCacher())
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			createGroupKey(groupKey, config.ABORTED)
+		}()
+		item, dataType, _, err := reader.Read("redis1", "item-strategy", time.Now().UnixMicro(),
+			newConfig(config.WaitThenResolve), false)
+		assert.NoError(t, err)
+		assert.Equal(t, trxn.Normal, dataType)
+		assert.Equal(t, util.ToJSONString(testutil.NewTestItem("item-strategy-pre")), item.Value())
+	})
+}
+
+func TestReaderReadRepair(t *testing.T) {
+	oldRate := config.Config.ReadRepairRate
+	defer func() { config.Config.ReadRepairRate = oldRate }()
+
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	cacher := NewCacher()
+	reader := NewReader(map[string]trxn.Connector{"redis1": conn}, &redis.RedisItemFactory{},
+		config.Config.Serializer, cacher)
+
+	// plantStale caches an ABORTED TSR whose stored state is COMMITTED
+	plantStale := func(groupKey string) {
+		reader.Cacher.Set(groupKey, trxn.NewGroupKeyItem(config.ABORTED, 0))
+		tsr, err := trxn.EncodeGroupKeyItem(trxn.NewGroupKeyItem(config.COMMITTED, 100))
+		assert.NoError(t, err)
+		assert.NoError(t, conn.Put(groupKey, tsr))
+	}
+
+	t.Run("the stale entry is refreshed at the sampled hit", func(t *testing.T) {
+		config.Config.ReadRepairRate = 0.25
+		reader.ClearCache()
+		plantStale("redis1:TestReaderReadRepair")
+
+		for i := 0; i < 3; i++ {
+			state, err := reader.ReadTSR("redis1", "TestReaderReadRepair")
+			assert.NoError(t, err)
+			assert.Equal(t, config.ABORTED, state)
+		}
+		state, err := reader.ReadTSR("redis1", "TestReaderReadRepair")
+		assert.NoError(t, err)
+		assert.Equal(t, config.COMMITTED, state)
+		assert.Equal(t, 1, cacher.RepairCheck)
+		assert.Equal(t, 1, cacher.Repaired)
+
+		// the refreshed entry is served from the cache
+		for i := 0; i < 8; i++ {
+			state, _ := reader.ReadTSR("redis1", "TestReaderReadRepair")
+			assert.Equal(t, config.COMMITTED, state)
+		}
+		assert.Equal(t, 3, cacher.RepairCheck)
+		assert.Equal(t, 1, cacher.Repaired)
+	})
+
+	t.Run("a deleted TSR keeps the cached entry", func(t *testing.T) {
+		config.Config.ReadRepairRate = 1
+		reader.ClearCache()
+		reader.Cacher.Set("redis1:TestReaderReadRepairDeleted", trxn.NewGroupKeyItem(config.COMMITTED, 100))
+
+		state, err := reader.ReadTSR("redis1", "TestReaderReadRepairDeleted")
+		assert.NoError(t, err)
+		assert.Equal(t, config.COMMITTED, state)
+		assert.Equal(t, 0, cacher.Repaired)
+	})
+
+	t.Run("a zero rate trusts the cache", func(t *testing.T) {
+		config.Config.ReadRepairRate = 0
+		reader.ClearCache()
+		plantStale("redis1:TestReaderReadRepairDisabled")
+
+		for i := 0; i < 10; i++ {
+			state, _ := reader.ReadTSR("redis1", "TestReaderReadRepairDisabled")
+			assert.Equal(t, config.ABORTED, state)
+		}
+		assert.Equal(t, 0, cacher.RepairCheck)
+	})
+}
+
+func TestReaderCacheScopedByDatastore(t *testing.T) {
+	conn1 := memkv.NewConnection(&redis.RedisItemFactory{})
+	conn2 := memkv.NewConnection(&redis.RedisItemFactory{})
+	cacher := NewCacher()
+	reader := NewReader(map[string]trxn.Connector{"redis1": conn1, "redis2": conn2},
+		&redis.RedisItemFactory{}, config.Config.Serializer, cacher)
+
+	// the same transaction prepared the same key in both datastores,
+	// its TSR is committed in redis1 and aborted in redis2
+	for dsName, conn := range map[string]*memkv.Connection{"redis1": conn1, "redis2": conn2} {
+		_, err := conn.PutItem("item1", &redis.RedisItem{
+			RKey: "item1", RValue: "value1", RGroupKeyList: dsName + ":txn1",
+			RTxnState: config.PREPARED, RTValid: 10, RTLease: time.Now().Add(time.Hour), RVersion: "1",
+		})
+		assert.NoError(t, err)
+	}
+	put := func(conn *memkv.Connection, groupKey string, state config.State) {
+		tsr, err := trxn.EncodeGroupKeyItem(trxn.NewGroupKeyItem(state, 10))
+		assert.NoError(t, err)
+		assert.NoError(t, conn.Put(groupKey, tsr))
+	}
+	put(conn1, "redis1:txn1", config.COMMITTED)
+	put(conn2, "redis2:txn1", config.ABORTED)
+
+	cfg := trxn.RecordConfig{MaxRecordLen: 2, ReadStrategy: config.Pessimistic, AblationLevel: 4}
+	item, _, _, err := reader.Read("redis1", "item1", 100, cfg, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "value1", item.Value())
+
+	assert.False(t, item.IsDeleted())
+
+	// the committed TSR cached for redis1 is not used for redis2,
+	// whose record is rolled back
+	item, _, _, err = reader.Read("redis2", "item1", 100, cfg, true)
+	assert.NoError(t, err)
+	assert.True(t, item.IsDeleted())
+
+	state, err := reader.ReadTSR("redis1", "txn1")
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, state)
+	state, err = reader.ReadTSR("redis2", "txn1")
+	assert.NoError(t, err)
+	assert.Equal(t, config.ABORTED, state)
+	assert.Equal(t, 2, cacher.CacheHit)
+}
@@ -0,0 +1,111 @@
+package network
+
+import "sync"
+
+var (
+	_ LoadBalancer   = (*RegionalBalancer)(nil)
+	_ RequestTracker = (*RegionalBalancer)(nil)
+)
+
+// RegionalBalancer sends the requests to the executors of the local region,
+// selected by a load balancer of their own, and only falls back to the
+// executors of the other regions, selected by another one, once every
+// local executor is marked down. The executors without a region are
+// in another region.
+type RegionalBalancer struct {
+	local  LoadBalancer
+	remote LoadBalancer
+
+	mu      sync.Mutex
+	isLocal map[string]bool
+	localUp int
+	down    map[string]bool
+}
+
+// NewRegionalBalancerFactory returns a LoadBalancerFactory creating
+// RegionalBalancers, which select among the executors of each region
+// with the load balancers created by newBalancer. regions maps the
+// executor addresses to their regions.
+func NewRegionalBalancerFactory(regions map[string]string, localRegion string,
+	newBalancer LoadBalancerFactory) LoadBalancerFactory {
+	return func(addrs []string) LoadBalancer {
+		var local, remote []string
+		for _, addr := range addrs {
+			if region, ok := regions[addr]; ok && region == localRegion {
+				local = append(local, addr)
+			} else {
+				remote = append(remote, addr)
+			}
+		}
+		// a single region needs no fallback
+		if len(local) == 0 || len(remote) == 0 {
+			return newBalancer(addrs)
+		}
+		b := &RegionalBalancer{
+			local:   newBalancer(local),
+			remote:  newBalancer(remote),
+			isLocal: make(map[string]bool, len(local)),
+			down:    make(map[string]bool),
+		}
+		for _, addr := range local {
+			b.isLocal[addr] = true
+		}
+		b.localUp = len(b.isLocal)
+		return b
+	}
+}
+
+func (b *RegionalBalancer) Next(key string) string {
+	if b.fallback() {
+		return b.remote.Next(key)
+	}
+	return b.local.Next(key)
+}
+
+// fallback reports whether the requests go to the other regions.
+func (b *RegionalBalancer) fallback() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.localUp == 0
+}
+
+func (b *RegionalBalancer) MarkDown(addr string) {
+	b.mu.Lock()
+	if !b.down[addr] {
+		b.down[addr] = true
+		if b.isLocal[addr] {
+			b.localUp--
+		}
+	}
+	b.mu.Unlock()
+	b.balancerOf(addr).MarkDown(addr)
+}
+
+func (b *RegionalBalancer) MarkUp(addr string) {
+	b.mu.Lock()
+	if b.down[addr] {
+		delete(b.down, addr)
+		if b.isLocal[addr] {
+			b.localUp++
+		}
+	}
+	b.mu.Unlock()
+	b.balancerOf(addr).MarkUp(addr)
+}
+
+// Done notifies the load balancer of the region of addr,
+// if it tracks the requests.
+func (b *RegionalBalancer) Done(addr string) {
+	if tracker, ok := b.balancerOf(addr).(RequestTracker); ok {
+		tracker.Done(addr)
+	}
+}
+
+func (b *RegionalBalancer) balancerOf(addr string) LoadBalancer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.isLocal[addr] {
+		return b.local
+	}
+	return b.remote
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+var testRegions = map[string]string{
+	"http://us1": "us",
+	"http://us2": "us",
+	"http://eu1": "eu",
+	"http://eu2": "eu",
+}
+
+// nextAddrs returns the addresses of the next n requests on dsName.
+func nextAddrs(client *Client, dsName string, n int) map[string]bool {
+	seen := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		seen[client.GetServerAddr(dsName)] = true
+	}
+	return seen
+}
+
+func TestClientPrefersLocalRegion(t *testing.T) {
+	client := NewClientWithOptions(map[string][]string{
+		"redis1": {"http://eu1", "http://us1", "http://eu2", "http://us2"},
+	}, ClientOptions{Regions: testRegions, LocalRegion: "us"})
+	balancer := client.GetLoadBalancer("redis1")
+	assert.IsType(t, &RegionalBalancer{}, balancer)
+
+	// round robin within the local region
+	assert.Equal(t, "http://us1", client.GetServerAddr("redis1"))
+	assert.Equal(t, "http://us2", client.GetServerAddr("redis1"))
+	assert.Equal(t, "http://us1", client.GetServerAddr("redis1"))
+
+	balancer.MarkDown("http://us1")
+	assert.Equal(t, map[string]bool{"http://us2": true}, nextAddrs(client, "redis1", 4))
+
+	// falls back to the other region once the local one is down
+	balancer.MarkDown("http://us2")
+	assert.Equal(t, map[string]bool{"http://eu1": true, "http://eu2": true}, nextAddrs(client, "redis1", 4))
+	balancer.MarkDown("http://eu1")
+	assert.Equal(t, map[string]bool{"http://eu2": true}, nextAddrs(client, "redis1", 4))
+
+	balancer.MarkUp("http://us2")
+	assert.Equal(t, map[string]bool{"http://us2": true}, nextAddrs(client, "redis1", 4))
+}
+
+func TestRegionalBalancerTracksRequests(t *testing.T) {
+	newBalancer := NewRegionalBalancerFactory(testRegions, "us", NewLeastConnectionsBalancer)
+	b := newBalancer([]string{"http://us1", "http://us2", "http://eu1"})
+
+	assert.Equal(t, "http://us1", b.Next(""))
+	assert.Equal(t, "http://us2", b.Next(""))
+	b.(RequestTracker).Done("http://us2")
+	assert.Equal(t, "http://us2", b.Next(""))
+
+	b.MarkDown("http://us1")
+	b.MarkDown("http://us2")
+	assert.Equal(t, "http://eu1", b.Next(""))
+	b.(RequestTracker).Done("http://eu1")
+}
+
+func TestRegionalBalancerSingleRegion(t *testing.T) {
+	newBalancer := NewRegionalBalancerFactory(testRegions, "us", NewRoundRobinBalancer)
+	assert.IsType(t, &RoundRobinBalancer{}, newBalancer([]string{"http://eu1", "http://eu2"}))
+	assert.IsType(t, &RoundRobinBalancer{}, newBalancer([]string{"http://us1", "http://us2"}))
+
+	client := NewClientWithOptions(map[string][]string{ALL: {"http://us1", "http://eu1"}},
+		ClientOptions{Regions: testRegions})
+	assert.IsType(t, &RoundRobinBalancer{}, client.GetLoadBalancer("redis1"))
+}
+
+// restartableServer is an executor that can be stopped
+// and started again on the same address.
+type restartableServer struct {
+	t        *testing.T
+	addr     string
+	server   *fasthttp.Server
+	requests atomic.Int64
+}
+
+func newRestartableServer(t *testing.T) *restartableServer {
+	s := &restartableServer{t: t}
+	s.start("127.0.0.1:0")
+	t.Cleanup(s.stop)
+	return s
+}
+
+func (s *restartableServer) start(hostPort string) {
+	ln, err := net.Listen("tcp", hostPort)
+	if err != nil {
+		s.t.Fatalf("failed to listen: %v", err)
+	}
+	s.addr = "http://" + ln.Addr().String()
+	s.server = &fasthttp.Server{Handler: func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == "/ping" {
+			ctx.WriteString("pong")
+			return
+		}
+		s.requests.Add(1)
+		WriteResponse(ctx, Response[string]{Status: "OK"})
+	}}
+	go func() {
+		_ = s.server.Serve(ln)
+	}()
+}
+
+func (s *restartableServer) stop() {
+	_ = s.server.Shutdown()
+}
+
+func TestClientFallsBackOnFailedRequests(t *testing.T) {
+	local, remote := newRestartableServer(t), newRestartableServer(t)
+	client := NewClientWithOptions(map[string][]string{
+		"redis1": {local.addr, remote.addr},
+	}, ClientOptions{
+		Regions:          map[string]string{local.addr: "us", remote.addr: "eu"},
+		LocalRegion:      "us",
+		ProbeInterval:    10 * time.Millisecond,
+		DisableKeepAlive: true,
+	})
+	defer client.Close()
+	abort := func() error {
+		_, err := client.Abort("redis1", []string{"item1"}, "txn1")
+		return err
+	}
+
+	assert.NoError(t, abort())
+	assert.Equal(t, int64(1), local.requests.Load())
+	assert.Zero(t, remote.requests.Load())
+
+	// a request failing to reach the local executor marks it down
+	local.stop()
+	assert.ErrorContains(t, abort(), "executor "+local.addr+" is unreachable")
+	assert.NoError(t, abort())
+	assert.NoError(t, abort())
+	assert.Equal(t, int64(2), remote.requests.Load())
+
+	// the probe marks the local executor up once it is back
+	local.start(local.addr[len("http://"):])
+	assert.Eventually(t, func() bool {
+		return client.GetServerAddr("redis1") == local.addr
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, abort())
+	assert.Equal(t, int64(2), local.requests.Load())
+	assert.Equal(t, int64(2), remote.requests.Load())
+}
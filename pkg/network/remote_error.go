@@ -0,0 +1,51 @@
+package network
+
+import (
+	"errors"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+)
+
+// errCodes identifies the errors of txn that keep their identity across
+// the executors, so that the client can tell them apart with errors.Is.
+var errCodes = map[string]error{
+	"version_mismatch": txn.VersionMismatch,
+	"key_exists":       txn.KeyExists,
+	"dirty_read":       txn.DirtyRead,
+	"read_set_changed": txn.ReadSetChanged,
+	"false_assumption": txn.FalseAssumption,
+}
+
+// ErrCode returns the code of the error of txn that err wraps,
+// or "" if it wraps none of them.
+func ErrCode(err error) string {
+	for code, target := range errCodes {
+		if errors.Is(err, target) {
+			return code
+		}
+	}
+	return ""
+}
+
+// remoteError is an error reported by an executor. It reads as the
+// message of the executor and wraps the error of txn its code stands for.
+type remoteError struct {
+	msg   string
+	cause error
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.cause
+}
+
+// responseError returns the error reported by an executor with msg and code.
+func responseError(msg string, code string) error {
+	if cause, ok := errCodes[code]; ok {
+		return &remoteError{msg: msg, cause: cause}
+	}
+	return errors.New(msg)
+}
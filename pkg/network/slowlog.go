@@ -0,0 +1,25 @@
+package network
+
+import (
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"go.uber.org/zap"
+)
+
+// LogSlowRequest is deferred by the executor handlers.
+// It logs the request op at WARN level only when it took longer than
+// config.Config.SlowRequestThreshold since startTime, so that the outliers
+// are surfaced without logging every request on the hot path.
+// A non-positive threshold disables the slow request log.
+// The latency of every request is still logged at DEBUG level in debug mode.
+func LogSlowRequest(log *zap.SugaredLogger, op string, startTime time.Time, keysAndValues ...interface{}) {
+	latency := time.Since(startTime)
+	if config.Debug.DebugMode {
+		log.Debugw(op+" request", append([]interface{}{"latency", latency}, keysAndValues...)...)
+	}
+	threshold := config.Config.SlowRequestThreshold
+	if threshold > 0 && latency > threshold {
+		log.Warnw("Slow "+op+" request", append([]interface{}{"latency", latency, "threshold", threshold}, keysAndValues...)...)
+	}
+}
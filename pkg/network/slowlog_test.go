@@ -0,0 +1,52 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogSlowRequest(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(core).Sugar()
+
+	oldThreshold := config.Config.SlowRequestThreshold
+	config.Config.SlowRequestThreshold = 20 * time.Millisecond
+	defer func() { config.Config.SlowRequestThreshold = oldThreshold }()
+
+	stubHandler := func(delay time.Duration) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			startTime := time.Now()
+			defer LogSlowRequest(log, "Read", startTime, "dsName", "redis1")
+			time.Sleep(delay)
+			ctx.SetStatusCode(fasthttp.StatusOK)
+		}
+	}
+
+	t.Run("fast request is not logged", func(t *testing.T) {
+		stubHandler(0)(&fasthttp.RequestCtx{})
+		assert.Empty(t, logs.TakeAll())
+	})
+
+	t.Run("slow request is logged at WARN", func(t *testing.T) {
+		stubHandler(40 * time.Millisecond)(&fasthttp.RequestCtx{})
+		entries := logs.TakeAll()
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+			assert.Equal(t, "Slow Read request", entries[0].Message)
+			assert.Equal(t, "redis1", entries[0].ContextMap()["dsName"])
+		}
+	})
+
+	t.Run("non-positive threshold disables the log", func(t *testing.T) {
+		config.Config.SlowRequestThreshold = 0
+		stubHandler(40 * time.Millisecond)(&fasthttp.RequestCtx{})
+		assert.Empty(t, logs.TakeAll())
+	})
+}
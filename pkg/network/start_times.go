@@ -0,0 +1,82 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultStartTimeTTL is how long the executor remembers
+// the start time of a transaction after its last request.
+const DefaultStartTimeTTL = time.Minute
+
+// ErrStartTimeRegression is returned by StartTimes.Check for a start time
+// older than one already seen for the same transaction.
+var ErrStartTimeRegression = errors.New("start time regression")
+
+// StartTimes remembers the start time of the transactions seen recently,
+// so that a request carrying an older start time than a previous request
+// of the same transaction, which would read a snapshot in its past, is
+// rejected instead of mixing two snapshots. This happens when the clock or
+// the time oracle of a client misbehaves.
+type StartTimes struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	seen map[string]startTimeEntry
+	// nextSweep is when the expired entries are dropped next.
+	nextSweep time.Time
+}
+
+type startTimeEntry struct {
+	startTime int64
+	expires   time.Time
+}
+
+// NewStartTimes returns a tracker forgetting a transaction ttl after
+// its last request, DefaultStartTimeTTL if ttl is not positive.
+func NewStartTimes(ttl time.Duration) *StartTimes {
+	if ttl <= 0 {
+		ttl = DefaultStartTimeTTL
+	}
+	return &StartTimes{
+		ttl:       ttl,
+		seen:      make(map[string]startTimeEntry),
+		nextSweep: time.Now().Add(ttl),
+	}
+}
+
+// Check records startTime for the transaction txnId, failing with
+// ErrStartTimeRegression if it is older than the latest start time seen
+// for it. The requests that don't carry their TxnId are not checked.
+func (s *StartTimes) Check(txnId string, startTime int64) error {
+	if txnId == "" {
+		return nil
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.After(s.nextSweep) {
+		for id, entry := range s.seen {
+			if now.After(entry.expires) {
+				delete(s.seen, id)
+			}
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+
+	entry, ok := s.seen[txnId]
+	if ok && now.After(entry.expires) {
+		ok = false
+	}
+	if ok && startTime < entry.startTime {
+		return fmt.Errorf("%w: transaction %s sent start time %d after %d",
+			ErrStartTimeRegression, txnId, startTime, entry.startTime)
+	}
+	if !ok || startTime > entry.startTime {
+		entry.startTime = startTime
+	}
+	entry.expires = now.Add(s.ttl)
+	s.seen[txnId] = entry
+	return nil
+}
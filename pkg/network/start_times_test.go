@@ -0,0 +1,28 @@
+package network
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartTimesCheck(t *testing.T) {
+	s := NewStartTimes(50 * time.Millisecond)
+	assert.NoError(t, s.Check("txn1", 100))
+	assert.NoError(t, s.Check("txn1", 100))
+	assert.NoError(t, s.Check("txn1", 120))
+	err := s.Check("txn1", 110)
+	assert.True(t, errors.Is(err, ErrStartTimeRegression), err)
+	assert.NoError(t, s.Check("txn2", 10))
+	assert.NoError(t, s.Check("", 1))
+
+	// the transaction is forgotten after the TTL
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, s.Check("txn1", 10))
+	s.mu.Lock()
+	_, ok := s.seen["txn2"]
+	s.mu.Unlock()
+	assert.False(t, ok)
+}
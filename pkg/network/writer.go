@@ -0,0 +1,41 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
+	"github.com/valyala/fasthttp"
+)
+
+// WriteResponse encodes resp with the configured codec into the response body.
+// Codecs implementing serializer.StreamSerializer write into the body directly,
+// so no intermediate []byte holding the whole message is allocated.
+// If resp cannot be encoded, the response becomes a 500 Internal Server Error.
+func WriteResponse(ctx *fasthttp.RequestCtx, resp any) {
+	var err error
+	if ss, ok := config.Config.Codec.(serializer.StreamSerializer); ok {
+		err = ss.SerializeTo(ctx, resp)
+	} else {
+		var bs []byte
+		bs, err = config.Config.Codec.Serialize(resp)
+		if err == nil {
+			_, err = ctx.Write(bs)
+		}
+	}
+	if err != nil {
+		ctx.Error("failed to encode the response: "+err.Error(), fasthttp.StatusInternalServerError)
+	}
+}
+
+// DecodeRequest decodes the body of ctx into req with the configured codec,
+// the one WriteResponse encodes with, so that every handler decodes the
+// messages of the client the same way. If the body cannot be decoded,
+// the response becomes a 400 Bad Request naming kind and false is returned.
+func DecodeRequest(ctx *fasthttp.RequestCtx, kind string, req any) bool {
+	if err := config.Config.Codec.Deserialize(ctx.PostBody(), req); err != nil {
+		ctx.Error(fmt.Sprintf("invalid %s request body: %s", kind, err.Error()), fasthttp.StatusBadRequest)
+		return false
+	}
+	return true
+}
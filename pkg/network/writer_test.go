@@ -0,0 +1,89 @@
+package network
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func newLargeReadResponse() ReadResponse {
+	return ReadResponse{
+		Status: "OK",
+		Data: &redis.RedisItem{
+			RKey:      "item1",
+			RValue:    strings.Repeat("x", 1<<20),
+			RTxnState: config.COMMITTED,
+			RTValid:   time.Now().UnixMicro(),
+			RTLease:   time.Now(),
+			RVersion:  "1",
+		},
+		ItemType: GetItemType("redis1"),
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	resp := newLargeReadResponse()
+	var ctx fasthttp.RequestCtx
+	WriteResponse(&ctx, resp)
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+
+	var got ReadResponse
+	err := config.Config.Codec.Deserialize(ctx.Response.Body(), &got)
+	assert.NoError(t, err)
+	assert.Equal(t, resp.Status, got.Status)
+	assert.Equal(t, resp.Data.Value(), got.Data.Value())
+
+	t.Run("the response can not be encoded", func(t *testing.T) {
+		var ctx fasthttp.RequestCtx
+		WriteResponse(&ctx, Response[chan int]{Status: "OK", Data: make(chan int)})
+		assert.Equal(t, fasthttp.StatusInternalServerError, ctx.Response.StatusCode())
+		assert.Contains(t, string(ctx.Response.Body()), "failed to encode the response")
+	})
+}
+
+func TestDecodeRequest(t *testing.T) {
+	req := CommitRequest{DsName: "redis1", TCommit: 100}
+	bs, err := config.Config.Codec.Serialize(req)
+	assert.NoError(t, err)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.SetBody(bs)
+	var got CommitRequest
+	assert.True(t, DecodeRequest(&ctx, "commit", &got))
+	assert.Equal(t, req, got)
+
+	t.Run("the body can not be decoded", func(t *testing.T) {
+		var ctx fasthttp.RequestCtx
+		ctx.Request.SetBody([]byte("{"))
+		var got CommitRequest
+		assert.False(t, DecodeRequest(&ctx, "commit", &got))
+		assert.Equal(t, fasthttp.StatusBadRequest, ctx.Response.StatusCode())
+		assert.Contains(t, string(ctx.Response.Body()), "invalid commit request body")
+	})
+}
+
+func BenchmarkWriteResponseBuffered(b *testing.B) {
+	resp := newLargeReadResponse()
+	var ctx fasthttp.RequestCtx
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx.Response.ResetBody()
+		respBytes, _ := config.Config.Codec.Serialize(resp)
+		ctx.Write(respBytes)
+	}
+}
+
+func BenchmarkWriteResponseStreamed(b *testing.B) {
+	resp := newLargeReadResponse()
+	var ctx fasthttp.RequestCtx
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx.Response.ResetBody()
+		WriteResponse(&ctx, resp)
+	}
+}
@@ -3,6 +3,7 @@ package serializer
 import (
 	"bytes"
 	"encoding/gob"
+	"io"
 )
 
 type GobSerializer struct {
@@ -22,6 +23,10 @@ func (s *GobSerializer) Serialize(data any) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+func (s *GobSerializer) SerializeTo(w io.Writer, data any) error {
+	return gob.NewEncoder(w).Encode(data)
+}
+
 func (s *GobSerializer) Deserialize(bs []byte, tar any) error {
 	buffer := bytes.NewBuffer(bs)
 	decoder := gob.NewDecoder(buffer)
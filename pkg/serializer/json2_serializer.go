@@ -1,10 +1,25 @@
 package serializer
 
-import jsoniter "github.com/json-iterator/go"
+import (
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+)
 
 var json2 = jsoniter.ConfigCompatibleWithStandardLibrary
 
+// json2Number is json2 decoding the numbers into interface values as json.Number.
+var json2Number = jsoniter.Config{
+	EscapeHTML:             true,
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+	UseNumber:              true,
+}.Froze()
+
 type JSON2Serializer struct {
+	// UseNumber decodes the numbers stored into an interface value as
+	// json.Number instead of float64, like JSONSerializer.UseNumber.
+	UseNumber bool
 }
 
 func NewJSON2Serializer() *JSON2Serializer {
@@ -15,6 +30,13 @@ func (s *JSON2Serializer) Serialize(data any) ([]byte, error) {
 	return json2.Marshal(data)
 }
 
+func (s *JSON2Serializer) SerializeTo(w io.Writer, data any) error {
+	return json2.NewEncoder(w).Encode(data)
+}
+
 func (s *JSON2Serializer) Deserialize(bs []byte, tar any) error {
+	if s.UseNumber {
+		return json2Number.Unmarshal(bs, tar)
+	}
 	return json2.Unmarshal(bs, tar)
 }
@@ -1,8 +1,17 @@
 package serializer
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
 
 type JSONSerializer struct {
+	// UseNumber decodes the numbers stored into an interface value as
+	// json.Number instead of float64, which is exact only up to 2^53.
+	// The numbers decoded into typed fields are exact either way.
+	UseNumber bool
 }
 
 func NewJSONSerializer() *JSONSerializer {
@@ -13,6 +22,26 @@ func (s *JSONSerializer) Serialize(data any) ([]byte, error) {
 	return json.Marshal(data)
 }
 
+func (s *JSONSerializer) SerializeTo(w io.Writer, data any) error {
+	return json.NewEncoder(w).Encode(data)
+}
+
 func (s *JSONSerializer) Deserialize(bs []byte, tar any) error {
-	return json.Unmarshal(bs, tar)
+	if !s.UseNumber {
+		return json.Unmarshal(bs, tar)
+	}
+	return decodeNumbers(json.NewDecoder(bytes.NewReader(bs)), tar)
+}
+
+// decodeNumbers decodes the only value read by dec into tar with json.Number
+// numbers, and fails like json.Unmarshal if anything but spaces follows.
+func decodeNumbers(dec *json.Decoder, tar any) error {
+	dec.UseNumber()
+	if err := dec.Decode(tar); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
 }
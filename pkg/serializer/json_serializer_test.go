@@ -1,6 +1,8 @@
 package serializer
 
 import (
+	"encoding/json"
+	"math"
 	"reflect"
 	"testing"
 )
@@ -53,3 +55,37 @@ func TestJSONSerializer_Deserialize(t *testing.T) {
 		t.Errorf("Original and deserialized data do not match. Original = %v, Deserialized = %v", testStruct, loadedStruct)
 	}
 }
+
+func TestJSONSerializer_UseNumber(t *testing.T) {
+	// 2^63-2 is not a float64, it would be rounded to 2^63
+	const data = `{"TValid":9223372036854775806}`
+
+	for name, s := range map[string]Serializer{
+		"json":     &JSONSerializer{UseNumber: true},
+		"jsoniter": &JSON2Serializer{UseNumber: true},
+	} {
+		var loaded map[string]any
+		if err := s.Deserialize([]byte(data), &loaded); err != nil {
+			t.Fatalf("%s: Deserialize() error = %v", name, err)
+		}
+		n, ok := loaded["TValid"].(json.Number)
+		if !ok {
+			t.Fatalf("%s: TValid is %T, want json.Number", name, loaded["TValid"])
+		}
+		if got, err := n.Int64(); err != nil || got != math.MaxInt64-1 {
+			t.Errorf("%s: TValid = %v (%v), want %d", name, n, err, int64(math.MaxInt64-1))
+		}
+
+		if err := s.Deserialize([]byte(data+"}"), &loaded); err == nil {
+			t.Errorf("%s: Deserialize() should fail on trailing data", name)
+		}
+	}
+
+	var loaded map[string]any
+	if err := NewJSONSerializer().Deserialize([]byte(data), &loaded); err != nil {
+		t.Fatalf("Deserialize() error = %v", err)
+	}
+	if _, ok := loaded["TValid"].(float64); !ok {
+		t.Errorf("TValid is %T, want float64 by default", loaded["TValid"])
+	}
+}
@@ -1,6 +1,14 @@
 package serializer
 
+import "io"
+
 type Serializer interface {
 	Serialize(data any) ([]byte, error)
 	Deserialize(bs []byte, tar any) error
 }
+
+// StreamSerializer is implemented by serializers that can encode data
+// directly into a writer without building the whole message in memory first.
+type StreamSerializer interface {
+	SerializeTo(w io.Writer, data any) error
+}
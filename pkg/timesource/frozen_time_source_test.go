@@ -0,0 +1,27 @@
+package timesource
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrozenTimeSource(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	ts := NewFrozenTimeSource(start)
+
+	first, err := ts.GetTime("common")
+	assert.NoError(t, err)
+	second, _ := ts.GetTime("common")
+	assert.Equal(t, start.UnixMicro(), first)
+	assert.Equal(t, first, second)
+
+	ts.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), ts.Now())
+	advanced, _ := ts.GetTime("common")
+	assert.Equal(t, first+time.Second.Microseconds(), advanced)
+
+	ts.Set(start)
+	assert.Equal(t, start, ts.Now())
+}
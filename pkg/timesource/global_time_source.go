@@ -1,7 +1,12 @@
 package timesource
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/backoff"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/valyala/fasthttp"
 )
 
@@ -17,7 +22,25 @@ func NewGlobalTimeSource(url string) *GlobalTimeSource {
 	}
 }
 
+// GetTime fetches a timestamp from the time oracle.
+// A failed fetch is retried up to config.Config.TimeOracleRetries times,
+// waiting config.Config.TimeOracleRetryDelay before each retry,
+// so that a transient failure of the oracle does not fail the transaction.
 func (g *GlobalTimeSource) GetTime(mode string) (int64, error) {
+	b := backoff.NewConstant(config.Config.TimeOracleRetryDelay)
+	timeValue, err := g.fetchTime()
+	for i := 0; err != nil && i < config.Config.TimeOracleRetries; i++ {
+		time.Sleep(b.Next())
+		timeValue, err = g.fetchTime()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get time from the oracle after %d retries: %w",
+			max(config.Config.TimeOracleRetries, 0), err)
+	}
+	return timeValue, nil
+}
+
+func (g *GlobalTimeSource) fetchTime() (int64, error) {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
@@ -35,7 +58,7 @@ func (g *GlobalTimeSource) GetTime(mode string) (int64, error) {
 
 	// 检查状态码
 	if resp.StatusCode() != fasthttp.StatusOK {
-		return 0, err
+		return 0, fmt.Errorf("time oracle responded with status %d", resp.StatusCode())
 	}
 
 	// 读取响应体
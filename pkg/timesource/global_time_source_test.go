@@ -0,0 +1,50 @@
+package timesource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFlakyOracle starts an oracle that fails the first failures requests.
+func newFlakyOracle(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("12345"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestGlobalTimeSourceRetry(t *testing.T) {
+	retries, delay := config.Config.TimeOracleRetries, config.Config.TimeOracleRetryDelay
+	defer func() {
+		config.Config.TimeOracleRetries, config.Config.TimeOracleRetryDelay = retries, delay
+	}()
+	config.Config.TimeOracleRetries = 2
+	config.Config.TimeOracleRetryDelay = time.Millisecond
+
+	t.Run("a single failure is masked", func(t *testing.T) {
+		server, calls := newFlakyOracle(t, 1)
+		ts, err := NewGlobalTimeSource(server.URL).GetTime("common")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(12345), ts)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("the error is returned after the retries", func(t *testing.T) {
+		server, calls := newFlakyOracle(t, 3)
+		_, err := NewGlobalTimeSource(server.URL).GetTime("common")
+		assert.ErrorContains(t, err, "after 2 retries")
+		assert.Equal(t, int32(3), calls.Load())
+	})
+}
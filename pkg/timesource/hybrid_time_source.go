@@ -44,6 +44,23 @@ func (ts *HybridTimeSource) updatePhysicalTime() {
 	}
 }
 
+// Snapshot returns the physical and the logical parts of the current time of ts.
+func (ts *HybridTimeSource) Snapshot() (physical, logical int64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.physicalTime, ts.logicalTime
+}
+
+// Restore sets the current time of ts to a time returned by Snapshot.
+// The physical time is still refreshed from the wall clock
+// at the next update interval.
+func (ts *HybridTimeSource) Restore(physical, logical int64) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.physicalTime = physical
+	ts.logicalTime = logical
+}
+
 func (ts *HybridTimeSource) GetTime(mode string) (int64, error) {
 
 	ts.mu.Lock()
@@ -0,0 +1,22 @@
+package timesource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHybridTimeSourceSnapshot(t *testing.T) {
+	ts := NewHybridTimeSource(60000, 10)
+	_, _ = ts.GetTime("common")
+	physical, logical := ts.Snapshot()
+	assert.Equal(t, int64(1), logical)
+
+	want, _ := ts.GetTime("common")
+	_, _ = ts.GetTime("common")
+	_, _ = ts.GetTime("common")
+
+	ts.Restore(physical, logical)
+	got, _ := ts.GetTime("common")
+	assert.Equal(t, want, got)
+}
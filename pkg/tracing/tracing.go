@@ -0,0 +1,98 @@
+// Package tracing wires the transaction lifecycle into OpenTelemetry.
+//
+// The tracer provider defaults to a no-op one, so no span is recorded
+// until SetTracerProvider is called.
+package tracing
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// InstrumentationName is the name of the tracer creating the spans.
+const InstrumentationName = "github.com/oreo-dtx-lab/oreo"
+
+type providerHolder struct {
+	tp trace.TracerProvider
+}
+
+var provider atomic.Value
+
+var propagator = propagation.TraceContext{}
+
+func init() {
+	provider.Store(providerHolder{tp: noop.NewTracerProvider()})
+}
+
+// SetTracerProvider sets the provider of the tracer used by Oreo.
+// A nil tp restores the no-op provider.
+func SetTracerProvider(tp trace.TracerProvider) {
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	provider.Store(providerHolder{tp: tp})
+}
+
+// Tracer returns the tracer used by Oreo.
+func Tracer() trace.Tracer {
+	return provider.Load().(providerHolder).tp.Tracer(InstrumentationName)
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// Inject writes the trace context in ctx into the headers of an outgoing request.
+func Inject(ctx context.Context, header *fasthttp.RequestHeader) {
+	if ctx == nil {
+		return
+	}
+	propagator.Inject(ctx, requestHeaderCarrier{header})
+}
+
+// Extract returns a context carrying the trace context sent along with the request.
+func Extract(reqCtx *fasthttp.RequestCtx) context.Context {
+	return propagator.Extract(context.Background(), requestHeaderCarrier{&reqCtx.Request.Header})
+}
+
+// requestHeaderCarrier adapts fasthttp request headers to propagation.TextMapCarrier.
+type requestHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+var _ propagation.TextMapCarrier = requestHeaderCarrier{}
+
+func (c requestHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c requestHeaderCarrier) Set(key string, value string) {
+	c.header.Set(key, value)
+}
+
+func (c requestHeaderCarrier) Keys() []string {
+	keys := make([]string, 0)
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// End records err on span if it is not nil and ends span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,74 @@
+package txn
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/backoff"
+)
+
+// asyncPollInterval is the longest wait between two checks of WaitAsync.
+const asyncPollInterval = 10 * time.Millisecond
+
+// AsyncStats counts the operations the transactions of the process
+// have left running in the background after Commit returned.
+type AsyncStats struct {
+	// Commits are the commit phases run in the background.
+	Commits int64
+	// TSRDeletes are the deletions of the TSRs of committed transactions.
+	TSRDeletes int64
+	// Recoveries are the transactions whose records are recommitted
+	// after their commit phase failed.
+	Recoveries int64
+}
+
+// Total returns the number of operations still running.
+func (s AsyncStats) Total() int64 {
+	return s.Commits + s.TSRDeletes + s.Recoveries
+}
+
+var asyncCommits, asyncTSRDeletes, asyncRecoveries atomic.Int64
+
+// goAsync runs f in the background, counted by counter while it runs.
+func goAsync(counter *atomic.Int64, f func()) {
+	counter.Add(1)
+	go func() {
+		defer counter.Add(-1)
+		f()
+	}()
+}
+
+// goBackground runs f in the background like goAsync,
+// and Start waits for it before starting the transaction again.
+func (t *Transaction) goBackground(counter *atomic.Int64, f func()) {
+	t.background.Add(1)
+	goAsync(counter, func() {
+		defer t.background.Done()
+		f()
+	})
+}
+
+// PendingAsync returns the operations left running in the background
+// by the transactions, e.g. to tell whether a process shutting down
+// would leave records prepared or TSRs behind.
+func PendingAsync() AsyncStats {
+	return AsyncStats{
+		Commits:    asyncCommits.Load(),
+		TSRDeletes: asyncTSRDeletes.Load(),
+		Recoveries: asyncRecoveries.Load(),
+	}
+}
+
+// WaitAsync waits up to timeout for the operations left running in the
+// background to finish, and reports whether they have.
+func WaitAsync(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	b := backoff.WithCap(backoff.NewExponential(time.Millisecond), asyncPollInterval)
+	for PendingAsync().Total() > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(b.Next())
+	}
+	return true
+}
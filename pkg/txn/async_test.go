@@ -0,0 +1,72 @@
+package txn_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockedCommitConnection blocks the first commit of a record until release is closed.
+type blockedCommitConnection struct {
+	*memkv.Connection
+	blocked atomic.Bool
+	release chan struct{}
+}
+
+func (c *blockedCommitConnection) ConditionalUpdate(key string, item txn.DataItem, doCreate bool) (string, error) {
+	if item.TxnState() == config.COMMITTED && c.blocked.CompareAndSwap(false, true) {
+		<-c.release
+	}
+	return c.Connection.ConditionalUpdate(key, item, doCreate)
+}
+
+// TestAsyncLevelTwoRollsForward tests that the records left prepared by a
+// transaction committing in the background under AsyncLevelTwo are read
+// as committed, since its TSR is committed before Commit returns.
+func TestAsyncLevelTwoRollsForward(t *testing.T) {
+	commitInForeground(t)
+	asyncLevel := config.Config.AsyncLevel
+	defer func() { config.Config.AsyncLevel = asyncLevel }()
+	config.Config.AsyncLevel = config.AsyncLevelTwo
+
+	conn := &blockedCommitConnection{
+		Connection: memkv.NewConnection(&redis.RedisItemFactory{}),
+		release:    make(chan struct{}),
+	}
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		_ = tx.AddDatastore(txn.NewDatastore("redis1", conn, &redis.RedisItemFactory{}))
+		return tx
+	}
+
+	writer := newTxn()
+	assert.NoError(t, writer.Start())
+	assert.NoError(t, writer.Write("redis1", "item1", "v1"))
+	assert.NoError(t, writer.Commit())
+	assert.True(t, writer.Stats().AsyncCommit)
+
+	// the commit phase is stuck with the record still prepared
+	assert.Eventually(t, conn.blocked.Load, time.Second, time.Millisecond)
+	item, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PREPARED, item.TxnState())
+
+	reader := newTxn()
+	assert.NoError(t, reader.Start())
+	var value string
+	assert.NoError(t, reader.Read("redis1", "item1", &value))
+	assert.Equal(t, "v1", value)
+	assert.NoError(t, reader.Commit())
+
+	close(conn.release)
+	assert.True(t, txn.WaitAsync(time.Second))
+	item, err = conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+}
@@ -0,0 +1,48 @@
+package txn
+
+import (
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"golang.org/x/sync/errgroup"
+)
+
+// BulkLoad writes values[i] under keys[i] to conn as committed records,
+// bypassing the prepare and commit phases of a transaction.
+//
+// It is meant to set up a dataset before any transaction runs:
+// the records are visible to every transaction, and a record already
+// in the datastore is overwritten whatever its state.
+func BulkLoad(conn Connector, factory DataItemFactory, keys []string, values []any) error {
+	if len(keys) != len(values) {
+		return errors.Errorf("bulk load got %d keys but %d values", len(keys), len(values))
+	}
+
+	items := make([]DataItem, 0, len(keys))
+	for i, key := range keys {
+		bs, err := config.Config.Serializer.Serialize(values[i])
+		if err != nil {
+			return err
+		}
+		items = append(items, factory.NewDataItem(ItemOptions{
+			Key:       key,
+			Value:     string(bs),
+			TxnState:  config.COMMITTED,
+			TValid:    0,
+			TLease:    time.Now(),
+			LinkedLen: 1,
+			Version:   "1",
+		}))
+	}
+
+	var eg errgroup.Group
+	for _, it := range items {
+		item := it
+		eg.Go(func() error {
+			_, err := conn.PutItem(item.Key(), item)
+			return err
+		})
+	}
+	return eg.Wait()
+}
@@ -0,0 +1,172 @@
+package txn
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrCloneCommitted is returned by Commit when another clone
+// of the transaction has already tried to commit.
+var ErrCloneCommitted = errors.Errorf("another clone of the transaction has committed")
+
+// CloneableDatastore is implemented by the datastores whose buffered
+// reads and writes can be copied for Transaction.Clone.
+type CloneableDatastore interface {
+	// CloneFor returns a datastore sharing the connection of the datastore,
+	// with its own copy of the buffered reads and writes, for txn.
+	CloneFor(txn *Transaction) Datastorer
+}
+
+// cloneGroup is shared by a transaction and its clones.
+type cloneGroup struct {
+	// committer is the first of them to have called Commit.
+	committer atomic.Pointer[Transaction]
+}
+
+// Clone returns a started transaction with a deep copy of the records
+// read and written by t so far, so that the two can go on separately
+// from the same state, e.g. to explore two outcomes of t.
+//
+// The clone shares the datastore connections, the time source and the
+// start time of t, and reads the same snapshot, but has its own TxnId.
+// Clone may be called again on t or on a clone, and the first transaction
+// of the group to call Commit is the only one that may commit: Commit
+// aborts the others with ErrCloneCommitted, even if that first commit fails.
+//
+// Clone fails unless t is started and holds no lock taken by
+// ReadForUpdate, and every datastore of t implements CloneableDatastore.
+// The transition hook of t is not carried over.
+func (t *Transaction) Clone() (*Transaction, error) {
+	if err := t.CheckState(config.STARTED); err != nil {
+		return nil, err
+	}
+	if len(t.lockedKeys) > 0 {
+		return nil, errors.New("a transaction holding locks can't be cloned")
+	}
+	for name, ds := range t.dataStoreMap {
+		if _, ok := ds.(CloneableDatastore); !ok {
+			return nil, errors.Errorf("datastore %s can't be cloned", name)
+		}
+	}
+
+	clone := &Transaction{
+		TxnId:              config.Config.IdGenerator.GenerateId(),
+		TxnStartTime:       t.TxnStartTime,
+		groupKeyMaintainer: *NewGroupKeyMaintainer(),
+		dataStoreMap:       make(map[string]Datastorer, len(t.dataStoreMap)),
+		timeSource:         t.timeSource,
+		locker:             t.locker,
+		isReadOnly:         t.isReadOnly,
+		isSnapshot:         t.isSnapshot,
+		writeCount:         t.writeCount,
+		writeSet:           copyNestedSet(t.writeSet),
+		writeSetSize:       t.writeSetSize,
+		commitWait:         t.commitWait,
+		isolation:          t.isolation,
+		protocol:           t.protocol,
+		client:             t.client,
+		isRemote:           t.isRemote,
+		StateMachine:       NewStateMachine(),
+		debugStart:         time.Now(),
+	}
+	t.readSetMu.Lock()
+	if t.readSet != nil {
+		clone.readSet = make(map[string]map[string]string, len(t.readSet))
+		for dsName, versions := range t.readSet {
+			clone.readSet[dsName] = make(map[string]string, len(versions))
+			for key, version := range versions {
+				clone.readSet[dsName][key] = version
+			}
+		}
+	}
+	t.readSetMu.Unlock()
+
+	for _, ds := range t.dataStoreMap {
+		if err := clone.AddDatastore(ds.(CloneableDatastore).CloneFor(clone)); err != nil {
+			return nil, err
+		}
+	}
+	if err := clone.SetState(config.STARTED); err != nil {
+		return nil, err
+	}
+	clone.ctx, clone.span = tracing.Start(context.Background(), "Transaction")
+	clone.span.SetAttributes(attribute.String("txn.id", clone.TxnId),
+		attribute.String("txn.clone_of", t.TxnId))
+
+	if t.clones == nil {
+		t.clones = &cloneGroup{}
+	}
+	clone.clones = t.clones
+	return clone, nil
+}
+
+// claimCommit makes t the only transaction of its clone group that may
+// commit, failing if another one has called Commit first.
+func (t *Transaction) claimCommit() error {
+	// a transaction that is not started fails to commit anyway
+	if t.clones == nil || t.CheckState(config.STARTED) != nil {
+		return nil
+	}
+	if !t.clones.committer.CompareAndSwap(nil, t) && t.clones.committer.Load() != t {
+		return ErrCloneCommitted
+	}
+	return nil
+}
+
+func copyNestedSet(set map[string]map[string]struct{}) map[string]map[string]struct{} {
+	if set == nil {
+		return nil
+	}
+	copied := make(map[string]map[string]struct{}, len(set))
+	for dsName, keys := range set {
+		copied[dsName] = make(map[string]struct{}, len(keys))
+		for key := range keys {
+			copied[dsName][key] = struct{}{}
+		}
+	}
+	return copied
+}
+
+// CloneFor returns a datastore for txn with copies of the records
+// read and written through r, which r and the copy can then change
+// without affecting each other.
+func (r *Datastore) CloneFor(txn *Transaction) Datastorer {
+	clone := NewDatastore(r.Name, r.conn, r.itemFactory)
+	clone.se = r.se
+	clone.Txn = txn
+	for key, item := range r.readCache {
+		clone.readCache[key] = r.copyItem(item)
+	}
+	for key, item := range r.writeCache {
+		clone.writeCache[key] = r.copyItem(item)
+	}
+	for key, invisible := range r.invisibleSet {
+		clone.invisibleSet[key] = invisible
+	}
+	for key, predicate := range r.validationSet {
+		clone.validationSet[key] = predicate
+	}
+	return clone
+}
+
+// copyItem returns a copy of item made by the item factory of r.
+func (r *Datastore) copyItem(item DataItem) DataItem {
+	return r.itemFactory.NewDataItem(ItemOptions{
+		Key:          item.Key(),
+		Value:        item.Value(),
+		GroupKeyList: item.GroupKeyList(),
+		TxnState:     item.TxnState(),
+		TValid:       item.TValid(),
+		TLease:       item.TLease(),
+		Prev:         item.Prev(),
+		LinkedLen:    item.LinkedLen(),
+		IsDeleted:    item.IsDeleted(),
+		Version:      item.Version(),
+	})
+}
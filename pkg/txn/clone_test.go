@@ -0,0 +1,71 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxnClone(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	tx := txn.NewTransaction()
+	_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("memkv", "item1", "v1"))
+	assert.NoError(t, tx.Write("memkv", "item2", "v1"))
+	assert.NoError(t, tx.Delete("memkv", "item2"))
+
+	clone, err := tx.Clone()
+	assert.NoError(t, err)
+	assert.NotEqual(t, tx.TxnId, clone.TxnId)
+	assert.Equal(t, tx.TxnStartTime, clone.TxnStartTime)
+	for _, tr := range []*txn.Transaction{tx, clone} {
+		var value string
+		assert.NoError(t, tr.Read("memkv", "item1", &value))
+		assert.Equal(t, "v1", value)
+		assert.EqualError(t, tr.Read("memkv", "item2", &value), txn.KeyNotFound.Error())
+	}
+
+	// the clones go on separately
+	assert.NoError(t, tx.Write("memkv", "item1", "v2"))
+	assert.NoError(t, clone.Write("memkv", "item1", "v3"))
+	var value string
+	assert.NoError(t, clone.Read("memkv", "item1", &value))
+	assert.Equal(t, "v3", value)
+
+	// only the first to commit does
+	assert.NoError(t, clone.Commit())
+	assert.ErrorIs(t, tx.Commit(), txn.ErrCloneCommitted)
+	time.Sleep(100 * time.Millisecond)
+	item, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, util.ToJSONString("v3"), item.Value())
+}
+
+// TestTxnCloneRestart tests that a transaction started
+// again after one of its clones has committed is free to commit.
+func TestTxnCloneRestart(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	tx := txn.NewTransaction()
+	_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("memkv", "item1", "v1"))
+
+	clone, err := tx.Clone()
+	assert.NoError(t, err)
+	assert.NoError(t, clone.Commit())
+	assert.ErrorIs(t, tx.Commit(), txn.ErrCloneCommitted)
+
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("memkv", "item2", "v2"))
+	assert.NoError(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+	item, err := conn.GetItem("item2")
+	assert.NoError(t, err)
+	assert.Equal(t, util.ToJSONString("v2"), item.Value())
+}
@@ -0,0 +1,213 @@
+package txn
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	. "github.com/oreo-dtx-lab/oreo/pkg/logger"
+)
+
+// CommitFailure is returned by Datastorer.Commit when
+// some records could not be updated to the COMMITTED state.
+type CommitFailure struct {
+	DsName string
+	Keys   []string
+	Err    error
+	// Recoverer commits the records of Keys again, nil if they cannot be.
+	Recoverer CommitRecoverer
+}
+
+func (e *CommitFailure) Error() string {
+	return fmt.Sprintf("failed to commit %d records in %s: %v", len(e.Keys), e.DsName, e.Err)
+}
+
+func (e *CommitFailure) Unwrap() error {
+	return e.Err
+}
+
+// CommitRecoverer keeps the records whose commit failed, apart from the
+// datastore and the transaction, so that they can be committed again later.
+type CommitRecoverer interface {
+	// RecommitKeys commits the records of keys kept by the failed Commit.
+	// It returns a *CommitFailure with the keys that still failed.
+	RecommitKeys(keys []string) error
+}
+
+// recoveryTask recommits the keys of the datastores of a transaction
+// once the TSR confirms that the transaction has committed, and deletes
+// the TSRs once every record is committed.
+type recoveryTask struct {
+	txnId    string
+	urls     []string
+	gm       *GroupKeyMaintainer
+	failures []*CommitFailure
+	attempts int
+}
+
+// recoveryQueue runs the recovery tasks in the background,
+// so that the records whose commit failed are committed without
+// waiting for their leases to expire and a reader to roll them forward.
+type recoveryQueue struct {
+	mu    sync.Mutex
+	tasks []*recoveryTask
+	// wake is signaled when a task is added
+	wake      chan struct{}
+	startOnce sync.Once
+}
+
+var commitRecoveryQueue = &recoveryQueue{wake: make(chan struct{}, 1)}
+
+// add queues task, which is counted as a pending recovery until it is finished.
+func (q *recoveryQueue) add(task *recoveryTask) {
+	q.startOnce.Do(func() {
+		go q.run()
+	})
+	asyncRecoveries.Add(1)
+	q.mu.Lock()
+	q.tasks = append(q.tasks, task)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run waits config.Config.CommitRecoveryInterval before every attempt of
+// the tasks queued. The configuration is only read while a task is pending,
+// so that it can be changed once WaitAsync has returned.
+func (q *recoveryQueue) run() {
+	for range q.wake {
+		for q.pending() {
+			time.Sleep(config.Config.CommitRecoveryInterval)
+			q.mu.Lock()
+			tasks := q.tasks
+			q.tasks = nil
+			q.mu.Unlock()
+			for _, task := range tasks {
+				if task.run() {
+					asyncRecoveries.Add(-1)
+					continue
+				}
+				q.mu.Lock()
+				q.tasks = append(q.tasks, task)
+				q.mu.Unlock()
+			}
+		}
+	}
+}
+
+// pending reports whether a task is queued.
+func (q *recoveryQueue) pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tasks) > 0
+}
+
+// run makes one attempt to recommit the keys.
+// It returns true if the task is finished, successfully or not.
+func (task *recoveryTask) run() bool {
+	task.attempts++
+	committed, err := task.resolveTSR()
+	if err != nil {
+		Log.Warnw("failed to resolve the TSR for commit recovery",
+			"txnId", task.txnId, "attempts", task.attempts, "cause", err)
+		return task.attempts >= config.Config.CommitRecoveryAttempts
+	}
+	if !committed {
+		// the readers have aborted the transaction and will roll the records back
+		Log.Infow("transaction is aborted, commit recovery is dropped", "txnId", task.txnId)
+		return true
+	}
+
+	remaining := task.failures[:0]
+	var cause error
+	for _, failure := range task.failures {
+		err := failure.Recoverer.RecommitKeys(failure.Keys)
+		if err == nil {
+			continue
+		}
+		var stillFailed *CommitFailure
+		if errors.As(err, &stillFailed) {
+			failure.Keys = stillFailed.Keys
+		}
+		remaining = append(remaining, failure)
+		cause = err
+	}
+	task.failures = remaining
+	if len(remaining) == 0 {
+		Log.Infow("commit recovery succeeded", "txnId", task.txnId)
+		// no record refers to the TSRs anymore
+		if err := task.gm.DeleteGroupKey(task.urls); err != nil {
+			Log.Warnw("failed to delete the TSRs after commit recovery",
+				"txnId", task.txnId, "cause", err)
+		}
+		return true
+	}
+	if task.attempts >= config.Config.CommitRecoveryAttempts {
+		Log.Errorw("commit recovery gave up", "txnId", task.txnId, "cause", cause)
+		return true
+	}
+	return false
+}
+
+// resolveTSR uses the TSRs of the transaction as the source of truth.
+// A missing TSR is created as COMMITTED so that no reader can abort
+// the transaction from now on, and is deleted along with the others once
+// the records are committed; an ABORTED one means the readers already did.
+func (task *recoveryTask) resolveTSR() (bool, error) {
+	for _, url := range task.urls {
+		if task.gm.CreateGroupKey([]string{url}, config.COMMITTED) == 1 {
+			continue
+		}
+		groupKey, err := task.gm.GetSingleGroupKey(url)
+		if err != nil {
+			return false, err
+		}
+		if groupKey.TxnState == config.ABORTED {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// commitWithRecovery runs the commit phase on every datastore.
+// The transaction has committed by then, so the records the datastores
+// fail to commit are handed to the recovery queue, along with copies of
+// the TSR urls and the connectors, which outlive the transaction.
+// It reports whether recovery has been scheduled, in which case the
+// recovery deletes the TSRs instead.
+func (t *Transaction) commitWithRecovery() bool {
+	var mu sync.Mutex
+	var failures []*CommitFailure
+	t.forEachDatastore(func(ds Datastorer) {
+		err := ds.Commit()
+		if err == nil {
+			return
+		}
+		var failure *CommitFailure
+		if !errors.As(err, &failure) || failure.Recoverer == nil {
+			Log.Errorw("commit phase failed", "txnId", t.TxnId, "ds", ds.GetName(), "cause", err)
+			return
+		}
+		Log.Warnw("commit phase failed, scheduling recovery", "txnId", t.TxnId,
+			"ds", ds.GetName(), "keys", strings.Join(failure.Keys, ","), "cause", failure.Err)
+		mu.Lock()
+		failures = append(failures, failure)
+		mu.Unlock()
+	})
+	if len(failures) == 0 {
+		return false
+	}
+	commitRecoveryQueue.add(&recoveryTask{
+		txnId:    t.TxnId,
+		urls:     append([]string(nil), t.GroupKeyUrls...),
+		gm:       &GroupKeyMaintainer{connMap: maps.Clone(t.groupKeyMaintainer.connMap)},
+		failures: failures,
+	})
+	return true
+}
@@ -0,0 +1,80 @@
+package txn_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// commitFailingConnection fails to update the records to the COMMITTED
+// state while failing is set, as if the connection was lost after the
+// transaction has committed.
+type commitFailingConnection struct {
+	tsrCountingConnection
+	failing atomic.Bool
+}
+
+func (c *commitFailingConnection) ConditionalUpdate(key string, value txn.DataItem, doCreate bool) (string, error) {
+	if c.failing.Load() && value.TxnState() == config.COMMITTED {
+		return "", errors.New("connection reset")
+	}
+	return c.Connection.ConditionalUpdate(key, value, doCreate)
+}
+
+// TestTxnCommitRecoveryAfterStart tests that the records whose commit
+// failed are recommitted once the connection is back, even though the
+// transaction has been started again meanwhile, and that the TSRs the
+// recovery relies on are deleted afterwards.
+func TestTxnCommitRecoveryAfterStart(t *testing.T) {
+	// the recoveries left in the background read the interval
+	txn.WaitAsync(time.Second)
+	oldInterval := config.Config.CommitRecoveryInterval
+	config.Config.CommitRecoveryInterval = 10 * time.Millisecond
+	defer func() {
+		txn.WaitAsync(time.Second)
+		config.Config.CommitRecoveryInterval = oldInterval
+	}()
+
+	conn := &commitFailingConnection{tsrCountingConnection: tsrCountingConnection{Connection: memkv.NewConnection(&redis.RedisItemFactory{})}}
+	conn.failing.Store(true)
+	tx := txn.NewTransaction()
+	_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+
+	tCommits := make(map[string]int64)
+	for _, key := range []string{"item1", "item2"} {
+		assert.NoError(t, tx.Start())
+		assert.NoError(t, tx.Write("memkv", key, "v1"))
+		assert.NoError(t, tx.Commit())
+		tCommits[key] = tx.TxnCommitTime
+	}
+	// the next start waits for the commit phase of the last transaction
+	assert.NoError(t, tx.Start())
+	conn.failing.Store(false)
+
+	assert.Eventually(t, func() bool {
+		for key := range tCommits {
+			item, err := conn.GetItem(key)
+			if err != nil || item.TxnState() != config.COMMITTED {
+				return false
+			}
+		}
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+	for key, tCommit := range tCommits {
+		item, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		assert.Equal(t, tCommit, item.TValid())
+	}
+
+	assert.Eventually(t, func() bool {
+		return conn.deletes.Load() == conn.creates.Load()
+	}, time.Second, 10*time.Millisecond)
+	assert.NotZero(t, conn.creates.Load())
+}
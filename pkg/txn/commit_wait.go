@@ -0,0 +1,57 @@
+package txn
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/backoff"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	. "github.com/oreo-dtx-lab/oreo/pkg/logger"
+)
+
+// commitWaitPollInterval is the longest wait between two reads of the
+// time source while waiting for it to pass the commit time.
+const commitWaitPollInterval = 10 * time.Millisecond
+
+// SetCommitWait overrides config.Config.CommitWait for the transaction.
+func (t *Transaction) SetCommitWait(enabled bool) {
+	t.commitWait = &enabled
+}
+
+// commitWaitEnabled reports whether Commit waits for the commit time
+// to be in the past before returning.
+func (t *Transaction) commitWaitEnabled() bool {
+	if t.commitWait != nil {
+		return *t.commitWait
+	}
+	return config.Config.CommitWait
+}
+
+// waitCommitTime blocks until the commit time of the transaction is in
+// the past for every client sharing its time source up to an offset of
+// config.Config.ClockUncertainty: the time source must first return a
+// time past TxnCommitTime, then the uncertainty is waited out on top.
+//
+// Timestamps are compared as returned by the time source, whatever their
+// unit, so the wait holds for the local clock and the time oracle alike.
+func (t *Transaction) waitCommitTime() error {
+	start := time.Now()
+	defer func() {
+		t.stats.CommitWaitDuration = time.Since(start)
+	}()
+
+	b := backoff.WithCap(backoff.NewExponential(time.Millisecond), commitWaitPollInterval)
+	for {
+		now, err := t.getTime("commit-wait")
+		if err != nil {
+			return fmt.Errorf("the transaction is committed, but failed to wait for its commit time: %w", err)
+		}
+		if now > t.TxnCommitTime {
+			break
+		}
+		time.Sleep(b.Next())
+	}
+	time.Sleep(config.Config.ClockUncertainty)
+	Log.Debugw("commit wait ends", "txnId", t.TxnId, "latency", time.Since(start))
+	return nil
+}
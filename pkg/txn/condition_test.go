@@ -0,0 +1,151 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxnCondition(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+		assert.NoError(t, tx.Start())
+		return tx
+	}
+	tx := newTxn()
+	assert.NoError(t, tx.Write("memkv", "balance", map[string]int{"Amount": 30}))
+	assert.NoError(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+
+	withdraw := func(above string) error {
+		tx := newTxn()
+		assert.NoError(t, tx.AddCondition("memkv", txn.PredicateInfo{
+			ItemKey: "balance", Op: txn.PredicateGreaterThan, Field: "Amount", Value: above,
+		}))
+		assert.NoError(t, tx.Write("memkv", "withdrawal", "v1"))
+		return tx.Commit()
+	}
+	assert.ErrorIs(t, withdraw("50"), txn.ErrConditionFailed)
+	_, err := conn.GetItem("withdrawal")
+	assert.EqualError(t, err, txn.KeyNotFound.Error())
+
+	assert.NoError(t, withdraw("20"))
+	time.Sleep(100 * time.Millisecond)
+	item, err := conn.GetItem("withdrawal")
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+
+	tx = newTxn()
+	assert.ErrorContains(t, tx.AddCondition("memkv", txn.PredicateInfo{ItemKey: "balance", Op: "unknown"}),
+		"unknown predicate operator")
+}
+
+// TestTxnConditionPaths tests that the commit conditions
+// are checked whichever way the transaction commits, or are rejected.
+func TestTxnConditionPaths(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	newTxn := func(protocol config.Protocol) *txn.Transaction {
+		tx := txn.NewTransaction()
+		tx.SetProtocol(protocol)
+		_ = tx.AddDatastore(txn.NewDatastore("ds1", conn, &redis.RedisItemFactory{}))
+		_ = tx.AddDatastore(txn.NewDatastore("ds2", conn, &redis.RedisItemFactory{}))
+		assert.NoError(t, tx.Start())
+		return tx
+	}
+	tx := newTxn(config.Oreo2PC)
+	assert.NoError(t, tx.Write("ds2", "balance", map[string]int{"Amount": 30}))
+	assert.NoError(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+
+	tests := []struct {
+		name     string
+		protocol config.Protocol
+		// write is the datastore written, none if empty
+		write string
+	}{
+		{"other datastore written", config.Oreo2PC, "ds1"},
+		{"read-only", config.Oreo2PC, ""},
+		{"cherry garcia", config.CherryGarcia, "ds1"},
+		{"cherry garcia read-only", config.CherryGarcia, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commitIf := func(above string) error {
+				tx := newTxn(tt.protocol)
+				assert.NoError(t, tx.AddCondition("ds2", txn.PredicateInfo{
+					ItemKey: "balance", Op: txn.PredicateGreaterThan, Field: "Amount", Value: above,
+				}))
+				if tt.write != "" {
+					assert.NoError(t, tx.Write(tt.write, "withdrawal-"+tt.name, "v1"))
+				}
+				return tx.Commit()
+			}
+			assert.ErrorIs(t, commitIf("50"), txn.ErrConditionFailed)
+			// the write may still be preparing, it is rolled back
+			// into a deletion once prepared
+			assert.Eventually(t, func() bool {
+				item, err := conn.GetItem("withdrawal-" + tt.name)
+				if err != nil {
+					return err.Error() == txn.KeyNotFound.Error()
+				}
+				return item.IsDeleted() && item.TxnState() == config.COMMITTED
+			}, time.Second, time.Millisecond)
+			assert.NoError(t, commitIf("20"))
+		})
+	}
+
+	t.Run("one phase", func(t *testing.T) {
+		tx := newTxn(config.OnePhase)
+		assert.Error(t, tx.AddCondition("ds2", txn.PredicateInfo{
+			ItemKey: "balance", Op: txn.PredicateNotDeleted,
+		}))
+
+		// nor may the protocol be switched once a condition is added
+		tx = newTxn(config.Oreo2PC)
+		assert.NoError(t, tx.AddCondition("ds2", txn.PredicateInfo{
+			ItemKey: "balance", Op: txn.PredicateNotDeleted,
+		}))
+		assert.NoError(t, tx.Write("ds1", "onephase", "v1"))
+		tx.SetProtocol(config.OnePhase)
+		assert.Error(t, tx.Commit())
+		_, err := conn.GetItem("onephase")
+		assert.EqualError(t, err, txn.KeyNotFound.Error())
+	})
+}
+
+// TestTxnConditionLargeNumber tests that the conditions
+// tell apart integers above 2^53, which are equal as float64.
+func TestTxnConditionLargeNumber(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+		assert.NoError(t, tx.Start())
+		return tx
+	}
+	tx := newTxn()
+	assert.NoError(t, tx.Write("memkv", "clock", map[string]int64{"Stamp": 1<<53 + 1}))
+	assert.NoError(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+
+	commitIf := func(op txn.PredicateOp, value string) error {
+		tx := newTxn()
+		assert.NoError(t, tx.AddCondition("memkv", txn.PredicateInfo{
+			ItemKey: "clock", Op: op, Field: "Stamp", Value: value,
+		}))
+		assert.NoError(t, tx.Write("memkv", "tick", "v1"))
+		return tx.Commit()
+	}
+	assert.ErrorIs(t, commitIf(txn.PredicateEquals, "9007199254740992"), txn.ErrConditionFailed)
+	assert.ErrorIs(t, commitIf(txn.PredicateGreaterThan, "9007199254740993"), txn.ErrConditionFailed)
+	assert.NoError(t, commitIf(txn.PredicateEquals, "9007199254740993.0"))
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, commitIf(txn.PredicateGreaterThan, "9007199254740992"))
+}
@@ -0,0 +1,59 @@
+package txn
+
+import (
+	"github.com/go-errors/errors"
+)
+
+// ConflictError is returned by Commit when the transaction is aborted because
+// another transaction has changed, or is writing, one of its records.
+// Unlike the other commit errors, retrying the transaction may succeed.
+type ConflictError struct {
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// IsConflict reports whether err has aborted a transaction
+// because of a conflict with another transaction.
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// conflictErrors are the errors a conflict fails the prepare phase with.
+// The executors report them with their error codes, so that the errors
+// of a remote prepare wrap them as well.
+var conflictErrors = []error{
+	VersionMismatch,
+	KeyExists,
+	DirtyRead,
+	ReadSetChanged,
+	FalseAssumption,
+}
+
+// isConflictCause reports whether the prepare phase has failed with cause
+// because of a conflict, rather than a failure of a datastore or the network.
+func isConflictCause(cause error) bool {
+	for _, conflictErr := range conflictErrors {
+		if errors.Is(cause, conflictErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareFailed returns the error of a prepare phase that has failed with
+// cause, which is a *ConflictError if it is a conflict.
+func prepareFailed(cause error) error {
+	err := errors.Errorf("prepare phase failed: %w", cause)
+	if isConflictCause(cause) {
+		return &ConflictError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package txn
+
+import (
+	"testing"
+
+	"github.com/go-errors/errors"
+)
+
+func TestTxnCommitConflict(t *testing.T) {
+	testCases := []struct {
+		name       string
+		prepareErr error
+		conflict   bool
+	}{
+		{"a version mismatch", errors.New(VersionMismatch), true},
+		{"a version mismatch reported by the executor",
+			errors.Join(errors.New("Remote prepare failed"), errors.New(VersionMismatch)), true},
+		{"a read set validation", errors.Errorf("%w: key has changed since it was read", ReadSetChanged), true},
+		{"a false assumption", errors.New(FalseAssumption), true},
+		{"a validation of an unknown status", errors.New("validation failed due to unknown status"), false},
+		{"a message mentioning a conflict", errors.New("connection refused: version mismatch"), false},
+		{"a datastore failure", errors.New("connection refused"), false},
+		{"a prepare timeout", ErrPrepareTimeout, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			txn := NewTransaction()
+			_ = txn.AddDatastore(&trackedDatastore{name: "ds", tracker: &peakTracker{}, prepareErr: tc.prepareErr})
+			if err := txn.Start(); err != nil {
+				t.Fatalf("Error starting transaction: %s", err)
+			}
+			writeAll(t, txn, "ds")
+
+			err := txn.Commit()
+			if err == nil {
+				t.Fatalf("Expected the commit to fail")
+			}
+			if IsConflict(err) != tc.conflict {
+				t.Errorf("Expected IsConflict to be %v for %v", tc.conflict, err)
+			}
+			if !errors.Is(err, tc.prepareErr) {
+				t.Errorf("Expected the error to wrap %v, got %v", tc.prepareErr, err)
+			}
+		})
+	}
+
+	if IsConflict(nil) {
+		t.Errorf("Expected nil not to be a conflict")
+	}
+}
@@ -1,5 +1,13 @@
 package txn
 
+import (
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/backoff"
+	. "github.com/oreo-dtx-lab/oreo/pkg/logger"
+	"golang.org/x/sync/errgroup"
+)
+
 type Connector interface {
 	Connect() error
 	GetItem(key string) (DataItem, error)
@@ -11,4 +19,156 @@ type Connector interface {
 	Put(name string, value any) error
 	Delete(name string) error
 	AtomicCreate(name string, value any) (string, error)
+	Close() error
+}
+
+// ConditionalUpdateRequest is a single conditional update in a batch.
+type ConditionalUpdateRequest struct {
+	Key      string
+	Item     DataItem
+	DoCreate bool
+}
+
+// BatchConnector is implemented by connectors that can apply a set of
+// conditional updates in one request: either all of them succeed or none
+// does. A FallibleBatchConnector may break that promise.
+type BatchConnector interface {
+	// ConditionalUpdateBatch returns the new versions in the order of reqs.
+	// It returns VersionMismatch if the condition of any request fails.
+	ConditionalUpdateBatch(reqs []ConditionalUpdateRequest) ([]string, error)
+}
+
+// FallibleBatchConnector is implemented by BatchConnectors whose
+// ConditionalUpdateBatch is not always atomic, such as DynamoDB falling back
+// to updating the items one by one when a batch is too large or throttled.
+// Its ConditionalUpdateAtomicBatch always is.
+type FallibleBatchConnector interface {
+	BatchConnector
+
+	// MaxAtomicBatch returns the largest batch ConditionalUpdateAtomicBatch accepts.
+	MaxAtomicBatch() int
+
+	// ConditionalUpdateAtomicBatch is ConditionalUpdateBatch without the
+	// fallback: it fails without writing anything instead.
+	ConditionalUpdateAtomicBatch(reqs []ConditionalUpdateRequest) ([]string, error)
+}
+
+// ConditionalUpdateResult is the outcome of a single request in a bulk update.
+type ConditionalUpdateResult struct {
+	Version string
+	Err     error
+}
+
+// BulkConnector is implemented by connectors that can send a set of
+// conditional updates in one round trip but apply each of them independently.
+type BulkConnector interface {
+	// ConditionalUpdateBulk returns the result of each request in the order of reqs.
+	// A failed condition is reported as VersionMismatch in the result of that request,
+	// while the returned error is only set if the whole bulk fails.
+	ConditionalUpdateBulk(reqs []ConditionalUpdateRequest) ([]ConditionalUpdateResult, error)
+}
+
+// GroupKeyScanner is implemented by connectors that can find the records
+// written by a transaction, so that it can be rolled back by its group key
+// without knowing the keys it has written.
+type GroupKeyScanner interface {
+	// KeysByGroupKey returns the keys of the records
+	// whose group key list contains groupKey.
+	KeysByGroupKey(groupKey string) ([]string, error)
+}
+
+// Pinger is implemented by connectors that can check that their
+// datastore is reachable. Ping bounds its own wait.
+type Pinger interface {
+	Ping() error
+}
+
+// SchemaEnsurer is implemented by connectors whose datastore must have
+// tables, collections or keyspaces set up before it can store the records.
+type SchemaEnsurer interface {
+	// EnsureSchema creates the structures the records are stored in if they
+	// are missing, and checks that the existing ones can store them. It is
+	// idempotent, and may be called before Connect.
+	EnsureSchema() error
+}
+
+// PrimaryReader is implemented by connectors that may serve GetItem
+// from replicas lagging behind the primary.
+type PrimaryReader interface {
+	// GetItemFromPrimary retrieves the item of key from the primary.
+	GetItemFromPrimary(key string) (DataItem, error)
+}
+
+// GetLatestItem retrieves the item of key from the primary if conn
+// serves GetItem from replicas, and by GetItem otherwise. It is used by
+// the reads that must see the latest version of a record, those checking
+// a version or deciding whether to roll a record back.
+func GetLatestItem(conn Connector, key string) (DataItem, error) {
+	if pr, ok := conn.(PrimaryReader); ok {
+		return pr.GetItemFromPrimary(key)
+	}
+	return conn.GetItem(key)
+}
+
+// WarmUpKey is the throwaway key read by WarmUp.
+const WarmUpKey = "warmup"
+
+// WarmUp establishes the pooled connections of conn by starting parallelism
+// workers, each of which reads WarmUpKey iterations times.
+//
+// The key is not expected to exist, so KeyNotFound is ignored;
+// the first other error is returned.
+func WarmUp(conn Connector, parallelism, iterations int) error {
+	var eg errgroup.Group
+	for i := 0; i < parallelism; i++ {
+		eg.Go(func() error {
+			for j := 0; j < iterations; j++ {
+				_, err := conn.Get(WarmUpKey)
+				if err != nil && err.Error() != KeyNotFound.Error() {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// maxConnectInterval caps the backoff of ConnectWithRetry.
+const maxConnectInterval = 30 * time.Second
+
+// ConnectWithRetry calls conn.Connect up to attempts times, so that a
+// datastore which is still starting up does not fail its clients.
+// The wait starts at interval and doubles after every failure.
+//
+// The error of the last attempt is returned.
+func ConnectWithRetry(name string, conn Connector, attempts int, interval time.Duration) error {
+	return withRetry(name, "connect", conn.Connect, attempts, interval)
+}
+
+// EnsureSchemaWithRetry calls EnsureSchema on conn if it is a SchemaEnsurer,
+// retrying like ConnectWithRetry. It does nothing for the other connectors.
+func EnsureSchemaWithRetry(name string, conn Connector, attempts int, interval time.Duration) error {
+	ensurer, ok := conn.(SchemaEnsurer)
+	if !ok {
+		return nil
+	}
+	return withRetry(name, "ensure the schema", ensurer.EnsureSchema, attempts, interval)
+}
+
+// withRetry calls f up to attempts times, waiting between the attempts
+// with an exponential backoff starting at interval.
+func withRetry(name string, what string, f func() error, attempts int, interval time.Duration) error {
+	b := backoff.WithCap(backoff.NewExponential(interval), maxConnectInterval)
+	var err error
+	for i := 1; ; i++ {
+		err = f()
+		if err == nil || i >= attempts {
+			return err
+		}
+		wait := b.Next()
+		Log.Warnw("failed to "+what+", retrying", "ds", name,
+			"attempt", i, "attempts", attempts, "wait", wait, "cause", err)
+		time.Sleep(wait)
+	}
 }
@@ -0,0 +1,157 @@
+package txn
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingConnector is a stub Connector that only counts Get calls.
+type countingConnector struct {
+	Connector
+	getTimes atomic.Int64
+	getErr   error
+}
+
+func (c *countingConnector) Get(name string) (string, error) {
+	c.getTimes.Add(1)
+	if c.getErr != nil {
+		return "", c.getErr
+	}
+	return "", errors.New(KeyNotFound)
+}
+
+func TestWarmUp(t *testing.T) {
+	conn := &countingConnector{}
+	err := WarmUp(conn, 8, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(40), conn.getTimes.Load())
+}
+
+func TestWarmUpZeroParallelism(t *testing.T) {
+	conn := &countingConnector{}
+	err := WarmUp(conn, 0, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), conn.getTimes.Load())
+}
+
+func TestWarmUpReturnsError(t *testing.T) {
+	conn := &countingConnector{getErr: errors.New("connection refused")}
+	err := WarmUp(conn, 4, 3)
+	assert.EqualError(t, err, "connection refused")
+	// every worker stops at its first failure
+	assert.Equal(t, int64(4), conn.getTimes.Load())
+}
+
+// flakyConnector fails the first failures calls to Connect.
+type flakyConnector struct {
+	Connector
+	failures     int
+	connectTimes int
+}
+
+func (c *flakyConnector) Connect() error {
+	c.connectTimes++
+	if c.connectTimes <= c.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	conn := &flakyConnector{failures: 2}
+	err := ConnectWithRetry("flaky", conn, 5, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, conn.connectTimes)
+}
+
+func TestConnectWithRetryGivesUp(t *testing.T) {
+	conn := &flakyConnector{failures: 5}
+	err := ConnectWithRetry("flaky", conn, 3, time.Millisecond)
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 3, conn.connectTimes)
+}
+
+func TestConnectWithRetryNoAttempts(t *testing.T) {
+	// Connect is still called once
+	conn := &flakyConnector{}
+	err := ConnectWithRetry("flaky", conn, 0, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, conn.connectTimes)
+}
+
+// flakySchemaConnector fails the first failures calls to EnsureSchema.
+type flakySchemaConnector struct {
+	Connector
+	failures    int
+	ensureTimes int
+}
+
+func (c *flakySchemaConnector) EnsureSchema() error {
+	c.ensureTimes++
+	if c.ensureTimes <= c.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestEnsureSchemaWithRetry(t *testing.T) {
+	conn := &flakySchemaConnector{failures: 2}
+	err := EnsureSchemaWithRetry("flaky", conn, 5, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, conn.ensureTimes)
+
+	// the connectors without a schema are left alone
+	assert.NoError(t, EnsureSchemaWithRetry("flaky", &flakyConnector{failures: 1}, 1, time.Millisecond))
+}
+
+// versionItem is a stub DataItem that only has a version.
+type versionItem struct {
+	DataItem
+	version string
+}
+
+func (i versionItem) Version() string { return i.version }
+
+// replicaConnector is a stub Connector whose GetItem is served
+// by a replica lagging behind the primary.
+type replicaConnector struct {
+	Connector
+	primary map[string]string
+	replica map[string]string
+}
+
+func (c *replicaConnector) GetItem(key string) (DataItem, error) {
+	return lookupVersion(c.replica, key)
+}
+
+func (c *replicaConnector) GetItemFromPrimary(key string) (DataItem, error) {
+	return lookupVersion(c.primary, key)
+}
+
+func lookupVersion(versions map[string]string, key string) (DataItem, error) {
+	version, ok := versions[key]
+	if !ok {
+		return nil, errors.New(KeyNotFound)
+	}
+	return versionItem{version: version}, nil
+}
+
+func TestCheckReadVersionReadsPrimary(t *testing.T) {
+	conn := &replicaConnector{
+		primary: map[string]string{"item1": "2", "item2": "1"},
+		replica: map[string]string{"item1": "1"},
+	}
+
+	// the replica has not seen the update of item1 nor the creation of item2
+	assert.Error(t, CheckReadVersion(conn, "item1", "1"))
+	assert.NoError(t, CheckReadVersion(conn, "item1", "2"))
+	assert.Error(t, CheckReadVersion(conn, "item2", ""))
+
+	item, err := GetLatestItem(conn, "item1")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", item.Version())
+}
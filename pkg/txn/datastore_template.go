@@ -5,6 +5,7 @@ import (
 	"cmp"
 	"fmt"
 	"log"
+	"maps"
 	"slices"
 	"strings"
 	"sync"
@@ -18,7 +19,10 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-var _ Datastorer = (*Datastore)(nil)
+var (
+	_ Datastorer      = (*Datastore)(nil)
+	_ CommitRecoverer = (*uncommittedRecords)(nil)
+)
 
 const (
 	EMPTY         string = ""
@@ -29,6 +33,21 @@ type PredicateInfo struct {
 	State     config.State
 	ItemKey   string
 	LeaseTime time.Time
+
+	// ReadVersion marks the predicate of a record read but not written
+	// by the transaction, which must still have Version when it commits.
+	// An empty Version means the record must still be absent.
+	ReadVersion bool
+	Version     string
+
+	// Op, Field and Value make the predicate a commit condition on the
+	// record of ItemKey, checked against the stored record when the
+	// transaction is prepared. Field is a top-level field of the value,
+	// the whole value if empty, and Value is the JSON encoding of the
+	// operand of Op. PredicateVersionEquals compares Version instead.
+	Op    PredicateOp `json:",omitempty"`
+	Field string      `json:",omitempty"`
+	Value string      `json:",omitempty"`
 }
 
 // Datastore represents a datastorer implementation using the underlying connector.
@@ -67,6 +86,12 @@ type Datastore struct {
 
 	// mu is the mutex used for locking the Datastore.
 	mu sync.Mutex
+
+	// rolledBack is the number of records rolled back by the last Abort.
+	rolledBack int
+
+	// locked are the records locked by LockRecord, as stored in the data store.
+	locked map[string]DataItem
 }
 
 // NewDatastore creates a new instance of Datastore with the given name and connection.
@@ -103,7 +128,7 @@ func (r *Datastore) Read(key string, value any) error {
 		return r.getValue(item, value)
 	}
 	// if the record is in the readCache
-	if item, ok := r.readCache[key]; ok {
+	if item, ok := r.cachedRead(key); ok {
 		return r.getValue(item, value)
 	}
 	if r.Txn.isRemote {
@@ -115,9 +140,20 @@ func (r *Datastore) Read(key string, value any) error {
 }
 
 func (r *Datastore) readFromRemote(key string, value any) error {
-	item, readStrategy, groupKeyList, err := r.Txn.RemoteRead(r.Name, key)
+	item, err := r.remoteRead(key, nil)
 	if err != nil {
-		return errors.Join(errors.New("Remote read failed"), err)
+		return err
+	}
+	r.readCache[item.Key()] = item
+	return r.getValue(item, value)
+}
+
+// remoteRead reads a record through the executor, its value projected
+// on fields if any, and tracks the read in the validation and read sets.
+func (r *Datastore) remoteRead(key string, fields []string) (DataItem, error) {
+	item, readStrategy, groupKeyList, err := r.Txn.remoteRead(r.Name, key, fields)
+	if err != nil {
+		return nil, errors.Join(errors.New("Remote read failed"), err)
 	}
 	// fmt.Printf("item: %v\n readStrategy: %v\n error: %v", item, readStrategy, err)
 	switch readStrategy {
@@ -133,16 +169,23 @@ func (r *Datastore) readFromRemote(key string, value any) error {
 		}
 	}
 
+	// the executor clears the version of the records it trims,
+	// such reads cannot be validated and are left out of the read set
+	if item.Version() != "" {
+		r.Txn.recordRead(r.Name, key, item.Version())
+	}
 	if item.IsDeleted() {
-		return errors.New(KeyNotFound)
+		return nil, errors.New(KeyNotFound)
 	}
-	r.readCache[item.Key()] = item
-	return r.getValue(item, value)
+	return item, nil
 }
 
 func (r *Datastore) readFromConn(key string, value any) error {
 	item, err := r.conn.GetItem(key)
 	if err != nil {
+		if err.Error() == KeyNotFound.Error() {
+			r.Txn.recordRead(r.Name, key, "")
+		}
 		errMsg := err.Error() + " at GetItem in " + r.Name
 		return errors.New(errMsg)
 	}
@@ -162,6 +205,8 @@ func (r *Datastore) readFromConn(key string, value any) error {
 	}
 
 	logicFunc := func(curItem DataItem, isFound bool) error {
+		// the read depends on the stored record rather than on the version found in its chain
+		r.Txn.recordRead(r.Name, key, resItem.Version())
 		// if the record has been deleted
 		if !isFound || curItem.IsDeleted() {
 			if curItem.IsDeleted() {
@@ -192,6 +237,53 @@ func (r *Datastore) readFromConn(key string, value any) error {
 	return r.treatAsCommitted(resItem, logicFunc)
 }
 
+// ReadAt reads the version of a record that was visible at timestamp ts.
+// It walks the Prev chain from the latest version until it finds the first
+// version whose TValid is not later than ts. Unlike Read, it bypasses both
+// caches and does not put the result into the readCache, so it has no effect
+// on the transaction's read set. In remote mode the executor walks the chain.
+//
+// It returns KeyNotFound if ts predates every version kept in the chain or
+// if the visible version is a deletion.
+func (r *Datastore) ReadAt(key string, ts int64, value any) error {
+	if r.Txn.isRemote {
+		item, err := r.Txn.remoteReadAt(r.Name, key, ts)
+		if err != nil {
+			return errors.Join(errors.New("Remote read failed"), err)
+		}
+		if item.IsDeleted() {
+			return errors.New(KeyNotFound)
+		}
+		return r.getValue(item, value)
+	}
+
+	item, err := r.conn.GetItem(key)
+	if err != nil {
+		errMsg := err.Error() + " at GetItem in " + r.Name
+		return errors.New(errMsg)
+	}
+
+	curItem, err := r.basicVisibilityProcessor(item)
+	if err != nil {
+		return err
+	}
+
+	for curItem.TValid() > ts {
+		if curItem.Prev() == "" {
+			return errors.New(KeyNotFound)
+		}
+		curItem, err = r.getPrevItem(curItem)
+		if err != nil {
+			return err
+		}
+	}
+
+	if curItem.IsDeleted() {
+		return errors.New(KeyNotFound)
+	}
+	return r.getValue(curItem, value)
+}
+
 // dirtyReadChecker will drop an item if it violates repeatable read rules.
 func (r *Datastore) dirtyReadChecker(item DataItem) (DataItem, error) {
 	if _, ok := r.invisibleSet[item.Key()]; ok {
@@ -234,29 +326,33 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 		return item, nil
 	}
 
+	// function to resolve the record according to its TSRs
+	resolveFunc := func(groupKeyList []GroupKey) (DataItem, error) {
+		if CommittedForAll(groupKeyList) {
+			// if all the group keys are in COMMITTED state
+			// Items in PREPARED state do not contain a valid TValid
+			// update its TCommit first
+			tCommit := int64(0)
+			for _, gk := range groupKeyList {
+				tCommit = max(tCommit, gk.TCommit)
+			}
+			item.SetTValid(tCommit)
+			return rollforwardFunc()
+		}
+		// or at least one of the group keys is in ABORTED state
+		return rollbackFunc()
+	}
+
 	if item.TxnState() == config.PREPARED {
 		groupKeyList, err := r.Txn.GetGroupKeyFromItem(item)
 		if err == nil {
-			if CommittedForAll(groupKeyList) {
-				// if all the group keys are in COMMITTED state
-				// Items in PREPARED state do not contain a valid TValid
-				// update its TCommit first
-				tCommit := int64(0)
-				for _, gk := range groupKeyList {
-					tCommit = max(tCommit, gk.TCommit)
-				}
-				item.SetTValid(tCommit)
-				return rollforwardFunc()
-			} else {
-				// or at least one of the group keys is in ABORTED state
-				return rollbackFunc()
-			}
+			return resolveFunc(groupKeyList)
 		}
 		// if at least one of the group key is not found
 		// and if t_lease has expired
 		// that is, item's TLease < current time
 		// we should roll back the record
-		if item.TLease().Before(time.Now()) {
+		if item.TLease().Before(r.Txn.now()) {
 			successNum := r.Txn.CreateGroupKeyFromItem(item, config.ABORTED)
 			if successNum == 0 {
 				return nil, fmt.Errorf("failed to rollback the record because none of the group keys are created")
@@ -281,7 +377,7 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 		// we should try check the previous record
 		if r.Txn.TxnStartTime < item.TValid() {
 			// Origin Cherry Garcia would do
-			if config.Debug.CherryGarciaMode {
+			if r.Txn.Protocol() == config.CherryGarcia {
 				return nil, errors.New(ReadFailed)
 			}
 
@@ -303,6 +399,12 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 			return nil, errors.New(ReadFailed)
 		} else {
 			switch config.Config.ReadStrategy {
+			case config.WaitThenResolve:
+				groupKeyList, err := r.waitForGroupKey(item, config.Config.ReadWaitTime)
+				if err != nil {
+					return nil, errors.New(ReadFailed)
+				}
+				return resolveFunc(groupKeyList)
 			case config.AssumeCommit:
 				r.validationSet[item.GroupKeyList()] = PredicateInfo{
 					ItemKey: item.Key(),
@@ -325,13 +427,32 @@ func (r *Datastore) basicVisibilityProcessor(item DataItem) (DataItem, error) {
 	return nil, errors.New(KeyNotFound)
 }
 
+// waitForGroupKey polls the TSRs of a PREPARED record until all of them
+// are found or waitTime elapses. The wait never outlives the record's TLease.
+func (r *Datastore) waitForGroupKey(item DataItem, waitTime time.Duration) ([]GroupKey, error) {
+	if untilExpiry := item.TLease().Sub(r.Txn.now()); untilExpiry < waitTime {
+		waitTime = untilExpiry
+	}
+	deadline := time.Now().Add(waitTime)
+	for {
+		groupKeyList, err := r.Txn.GetGroupKeyFromItem(item)
+		if err == nil {
+			return groupKeyList, nil
+		}
+		if !time.Now().Add(RETRYINTERVAL).Before(deadline) {
+			return nil, err
+		}
+		time.Sleep(RETRYINTERVAL)
+	}
+}
+
 // treatAsCommitted treats a DataItem as committed, finds a corresponding version
 // according to its timestamp, and performs the given logic function on it.
 func (r *Datastore) treatAsCommitted(item DataItem, logicFunc func(DataItem, bool) error) error {
 	curItem := item
 	for i := 1; i <= config.Config.MaxRecordLength; i++ {
 
-		if curItem.TValid() < r.Txn.TxnStartTime {
+		if curItem.TValid() < r.Txn.readTime() {
 			// find the corresponding version,
 			// do some business logic.
 			return logicFunc(curItem, true)
@@ -455,18 +576,28 @@ func (r *Datastore) conditionalUpdate(cacheItem DataItem) error {
 		logger.Log.Debugw("Datastore.conditionalUpdate() finishes", "LatencyInFunc", time.Since(debugStart))
 	}()
 
+	dbItem, err := r.itemToUpdate(cacheItem)
+	if err != nil {
+		return err
+	}
+	return r.doConditionalUpdate(cacheItem, dbItem)
+}
+
+// itemToUpdate returns the record that cacheItem is going to overwrite,
+// or nil if the record does not exist or is invisible to the transaction.
+func (r *Datastore) itemToUpdate(cacheItem DataItem) (DataItem, error) {
 	// if the cacheItem follows read-modified-write pattern,
 	// it already has a valid version, we can skip the read step.
 	if cacheItem.Version() != "" {
 		dbItem, _ := r.readCache[cacheItem.Key()]
-		return r.doConditionalUpdate(cacheItem, dbItem)
+		return dbItem, nil
 	}
 
 	// else we read from connection
 	err := r.readFromConn(cacheItem.Key(), nil)
 	if err != nil {
 		if !strings.Contains(err.Error(), "key not found") {
-			return err
+			return nil, err
 		}
 	}
 	dbItem, _ := r.readCache[cacheItem.Key()]
@@ -474,7 +605,7 @@ func (r *Datastore) conditionalUpdate(cacheItem DataItem) error {
 	if res, ok := r.invisibleSet[cacheItem.Key()]; ok && res {
 		dbItem = nil
 	}
-	return r.doConditionalUpdate(cacheItem, dbItem)
+	return dbItem, nil
 }
 
 // truncate truncates the linked list of DataItems
@@ -555,16 +686,21 @@ func (r *Datastore) updateMetadata(newItem DataItem, oldItem DataItem) (DataItem
 		return nil, err
 	}
 
+	// a record locked by the transaction is updated over its lock
+	if lock, ok := r.locked[newItem.Key()]; ok {
+		newItem.SetVersion(lock.Version())
+	}
+
 	newItem.SetTxnState(config.PREPARED)
 	newItem.SetTValid(r.Txn.TxnCommitTime)
 	// TODO: time.Now() is temporary
-	newItem.SetTLease(time.Now().Add(config.Config.LeaseTime))
+	newItem.SetTLease(r.Txn.now().Add(config.Config.LeaseTime))
 	return newItem, nil
 }
 
 func (r *Datastore) rollbackFromConn(key string) error {
 
-	item, err := r.conn.GetItem(key)
+	item, err := GetLatestItem(r.conn, key)
 	if err != nil {
 		return err
 	}
@@ -576,7 +712,7 @@ func (r *Datastore) rollbackFromConn(key string) error {
 	// 	return errors.New("rollback failed due to wrong txnId")
 	// }
 
-	if item.TLease().Before(time.Now()) {
+	if item.TLease().Before(r.Txn.now()) {
 		successNum := r.Txn.CreateGroupKeyFromItem(item, config.ABORTED)
 		if successNum == 0 {
 			return fmt.Errorf("failed to rollback the record because none of the group keys are created")
@@ -588,6 +724,9 @@ func (r *Datastore) rollbackFromConn(key string) error {
 }
 
 func (r *Datastore) validate() error {
+	if err := EvaluateConditions(r.conn, r.se, r.validationSet); err != nil {
+		return err
+	}
 
 	if config.Config.ReadStrategy == config.Pessimistic {
 		return nil
@@ -601,13 +740,16 @@ func (r *Datastore) validate() error {
 			log.Fatalf("item's key is empty")
 			continue
 		}
+		if pred.IsCondition() {
+			continue
+		}
 		eg.Go(func() error {
 			urlList := strings.Split(gk, ",")
 			groupKey, err := r.Txn.GetGroupKeyFromUrls(urlList)
 			// curState, err := r.Txn.tsrMaintainer.ReadTSR(txnId)
 			if err != nil {
 				if config.Config.ReadStrategy == config.AssumeAbort {
-					if pred.LeaseTime.Before(time.Now()) {
+					if pred.LeaseTime.Before(r.Txn.now()) {
 						key := pred.ItemKey
 						err := r.rollbackFromConn(key)
 						if err != nil {
@@ -622,7 +764,7 @@ func (r *Datastore) validate() error {
 			if AtLeastOneAborted(groupKey) {
 				return nil
 			} else {
-				return errors.New("validation failed due to false assumption")
+				return errors.New(FalseAssumption)
 			}
 			// if groupKey.TxnState != pred.State {
 			// 	return errors.New("validation failed due to false assumption")
@@ -643,9 +785,19 @@ func (r *Datastore) Prepare() (int64, error) {
 		v.SetGroupKeyList(strings.Join(r.Txn.GroupKeyUrls, ","))
 		items = append(items, v)
 	}
+	// the records are prepared in a canonical order, so that two transactions
+	// writing overlapping keys conflict on the first shared key instead of
+	// each preparing some of them and aborting each other
+	slices.SortFunc(items, func(i, j DataItem) int {
+		return cmp.Compare(i.Key(), j.Key())
+	})
 
 	if len(items) == 0 {
-		return 0, nil
+		// the commit conditions hold even if nothing is written
+		if err := r.CheckConditions(); err != nil {
+			return 0, err
+		}
+		return 0, r.ValidateReadSet()
 	}
 
 	if config.Debug.NativeMode {
@@ -663,18 +815,17 @@ func (r *Datastore) Prepare() (int64, error) {
 	}
 
 	if config.Config.ConcurrentOptimizationLevel < config.PARALLELIZE_ON_UPDATE {
-		// sort records by key
-		slices.SortFunc(
-			items, func(i, j DataItem) int {
-				return cmp.Compare(i.Key(), j.Key())
-			},
-		)
 		for _, item := range items {
+			// stops early once another datastore has failed to prepare
+			if err := r.Txn.prepareCanceled(); err != nil {
+				return 0, err
+			}
 			if err := r.conditionalUpdate(item); err != nil {
 				return 0, err
 			}
 		}
-		return 0, nil
+		// the read set is validated once the written records are prepared
+		return 0, r.ValidateReadSet()
 	}
 
 	var eg errgroup.Group
@@ -682,10 +833,16 @@ func (r *Datastore) Prepare() (int64, error) {
 	for _, item := range items {
 		it := item
 		eg.Go(func() error {
+			if err := r.Txn.prepareCanceled(); err != nil {
+				return err
+			}
 			return r.conditionalUpdate(it)
 		})
 	}
-	return 0, eg.Wait()
+	if err := eg.Wait(); err != nil {
+		return 0, err
+	}
+	return 0, r.ValidateReadSet()
 }
 
 func (r *Datastore) prepareInNative(items []DataItem) (int64, error) {
@@ -729,7 +886,14 @@ func (r *Datastore) prepareInRemote(items []DataItem) (int64, error) {
 		config.Debug.AssumptionCount++
 	}
 
-	verMap, tCommit, err := r.Txn.RemotePrepare(r.Name, items, r.validationSet)
+	validationMap := r.validationSet
+	if readPredicates := r.readPredicates(r.unwrittenReads()); len(readPredicates) != 0 {
+		validationMap = make(map[string]PredicateInfo, len(r.validationSet)+len(readPredicates))
+		maps.Copy(validationMap, r.validationSet)
+		maps.Copy(validationMap, readPredicates)
+	}
+
+	verMap, tCommit, err := r.Txn.RemotePrepare(r.Name, items, validationMap)
 	logger.Log.Debugw("Remote prepare Result",
 		"TxnId", r.Txn.TxnId, "verMap", verMap, "err", err, "Latency", time.Since(r.Txn.debugStart), "Topic", "CheckPoint")
 	if err != nil {
@@ -741,6 +905,55 @@ func (r *Datastore) prepareInRemote(items []DataItem) (int64, error) {
 	return tCommit, nil
 }
 
+// RenewLease extends the leases of the records prepared by the transaction.
+// It fails if any of them has been modified by others in the meantime,
+// e.g. rolled back because its lease had expired.
+// In remote mode the executor renews them, if the client supports it.
+func (r *Datastore) RenewLease() error {
+	if config.Debug.NativeMode {
+		return nil
+	}
+	if r.Txn.isRemote {
+		return r.renewLeaseInRemote()
+	}
+
+	var eg errgroup.Group
+	for _, item := range r.writeCache {
+		it := item
+		eg.Go(func() error {
+			it.SetTLease(r.Txn.now().Add(config.Config.LeaseTime))
+			newVer, err := r.conn.ConditionalUpdate(it.Key(), it, false)
+			if err != nil {
+				return err
+			}
+			it.SetVersion(newVer)
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+func (r *Datastore) renewLeaseInRemote() error {
+	client, ok := r.Txn.remoteClient().(LeaseRenewClient)
+	if !ok {
+		return errors.New("the remote client can't renew the leases")
+	}
+	infoList := make([]CommitInfo, 0, len(r.writeCache))
+	for _, item := range r.writeCache {
+		infoList = append(infoList, CommitInfo{Key: item.Key(), Version: item.Version()})
+	}
+	verMap, err := client.RenewLease(r.Name, infoList)
+	if err != nil {
+		return err
+	}
+	for key, version := range verMap {
+		if item, ok := r.writeCache[key]; ok {
+			item.SetVersion(version)
+		}
+	}
+	return nil
+}
+
 // Commit updates the state of records in the data store to COMMITTED.
 //
 // It iterates over the write cache and updates each record's state to COMMITTED.
@@ -755,40 +968,150 @@ func (r *Datastore) Commit() error {
 	}
 
 	// update record's state to the COMMITTED state in the data store
+	items := make([]DataItem, 0, len(r.writeCache))
+	for _, item := range r.writeCache {
+		items = append(items, item)
+	}
+	failed, err := commitItems(r.conn, items)
+	logger.Log.Debugw("Datastore.Commit() finishes", "TxnId", r.Txn.TxnId)
+	r.clear()
+	return r.keepUncommitted(failed, r.Txn.TxnCommitTime, err)
+}
+
+// commitItems updates items to the COMMITTED state in the data store of conn
+// and returns the ones that failed along with the last error.
+func commitItems(conn Connector, items []DataItem) ([]DataItem, error) {
+	var mu sync.Mutex
+	failed := make([]DataItem, 0)
+	var cause error
 	var eg errgroup.Group
 	// eg.SetLimit(config.Config.MaxOutstandingRequest)
-	for _, item := range r.writeCache {
+	for _, item := range items {
 		it := item
 		eg.Go(func() error {
 			it.SetTxnState(config.COMMITTED)
 
-			_, err := r.conn.ConditionalUpdate(it.Key(), it, false)
-			if errors.Is(err, VersionMismatch) {
-				// this indicates that the record has been rolled forward
-				// by another transaction.
+			_, err := conn.ConditionalUpdate(it.Key(), it, false)
+			if err == nil || errors.Is(err, VersionMismatch) {
+				// a version mismatch indicates that the record
+				// has been rolled forward by another transaction.
 				return nil
 			}
+			mu.Lock()
+			failed, cause = append(failed, it), err
+			mu.Unlock()
 			return err
 		})
 	}
 	eg.Wait()
-	logger.Log.Debugw("Datastore.Commit() finishes", "TxnId", r.Txn.TxnId)
-	r.clear()
-	return nil
+	return failed, cause
 }
 
 func (r *Datastore) commitInRemote() error {
-	infoList := make([]CommitInfo, 0, len(r.writeCache))
+	items := make([]DataItem, 0, len(r.writeCache))
 	for _, item := range r.writeCache {
-		infoList = append(infoList, CommitInfo{Key: item.Key(), Version: item.Version()})
+		items = append(items, item)
 	}
 
-	err := r.Txn.RemoteCommit(r.Name, infoList)
+	err := commitItemsInRemote(r.Txn.remoteClient(), r.Name, items, r.Txn.TxnCommitTime)
 	if err != nil {
 		logger.Log.Infow("Remote commit failed", "TxnId", r.Txn.TxnId)
+		return r.keepUncommitted(items, r.Txn.TxnCommitTime, err)
+	}
+	return nil
+}
+
+// commitItemsInRemote has the executors of client
+// update the items of dsName to the COMMITTED state with tCommit.
+// It fails if an executor reports another commit timestamp, as the
+// records would then disagree with the TSR of the transaction.
+func commitItemsInRemote(client RemoteClient, dsName string, items []DataItem, tCommit int64) error {
+	infoList := make([]CommitInfo, 0, len(items))
+	for _, item := range items {
+		infoList = append(infoList, CommitInfo{Key: item.Key(), Version: item.Version()})
+	}
+	applied, err := client.Commit(dsName, infoList, tCommit)
+	if err != nil {
 		return err
 	}
-	return err
+	if applied != tCommit {
+		return errors.Errorf("the records of %s have been committed with %d instead of %d", dsName, applied, tCommit)
+	}
+	return nil
+}
+
+// keepUncommitted reports the items whose commit failed in a *CommitFailure
+// along with a copy of what recommitting them needs, so that the next
+// transactions of the datastore leave them untouched.
+// It returns nil if there is none.
+func (r *Datastore) keepUncommitted(items []DataItem, tCommit int64, cause error) error {
+	if len(items) == 0 {
+		return nil
+	}
+	records := &uncommittedRecords{
+		dsName:  r.Name,
+		conn:    r.conn,
+		items:   make(map[string]DataItem, len(items)),
+		tCommit: tCommit,
+	}
+	if r.Txn.isRemote {
+		records.client = r.Txn.remoteClient()
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		records.items[item.Key()] = item
+		keys = append(keys, item.Key())
+	}
+	slices.Sort(keys)
+	return &CommitFailure{DsName: r.Name, Keys: keys, Err: cause, Recoverer: records}
+}
+
+// uncommittedRecords are the records of a datastore whose commit failed,
+// kept until they are committed by RecommitKeys.
+type uncommittedRecords struct {
+	dsName string
+	conn   Connector
+	// client commits the records through the executors if not nil
+	client  RemoteClient
+	mu      sync.Mutex
+	items   map[string]DataItem
+	tCommit int64
+}
+
+// RecommitKeys commits the records of keys kept by the failed Commit.
+func (u *uncommittedRecords) RecommitKeys(keys []string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	items := make([]DataItem, 0, len(keys))
+	for _, key := range keys {
+		if item, ok := u.items[key]; ok {
+			items = append(items, item)
+		}
+	}
+
+	var failed []DataItem
+	var err error
+	if u.client != nil {
+		if err = commitItemsInRemote(u.client, u.dsName, items, u.tCommit); err != nil {
+			failed = items
+		}
+	} else {
+		failed, err = commitItems(u.conn, items)
+	}
+
+	for _, item := range items {
+		delete(u.items, item.Key())
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	failedKeys := make([]string, 0, len(failed))
+	for _, item := range failed {
+		u.items[item.Key()] = item
+		failedKeys = append(failedKeys, item.Key())
+	}
+	slices.Sort(failedKeys)
+	return &CommitFailure{DsName: u.dsName, Keys: failedKeys, Err: err}
 }
 
 // Abort discards the changes made in the current transaction.
@@ -796,9 +1119,11 @@ func (r *Datastore) commitInRemote() error {
 //   - If hasCommitted is false, it clears the write cache.
 //   - If hasCommitted is true, it rolls back the changes made by the current transaction.
 //
+// Either way, the records locked by the transaction are released first.
 // It returns an error if there is any issue during the rollback process.
 func (r *Datastore) Abort(hasCommitted bool) error {
-
+	r.rolledBack = 0
+	r.unlockRecords()
 	if !hasCommitted {
 		r.clear()
 		return nil
@@ -809,31 +1134,63 @@ func (r *Datastore) Abort(hasCommitted bool) error {
 		for _, item := range r.writeCache {
 			keyList = append(keyList, item.Key())
 		}
-		return r.Txn.RemoteAbort(r.Name, keyList)
+		rolledBack, err := r.Txn.RemoteAbort(r.Name, keyList)
+		r.rolledBack = rolledBack
+		return err
 	}
 
+	curGroupKeyList := strings.Join(r.Txn.GroupKeyUrls, ",")
 	for _, v := range r.writeCache {
-		item, err := r.conn.GetItem(v.Key())
+		item, err := GetLatestItem(r.conn, v.Key())
 		if err != nil {
+			// the record was never written by this transaction
+			if err.Error() == KeyNotFound.Error() {
+				continue
+			}
 			return err
 		}
 		// if the record has been modified by this transaction
-		curGroupKeyList := strings.Join(r.Txn.GroupKeyUrls, ",")
-		if item.GroupKeyList() == curGroupKeyList {
-			r.rollback(item)
+		// and has not been rolled back by others yet
+		if item.GroupKeyList() == curGroupKeyList && item.TxnState() != config.COMMITTED {
+			_, err := r.rollback(item)
+			if err != nil {
+				logger.Log.Debugw("record has been rolled back concurrently", "key", item.Key(), "cause", err)
+				continue
+			}
+			r.rolledBack++
 		}
 	}
 	r.clear()
 	return nil
 }
 
+// RolledBackCount returns the number of records rolled back by the last Abort.
+// The records rolled back concurrently by other transactions are not counted.
+func (r *Datastore) RolledBackCount() int {
+	return r.rolledBack
+}
+
+// OnePhaseCommit writes the only record of the write cache as committed
+// at the commit time of the transaction, with a single conditional update.
 func (r *Datastore) OnePhaseCommit() error {
-	if len(r.writeCache) == 0 {
-		return nil
-	}
 	// there is only one record in the writeCache
-	for _, item := range r.writeCache {
-		return r.conditionalUpdate(item)
+	for _, cacheItem := range r.writeCache {
+		dbItem, err := r.itemToUpdate(cacheItem)
+		if err != nil {
+			return err
+		}
+		newItem, err := r.updateMetadata(cacheItem, dbItem)
+		if err != nil {
+			return err
+		}
+		newItem.SetTxnState(config.COMMITTED)
+		newVer, err := r.conn.ConditionalUpdate(newItem.Key(), newItem, dbItem == nil || dbItem.Empty())
+		if err != nil {
+			return err
+		}
+		newItem.SetVersion(newVer)
+		r.writeCache[newItem.Key()] = newItem
+		return nil
 	}
 	return nil
 }
@@ -965,6 +1322,11 @@ func (r *Datastore) GetConn() Connector {
 	return r.conn
 }
 
+// ClearReadCache drops the records read so far.
+func (r *Datastore) ClearReadCache() {
+	r.readCache = make(map[string]DataItem)
+}
+
 func (r *Datastore) GetWriteCacheSize() int {
 	return len(r.writeCache)
 }
@@ -975,4 +1337,5 @@ func (r *Datastore) clear() {
 	// r.writtenSet = util.NewConcurrentMap[bool]()
 	r.invisibleSet = make(map[string]bool)
 	r.validationSet = make(map[string]PredicateInfo)
+	r.locked = nil
 }
@@ -11,6 +11,10 @@ type Datastorer interface {
 	// it reads the record from the connection and puts it into the cache.
 	Read(key string, value any) error
 
+	// ReadAt reads the version of a record that was visible at timestamp ts.
+	// It neither consults nor populates the caches.
+	ReadAt(key string, ts int64, value any) error
+
 	// Write writes records into the writeCache.
 	Write(key string, value any) error
 
@@ -22,6 +26,9 @@ type Datastorer interface {
 
 	Prepare() (int64, error)
 
+	// RenewLease extends the leases of the records prepared by the transaction.
+	RenewLease() error
+
 	// Commit executes the commit phase of transaction commit.
 	// It updates the records in the writeCache to the COMMITTED state
 	// in the data store.
@@ -48,3 +55,16 @@ type Datastorer interface {
 	// GetWriteCacheSize returns the size of the writeCache.
 	GetWriteCacheSize() int
 }
+
+// AbortReporter is implemented by datastores that report
+// how many records their last Abort rolled back.
+type AbortReporter interface {
+	RolledBackCount() int
+}
+
+// ReadCacheClearer is implemented by datastores that cache the records
+// they read, which Transaction.Refresh drops so that the reads after
+// the refresh see the new snapshot.
+type ReadCacheClearer interface {
+	ClearReadCache()
+}
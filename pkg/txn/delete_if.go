@@ -0,0 +1,81 @@
+package txn
+
+import (
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// ConditionalDeleter is implemented by datastores that can delete
+// a record only if it still has a given version.
+type ConditionalDeleter interface {
+	DeleteIf(key string, expectedVersion string) error
+}
+
+// DeleteIf deletes key like Delete, but only if the record visible to the
+// transaction has expectedVersion. It returns VersionMismatch otherwise,
+// and KeyNotFound if the record does not exist.
+//
+// The delete is prepared against expectedVersion, so the transaction
+// aborts if another one updates the record before it commits.
+func (t *Transaction) DeleteIf(dsName string, key string, expectedVersion string) error {
+	return t.delete(dsName, key, func(ds Datastorer) error {
+		deleter, ok := ds.(ConditionalDeleter)
+		if !ok {
+			return errors.Errorf("datastore %s does not support conditional deletes", dsName)
+		}
+		return deleter.DeleteIf(key, expectedVersion)
+	})
+}
+
+// DeleteIf deletes key if the record the transaction sees has expectedVersion.
+func (r *Datastore) DeleteIf(key string, expectedVersion string) error {
+	current, err := r.baseVersion(key)
+	if err != nil {
+		return err
+	}
+	if current != expectedVersion {
+		return errors.New(VersionMismatch)
+	}
+	if err := r.Delete(key); err != nil {
+		return err
+	}
+	// a blind delete would be prepared against whatever version it finds
+	r.writeCache[key].SetVersion(current)
+	return nil
+}
+
+// baseVersion returns the stored version of key the writes of the
+// transaction are based on, reading the record if it has not been read.
+func (r *Datastore) baseVersion(key string) (string, error) {
+	if item, ok := r.writeCache[key]; ok {
+		if item.IsDeleted() {
+			return "", errors.New(KeyNotFound)
+		}
+		if item.Version() != "" {
+			return item.Version(), nil
+		}
+	}
+
+	item, ok := r.cachedRead(key)
+	if !ok {
+		var err error
+		if r.Txn.isRemote {
+			if item, err = r.remoteRead(key, nil); err == nil {
+				r.readCache[key] = item
+			}
+		} else if err = r.readFromConn(key, nil); err == nil {
+			item = r.readCache[key]
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), KeyNotFound.Error()) {
+				return "", errors.New(KeyNotFound)
+			}
+			return "", err
+		}
+	}
+	if item.IsDeleted() {
+		return "", errors.New(KeyNotFound)
+	}
+	return item.Version(), nil
+}
@@ -0,0 +1,64 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxnDeleteIf(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		ds := txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{})
+		_ = tx.AddDatastore(ds)
+		return tx
+	}
+	write := func(key string, value string) string {
+		tx := newTxn()
+		assert.NoError(t, tx.Start())
+		assert.NoError(t, tx.Write("memkv", key, value))
+		assert.NoError(t, tx.Commit())
+		time.Sleep(100 * time.Millisecond)
+		item, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		return item.Version()
+	}
+
+	version := write("item1", "v1")
+	stale := newTxn()
+	assert.NoError(t, stale.Start())
+	err := stale.DeleteIf("memkv", "item1", version+"0")
+	assert.EqualError(t, err, txn.VersionMismatch.Error())
+	assert.NoError(t, stale.Abort())
+
+	tx := newTxn()
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.DeleteIf("memkv", "item1", version))
+	assert.NoError(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+	item, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.True(t, item.IsDeleted())
+
+	missing := newTxn()
+	assert.NoError(t, missing.Start())
+	assert.EqualError(t, missing.DeleteIf("memkv", "item2", ""), txn.KeyNotFound.Error())
+	assert.NoError(t, missing.Abort())
+
+	// an update committed after the check makes the delete abort
+	version = write("item3", "v1")
+	tx = newTxn()
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.DeleteIf("memkv", "item3", version))
+	write("item3", "v2")
+	assert.Error(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+	item, err = conn.GetItem("item3")
+	assert.NoError(t, err)
+	assert.False(t, item.IsDeleted())
+}
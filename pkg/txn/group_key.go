@@ -1,7 +1,10 @@
 package txn
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 )
@@ -33,6 +36,58 @@ func NewGroupKey(key string, txnState config.State, tCommit int64) *GroupKey {
 	}
 }
 
+// TSREncodingFromName returns the TSR encoding called name, json if name is empty.
+func TSREncodingFromName(name string) (config.TSREncoding, error) {
+	switch config.TSREncoding(name) {
+	case "", config.TSRJSON:
+		return config.TSRJSON, nil
+	case config.TSRCompact:
+		return config.TSRCompact, nil
+	default:
+		return "", fmt.Errorf("unsupported TSR encoding %q, expect json or compact", name)
+	}
+}
+
+// EncodeGroupKeyItem encodes the TSR item in config.Config.TSREncoding.
+// The compact encoding leaves out the transaction id, which is already
+// part of the key the TSR is stored under.
+func EncodeGroupKeyItem(item GroupKeyItem) (string, error) {
+	if config.Config.TSREncoding == config.TSRCompact {
+		return strconv.Itoa(int(item.TxnState)) + ":" + strconv.FormatInt(item.TCommit, 10), nil
+	}
+	bs, err := json.Marshal(item)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal group key item %v", item)
+	}
+	return string(bs), nil
+}
+
+// DecodeGroupKeyItem decodes a TSR item written by EncodeGroupKeyItem
+// in either encoding, so that the clients and executors configured
+// with different encodings can still read each other's TSRs.
+func DecodeGroupKeyItem(str string) (GroupKeyItem, error) {
+	var item GroupKeyItem
+	if strings.HasPrefix(str, "{") {
+		if err := json.Unmarshal([]byte(str), &item); err != nil {
+			return GroupKeyItem{}, fmt.Errorf("failed to unmarshal group key item %s", str)
+		}
+		return item, nil
+	}
+	stateStr, tCommitStr, ok := strings.Cut(str, ":")
+	if !ok {
+		return GroupKeyItem{}, fmt.Errorf("failed to decode group key item %s", str)
+	}
+	state, err := strconv.Atoi(stateStr)
+	if err != nil {
+		return GroupKeyItem{}, fmt.Errorf("failed to decode group key item %s", str)
+	}
+	tCommit, err := strconv.ParseInt(tCommitStr, 10, 64)
+	if err != nil {
+		return GroupKeyItem{}, fmt.Errorf("failed to decode group key item %s", str)
+	}
+	return NewGroupKeyItem(config.State(state), tCommit), nil
+}
+
 func (gk *GroupKey) String() string {
 	return fmt.Sprintf(`GroupKey{
 		Key: %s,
@@ -59,6 +114,17 @@ func AtLeastOneAborted(groupKeys []GroupKey) bool {
 	return false
 }
 
+// HasGroupKey reports whether the comma-separated groupKeyList
+// of a record contains groupKey.
+func HasGroupKey(groupKeyList string, groupKey string) bool {
+	for _, gk := range strings.Split(groupKeyList, ",") {
+		if gk == groupKey {
+			return true
+		}
+	}
+	return false
+}
+
 // func MakeGroupKeyListFromUrls(urls []string) string {
 // 	list := ""
 // 	for _, url := range urls {
@@ -0,0 +1,64 @@
+package txn
+
+import (
+	"testing"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// TestGroupKeyItemEncoding tests that a TSR item is decoded back
+// whichever encoding it was written in.
+func TestGroupKeyItemEncoding(t *testing.T) {
+	encoding := config.Config.TSREncoding
+	defer func() { config.Config.TSREncoding = encoding }()
+
+	states := []config.State{config.EMPTY, config.STARTED, config.PREPARED, config.COMMITTED, config.ABORTED}
+	for _, enc := range []config.TSREncoding{config.TSRJSON, config.TSRCompact} {
+		config.Config.TSREncoding = enc
+		for _, state := range states {
+			item := NewGroupKeyItem(state, 1700000000123456)
+			str, err := EncodeGroupKeyItem(item)
+			if err != nil {
+				t.Fatalf("Error encoding %v in %s: %s", item, enc, err)
+			}
+			// the TSR is read by a reader configured with the other encoding
+			config.Config.TSREncoding = config.TSRJSON
+			if enc == config.TSRJSON {
+				config.Config.TSREncoding = config.TSRCompact
+			}
+			decoded, err := DecodeGroupKeyItem(str)
+			config.Config.TSREncoding = enc
+			if err != nil {
+				t.Fatalf("Error decoding %q: %s", str, err)
+			}
+			if decoded != item {
+				t.Errorf("Expected %v after a round trip in %s, got %v", item, enc, decoded)
+			}
+		}
+	}
+
+	config.Config.TSREncoding = config.TSRCompact
+	if str, _ := EncodeGroupKeyItem(NewGroupKeyItem(config.COMMITTED, 42)); str != "3:42" {
+		t.Errorf("Expected the compact encoding to be 3:42, got %q", str)
+	}
+	// the TSRs written before the compact encoding existed
+	if item, err := DecodeGroupKeyItem(`{"Key":"redis:txn1","TxnState":4,"TCommit":0}`); err != nil || item.TxnState != config.ABORTED {
+		t.Errorf("Expected an ABORTED item, got %v %v", item, err)
+	}
+	for _, str := range []string{"", "3", "x:1", "3:x", "{"} {
+		if _, err := DecodeGroupKeyItem(str); err == nil {
+			t.Errorf("Expected an error decoding %q", str)
+		}
+	}
+}
+
+func TestTSREncodingFromName(t *testing.T) {
+	for name, expected := range map[string]config.TSREncoding{"": config.TSRJSON, "json": config.TSRJSON, "compact": config.TSRCompact} {
+		if enc, err := TSREncodingFromName(name); err != nil || enc != expected {
+			t.Errorf("Expected %q to be %s, got %s %v", name, expected, enc, err)
+		}
+	}
+	if _, err := TSREncodingFromName("gob"); err == nil {
+		t.Errorf("Expected an error for an unsupported encoding")
+	}
+}
@@ -1,12 +1,10 @@
 package txn
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
 
-	"github.com/oreo-dtx-lab/oreo/internal/util"
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 	"github.com/oreo-dtx-lab/oreo/pkg/logger"
 	"golang.org/x/sync/errgroup"
@@ -34,7 +32,7 @@ func (g *GroupKeyMaintainer) AddConnector(ds Datastorer) {
 }
 
 func (g *GroupKeyMaintainer) GetGroupKey(urls []string) ([]GroupKey, error) {
-	groupKeys := make([]GroupKey, len(urls))
+	groupKeys := make([]GroupKey, 0, len(urls))
 	var mu sync.Mutex
 	var eg errgroup.Group
 	for _, urll := range urls {
@@ -69,10 +67,9 @@ func (g *GroupKeyMaintainer) GetSingleGroupKey(url string) (GroupKey, error) {
 	if err != nil {
 		return GroupKey{}, err
 	}
-	var keyItem GroupKeyItem
-	err = json.Unmarshal([]byte(groupKeyStr), &keyItem)
+	keyItem, err := DecodeGroupKeyItem(groupKeyStr)
 	if err != nil {
-		return GroupKey{}, fmt.Errorf("failed to unmarshal group key item %s", groupKeyStr)
+		return GroupKey{}, err
 	}
 	return *NewGroupKey(url, keyItem.TxnState, keyItem.TCommit), nil
 }
@@ -97,14 +94,13 @@ func (g *GroupKeyMaintainer) CreateGroupKey(urls []string, state config.State) i
 				return
 			}
 
-			groupKey := NewGroupKey(url, state, 0)
-			groupKeyStr, err := json.Marshal(groupKey)
+			groupKeyStr, err := EncodeGroupKeyItem(NewGroupKeyItem(state, 0))
 			if err != nil {
-				resChan <- fmt.Errorf("failed to marshal group key item %s", groupKey)
+				resChan <- err
 				return
 			}
 			// CHECK: we do not need the returned value?
-			_, err = conn.AtomicCreate(url, util.ToString(groupKeyStr))
+			_, err = conn.AtomicCreate(url, groupKeyStr)
 			if err != nil {
 				resChan <- err
 				return
@@ -138,14 +134,13 @@ func (g *GroupKeyMaintainer) CreateGroupKeyList(item DataItem, state config.Stat
 				return
 			}
 
-			groupKey := NewGroupKey(url, state, 0)
-			groupKeyStr, err := json.Marshal(groupKey)
+			groupKeyStr, err := EncodeGroupKeyItem(NewGroupKeyItem(state, 0))
 			if err != nil {
-				resChan <- fmt.Errorf("failed to marshal group key item %s", groupKey)
+				resChan <- err
 				return
 			}
 			// CHECK: we do not need the returned value?
-			_, err = conn.AtomicCreate(url, util.ToString(groupKeyStr))
+			_, err = conn.AtomicCreate(url, groupKeyStr)
 			if err != nil {
 				resChan <- err
 				return
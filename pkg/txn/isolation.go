@@ -0,0 +1,39 @@
+package txn
+
+import (
+	"math"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// SetIsolationLevel sets the isolation level of the reads of the transaction,
+// config.Snapshot unless set. It applies to the reads issued afterwards,
+// local or remote, and is kept when the transaction is started again.
+func (t *Transaction) SetIsolationLevel(level config.IsolationLevel) {
+	t.isolation = level
+}
+
+// IsolationLevel returns the isolation level of the reads of the transaction.
+func (t *Transaction) IsolationLevel() config.IsolationLevel {
+	return t.isolation
+}
+
+// readTime returns the timestamp the committed versions read by the
+// transaction must precede: its start time for a snapshot, none otherwise.
+func (t *Transaction) readTime() int64 {
+	if t.isolation == config.ReadCommitted {
+		return math.MaxInt64
+	}
+	return t.TxnStartTime
+}
+
+// cachedRead returns the record of key read earlier by the transaction.
+// A read-committed transaction reads the latest version every time,
+// so it does not serve its reads from the read cache.
+func (r *Datastore) cachedRead(key string) (DataItem, bool) {
+	if r.Txn.isolation == config.ReadCommitted {
+		return nil, false
+	}
+	item, ok := r.readCache[key]
+	return item, ok
+}
@@ -0,0 +1,56 @@
+package txn
+
+import "sync"
+
+// itemRegistry maps the item types to their factories
+// and the datastore names to the item types they store.
+var itemRegistry = struct {
+	mu        sync.RWMutex
+	factories map[ItemType]DataItemFactory
+	dsTypes   map[string]ItemType
+}{
+	factories: make(map[ItemType]DataItemFactory),
+	dsTypes:   make(map[string]ItemType),
+}
+
+// RegisterItemFactory makes the items of itemType creatable by factory.
+// Datastore packages call it from their init functions.
+func RegisterItemFactory(itemType ItemType, factory DataItemFactory) {
+	itemRegistry.mu.Lock()
+	defer itemRegistry.mu.Unlock()
+	itemRegistry.factories[itemType] = factory
+}
+
+// RegisterDatastore records that the datastore dsName stores items of itemType.
+func RegisterDatastore(dsName string, itemType ItemType) {
+	itemRegistry.mu.Lock()
+	defer itemRegistry.mu.Unlock()
+	itemRegistry.dsTypes[dsName] = itemType
+}
+
+// ItemTypeOf returns the item type stored in the datastore dsName,
+// or NoneItem if dsName is not registered.
+func ItemTypeOf(dsName string) ItemType {
+	itemRegistry.mu.RLock()
+	defer itemRegistry.mu.RUnlock()
+	return itemRegistry.dsTypes[dsName]
+}
+
+// ItemFactoryOf returns the factory of itemType, or nil if it is not registered.
+func ItemFactoryOf(itemType ItemType) DataItemFactory {
+	itemRegistry.mu.RLock()
+	defer itemRegistry.mu.RUnlock()
+	return itemRegistry.factories[itemType]
+}
+
+// ItemFactoryFor returns the factory of the items stored in the datastore dsName,
+// or nil if either the datastore or its item type is not registered.
+func ItemFactoryFor(dsName string) DataItemFactory {
+	itemRegistry.mu.RLock()
+	defer itemRegistry.mu.RUnlock()
+	itemType, ok := itemRegistry.dsTypes[dsName]
+	if !ok {
+		return nil
+	}
+	return itemRegistry.factories[itemType]
+}
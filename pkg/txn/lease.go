@@ -0,0 +1,60 @@
+package txn
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// EncodeLease returns the lease time t as the number of nanoseconds since
+// the Unix epoch. It is how the lease is stored and sent over the network,
+// so that a lease compares exactly equal after any number of round trips,
+// whatever the codecs on the way. The zero time is encoded as 0.
+func EncodeLease(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// DecodeLease returns the lease time encoded as n by EncodeLease, in UTC.
+func DecodeLease(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// ParseLease parses a lease time encoded by EncodeLease in decimal,
+// or in RFC 3339 as the items written by the older versions store it.
+// An empty string is the zero time.
+func ParseLease(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return DecodeLease(n), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return DecodeLease(EncodeLease(t)), nil
+}
+
+// UnmarshalLease decodes a lease time from its JSON encoding,
+// a number as written by EncodeLease or an RFC 3339 string.
+// A missing or null field is the zero time.
+func UnmarshalLease(data json.RawMessage) (time.Time, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return time.Time{}, nil
+	}
+	var s string
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return time.Time{}, err
+		}
+		return ParseLease(s)
+	}
+	return ParseLease(string(data))
+}
@@ -0,0 +1,176 @@
+package txn_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/cassandra"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/couchdb"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/dynamodb"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/mongo"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/tikv"
+	"github.com/oreo-dtx-lab/oreo/pkg/timesource"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestLeaseStorageRoundTrip tests that every connector stores the lease of
+// an item as txn.EncodeLease does, so that a lease read back from any of
+// them, or sent over the network, compares exactly equal to the others.
+func TestLeaseStorageRoundTrip(t *testing.T) {
+	// stored lease as read back by each connector
+	roundTrips := map[string]func(lease time.Time) (time.Time, error){
+		// a field of the hash of the item
+		"redis": func(lease time.Time) (time.Time, error) {
+			return txn.ParseLease(strconv.FormatInt(txn.EncodeLease(lease), 10))
+		},
+		// a bigint column
+		"cassandra": func(lease time.Time) (time.Time, error) {
+			return txn.DecodeLease(txn.EncodeLease(lease)), nil
+		},
+		"mongo": func(lease time.Time) (time.Time, error) {
+			_, raw, err := mongo.MongoItem{MTLease: lease}.MarshalBSONValue()
+			if err != nil {
+				return time.Time{}, err
+			}
+			var item mongo.MongoItem
+			err = item.UnmarshalBSONValue(bson.TypeEmbeddedDocument, raw)
+			return item.MTLease, err
+		},
+		"dynamodb": func(lease time.Time) (time.Time, error) {
+			av, err := attributevalue.MarshalMap(dynamodb.DynamoDBItem{DKey: "item1", DTLease: lease})
+			if err != nil {
+				return time.Time{}, err
+			}
+			var item dynamodb.DynamoDBItem
+			err = attributevalue.UnmarshalMap(av, &item)
+			return item.DTLease, err
+		},
+		// a JSON document
+		"couchdb": func(lease time.Time) (time.Time, error) {
+			var item couchdb.CouchDBItem
+			err := jsonRoundTrip(couchdb.CouchDBItem{CTLease: lease}, &item)
+			return item.CTLease, err
+		},
+		"tikv": func(lease time.Time) (time.Time, error) {
+			var item tikv.TiKVItem
+			err := jsonRoundTrip(tikv.TiKVItem{KTLease: lease}, &item)
+			return item.KTLease, err
+		},
+		// the items sent to and from the executors
+		"network": func(lease time.Time) (time.Time, error) {
+			var item cassandra.CassandraItem
+			err := jsonRoundTrip(redis.RedisItem{RTLease: lease}, &item)
+			return item.CTLease, err
+		},
+	}
+
+	leases := map[string]time.Time{
+		// in the local time zone, with a monotonic reading
+		// and a precision finer than a microsecond
+		"now":  time.Now().Add(3*time.Second + 789*time.Nanosecond),
+		"zero": {},
+	}
+	for leaseName, lease := range leases {
+		want := txn.DecodeLease(txn.EncodeLease(lease))
+		assert.True(t, want.Equal(lease), leaseName)
+		for name, roundTrip := range roundTrips {
+			got, err := roundTrip(lease)
+			if assert.NoError(t, err, name) {
+				assert.Equal(t, want, got, "%s lease through %s", leaseName, name)
+			}
+		}
+	}
+}
+
+// TestLeaseOlderEncodings tests that the leases stored
+// in RFC 3339 by the older versions are still read.
+func TestLeaseOlderEncodings(t *testing.T) {
+	lease := time.Now().Add(789 * time.Nanosecond)
+	want := txn.DecodeLease(txn.EncodeLease(lease))
+
+	raw, err := bson.Marshal(bson.M{"TLease": lease.Format(time.RFC3339Nano)})
+	assert.NoError(t, err)
+	var mongoItem mongo.MongoItem
+	assert.NoError(t, mongoItem.UnmarshalBSONValue(bson.TypeEmbeddedDocument, raw))
+	assert.Equal(t, want, mongoItem.MTLease)
+
+	av, err := attributevalue.MarshalMap(map[string]string{"ID": "item1", "TLease": lease.Format(time.RFC3339Nano)})
+	assert.NoError(t, err)
+	var dynamoItem dynamodb.DynamoDBItem
+	assert.NoError(t, attributevalue.UnmarshalMap(av, &dynamoItem))
+	assert.Equal(t, want, dynamoItem.DTLease)
+	assert.Equal(t, "item1", dynamoItem.DKey)
+}
+
+func jsonRoundTrip(in any, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func TestTxnLeaseExpiry(t *testing.T) {
+	strategy := config.Config.ReadStrategy
+	defer func() { config.Config.ReadStrategy = strategy }()
+	config.Config.ReadStrategy = config.Pessimistic
+
+	clock := timesource.NewFrozenTimeSource(time.Unix(1700000000, 0))
+	committed := &redis.RedisItem{
+		RKey:          "item1",
+		RValue:        util.ToJSONString("v0"),
+		RGroupKeyList: "memkv:committer",
+		RTxnState:     config.COMMITTED,
+		RTValid:       clock.Now().Add(-2 * time.Second).UnixMicro(),
+		RTLease:       clock.Now().Add(-2 * time.Second),
+		RLinkedLen:    1,
+		RVersion:      "1",
+	}
+	// left by a transaction that crashed before creating its TSR
+	prepared := &redis.RedisItem{
+		RKey:          "item1",
+		RValue:        util.ToJSONString("v1"),
+		RGroupKeyList: "memkv:crashed",
+		RTxnState:     config.PREPARED,
+		RTValid:       clock.Now().Add(-time.Second).UnixMicro(),
+		RTLease:       clock.Now().Add(config.Config.LeaseTime),
+		RPrev:         util.ToJSONString(committed),
+		RLinkedLen:    2,
+		RVersion:      "2",
+	}
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	_, _ = conn.PutItem("item1", prepared)
+
+	read := func() (string, error) {
+		tx := txn.NewTransactionWithOracle(clock)
+		_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+		assert.NoError(t, tx.Start())
+		var value string
+		err := tx.Read("memkv", "item1", &value)
+		return value, err
+	}
+
+	// the record is not recovered while its lease holds
+	clock.Advance(config.Config.LeaseTime - time.Millisecond)
+	_, err := read()
+	assert.EqualError(t, err, txn.ReadFailed.Error())
+	item, _ := conn.GetItem("item1")
+	assert.Equal(t, config.PREPARED, item.TxnState())
+
+	// and is rolled back once it has expired
+	clock.Advance(2 * time.Millisecond)
+	value, err := read()
+	assert.NoError(t, err)
+	assert.Equal(t, "v0", value)
+	_, err = conn.Get("memkv:crashed")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,44 @@
+package txn
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// ErrLinkedLenMismatch is the cause of an item refused because its
+// LinkedLen is not the number of versions chained through Prev.
+var ErrLinkedLenMismatch = errors.Errorf("linked length mismatch")
+
+// CheckLinkedLen returns an error wrapping ErrLinkedLenMismatch if the
+// LinkedLen of item, or of one of its previous versions, disagrees with
+// the depth of the chain below it. The previous versions are decoded
+// with config.Config.Serializer into items created by factory.
+//
+// The chain is only walked if config.Debug.CheckLinkedLen is set.
+// An item without previous versions may have a zero LinkedLen,
+// as left by the loaders writing the items directly.
+func CheckLinkedLen(item DataItem, factory DataItemFactory) error {
+	if !config.Debug.CheckLinkedLen {
+		return nil
+	}
+
+	chain := []DataItem{item}
+	for cur := item; cur.Prev() != ""; {
+		prev := factory.NewDataItem(ItemOptions{})
+		if err := config.Config.Serializer.Deserialize([]byte(cur.Prev()), &prev); err != nil {
+			return errors.Errorf("cannot decode version %d of %s: %v", len(chain)+1, item.Key(), err)
+		}
+		chain = append(chain, prev)
+		cur = prev
+	}
+	if len(chain) == 1 && item.LinkedLen() == 0 {
+		return nil
+	}
+	for i, version := range chain {
+		if want := len(chain) - i; version.LinkedLen() != want {
+			return errors.Errorf("version %d of %s has LinkedLen %d, but %d versions are chained: %w",
+				i+1, item.Key(), version.LinkedLen(), want, ErrLinkedLenMismatch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package txn
+
+import "strings"
+
+// NamespaceSeparator separates the namespace from the key it prefixes.
+const NamespaceSeparator = ":"
+
+// Namespace is the prefix a connector puts before every key it stores,
+// the keys of the records as well as those of the TSRs, so that
+// several experiments can share a database without clobbering
+// each other's keys. The zero value leaves the keys as they are.
+type Namespace string
+
+// Key returns the key under which key is stored in the namespace.
+func (ns Namespace) Key(key string) string {
+	if ns == "" {
+		return key
+	}
+	return string(ns) + NamespaceSeparator + key
+}
+
+// Strip returns the key stored as stored, and false
+// if stored does not belong to the namespace.
+func (ns Namespace) Strip(stored string) (string, bool) {
+	if ns == "" {
+		return stored, true
+	}
+	return strings.CutPrefix(stored, string(ns)+NamespaceSeparator)
+}
+
+// Pattern returns the glob pattern matching the keys stored in the namespace.
+func (ns Namespace) Pattern() string {
+	return ns.Key("*")
+}
@@ -0,0 +1,148 @@
+package txn
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	. "github.com/oreo-dtx-lab/oreo/pkg/logger"
+)
+
+// NativeTxnConnector is implemented by the connectors of datastores
+// supporting multi-record ACID transactions, such as MongoDB on a replica set.
+type NativeTxnConnector interface {
+	// CommitInTxn writes items in one native transaction.
+	// An item with an empty version is created if the record does not exist,
+	// the others are written only if the record still has the item's version.
+	// Nothing is written if any of them fails, in which case
+	// a VersionMismatch error is returned for a version conflict.
+	CommitInTxn(items []DataItem) error
+}
+
+// NativeTxnCommitter is implemented by the datastores
+// able to commit their writes in a native transaction.
+type NativeTxnCommitter interface {
+	Datastorer
+
+	// NativeTxnSupported reports whether the writes can be committed in a native transaction.
+	NativeTxnSupported() bool
+
+	// CommitInNativeTxn writes the records in the writeCache
+	// in the COMMITTED state with tCommit in one native transaction.
+	CommitInNativeTxn(tCommit int64) error
+}
+
+var _ NativeTxnCommitter = (*Datastore)(nil)
+
+// nativeTxnDatastore returns the datastore whose writes can be committed
+// in a native transaction, or nil if config.Config.NativeTxnCommit is off
+// or the transaction writes more than one datastore.
+func (t *Transaction) nativeTxnDatastore() NativeTxnCommitter {
+	// the read set is only validated by the two-phase commit
+	if !config.Config.NativeTxnCommit || t.isRemote || config.Config.ReadSetValidation {
+		return nil
+	}
+	var written Datastorer
+	for _, ds := range t.dataStoreMap {
+		if ds.GetWriteCacheSize() == 0 {
+			continue
+		}
+		if written != nil {
+			return nil
+		}
+		written = ds
+	}
+	committer, ok := written.(NativeTxnCommitter)
+	if !ok || !committer.NativeTxnSupported() {
+		return nil
+	}
+	return committer
+}
+
+// commitInNativeTxn commits a transaction writing a single datastore
+// in a native transaction of the datastore, skipping the two-phase commit.
+// Nothing is written if it fails, so neither TSRs nor rollbacks are involved.
+func (t *Transaction) commitInNativeTxn(ds NativeTxnCommitter) error {
+	var err error
+	t.TxnCommitTime, err = t.getTime("commit")
+	if err != nil {
+		return fmt.Errorf("failed to get time: %v", err)
+	}
+
+	commitStart := time.Now()
+	err = ds.CommitInNativeTxn(t.TxnCommitTime)
+	t.stats.CommitDuration = time.Since(commitStart)
+	if err != nil {
+		Log.Errorw("native transaction failed", "txnId", t.TxnId, "ds", ds.GetName(), "cause", err)
+		_, _ = t.TransitTo(config.ABORTED)
+		return errors.New("native transaction failed: " + err.Error())
+	}
+	return nil
+}
+
+// NativeTxnSupported reports whether the connector supports native transactions.
+// Remote transactions are committed by the executors and never use them.
+func (r *Datastore) NativeTxnSupported() bool {
+	_, ok := r.conn.(NativeTxnConnector)
+	return ok && !r.Txn.isRemote
+}
+
+// CommitInNativeTxn builds the committed records from the writeCache
+// the same way Prepare does and writes them in one native transaction.
+// The caches are cleared whether it succeeds or not, and the records
+// locked by the transaction are released if it fails.
+func (r *Datastore) CommitInNativeTxn(tCommit int64) (err error) {
+	defer func() {
+		if err != nil {
+			r.unlockRecords()
+		}
+		r.clear()
+	}()
+
+	conn, ok := r.conn.(NativeTxnConnector)
+	if !ok {
+		return errors.Errorf("%s does not support native transactions", r.Name)
+	}
+
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	reqs, err := r.committedWrites(tCommit)
+	if err != nil {
+		return err
+	}
+	items := make([]DataItem, 0, len(reqs))
+	for _, req := range reqs {
+		items = append(items, req.Item)
+	}
+	return conn.CommitInTxn(items)
+}
+
+// committedWrites builds the records in the writeCache the same way Prepare
+// does, in the COMMITTED state with tCommit and without a TSR, as the
+// conditional updates writing them.
+func (r *Datastore) committedWrites(tCommit int64) ([]ConditionalUpdateRequest, error) {
+	reqs := make([]ConditionalUpdateRequest, 0, len(r.writeCache))
+	for _, cacheItem := range r.writeCache {
+		dbItem, err := r.itemToUpdate(cacheItem)
+		if err != nil {
+			return nil, err
+		}
+		newItem, err := r.updateMetadata(cacheItem, dbItem)
+		if err != nil {
+			return nil, err
+		}
+		// no TSR is created for the transaction
+		newItem.SetGroupKeyList("")
+		newItem.SetTxnState(config.COMMITTED)
+		newItem.SetTValid(tCommit)
+		reqs = append(reqs, ConditionalUpdateRequest{
+			Key:      newItem.Key(),
+			Item:     newItem,
+			DoCreate: dbItem == nil || dbItem.Empty(),
+		})
+	}
+	return reqs, nil
+}
@@ -0,0 +1,343 @@
+package txn
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
+	"golang.org/x/sync/errgroup"
+)
+
+// PredicateOp names the operator of a commit condition.
+type PredicateOp string
+
+// The operators registered by default.
+const (
+	// PredicateEquals holds if the field equals Value.
+	PredicateEquals PredicateOp = "equals"
+	// PredicateGreaterThan holds if the field is a number greater than Value.
+	PredicateGreaterThan PredicateOp = "greater_than"
+	// PredicateVersionEquals holds if the record has Version.
+	PredicateVersionEquals PredicateOp = "version_equals"
+	// PredicateNotDeleted holds if the record exists.
+	PredicateNotDeleted PredicateOp = "not_deleted"
+)
+
+// conditionPredicatePrefix keeps the commit conditions apart from
+// the TSR predicates keyed by their group key lists.
+const conditionPredicatePrefix = "cond:"
+
+// ErrConditionFailed is the cause of a prepare aborted
+// because a commit condition does not hold.
+var ErrConditionFailed = errors.Errorf("commit condition not met")
+
+// PredicateEvaluator tells whether the predicate pred holds for item,
+// the latest committed record of pred.ItemKey. item is nil if the record
+// does not exist or is deleted. field is the raw value of pred.Field,
+// or of the whole record if pred.Field is empty, and is nil if missing.
+type PredicateEvaluator func(item DataItem, field json.RawMessage, pred PredicateInfo) (bool, error)
+
+var (
+	predicatesMu sync.RWMutex
+	predicates   = map[PredicateOp]PredicateEvaluator{
+		PredicateEquals:        evalEquals,
+		PredicateGreaterThan:   evalGreaterThan,
+		PredicateVersionEquals: evalVersionEquals,
+		PredicateNotDeleted:    evalNotDeleted,
+	}
+)
+
+// RegisterPredicate registers eval as the evaluator of op, replacing any
+// evaluator of the same op. The executors must register the same operators
+// as their clients, since the remote prepares are evaluated by them.
+func RegisterPredicate(op PredicateOp, eval PredicateEvaluator) {
+	predicatesMu.Lock()
+	defer predicatesMu.Unlock()
+	predicates[op] = eval
+}
+
+func predicateEvaluator(op PredicateOp) (PredicateEvaluator, bool) {
+	predicatesMu.RLock()
+	defer predicatesMu.RUnlock()
+	eval, ok := predicates[op]
+	return eval, ok
+}
+
+// IsCondition reports whether pred is a commit condition
+// rather than a predicate on the state of a TSR or a read version.
+func (pred PredicateInfo) IsCondition() bool {
+	return pred.Op != ""
+}
+
+// EvaluatePredicate checks the commit condition pred against the record
+// of pred.ItemKey stored in conn, whose value is deserialized by se.
+// A record prepared by another transaction fails the condition, since
+// the value it will have once resolved is unknown.
+func EvaluatePredicate(conn Connector, se serializer.Serializer, pred PredicateInfo) error {
+	eval, ok := predicateEvaluator(pred.Op)
+	if !ok {
+		return errors.Errorf("unknown predicate operator %q", pred.Op)
+	}
+
+	item, err := GetLatestItem(conn, pred.ItemKey)
+	if err != nil {
+		if err.Error() != KeyNotFound.Error() {
+			return err
+		}
+		item = nil
+	}
+	if item != nil && item.TxnState() == config.PREPARED {
+		return errors.Errorf("%s is being written by another transaction: %w",
+			pred.ItemKey, ErrConditionFailed)
+	}
+	if item != nil && item.IsDeleted() {
+		item = nil
+	}
+
+	var field json.RawMessage
+	if item != nil {
+		if pred.Field == "" {
+			// kept raw, so that the numbers are compared exactly
+			if err := se.Deserialize([]byte(item.Value()), &field); err != nil {
+				return err
+			}
+		} else {
+			var obj map[string]json.RawMessage
+			if err := se.Deserialize([]byte(item.Value()), &obj); err != nil {
+				return errors.Errorf("the field %s of %s cannot be evaluated: %v",
+					pred.Field, pred.ItemKey, err)
+			}
+			field = obj[pred.Field]
+		}
+	}
+
+	holds, err := eval(item, field, pred)
+	if err != nil {
+		return err
+	}
+	if !holds {
+		return errors.Errorf("%s %s %s does not hold for %s: %w",
+			pred.Field, pred.Op, pred.Value, pred.ItemKey, ErrConditionFailed)
+	}
+	return nil
+}
+
+// EvaluateConditions checks the commit conditions of validationMap
+// against the records stored in conn, serialized by se.
+func EvaluateConditions(conn Connector, se serializer.Serializer,
+	validationMap map[string]PredicateInfo) error {
+	var eg errgroup.Group
+	for _, predicate := range validationMap {
+		pred := predicate
+		if !pred.IsCondition() {
+			continue
+		}
+		eg.Go(func() error {
+			return EvaluatePredicate(conn, se, pred)
+		})
+	}
+	return eg.Wait()
+}
+
+func evalEquals(item DataItem, field json.RawMessage, pred PredicateInfo) (bool, error) {
+	if field == nil {
+		return false, nil
+	}
+	actual, err := decodeExact(field)
+	if err != nil {
+		return false, err
+	}
+	expected, err := decodeExact([]byte(pred.Value))
+	if err != nil {
+		return false, errors.Errorf("the operand of %s is not JSON: %v", pred.Op, err)
+	}
+	return reflect.DeepEqual(actual, expected), nil
+}
+
+func evalGreaterThan(item DataItem, field json.RawMessage, pred PredicateInfo) (bool, error) {
+	bound, ok := new(big.Rat).SetString(pred.Value)
+	if !ok {
+		return false, errors.Errorf("the operand of %s is not a number: %s", pred.Op, pred.Value)
+	}
+	if field == nil {
+		return false, nil
+	}
+	actual, err := decodeExact(field)
+	if err != nil {
+		return false, err
+	}
+	number, ok := actual.(exactNumber)
+	if !ok {
+		return false, errors.Errorf("the field %s of %s is not a number", pred.Field, pred.ItemKey)
+	}
+	r, _ := new(big.Rat).SetString(string(number))
+	return r.Cmp(bound) > 0, nil
+}
+
+// exactNumber is a JSON number in the canonical form of big.Rat.RatString.
+type exactNumber string
+
+// decodeExact decodes data with its numbers as exactNumber, so that large
+// integers such as timestamps are neither rounded to a float64 nor told
+// apart by how they are written, e.g. 1 and 1.0.
+func decodeExact(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return exactNumbers(v)
+}
+
+func exactNumbers(v any) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		r, ok := new(big.Rat).SetString(string(v))
+		if !ok {
+			return nil, errors.Errorf("invalid number %s", v)
+		}
+		return exactNumber(r.RatString()), nil
+	case []any:
+		for i := range v {
+			elem, err := exactNumbers(v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = elem
+		}
+	case map[string]any:
+		for k := range v {
+			elem, err := exactNumbers(v[k])
+			if err != nil {
+				return nil, err
+			}
+			v[k] = elem
+		}
+	}
+	return v, nil
+}
+
+func evalVersionEquals(item DataItem, _ json.RawMessage, pred PredicateInfo) (bool, error) {
+	if item == nil {
+		return pred.Version == "", nil
+	}
+	return item.Version() == pred.Version, nil
+}
+
+func evalNotDeleted(item DataItem, _ json.RawMessage, _ PredicateInfo) (bool, error) {
+	return item != nil, nil
+}
+
+// ConditionAdder is implemented by datastores able to check
+// commit conditions when they are prepared.
+type ConditionAdder interface {
+	// AddCondition adds the commit condition pred to the transaction.
+	AddCondition(pred PredicateInfo) error
+
+	// HasConditions reports whether a commit condition has been added.
+	HasConditions() bool
+
+	// CheckConditions evaluates the commit conditions
+	// when the transaction commits without preparing the datastore.
+	CheckConditions() error
+}
+
+var _ ConditionAdder = (*Datastore)(nil)
+
+// AddCondition makes the commit of the transaction conditional on pred,
+// a predicate on the record pred.ItemKey of the datastore dsName: the
+// prepare of the datastore fails with ErrConditionFailed unless pred
+// holds for the stored record, which aborts the transaction.
+//
+// The conditions are checked by the prepare phase, even for a datastore
+// the transaction does not write to, and by the commit of a read-only
+// transaction. The one-phase protocol has no prepare phase to check
+// them, so its transactions can't have any.
+func (t *Transaction) AddCondition(dsName string, pred PredicateInfo) error {
+	if err := t.CheckState(config.STARTED); err != nil {
+		return err
+	}
+	if t.Protocol() == config.OnePhase {
+		return errOnePhaseConditions
+	}
+	ds, ok := t.dataStoreMap[dsName]
+	if !ok {
+		return errors.New("datastore not found: " + dsName)
+	}
+	adder, ok := ds.(ConditionAdder)
+	if !ok {
+		return errors.Errorf("datastore %s does not support commit conditions", dsName)
+	}
+	return adder.AddCondition(pred)
+}
+
+var errOnePhaseConditions = errors.Errorf("the one-phase protocol does not support commit conditions")
+
+// hasConditions reports whether a commit condition has been added to a
+// datastore of the transaction.
+func (t *Transaction) hasConditions() bool {
+	for _, ds := range t.dataStoreMap {
+		if adder, ok := ds.(ConditionAdder); ok && adder.HasConditions() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConditions evaluates the commit conditions of every datastore,
+// for a transaction committing without a prepare phase.
+func (t *Transaction) checkConditions() error {
+	var eg errgroup.Group
+	for _, ds := range t.dataStoreMap {
+		adder, ok := ds.(ConditionAdder)
+		if !ok || !adder.HasConditions() {
+			continue
+		}
+		eg.Go(adder.CheckConditions)
+	}
+	return eg.Wait()
+}
+
+// AddCondition adds the commit condition pred, checked by Prepare.
+func (r *Datastore) AddCondition(pred PredicateInfo) error {
+	if pred.ItemKey == "" {
+		return errors.New("a commit condition needs the key of its record")
+	}
+	if _, ok := predicateEvaluator(pred.Op); !ok {
+		return errors.Errorf("unknown predicate operator %q", pred.Op)
+	}
+	// the entries of validationSet are never removed before it is reset
+	r.validationSet[conditionPredicatePrefix+strconv.Itoa(len(r.validationSet))] = pred
+	return nil
+}
+
+// HasConditions reports whether a commit condition has been added.
+func (r *Datastore) HasConditions() bool {
+	for _, pred := range r.validationSet {
+		if pred.IsCondition() {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckConditions evaluates the commit conditions against the stored
+// records, or has the executor evaluate them for a remote transaction
+// with a prepare request writing nothing.
+func (r *Datastore) CheckConditions() error {
+	if !r.HasConditions() {
+		return nil
+	}
+	if r.Txn.isRemote {
+		_, err := r.prepareInRemote(nil)
+		return err
+	}
+	return EvaluateConditions(r.conn, r.se, r.validationSet)
+}
@@ -0,0 +1,100 @@
+package txn
+
+import (
+	"sync"
+
+	"github.com/go-errors/errors"
+)
+
+// prepareBatch gathers the remote prepares of the datastores of a
+// transaction, so that a PrepareBatchClient sends them at once, in one
+// request per executor, instead of one request per datastore.
+type prepareBatch struct {
+	txn    *Transaction
+	client PrepareBatchClient
+
+	mu sync.Mutex
+	// pending are the datastores that have neither joined nor left
+	pending map[string]struct{}
+	reqs    map[string]RemotePrepare
+	results map[string]RemotePrepareResult
+	// done is closed once the results are in
+	done chan struct{}
+}
+
+// newPrepareBatch returns a batch of the remote prepares of the datastores
+// of t, or nil if they are sent one by one: the client cannot batch them,
+// there is a single datastore, or the datastores do not prepare at once.
+func (t *Transaction) newPrepareBatch(parallelism int) *prepareBatch {
+	if !t.isRemote || len(t.dataStoreMap) < 2 || parallelism > 0 {
+		return nil
+	}
+	client, ok := t.remoteClient().(PrepareBatchClient)
+	if !ok {
+		return nil
+	}
+	b := &prepareBatch{
+		txn:     t,
+		client:  client,
+		pending: make(map[string]struct{}, len(t.dataStoreMap)),
+		reqs:    make(map[string]RemotePrepare, len(t.dataStoreMap)),
+		done:    make(chan struct{}),
+	}
+	for name := range t.dataStoreMap {
+		b.pending[name] = struct{}{}
+	}
+	return b
+}
+
+// join adds the remote prepare of dsName to the batch
+// and returns its result once the batch has been sent.
+func (b *prepareBatch) join(dsName string, req RemotePrepare) RemotePrepareResult {
+	b.mu.Lock()
+	if _, ok := b.pending[dsName]; !ok {
+		b.mu.Unlock()
+		return RemotePrepareResult{Err: errors.Errorf("%s has already been prepared in the batch", dsName)}
+	}
+	delete(b.pending, dsName)
+	b.reqs[dsName] = req
+	b.sendIfComplete()
+	<-b.done
+	res, ok := b.results[dsName]
+	if !ok {
+		return RemotePrepareResult{Err: errors.Errorf("no prepare result for %s", dsName)}
+	}
+	return res
+}
+
+// leave is called once the prepare of dsName returns, so that the batch
+// does not wait for a datastore with nothing to prepare remotely.
+func (b *prepareBatch) leave(dsName string) {
+	b.mu.Lock()
+	if _, ok := b.pending[dsName]; !ok {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, dsName)
+	b.sendIfComplete()
+}
+
+// sendIfComplete sends the batch once every datastore has joined or left.
+// It is called with mu held and releases it.
+func (b *prepareBatch) sendIfComplete() {
+	if len(b.pending) != 0 {
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Unlock()
+
+	// the batch is complete, no one modifies reqs anymore
+	if err := b.txn.prepareCanceled(); err != nil {
+		results := make(map[string]RemotePrepareResult, len(b.reqs))
+		for dsName := range b.reqs {
+			results[dsName] = RemotePrepareResult{Err: err}
+		}
+		b.results = results
+	} else if len(b.reqs) != 0 {
+		b.results = b.client.PrepareDatastores(b.txn.TxnStartTime, b.txn.prepareConfig(), b.reqs)
+	}
+	close(b.done)
+}
@@ -0,0 +1,166 @@
+package txn
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// ErrPrepareTimeout is the cause of a commit aborted because a datastore
+// has not finished its prepare phase within config.Config.PrepareTimeout.
+var ErrPrepareTimeout = errors.Errorf("prepare timeout")
+
+// prepareState tracks the prepare phase of a transaction for the
+// datastores still preparing after the phase has been given up.
+type prepareState struct {
+	// ctx is cancelled once the prepare phase of a datastore fails,
+	// so that the others stop preparing their remaining records.
+	ctx context.Context
+	// pending are closed when the prepare of the datastore returns.
+	pending map[string]chan struct{}
+	// batch gathers the remote prepares of the datastores, if not nil.
+	batch *prepareBatch
+}
+
+// prepareDatastores runs prepare on every datastore concurrently, at most
+// parallelism at a time if positive, and returns the first error.
+//
+// It returns as soon as a datastore fails or exceeds PrepareTimeout instead
+// of waiting for all of them: the other datastores are cancelled and stop
+// before their next conditional update, and Abort rolls back the datastores
+// still preparing once their prepare returns. The next Start of the
+// transaction waits for those until then.
+func (t *Transaction) prepareDatastores(parallelism int, prepare func(ds Datastorer) error) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	timeout := config.Config.PrepareTimeout
+
+	state := &prepareState{
+		ctx:     ctx,
+		pending: make(map[string]chan struct{}, len(t.dataStoreMap)),
+		batch:   t.newPrepareBatch(parallelism),
+	}
+	for name := range t.dataStoreMap {
+		state.pending[name] = make(chan struct{})
+	}
+	if batch := state.batch; batch != nil {
+		prepareOne := prepare
+		prepare = func(ds Datastorer) error {
+			defer batch.leave(ds.GetName())
+			return prepareOne(ds)
+		}
+	}
+	t.prepareMu.Lock()
+	t.prepare = state
+	t.prepareMu.Unlock()
+
+	var sem chan struct{}
+	if parallelism > 0 {
+		sem = make(chan struct{}, parallelism)
+	}
+	results := make(chan error, len(t.dataStoreMap))
+	for name, ds := range t.dataStoreMap {
+		name, ds := name, ds
+		go func() {
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+					// the phase may have failed while this one was waiting
+					if err := context.Cause(ctx); err != nil {
+						close(state.pending[name])
+						results <- err
+						return
+					}
+				case <-ctx.Done():
+					close(state.pending[name])
+					results <- context.Cause(ctx)
+					return
+				}
+			}
+			results <- t.prepareWithTimeout(ds, timeout, state.pending[name], prepare)
+		}()
+	}
+
+	for range t.dataStoreMap {
+		if err := <-results; err != nil {
+			cancel(err)
+			return err
+		}
+	}
+	// every datastore has prepared, there is nothing left to cancel
+	t.prepareMu.Lock()
+	t.prepare = nil
+	t.prepareMu.Unlock()
+	cancel(nil)
+	return nil
+}
+
+// prepareWithTimeout runs prepare on ds and closes done once it returns.
+// It gives up waiting after timeout, if positive.
+func (t *Transaction) prepareWithTimeout(ds Datastorer, timeout time.Duration, done chan struct{},
+	prepare func(ds Datastorer) error) error {
+	result := make(chan error, 1)
+	t.background.Add(1)
+	go func() {
+		defer t.background.Done()
+		defer close(done)
+		result <- prepare(ds)
+	}()
+	if timeout <= 0 {
+		return <-result
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+		return errors.Errorf("%s has not prepared within %v: %w",
+			ds.GetName(), timeout, ErrPrepareTimeout)
+	}
+}
+
+// prepareCanceled returns the cause of the failure of the prepare phase
+// if another datastore has failed, or nil to go on preparing.
+func (t *Transaction) prepareCanceled() error {
+	t.prepareMu.Lock()
+	defer t.prepareMu.Unlock()
+	if t.prepare == nil {
+		return nil
+	}
+	return context.Cause(t.prepare.ctx)
+}
+
+// prepareBatch returns the batch gathering the remote prepares
+// of the datastores, or nil if they are sent one by one.
+func (t *Transaction) prepareBatch() *prepareBatch {
+	t.prepareMu.Lock()
+	defer t.prepareMu.Unlock()
+	if t.prepare == nil {
+		return nil
+	}
+	return t.prepare.batch
+}
+
+// pendingPrepare returns a channel closed when the prepare of the datastore
+// dsName returns, or nil if it is not preparing.
+func (t *Transaction) pendingPrepare(dsName string) <-chan struct{} {
+	t.prepareMu.Lock()
+	defer t.prepareMu.Unlock()
+	if t.prepare == nil {
+		return nil
+	}
+	done, ok := t.prepare.pending[dsName]
+	if !ok {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	default:
+		return done
+	}
+}
@@ -0,0 +1,252 @@
+package txn
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// hungDatastore does not finish its prepare phase until release is closed.
+type hungDatastore struct {
+	trackedDatastore
+	release chan struct{}
+	aborted chan struct{}
+}
+
+func (ds *hungDatastore) Prepare() (int64, error) {
+	<-ds.release
+	return 0, nil
+}
+
+func (ds *hungDatastore) OnePhaseCommit() error {
+	<-ds.release
+	return nil
+}
+
+func (ds *hungDatastore) Abort(bool) error {
+	close(ds.aborted)
+	return nil
+}
+
+func newHungDatastore(name string) *hungDatastore {
+	return &hungDatastore{
+		trackedDatastore: trackedDatastore{name: name},
+		release:          make(chan struct{}),
+		aborted:          make(chan struct{}),
+	}
+}
+
+// cancelAwareDatastore prepares its records one by one
+// and stops once the prepare phase is cancelled.
+type cancelAwareDatastore struct {
+	trackedDatastore
+	txn      *Transaction
+	prepared atomic.Int32
+	canceled atomic.Value
+}
+
+func (ds *cancelAwareDatastore) SetTxn(txn *Transaction) { ds.txn = txn }
+
+func (ds *cancelAwareDatastore) Prepare() (int64, error) {
+	for i := 0; i < 100; i++ {
+		if err := ds.txn.prepareCanceled(); err != nil {
+			ds.canceled.Store(err)
+			return 0, err
+		}
+		time.Sleep(5 * time.Millisecond)
+		ds.prepared.Add(1)
+	}
+	return 0, nil
+}
+
+func withPrepareTimeout(timeout time.Duration) func() {
+	old := config.Config.PrepareTimeout
+	config.Config.PrepareTimeout = timeout
+	return func() { config.Config.PrepareTimeout = old }
+}
+
+// writeAll writes a record to each of the datastores dsNames.
+func writeAll(t *testing.T, txn *Transaction, dsNames ...string) {
+	for _, dsName := range dsNames {
+		if err := txn.Write(dsName, "key", "value"); err != nil {
+			t.Fatalf("Error writing: %s", err)
+		}
+	}
+}
+
+// TestTxnPrepareTimeout tests that a hung datastore aborts the transaction
+// once the prepare timeout has elapsed, not once it returns.
+func TestTxnPrepareTimeout(t *testing.T) {
+	defer withPrepareTimeout(50 * time.Millisecond)()
+
+	hung := &hungDatastore{
+		trackedDatastore: trackedDatastore{name: "hung"},
+		release:          make(chan struct{}),
+		aborted:          make(chan struct{}),
+	}
+	txn := NewTransaction()
+	_ = txn.AddDatastore(&trackedDatastore{name: "fast", tracker: &peakTracker{}})
+	_ = txn.AddDatastore(hung)
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+	writeAll(t, txn, "fast", "hung")
+
+	// the hung datastore returns long after the timeout
+	time.AfterFunc(500*time.Millisecond, func() { close(hung.release) })
+	start := time.Now()
+	err := txn.Commit()
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrPrepareTimeout) {
+		t.Errorf("Expected ErrPrepareTimeout, got %v", err)
+	}
+	if elapsed < 50*time.Millisecond || elapsed > 250*time.Millisecond {
+		t.Errorf("Expected the commit to abort near the timeout, took %v", elapsed)
+	}
+	if txn.GetState() != config.ABORTED {
+		t.Errorf("Expected the transaction to be aborted, got %v", txn.GetState())
+	}
+
+	// the hung datastore is rolled back once its prepare returns
+	select {
+	case <-hung.aborted:
+		t.Errorf("Expected the hung datastore not to be aborted while preparing")
+	default:
+	}
+	select {
+	case <-hung.aborted:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the hung datastore to be aborted once its prepare returns")
+	}
+}
+
+// TestTxnPrepareTimeoutCancelsSiblings tests that the datastores still
+// preparing stop once another one has timed out.
+func TestTxnPrepareTimeoutCancelsSiblings(t *testing.T) {
+	defer withPrepareTimeout(50 * time.Millisecond)()
+
+	hung := &hungDatastore{
+		trackedDatastore: trackedDatastore{name: "hung"},
+		release:          make(chan struct{}),
+		aborted:          make(chan struct{}),
+	}
+	defer close(hung.release)
+	sibling := &cancelAwareDatastore{trackedDatastore: trackedDatastore{name: "sibling"}}
+	txn := NewTransaction()
+	_ = txn.AddDatastore(hung)
+	_ = txn.AddDatastore(sibling)
+	_ = txn.Start()
+	writeAll(t, txn, "hung", "sibling")
+
+	if err := txn.Commit(); !errors.Is(err, ErrPrepareTimeout) {
+		t.Errorf("Expected ErrPrepareTimeout, got %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	canceled, _ := sibling.canceled.Load().(error)
+	if !errors.Is(canceled, ErrPrepareTimeout) {
+		t.Errorf("Expected the sibling to be cancelled by the timeout, got %v", canceled)
+	}
+	if n := sibling.prepared.Load(); n >= 100 {
+		t.Errorf("Expected the sibling to stop preparing, prepared %d records", n)
+	}
+}
+
+// TestTxnPrepareWithoutTimeout tests that a slow datastore is waited
+// for when no prepare timeout is set.
+func TestTxnPrepareWithoutTimeout(t *testing.T) {
+	defer withPrepareTimeout(0)()
+
+	hung := &hungDatastore{
+		trackedDatastore: trackedDatastore{name: "slow"},
+		release:          make(chan struct{}),
+		aborted:          make(chan struct{}),
+	}
+	txn := NewTransaction()
+	_ = txn.AddDatastore(&trackedDatastore{name: "fast", tracker: &peakTracker{}})
+	_ = txn.AddDatastore(hung)
+	_ = txn.Start()
+	writeAll(t, txn, "fast", "slow")
+
+	time.AfterFunc(100*time.Millisecond, func() { close(hung.release) })
+	start := time.Now()
+	if err := txn.Commit(); err != nil {
+		t.Errorf("Error committing: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the commit to wait for the slow datastore, took %v", elapsed)
+	}
+}
+
+// TestTxnPrepareTimeoutRestart tests that the transaction started again
+// after a prepare timeout waits for the hung datastore to be rolled back,
+// so that the abandoned prepare does not touch the next attempt.
+func TestTxnPrepareTimeoutRestart(t *testing.T) {
+	defer withPrepareTimeout(50 * time.Millisecond)()
+
+	hung := newHungDatastore("hung")
+	txn := NewTransaction()
+	_ = txn.AddDatastore(&trackedDatastore{name: "fast", tracker: &peakTracker{}})
+	_ = txn.AddDatastore(hung)
+	_ = txn.Start()
+	writeAll(t, txn, "fast", "hung")
+	if err := txn.Commit(); !errors.Is(err, ErrPrepareTimeout) {
+		t.Fatalf("Expected ErrPrepareTimeout, got %v", err)
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { close(hung.release) })
+	start := time.Now()
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected Start to wait for the hung datastore, took %v", elapsed)
+	}
+	select {
+	case <-hung.aborted:
+	default:
+		t.Errorf("Expected the hung datastore to be aborted before Start returns")
+	}
+}
+
+// TestTxnPrepareTimeoutProtocols tests that the prepare timeout also
+// bounds the commit of the Cherry Garcia and one-phase protocols.
+func TestTxnPrepareTimeoutProtocols(t *testing.T) {
+	defer withPrepareTimeout(50 * time.Millisecond)()
+
+	testCases := []struct {
+		protocol config.Protocol
+		dsNames  []string
+	}{
+		{config.CherryGarcia, []string{"fast", "hung"}},
+		// the one-phase protocol commits a single record
+		{config.OnePhase, []string{"hung"}},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.protocol), func(t *testing.T) {
+			hung := newHungDatastore("hung")
+			defer close(hung.release)
+			txn := NewTransaction()
+			txn.SetProtocol(tc.protocol)
+			if len(tc.dsNames) > 1 {
+				_ = txn.AddDatastore(&trackedDatastore{name: "fast", tracker: &peakTracker{}})
+			}
+			_ = txn.AddDatastore(hung)
+			_ = txn.Start()
+			writeAll(t, txn, tc.dsNames...)
+
+			start := time.Now()
+			err := txn.Commit()
+			elapsed := time.Since(start)
+			if !errors.Is(err, ErrPrepareTimeout) {
+				t.Errorf("Expected ErrPrepareTimeout, got %v", err)
+			}
+			if elapsed > 250*time.Millisecond {
+				t.Errorf("Expected the commit to give up near the timeout, took %v", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package txn
+
+import (
+	"fmt"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// SetProtocol overrides config.Config.Protocol for the transaction,
+// so that transactions committed by different protocols can run side by side.
+func (t *Transaction) SetProtocol(protocol config.Protocol) {
+	t.protocol = &protocol
+}
+
+// Protocol returns the protocol committing the transaction.
+func (t *Transaction) Protocol() config.Protocol {
+	if t.protocol != nil {
+		return *t.protocol
+	}
+	return config.Config.Protocol
+}
+
+// commitInOnePhase commits the transaction with the one-phase protocol,
+// which only applies to a transaction writing a single record.
+func (t *Transaction) commitInOnePhase() error {
+	written := 0
+	for _, ds := range t.dataStoreMap {
+		written += ds.GetWriteCacheSize()
+	}
+	if written > 1 {
+		_ = t.Abort()
+		return errors.Errorf("the one-phase protocol commits a single record, the transaction has written %d", written)
+	}
+	if t.isRemote {
+		_ = t.Abort()
+		return errors.New("the one-phase protocol is not supported by remote transactions")
+	}
+	if t.hasConditions() {
+		_ = t.Abort()
+		return errOnePhaseConditions
+	}
+
+	var err error
+	if t.TxnCommitTime, err = t.getTime("commit"); err != nil {
+		_ = t.Abort()
+		return fmt.Errorf("failed to get time: %v", err)
+	}
+	return t.onePhaseCommit()
+}
@@ -0,0 +1,61 @@
+package txn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxnProtocol(t *testing.T) {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	commit := func(protocol config.Protocol, dsNames ...string) *txn.Transaction {
+		tx := txn.NewTransaction()
+		tx.SetProtocol(protocol)
+		for _, name := range dsNames {
+			_ = tx.AddDatastore(txn.NewDatastore(name, conn, &redis.RedisItemFactory{}))
+		}
+		assert.NoError(t, tx.Start())
+		for _, name := range dsNames {
+			assert.NoError(t, tx.Write(name, name+"-"+string(protocol), "v1"))
+		}
+		assert.NoError(t, tx.Commit())
+		time.Sleep(100 * time.Millisecond)
+		return tx
+	}
+
+	tx := commit(config.Oreo2PC, "memkv")
+	assert.Equal(t, config.Oreo2PC, tx.Protocol())
+	assert.True(t, tx.Stats().AsyncCommit)
+
+	tx = commit(config.CherryGarcia, "memkv")
+	assert.False(t, tx.Stats().AsyncCommit)
+	assert.False(t, tx.Stats().OnePhase)
+
+	tx = commit(config.OnePhase, "memkv")
+	assert.True(t, tx.Stats().OnePhase)
+	item, err := conn.GetItem("memkv-one-phase")
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	assert.Equal(t, tx.TxnCommitTime, item.TValid())
+	_, err = conn.Get("memkv:" + tx.TxnId)
+	assert.Error(t, err)
+
+	// Cherry Garcia shares a single group key between the datastores
+	tx = commit(config.Oreo2PC, "ds1", "ds2")
+	assert.Len(t, tx.GroupKeyUrls, 2)
+	tx = commit(config.CherryGarcia, "ds1", "ds2")
+	assert.Len(t, tx.GroupKeyUrls, 1)
+
+	tx = txn.NewTransaction()
+	tx.SetProtocol(config.OnePhase)
+	_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("memkv", "item1", "v1"))
+	assert.NoError(t, tx.Write("memkv", "item2", "v1"))
+	assert.Error(t, tx.Commit())
+}
@@ -0,0 +1,104 @@
+package txn
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/internal/testutil"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
+)
+
+// FieldReader is implemented by datastores able to read
+// some fields of a record without the rest of its value.
+type FieldReader interface {
+	// ReadFields reads the fields of the record named by fields into value.
+	ReadFields(key string, fields []string, value any) error
+}
+
+var _ FieldReader = (*Datastore)(nil)
+
+// ReadFields reads the fields of the value of key named by fields into
+// value, leaving the other fields of value untouched, which are zero-valued
+// if value is. The fields are the top-level keys of the value as stored,
+// which must be an object encoded by a JSON serializer.
+//
+// A field absent from the stored value is not an error: it is treated
+// like a field that is not requested, as a record written before the field
+// was added would be read by Read.
+//
+// In remote mode, the executor sends back the requested fields only if the
+// client supports it, so that reading a field of a wide record does not
+// transfer the whole record.
+func (t *Transaction) ReadFields(dsName string, key string, fields []string, value any) error {
+	err := t.CheckState(config.STARTED)
+	if err != nil {
+		return err
+	}
+	ds, ok := t.dataStoreMap[dsName]
+	if !ok {
+		return errors.New("datastore not found: " + dsName)
+	}
+
+	t.debug(testutil.DRead, "read fields %v in %v: [Key: %v]", fields, dsName, key)
+	if fr, ok := ds.(FieldReader); ok {
+		return fr.ReadFields(key, fields, value)
+	}
+	var raw json.RawMessage
+	if err := ds.Read(key, &raw); err != nil {
+		return err
+	}
+	return decodeFields(config.Config.Serializer, raw, fields, value)
+}
+
+// ReadFields reads the fields of a record named by fields into value.
+// In remote mode, a record in neither cache is read from the executor
+// projected on fields, and is not cached since the rest of its value
+// is missing.
+func (r *Datastore) ReadFields(key string, fields []string, value any) error {
+	_, written := r.writeCache[key]
+	_, read := r.cachedRead(key)
+	if r.Txn.isRemote && !written && !read {
+		if _, ok := r.Txn.remoteClient().(FieldReadClient); ok {
+			item, err := r.remoteRead(key, fields)
+			if err != nil {
+				return err
+			}
+			// the executor may not support the projection
+			return decodeFields(r.se, []byte(item.Value()), fields, value)
+		}
+	}
+
+	var raw json.RawMessage
+	if err := r.Read(key, &raw); err != nil {
+		return err
+	}
+	return decodeFields(r.se, raw, fields, value)
+}
+
+// ProjectValue returns the value data serialized by se with only
+// the top-level fields named by fields. The fields absent from data
+// are left out.
+func ProjectValue(se serializer.Serializer, data []byte, fields []string) ([]byte, error) {
+	var obj map[string]json.RawMessage
+	if err := se.Deserialize(data, &obj); err != nil {
+		return nil, fmt.Errorf("the value cannot be projected on fields: %w", err)
+	}
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if v, ok := obj[field]; ok {
+			projected[field] = v
+		}
+	}
+	return se.Serialize(projected)
+}
+
+// decodeFields deserializes the fields of data named by fields into value.
+func decodeFields(se serializer.Serializer, data []byte, fields []string, value any) error {
+	projected, err := ProjectValue(se, data, fields)
+	if err != nil {
+		return err
+	}
+	return se.Deserialize(projected, value)
+}
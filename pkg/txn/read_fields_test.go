@@ -0,0 +1,25 @@
+package txn
+
+import (
+	"testing"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectValue(t *testing.T) {
+	se := serializer.NewJSON2Serializer()
+	data := []byte(`{"Name":"John","Age":30,"Bio":"a long story","Id":9007199254740993}`)
+
+	projected, err := ProjectValue(se, data, []string{"Name", "Id", "Email"})
+	assert.NoError(t, err)
+	// the values are kept as they are, without a round trip through float64
+	assert.JSONEq(t, `{"Name":"John","Id":9007199254740993}`, string(projected))
+
+	projected, err = ProjectValue(se, data, nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(projected))
+
+	_, err = ProjectValue(se, []byte(`"a string"`), []string{"Name"})
+	assert.ErrorContains(t, err, "cannot be projected")
+}
@@ -0,0 +1,166 @@
+package txn
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/internal/testutil"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	. "github.com/oreo-dtx-lab/oreo/pkg/logger"
+)
+
+// RecordLocker is implemented by the datastores that can lock
+// the records read by a transaction, for Transaction.ReadForUpdate.
+type RecordLocker interface {
+	// LockRecord locks the record of key last read by the transaction.
+	// It returns an error wrapping RecordLocked if another transaction
+	// holds the record, which may be read and locked again later.
+	LockRecord(key string) error
+}
+
+var _ RecordLocker = (*Datastore)(nil)
+
+// ReadForUpdate reads the value of key like Read, then locks its record
+// in the datastore so that no other transaction can write it, or read it
+// for update, until the transaction commits or aborts. The other readers
+// see the record as they see any record being written.
+// A read-modify-write then no longer aborts at prepare because a concurrent
+// transaction committed the key after it was read.
+//
+// The record is locked the way the prepare phase marks it: it is put in
+// the PREPARED state by the transaction with a lease of
+// config.Config.ReadForUpdateLease, after which the other transactions
+// roll it back if the transaction has neither committed nor aborted.
+// A record held by another transaction is read again until it is released
+// or config.Config.ReadForUpdateWait elapses, and then an error wrapping
+// RecordLocked is returned and the transaction should be aborted.
+//
+// A snapshot transaction only reads the versions committed before it
+// started, so it fails with VersionMismatch if the record has been written
+// since: the prepare phase would fail anyway. A record written before
+// it is read is not locked and is only checked at prepare, and the
+// records read through the executors cannot be locked.
+func (t *Transaction) ReadForUpdate(dsName string, key string, value any) error {
+	err := t.CheckState(config.STARTED)
+	if err != nil {
+		return err
+	}
+	if t.isSnapshot {
+		return errors.New("read for update in a read-only transaction")
+	}
+	ds, ok := t.dataStoreMap[dsName]
+	if !ok {
+		return errors.New("datastore not found: " + dsName)
+	}
+	locker, ok := ds.(RecordLocker)
+	if !ok || t.isRemote {
+		return errors.Errorf("datastore %s can't lock its records", dsName)
+	}
+
+	t.debug(testutil.DRead, "read for update in %v: [Key: %v]", dsName, key)
+	deadline := time.Now().Add(config.Config.ReadForUpdateWait)
+	for {
+		err = ds.Read(key, value)
+		if errors.Is(err, ReadFailed) || errors.Is(err, VersionMismatch) {
+			// the record is prepared by a transaction still running,
+			// or is being committed or rolled back by it
+			err = errors.Errorf("%w: %s", RecordLocked, key)
+		} else if err == nil {
+			err = locker.LockRecord(key)
+		}
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, RecordLocked) || !time.Now().Add(RETRYINTERVAL).Before(deadline) {
+			return err
+		}
+		time.Sleep(RETRYINTERVAL)
+	}
+
+	// the lock is released by the commit or the abort of the record
+	t.isReadOnly = false
+	if t.lockedKeys == nil {
+		t.lockedKeys = make(map[string]struct{})
+	}
+	t.lockedKeys[dsName+":"+key] = struct{}{}
+	return nil
+}
+
+// LockRecord locks the record of key read by the transaction with a
+// conditional update putting it in the PREPARED state, as a write of the
+// value read, so that the record is released by the commit or the abort.
+// The record is updated over its lock by the prepare phase.
+func (r *Datastore) LockRecord(key string) error {
+	if _, ok := r.locked[key]; ok {
+		return nil
+	}
+	item, ok := r.readCache[key]
+	if !ok {
+		// the record has been written before it is read
+		return nil
+	}
+	if item.TxnState() != config.COMMITTED || r.invisibleSet[key] {
+		r.forget(key)
+		return errors.Errorf("%w: %s", RecordLocked, key)
+	}
+
+	lock := r.itemFactory.NewDataItem(ItemOptions{
+		Key:          key,
+		Value:        item.Value(),
+		GroupKeyList: fmt.Sprintf("%s:%s", r.Name, r.Txn.TxnId),
+	})
+	lock, err := r.updateMetadata(lock, item)
+	if err != nil {
+		return err
+	}
+	// the snapshots taken before the lock read the record it holds
+	lock.SetTValid(r.Txn.TxnStartTime)
+	lock.SetTLease(r.Txn.now().Add(config.Config.ReadForUpdateLease))
+	newVer, err := r.conn.ConditionalUpdate(key, lock, false)
+	if err != nil {
+		if errors.Is(err, VersionMismatch) && r.isHeld(key) {
+			r.forget(key)
+			return errors.Errorf("%w: %s", RecordLocked, key)
+		}
+		return err
+	}
+	lock.SetVersion(newVer)
+
+	if r.locked == nil {
+		r.locked = make(map[string]DataItem)
+	}
+	r.locked[key] = lock
+	if _, ok := r.writeCache[key]; !ok {
+		return r.writeToCache(r.itemFactory.NewDataItem(ItemOptions{
+			Key:   key,
+			Value: item.Value(),
+		}))
+	}
+	return nil
+}
+
+// isHeld reports whether the record of key is prepared by a transaction.
+func (r *Datastore) isHeld(key string) bool {
+	item, err := GetLatestItem(r.conn, key)
+	return err == nil && item.TxnState() == config.PREPARED
+}
+
+// forget drops the read of key, so that the record is read again.
+func (r *Datastore) forget(key string) {
+	delete(r.readCache, key)
+	delete(r.invisibleSet, key)
+}
+
+// unlockRecords rolls back the records still locked by the transaction
+// to the versions they hold. The records prepared since are left to the
+// abort of the prepare phase.
+func (r *Datastore) unlockRecords() {
+	for key, lock := range r.locked {
+		if _, err := r.rollback(lock); err != nil {
+			// prepared since, or rolled back by another transaction once the lease has expired
+			Log.Debugw("failed to release the lock", "txnId", r.Txn.TxnId, "key", key, "cause", err)
+		}
+	}
+	r.locked = nil
+}
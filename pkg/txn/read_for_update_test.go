@@ -0,0 +1,174 @@
+package txn_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRMWTxn returns a read-committed transaction on the datastore
+// "redis1" of conn, which reads the latest committed counter.
+func newRMWTxn(conn txn.Connector) *txn.Transaction {
+	tx := txn.NewTransaction()
+	ds := txn.NewDatastore("redis1", conn, &redis.RedisItemFactory{})
+	_ = tx.AddDatastore(ds)
+	tx.SetGlobalDatastore(ds)
+	tx.SetIsolationLevel(config.ReadCommitted)
+	return tx
+}
+
+// commitInForeground makes the local transactions create their TSRs
+// and commit their records before Commit returns.
+func commitInForeground(t *testing.T) {
+	ablationLevel := config.Config.AblationLevel
+	t.Cleanup(func() { config.Config.AblationLevel = ablationLevel })
+	config.Config.AblationLevel = 3
+}
+
+// runIncrements increments a hot counter from concurrent transactions
+// and returns the number of aborted ones.
+func runIncrements(t *testing.T, conn txn.Connector, forUpdate bool) int {
+	const workers, rounds = 8, 10
+	var aborts atomic.Int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				tx := newRMWTxn(conn)
+				if !assert.NoError(t, tx.Start()) {
+					return
+				}
+				var counter int
+				read := tx.Read
+				if forUpdate {
+					read = tx.ReadForUpdate
+				}
+				if err := read("redis1", "hot", &counter); err != nil {
+					aborts.Add(1)
+					_ = tx.Abort()
+					continue
+				}
+				// widens the window between the read and the write
+				time.Sleep(time.Millisecond)
+				_ = tx.Write("redis1", "hot", counter+1)
+				if err := tx.Commit(); err != nil {
+					aborts.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return int(aborts.Load())
+}
+
+// readCounter returns the committed value of the hot counter.
+func readCounter(t *testing.T, conn txn.Connector) int {
+	tx := newRMWTxn(conn)
+	assert.NoError(t, tx.Start())
+	var counter int
+	assert.NoError(t, tx.Read("redis1", "hot", &counter))
+	assert.NoError(t, tx.Commit())
+	return counter
+}
+
+// newCounter returns a connection holding the hot counter at 0.
+func newCounter(t *testing.T) txn.Connector {
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	tx := newRMWTxn(conn)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.Write("redis1", "hot", 0))
+	assert.NoError(t, tx.Commit())
+	return conn
+}
+
+// TestTxnReadForUpdate tests that locking the record of the hot key at
+// read time prevents the aborts of concurrent read-modify-write transactions.
+func TestTxnReadForUpdate(t *testing.T) {
+	commitInForeground(t)
+	plain := newCounter(t)
+	plainAborts := runIncrements(t, plain, false)
+	assert.NotZero(t, plainAborts, "the read-modify-writes should conflict without ReadForUpdate")
+	assert.Equal(t, 80-plainAborts, readCounter(t, plain))
+
+	locked := newCounter(t)
+	lockedAborts := runIncrements(t, locked, true)
+	assert.Zero(t, lockedAborts)
+	assert.Equal(t, 80, readCounter(t, locked))
+	t.Logf("aborts without ReadForUpdate: %d, with: %d", plainAborts, lockedAborts)
+}
+
+// TestTxnReadForUpdateLock tests that the record read for update is locked
+// in the datastore until its holder aborts, and that ReadForUpdate gives up
+// on a record held for too long.
+func TestTxnReadForUpdateLock(t *testing.T) {
+	commitInForeground(t)
+	wait := config.Config.ReadForUpdateWait
+	defer func() { config.Config.ReadForUpdateWait = wait }()
+	config.Config.ReadForUpdateWait = 20 * time.Millisecond
+
+	conn := newCounter(t)
+	holder := newRMWTxn(conn)
+	assert.NoError(t, holder.Start())
+	var counter int
+	assert.NoError(t, holder.ReadForUpdate("redis1", "hot", &counter))
+	item, err := conn.GetItem("hot")
+	assert.NoError(t, err)
+	assert.Equal(t, config.PREPARED, item.TxnState())
+
+	waiter := newRMWTxn(conn)
+	assert.NoError(t, waiter.Start())
+	assert.ErrorIs(t, waiter.ReadForUpdate("redis1", "hot", &counter), txn.RecordLocked)
+
+	// nor can the record be written
+	writer := newRMWTxn(conn)
+	assert.NoError(t, writer.Start())
+	assert.NoError(t, writer.Write("redis1", "hot", 10))
+	assert.Error(t, writer.Commit())
+
+	// the lock is released by the abort of its holder
+	assert.NoError(t, holder.Abort())
+	item, err = conn.GetItem("hot")
+	assert.NoError(t, err)
+	assert.Equal(t, config.COMMITTED, item.TxnState())
+	assert.NoError(t, waiter.ReadForUpdate("redis1", "hot", &counter))
+	assert.Equal(t, 0, counter)
+	assert.NoError(t, waiter.Abort())
+	assert.Equal(t, 0, readCounter(t, conn))
+}
+
+// TestTxnReadForUpdateLeaseExpires tests that a record locked by a
+// transaction that neither commits nor aborts is released once its lease
+// has expired, and that the late commit of the holder then fails.
+func TestTxnReadForUpdateLeaseExpires(t *testing.T) {
+	commitInForeground(t)
+	lease := config.Config.ReadForUpdateLease
+	defer func() { config.Config.ReadForUpdateLease = lease }()
+	config.Config.ReadForUpdateLease = 10 * time.Millisecond
+
+	conn := newCounter(t)
+	holder := newRMWTxn(conn)
+	assert.NoError(t, holder.Start())
+	var counter int
+	assert.NoError(t, holder.ReadForUpdate("redis1", "hot", &counter))
+
+	time.Sleep(20 * time.Millisecond)
+	config.Config.ReadForUpdateLease = lease
+	tx := newRMWTxn(conn)
+	assert.NoError(t, tx.Start())
+	assert.NoError(t, tx.ReadForUpdate("redis1", "hot", &counter))
+	assert.NoError(t, tx.Write("redis1", "hot", counter+1))
+	assert.NoError(t, tx.Commit())
+
+	assert.NoError(t, holder.Write("redis1", "hot", counter+10))
+	assert.Error(t, holder.Commit())
+	assert.Equal(t, 1, readCounter(t, conn))
+}
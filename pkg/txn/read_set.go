@@ -0,0 +1,178 @@
+package txn
+
+import (
+	"maps"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"golang.org/x/sync/errgroup"
+)
+
+// readPredicatePrefix keeps the read set predicates apart from
+// the TSR predicates keyed by their group key lists.
+const readPredicatePrefix = "read:"
+
+// ReadSetValidator is implemented by datastores that can check
+// the records read by the transaction at commit time.
+type ReadSetValidator interface {
+	// ValidateReadSet returns an error if a record read but not written
+	// by the transaction no longer has the version it observed.
+	ValidateReadSet() error
+}
+
+// cachedReadValidator is implemented by datastores that can check the
+// records read by the transaction for Validate, whose read set may not
+// have been recorded.
+type cachedReadValidator interface {
+	validateCachedReads() error
+}
+
+// recordRead adds the version of key observed by the transaction to
+// its read set. An empty version means the key was not found.
+// Snapshot reads are not recorded since they never see a later write.
+func (t *Transaction) recordRead(dsName string, key string, version string) {
+	if !config.Config.ReadSetValidation || t.isSnapshot {
+		return
+	}
+	t.readSetMu.Lock()
+	defer t.readSetMu.Unlock()
+	if t.readSet == nil {
+		t.readSet = make(map[string]map[string]string)
+	}
+	if t.readSet[dsName] == nil {
+		t.readSet[dsName] = make(map[string]string)
+	}
+	// the first read decides the version the transaction depends on
+	if _, ok := t.readSet[dsName][key]; !ok {
+		t.readSet[dsName][key] = version
+	}
+}
+
+// readVersions returns the read set of the datastore dsName.
+func (t *Transaction) readVersions(dsName string) map[string]string {
+	t.readSetMu.Lock()
+	defer t.readSetMu.Unlock()
+	return t.readSet[dsName]
+}
+
+// validateCachedReads validates the reads of the transaction for Validate.
+func (t *Transaction) validateCachedReads() error {
+	var eg errgroup.Group
+	for _, ds := range t.dataStoreMap {
+		validator, ok := ds.(cachedReadValidator)
+		if !ok {
+			continue
+		}
+		eg.Go(validator.validateCachedReads)
+	}
+	return eg.Wait()
+}
+
+// validateReadSet validates the read set of a read-only transaction,
+// whose Commit does not run the prepare phase.
+func (t *Transaction) validateReadSet() error {
+	var eg errgroup.Group
+	for _, ds := range t.dataStoreMap {
+		validator, ok := ds.(ReadSetValidator)
+		if !ok {
+			continue
+		}
+		eg.Go(validator.ValidateReadSet)
+	}
+	return eg.Wait()
+}
+
+// CheckReadVersion returns an error if the record of key
+// does not have version, or exists while version is empty.
+func CheckReadVersion(conn Connector, key string, version string) error {
+	current := ""
+	item, err := GetLatestItem(conn, key)
+	if err != nil {
+		if err.Error() != KeyNotFound.Error() {
+			return err
+		}
+	} else {
+		current = item.Version()
+	}
+	if current != version {
+		return errors.Errorf("%w: %s has changed since it was read", ReadSetChanged, key)
+	}
+	return nil
+}
+
+// unwrittenReads returns the keys read but not written by the transaction
+// with their observed versions. The written keys are already checked by
+// the conditional updates of the prepare phase.
+func (r *Datastore) unwrittenReads() map[string]string {
+	reads := make(map[string]string)
+	for key, version := range r.Txn.readVersions(r.Name) {
+		if _, ok := r.writeCache[key]; !ok {
+			reads[key] = version
+		}
+	}
+	return reads
+}
+
+// cachedReads returns the keys read but not written by the transaction
+// with the versions observed, taken from the read set if it is recorded
+// and from the read cache otherwise, which misses the keys not found.
+// The records of the read cache whose version was trimmed are left out.
+func (r *Datastore) cachedReads() map[string]string {
+	reads := make(map[string]string)
+	for key, item := range r.readCache {
+		if _, ok := r.writeCache[key]; !ok && item.Version() != "" {
+			reads[key] = item.Version()
+		}
+	}
+	maps.Copy(reads, r.unwrittenReads())
+	return reads
+}
+
+// readPredicates returns the reads to be validated by the executor.
+func (r *Datastore) readPredicates(reads map[string]string) map[string]PredicateInfo {
+	predicates := make(map[string]PredicateInfo, len(reads))
+	for key, version := range reads {
+		predicates[readPredicatePrefix+key] = PredicateInfo{
+			ItemKey:     key,
+			ReadVersion: true,
+			Version:     version,
+		}
+	}
+	return predicates
+}
+
+// ValidateReadSet checks that no record read but not written by the
+// transaction has changed since it was read.
+//
+// Any change aborts the transaction, including a concurrent writer
+// that has only prepared the record, so the check is conservative.
+func (r *Datastore) ValidateReadSet() error {
+	return r.validateReads(r.unwrittenReads())
+}
+
+// validateReads checks that the records of reads still have their versions.
+func (r *Datastore) validateReads(reads map[string]string) error {
+	if len(reads) == 0 {
+		return nil
+	}
+	if r.Txn.isRemote {
+		// a prepare request without items only validates the predicates
+		_, _, err := r.Txn.RemotePrepare(r.Name, nil, r.readPredicates(reads))
+		return err
+	}
+
+	var eg errgroup.Group
+	for k, v := range reads {
+		key, version := k, v
+		eg.Go(func() error {
+			return CheckReadVersion(r.conn, key, version)
+		})
+	}
+	return eg.Wait()
+}
+
+// validateCachedReads checks the reads of the transaction like
+// ValidateReadSet, whether ReadSetValidation is on or not.
+func (r *Datastore) validateCachedReads() error {
+	return r.validateReads(r.cachedReads())
+}
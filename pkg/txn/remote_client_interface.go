@@ -1,6 +1,9 @@
 package txn
 
 import (
+	"context"
+	"time"
+
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 )
 
@@ -38,8 +41,63 @@ type RecordConfig struct {
 	// GlobalName                  string
 	MaxRecordLen                int
 	ReadStrategy                config.ReadStrategy
+	ReadWaitTime                time.Duration
 	ConcurrentOptimizationLevel int
 	AblationLevel               int
+	// IsolationLevel decides which committed version a read returns.
+	IsolationLevel config.IsolationLevel
+	// Protocol is the commit protocol of the transaction,
+	// whose reads it may change.
+	Protocol config.Protocol
+	// TxnId is the transaction sending the request, whose start time
+	// the executor checks against its previous requests.
+	TxnId string
+}
+
+// ContextClient is implemented by remote clients that can propagate
+// the trace context of a transaction to the executors.
+type ContextClient interface {
+	// WithContext returns a client sending its requests within ctx.
+	WithContext(ctx context.Context) RemoteClient
+}
+
+// FieldReadClient is implemented by remote clients that can have the
+// executors project the value of a record on some of its fields before
+// sending it back.
+type FieldReadClient interface {
+	// ReadFields reads a record like Read, with its value projected on
+	// fields as by ProjectValue.
+	ReadFields(dsName string, key string, ts int64, config RecordConfig, fields []string) (DataItem, RemoteDataStrategy, string, error)
+}
+
+// ReadAtClient is implemented by remote clients that can have the
+// executors read a record as of a timestamp other than the start time
+// of the transaction.
+type ReadAtClient interface {
+	// ReadAt reads the version of a record visible at readTs for the
+	// transaction started at ts.
+	ReadAt(dsName string, key string, ts int64, readTs int64, config RecordConfig) (DataItem, RemoteDataStrategy, string, error)
+}
+
+// RemotePrepare is the prepare of a datastore sent by a PrepareBatchClient.
+type RemotePrepare struct {
+	ItemList      []DataItem
+	ValidationMap map[string]PredicateInfo
+}
+
+// RemotePrepareResult is the outcome of a RemotePrepare.
+type RemotePrepareResult struct {
+	VerMap  map[string]string
+	TCommit int64
+	Err     error
+}
+
+// PrepareBatchClient is implemented by remote clients that can prepare
+// the datastores served by the same executor in a single request.
+type PrepareBatchClient interface {
+	// PrepareDatastores prepares each datastore of reqs, keyed by their
+	// names, like Prepare and returns their outcomes keyed alike.
+	PrepareDatastores(startTime int64, config RecordConfig, reqs map[string]RemotePrepare) map[string]RemotePrepareResult
 }
 
 type RemoteClient interface {
@@ -47,6 +105,16 @@ type RemoteClient interface {
 	Prepare(dsName string, itemList []DataItem,
 		startTime int64,
 		config RecordConfig, validationMap map[string]PredicateInfo) (map[string]string, int64, error)
-	Commit(dsName string, infoList []CommitInfo, TCommit int64) error
-	Abort(dsName string, keyList []string, txnId string) error
+	// Commit returns the timestamp the records are committed with.
+	Commit(dsName string, infoList []CommitInfo, TCommit int64) (int64, error)
+	// Abort returns the number of records rolled back.
+	Abort(dsName string, keyList []string, groupKeyList string) (int, error)
+}
+
+// LeaseRenewClient is implemented by remote clients that can have the
+// executors extend the leases of the records prepared by a transaction.
+type LeaseRenewClient interface {
+	// RenewLease extends the leases of the records of infoList, prepared
+	// with their versions, and returns their new versions by key.
+	RenewLease(dsName string, infoList []CommitInfo) (map[string]string, error)
 }
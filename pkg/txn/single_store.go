@@ -0,0 +1,131 @@
+package txn
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	. "github.com/oreo-dtx-lab/oreo/pkg/logger"
+)
+
+// SingleStoreCommitter is implemented by the datastores able to commit
+// their writes without a TSR, when they are the only one written.
+type SingleStoreCommitter interface {
+	Datastorer
+
+	// SingleStoreSupported reports whether the writes can be applied atomically.
+	SingleStoreSupported() bool
+
+	// CommitWithoutTSR writes the records in the writeCache in the
+	// COMMITTED state with tCommit, all of them or none.
+	CommitWithoutTSR(tCommit int64) error
+}
+
+var _ SingleStoreCommitter = (*Datastore)(nil)
+
+// singleStoreDatastore returns the datastore whose writes can be committed
+// without a TSR, or nil if config.Config.SingleStoreSkipTSR is off or the
+// transaction writes more than one datastore. A TSR is what makes the
+// records written to several datastores commit or abort together, so it
+// is only ever skipped for a single one.
+func (t *Transaction) singleStoreDatastore() SingleStoreCommitter {
+	// the read set is only validated by the two-phase commit
+	if !config.Config.SingleStoreSkipTSR || t.isRemote || config.Config.ReadSetValidation {
+		return nil
+	}
+	var written Datastorer
+	for _, ds := range t.dataStoreMap {
+		if ds.GetWriteCacheSize() == 0 {
+			continue
+		}
+		if written != nil {
+			return nil
+		}
+		written = ds
+	}
+	committer, ok := written.(SingleStoreCommitter)
+	if !ok || !committer.SingleStoreSupported() {
+		return nil
+	}
+	return committer
+}
+
+// commitWithoutTSR commits a transaction writing a single datastore with
+// the conditional updates of the datastore alone, saving the prepare phase
+// along with the creation and the deletion of the TSR. Nothing is written
+// if it fails, so there is nothing to roll back either.
+func (t *Transaction) commitWithoutTSR(ds SingleStoreCommitter) error {
+	var err error
+	t.TxnCommitTime, err = t.getTime("commit")
+	if err != nil {
+		return fmt.Errorf("failed to get time: %v", err)
+	}
+
+	commitStart := time.Now()
+	err = ds.CommitWithoutTSR(t.TxnCommitTime)
+	t.stats.CommitDuration = time.Since(commitStart)
+	if err != nil {
+		Log.Errorw("commit without TSR failed", "txnId", t.TxnId, "ds", ds.GetName(), "cause", err)
+		_, _ = t.TransitTo(config.ABORTED)
+		return errors.New("commit without TSR failed: " + err.Error())
+	}
+	return nil
+}
+
+// SingleStoreSupported reports whether the writes can be applied atomically:
+// a single record is written by one conditional update, several records
+// need a BatchConnector, and no more than its MaxAtomicBatch if it is an
+// FallibleBatchConnector. Remote transactions are committed by the executors
+// and always create their TSRs.
+func (r *Datastore) SingleStoreSupported() bool {
+	if r.Txn.isRemote {
+		return false
+	}
+	if len(r.writeCache) == 1 {
+		return true
+	}
+	if conn, ok := r.conn.(FallibleBatchConnector); ok {
+		return len(r.writeCache) <= conn.MaxAtomicBatch()
+	}
+	_, ok := r.conn.(BatchConnector)
+	return ok
+}
+
+// CommitWithoutTSR builds the committed records from the writeCache
+// the same way Prepare does and writes them with a single conditional
+// update, or with a single batch if there are several, which must not fall
+// back to updating them one by one.
+// The caches are cleared whether it succeeds or not, and the records
+// locked by the transaction are released if it fails.
+func (r *Datastore) CommitWithoutTSR(tCommit int64) (err error) {
+	defer func() {
+		if err != nil {
+			r.unlockRecords()
+		}
+		r.clear()
+	}()
+
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	reqs, err := r.committedWrites(tCommit)
+	if err != nil {
+		return err
+	}
+	if len(reqs) == 1 {
+		_, err = r.conn.ConditionalUpdate(reqs[0].Key, reqs[0].Item, reqs[0].DoCreate)
+		return err
+	}
+	if conn, ok := r.conn.(FallibleBatchConnector); ok {
+		_, err = conn.ConditionalUpdateAtomicBatch(reqs)
+		return err
+	}
+	conn, ok := r.conn.(BatchConnector)
+	if !ok {
+		return errors.Errorf("%s cannot write %d records atomically", r.Name, len(reqs))
+	}
+	_, err = conn.ConditionalUpdateBatch(reqs)
+	return err
+}
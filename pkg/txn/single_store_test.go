@@ -0,0 +1,167 @@
+package txn_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/internal/util"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// tsrCountingConnection counts the TSRs created and deleted through it.
+type tsrCountingConnection struct {
+	*memkv.Connection
+	creates, deletes atomic.Int32
+}
+
+func (c *tsrCountingConnection) AtomicCreate(name string, value any) (string, error) {
+	c.creates.Add(1)
+	return c.Connection.AtomicCreate(name, value)
+}
+
+func (c *tsrCountingConnection) Delete(name string) error {
+	c.deletes.Add(1)
+	return c.Connection.Delete(name)
+}
+
+func TestTxnSkipTSR(t *testing.T) {
+	oldSkipTSR := config.Config.SingleStoreSkipTSR
+	config.Config.SingleStoreSkipTSR = true
+	defer func() { config.Config.SingleStoreSkipTSR = oldSkipTSR }()
+	// the TSRs are only created by the commits below level 4
+	commitInForeground(t)
+
+	conn := &tsrCountingConnection{Connection: memkv.NewConnection(&redis.RedisItemFactory{})}
+	newTxn := func(dsNames ...string) *txn.Transaction {
+		tx := txn.NewTransaction()
+		for _, name := range dsNames {
+			_ = tx.AddDatastore(txn.NewDatastore(name, conn, &redis.RedisItemFactory{}))
+		}
+		assert.NoError(t, tx.Start())
+		return tx
+	}
+
+	tx := newTxn("memkv")
+	assert.NoError(t, tx.Write("memkv", "acc1", 10))
+	assert.NoError(t, tx.Write("memkv", "acc2", 20))
+	assert.NoError(t, tx.Commit())
+
+	// two transfers racing on the same accounts, only the first commits
+	transfer := func() *txn.Transaction {
+		tx := newTxn("memkv")
+		var acc1, acc2 int
+		assert.NoError(t, tx.Read("memkv", "acc1", &acc1))
+		assert.NoError(t, tx.Read("memkv", "acc2", &acc2))
+		assert.NoError(t, tx.Write("memkv", "acc1", acc1-5))
+		assert.NoError(t, tx.Write("memkv", "acc2", acc2+5))
+		return tx
+	}
+	tx1, tx2 := transfer(), transfer()
+	assert.NoError(t, tx1.Commit())
+	assert.Error(t, tx2.Commit())
+	time.Sleep(100 * time.Millisecond)
+	assert.Zero(t, conn.creates.Load())
+	assert.Zero(t, conn.deletes.Load())
+
+	for key, expected := range map[string]string{"acc1": "5", "acc2": "25"} {
+		item, err := conn.GetItem(key)
+		assert.NoError(t, err)
+		assert.Equal(t, config.COMMITTED, item.TxnState())
+		assert.Equal(t, tx1.TxnCommitTime, item.TValid())
+		assert.Equal(t, expected, item.Value())
+	}
+
+	// the records of several datastores only commit together through a TSR
+	tx = newTxn("ds1", "ds2")
+	assert.NoError(t, tx.Write("ds1", "item1", "v1"))
+	assert.NoError(t, tx.Write("ds2", "item2", "v1"))
+	assert.NoError(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+	assert.NotZero(t, conn.creates.Load())
+}
+
+// fallbackConnection applies its batches atomically up to maxBatch records
+// and, like DynamoDB, falls back to updating the records one by one for a
+// larger batch or a throttled one.
+type fallbackConnection struct {
+	tsrCountingConnection
+	maxBatch  int
+	throttled atomic.Bool
+	fallbacks atomic.Int32
+}
+
+func (c *fallbackConnection) ConditionalUpdateBatch(reqs []txn.ConditionalUpdateRequest) ([]string, error) {
+	if len(reqs) <= c.maxBatch && !c.throttled.Load() {
+		return c.Connection.ConditionalUpdateBatch(reqs)
+	}
+	c.fallbacks.Add(1)
+	versions := make([]string, len(reqs))
+	for i, req := range reqs {
+		ver, err := c.ConditionalUpdate(req.Key, req.Item, req.DoCreate)
+		if err != nil {
+			return nil, err
+		}
+		versions[i] = ver
+	}
+	return versions, nil
+}
+
+func (c *fallbackConnection) MaxAtomicBatch() int {
+	return c.maxBatch
+}
+
+func (c *fallbackConnection) ConditionalUpdateAtomicBatch(reqs []txn.ConditionalUpdateRequest) ([]string, error) {
+	if len(reqs) > c.maxBatch || c.throttled.Load() {
+		return nil, errors.New("throttled")
+	}
+	return c.Connection.ConditionalUpdateBatch(reqs)
+}
+
+// TestTxnSkipTSRFallback tests that a transaction is only
+// committed without a TSR by a batch that cannot fall back to non-atomic updates.
+func TestTxnSkipTSRFallback(t *testing.T) {
+	oldSkipTSR := config.Config.SingleStoreSkipTSR
+	config.Config.SingleStoreSkipTSR = true
+	defer func() { config.Config.SingleStoreSkipTSR = oldSkipTSR }()
+	commitInForeground(t)
+
+	conn := &fallbackConnection{
+		tsrCountingConnection: tsrCountingConnection{Connection: memkv.NewConnection(&redis.RedisItemFactory{})},
+		maxBatch:              2,
+	}
+	newTxn := func() *txn.Transaction {
+		tx := txn.NewTransaction()
+		_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+		assert.NoError(t, tx.Start())
+		return tx
+	}
+
+	// a batch larger than the atomic limit goes through a TSR
+	tx := newTxn()
+	for _, key := range []string{"item1", "item2", "item3"} {
+		assert.NoError(t, tx.Write("memkv", key, "v1"))
+	}
+	assert.NoError(t, tx.Commit())
+	time.Sleep(100 * time.Millisecond)
+	assert.NotZero(t, conn.creates.Load())
+
+	// a throttled batch fails and writes nothing instead of falling back
+	conn.throttled.Store(true)
+	conn.fallbacks.Store(0)
+	tx = newTxn()
+	assert.NoError(t, tx.Write("memkv", "item1", "v2"))
+	assert.NoError(t, tx.Write("memkv", "item4", "v2"))
+	assert.Error(t, tx.Commit())
+	assert.Zero(t, conn.fallbacks.Load())
+	item, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, util.ToJSONString("v1"), item.Value())
+	_, err = conn.GetItem("item4")
+	assert.Error(t, err)
+}
@@ -3,12 +3,53 @@ package txn
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
 )
 
 type StateMachine struct {
+	mu    sync.Mutex
 	state config.State
+	// hook is called with every transition attempted by SetState or TransitTo.
+	hook func(Transition)
+}
+
+// Transition is a state transition attempted on a StateMachine.
+type Transition struct {
+	Time time.Time
+	From config.State
+	To   config.State
+	// Err is the error the transition has been rejected with,
+	// or nil if it has taken place.
+	Err error
+}
+
+func (tr Transition) String() string {
+	s := fmt.Sprintf("%s %s -> %s", tr.Time.Format(time.RFC3339Nano), StateName(tr.From), StateName(tr.To))
+	if tr.Err != nil {
+		s += " rejected: " + tr.Err.Error()
+	}
+	return s
+}
+
+// StateName returns the name of a transaction state.
+func StateName(state config.State) string {
+	switch state {
+	case config.EMPTY:
+		return "EMPTY"
+	case config.STARTED:
+		return "STARTED"
+	case config.PREPARED:
+		return "PREPARED"
+	case config.COMMITTED:
+		return "COMMITTED"
+	case config.ABORTED:
+		return "ABORTED"
+	default:
+		return fmt.Sprintf("State(%d)", int(state))
+	}
 }
 
 func NewStateMachine() *StateMachine {
@@ -17,11 +58,41 @@ func NewStateMachine() *StateMachine {
 	}
 }
 
+// SetTransitionHook sets hook to be called with every transition attempted
+// afterwards, including the rejected ones. It is called once the state
+// machine is unlocked, in the goroutine attempting the transition.
+// A nil hook removes the current one.
+func (st *StateMachine) SetTransitionHook(hook func(Transition)) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.hook = hook
+}
+
 func (st *StateMachine) SetState(state config.State) error {
+	_, err := st.TransitTo(state)
+	return err
+}
+
+// TransitTo atomically sets the state and returns the state before the transition.
+func (st *StateMachine) TransitTo(state config.State) (config.State, error) {
+	st.mu.Lock()
+	lastState := st.state
+	err := st.setState(state)
+	hook := st.hook
+	st.mu.Unlock()
+
+	if hook != nil {
+		hook(Transition{Time: time.Now(), From: lastState, To: state, Err: err})
+	}
+	return lastState, err
+}
+
+func (st *StateMachine) setState(state config.State) error {
 	switch state {
 	case config.STARTED:
-		if st.state != config.EMPTY {
-			return errors.New("transaction can't be started as it is not in an empty state")
+		// a committed or aborted transaction may be started again
+		if st.state != config.EMPTY && st.state != config.COMMITTED && st.state != config.ABORTED {
+			return errors.New("transaction can't be started as it is in progress")
 		}
 	case config.COMMITTED:
 		if st.state != config.STARTED {
@@ -40,6 +111,8 @@ func (st *StateMachine) SetState(state config.State) error {
 }
 
 func (st *StateMachine) CheckState(state config.State) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	if st.state != state {
 		switch state {
 		case config.EMPTY:
@@ -58,5 +131,7 @@ func (st *StateMachine) CheckState(state config.State) error {
 }
 
 func (st *StateMachine) GetState() config.State {
+	st.mu.Lock()
+	defer st.mu.Unlock()
 	return st.state
 }
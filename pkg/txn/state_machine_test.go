@@ -1,6 +1,7 @@
 package txn
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/oreo-dtx-lab/oreo/pkg/config"
@@ -76,3 +77,98 @@ func TestGetState(t *testing.T) {
 		t.Errorf("Expected to get state STARTED, received: %#v", received)
 	}
 }
+
+func TestTransitionHook(t *testing.T) {
+	sm := NewStateMachine()
+	var transitions []Transition
+	sm.SetTransitionHook(func(tr Transition) { transitions = append(transitions, tr) })
+
+	_ = sm.SetState(config.STARTED)
+	_ = sm.SetState(config.STARTED)
+	sm.SetTransitionHook(nil)
+	_ = sm.SetState(config.ABORTED)
+
+	if len(transitions) != 2 {
+		t.Fatalf("Expected 2 transitions, received: %v", transitions)
+	}
+	if tr := transitions[0]; tr.From != config.EMPTY || tr.To != config.STARTED || tr.Err != nil {
+		t.Errorf("Expected EMPTY -> STARTED, received: %v", tr)
+	}
+	if tr := transitions[1]; tr.From != config.STARTED || tr.To != config.STARTED || tr.Err == nil {
+		t.Errorf("Expected STARTED -> STARTED to be rejected, received: %v", tr)
+	}
+}
+
+func TestTxnRecordsRejectedCommit(t *testing.T) {
+	txn := NewTransaction()
+	_ = txn.AddDatastore(&trackedDatastore{name: "ds", tracker: &peakTracker{}})
+	txn.RecordTransitions()
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+	_ = txn.Abort()
+
+	err := txn.Commit()
+	if err == nil {
+		t.Fatalf("Expected an error committing an aborted transaction")
+	}
+
+	transitions := txn.Transitions()
+	if len(transitions) != 3 {
+		t.Fatalf("Expected 3 transitions, received: %v", transitions)
+	}
+	for i, expected := range []config.State{config.STARTED, config.ABORTED} {
+		if tr := transitions[i]; tr.To != expected || tr.Err != nil {
+			t.Errorf("Expected the transition %d to %s, received: %v", i, StateName(expected), tr)
+		}
+	}
+	rejected := transitions[2]
+	if rejected.From != config.ABORTED || rejected.To != config.COMMITTED || rejected.Err != err {
+		t.Errorf("Expected ABORTED -> COMMITTED to be rejected with %v, received: %v", err, rejected)
+	}
+	if s := rejected.String(); !strings.Contains(s, "ABORTED -> COMMITTED rejected: "+err.Error()) {
+		t.Errorf("Unexpected transition log: %s", s)
+	}
+}
+
+func TestTxnTransitionsResetOnStart(t *testing.T) {
+	txn := NewTransaction()
+	_ = txn.AddDatastore(&trackedDatastore{name: "ds", tracker: &peakTracker{}})
+	txn.RecordTransitions()
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+	_ = txn.Abort()
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction again: %s", err)
+	}
+
+	transitions := txn.Transitions()
+	if len(transitions) != 1 {
+		t.Fatalf("Expected only the transition of the second start, received: %v", transitions)
+	}
+	if tr := transitions[0]; tr.From != config.ABORTED || tr.To != config.STARTED || tr.Err != nil {
+		t.Errorf("Expected ABORTED -> STARTED, received: %v", tr)
+	}
+	_ = txn.Abort()
+}
+
+func TestTxnRecordTransitionsConcurrently(t *testing.T) {
+	txn := NewTransaction()
+	_ = txn.AddDatastore(&trackedDatastore{name: "ds", tracker: &peakTracker{}})
+	txn.RecordTransitions()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			txn.RecordTransitions()
+			_ = txn.Transitions()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = txn.Start()
+		_ = txn.Abort()
+	}
+	<-done
+}
@@ -1,7 +1,9 @@
 package txn
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +14,10 @@ import (
 	"github.com/oreo-dtx-lab/oreo/pkg/locker"
 	. "github.com/oreo-dtx-lab/oreo/pkg/logger"
 	"github.com/oreo-dtx-lab/oreo/pkg/timesource"
+	"github.com/oreo-dtx-lab/oreo/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 )
 
 type SourceType string
@@ -29,6 +35,15 @@ var (
 	VersionMismatch  = errors.Errorf("version mismatch")
 	KeyExists        = errors.Errorf("key exists")
 	ReadFailed       = errors.Errorf("read failed due to unknown txn status")
+	// ReadSetChanged is returned when a record read by the transaction
+	// has changed before the transaction commits.
+	ReadSetChanged = errors.Errorf("read set validation failed")
+	// FalseAssumption is returned when a record prepared by another
+	// transaction has been read assuming a state that turns out wrong.
+	FalseAssumption = errors.Errorf("validation failed due to false assumption")
+	// RecordLocked is returned by ReadForUpdate when the record
+	// is still locked by another transaction.
+	RecordLocked = errors.Errorf("record is locked by another transaction")
 )
 
 const (
@@ -46,6 +61,8 @@ type Transaction struct {
 	// TxnStartTime is the timestamp when the transaction started.
 	TxnStartTime int64
 	// TxnCommitTime is the timestamp when the transaction was committed.
+	// It is set once Commit returns nil, and can be used to correlate
+	// the transaction with later snapshot reads.
 	TxnCommitTime int64
 
 	GroupKeyUrls []string
@@ -65,9 +82,54 @@ type Transaction struct {
 	// isReadOnly indicates whether the transaction is read-only.
 	isReadOnly bool
 
+	// isSnapshot indicates whether the transaction is started by StartReadOnly.
+	isSnapshot bool
+
 	// writeCount is the number of write operations performed by the transaction.
 	writeCount int
 
+	// writeSet holds the keys written or deleted by the transaction,
+	// keyed by the datastore name, and writeSetSize counts them.
+	writeSet     map[string]map[string]struct{}
+	writeSetSize int
+
+	// readSet records the version of every record read by the transaction,
+	// keyed by the datastore name and then the key, if ReadSetValidation is on.
+	readSet   map[string]map[string]string
+	readSetMu sync.Mutex
+
+	// commitWait overrides config.Config.CommitWait if set by SetCommitWait.
+	commitWait *bool
+
+	// isolation is the isolation level of the reads, set by SetIsolationLevel.
+	isolation config.IsolationLevel
+
+	// protocol overrides config.Config.Protocol if set by SetProtocol.
+	protocol *config.Protocol
+
+	// clones is shared with the transactions cloned from this one
+	// or the one it was cloned from, set by Clone.
+	clones *cloneGroup
+
+	// lockedKeys are the records locked by ReadForUpdate, which
+	// their datastores release when the transaction commits or aborts.
+	lockedKeys map[string]struct{}
+
+	// prepare tracks the datastores still preparing after the prepare
+	// phase has failed, until the transaction is started again.
+	prepare   *prepareState
+	prepareMu sync.Mutex
+	// background tracks the work of the last commit still running after
+	// it returned: the prepares given up on by a timeout, the aborts
+	// waiting for them and the asynchronous commit phase. Start waits
+	// for it before reusing the datastores of the transaction.
+	background sync.WaitGroup
+
+	// transitions are the state transitions attempted since the
+	// transaction has last been started, once RecordTransitions has been called.
+	transitions   []Transition
+	transitionsMu sync.Mutex
+
 	// client is the network client used by the transaction.
 	client RemoteClient
 
@@ -77,6 +139,41 @@ type Transaction struct {
 	*StateMachine
 
 	debugStart time.Time
+
+	// stats summarizes the last commit of the transaction.
+	stats TxnStats
+
+	// ctx carries span, which covers the transaction
+	// from Start to the end of Commit or Abort.
+	ctx         context.Context
+	span        trace.Span
+	endSpanOnce sync.Once
+}
+
+// TxnStats summarizes the commit of a transaction.
+type TxnStats struct {
+	// DatastoreCount is the number of datastores written by the transaction.
+	DatastoreCount int
+	// WriteCount is the number of write operations performed by the transaction.
+	WriteCount int
+	// ReadOnly indicates whether the transaction is read-only.
+	ReadOnly bool
+	// AsyncCommit indicates whether the commit phase runs in the background
+	// after Commit returns, in which case CommitDuration is zero.
+	AsyncCommit bool
+	// OnePhase indicates whether the transaction is committed by OnePhaseCommit.
+	OnePhase bool
+	// PrepareDuration is the time spent in the prepare phase.
+	PrepareDuration time.Duration
+	// CommitDuration is the time spent in the commit phase.
+	CommitDuration time.Duration
+	// CommitWaitDuration is the time spent waiting for the commit time
+	// to be in the past, if commit-wait is on.
+	CommitWaitDuration time.Duration
+	// AbortCause is the error the commit failed with, if any.
+	AbortCause error
+	// RolledBack is the number of records rolled back by Abort in each datastore.
+	RolledBack map[string]int
 }
 
 // NewTransaction creates a new Transaction object.
@@ -124,22 +221,54 @@ func NewTransactionWithRemote(client RemoteClient, oracle timesource.TimeSourcer
 // It sets the transaction state to STARTED and generates a unique transaction ID.
 // It starts each datastore associated with the transaction.
 // Returns an error if any of the above steps fail, otherwise returns nil.
+//
+// A committed or aborted transaction can be started again as a new
+// transaction, once the work its last commit left in the background is done.
 func (t *Transaction) Start() error {
+	return t.start(false)
+}
+
+// start starts the transaction, read-only with a single snapshot if snapshot is set.
+func (t *Transaction) start(snapshot bool) (err error) {
+	t.background.Wait()
 	t.debugStart = time.Now()
+	t.ctx, t.span = tracing.Start(context.Background(), "Transaction")
+	span := t.startSpan("Start")
 	defer func() {
+		tracing.End(span, err)
+		if err != nil {
+			t.endSpan(err)
+		}
 		Log.Debugw("txn.Start() ends", "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
 	}()
 
-	err := t.SetState(config.STARTED)
+	err = t.SetState(config.STARTED)
 	if err != nil {
 		return err
 	}
+	t.resetTransitions()
+	t.isSnapshot = snapshot
+	t.isReadOnly = true
+	t.writeCount = 0
+	t.GroupKeyUrls = nil
+	t.TxnCommitTime = 0
+	t.endSpanOnce = sync.Once{}
+	t.readSet = nil
+	t.writeSet = nil
+	t.writeSetSize = 0
+	t.lockedKeys = nil
+	// the clones of the last transaction no longer share its commit
+	t.clones = nil
+	t.prepareMu.Lock()
+	t.prepare = nil
+	t.prepareMu.Unlock()
 
 	if len(t.dataStoreMap) == 0 {
 		return errors.New("no datastores added")
 	}
 	t.TxnId = config.Config.IdGenerator.GenerateId()
-	Log.Infow("starting transaction", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
+	t.span.SetAttributes(attribute.String("txn.id", t.TxnId))
+	Log.Debugw("starting transaction", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
 
 	// only get the Tstart in Oreo and Cherry Garcia mode
 	if !config.Debug.NativeMode {
@@ -161,6 +290,53 @@ func (t *Transaction) Start() error {
 	return nil
 }
 
+// StartReadOnly starts a read-only transaction that reads a single snapshot.
+//
+// The snapshot timestamp is obtained once from the time source of the transaction
+// and used as TxnStartTime by every read, local or remote. The reads across
+// datastores therefore reflect one consistent snapshot, provided that every
+// writer takes its commit timestamp from the same time source.
+// Writes and deletes are rejected.
+func (t *Transaction) StartReadOnly() error {
+	if config.Debug.NativeMode {
+		return errors.New("snapshot reads are not supported in native mode")
+	}
+	return t.start(true)
+}
+
+// SnapshotTime returns the timestamp that the reads of the transaction are based on.
+func (t *Transaction) SnapshotTime() int64 {
+	return t.TxnStartTime
+}
+
+// Refresh advances the snapshot of a transaction started by StartReadOnly
+// to a fresh timestamp, so that a long-running read-only transaction can go on
+// reading after the versions of its original snapshot have been truncated.
+//
+// The reads after Refresh see the records committed since the transaction
+// started, so the reads across the refresh do not reflect one snapshot.
+// It is rejected for read-write transactions.
+func (t *Transaction) Refresh() error {
+	if err := t.CheckState(config.STARTED); err != nil {
+		return err
+	}
+	if !t.isSnapshot {
+		return errors.New("refresh is only supported by a transaction started by StartReadOnly")
+	}
+	ts, err := t.getTime("start")
+	if err != nil {
+		return err
+	}
+	t.TxnStartTime = ts
+	for _, ds := range t.dataStoreMap {
+		if clearer, ok := ds.(ReadCacheClearer); ok {
+			clearer.ClearReadCache()
+		}
+	}
+	Log.Debugw("transaction snapshot refreshed", "txnId", t.TxnId, "snapshot", ts)
+	return nil
+}
+
 // AddDatastore adds a datastore to the transaction.
 // It checks if the datastore name is duplicated and returns an error if it is.
 // Otherwise, it sets the transaction for the datastore and adds it to the transaction's datastore map.
@@ -197,8 +373,11 @@ func (t *Transaction) SetGlobalDatastore(ds Datastorer) {
 
 // Read reads the value associated with the given key from the specified datastore.
 // It returns an error if the transaction is not in the STARTED state or if the datastore is not found.
-func (t *Transaction) Read(dsName string, key string, value any) error {
-	err := t.CheckState(config.STARTED)
+func (t *Transaction) Read(dsName string, key string, value any) (err error) {
+	span := t.startSpan("Read", attribute.String("ds", dsName), attribute.String("key", key))
+	defer func() { tracing.End(span, err) }()
+
+	err = t.CheckState(config.STARTED)
 	if err != nil {
 		return err
 	}
@@ -210,35 +389,150 @@ func (t *Transaction) Read(dsName string, key string, value any) error {
 	return errors.New("datastore not found: " + dsName)
 }
 
-// Write writes the given key-value pair to the specified datastore in the transaction.
-// It returns an error if the transaction is not in the STARTED state or if the datastore is not found.
-func (t *Transaction) Write(dsName string, key string, value any) error {
+// ReadAt reads the value associated with the given key as it was at timestamp ts
+// from the specified datastore.
+// It returns KeyNotFound if no version of the record is visible at ts.
+func (t *Transaction) ReadAt(dsName string, key string, ts int64, value any) error {
 	err := t.CheckState(config.STARTED)
 	if err != nil {
 		return err
 	}
-	t.isReadOnly = false
-	t.writeCount++
+
+	t.debug(testutil.DRead, "read in %v at %v: [Key: %v]", dsName, ts, key)
 	if ds, ok := t.dataStoreMap[dsName]; ok {
-		return ds.Write(key, value)
+		return ds.ReadAt(key, ts, value)
 	}
 	return errors.New("datastore not found: " + dsName)
 }
 
+// ReadSpec describes a single read issued by ReadMany.
+type ReadSpec struct {
+	DsName string
+	Key    string
+	// Value is where the read value is deserialized into.
+	Value any
+}
+
+// ReadResult is the outcome of a single read issued by ReadMany.
+type ReadResult struct {
+	DsName string
+	Key    string
+	Err    error
+}
+
+// ReadMany performs the given reads concurrently and returns their results
+// in the same order as reqs.
+// Reads on different datastores run in parallel, bounded by config.Config.ReadParallelism,
+// while reads on the same datastore run one after another because a datastore's
+// caches are not safe for concurrent use.
+// If any read fails, the error of the first failed read in reqs is returned as well.
+func (t *Transaction) ReadMany(reqs []ReadSpec) ([]ReadResult, error) {
+	err := t.CheckState(config.STARTED)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ReadResult, len(reqs))
+	groups := make(map[string][]int)
+	for i, req := range reqs {
+		results[i] = ReadResult{DsName: req.DsName, Key: req.Key}
+		groups[req.DsName] = append(groups[req.DsName], i)
+	}
+
+	var eg errgroup.Group
+	if config.Config.ReadParallelism > 0 {
+		eg.SetLimit(config.Config.ReadParallelism)
+	}
+	for dsName, indexes := range groups {
+		ds, ok := t.dataStoreMap[dsName]
+		if !ok {
+			for _, i := range indexes {
+				results[i].Err = errors.New("datastore not found: " + dsName)
+			}
+			continue
+		}
+		indexes := indexes
+		eg.Go(func() error {
+			for _, i := range indexes {
+				t.debug(testutil.DRead, "read in %v: [Key: %v]", reqs[i].DsName, reqs[i].Key)
+				results[i].Err = ds.Read(reqs[i].Key, reqs[i].Value)
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+
+	for _, res := range results {
+		if res.Err != nil {
+			return results, res.Err
+		}
+	}
+	return results, nil
+}
+
+// Write writes the given key-value pair to the specified datastore in the transaction.
+// It returns an error if the transaction is not in the STARTED state, if the datastore is not found
+// or if the write set would grow past config.Config.MaxWriteSetSize.
+func (t *Transaction) Write(dsName string, key string, value any) (err error) {
+	span := t.startSpan("Write", attribute.String("ds", dsName), attribute.String("key", key))
+	defer func() { tracing.End(span, err) }()
+
+	err = t.CheckState(config.STARTED)
+	if err != nil {
+		return err
+	}
+	if t.isSnapshot {
+		return errors.New("write in a read-only transaction")
+	}
+	ds, ok := t.dataStoreMap[dsName]
+	if !ok {
+		return errors.New("datastore not found: " + dsName)
+	}
+	if err = t.checkWriteSetSize(dsName, key); err != nil {
+		return err
+	}
+	t.isReadOnly = false
+	t.writeCount++
+	if err = ds.Write(key, value); err != nil {
+		return err
+	}
+	t.recordWrite(dsName, key)
+	return nil
+}
+
 // Delete deletes a key from the specified datastore in the transaction.
-// It returns an error if the transaction is not in the STARTED state or if the datastore is not found.
+// It returns an error if the transaction is not in the STARTED state, if the datastore is not found
+// or if the write set would grow past config.Config.MaxWriteSetSize.
 func (t *Transaction) Delete(dsName string, key string) error {
+	return t.delete(dsName, key, func(ds Datastorer) error {
+		return ds.Delete(key)
+	})
+}
+
+// delete deletes key from the datastore dsName with del.
+func (t *Transaction) delete(dsName string, key string, del func(ds Datastorer) error) error {
 	err := t.CheckState(config.STARTED)
 	if err != nil {
 		return err
 	}
+	if t.isSnapshot {
+		return errors.New("delete in a read-only transaction")
+	}
+	ds, ok := t.dataStoreMap[dsName]
+	if !ok {
+		return errors.New("datastore not found: " + dsName)
+	}
+	if err = t.checkWriteSetSize(dsName, key); err != nil {
+		return err
+	}
 	t.isReadOnly = false
 	msgStr := fmt.Sprintf("delete in %v: [Key: %v]", dsName, key)
 	Log.Debugw(msgStr, "txnId", t.TxnId, "topic", testutil.DDelete)
-	if ds, ok := t.dataStoreMap[dsName]; ok {
-		return ds.Delete(key)
+	if err = del(ds); err != nil {
+		return err
 	}
-	return errors.New("datastore not found: " + dsName)
+	t.recordWrite(dsName, key)
+	return nil
 }
 
 // Commit commits the transaction.
@@ -247,32 +541,217 @@ func (t *Transaction) Delete(dsName string, key string) error {
 // Otherwise, it proceeds to the commit phase and commits the transaction in all data stores.
 // Finally, it deletes the transaction state record.
 // Returns an error if any operation fails.
-func (t *Transaction) Commit() error {
-
+func (t *Transaction) Commit() (err error) {
+	span := t.startSpan("Commit")
 	defer func() {
+		tracing.End(span, err)
+		t.endSpan(err)
 		Log.Debugw("txn.Commit() ends", "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
 	}()
 
-	Log.Infow("Starts to txn.Commit()", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
-	err := t.SetState(config.COMMITTED)
+	Log.Debugw("Starts to txn.Commit()", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
+	// a read-only transaction has no prepare phase to validate its read set
+	if t.isReadOnly && config.Config.ReadSetValidation {
+		if err = t.validateReadSet(); err != nil {
+			_ = t.Abort()
+			if isConflictCause(err) {
+				err = &ConflictError{Err: err}
+			}
+			return err
+		}
+	}
+	// nor to check its commit conditions
+	if t.isReadOnly {
+		if err = t.checkConditions(); err != nil {
+			_ = t.Abort()
+			return err
+		}
+	}
+	if err = t.claimCommit(); err != nil {
+		_ = t.Abort()
+		return err
+	}
+	err = t.SetState(config.COMMITTED)
 	if err != nil {
 		return err
 	}
+	t.resetStats()
 
 	if t.isReadOnly {
 		Log.Infow("transaction is read-only, Commit() complete", "txnId", t.TxnId)
 		return nil
 	}
 
-	// generate groupKeyUrls
+	t.generateGroupKeyUrls()
+
+	if config.Debug.NativeMode {
+		err = t.commitInNative()
+	} else if t.Protocol() == config.CherryGarcia {
+		err = t.commitInCherryGarcia()
+	} else if t.Protocol() == config.OnePhase {
+		err = t.commitInOnePhase()
+	} else if ds := t.nativeTxnDatastore(); ds != nil {
+		err = t.commitInNativeTxn(ds)
+	} else if ds := t.singleStoreDatastore(); ds != nil {
+		err = t.commitWithoutTSR(ds)
+	} else {
+		err = t.commitInOreo()
+	}
+	t.stats.AbortCause = err
+	if err == nil && t.commitWaitEnabled() {
+		err = t.waitCommitTime()
+	}
+	return err
+}
+
+// Stats returns the statistics of the last Commit or OnePhaseCommit.
+// It returns a zero TxnStats if the transaction has not been committed.
+func (t *Transaction) Stats() TxnStats {
+	return t.stats
+}
+
+// RecordTransitions makes the transaction record the state transitions
+// attempted from then on, returned by Transitions, and log them at DEBUG
+// level, or at WARN level for the transitions that are rejected.
+func (t *Transaction) RecordTransitions() {
+	t.transitionsMu.Lock()
+	defer t.transitionsMu.Unlock()
+	t.transitions = nil
+	t.SetTransitionHook(func(tr Transition) {
+		t.transitionsMu.Lock()
+		t.transitions = append(t.transitions, tr)
+		t.transitionsMu.Unlock()
+		if tr.Err != nil {
+			Log.Warnw("state transition rejected", "txnId", t.TxnId, "transition", tr.String())
+			return
+		}
+		Log.Debugw("state transition", "txnId", t.TxnId, "transition", tr.String())
+	})
+}
+
+// Transitions returns the state transitions recorded since the transaction
+// has last been started, in the order they were attempted.
+func (t *Transaction) Transitions() []Transition {
+	t.transitionsMu.Lock()
+	defer t.transitionsMu.Unlock()
+	return slices.Clone(t.transitions)
+}
+
+// resetTransitions drops the transitions recorded before the transaction
+// has been started again, keeping its transition to STARTED.
+func (t *Transaction) resetTransitions() {
+	t.transitionsMu.Lock()
+	defer t.transitionsMu.Unlock()
+	if n := len(t.transitions); n > 1 {
+		t.transitions = slices.Clone(t.transitions[n-1:])
+	}
+}
+
+// startSpan starts a span as a child of the transaction span.
+func (t *Transaction) startSpan(name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := tracing.Start(t.ctx, name, trace.WithAttributes(attrs...))
+	return span
+}
+
+// endSpan ends the transaction span. Only the first call takes effect.
+func (t *Transaction) endSpan(err error) {
+	if t.span == nil {
+		return
+	}
+	t.endSpanOnce.Do(func() {
+		tracing.End(t.span, err)
+	})
+}
+
+// remoteClient returns the client that sends the requests to the executors,
+// carrying the trace context of the transaction if the client supports it.
+func (t *Transaction) remoteClient() RemoteClient {
+	if cc, ok := t.client.(ContextClient); ok && t.ctx != nil {
+		return cc.WithContext(t.ctx)
+	}
+	return t.client
+}
+
+// resetStats starts the statistics of a new commit
+// from the current shape of the transaction.
+func (t *Transaction) resetStats() {
+	t.stats = TxnStats{
+		WriteCount: t.writeCount,
+		ReadOnly:   t.isReadOnly,
+	}
+	for _, ds := range t.dataStoreMap {
+		if ds.GetWriteCacheSize() > 0 {
+			t.stats.DatastoreCount++
+		}
+	}
+}
+
+// Validate performs a dry run of Commit.
+// It checks that the records read by the transaction have not changed since,
+// read-only or not and whether config.Config.ReadSetValidation is on or not,
+// and runs the prepare phase to check its writes. It then aborts the
+// transaction instead of committing it, which releases every record locked
+// during the prepare phase, and deletes the ABORTED group keys once nothing
+// refers to them. Snapshot reads are not checked since they never see a later write.
+// It returns nil if the commit would have succeeded.
+// The transaction is ABORTED afterwards and cannot be committed.
+func (t *Transaction) Validate() error {
+	if config.Debug.NativeMode {
+		return errors.New("validate is not supported in native mode")
+	}
+
+	err := t.SetState(config.COMMITTED)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the group keys are kept if a record could not be rolled back
+		if err := t.Abort(); err == nil && len(t.GroupKeyUrls) > 0 {
+			_ = t.DeleteGroupKeyFromUrls(t.GroupKeyUrls)
+		}
+	}()
+
+	if !t.isSnapshot {
+		if err := t.validateCachedReads(); err != nil {
+			Log.Infow("validation failed", "txnId", t.TxnId, "cause", err)
+			return errors.New("read set validation failed: " + err.Error())
+		}
+	}
+	if t.isReadOnly {
+		return nil
+	}
+
+	t.generateGroupKeyUrls()
+
+	var cause error
+	mu := sync.Mutex{}
+	t.forEachDatastore(func(ds Datastorer) {
+		_, err := ds.Prepare()
+		if err != nil {
+			mu.Lock()
+			cause = err
+			mu.Unlock()
+		}
+	})
+
+	if cause != nil {
+		Log.Infow("validation failed", "txnId", t.TxnId, "cause", cause)
+		return errors.New("prepare phase failed: " + cause.Error())
+	}
+	return nil
+}
+
+// generateGroupKeyUrls generates the group key urls for the datastores
+// that have been written by the transaction.
+func (t *Transaction) generateGroupKeyUrls() {
 	i := 0
 	for _, ds := range t.dataStoreMap {
 		if ds.GetWriteCacheSize() == 0 {
 			continue
 		}
-		// When in Cherry Garcia mode or AblationLevel < 4,
-		// we need a single group key for all datastores
-		if i == 1 && (config.Debug.CherryGarciaMode || config.Config.AblationLevel < 4) {
+		// Cherry Garcia or AblationLevel < 4 needs
+		// a single group key for all datastores
+		if i == 1 && (t.Protocol() == config.CherryGarcia || config.Config.AblationLevel < 4) {
 			break
 		}
 		url := fmt.Sprintf("%s:%s", ds.GetName(), t.TxnId)
@@ -280,16 +759,6 @@ func (t *Transaction) Commit() error {
 		i++
 	}
 	Log.Debugw("GroupKeyUrls created", "GroupKeyUrls", t.GroupKeyUrls, "Topic", "CheckPoint")
-
-	if config.Debug.NativeMode {
-		return t.commitInNative()
-	}
-
-	if config.Debug.CherryGarciaMode {
-		return t.commitInCherryGarcia()
-	} else {
-		return t.commitInOreo()
-	}
 }
 
 func (t *Transaction) commitInNative() error {
@@ -314,11 +783,7 @@ func (t *Transaction) commitInCherryGarcia() error {
 
 	Log.Debugw("Finish obtaining commit time", "Latency", time.Since(t.debugStart), "Topic", "CheckPoint")
 
-	success := true
-	var cause error
-	mu := sync.Mutex{}
-
-	prepareDatastoreFunc := func(ds Datastorer) {
+	prepareDatastoreFunc := func(ds Datastorer) error {
 		defer func() {
 			msg := fmt.Sprintf("%s prepare phase ends", ds.GetName())
 			Log.Debugw(msg, "Latency", time.Since(t.debugStart), "Topic", "CheckPoint")
@@ -326,57 +791,57 @@ func (t *Transaction) commitInCherryGarcia() error {
 		// Cherry Garcia's prepare stage will not return the TCommit
 		_, err := ds.Prepare()
 		if err != nil {
-			mu.Lock()
-			success, cause = false, err
-			mu.Unlock()
 			if stackError, ok := err.(*errors.Error); ok {
 				errMsg := fmt.Sprintf("prepare phase failed: %v", stackError.ErrorStack())
 				Log.Errorw(errMsg, "txnId", t.TxnId, "ds", ds.GetName())
 			}
 			Log.Errorw("prepare phase failed", "txnId", t.TxnId, "cause", err, "ds", ds.GetName())
 		}
+		return err
 	}
 
-	for _, ds := range t.dataStoreMap {
-		prepareDatastoreFunc(ds)
+	prepareStart := time.Now()
+	// the datastores prepare one after the other, as in Cherry Garcia
+	cause := t.prepareDatastores(1, prepareDatastoreFunc)
+	t.stats.PrepareDuration = time.Since(prepareStart)
+
+	if cause != nil {
+		// a failed abort is reported along so that the caller can re-drive it
+		abortErr := t.Abort()
+		return errors.Join(prepareFailed(cause), abortErr)
 	}
 
-	if !success {
+	Log.Debugw("finishes prepare phase", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
+
+	if err := t.renewLeases(prepareStart); err != nil {
 		t.Abort()
-		return errors.New("prepare phase failed: " + cause.Error())
+		return errors.New("lease renewal failed: " + err.Error())
 	}
 
-	Log.Infow("finishes prepare phase", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
-
 	successNum := t.CreateGroupKeyFromUrls(t.GroupKeyUrls, config.COMMITTED)
 	if successNum != len(t.GroupKeyUrls) {
 		t.Abort()
-		return fmt.Errorf("transaction is aborted by other transaction when creating group keys, successNum: %d, len(t.GroupKeyUrls): %d", successNum, len(t.GroupKeyUrls))
+		return &ConflictError{Err: fmt.Errorf("transaction is aborted by other transaction when creating group keys, successNum: %d, len(t.GroupKeyUrls): %d", successNum, len(t.GroupKeyUrls))}
 	}
 	Log.Debugw("GroupKey created", "Latency", time.Since(t.debugStart), "Topic", "CheckPoint")
 
-	var wg = sync.WaitGroup{}
-	for _, ds := range t.dataStoreMap {
-		wg.Add(1)
-		go func(ds Datastorer) {
-			defer wg.Done()
-			ds.Commit()
-		}(ds)
-	}
-	wg.Wait()
+	commitStart := time.Now()
+	recovering := t.commitWithRecovery()
+	t.stats.CommitDuration = time.Since(commitStart)
 
-	go func() {
-		t.DeleteGroupKeyFromUrls(t.GroupKeyUrls)
-	}()
+	// the recovery still needs the group keys and deletes them once done
+	if !recovering {
+		t.goBackground(&asyncTSRDeletes, func() {
+			t.DeleteGroupKeyFromUrls(t.GroupKeyUrls)
+		})
+	}
 	return nil
 }
 
 func (t *Transaction) commitInOreo() error {
 	tCommit := int64(0)
-	success := true
-	var cause error
 	mu := sync.Mutex{}
-	prepareDatastoreFunc := func(ds Datastorer) {
+	prepareDatastoreFunc := func(ds Datastorer) error {
 		defer func() {
 			msg := fmt.Sprintf("%s prepare phase ends", ds.GetName())
 			Log.Debugw(msg, "Latency", time.Since(t.debugStart), "Topic", "CheckPoint")
@@ -384,38 +849,37 @@ func (t *Transaction) commitInOreo() error {
 		ts, err := ds.Prepare()
 		mu.Lock()
 		tCommit = max(tCommit, ts)
+		mu.Unlock()
 		if err != nil {
-			success, cause = false, err
 			if stackError, ok := err.(*errors.Error); ok {
 				errMsg := fmt.Sprintf("prepare phase failed: %v", stackError.ErrorStack())
 				Log.Errorw(errMsg, "txnId", t.TxnId, "ds", ds.GetName())
 			}
 			Log.Errorw("prepare phase failed", "txnId", t.TxnId, "cause", err, "ds", ds.GetName())
 		}
-		mu.Unlock()
+		return err
 	}
 
-	Log.Infow("Starting to call ds.Prepare()", "txnId", t.TxnId, "Latency", time.Since(t.debugStart), "Topic", "CheckPoint")
+	Log.Debugw("Starting to call ds.Prepare()", "txnId", t.TxnId, "Latency", time.Since(t.debugStart), "Topic", "CheckPoint")
 
-	var wg = sync.WaitGroup{}
-	for _, ds := range t.dataStoreMap {
-		wg.Add(1)
-		go func(ds Datastorer) {
-			defer wg.Done()
-			prepareDatastoreFunc(ds)
-		}(ds)
-	}
-	wg.Wait()
+	prepareStart := time.Now()
+	// a failed or timed out datastore ends the prepare phase of all of them
+	cause := t.prepareDatastores(config.Config.CommitParallelism, prepareDatastoreFunc)
+	t.stats.PrepareDuration = time.Since(prepareStart)
 
-	if !success {
-		go t.Abort()
-		return errors.New("prepare phase failed: " + cause.Error())
+	if cause != nil {
+		// abort before returning, so that the caller sees the records rolled back
+		// and a failed abort is reported along
+		abortErr := t.Abort()
+		return errors.Join(prepareFailed(cause), abortErr)
 	}
 
-	Log.Infow("finishes prepare phase", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
+	Log.Debugw("finishes prepare phase", "txnId", t.TxnId, "latency", time.Since(t.debugStart), "Topic", "CheckPoint")
 
 	if config.Config.AblationLevel >= 3 {
+		mu.Lock()
 		t.TxnCommitTime = tCommit
+		mu.Unlock()
 	} else {
 		var err error
 		t.TxnCommitTime, err = t.getTime("commit")
@@ -430,76 +894,219 @@ func (t *Transaction) commitInOreo() error {
 		if config.Debug.DebugMode {
 			time.Sleep(config.GetMaxDebugLatency())
 		}
+		if err := t.renewLeases(prepareStart); err != nil {
+			t.Abort()
+			return errors.New("lease renewal failed: " + err.Error())
+		}
 		successNum := t.CreateGroupKeyFromUrls(t.GroupKeyUrls, config.COMMITTED)
 		if successNum != len(t.GroupKeyUrls) {
 			t.Abort()
-			return fmt.Errorf("transaction is aborted by other transaction when creating group keys, successNum: %d, len(t.GroupKeyUrls): %d", successNum, len(t.GroupKeyUrls))
+			return &ConflictError{Err: fmt.Errorf("transaction is aborted by other transaction when creating group keys, successNum: %d, len(t.GroupKeyUrls): %d", successNum, len(t.GroupKeyUrls))}
 		}
-		Log.Infow("Starting to call ds.Commit()", "txnId", t.TxnId)
-		var wg = sync.WaitGroup{}
-		for _, ds := range t.dataStoreMap {
-			wg.Add(1)
-			go func(ds Datastorer) {
-				defer wg.Done()
-				ds.Commit()
-			}(ds)
+		if config.Config.AsyncLevel >= config.AsyncLevelTwo {
+			// the TSRs are committed, the readers roll the records
+			// forward until the commit phase has finished
+			t.stats.AsyncCommit = true
+			t.goBackground(&asyncCommits, func() {
+				t.commitWithRecovery()
+			})
+			return nil
 		}
-		wg.Wait()
+		Log.Debugw("Starting to call ds.Commit()", "txnId", t.TxnId)
+		commitStart := time.Now()
+		t.commitWithRecovery()
+		t.stats.CommitDuration = time.Since(commitStart)
 		return nil
 	}
 
-	go func() {
-		Log.Infow("Starting to call ds.Commit()", "txnId", t.TxnId)
-		var wg = sync.WaitGroup{}
-		for _, ds := range t.dataStoreMap {
-			wg.Add(1)
-			go func(ds Datastorer) {
-				defer wg.Done()
-				ds.Commit()
-			}(ds)
-		}
-		wg.Wait()
+	if err := t.renewLeases(prepareStart); err != nil {
+		t.Abort()
+		return errors.New("lease renewal failed: " + err.Error())
+	}
+
+	t.stats.AsyncCommit = true
+	t.goBackground(&asyncCommits, func() {
+		Log.Debugw("Starting to call ds.Commit()", "txnId", t.TxnId)
+		t.commitWithRecovery()
 		// t.DeleteGroupKeyFromUrls(t.GroupKeyUrls)
-	}()
+	})
 	return nil
 
 }
 
-func (t *Transaction) OnePhaseCommit() error {
+// forEachDatastore calls f on every datastore of the transaction concurrently
+// and waits for all of them. At most config.Config.CommitParallelism calls
+// run at the same time.
+func (t *Transaction) forEachDatastore(f func(ds Datastorer)) {
+	var eg errgroup.Group
+	if config.Config.CommitParallelism > 0 {
+		eg.SetLimit(config.Config.CommitParallelism)
+	}
+	for _, ds := range t.dataStoreMap {
+		ds := ds
+		eg.Go(func() error {
+			f(ds)
+			return nil
+		})
+	}
+	_ = eg.Wait()
+}
+
+// renewLeases renews the leases of the prepared records if the time elapsed
+// since prepareStart gets close to config.Config.LeaseTime, so that a slow
+// commit phase is not mistaken for a crashed transaction and rolled back.
+func (t *Transaction) renewLeases(prepareStart time.Time) error {
+	elapsed := time.Since(prepareStart)
+	if elapsed < config.Config.LeaseTime-config.Config.LeaseRenewalThreshold {
+		return nil
+	}
+
+	Log.Infow("renewing leases", "txnId", t.TxnId, "elapsed", elapsed)
+	var eg errgroup.Group
 	for _, ds := range t.dataStoreMap {
+		if ds.GetWriteCacheSize() == 0 {
+			continue
+		}
+		eg.Go(ds.RenewLease)
+	}
+	return eg.Wait()
+}
+
+// OnePhaseCommit commits the transaction writing a single record
+// with the one-phase protocol, whatever its Protocol.
+func (t *Transaction) OnePhaseCommit() error {
+	t.resetStats()
+	var err error
+	if t.TxnCommitTime, err = t.getTime("commit"); err != nil {
+		return err
+	}
+	return t.onePhaseCommit()
+}
+
+// onePhaseCommit commits the record written in each datastore
+// with a single conditional update.
+func (t *Transaction) onePhaseCommit() error {
+	t.stats.OnePhase = true
+	commitStart := time.Now()
+	defer func() {
+		t.stats.CommitDuration = time.Since(commitStart)
+	}()
+	err := t.prepareDatastores(0, func(ds Datastorer) error {
 		err := ds.OnePhaseCommit()
 		if err != nil {
 			Log.Errorw("one phase commit failed", "txnId", t.TxnId, "ds", ds.GetName(), "cause", err)
-			go t.Abort()
-			return err
 		}
+		return err
+	})
+	if err == nil {
+		return nil
 	}
-	return nil
+	// the record written by a timed out commit is committed if it lands,
+	// the abort only rolls back the records still prepared
+	if errors.Is(err, ErrPrepareTimeout) {
+		err = errors.Errorf("the outcome of the one-phase commit is unknown: %w", err)
+	}
+	t.stats.AbortCause = err
+	t.Abort()
+	return err
+}
+
+// AbortFailure is returned by Transaction.Abort when some datastores
+// failed to abort. The transaction is aborted anyway, while the failed
+// datastores keep the records to roll back, so that calling their Abort
+// again re-drives the rollback.
+type AbortFailure struct {
+	// Errs maps the name of each failed datastore to its error.
+	Errs map[string]error
+}
+
+func (e *AbortFailure) Error() string {
+	names := make([]string, 0, len(e.Errs))
+	for name := range e.Errs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	causes := make([]string, 0, len(names))
+	for _, name := range names {
+		causes = append(causes, fmt.Sprintf("%s: %v", name, e.Errs[name]))
+	}
+	return fmt.Sprintf("failed to abort in %s: %s", strings.Join(names, ", "), strings.Join(causes, "; "))
+}
+
+func (e *AbortFailure) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		errs = append(errs, err)
+	}
+	return errs
 }
 
 // Abort aborts the transaction.
-// It checks the current state of the transaction and returns an error if the transaction is already committed, aborted, or not started.
-// If the transaction is in a valid state, it sets the transaction state to ABORTED and calls the Abort method on each data store associated with the transaction.
-// Returns an error if any of the data store's Abort method returns an error, otherwise returns nil.
-func (t *Transaction) Abort() error {
-	lastState := t.GetState()
-	err := t.SetState(config.ABORTED)
+// It sets the transaction state to ABORTED and calls the Abort method on each data store associated with the transaction.
+// The datastores are aborted concurrently, and Abort returns once all of them are done,
+// with an *AbortFailure naming the datastores that failed, if any.
+// The records rolled back by each datastore are reported in Stats().RolledBack.
+// It is idempotent: only the first call does the work,
+// later or concurrent calls return nil immediately.
+func (t *Transaction) Abort() (err error) {
+	lastState, err := t.TransitTo(config.ABORTED)
 	if err != nil {
 		return err
 	}
+	if lastState == config.ABORTED {
+		Log.Debugw("transaction has already been aborted", "txnId", t.TxnId)
+		return nil
+	}
 
 	hasCommitted := false
 	if lastState == config.COMMITTED {
 		hasCommitted = true
 	}
+	span := t.startSpan("Abort")
+	defer func() {
+		tracing.End(span, err)
+		// an aborted commit ends the transaction span by itself
+		if !hasCommitted {
+			t.endSpan(nil)
+		}
+	}()
 	Log.Infow("aborting transaction", "txnId", t.TxnId, "hasCommitted", hasCommitted)
 	t.CreateGroupKeyFromUrls(t.GroupKeyUrls, config.ABORTED)
-	for _, ds := range t.dataStoreMap {
+
+	var mu sync.Mutex
+	rolledBack := make(map[string]int)
+	failed := make(map[string]error)
+	t.forEachDatastore(func(ds Datastorer) {
+		// a datastore still preparing after a prepare timeout is rolled
+		// back once its prepare returns; the ABORTED group keys created
+		// above already make its records invisible to the readers
+		if pending := t.pendingPrepare(ds.GetName()); pending != nil {
+			t.background.Add(1)
+			go func() {
+				defer t.background.Done()
+				<-pending
+				if err := ds.Abort(hasCommitted); err != nil {
+					Log.Errorw("abort failed", "txnId", t.TxnId, "cause", err, "ds", ds.GetName())
+				}
+			}()
+			return
+		}
 		err := ds.Abort(hasCommitted)
+		mu.Lock()
+		defer mu.Unlock()
 		if err != nil {
 			Log.Errorw("abort failed", "txnId", t.TxnId, "cause", err, "ds", ds.GetName())
+			failed[ds.GetName()] = err
 		}
+		if reporter, ok := ds.(AbortReporter); ok {
+			rolledBack[ds.GetName()] = reporter.RolledBackCount()
+		}
+	})
+	t.stats.RolledBack = rolledBack
+	if len(failed) > 0 {
+		return &AbortFailure{Errs: failed}
 	}
+	Log.Infow("transaction aborted", "txnId", t.TxnId, "rolledBack", rolledBack)
 	return nil
 }
 
@@ -559,19 +1166,63 @@ func (t *Transaction) getTime(mode string) (int64, error) {
 	return t.timeSource.GetTime(mode)
 }
 
+// now returns the time the leases of the records are set and checked
+// against, taken from the time source if it is a timesource.Clock.
+func (t *Transaction) now() time.Time {
+	if clock, ok := t.timeSource.(timesource.Clock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
 func (t *Transaction) RemoteRead(dsName string, key string) (DataItem, RemoteDataStrategy, string, error) {
+	return t.remoteRead(dsName, key, nil)
+}
+
+// remoteRead reads a record through the executors. If fields is not empty
+// and the client supports it, the value of the record is projected on
+// fields by the executor.
+func (t *Transaction) remoteRead(dsName string, key string, fields []string) (DataItem, RemoteDataStrategy, string, error) {
 	if !t.isRemote {
 		return nil, Normal, "", errors.New("not a remote transaction")
 	}
 
+	cfg := t.readConfig()
+	client := t.remoteClient()
+	if fc, ok := client.(FieldReadClient); ok && len(fields) > 0 {
+		return fc.ReadFields(dsName, key, t.TxnStartTime, cfg, fields)
+	}
+	return client.Read(dsName, key, t.TxnStartTime, cfg)
+}
+
+// remoteReadAt reads the version of a record visible at ts through the executors.
+func (t *Transaction) remoteReadAt(dsName string, key string, ts int64) (DataItem, error) {
+	client, ok := t.remoteClient().(ReadAtClient)
+	if !ok {
+		return nil, errors.New("the remote client does not support ReadAt")
+	}
+
+	cfg := t.readConfig()
+	// a read-committed read would ignore ts
+	cfg.IsolationLevel = config.Snapshot
+	item, _, _, err := client.ReadAt(dsName, key, t.TxnStartTime, ts, cfg)
+	return item, err
+}
+
+// readConfig returns the configuration the executors read the records with.
+func (t *Transaction) readConfig() RecordConfig {
 	// globalName := t.groupKeyMaintainer.(Datastorer).GetName()
 
-	return t.client.Read(dsName, key, t.TxnStartTime, RecordConfig{
+	return RecordConfig{
 		// GlobalName:                  globalName,
 		MaxRecordLen:                config.Config.MaxRecordLength,
 		ReadStrategy:                config.Config.ReadStrategy,
+		ReadWaitTime:                config.Config.ReadWaitTime,
 		ConcurrentOptimizationLevel: config.Config.ConcurrentOptimizationLevel,
-	})
+		IsolationLevel:              t.isolation,
+		Protocol:                    t.Protocol(),
+		TxnId:                       t.TxnId,
+	}
 }
 
 func (t *Transaction) RemoteValidate(dsName string, key string, item DataItem) error {
@@ -588,30 +1239,42 @@ func (t *Transaction) RemotePrepare(dsName string, itemList []DataItem, validati
 		item.SetGroupKeyList(groupKeyList)
 	}
 
-	cfg := RecordConfig{
+	if batch := t.prepareBatch(); batch != nil {
+		res := batch.join(dsName, RemotePrepare{ItemList: itemList, ValidationMap: validationMap})
+		return res.VerMap, res.TCommit, res.Err
+	}
+	return t.remoteClient().Prepare(dsName, itemList, t.TxnStartTime,
+		t.prepareConfig(), validationMap)
+}
+
+// prepareConfig returns the configuration the executors prepare the records with.
+func (t *Transaction) prepareConfig() RecordConfig {
+	return RecordConfig{
 		// GlobalName:                  globalName,
 		MaxRecordLen:                config.Config.MaxRecordLength,
 		ReadStrategy:                config.Config.ReadStrategy,
 		ConcurrentOptimizationLevel: config.Config.ConcurrentOptimizationLevel,
 		AblationLevel:               config.Config.AblationLevel,
+		TxnId:                       t.TxnId,
 	}
-	return t.client.Prepare(dsName, itemList, t.TxnStartTime,
-		cfg, validationMap)
 }
 
-func (t *Transaction) RemoteCommit(dsName string, infoList []CommitInfo) error {
+func (t *Transaction) RemoteCommit(dsName string, infoList []CommitInfo) (int64, error) {
 	if !t.isRemote {
-		return errors.New("not a remote transaction")
+		return 0, errors.New("not a remote transaction")
 	}
 	Log.Debugw("RemoteCommit", "infoList", infoList, "t.TxnCommitTime", t.TxnCommitTime)
-	return t.client.Commit(dsName, infoList, t.TxnCommitTime)
+	return t.remoteClient().Commit(dsName, infoList, t.TxnCommitTime)
 }
 
-func (t *Transaction) RemoteAbort(dsName string, keyList []string) error {
+// RemoteAbort has the executor roll back the records of keyList
+// written by the transaction and returns the number rolled back.
+func (t *Transaction) RemoteAbort(dsName string, keyList []string) (int, error) {
 	if !t.isRemote {
-		return errors.New("not a remote transaction")
+		return 0, errors.New("not a remote transaction")
 	}
-	return t.client.Abort(dsName, keyList, t.TxnId)
+	// the executor rolls back the records tagged with the group keys of the transaction
+	return t.remoteClient().Abort(dsName, keyList, strings.Join(t.GroupKeyUrls, ","))
 }
 
 func (t *Transaction) debug(topic testutil.TxnTopic, format string, a ...interface{}) {
@@ -1,9 +1,19 @@
 package txn
 
 import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/go-errors/errors"
 	"github.com/oreo-dtx-lab/oreo/internal/testutil"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func NewTransactionWithSetup() *Transaction {
@@ -72,3 +82,671 @@ func TestTxnOperateWithoutStart(t *testing.T) {
 		t.Errorf("Expected error deleting record")
 	}
 }
+
+// abortCountingDatastore is a Datastorer stub that counts Abort calls.
+type abortCountingDatastore struct {
+	Datastorer
+	abortTimes atomic.Int32
+}
+
+func (ds *abortCountingDatastore) GetName() string     { return "counting" }
+func (ds *abortCountingDatastore) SetTxn(*Transaction) {}
+func (ds *abortCountingDatastore) GetConn() Connector  { return nil }
+func (ds *abortCountingDatastore) Start() error        { return nil }
+func (ds *abortCountingDatastore) Abort(bool) error    { ds.abortTimes.Add(1); return nil }
+
+// TestTxnAbortTwiceConcurrently tests that concurrent aborts on the same
+// transaction only abort the datastores once and never return errors.
+func TestTxnAbortTwiceConcurrently(t *testing.T) {
+	txn := NewTransaction()
+	ds := &abortCountingDatastore{}
+	if err := txn.AddDatastore(ds); err != nil {
+		t.Fatalf("Error adding datastore: %s", err)
+	}
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- txn.Abort()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected no error aborting transaction, got %s", err)
+		}
+	}
+	if txn.GetState() != config.ABORTED {
+		t.Errorf("Expected state %v, got %v", config.ABORTED, txn.GetState())
+	}
+	if got := ds.abortTimes.Load(); got != 1 {
+		t.Errorf("Expected datastore to be aborted once, got %d", got)
+	}
+
+	// aborting again afterwards is a no-op as well
+	if err := txn.Abort(); err != nil {
+		t.Errorf("Expected no error aborting transaction again, got %s", err)
+	}
+	if got := ds.abortTimes.Load(); got != 1 {
+		t.Errorf("Expected datastore to be aborted once, got %d", got)
+	}
+}
+
+// slowDatastore answers every read after a fixed delay.
+type slowDatastore struct {
+	Datastorer
+	name  string
+	delay time.Duration
+}
+
+func (ds *slowDatastore) GetName() string     { return ds.name }
+func (ds *slowDatastore) SetTxn(*Transaction) {}
+func (ds *slowDatastore) GetConn() Connector  { return nil }
+func (ds *slowDatastore) Start() error        { return nil }
+func (ds *slowDatastore) Read(key string, value any) error {
+	time.Sleep(ds.delay)
+	if key == "missing" {
+		return errors.New(KeyNotFound)
+	}
+	*(value.(*string)) = ds.name + ":" + key
+	return nil
+}
+
+// TestTxnReadMany tests that reads on different datastores run concurrently
+// and that the results keep the order of the requests.
+func TestTxnReadMany(t *testing.T) {
+	delay := 100 * time.Millisecond
+	txn := NewTransaction()
+	for _, name := range []string{"ds1", "ds2", "ds3"} {
+		if err := txn.AddDatastore(&slowDatastore{name: name, delay: delay}); err != nil {
+			t.Fatalf("Error adding datastore: %s", err)
+		}
+	}
+
+	values := make([]string, 3)
+	reqs := []ReadSpec{
+		{DsName: "ds3", Key: "key1", Value: &values[0]},
+		{DsName: "ds1", Key: "key2", Value: &values[1]},
+		{DsName: "ds2", Key: "key3", Value: &values[2]},
+	}
+
+	if _, err := txn.ReadMany(reqs); err == nil {
+		t.Errorf("Expected an error reading before the transaction starts")
+	}
+
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+
+	start := time.Now()
+	results, err := txn.ReadMany(reqs)
+	latency := time.Since(start)
+	if err != nil {
+		t.Fatalf("Error reading: %s", err)
+	}
+	if latency >= 2*delay {
+		t.Errorf("Expected latency close to %v, got %v", delay, latency)
+	}
+
+	expected := []string{"ds3:key1", "ds1:key2", "ds2:key3"}
+	for i := range reqs {
+		if results[i].Key != reqs[i].Key || results[i].Err != nil {
+			t.Errorf("Unexpected result %d: %+v", i, results[i])
+		}
+		if values[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], values[i])
+		}
+	}
+
+	var value string
+	results, err = txn.ReadMany([]ReadSpec{
+		{DsName: "ds1", Key: "key1", Value: &value},
+		{DsName: "ds2", Key: "missing", Value: &value},
+		{DsName: "ds4", Key: "key1", Value: &value},
+	})
+	if err == nil || err.Error() != KeyNotFound.Error() {
+		t.Errorf("Expected %s, got %v", KeyNotFound, err)
+	}
+	if results[0].Err != nil || results[2].Err == nil {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+// leaseDatastore takes prepareLatency to prepare its single record
+// and counts the lease renewals.
+type leaseDatastore struct {
+	Datastorer
+	prepareLatency time.Duration
+	renewTimes     atomic.Int32
+}
+
+func (ds *leaseDatastore) GetName() string                   { return "lease" }
+func (ds *leaseDatastore) SetTxn(*Transaction)               {}
+func (ds *leaseDatastore) GetConn() Connector                { return nil }
+func (ds *leaseDatastore) Start() error                      { return nil }
+func (ds *leaseDatastore) Write(key string, value any) error { return nil }
+func (ds *leaseDatastore) GetWriteCacheSize() int            { return 1 }
+func (ds *leaseDatastore) Commit() error                     { return nil }
+func (ds *leaseDatastore) Abort(bool) error                  { return nil }
+func (ds *leaseDatastore) RenewLease() error                 { ds.renewTimes.Add(1); return nil }
+func (ds *leaseDatastore) Prepare() (int64, error) {
+	time.Sleep(ds.prepareLatency)
+	return 0, nil
+}
+
+// TestTxnCommitRenewsLease tests that the leases are renewed before committing
+// only if the commit phase gets close to the lease duration.
+func TestTxnCommitRenewsLease(t *testing.T) {
+	leaseTime, threshold := config.Config.LeaseTime, config.Config.LeaseRenewalThreshold
+	defer func() {
+		config.Config.LeaseTime, config.Config.LeaseRenewalThreshold = leaseTime, threshold
+	}()
+	config.Config.LeaseTime = 100 * time.Millisecond
+	config.Config.LeaseRenewalThreshold = 40 * time.Millisecond
+
+	testCases := []struct {
+		name           string
+		prepareLatency time.Duration
+		renewTimes     int32
+	}{
+		{"fast commit", 0, 0},
+		{"slow commit", 80 * time.Millisecond, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			txn := NewTransaction()
+			ds := &leaseDatastore{prepareLatency: tc.prepareLatency}
+			if err := txn.AddDatastore(ds); err != nil {
+				t.Fatalf("Error adding datastore: %s", err)
+			}
+			if err := txn.Start(); err != nil {
+				t.Fatalf("Error starting transaction: %s", err)
+			}
+			if err := txn.Write("lease", "key", "value"); err != nil {
+				t.Fatalf("Error writing: %s", err)
+			}
+			if err := txn.Commit(); err != nil {
+				t.Fatalf("Error committing transaction: %s", err)
+			}
+			if got := ds.renewTimes.Load(); got != tc.renewTimes {
+				t.Errorf("Expected leases to be renewed %d times, got %d", tc.renewTimes, got)
+			}
+		})
+	}
+}
+
+// peakTracker records the peak number of concurrent prepares.
+type peakTracker struct {
+	cur  atomic.Int32
+	peak atomic.Int32
+}
+
+// trackedDatastore takes a while to prepare its single record
+// and reports the concurrent prepares to the tracker.
+type trackedDatastore struct {
+	Datastorer
+	name       string
+	tracker    *peakTracker
+	prepareErr error
+}
+
+func (ds *trackedDatastore) GetName() string                   { return ds.name }
+func (ds *trackedDatastore) SetTxn(*Transaction)               {}
+func (ds *trackedDatastore) Start() error                      { return nil }
+func (ds *trackedDatastore) Write(key string, value any) error { return nil }
+func (ds *trackedDatastore) GetWriteCacheSize() int            { return 1 }
+func (ds *trackedDatastore) Commit() error                     { return nil }
+func (ds *trackedDatastore) Abort(bool) error                  { return nil }
+func (ds *trackedDatastore) RenewLease() error                 { return nil }
+func (ds *trackedDatastore) GetConn() Connector {
+	// keeps the TSRs created by an aborted commit
+	return &memoryTSRConnector{tsrs: make(map[string]string)}
+}
+func (ds *trackedDatastore) Prepare() (int64, error) {
+	cur := ds.tracker.cur.Add(1)
+	defer ds.tracker.cur.Add(-1)
+	for {
+		peak := ds.tracker.peak.Load()
+		if cur <= peak || ds.tracker.peak.CompareAndSwap(peak, cur) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return 0, ds.prepareErr
+}
+
+// TestTxnCommitParallelism tests that no more than
+// config.Config.CommitParallelism datastores are prepared at the same time.
+func TestTxnCommitParallelism(t *testing.T) {
+	// the commits left in the background read the parallelism
+	WaitAsync(time.Second)
+	parallelism := config.Config.CommitParallelism
+	defer func() {
+		WaitAsync(time.Second)
+		config.Config.CommitParallelism = parallelism
+	}()
+
+	testCases := []struct {
+		name        string
+		parallelism int
+		maxPeak     int32
+	}{
+		{"limited", 2, 2},
+		{"unbounded", 0, 6},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			WaitAsync(time.Second)
+			config.Config.CommitParallelism = tc.parallelism
+			tracker := &peakTracker{}
+			txn := NewTransaction()
+			for i := 0; i < 6; i++ {
+				ds := &trackedDatastore{name: "ds" + strconv.Itoa(i), tracker: tracker}
+				if err := txn.AddDatastore(ds); err != nil {
+					t.Fatalf("Error adding datastore: %s", err)
+				}
+			}
+			if err := txn.Start(); err != nil {
+				t.Fatalf("Error starting transaction: %s", err)
+			}
+			for i := 0; i < 6; i++ {
+				if err := txn.Write("ds"+strconv.Itoa(i), "key", "value"); err != nil {
+					t.Fatalf("Error writing: %s", err)
+				}
+			}
+			if err := txn.Commit(); err != nil {
+				t.Fatalf("Error committing transaction: %s", err)
+			}
+			if peak := tracker.peak.Load(); peak > tc.maxPeak || peak < 2 {
+				t.Errorf("Expected at most %d concurrent prepares, got %d", tc.maxPeak, peak)
+			}
+		})
+	}
+}
+
+// TestTxnStats tests that the stats reflect the shape of the committed transaction.
+func TestTxnStats(t *testing.T) {
+	newTxn := func(prepareErr error) *Transaction {
+		txn := NewTransaction()
+		for i := 0; i < 3; i++ {
+			ds := &trackedDatastore{name: "ds" + strconv.Itoa(i), tracker: &peakTracker{}}
+			if i == 2 {
+				ds.prepareErr = prepareErr
+			}
+			if err := txn.AddDatastore(ds); err != nil {
+				t.Fatalf("Error adding datastore: %s", err)
+			}
+		}
+		if err := txn.Start(); err != nil {
+			t.Fatalf("Error starting transaction: %s", err)
+		}
+		return txn
+	}
+	write := func(txn *Transaction) {
+		for _, dsName := range []string{"ds0", "ds0", "ds1", "ds2"} {
+			if err := txn.Write(dsName, "key", "value"); err != nil {
+				t.Fatalf("Error writing: %s", err)
+			}
+		}
+	}
+
+	t.Run("read-only transaction", func(t *testing.T) {
+		txn := newTxn(nil)
+		if err := txn.Commit(); err != nil {
+			t.Fatalf("Error committing transaction: %s", err)
+		}
+		stats := txn.Stats()
+		if !stats.ReadOnly || stats.WriteCount != 0 || stats.PrepareDuration != 0 {
+			t.Errorf("Unexpected stats of a read-only transaction: %+v", stats)
+		}
+	})
+
+	t.Run("read-write transaction", func(t *testing.T) {
+		txn := newTxn(nil)
+		write(txn)
+		if err := txn.Commit(); err != nil {
+			t.Fatalf("Error committing transaction: %s", err)
+		}
+		stats := txn.Stats()
+		if stats.ReadOnly || stats.WriteCount != 4 || stats.DatastoreCount != 3 {
+			t.Errorf("Unexpected shape of a read-write transaction: %+v", stats)
+		}
+		if stats.PrepareDuration < 20*time.Millisecond {
+			t.Errorf("Expected the prepare phase to take at least 20ms, got %v", stats.PrepareDuration)
+		}
+		if !stats.AsyncCommit || stats.OnePhase || stats.AbortCause != nil {
+			t.Errorf("Unexpected commit path: %+v", stats)
+		}
+	})
+
+	t.Run("aborted transaction", func(t *testing.T) {
+		txn := newTxn(errors.New(VersionMismatch))
+		write(txn)
+		if err := txn.Commit(); err == nil {
+			t.Fatalf("Expected the commit to fail")
+		}
+		stats := txn.Stats()
+		if stats.AbortCause == nil || stats.AsyncCommit {
+			t.Errorf("Unexpected stats of an aborted transaction: %+v", stats)
+		}
+	})
+}
+
+// TestTxnTracing tests that every phase of the transaction
+// is recorded as a child span of the transaction span.
+func TestTxnTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(nil)
+
+	txn := NewTransaction()
+	ds := &trackedDatastore{name: "ds1", tracker: &peakTracker{}}
+	if err := txn.AddDatastore(ds); err != nil {
+		t.Fatalf("Error adding datastore: %s", err)
+	}
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+	if err := txn.Write("ds1", "key", "value"); err != nil {
+		t.Fatalf("Error writing: %s", err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatalf("Error committing transaction: %s", err)
+	}
+
+	spans := exporter.GetSpans()
+	var root tracetest.SpanStub
+	children := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		if span.Name == "Transaction" {
+			root = span
+		} else {
+			children[span.Name] = span
+		}
+	}
+	if !root.SpanContext.IsValid() {
+		t.Fatalf("Expected a transaction span, got %d spans", len(spans))
+	}
+	for _, name := range []string{"Start", "Write", "Commit"} {
+		span, ok := children[name]
+		if !ok {
+			t.Errorf("Expected a %s span", name)
+			continue
+		}
+		if span.Parent.SpanID() != root.SpanContext.SpanID() ||
+			span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("Expected the %s span to be a child of the transaction span", name)
+		}
+	}
+}
+
+// memoryTSRConnector keeps the TSRs in memory.
+type memoryTSRConnector struct {
+	Connector
+	mu   sync.Mutex
+	tsrs map[string]string
+}
+
+func (c *memoryTSRConnector) Get(name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if str, ok := c.tsrs[name]; ok {
+		return str, nil
+	}
+	return "", KeyNotFound
+}
+
+func (c *memoryTSRConnector) AtomicCreate(name string, value any) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if str, ok := c.tsrs[name]; ok {
+		return str, KeyExists
+	}
+	c.tsrs[name] = value.(string)
+	return "", nil
+}
+
+func (c *memoryTSRConnector) Delete(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tsrs, name)
+	return nil
+}
+
+// failingCommitDatastore always fails to commit its records,
+// while recommitting them succeeds after failedRecommits attempts.
+type failingCommitDatastore struct {
+	trackedDatastore
+	conn            *memoryTSRConnector
+	failedRecommits int32
+	recommits       atomic.Int32
+	recommitted     atomic.Value
+}
+
+func (ds *failingCommitDatastore) GetConn() Connector { return ds.conn }
+func (ds *failingCommitDatastore) Commit() error {
+	return &CommitFailure{DsName: ds.name, Keys: []string{"key1", "key2"},
+		Err: errors.New("connection reset"), Recoverer: ds}
+}
+func (ds *failingCommitDatastore) RecommitKeys(keys []string) error {
+	if ds.recommits.Add(1) <= ds.failedRecommits {
+		return &CommitFailure{DsName: ds.name, Keys: keys[len(keys)-1:], Err: errors.New("connection reset")}
+	}
+	ds.recommitted.Store(keys)
+	return nil
+}
+
+// TestTxnCommitRecovery tests that the records whose commit failed
+// are recommitted in the background once the TSR confirms the commit.
+func TestTxnCommitRecovery(t *testing.T) {
+	// the recoveries left in the background read the interval
+	WaitAsync(time.Second)
+	interval := config.Config.CommitRecoveryInterval
+	config.Config.CommitRecoveryInterval = time.Millisecond
+	defer func() {
+		WaitAsync(time.Second)
+		config.Config.CommitRecoveryInterval = interval
+	}()
+
+	newTxn := func(ds *failingCommitDatastore) *Transaction {
+		txn := NewTransaction()
+		if err := txn.AddDatastore(ds); err != nil {
+			t.Fatalf("Error adding datastore: %s", err)
+		}
+		if err := txn.Start(); err != nil {
+			t.Fatalf("Error starting transaction: %s", err)
+		}
+		if err := txn.Write("ds1", "key1", "value"); err != nil {
+			t.Fatalf("Error writing: %s", err)
+		}
+		return txn
+	}
+	newDatastore := func() *failingCommitDatastore {
+		return &failingCommitDatastore{
+			trackedDatastore: trackedDatastore{name: "ds1", tracker: &peakTracker{}},
+			conn:             &memoryTSRConnector{tsrs: make(map[string]string)},
+			failedRecommits:  2,
+		}
+	}
+
+	t.Run("the failed keys are recommitted", func(t *testing.T) {
+		ds := newDatastore()
+		txn := newTxn(ds)
+		if err := txn.Commit(); err != nil {
+			t.Fatalf("Error committing transaction: %s", err)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for ds.recommitted.Load() == nil && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		keys, _ := ds.recommitted.Load().([]string)
+		if len(keys) != 1 || keys[0] != "key2" {
+			t.Fatalf("Expected key2 to be recommitted at last, got %v", keys)
+		}
+		if got := ds.recommits.Load(); got != 3 {
+			t.Errorf("Expected 3 recommits, got %d", got)
+		}
+		// no record refers to the TSR once they are all committed
+		deadline = time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if _, err := ds.conn.Get("ds1:" + txn.TxnId); err != nil {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Errorf("Expected the TSR to be deleted after the recovery")
+	})
+
+	t.Run("an aborted transaction is not recommitted", func(t *testing.T) {
+		ds := newDatastore()
+		txn := newTxn(ds)
+		// a reader has aborted the transaction
+		ds.conn.tsrs["ds1:"+txn.TxnId] = `{"TxnState":4,"TCommit":0}`
+		if err := txn.Commit(); err != nil {
+			t.Fatalf("Error committing transaction: %s", err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		if got := ds.recommits.Load(); got != 0 {
+			t.Errorf("Expected no recommit, got %d", got)
+		}
+	})
+}
+
+// rollbackDatastore keeps its records prepared until
+// its Abort, which takes a while, rolls them back.
+type rollbackDatastore struct {
+	trackedDatastore
+	mu       sync.Mutex
+	prepared map[string]bool
+}
+
+func (ds *rollbackDatastore) Prepare() (int64, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.prepared = map[string]bool{"key1": true, "key2": true}
+	return 0, nil
+}
+func (ds *rollbackDatastore) Abort(hasCommitted bool) error {
+	time.Sleep(20 * time.Millisecond)
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for key := range ds.prepared {
+		ds.prepared[key] = false
+	}
+	return nil
+}
+func (ds *rollbackDatastore) RolledBackCount() int {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return len(ds.prepared)
+}
+
+// TestTxnCommitWaitsForAbort tests that the records prepared by a failed
+// commit are rolled back by the time Commit returns the error.
+func TestTxnCommitWaitsForAbort(t *testing.T) {
+	ds := &rollbackDatastore{trackedDatastore: trackedDatastore{name: "ds1", tracker: &peakTracker{}}}
+	failing := &trackedDatastore{name: "ds2", tracker: &peakTracker{}, prepareErr: errors.New("version mismatch")}
+	txn := NewTransaction()
+	for _, d := range []Datastorer{ds, failing} {
+		if err := txn.AddDatastore(d); err != nil {
+			t.Fatalf("Error adding datastore: %s", err)
+		}
+	}
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+	for _, name := range []string{"ds1", "ds2"} {
+		if err := txn.Write(name, "key1", "value"); err != nil {
+			t.Fatalf("Error writing: %s", err)
+		}
+	}
+
+	if err := txn.Commit(); err == nil {
+		t.Fatalf("Expected the commit to fail")
+	}
+	ds.mu.Lock()
+	for key, prepared := range ds.prepared {
+		if prepared {
+			t.Errorf("Expected %s to be rolled back when Commit returns", key)
+		}
+	}
+	ds.mu.Unlock()
+	if got := txn.Stats().RolledBack["ds1"]; got != 2 {
+		t.Errorf("Expected 2 records rolled back in ds1, got %d", got)
+	}
+}
+
+// abortingDatastore counts its aborts, which fail with abortErr.
+type abortingDatastore struct {
+	trackedDatastore
+	abortErr   error
+	abortTimes atomic.Int32
+}
+
+func (ds *abortingDatastore) Abort(bool) error {
+	ds.abortTimes.Add(1)
+	return ds.abortErr
+}
+
+// TestTxnAbortReportsFailedDatastores tests that every datastore is aborted
+// even if one of them fails, and that the error names the failed one.
+func TestTxnAbortReportsFailedDatastores(t *testing.T) {
+	names := []string{"mongo", "kvrocks", "redis", "cassandra"}
+	datastores := make(map[string]*abortingDatastore)
+	txn := NewTransaction()
+	for _, name := range names {
+		ds := &abortingDatastore{trackedDatastore: trackedDatastore{name: name, tracker: &peakTracker{}}}
+		if name == "cassandra" {
+			ds.abortErr = errors.New("connection refused")
+		}
+		if name == "mongo" {
+			// the prepare phase fails in one datastore and the others are aborted
+			ds.prepareErr = errors.New("version mismatch")
+		}
+		datastores[name] = ds
+		if err := txn.AddDatastore(ds); err != nil {
+			t.Fatalf("Error adding datastore: %s", err)
+		}
+	}
+	if err := txn.Start(); err != nil {
+		t.Fatalf("Error starting transaction: %s", err)
+	}
+	for _, name := range names {
+		if err := txn.Write(name, "key", "value"); err != nil {
+			t.Fatalf("Error writing: %s", err)
+		}
+	}
+
+	err := txn.Commit()
+	if err == nil {
+		t.Fatalf("Expected the commit to fail")
+	}
+	var failure *AbortFailure
+	if !errors.As(err, &failure) {
+		t.Fatalf("Expected an AbortFailure, got %v", err)
+	}
+	if len(failure.Errs) != 1 || failure.Errs["cassandra"] == nil {
+		t.Errorf("Expected only cassandra to fail to abort, got %v", failure.Errs)
+	}
+	if !strings.Contains(err.Error(), "prepare phase failed: version mismatch") ||
+		!strings.Contains(err.Error(), "failed to abort in cassandra: cassandra: connection refused") {
+		t.Errorf("Expected the error to name the prepare and abort failures, got %q", err)
+	}
+	for _, name := range names {
+		if got := datastores[name].abortTimes.Load(); got != 1 {
+			t.Errorf("Expected %s to be aborted once, got %d", name, got)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package txn_test
+
+import (
+	"testing"
+
+	"github.com/oreo-dtx-lab/oreo/internal/memkv"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/oreo-dtx-lab/oreo/pkg/datastore/redis"
+	"github.com/oreo-dtx-lab/oreo/pkg/txn"
+	"github.com/stretchr/testify/assert"
+)
+
+// newValidateTxn returns a started transaction on the datastore "memkv" of conn.
+func newValidateTxn(t *testing.T, conn txn.Connector) *txn.Transaction {
+	tx := txn.NewTransaction()
+	_ = tx.AddDatastore(txn.NewDatastore("memkv", conn, &redis.RedisItemFactory{}))
+	assert.NoError(t, tx.Start())
+	return tx
+}
+
+// TestTxnValidateReadOnly tests that Validate checks the records read by
+// a read-only transaction, although ReadSetValidation is off.
+func TestTxnValidateReadOnly(t *testing.T) {
+	commitInForeground(t)
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	write := func(value string) {
+		tx := newValidateTxn(t, conn)
+		assert.NoError(t, tx.Write("memkv", "item1", value))
+		assert.NoError(t, tx.Commit())
+	}
+	read := func() *txn.Transaction {
+		tx := newValidateTxn(t, conn)
+		var value string
+		assert.NoError(t, tx.Read("memkv", "item1", &value))
+		return tx
+	}
+	write("v1")
+
+	assert.NoError(t, read().Validate())
+
+	tx := read()
+	write("v2")
+	assert.ErrorContains(t, tx.Validate(), txn.ReadSetChanged.Error())
+}
+
+// TestTxnValidateLeavesNoTSR tests that a dry run leaves the records
+// as they were and no group key behind.
+func TestTxnValidateLeavesNoTSR(t *testing.T) {
+	commitInForeground(t)
+	conn := memkv.NewConnection(&redis.RedisItemFactory{})
+	tx := newValidateTxn(t, conn)
+	assert.NoError(t, tx.Write("memkv", "item1", "v1"))
+	assert.NoError(t, tx.Commit())
+	before, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+
+	tx = newValidateTxn(t, conn)
+	var value string
+	assert.NoError(t, tx.Read("memkv", "item1", &value))
+	assert.NoError(t, tx.Write("memkv", "item1", "v2"))
+	assert.NoError(t, tx.Validate())
+	assert.NotEmpty(t, tx.GroupKeyUrls)
+	for _, url := range tx.GroupKeyUrls {
+		_, err := conn.Get(url)
+		assert.EqualError(t, err, txn.KeyNotFound.Error())
+	}
+
+	after, err := conn.GetItem("item1")
+	assert.NoError(t, err)
+	assert.Equal(t, before.Value(), after.Value())
+	assert.Equal(t, config.COMMITTED, after.TxnState())
+	assert.Error(t, tx.Commit())
+}
@@ -0,0 +1,82 @@
+package txn
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// The compressed values start with a NUL byte, which no JSON document
+// starts with, followed by the name of the algorithm. Values stored
+// before compression was turned on are therefore read as they are.
+const (
+	gzipValuePrefix   = "\x00gzip:"
+	snappyValuePrefix = "\x00snappy:"
+)
+
+// CompressionFromName returns the compression called name, none if name is empty.
+func CompressionFromName(name string) (config.Compression, error) {
+	switch config.Compression(name) {
+	case config.NoCompression, config.GzipCompression, config.SnappyCompression:
+		return config.Compression(name), nil
+	default:
+		return "", fmt.Errorf("unsupported compression %q, expect gzip or snappy", name)
+	}
+}
+
+// CompressValue compresses value with config.Config.ValueCompression
+// if it is at least config.Config.ValueCompressionThreshold bytes long.
+// Connectors call it on the value and the previous versions of a record
+// before storing them, and DecompressValue after reading them.
+func CompressValue(value string) (string, error) {
+	if len(value) < config.Config.ValueCompressionThreshold {
+		return value, nil
+	}
+	switch config.Config.ValueCompression {
+	case config.GzipCompression:
+		var buf bytes.Buffer
+		buf.WriteString(gzipValuePrefix)
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write([]byte(value)); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	case config.SnappyCompression:
+		return snappyValuePrefix + string(snappy.Encode(nil, []byte(value))), nil
+	default:
+		return value, nil
+	}
+}
+
+// DecompressValue returns the value compressed by CompressValue,
+// or value itself if it is not compressed.
+func DecompressValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, gzipValuePrefix):
+		zr, err := gzip.NewReader(strings.NewReader(value[len(gzipValuePrefix):]))
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress the value: %w", err)
+		}
+		bs, err := io.ReadAll(zr)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress the value: %w", err)
+		}
+		return string(bs), nil
+	case strings.HasPrefix(value, snappyValuePrefix):
+		bs, err := snappy.Decode(nil, []byte(value[len(snappyValuePrefix):]))
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress the value: %w", err)
+		}
+		return string(bs), nil
+	default:
+		return value, nil
+	}
+}
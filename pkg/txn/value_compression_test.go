@@ -0,0 +1,72 @@
+package txn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueCompression(t *testing.T) {
+	compression, threshold := config.Config.ValueCompression, config.Config.ValueCompressionThreshold
+	defer func() {
+		config.Config.ValueCompression = compression
+		config.Config.ValueCompressionThreshold = threshold
+	}()
+	config.Config.ValueCompressionThreshold = 1024
+
+	large := `{"Name":"` + strings.Repeat("oreo", 2048) + `"}`
+	small := `{"Name":"oreo"}`
+
+	for _, c := range []config.Compression{config.GzipCompression, config.SnappyCompression} {
+		t.Run(string(c), func(t *testing.T) {
+			config.Config.ValueCompression = c
+
+			compressed, err := CompressValue(large)
+			assert.NoError(t, err)
+			assert.Less(t, len(compressed), len(large))
+			value, err := DecompressValue(compressed)
+			assert.NoError(t, err)
+			assert.Equal(t, large, value)
+
+			// a value below the threshold is stored as it is
+			compressed, err = CompressValue(small)
+			assert.NoError(t, err)
+			assert.Equal(t, small, compressed)
+		})
+	}
+
+	t.Run("values stored before compression are read as they are", func(t *testing.T) {
+		config.Config.ValueCompression = config.GzipCompression
+		for _, v := range []string{"", small, large} {
+			value, err := DecompressValue(v)
+			assert.NoError(t, err)
+			assert.Equal(t, v, value)
+		}
+	})
+
+	t.Run("a compressed value is read with compression off", func(t *testing.T) {
+		config.Config.ValueCompression = config.SnappyCompression
+		compressed, err := CompressValue(large)
+		assert.NoError(t, err)
+		config.Config.ValueCompression = config.NoCompression
+		value, err := DecompressValue(compressed)
+		assert.NoError(t, err)
+		assert.Equal(t, large, value)
+	})
+
+	t.Run("a corrupted value fails", func(t *testing.T) {
+		_, err := DecompressValue(gzipValuePrefix + "garbage")
+		assert.ErrorContains(t, err, "failed to decompress the value")
+	})
+}
+
+func TestCompressionFromName(t *testing.T) {
+	c, err := CompressionFromName("snappy")
+	assert.NoError(t, err)
+	assert.Equal(t, config.SnappyCompression, c)
+
+	_, err = CompressionFromName("lz4")
+	assert.EqualError(t, err, `unsupported compression "lz4", expect gzip or snappy`)
+}
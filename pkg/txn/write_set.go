@@ -0,0 +1,42 @@
+package txn
+
+import (
+	"github.com/go-errors/errors"
+	"github.com/oreo-dtx-lab/oreo/pkg/config"
+)
+
+// ErrWriteSetTooLarge is the cause of a write or a delete refused because
+// the transaction would write more than config.Config.MaxWriteSetSize records.
+var ErrWriteSetTooLarge = errors.Errorf("write set too large")
+
+// checkWriteSetSize returns an error if writing key of the datastore dsName
+// would take the write set of the transaction past MaxWriteSetSize.
+// Writing a key already in the write set again never fails.
+func (t *Transaction) checkWriteSetSize(dsName string, key string) error {
+	limit := config.Config.MaxWriteSetSize
+	if limit <= 0 {
+		return nil
+	}
+	if _, ok := t.writeSet[dsName][key]; ok {
+		return nil
+	}
+	if t.writeSetSize >= limit {
+		return errors.Errorf("cannot write %s in %s, the transaction has already written %d records: %w",
+			key, dsName, t.writeSetSize, ErrWriteSetTooLarge)
+	}
+	return nil
+}
+
+// recordWrite adds key of the datastore dsName to the write set.
+func (t *Transaction) recordWrite(dsName string, key string) {
+	if t.writeSet == nil {
+		t.writeSet = make(map[string]map[string]struct{})
+	}
+	if t.writeSet[dsName] == nil {
+		t.writeSet[dsName] = make(map[string]struct{})
+	}
+	if _, ok := t.writeSet[dsName][key]; !ok {
+		t.writeSet[dsName][key] = struct{}{}
+		t.writeSetSize++
+	}
+}